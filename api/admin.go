@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/audit"
+	"github.com/grustamli/insider-msg-sender/logging"
+)
+
+// CacheAdmin manages the cache of sent messages.
+type CacheAdmin interface {
+	// Flush removes all cached entries.
+	Flush(ctx context.Context) error
+	// Rebuild repopulates the cache from the primary store and returns the number of cached entries.
+	Rebuild(ctx context.Context) (int, error)
+}
+
+// LogLevelRequest is the payload for changing the log level at runtime.
+//
+// swagger:model LogLevelRequest
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required,oneof=TRACE DEBUG INFO WARN ERROR"` // new minimum log level
+}
+
+// LogLevelResponse reports the log level currently in effect.
+//
+// swagger:model LogLevelResponse
+type LogLevelResponse struct {
+	Level string `json:"level"` // current minimum log level
+}
+
+// registerAdmin mounts the administrative endpoints under /admin behind admin authentication.
+// Only endpoints whose dependencies were configured are registered.
+func (s *Server) registerAdmin() {
+	g := s.router.Group("/admin", s.validated(s.adminAuth())...)
+	g.GET("/loglevel", s.getLogLevel)
+	g.PUT("/loglevel", s.audited(audit.ActionLogLevelChange), s.setLogLevel)
+	if s.opts.cacheAdmin != nil {
+		g.POST("/cache/flush", s.audited(audit.ActionCacheFlush), s.flushCache)
+		g.POST("/cache/rebuild", s.audited(audit.ActionCacheRebuild), s.rebuildCache)
+	}
+}
+
+// flushCache clears the cached sent messages list. It is repopulated from the database on the next read.
+func (s *Server) flushCache(c *gin.Context) {
+	if err := s.opts.cacheAdmin.Flush(c); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cache flushed",
+	})
+}
+
+// rebuildCache replaces the cached sent messages list with the current contents of the database.
+func (s *Server) rebuildCache(c *gin.Context) {
+	n, err := s.opts.cacheAdmin.Rebuild(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	setAuditDetails(c, "cached %d messages", n)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cache rebuilt",
+		"cached":  n,
+	})
+}
+
+// getLogLevel returns the minimum log level currently in effect.
+func (s *Server) getLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, &LogLevelResponse{Level: string(logging.CurrentLevel())})
+}
+
+// setLogLevel changes the minimum log level of the running service without a restart, e.g. to enable DEBUG temporarily.
+// The change is not persisted; the LOG_LEVEL setting applies again after a restart.
+func (s *Server) setLogLevel(c *gin.Context) {
+	var req LogLevelRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	previous := logging.CurrentLevel()
+	if err := logging.SetLevel(logging.Level(req.Level)); err != nil {
+		c.Error(err)
+		return
+	}
+	s.log.Warn().
+		Str("from", string(previous)).
+		Str("to", req.Level).
+		Str("user", c.GetString(gin.AuthUserKey)).
+		Msg("Log level changed")
+	setAuditDetails(c, "%s -> %s", previous, req.Level)
+	c.JSON(http.StatusOK, &LogLevelResponse{Level: req.Level})
+}
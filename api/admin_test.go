@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/logging"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogLevelRequest(method, body string) *http.Request {
+	req := httptest.NewRequest(method, "/admin/loglevel", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth("admin", "secret")
+	return req
+}
+
+func decodeLogLevel(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var resp api.LogLevelResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Level
+}
+
+func TestAdminLogLevel(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+	require.NoError(t, logging.SetLevel(logging.INFO))
+	router := newTestRouter(t, &MockApp{}, api.WithAdminAuth("admin", "secret"))
+
+	w := serve(router, newLogLevelRequest(http.MethodGet, ""))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "INFO", decodeLogLevel(t, w))
+
+	w = serve(router, newLogLevelRequest(http.MethodPut, `{"level":"DEBUG"}`))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "DEBUG", decodeLogLevel(t, w))
+	assert.Equal(t, logging.DEBUG, logging.CurrentLevel())
+
+	w = serve(router, newLogLevelRequest(http.MethodGet, ""))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "DEBUG", decodeLogLevel(t, w))
+}
+
+func TestAdminLogLevel_RejectsUnknownLevel(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+	require.NoError(t, logging.SetLevel(logging.WARN))
+	router := newTestRouter(t, &MockApp{}, api.WithAdminAuth("admin", "secret"))
+
+	w := serve(router, newLogLevelRequest(http.MethodPut, `{"level":"VERBOSE"}`))
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	resp := decodeError(t, w)
+	assert.Equal(t, api.CodeValidationFailed, resp.Code)
+	require.Len(t, resp.Details, 1)
+	assert.Equal(t, "level", resp.Details[0].Field)
+	assert.Equal(t, logging.WARN, logging.CurrentLevel())
+}
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []api.OptFunc
+		user     string
+		password string
+		want     int
+		wantCode string
+	}{
+		{name: "not configured", user: "admin", password: "secret", want: http.StatusForbidden, wantCode: api.CodeForbidden},
+		{name: "wrong password", opts: []api.OptFunc{api.WithAdminAuth("admin", "secret")}, user: "admin", password: "guess", want: http.StatusUnauthorized, wantCode: api.CodeUnauthorized},
+		{name: "unknown user", opts: []api.OptFunc{api.WithAdminAuth("admin", "secret")}, user: "root", password: "secret", want: http.StatusUnauthorized, wantCode: api.CodeUnauthorized},
+		{name: "valid", opts: []api.OptFunc{api.WithAdminAuth("admin", "secret")}, user: "admin", password: "secret", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, &MockApp{}, tt.opts...)
+			req := httptest.NewRequest(http.MethodGet, "/admin/loglevel", nil)
+			req.SetBasicAuth(tt.user, tt.password)
+
+			w := serve(router, req)
+
+			require.Equal(t, tt.want, w.Code)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, decodeError(t, w).Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// AttemptResponse represents one try to deliver a message in API responses.
+//
+// swagger:model AttemptResponse
+type AttemptResponse struct {
+	StartedAt  time.Time `json:"started_at"`            // when the message was handed to the provider
+	DurationMs int64     `json:"duration_ms"`           // how long the provider took to accept or fail it, in milliseconds
+	Succeeded  bool      `json:"succeeded"`             // whether the message was delivered by the attempt
+	StatusCode int       `json:"status_code,omitempty"` // HTTP status code the provider answered a failed attempt with, if any
+	Error      string    `json:"error,omitempty"`       // why the attempt failed
+}
+
+// ListAttemptsResponse wraps the delivery attempts of a message.
+//
+// swagger:model ListAttemptsResponse
+type ListAttemptsResponse struct {
+	Items []*AttemptResponse `json:"items"` // attempts, oldest first
+}
+
+// newAttemptResponse converts a domain Attempt into an AttemptResponse.
+func newAttemptResponse(a *message.Attempt) *AttemptResponse {
+	return &AttemptResponse{
+		StartedAt:  a.StartedAt,
+		DurationMs: a.Duration.Milliseconds(),
+		Succeeded:  a.Succeeded(),
+		StatusCode: a.StatusCode,
+		Error:      a.Error,
+	}
+}
+
+// getMessage returns a single message of the tenant in whatever state it is in.
+func (s *Server) getMessage(c *gin.Context) {
+	msg, err := s.app.GetMessage(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newMessageResponse(msg))
+}
+
+// listMessageAttempts returns every attempt to deliver a message of the tenant, oldest first, with the status code
+// and error of the failed ones, so disputed or retried deliveries can be traced.
+func (s *Server) listMessageAttempts(c *gin.Context) {
+	attempts, err := s.app.ListAttempts(c, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	resp := ListAttemptsResponse{Items: make([]*AttemptResponse, len(attempts))}
+	for i, a := range attempts {
+		resp.Items[i] = newAttemptResponse(a)
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,101 @@
+package api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMessage(t *testing.T) {
+	createdAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	msg := &message.Message{ID: "7", To: "+905551234567", Content: "hello", Tenant: "default", CreatedAt: createdAt}
+	msg.SetAttemptFailed(errors.New("provider down"), createdAt.Add(time.Minute))
+	app := &MockApp{}
+	app.On("GetMessage", mock.Anything, "7").Return(msg, nil)
+	app.On("GetMessage", mock.Anything, "8").Return(nil, message.ErrMessageNotFound)
+	sent := &message.Message{ID: "9", To: "+905551234567", Content: "hello", Tenant: "default", CreatedAt: createdAt}
+	require.NoError(t, sent.SetSentResult(&message.SendResult{MessageID: "ext-9", SentAt: createdAt.Add(time.Minute),
+		StatusCode: http.StatusAccepted, Response: []byte(`{"messageId":"ext-9"}`)}))
+	app.On("GetMessage", mock.Anything, "9").Return(sent, nil)
+	router := newTestRouter(t, app, api.WithRequestValidation())
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages/7", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.MessageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "7", resp.ID)
+	assert.Equal(t, "pending", resp.Status)
+	require.NotNil(t, resp.CreatedAt)
+	assert.True(t, createdAt.Equal(*resp.CreatedAt))
+	require.NotNil(t, resp.NextAttemptAt)
+	assert.True(t, createdAt.Add(time.Minute).Equal(*resp.NextAttemptAt))
+
+	assert.Zero(t, resp.ProviderStatusCode)
+	assert.Nil(t, resp.ProviderResponse)
+
+	w = serve(router, httptest.NewRequest(http.MethodGet, "/messages/9", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = api.MessageResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusAccepted, resp.ProviderStatusCode)
+	assert.JSONEq(t, `{"messageId":"ext-9"}`, string(resp.ProviderResponse))
+
+	w = serve(router, httptest.NewRequest(http.MethodGet, "/messages/8", nil))
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, api.CodeNotFound, decodeError(t, w).Code)
+}
+
+func TestDeleteMessage(t *testing.T) {
+	app := &MockApp{}
+	app.On("DeleteMessage", mock.Anything, "7").Return(nil)
+	app.On("DeleteMessage", mock.Anything, "8").Return(message.ErrMessagePending)
+	app.On("DeleteMessage", mock.Anything, "9").Return(message.ErrMessageNotFound)
+	router := newTestRouter(t, app, api.WithRequestValidation())
+
+	w := serve(router, httptest.NewRequest(http.MethodDelete, "/messages/7", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serve(router, httptest.NewRequest(http.MethodDelete, "/messages/8", nil))
+	require.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, api.CodeConflict, decodeError(t, w).Code)
+
+	w = serve(router, httptest.NewRequest(http.MethodDelete, "/messages/9", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	app.AssertExpectations(t)
+}
+
+func TestListMessageAttempts(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	app := &MockApp{}
+	app.On("ListAttempts", mock.Anything, "7").Return([]*message.Attempt{
+		{MessageID: "7", StartedAt: start, Duration: 1500 * time.Millisecond, StatusCode: 503, Error: "received status 503"},
+		{MessageID: "7", StartedAt: start.Add(time.Minute), Duration: 200 * time.Millisecond},
+	}, nil)
+	app.On("ListAttempts", mock.Anything, "8").Return(nil, message.ErrMessageNotFound)
+	router := newTestRouter(t, app, api.WithRequestValidation())
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages/7/attempts", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.ListAttemptsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 2)
+	assert.Equal(t, &api.AttemptResponse{StartedAt: start, DurationMs: 1500, StatusCode: 503, Error: "received status 503"}, resp.Items[0])
+	assert.Equal(t, &api.AttemptResponse{StartedAt: start.Add(time.Minute), DurationMs: 200, Succeeded: true}, resp.Items[1])
+
+	w = serve(router, httptest.NewRequest(http.MethodGet, "/messages/8/attempts", nil))
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
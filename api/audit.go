@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/audit"
+)
+
+const (
+	// auditDetailsKey is the gin.Context key under which handlers leave action details for the audit entry.
+	auditDetailsKey = "audit_details"
+	// defaultAuditLimit is the page size of GET /audit when no limit is requested.
+	defaultAuditLimit = 100
+)
+
+// AuditQuery holds the query parameters of GET /audit.
+type AuditQuery struct {
+	Action string `form:"action" json:"action"`                                  // only entries of this action
+	Before string `form:"before" json:"before" binding:"omitempty,numeric"`      // only entries older than this entry ID
+	Limit  int    `form:"limit" json:"limit" binding:"omitempty,min=1,max=1000"` // page size
+}
+
+// AuditEntryResponse represents a recorded control action.
+//
+// swagger:model AuditEntryResponse
+type AuditEntryResponse struct {
+	ID         string    `json:"id"`                // entry identifier
+	Action     string    `json:"action"`            // action taken
+	Actor      string    `json:"actor,omitempty"`   // authenticated user that took the action
+	APIKey     string    `json:"api_key,omitempty"` // fingerprint of the API key the request carried
+	RequestID  string    `json:"request_id"`        // ID of the request that took the action
+	RemoteAddr string    `json:"remote_addr"`       // client IP address
+	Details    string    `json:"details,omitempty"` // action specific description
+	CreatedAt  time.Time `json:"created_at"`        // timestamp of the action
+}
+
+// ListAuditEntriesResponse wraps a page of audit entries.
+//
+// swagger:model ListAuditEntriesResponse
+type ListAuditEntriesResponse struct {
+	Items []*AuditEntryResponse `json:"items"`          // entries, newest first
+	Next  string                `json:"next,omitempty"` // value of the before parameter fetching the next page, if there may be one
+}
+
+// audited returns the middleware recording action in the audit log once the handler succeeded.
+// A failure to record is logged but does not fail the request, as the action already took effect.
+func (s *Server) audited(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if s.opts.auditLog == nil || len(c.Errors) > 0 || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		entry := &audit.Entry{
+			Action:     action,
+			Actor:      c.GetString(gin.AuthUserKey),
+			APIKey:     audit.KeyFingerprint(c.GetHeader(apiKeyHeader)),
+			RequestID:  c.GetString("request_id"),
+			RemoteAddr: c.ClientIP(),
+			Details:    c.GetString(auditDetailsKey),
+		}
+		if err := s.opts.auditLog.Record(c.Request.Context(), entry); err != nil {
+			s.log.Error().Err(err).Str("action", action).Msg("Failed to record audit entry")
+		}
+	}
+}
+
+// setAuditDetails describes the action taken by the current request in its audit entry.
+func setAuditDetails(c *gin.Context, format string, args ...any) {
+	c.Set(auditDetailsKey, fmt.Sprintf(format, args...))
+}
+
+// registerAudit mounts GET /audit behind admin authentication, when an audit log is configured.
+func (s *Server) registerAudit() {
+	if s.opts.auditLog == nil {
+		return
+	}
+	s.router.Group("", s.validated(s.adminAuth())...).GET("/audit", s.listAuditEntries)
+}
+
+// listAuditEntries returns recorded control actions, newest first, for compliance review.
+// Pass the returned next value as before to page through older entries.
+func (s *Server) listAuditEntries(c *gin.Context) {
+	var q AuditQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultAuditLimit
+	}
+	entries, err := s.opts.auditLog.List(c, audit.Filter{Action: q.Action, BeforeID: q.Before, Limit: q.Limit})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	resp := ListAuditEntriesResponse{Items: make([]*AuditEntryResponse, len(entries))}
+	for i, e := range entries {
+		resp.Items[i] = &AuditEntryResponse{
+			ID:         e.ID,
+			Action:     e.Action,
+			Actor:      e.Actor,
+			APIKey:     e.APIKey,
+			RequestID:  e.RequestID,
+			RemoteAddr: e.RemoteAddr,
+			Details:    e.Details,
+			CreatedAt:  e.CreatedAt,
+		}
+	}
+	if len(entries) == q.Limit {
+		resp.Next = entries[len(entries)-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/audit"
+	"github.com/grustamli/insider-msg-sender/logging"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryAuditLog keeps recorded entries in memory and records the filter entries were listed with.
+type memoryAuditLog struct {
+	entries []*audit.Entry
+	filter  audit.Filter
+}
+
+func (l *memoryAuditLog) Record(_ context.Context, e *audit.Entry) error {
+	e.ID = strconv.Itoa(len(l.entries) + 1)
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+func (l *memoryAuditLog) List(_ context.Context, f audit.Filter) ([]*audit.Entry, error) {
+	l.filter = f
+	return l.entries, nil
+}
+
+func TestAudit_RecordsSuccessfulControlActions(t *testing.T) {
+	scheduler := &MockDaemon{}
+	scheduler.On("Start", mock.Anything).Return(nil)
+	scheduler.On("Stop", mock.Anything).Return(errors.New("daemon stuck"))
+	auditLog := &memoryAuditLog{}
+	router := newTestRouterWithDaemon(t, &MockApp{}, scheduler, api.WithAuditLog(auditLog))
+
+	req := httptest.NewRequest(http.MethodPost, "/start", nil)
+	req.Header.Set("X-API-Key", "key-1")
+	w := serve(router, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	stopped := serve(router, httptest.NewRequest(http.MethodPost, "/stop", nil))
+	require.Equal(t, http.StatusInternalServerError, stopped.Code)
+
+	require.Len(t, auditLog.entries, 1, "failed actions are not recorded")
+	e := auditLog.entries[0]
+	assert.Equal(t, audit.ActionStart, e.Action)
+	assert.Equal(t, w.Header().Get("X-Request-ID"), e.RequestID)
+	assert.Equal(t, audit.KeyFingerprint("key-1"), e.APIKey)
+	assert.NotContains(t, e.APIKey, "key-1")
+	assert.NotEmpty(t, e.RemoteAddr)
+}
+
+func TestAudit_RecordsLogLevelChanges(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+	require.NoError(t, logging.SetLevel(logging.INFO))
+	auditLog := &memoryAuditLog{}
+	router := newTestRouter(t, &MockApp{}, api.WithAdminAuth("admin", "secret"), api.WithAuditLog(auditLog))
+
+	w := serve(router, newLogLevelRequest(http.MethodPut, `{"level":"DEBUG"}`))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, auditLog.entries, 1)
+	assert.Equal(t, audit.ActionLogLevelChange, auditLog.entries[0].Action)
+	assert.Equal(t, "admin", auditLog.entries[0].Actor)
+	assert.Equal(t, "INFO -> DEBUG", auditLog.entries[0].Details)
+}
+
+func TestListAuditEntries(t *testing.T) {
+	auditLog := &memoryAuditLog{entries: []*audit.Entry{
+		{ID: "9", Action: audit.ActionStop, RequestID: "req-9"},
+		{ID: "8", Action: audit.ActionStop, RequestID: "req-8"},
+	}}
+	router := newTestRouter(t, &MockApp{}, api.WithAdminAuth("admin", "secret"), api.WithAuditLog(auditLog))
+
+	req := httptest.NewRequest(http.MethodGet, "/audit?action=scheduler.stop&before=10&limit=2", nil)
+	req.SetBasicAuth("admin", "secret")
+	w := serve(router, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, audit.Filter{Action: audit.ActionStop, BeforeID: "10", Limit: 2}, auditLog.filter)
+	var resp api.ListAuditEntriesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 2)
+	assert.Equal(t, "req-9", resp.Items[0].RequestID)
+	assert.Equal(t, "8", resp.Next)
+}
+
+func TestListAuditEntries_RequiresAdmin(t *testing.T) {
+	router := newTestRouter(t, &MockApp{}, api.WithAdminAuth("admin", "secret"), api.WithAuditLog(&memoryAuditLog{}))
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/audit", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestListAuditEntries_InvalidQuery(t *testing.T) {
+	router := newTestRouter(t, &MockApp{}, api.WithAdminAuth("admin", "secret"), api.WithAuditLog(&memoryAuditLog{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/audit?limit=5000", nil)
+	req.SetBasicAuth("admin", "secret")
+	w := serve(router, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	resp := decodeError(t, w)
+	assert.Equal(t, api.CodeValidationFailed, resp.Code)
+	require.NotEmpty(t, resp.Details)
+	assert.Equal(t, "limit", resp.Details[0].Field)
+}
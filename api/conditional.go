@@ -0,0 +1,34 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// sentMessagesETag derives a strong entity tag for a list of sent messages.
+// Sent messages are append-only, so the count and latest send time identify a version of the list.
+// It is computed from the very list that is served, so the tag always describes the body it is sent with.
+func sentMessagesETag(msgs []*message.SentMessage) string {
+	var lastSent int64
+	for _, m := range msgs {
+		lastSent = max(lastSent, m.SentAt.UnixNano())
+	}
+	return fmt.Sprintf(`"%d-%x"`, len(msgs), lastSent)
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+// It accepts "*", comma-separated lists and weak validators, as per RFC 9110 weak comparison.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerPprof mounts the net/http/pprof handlers under /debug/pprof behind admin authentication.
+// Named profiles (heap, goroutine, allocs, block, mutex, threadcreate) are served by the index handler.
+func (s *Server) registerPprof() {
+	g := s.router.Group("/debug/pprof", s.adminAuth())
+	g.GET("/", gin.WrapF(pprof.Index))
+	g.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	g.GET("/profile", gin.WrapF(pprof.Profile))
+	g.GET("/symbol", gin.WrapF(pprof.Symbol))
+	g.POST("/symbol", gin.WrapF(pprof.Symbol))
+	g.GET("/trace", gin.WrapF(pprof.Trace))
+	g.GET("/:profile", gin.WrapF(pprof.Index))
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// reportTokenHeader is the request header carrying the token providers authenticate delivery reports with.
+const reportTokenHeader = "X-Report-Token"
+
+// DeliveryReportRequest is the body of POST /delivery-reports, a provider reporting the final status of a message.
+//
+// swagger:model DeliveryReportRequest
+type DeliveryReportRequest struct {
+	MessageID  string `json:"message_id" binding:"required,max=100"`                              // provider message ID returned when the message was sent
+	Status     string `json:"status" binding:"required,oneof=delivered undelivered"`              // final delivery outcome
+	ReportedAt string `json:"reported_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"` // when the provider observed the status, in RFC 3339; the time of receipt when omitted
+	Error      string `json:"error" binding:"max=1000"`                                           // why the message was not delivered, if known
+}
+
+// registerDeliveryReports enables POST /delivery-reports for providers, if a report token is configured.
+func (s *Server) registerDeliveryReports() {
+	if s.opts.reportToken == "" {
+		return
+	}
+	s.router.Group("", s.validated(s.reportAuth())...).POST("/delivery-reports", s.recordDeliveryReport)
+}
+
+// reportAuth returns the middleware rejecting requests without the configured report token in the X-Report-Token header.
+func (s *Server) reportAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(reportTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.reportToken)) != 1 {
+			abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "invalid report token")
+			return
+		}
+		c.Next()
+	}
+}
+
+// recordDeliveryReport records the final delivery status a provider reports for a message it accepted earlier,
+// identified by the provider message ID. Reports of unknown or unsent messages are rejected with 404 Not Found.
+func (s *Server) recordDeliveryReport(c *gin.Context) {
+	var req DeliveryReportRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	var reportedAt time.Time
+	if req.ReportedAt != "" {
+		// already validated by the binding
+		reportedAt, _ = time.Parse(time.RFC3339, req.ReportedAt)
+	}
+	report, err := message.NewDeliveryReport(req.MessageID, message.DeliveryStatus(req.Status), reportedAt, req.Error)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := s.app.RecordDeliveryReport(c, report); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Delivery report recorded",
+	})
+}
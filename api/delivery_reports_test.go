@@ -0,0 +1,107 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordDeliveryReport(t *testing.T) {
+	reportedAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		report     *message.DeliveryReport
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "delivered",
+			token:      "secret-token",
+			body:       `{"message_id":"ext-7","status":"delivered","reported_at":"2026-10-16T09:00:00Z"}`,
+			report:     &message.DeliveryReport{MessageID: "ext-7", Status: message.DeliveryDelivered, ReportedAt: reportedAt},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "undelivered with reason",
+			token: "secret-token",
+			body:  `{"message_id":"ext-7","status":"undelivered","reported_at":"2026-10-16T09:00:00Z","error":"absent subscriber"}`,
+			report: &message.DeliveryReport{MessageID: "ext-7", Status: message.DeliveryUndelivered, ReportedAt: reportedAt,
+				Reason: "absent subscriber"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown message",
+			token:      "secret-token",
+			body:       `{"message_id":"ext-7","status":"delivered","reported_at":"2026-10-16T09:00:00Z"}`,
+			report:     &message.DeliveryReport{MessageID: "ext-7", Status: message.DeliveryDelivered, ReportedAt: reportedAt},
+			err:        message.ErrMessageNotFound,
+			wantStatus: http.StatusNotFound,
+			wantCode:   api.CodeNotFound,
+		},
+		{
+			name:       "unknown status",
+			token:      "secret-token",
+			body:       `{"message_id":"ext-7","status":"read"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   api.CodeValidationFailed,
+		},
+		{
+			name:       "wrong token",
+			token:      "guess",
+			body:       `{"message_id":"ext-7","status":"delivered"}`,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   api.CodeUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &MockApp{}
+			if tt.report != nil {
+				app.On("RecordDeliveryReport", mock.Anything, tt.report).Return(tt.err)
+			}
+			router := newTestRouter(t, app, api.WithDeliveryReports("secret-token"), api.WithRequestValidation())
+			req := newJSONRequest(http.MethodPost, "/delivery-reports", tt.body)
+			req.Header.Set("X-Report-Token", tt.token)
+
+			w := serve(router, req)
+
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, decodeError(t, w).Code)
+			}
+			app.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecordDeliveryReport_DefaultsToReceiptTime(t *testing.T) {
+	app := &MockApp{}
+	app.On("RecordDeliveryReport", mock.Anything, mock.MatchedBy(func(r *message.DeliveryReport) bool {
+		return time.Since(r.ReportedAt) < time.Minute
+	})).Return(nil)
+	router := newTestRouter(t, app, api.WithDeliveryReports("secret-token"))
+	req := newJSONRequest(http.MethodPost, "/delivery-reports", `{"message_id":"ext-7","status":"delivered"}`)
+	req.Header.Set("X-Report-Token", "secret-token")
+
+	w := serve(router, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	app.AssertExpectations(t)
+}
+
+func TestRecordDeliveryReport_Disabled(t *testing.T) {
+	router := newTestRouter(t, &MockApp{})
+
+	w := serve(router, newJSONRequest(http.MethodPost, "/delivery-reports", `{"message_id":"ext-7","status":"delivered"}`))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
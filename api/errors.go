@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// Error codes returned in ErrorResponse.Code.
+const (
+	CodeValidationFailed = "validation_failed" // request payload or parameters are invalid
+	CodePayloadTooLarge  = "payload_too_large" // request body exceeds the configured limit
+	CodeUnauthorized     = "unauthorized"      // credentials are missing or wrong
+	CodeForbidden        = "forbidden"         // the endpoint is not accessible
+	CodeNotFound         = "not_found"         // the requested resource or route does not exist
+	CodeConflict         = "conflict"          // the resource is not in a state allowing the request
+	CodeIdempotencyReuse = "idempotency_reuse" // an idempotency key was replayed with a different payload
+	CodeProviderFailure  = "provider_failure"  // the message provider rejected or failed a delivery
+	CodeInternal         = "internal_error"    // any other unexpected failure
+)
+
+// ErrorResponse is the envelope returned for every failed request.
+//
+// swagger:model ErrorResponse
+type ErrorResponse struct {
+	Code      string        `json:"code"`              // machine-readable error code
+	Message   string        `json:"message"`           // human-readable description
+	RequestID string        `json:"request_id"`        // ID of the request, also sent as X-Request-ID
+	Details   []*FieldError `json:"details,omitempty"` // per-field validation failures, if any
+}
+
+// errorMapping associates a domain error with the HTTP status and code it is reported as.
+type errorMapping struct {
+	target error  // sentinel matched with errors.Is
+	status int    // HTTP status code to respond with
+	code   string // ErrorResponse code
+}
+
+// domainErrors lists the sentinel errors that map to client-facing statuses.
+// Errors not listed here are reported as internal errors.
+var domainErrors = []errorMapping{
+	{message.ErrMessageNotFound, http.StatusNotFound, CodeNotFound},
+	{message.ErrBlankID, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrInvalidPhoneNumber, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrBlankContent, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrBlankTemplate, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrUnknownTemplate, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrInvalidEmailAddress, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrBlankRecipient, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrUnknownChannel, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrChannelNotConfigured, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrBlankMessageID, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrUnknownDeliveryStatus, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, CodeIdempotencyReuse},
+	{message.ErrMessageNotFailed, http.StatusConflict, CodeConflict},
+	{message.ErrMessageNotPending, http.StatusConflict, CodeConflict},
+	{message.ErrMessageBeingSent, http.StatusConflict, CodeConflict},
+	{message.ErrMessagePending, http.StatusConflict, CodeConflict},
+	{message.ErrMessageDeleted, http.StatusConflict, CodeConflict},
+	{message.ErrNegativeCharacterLimit, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrSubscriptionNotFound, http.StatusNotFound, CodeNotFound},
+	{message.ErrInvalidCallbackURL, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrNoEventTypes, http.StatusBadRequest, CodeValidationFailed},
+	{message.ErrUnknownEventType, http.StatusBadRequest, CodeValidationFailed},
+}
+
+// ErrorHandler returns a Gin middleware that renders errors attached with c.Error
+// as an ErrorResponse, unless the handler already wrote a response.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		status, code, msg := classifyError(c.Errors.Last().Err)
+		abortWithError(c, status, code, msg)
+	}
+}
+
+// classifyError maps an error to an HTTP status, error code and client-facing message.
+// Internal errors get a generic message so storage details are not leaked.
+func classifyError(err error) (int, string, string) {
+	for _, m := range domainErrors {
+		if errors.Is(err, m.target) {
+			return m.status, m.code, m.target.Error()
+		}
+	}
+	var sendErr *message.SendError
+	if errors.As(err, &sendErr) {
+		return http.StatusBadGateway, CodeProviderFailure, sendErr.Error()
+	}
+	return http.StatusInternalServerError, CodeInternal, "internal server error"
+}
+
+// abortWithError aborts the request and writes an ErrorResponse with the given status, code and message.
+func abortWithError(c *gin.Context, status int, code, msg string, details ...*FieldError) {
+	c.AbortWithStatusJSON(status, &ErrorResponse{
+		Code:      code,
+		Message:   msg,
+		RequestID: c.GetString("request_id"),
+		Details:   details,
+	})
+}
+
+// recoverPanic renders recovered panics as an internal ErrorResponse.
+func recoverPanic(c *gin.Context, _ any) {
+	abortWithError(c, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// notFound renders unmatched routes as a not found ErrorResponse.
+func notFound(c *gin.Context) {
+	abortWithError(c, http.StatusNotFound, CodeNotFound, "route not found")
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// Supported export formats.
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+)
+
+// exportFlushEvery is the number of rows written between flushes to the client.
+const exportFlushEvery = 100
+
+// exportCSVHeader lists the CSV columns written by the export, in order.
+var exportCSVHeader = []string{"id", "to", "content", "message_id", "sent_at"}
+
+// ExportQuery holds the query parameters of the export endpoint.
+type ExportQuery struct {
+	Format string `form:"format" json:"format" binding:"omitempty,oneof=csv ndjson"` // output format, csv by default
+}
+
+// exportWriter encodes sent messages into a streamed export format.
+type exportWriter interface {
+	// write encodes a single message.
+	write(m *message.SentMessage) error
+	// flush pushes buffered output to the underlying writer.
+	flush() error
+}
+
+// csvExportWriter writes messages as CSV rows preceded by a header row.
+type csvExportWriter struct {
+	w          *csv.Writer // buffered CSV encoder
+	headerDone bool        // whether the header row was written
+}
+
+func (e *csvExportWriter) write(m *message.SentMessage) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.w.Write([]string{m.ID, m.To, m.Content, m.MessageID, m.SentAt.Format(time.RFC3339Nano)})
+}
+
+// flush also writes the header row, so an empty export still describes its columns.
+func (e *csvExportWriter) flush() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// writeHeader writes the header row once.
+func (e *csvExportWriter) writeHeader() error {
+	if e.headerDone {
+		return nil
+	}
+	e.headerDone = true
+	return e.w.Write(exportCSVHeader)
+}
+
+// ndjsonExportWriter writes messages as newline-delimited JSON objects.
+type ndjsonExportWriter struct {
+	buf *bufio.Writer // buffers encoded lines until flushed
+	enc *json.Encoder // JSON encoder writing into buf, one value per line
+}
+
+func (e *ndjsonExportWriter) write(m *message.SentMessage) error {
+	return e.enc.Encode(m)
+}
+
+func (e *ndjsonExportWriter) flush() error {
+	return e.buf.Flush()
+}
+
+// newExportWriter returns the exportWriter for format. Output is buffered until flushed.
+func newExportWriter(format string, w io.Writer) exportWriter {
+	if format == exportFormatNDJSON {
+		buf := bufio.NewWriter(w)
+		return &ndjsonExportWriter{buf: buf, enc: json.NewEncoder(buf)}
+	}
+	return &csvExportWriter{w: csv.NewWriter(w)}
+}
+
+// exportMediaType returns the content type and file extension of format.
+func exportMediaType(format string) (string, string) {
+	if format == exportFormatNDJSON {
+		return "application/x-ndjson", "ndjson"
+	}
+	return "text/csv; charset=utf-8", "csv"
+}
+
+// exportResponse is the io.Writer an export is streamed to.
+// The response status and headers are only sent along with the first bytes, so until then
+// a failure can still be reported as a regular ErrorResponse.
+type exportResponse struct {
+	c       *gin.Context
+	format  string // export format, determines the content type and file name
+	started bool   // whether the response headers were sent
+}
+
+func (r *exportResponse) Write(p []byte) (int, error) {
+	if !r.started {
+		r.started = true
+		contentType, ext := exportMediaType(r.format)
+		r.c.Header("Content-Type", contentType)
+		r.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="sent-messages.%s"`, ext))
+		r.c.Status(http.StatusOK)
+	}
+	return r.c.Writer.Write(p)
+}
+
+// exportSentMessages streams every sent message with recipient, content, provider message ID and sent timestamp.
+// Rows are written as they are read, so large exports are not buffered in memory.
+// If an error occurs after streaming has started the response is truncated.
+func (s *Server) exportSentMessages(c *gin.Context) {
+	var q ExportQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	if q.Format == "" {
+		q.Format = exportFormatCSV
+	}
+	out := newExportWriter(q.Format, &exportResponse{c: c, format: q.Format})
+	rows := 0
+	err := s.app.ExportSentMessages(c, func(m *message.SentMessage) error {
+		if err := out.write(m); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			if err := out.flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// rendered as an ErrorResponse unless rows were already sent, in which case the export is truncated
+		c.Error(err)
+		return
+	}
+	if err := out.flush(); err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,134 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// walkSent makes the mocked ExportSentMessages feed msgs to the export callback and then return err.
+func walkSent(app *MockApp, msgs []*message.SentMessage, err error) {
+	app.On("ExportSentMessages", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(1).(func(*message.SentMessage) error)
+			for _, m := range msgs {
+				if fn(m) != nil {
+					return
+				}
+			}
+		}).
+		Return(err)
+}
+
+func exportedMessages() []*message.SentMessage {
+	sentAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	return []*message.SentMessage{
+		{ID: "1", To: "+905551111111", Content: "first", MessageID: "ext-1", SentAt: sentAt, Tenant: "default"},
+		{ID: "2", To: "+905552222222", Content: "second, with comma", MessageID: "ext-2", SentAt: sentAt, Tenant: "default"},
+	}
+}
+
+func TestExportSentMessages(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		wantContentType string
+		wantFile        string
+		wantBody        string
+	}{
+		{
+			name:            "csv by default",
+			wantContentType: "text/csv; charset=utf-8",
+			wantFile:        "sent-messages.csv",
+			wantBody: "id,to,content,message_id,sent_at\n" +
+				"1,+905551111111,first,ext-1,2026-10-16T09:00:00Z\n" +
+				"2,+905552222222,\"second, with comma\",ext-2,2026-10-16T09:00:00Z\n",
+		},
+		{
+			name:            "ndjson",
+			query:           "?format=ndjson",
+			wantContentType: "application/x-ndjson",
+			wantFile:        "sent-messages.ndjson",
+			wantBody: `{"id":"1","to":"+905551111111","content":"first","message_id":"ext-1","sent_at":"2026-10-16T09:00:00Z","tenant":"default"}` + "\n" +
+				`{"id":"2","to":"+905552222222","content":"second, with comma","message_id":"ext-2","sent_at":"2026-10-16T09:00:00Z","tenant":"default"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &MockApp{}
+			walkSent(app, exportedMessages(), nil)
+			router := newTestRouter(t, app)
+
+			w := serve(router, httptest.NewRequest(http.MethodGet, "/messages/export"+tt.query, nil))
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Header().Get("Content-Disposition"), tt.wantFile)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestExportSentMessages_EmptyCSVHasHeader(t *testing.T) {
+	app := &MockApp{}
+	walkSent(app, nil, nil)
+	router := newTestRouter(t, app)
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages/export", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "id,to,content,message_id,sent_at\n", w.Body.String())
+}
+
+func TestExportSentMessages_ErrorBeforeFirstRow(t *testing.T) {
+	app := &MockApp{}
+	walkSent(app, nil, errors.New("exporting sent messages: connection refused"))
+	router := newTestRouter(t, app)
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages/export", nil))
+
+	// nothing was streamed yet, so the failure is reported as a regular error response
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, api.CodeInternal, decodeError(t, w).Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+}
+
+func TestExportSentMessages_ErrorAfterRowsTruncates(t *testing.T) {
+	msgs := make([]*message.SentMessage, 150)
+	for i := range msgs {
+		msgs[i] = &message.SentMessage{ID: "1", To: "+905551111111", Content: "row", MessageID: "ext", SentAt: time.Now()}
+	}
+	app := &MockApp{}
+	walkSent(app, msgs, errors.New("exporting sent messages: connection reset"))
+	router := newTestRouter(t, app)
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages/export", nil))
+
+	// the first batch of rows was already flushed, the rest of the export is cut off
+	require.Equal(t, http.StatusOK, w.Code)
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	assert.Len(t, lines, 101, "header and the first flushed batch")
+	assert.NotContains(t, w.Body.String(), `"code"`)
+}
+
+func TestExportSentMessages_InvalidFormat(t *testing.T) {
+	app := &MockApp{}
+	router := newTestRouter(t, app)
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages/export?format=xml", nil))
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	resp := decodeError(t, w)
+	require.Len(t, resp.Details, 1)
+	assert.Equal(t, "format", resp.Details[0].Field)
+	app.AssertNotCalled(t, "ExportSentMessages", mock.Anything, mock.Anything)
+}
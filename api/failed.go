@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// FailedMessagesQuery holds the query parameters of GET /messages/failed.
+type FailedMessagesQuery struct {
+	To       string `form:"to" json:"to"`                                          // only messages to this recipient
+	Contains string `form:"contains" json:"contains"`                              // only messages containing this text, case-insensitive
+	Limit    int    `form:"limit" json:"limit" binding:"omitempty,min=1,max=1000"` // maximum number of messages
+}
+
+// ListMessagesResponse wraps a list of stored messages.
+//
+// swagger:model ListMessagesResponse
+type ListMessagesResponse struct {
+	Items []*MessageResponse `json:"items"` // matching messages
+}
+
+// listFailedMessages returns the messages of the tenant whose delivery was given up, most recently failed first,
+// along with the number of attempts and the last error, so they can be inspected before re-queueing them.
+func (s *Server) listFailedMessages(c *gin.Context) {
+	var q FailedMessagesQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	msgs, err := s.app.FindFailedMessages(c, message.Filter{To: q.To, Contains: q.Contains, Limit: q.Limit})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	resp := ListMessagesResponse{Items: make([]*MessageResponse, len(msgs))}
+	for i, msg := range msgs {
+		resp.Items[i] = newMessageResponse(msg)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// cancelMessage withdraws a message that is still waiting to be sent, so it is never delivered.
+// Messages that were sent, given up or canceled already, or are being delivered right now, are rejected with 409 Conflict.
+func (s *Server) cancelMessage(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.app.CancelMessage(c, id); err != nil {
+		c.Error(err)
+		return
+	}
+	setAuditDetails(c, "message %s", id)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Message canceled",
+	})
+}
+
+// deleteMessage deletes a message that is no longer pending, so it no longer shows up in listings, lookups and stats.
+// Pending messages are rejected with 409 Conflict; they are canceled instead.
+func (s *Server) deleteMessage(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.app.DeleteMessage(c, id); err != nil {
+		c.Error(err)
+		return
+	}
+	setAuditDetails(c, "message %s", id)
+	c.Status(http.StatusNoContent)
+}
+
+// requeueMessage queues a message whose delivery was given up for sending again, with its attempts reset.
+// Messages that were sent or are still being retried are rejected with 409 Conflict.
+func (s *Server) requeueMessage(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.app.RequeueMessage(c, id); err != nil {
+		c.Error(err)
+		return
+	}
+	setAuditDetails(c, "message %s", id)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Message requeued",
+	})
+}
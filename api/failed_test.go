@@ -0,0 +1,112 @@
+package api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/audit"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFailedMessages(t *testing.T) {
+	msg := &message.Message{ID: "7", To: "+905551234567", Content: "hello", Tenant: "default"}
+	msg.SetFailed(errors.New("provider down"), time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	app := &MockApp{}
+	app.On("FindFailedMessages", mock.Anything, message.Filter{To: "+905551234567", Limit: 10}).
+		Return([]*message.Message{msg}, nil)
+	router := newTestRouter(t, app)
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages/failed?to=%2B905551234567&limit=10", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.ListMessagesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	item := resp.Items[0]
+	assert.Equal(t, "7", item.ID)
+	assert.True(t, item.Failed)
+	assert.False(t, item.Sent)
+	assert.Equal(t, 1, item.Attempts)
+	assert.Equal(t, "provider down", item.LastError)
+	require.NotNil(t, item.FailedAt)
+	assert.True(t, msg.FailedAt.Equal(*item.FailedAt))
+}
+
+func TestRequeueMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantAudit  int
+	}{
+		{name: "requeued", wantStatus: http.StatusAccepted, wantAudit: 1},
+		{name: "unknown message", err: message.ErrMessageNotFound, wantStatus: http.StatusNotFound, wantCode: api.CodeNotFound},
+		{name: "not failed", err: message.ErrMessageNotFailed, wantStatus: http.StatusConflict, wantCode: api.CodeConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &MockApp{}
+			app.On("RequeueMessage", mock.Anything, "7").Return(tt.err)
+			auditLog := &memoryAuditLog{}
+			router := newTestRouter(t, app, api.WithAuditLog(auditLog))
+
+			w := serve(router, httptest.NewRequest(http.MethodPost, "/messages/7/requeue", nil))
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, decodeError(t, w).Code)
+			}
+			require.Len(t, auditLog.entries, tt.wantAudit)
+			if tt.wantAudit > 0 {
+				assert.Equal(t, audit.ActionMessageRequeue, auditLog.entries[0].Action)
+				assert.Equal(t, "message 7", auditLog.entries[0].Details)
+			}
+			app.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCancelMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantAudit  int
+	}{
+		{name: "canceled", wantStatus: http.StatusOK, wantAudit: 1},
+		{name: "unknown message", err: message.ErrMessageNotFound, wantStatus: http.StatusNotFound, wantCode: api.CodeNotFound},
+		{name: "not pending", err: message.ErrMessageNotPending, wantStatus: http.StatusConflict, wantCode: api.CodeConflict},
+		{name: "being sent", err: message.ErrMessageBeingSent, wantStatus: http.StatusConflict, wantCode: api.CodeConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &MockApp{}
+			app.On("CancelMessage", mock.Anything, "7").Return(tt.err)
+			auditLog := &memoryAuditLog{}
+			router := newTestRouter(t, app, api.WithAuditLog(auditLog))
+
+			w := serve(router, httptest.NewRequest(http.MethodPost, "/messages/7/cancel", nil))
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, decodeError(t, w).Code)
+			}
+			require.Len(t, auditLog.entries, tt.wantAudit)
+			if tt.wantAudit > 0 {
+				assert.Equal(t, audit.ActionMessageCancel, auditLog.entries[0].Action)
+				assert.Equal(t, "message 7", auditLog.entries[0].Details)
+			}
+			app.AssertExpectations(t)
+		})
+	}
+}
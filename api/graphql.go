@@ -0,0 +1,199 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// GraphQLRequest is the standard GraphQL-over-HTTP request body.
+//
+// swagger:model GraphQLRequest
+type GraphQLRequest struct {
+	Query         string         `json:"query" binding:"required"` // GraphQL query document
+	OperationName string         `json:"operationName"`            // operation to execute when the document has several
+	Variables     map[string]any `json:"variables"`                // values for the query variables
+}
+
+// messageNode is the GraphQL representation of a message, sent or unsent.
+// Field names follow the GraphQL schema so the default resolver can read them.
+type messageNode struct {
+	ID        string     `json:"id"`
+	To        string     `json:"to"`
+	Content   string     `json:"content"`
+	MessageID *string    `json:"messageId"`
+	SentAt    *time.Time `json:"sentAt"`
+	Sent      bool       `json:"sent"`
+}
+
+// nodeFromMessage converts a domain Message into a messageNode.
+func nodeFromMessage(m *message.Message) *messageNode {
+	n := &messageNode{
+		ID:      m.ID,
+		To:      m.To,
+		Content: m.Content,
+	}
+	if m.IsSent() {
+		n.MessageID = &m.MessageID
+		n.SentAt = &m.SentAt
+		n.Sent = true
+	}
+	return n
+}
+
+// nodeFromSentMessage converts a SentMessage into a messageNode.
+func nodeFromSentMessage(m *message.SentMessage) *messageNode {
+	return &messageNode{
+		ID:        m.ID,
+		To:        m.To,
+		Content:   m.Content,
+		MessageID: &m.MessageID,
+		SentAt:    &m.SentAt,
+		Sent:      true,
+	}
+}
+
+// filterFromArgs builds a message.Filter from resolver arguments.
+func filterFromArgs(args map[string]any) message.Filter {
+	var f message.Filter
+	if v, ok := args["to"].(string); ok {
+		f.To = v
+	}
+	if v, ok := args["contains"].(string); ok {
+		f.Contains = v
+	}
+	if v, ok := args["sentAfter"].(time.Time); ok {
+		f.SentAfter = v
+	}
+	if v, ok := args["sentBefore"].(time.Time); ok {
+		f.SentBefore = v
+	}
+	if v, ok := args["limit"].(int); ok && v > 0 {
+		f.Limit = v
+	}
+	return f
+}
+
+// newGraphQLSchema builds the GraphQL schema for message queries, resolving data through app.
+func (s *Server) newGraphQLSchema() (graphql.Schema, error) {
+	messageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Message",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"to":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"content":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"messageId": &graphql.Field{Type: graphql.String},
+			"sentAt":    &graphql.Field{Type: graphql.DateTime},
+			"sent":      &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+	messageList := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(messageType)))
+	filterArgs := graphql.FieldConfigArgument{
+		"to":       &graphql.ArgumentConfig{Type: graphql.String},
+		"contains": &graphql.ArgumentConfig{Type: graphql.String},
+		"limit":    &graphql.ArgumentConfig{Type: graphql.Int},
+	}
+	sentFilterArgs := graphql.FieldConfigArgument{
+		"sentAfter":  &graphql.ArgumentConfig{Type: graphql.DateTime},
+		"sentBefore": &graphql.ArgumentConfig{Type: graphql.DateTime},
+	}
+	for k, v := range filterArgs {
+		sentFilterArgs[k] = v
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"sentMessages": &graphql.Field{
+				Type:    messageList,
+				Args:    sentFilterArgs,
+				Resolve: s.resolveSentMessages,
+			},
+			"unsentMessages": &graphql.Field{
+				Type:    messageList,
+				Args:    filterArgs,
+				Resolve: s.resolveUnsentMessages,
+			},
+			"message": &graphql.Field{
+				Type: messageType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: s.resolveMessage,
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// resolveSentMessages returns sent messages matching the query arguments.
+func (s *Server) resolveSentMessages(p graphql.ResolveParams) (any, error) {
+	msgs, err := s.app.FindSentMessages(p.Context, filterFromArgs(p.Args))
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*messageNode, len(msgs))
+	for i, m := range msgs {
+		nodes[i] = nodeFromSentMessage(m)
+	}
+	return nodes, nil
+}
+
+// resolveUnsentMessages returns queued messages matching the query arguments.
+func (s *Server) resolveUnsentMessages(p graphql.ResolveParams) (any, error) {
+	msgs, err := s.app.FindUnsentMessages(p.Context, filterFromArgs(p.Args))
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*messageNode, len(msgs))
+	for i, m := range msgs {
+		nodes[i] = nodeFromMessage(m)
+	}
+	return nodes, nil
+}
+
+// resolveMessage returns a single message by ID, or null if it does not exist.
+func (s *Server) resolveMessage(p graphql.ResolveParams) (any, error) {
+	id, _ := p.Args["id"].(string)
+	msg, err := s.app.GetMessage(p.Context, id)
+	if err != nil {
+		if errors.Is(err, message.ErrMessageNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return nodeFromMessage(msg), nil
+}
+
+// registerGraphQL builds the schema and mounts the /graphql endpoint on r.
+// The schema is static, so a construction failure is a programming error and panics.
+func (s *Server) registerGraphQL(r gin.IRoutes) {
+	schema, err := s.newGraphQLSchema()
+	if err != nil {
+		panic(errors.Wrap(err, "building graphql schema"))
+	}
+	r.POST("/graphql", s.graphQL(schema))
+}
+
+// graphQL executes a GraphQL query against sent and unsent messages.
+// Available queries are sentMessages, unsentMessages and message(id). Only enabled when API_GRAPHQL_ENABLED is set.
+func (s *Server) graphQL(schema graphql.Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GraphQLRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		res := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        c.Request.Context(),
+		})
+		c.JSON(http.StatusOK, res)
+	}
+}
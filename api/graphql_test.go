@@ -0,0 +1,83 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// graphQLResult is the subset of a GraphQL response the tests look at.
+type graphQLResult struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func queryGraphQL(t *testing.T, app *MockApp, query string, variables map[string]any) *graphQLResult {
+	t.Helper()
+	body, err := json.Marshal(api.GraphQLRequest{Query: query, Variables: variables})
+	require.NoError(t, err)
+	router := newTestRouter(t, app, api.WithGraphQL())
+
+	w := serve(router, newJSONRequest(http.MethodPost, "/graphql", string(body)))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var ret graphQLResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ret))
+	return &ret
+}
+
+func TestGraphQL_SentMessagesFilters(t *testing.T) {
+	sentAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	app := &MockApp{}
+	app.On("FindSentMessages", mock.Anything, message.Filter{
+		To:         "+905551111111",
+		Contains:   "promo",
+		SentAfter:  time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		SentBefore: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		Limit:      5,
+	}).Return([]*message.SentMessage{
+		{ID: "1", To: "+905551111111", Content: "promo code", MessageID: "ext-1", SentAt: sentAt},
+	}, nil)
+
+	res := queryGraphQL(t, app, `query($after: DateTime) {
+		sentMessages(to: "+905551111111", contains: "promo", sentAfter: $after, sentBefore: "2026-10-17T00:00:00Z", limit: 5) {
+			id messageId sent
+		}
+	}`, map[string]any{"after": "2026-10-01T00:00:00Z"})
+
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `[{"id":"1","messageId":"ext-1","sent":true}]`, string(res.Data["sentMessages"]))
+	app.AssertExpectations(t)
+}
+
+func TestGraphQL_UnsentMessagesWithoutFilters(t *testing.T) {
+	app := &MockApp{}
+	app.On("FindUnsentMessages", mock.Anything, message.Filter{}).Return([]*message.Message{
+		{ID: "7", To: "+905552222222", Content: "queued"},
+	}, nil)
+
+	res := queryGraphQL(t, app, `{ unsentMessages { id to sent sentAt } }`, nil)
+
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `[{"id":"7","to":"+905552222222","sent":false,"sentAt":null}]`, string(res.Data["unsentMessages"]))
+	app.AssertExpectations(t)
+}
+
+func TestGraphQL_MessageNotFoundIsNull(t *testing.T) {
+	app := &MockApp{}
+	app.On("GetMessage", mock.Anything, "404").Return(nil, message.ErrMessageNotFound)
+
+	res := queryGraphQL(t, app, `{ message(id: "404") { id } }`, nil)
+
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `null`, string(res.Data["message"]))
+}
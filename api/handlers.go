@@ -1,22 +1,16 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
 	"net/http"
 	"time"
 )
 
-// startSender godoc
-// @Description  Initiates the scheduler to begin sending messages at configured intervals.
-// @id startSender
-// @Tags Scheduler
-// @Summary Start message sender
-// @Accept json
-// @Produce json
-// @Success      202  {object}  map[string]string  "OK"
-// @Failure      500  {object}  map[string]string  "Internal Server Error"
-// @Router       /start [post]
+// startSender initiates the scheduler to begin sending messages at configured intervals.
 func (s *Server) startSender(c *gin.Context) {
 	if err := s.scheduler.Start(c); err != nil {
 		c.Error(err)
@@ -27,15 +21,8 @@ func (s *Server) startSender(c *gin.Context) {
 	})
 }
 
-// stopSender godoc
-// @Summary      Stop the message sender
-// @Description  Halts the scheduler, stopping any further message dispatch until restarted.
-// @Tags         Scheduler
-// @Accept       json
-// @Produce      json
-// @Success      202  {object}  map[string]string  "Accepted"
-// @Failure      500  {object}  map[string]string  "Internal Server Error"
-// @Router       /stop [post]
+// stopSender halts the scheduler, stopping any further message dispatch until restarted, also across service restarts
+// when the scheduler remembers its state.
 func (s *Server) stopSender(c *gin.Context) {
 	if err := s.scheduler.Stop(c); err != nil {
 		c.Error(err)
@@ -60,26 +47,97 @@ type MessageOut struct {
 type ListSentMessagesResponse struct {
 	// items is the array of messages that have been sent.
 	Items []*MessageOut `json:"items"`
+	// next_cursor continues the listing after the last item; only set on full pages.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// next is the link to the following page; only set on full pages.
+	Next string `json:"next,omitempty"`
 }
 
-// listSentMessages godoc
-// @Summary      List sent messages
-// @Description  Retrieve all messages that have been sent, including their IDs and timestamps.
-// @Tags         Scheduler
-// @Accept       json
-// @Produce      json
-// @Success      200  {object}  ListSentMessagesResponse
-// @Failure      500  {object}  map[string]string  "Internal Server Error"
-// @Router       /messages [get]
+// SentMessagesQuery holds the query parameters of GET /messages.
+type SentMessagesQuery struct {
+	PageQuery
+	To       string `form:"to" json:"to"`             // only messages sent to this recipient
+	Contains string `form:"contains" json:"contains"` // only messages containing this text, case-insensitive
+}
+
+// FoundMessage represents a sent message matching a search, with what support staff need to tell which message it is
+// and to look it up by its id.
+//
+// swagger:model FoundMessage
+type FoundMessage struct {
+	ID        string    `json:"id"`         // internal message identifier, as used by GET /messages/{id}
+	To        string    `json:"to"`         // recipient the message was sent to
+	Content   string    `json:"content"`    // message payload
+	MessageID string    `json:"message_id"` // message ID received from the provider
+	SentAt    time.Time `json:"sent_at"`    // when the message was sent
+}
+
+// SearchSentMessagesResponse wraps a page of sent messages matching a search.
+//
+// swagger:model SearchSentMessagesResponse
+type SearchSentMessagesResponse struct {
+	Items      []*FoundMessage `json:"items"`                 // matching messages in delivery order
+	NextCursor string          `json:"next_cursor,omitempty"` // continues the search after the last item; only set on full pages
+	Next       string          `json:"next,omitempty"`        // link to the following page; only set on full pages
+}
+
+// searched reports whether q narrows the listing down to matching messages.
+func (q *SentMessagesQuery) searched() bool {
+	return q.To != "" || q.Contains != ""
+}
+
+// listSentMessages retrieves all messages that have been sent, including their IDs and timestamps.
+// Given a limit or cursor, it returns a single page in delivery order instead, along with the cursor and link
+// of the next page. Messages sent meanwhile are appended to the end, so paging neither skips nor repeats messages.
+// Searches by recipient or content are always paged, so support staff can look up matching messages without
+// reading the whole list, and return the internal ID, recipient and content of each message along with the rest.
+// Responses carry an ETag; send it back in If-None-Match to get 304 Not Modified when nothing was sent since.
 func (s *Server) listSentMessages(c *gin.Context) {
-	sentMessages, err := s.app.ListSentMessages(c)
+	var q SentMessagesQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	var (
+		resp         ListSentMessagesResponse
+		sentMessages []*message.SentMessage
+		err          error
+	)
+	if q.paged() || q.searched() {
+		f, ok := pageFilter(c, &q.PageQuery)
+		if !ok {
+			return
+		}
+		f.To, f.Contains = q.To, q.Contains
+		sentMessages, err = s.app.FindSentMessages(c, f)
+		// a full page may be followed by more messages
+		if err == nil && len(sentMessages) == f.Limit {
+			resp.NextCursor = encodeCursor(message.PositionOf(sentMessages[len(sentMessages)-1]))
+			resp.Next = nextPageLink(c, resp.NextCursor, f.Limit)
+		}
+	} else {
+		sentMessages, err = s.app.ListSentMessages(c)
+	}
 	if err != nil {
 		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, ListSentMessagesResponse{
-		Items: buildMessageOuts(sentMessages),
-	})
+	// unchanged lists are not serialized nor sent again
+	etag := sentMessagesETag(sentMessages)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	if q.searched() {
+		c.JSON(http.StatusOK, SearchSentMessagesResponse{
+			Items:      buildFoundMessages(sentMessages),
+			NextCursor: resp.NextCursor,
+			Next:       resp.Next,
+		})
+		return
+	}
+	resp.Items = buildMessageOuts(sentMessages)
+	c.JSON(http.StatusOK, resp)
 }
 
 func buildMessageOuts(messages []*message.SentMessage) []*MessageOut {
@@ -92,3 +150,284 @@ func buildMessageOuts(messages []*message.SentMessage) []*MessageOut {
 	}
 	return ret
 }
+
+func buildFoundMessages(messages []*message.SentMessage) []*FoundMessage {
+	ret := make([]*FoundMessage, len(messages))
+	for i, m := range messages {
+		ret[i] = &FoundMessage{
+			ID:        m.ID,
+			To:        m.To,
+			Content:   m.Content,
+			MessageID: m.MessageID,
+			SentAt:    m.SentAt,
+		}
+	}
+	return ret
+}
+
+// idempotencyKeyHeader is the request header carrying the client idempotency key for message creation.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength matches the size of the idempotency_key column.
+const maxIdempotencyKeyLength = 255
+
+// CreateMessageRequest is the payload for creating a single message.
+//
+// swagger:model CreateMessageRequest
+type CreateMessageRequest struct {
+	To        string            `json:"to" binding:"required,max=320"`                                     // recipient: E.164 phone number, email address or device token, depending on the channel
+	Channel   string            `json:"channel" binding:"omitempty,oneof=sms email push chat"`             // medium the message is delivered through; sms when omitted
+	Content   string            `json:"content" binding:"required_without=Template"`                       // message payload, unless rendered from a template
+	Template  string            `json:"template" binding:"excluded_with=Content,max=100"`                  // name of the template the payload is rendered from at send time
+	Variables map[string]string `json:"variables"`                                                         // values the template is rendered with
+	Priority  int               `json:"priority" binding:"min=-100,max=100"`                               // messages with a higher priority are sent first
+	SendAt    string            `json:"send_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`    // earliest delivery time in RFC 3339; sent right away when omitted
+	ExpiresAt string            `json:"expires_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"` // time in RFC 3339 after which the message is no longer sent
+}
+
+// MessageResponse represents a stored message, sent or not.
+//
+// swagger:model MessageResponse
+type MessageResponse struct {
+	ID                 string            `json:"id"`                             // internal message identifier
+	To                 string            `json:"to"`                             // recipient phone number, email address or device token
+	Channel            string            `json:"channel"`                        // medium the message is delivered through
+	Content            string            `json:"content"`                        // message payload; empty until sent for templated messages
+	Template           string            `json:"template,omitempty"`             // name of the template the payload is rendered from, if any
+	Variables          map[string]string `json:"variables,omitempty"`            // values the template is rendered with
+	Status             string            `json:"status"`                         // state in the delivery lifecycle: pending, sent, failed, rejected, canceled or expired
+	CreatedAt          *time.Time        `json:"created_at,omitempty"`           // when the message was queued
+	Sent               bool              `json:"sent"`                           // whether the message was delivered
+	MessageID          string            `json:"message_id,omitempty"`           // provider message ID, once sent
+	SentAt             *time.Time        `json:"sent_at,omitempty"`              // delivery timestamp, once sent
+	Tenant             string            `json:"tenant"`                         // tenant owning the message
+	Attempts           int               `json:"attempts,omitempty"`             // failed delivery attempts so far
+	LastError          string            `json:"last_error,omitempty"`           // reason the last delivery attempt failed
+	NextAttemptAt      *time.Time        `json:"next_attempt_at,omitempty"`      // earliest time of the next attempt after a failed one
+	Failed             bool              `json:"failed"`                         // whether delivery was given up
+	FailedAt           *time.Time        `json:"failed_at,omitempty"`            // when delivery was given up
+	Priority           int               `json:"priority"`                       // messages with a higher priority are sent first
+	SendAt             *time.Time        `json:"send_at,omitempty"`              // earliest delivery time, if scheduled
+	ExpiresAt          *time.Time        `json:"expires_at,omitempty"`           // time after which the message is no longer sent, if any
+	Expired            bool              `json:"expired"`                        // whether the message was given up because it expired
+	ExpiredAt          *time.Time        `json:"expired_at,omitempty"`           // when the message was found expired
+	Canceled           bool              `json:"canceled"`                       // whether the message was canceled before being sent
+	CanceledAt         *time.Time        `json:"canceled_at,omitempty"`          // when the message was canceled
+	Rejected           bool              `json:"rejected"`                       // whether validation rejected the message before sending, see last_error
+	RejectedAt         *time.Time        `json:"rejected_at,omitempty"`          // when the message was rejected
+	DeliveryStatus     string            `json:"delivery_status,omitempty"`      // final delivery outcome reported by the provider, once reported
+	DeliveryReportedAt *time.Time        `json:"delivery_reported_at,omitempty"` // when the provider observed the delivery status
+	DeliveryError      string            `json:"delivery_error,omitempty"`       // why the provider could not deliver the message, if it said
+	ProviderStatusCode int               `json:"provider_status_code,omitempty"` // HTTP status code the provider accepted the message with, if known
+	ProviderResponse   json.RawMessage   `json:"provider_response,omitempty"`    // JSON the provider accepted the message with, if known
+}
+
+// newMessageResponse converts a domain Message into a MessageResponse.
+func newMessageResponse(m *message.Message) *MessageResponse {
+	ret := &MessageResponse{
+		ID:        m.ID,
+		To:        m.To,
+		Channel:   string(message.ChannelOf(m)),
+		Content:   m.Content,
+		Tenant:    m.Tenant,
+		Attempts:  m.Attempts,
+		LastError: m.LastError,
+		Priority:  m.Priority,
+		Template:  m.Template,
+		Variables: m.Variables,
+		Status:    string(m.Status()),
+	}
+	if !m.CreatedAt.IsZero() {
+		ret.CreatedAt = &m.CreatedAt
+	}
+	if !m.NextAttemptAt.IsZero() {
+		ret.NextAttemptAt = &m.NextAttemptAt
+	}
+	if !m.ScheduledAt.IsZero() {
+		ret.SendAt = &m.ScheduledAt
+	}
+	if !m.ExpiresAt.IsZero() {
+		ret.ExpiresAt = &m.ExpiresAt
+	}
+	if m.IsExpired() {
+		ret.Expired = true
+		ret.ExpiredAt = &m.ExpiredAt
+	}
+	if m.IsCanceled() {
+		ret.Canceled = true
+		ret.CanceledAt = &m.CanceledAt
+	}
+	if m.IsRejected() {
+		ret.Rejected = true
+		ret.RejectedAt = &m.RejectedAt
+	}
+	if m.IsFailed() {
+		ret.Failed = true
+		ret.FailedAt = &m.FailedAt
+	}
+	if m.IsSent() {
+		ret.Sent = true
+		ret.MessageID = m.MessageID
+		ret.SentAt = &m.SentAt
+		ret.ProviderStatusCode = m.ProviderStatusCode
+		ret.ProviderResponse = m.ProviderResponse
+	}
+	if m.DeliveryStatus != "" {
+		ret.DeliveryStatus = string(m.DeliveryStatus)
+		ret.DeliveryReportedAt = &m.DeliveryReportedAt
+		ret.DeliveryError = m.DeliveryError
+	}
+	return ret
+}
+
+// createMessage queues a new message for sending.
+// Send an Idempotency-Key header to make retries safe: repeating a request with the same key returns the originally
+// created message with status 200 and the Idempotent-Replayed header instead of queueing a duplicate.
+func (s *Server) createMessage(c *gin.Context) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		abortWithError(c, http.StatusBadRequest, CodeValidationFailed, "request validation failed", &FieldError{
+			Field:   idempotencyKeyHeader,
+			Message: fmt.Sprintf("must be at most %d characters", maxIdempotencyKeyLength),
+		})
+		return
+	}
+	var req CreateMessageRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	msg, err := newUnsentMessage(&req)
+	if errors.Is(err, message.ErrInvalidPhoneNumber) || errors.Is(err, message.ErrInvalidEmailAddress) {
+		abortWithError(c, http.StatusBadRequest, CodeValidationFailed, "request validation failed",
+			&FieldError{Field: "to", Message: fmt.Sprintf("must be a valid recipient on the %s channel", channelOf(&req))})
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	msg.IdempotencyKey = key
+	msg.Priority = req.Priority
+	if req.SendAt != "" {
+		// already validated by the binding
+		msg.ScheduledAt, _ = time.Parse(time.RFC3339, req.SendAt)
+	}
+	if req.ExpiresAt != "" {
+		msg.ExpiresAt, _ = time.Parse(time.RFC3339, req.ExpiresAt)
+	}
+
+	stored, created, err := s.app.CreateMessage(c, msg)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !created {
+		c.Header("Idempotent-Replayed", "true")
+		c.JSON(http.StatusOK, newMessageResponse(stored))
+		return
+	}
+	c.JSON(http.StatusCreated, newMessageResponse(stored))
+}
+
+// newUnsentMessage constructs the message requested by req, rendered from a template if req names one.
+func newUnsentMessage(req *CreateMessageRequest) (*message.Message, error) {
+	if req.Template != "" {
+		return message.NewTemplatedMessage(channelOf(req), req.To, req.Template, req.Variables)
+	}
+	return message.NewUnsentChannelMessage(channelOf(req), req.To, req.Content)
+}
+
+// channelOf returns the channel requested by req, message.ChannelSMS if it names none.
+func channelOf(req *CreateMessageRequest) message.Channel {
+	if req.Channel == "" {
+		return message.ChannelSMS
+	}
+	// already validated by the binding
+	return message.Channel(req.Channel)
+}
+
+// StatsResponse holds aggregate message statistics.
+//
+// swagger:model StatsResponse
+type StatsResponse struct {
+	Sent              int64                `json:"sent"`                // number of delivered messages
+	Unsent            int64                `json:"unsent"`              // number of messages not delivered yet, failed, expired, canceled and rejected ones excluded
+	Failed            int64                `json:"failed"`              // number of messages whose delivery was given up
+	Expired           int64                `json:"expired"`             // number of messages given up because they expired unsent
+	Canceled          int64                `json:"canceled"`            // number of messages canceled before being sent
+	Rejected          int64                `json:"rejected"`            // number of messages rejected by validation before being sent
+	FailureRate       float64              `json:"failure_rate"`        // share of finished deliveries that were given up, between 0 and 1
+	QueueDepth        int64                `json:"queue_depth"`         // messages waiting to be sent, i.e. the unsent count
+	SentLastHour      int64                `json:"sent_last_hour"`      // messages delivered within the last hour
+	SentLastDay       int64                `json:"sent_last_day"`       // messages delivered within the last 24 hours
+	AvgLatencySeconds float64              `json:"avg_latency_seconds"` // average time from creation to delivery
+	SentByHour        []*HourCountResponse `json:"sent_by_hour"`        // messages delivered in each of the last 24 hours, oldest first
+	Quota             *QuotaResponse       `json:"quota,omitempty"`     // daily quota usage of the tenant, if it has a daily quota
+}
+
+// HourCountResponse holds the number of messages counted within one hour.
+//
+// swagger:model HourCountResponse
+type HourCountResponse struct {
+	Hour  time.Time `json:"hour"`  // start of the hour
+	Count int64     `json:"count"` // number of messages within the hour
+}
+
+// QuotaResponse holds how much of its daily quota a tenant used today.
+//
+// swagger:model QuotaResponse
+type QuotaResponse struct {
+	Tenant    string    `json:"tenant"`    // tenant the quota belongs to
+	Limit     int64     `json:"limit"`     // messages the tenant may send per day
+	Used      int64     `json:"used"`      // messages the tenant sent today
+	Remaining int64     `json:"remaining"` // messages the tenant may still send today
+	ResetsAt  time.Time `json:"resets_at"` // start of the next day, when messages deferred over the quota are sent
+}
+
+// getStats returns counts of sent and unsent messages, recent delivery volume by hour, failure rate, average delivery
+// latency and queue depth, along with the daily quota usage of the tenant.
+func (s *Server) getStats(c *gin.Context) {
+	stats, err := s.app.Stats(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, &StatsResponse{
+		Sent:              stats.Sent,
+		Unsent:            stats.Unsent,
+		Failed:            stats.Failed,
+		Expired:           stats.Expired,
+		Canceled:          stats.Canceled,
+		Rejected:          stats.Rejected,
+		FailureRate:       stats.FailureRate(),
+		QueueDepth:        stats.Unsent,
+		SentLastHour:      stats.SentLastHour,
+		SentLastDay:       stats.SentLastDay,
+		AvgLatencySeconds: stats.AvgLatency.Seconds(),
+		SentByHour:        hourCountResponses(stats.SentByHour),
+		Quota:             quotaResponse(stats.Quota),
+	})
+}
+
+// hourCountResponses converts domain hour counts into their API responses.
+func hourCountResponses(counts []message.HourCount) []*HourCountResponse {
+	ret := make([]*HourCountResponse, len(counts))
+	for i, c := range counts {
+		ret[i] = &HourCountResponse{Hour: c.Hour, Count: c.Count}
+	}
+	return ret
+}
+
+// quotaResponse converts a domain quota usage into its API response, nil if there is none.
+func quotaResponse(u *message.QuotaUsage) *QuotaResponse {
+	if u == nil {
+		return nil
+	}
+	return &QuotaResponse{
+		Tenant:    u.Tenant,
+		Limit:     u.Limit,
+		Used:      u.Used,
+		Remaining: u.Remaining(),
+		ResetsAt:  u.ResetsAt,
+	}
+}
@@ -0,0 +1,365 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListSentMessages_ETag(t *testing.T) {
+	sentAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	first := []*message.SentMessage{{ID: "1", MessageID: "ext-1", SentAt: sentAt}}
+	second := append(first, &message.SentMessage{ID: "2", MessageID: "ext-2", SentAt: sentAt.Add(time.Minute)})
+	app := &MockApp{}
+	app.On("ListSentMessages", mock.Anything).Return(first, nil).Twice()
+	app.On("ListSentMessages", mock.Anything).Return(second, nil).Once()
+	router := newTestRouter(t, app)
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	var resp api.ListSentMessagesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, "ext-1", resp.Items[0].ID)
+
+	// unchanged list: the body is not sent again
+	req := httptest.NewRequest(http.MethodGet, "/messages", nil)
+	req.Header.Set("If-None-Match", "W/"+etag)
+	w = serve(router, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	// a new message changes the tag the body is served with
+	req = httptest.NewRequest(http.MethodGet, "/messages", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = serve(router, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Items, 2)
+	app.AssertExpectations(t)
+}
+
+func TestListSentMessages_Pages(t *testing.T) {
+	sentAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	firstPage := []*message.SentMessage{
+		{ID: "1", MessageID: "ext-1", SentAt: sentAt},
+		{ID: "2", MessageID: "ext-2", SentAt: sentAt.Add(time.Second)},
+	}
+	lastPage := []*message.SentMessage{{ID: "3", MessageID: "ext-3", SentAt: sentAt.Add(2 * time.Second)}}
+	app := &MockApp{}
+	app.On("FindSentMessages", mock.Anything, message.Filter{Limit: 2}).Return(firstPage, nil).Once()
+	app.On("FindSentMessages", mock.Anything, message.Filter{
+		After: &message.Position{SentAt: sentAt.Add(time.Second), ID: "2"},
+		Limit: 2,
+	}).Return(lastPage, nil).Once()
+	router := newTestRouter(t, app, api.WithRequestValidation())
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages?limit=2", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp api.ListSentMessagesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 2)
+	require.NotEmpty(t, resp.NextCursor)
+	assert.Equal(t, "/messages?cursor="+resp.NextCursor+"&limit=2", resp.Next)
+
+	// the next link continues after the last message of the page; a partial page is the last one
+	w = serve(router, httptest.NewRequest(http.MethodGet, resp.Next, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	resp = api.ListSentMessagesResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, "ext-3", resp.Items[0].ID)
+	assert.Empty(t, resp.NextCursor)
+	assert.Empty(t, resp.Next)
+	app.AssertExpectations(t)
+}
+
+func TestListSentMessages_Search(t *testing.T) {
+	sentAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	found := []*message.SentMessage{{ID: "1", To: "+905551234567", Content: "Your order 42 shipped", MessageID: "ext-1",
+		SentAt: sentAt, Tenant: message.DefaultTenant}}
+	app := &MockApp{}
+	app.On("FindSentMessages", mock.Anything, message.Filter{
+		To:       "+905551234567",
+		Contains: "order 42",
+		Limit:    100,
+	}).Return(found, nil).Once()
+	router := newTestRouter(t, app, api.WithRequestValidation())
+
+	// searches are paged even without a limit or cursor
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages?to=%2B905551234567&contains=order+42", nil))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp api.SearchSentMessagesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	// matches carry the internal ID to look them up by, along with recipient and content
+	assert.Equal(t, []*api.FoundMessage{{
+		ID:        "1",
+		To:        "+905551234567",
+		Content:   "Your order 42 shipped",
+		MessageID: "ext-1",
+		SentAt:    sentAt,
+	}}, resp.Items)
+	assert.Empty(t, resp.Next)
+	app.AssertExpectations(t)
+}
+
+func TestListSentMessages_InvalidCursor(t *testing.T) {
+	router := newTestRouter(t, &MockApp{})
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/messages?cursor=not-a-cursor", nil))
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	resp := decodeError(t, w)
+	assert.Equal(t, api.CodeValidationFailed, resp.Code)
+	require.NotEmpty(t, resp.Details)
+	assert.Equal(t, "cursor", resp.Details[0].Field)
+}
+
+func TestCreateMessage(t *testing.T) {
+	stored := &message.Message{ID: "42", To: "+905551234567", Content: "hello", Tenant: message.DefaultTenant}
+	tests := []struct {
+		name         string
+		key          string
+		created      bool
+		err          error
+		wantStatus   int
+		wantReplayed string
+		wantCode     string
+	}{
+		{name: "created", key: "key-1", created: true, wantStatus: http.StatusCreated},
+		{name: "replayed", key: "key-1", wantStatus: http.StatusOK, wantReplayed: "true"},
+		{name: "key reused with another payload", key: "key-1", err: message.ErrIdempotencyKeyReused,
+			wantStatus: http.StatusUnprocessableEntity, wantCode: api.CodeIdempotencyReuse},
+		{name: "key of a deleted message", key: "key-1", err: message.ErrMessageDeleted,
+			wantStatus: http.StatusConflict, wantCode: api.CodeConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &MockApp{}
+			isRequested := mock.MatchedBy(func(m *message.Message) bool {
+				return m.To == "+905551234567" && m.Content == "hello" && m.IdempotencyKey == tt.key
+			})
+			if tt.err != nil {
+				app.On("CreateMessage", mock.Anything, isRequested).Return(nil, false, tt.err)
+			} else {
+				app.On("CreateMessage", mock.Anything, isRequested).Return(stored, tt.created, nil)
+			}
+			router := newTestRouter(t, app)
+			req := newJSONRequest(http.MethodPost, "/messages", `{"to":"+905551234567","content":"hello"}`)
+			req.Header.Set("Idempotency-Key", tt.key)
+
+			w := serve(router, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantReplayed, w.Header().Get("Idempotent-Replayed"))
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, decodeError(t, w).Code)
+			} else {
+				var resp api.MessageResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, "42", resp.ID)
+				assert.False(t, resp.Sent)
+			}
+			app.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateMessage_Priority(t *testing.T) {
+	app := &MockApp{}
+	stored := &message.Message{ID: "42", To: "+905551234567", Content: "hello", Tenant: message.DefaultTenant, Priority: 10}
+	app.On("CreateMessage", mock.Anything, mock.MatchedBy(func(m *message.Message) bool {
+		return m.Priority == 10
+	})).Return(stored, true, nil)
+	router := newTestRouter(t, app)
+
+	w := serve(router, newJSONRequest(http.MethodPost, "/messages", `{"to":"+905551234567","content":"hello","priority":10}`))
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp api.MessageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 10, resp.Priority)
+	app.AssertExpectations(t)
+}
+
+func TestCreateMessage_Scheduled(t *testing.T) {
+	app := &MockApp{}
+	sendAt := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	stored := &message.Message{ID: "42", To: "+905551234567", Content: "hello", Tenant: message.DefaultTenant, ScheduledAt: sendAt}
+	app.On("CreateMessage", mock.Anything, mock.MatchedBy(func(m *message.Message) bool {
+		return m.ScheduledAt.Equal(sendAt)
+	})).Return(stored, true, nil)
+	router := newTestRouter(t, app)
+
+	w := serve(router, newJSONRequest(http.MethodPost, "/messages",
+		`{"to":"+905551234567","content":"hello","send_at":"2026-10-17T09:00:00Z"}`))
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp api.MessageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.SendAt)
+	assert.True(t, sendAt.Equal(*resp.SendAt))
+	app.AssertExpectations(t)
+}
+
+func TestCreateMessage_Templated(t *testing.T) {
+	app := &MockApp{}
+	vars := map[string]string{"name": "Ada"}
+	stored := &message.Message{ID: "42", To: "+905551234567", Tenant: message.DefaultTenant, Template: "welcome", Variables: vars}
+	app.On("CreateMessage", mock.Anything, mock.MatchedBy(func(m *message.Message) bool {
+		return m.Template == "welcome" && m.Variables["name"] == "Ada" && m.Content == ""
+	})).Return(stored, true, nil)
+	router := newTestRouter(t, app)
+
+	w := serve(router, newJSONRequest(http.MethodPost, "/messages",
+		`{"to":"+905551234567","template":"welcome","variables":{"name":"Ada"}}`))
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp api.MessageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "welcome", resp.Template)
+	assert.Equal(t, vars, resp.Variables)
+	app.AssertExpectations(t)
+}
+
+func TestCreateMessage_UnknownTemplate(t *testing.T) {
+	app := &MockApp{}
+	app.On("CreateMessage", mock.Anything, mock.Anything).Return(nil, false, message.ErrUnknownTemplate)
+	router := newTestRouter(t, app)
+
+	w := serve(router, newJSONRequest(http.MethodPost, "/messages", `{"to":"+905551234567","template":"missing"}`))
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, api.CodeValidationFailed, decodeError(t, w).Code)
+}
+
+func TestCreateMessage_Channel(t *testing.T) {
+	app := &MockApp{}
+	stored := &message.Message{ID: "42", To: "ada@example.com", Content: "hello", Tenant: message.DefaultTenant,
+		Channel: message.ChannelEmail}
+	app.On("CreateMessage", mock.Anything, mock.MatchedBy(func(m *message.Message) bool {
+		return m.Channel == message.ChannelEmail && m.To == "ada@example.com"
+	})).Return(stored, true, nil)
+	router := newTestRouter(t, app)
+
+	w := serve(router, newJSONRequest(http.MethodPost, "/messages", `{"to":"ada@example.com","content":"hello","channel":"email"}`))
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp api.MessageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "email", resp.Channel)
+	app.AssertExpectations(t)
+}
+
+func TestCreateMessage_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		key         string
+		wantMessage string
+		wantFields  []string
+	}{
+		{name: "missing fields", body: `{}`, wantMessage: "request validation failed", wantFields: []string{"to", "content"}},
+		{name: "invalid phone number", body: `{"to":"12345","content":"hi"}`, wantMessage: "request validation failed", wantFields: []string{"to"}},
+		{name: "wrong type", body: `{"to":5,"content":"hi"}`, wantMessage: "request validation failed", wantFields: []string{"to"}},
+		{name: "malformed JSON", body: `{"to":`, wantMessage: "request body is not valid JSON"},
+		{name: "malformed send time", body: `{"to":"+905551234567","content":"hi","send_at":"tomorrow"}`,
+			wantMessage: "request validation failed", wantFields: []string{"send_at"}},
+		{name: "phone number on email channel", body: `{"to":"+905551234567","content":"hi","channel":"email"}`,
+			wantMessage: "request validation failed", wantFields: []string{"to"}},
+		{name: "unknown channel", body: `{"to":"+905551234567","content":"hi","channel":"fax"}`,
+			wantMessage: "request validation failed", wantFields: []string{"channel"}},
+		{name: "content and template", body: `{"to":"+905551234567","content":"hi","template":"welcome"}`,
+			wantMessage: "request validation failed", wantFields: []string{"template"}},
+		{name: "priority out of range", body: `{"to":"+905551234567","content":"hi","priority":101}`,
+			wantMessage: "request validation failed", wantFields: []string{"priority"}},
+		{name: "idempotency key too long", body: `{"to":"+905551234567","content":"hi"}`, key: strings.Repeat("k", 256),
+			wantMessage: "request validation failed", wantFields: []string{"Idempotency-Key"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &MockApp{}
+			router := newTestRouter(t, app)
+			req := newJSONRequest(http.MethodPost, "/messages", tt.body)
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+
+			w := serve(router, req)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			resp := decodeError(t, w)
+			assert.Equal(t, api.CodeValidationFailed, resp.Code)
+			assert.Equal(t, tt.wantMessage, resp.Message)
+			fields := make([]string, len(resp.Details))
+			for i, d := range resp.Details {
+				fields[i] = d.Field
+			}
+			assert.ElementsMatch(t, tt.wantFields, fields)
+			app.AssertNotCalled(t, "CreateMessage", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	app := &MockApp{}
+	app.On("Stats", mock.Anything).Return(&message.Stats{
+		Sent:         10,
+		Unsent:       4,
+		Failed:       1,
+		SentLastHour: 2,
+		SentLastDay:  7,
+		AvgLatency:   1500 * time.Millisecond,
+		SentByHour:   []message.HourCount{{Hour: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), Count: 2}},
+	}, nil)
+	router := newTestRouter(t, app)
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.StatsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, api.StatsResponse{
+		Sent:              10,
+		Unsent:            4,
+		Failed:            1,
+		FailureRate:       1.0 / 11,
+		QueueDepth:        4,
+		SentLastHour:      2,
+		SentLastDay:       7,
+		AvgLatencySeconds: 1.5,
+		SentByHour:        []*api.HourCountResponse{{Hour: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), Count: 2}},
+	}, resp)
+}
+
+func TestGetStats_ReportsQuotaUsage(t *testing.T) {
+	app := &MockApp{}
+	resetsAt := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	app.On("Stats", mock.Anything).Return(&message.Stats{
+		Sent:  10,
+		Quota: &message.QuotaUsage{Tenant: "acme", Limit: 100, Used: 40, ResetsAt: resetsAt},
+	}, nil)
+	router := newTestRouter(t, app)
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.StatsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Quota)
+	assert.Equal(t, api.QuotaResponse{Tenant: "acme", Limit: 100, Used: 40, Remaining: 60, ResetsAt: resetsAt}, *resp.Quota)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/health"
+)
+
+// HealthChecker reports the status of the service's dependencies.
+type HealthChecker interface {
+	// Check runs every registered check and returns their combined outcome.
+	Check(ctx context.Context) *health.Report
+}
+
+// ComponentHealth reports the status of a single dependency.
+//
+// swagger:model ComponentHealth
+type ComponentHealth struct {
+	OK          bool       `json:"ok"`                     // whether the component is usable
+	LatencyMS   *float64   `json:"latency_ms,omitempty"`   // round trip time of the probe in milliseconds, for probed components
+	LastSuccess *time.Time `json:"last_success,omitempty"` // last successful use, for passively monitored components
+	Running     *bool      `json:"running,omitempty"`      // whether the component is running, for background workers
+}
+
+// HealthResponse reports the status of every dependency.
+//
+// swagger:model HealthResponse
+type HealthResponse struct {
+	Status     string                      `json:"status"`     // ok when every component is, unavailable otherwise
+	Components map[string]*ComponentHealth `json:"components"` // status of each component by name
+}
+
+// newComponentHealth converts a health.Status into a ComponentHealth.
+func newComponentHealth(s health.Status) *ComponentHealth {
+	ret := &ComponentHealth{
+		OK:      s.OK,
+		Running: s.Running,
+	}
+	if s.Latency > 0 {
+		ms := float64(s.Latency) / float64(time.Millisecond)
+		ret.LatencyMS = &ms
+	}
+	if !s.LastSuccess.IsZero() {
+		ret.LastSuccess = &s.LastSuccess
+	}
+	return ret
+}
+
+// getHealth reports the status of every dependency, answering 503 Service Unavailable if any is not usable.
+// The reasons of failures are logged rather than returned, as the endpoint is not authenticated.
+func (s *Server) getHealth(c *gin.Context) {
+	report := s.opts.health.Check(c)
+	resp := HealthResponse{Status: "ok", Components: make(map[string]*ComponentHealth, len(report.Components))}
+	for name, status := range report.Components {
+		resp.Components[name] = newComponentHealth(status)
+		if !status.OK {
+			s.log.Warn().Str("component", name).Str("reason", status.Error).Msg("Health check failed")
+		}
+	}
+	if !report.OK {
+		resp.Status = "unavailable"
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
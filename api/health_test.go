@@ -0,0 +1,54 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRunner bool
+
+func (r stubRunner) Running() bool { return bool(r) }
+
+func TestGetHealth(t *testing.T) {
+	registry := health.NewRegistry()
+	registry.Register("postgres", health.Ping(func(context.Context) error { return nil }))
+	registry.Register("scheduler", health.Worker(stubRunner(true)))
+	router := newTestRouter(t, &MockApp{}, api.WithHealthChecks(registry), api.WithRequestValidation())
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp api.HealthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "ok", resp.Status)
+	require.Contains(t, resp.Components, "postgres")
+	assert.True(t, resp.Components["postgres"].OK)
+	assert.NotNil(t, resp.Components["postgres"].LatencyMS)
+	require.Contains(t, resp.Components, "scheduler")
+	require.NotNil(t, resp.Components["scheduler"].Running)
+	assert.True(t, *resp.Components["scheduler"].Running)
+}
+
+func TestGetHealth_Unavailable(t *testing.T) {
+	registry := health.NewRegistry()
+	registry.Register("redis", health.Ping(func(context.Context) error { return errors.New("dial tcp 10.0.0.5:6379: refused") }))
+	router := newTestRouter(t, &MockApp{}, api.WithHealthChecks(registry))
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotContains(t, w.Body.String(), "10.0.0.5", "failure reasons are not exposed")
+	var resp api.HealthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "unavailable", resp.Status)
+	assert.False(t, resp.Components["redis"].OK)
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/application"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// importFileField is the multipart form field carrying the CSV file.
+const importFileField = "file"
+
+// maxImportRowErrors caps the number of rejected rows described in an ImportResponse.
+const maxImportRowErrors = 100
+
+// RowError describes why a single CSV row was rejected.
+//
+// swagger:model RowError
+type RowError struct {
+	Row     int    `json:"row"`     // 1-based line number in the uploaded file, header included
+	Message string `json:"message"` // reason the row was rejected
+}
+
+// ImportResponse summarizes the outcome of a CSV import.
+//
+// swagger:model ImportResponse
+type ImportResponse struct {
+	Accepted int         `json:"accepted"`         // number of rows stored as new messages
+	Rejected int         `json:"rejected"`         // number of rows that failed validation
+	Errors   []*RowError `json:"errors,omitempty"` // reasons for the first rejected rows
+}
+
+// importColumns holds the positions of the required columns in the CSV header.
+type importColumns struct {
+	to      int // index of the recipient column
+	content int // index of the content column
+}
+
+// parseImportHeader locates the recipient and content columns in the header row.
+// The recipient column may be named "to" or "recipient"; names are case-insensitive.
+func parseImportHeader(header []string) (importColumns, error) {
+	cols := importColumns{to: -1, content: -1}
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "to", "recipient":
+			cols.to = i
+		case "content":
+			cols.content = i
+		}
+	}
+	if cols.to < 0 || cols.content < 0 {
+		return cols, errors.New(`header must contain "to" (or "recipient") and "content" columns`)
+	}
+	return cols, nil
+}
+
+// parseImportRow converts a single CSV record into the input of a new SMS, validated when it is enqueued.
+func parseImportRow(cols importColumns, record []string) (*application.NewMessageInput, error) {
+	if cols.to >= len(record) || cols.content >= len(record) {
+		return nil, errors.New("missing columns")
+	}
+	return &application.NewMessageInput{
+		Channel: message.ChannelSMS,
+		To:      strings.TrimSpace(record[cols.to]),
+		Content: record[cols.content],
+	}, nil
+}
+
+// importRows holds the inputs read from a CSV file along with the line number of each.
+type importRows struct {
+	inputs []*application.NewMessageInput // inputs of the rows with all columns
+	lines  []int                          // 1-based line number of each input, header included
+}
+
+// readImport reads every row of the CSV into inputs, and a result describing the rows lacking columns.
+func readImport(r io.Reader) (*importRows, *ImportResponse, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // row width is checked per row so it can be reported as a rejection
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("file is empty")
+		}
+		return nil, nil, err
+	}
+	cols, err := parseImportHeader(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := &importRows{}
+	res := &ImportResponse{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		in, err := parseImportRow(cols, record)
+		if err != nil {
+			res.reject(line, err)
+			continue
+		}
+		rows.inputs = append(rows.inputs, in)
+		rows.lines = append(rows.lines, line)
+	}
+	return rows, res, nil
+}
+
+// reject counts the row at line as rejected for err.
+func (r *ImportResponse) reject(line int, err error) {
+	r.Rejected++
+	r.Errors = append(r.Errors, &RowError{Row: line, Message: err.Error()})
+}
+
+// importMessages accepts a CSV file with a header row containing "to" (or "recipient") and "content" columns.
+// Each row is validated separately by the application; valid rows are stored as unsent messages in a single
+// transaction and invalid rows are reported back, in file order.
+// The whole upload is subject to the request body limit (API_MAX_BODY_BYTES).
+func (s *Server) importMessages(c *gin.Context) {
+	fh, err := c.FormFile(importFileField)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			abortWithError(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
+		abortWithError(c, http.StatusBadRequest, CodeValidationFailed, "request validation failed",
+			&FieldError{Field: importFileField, Message: "is required"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.Error(errors.Wrap(err, "opening uploaded file"))
+		return
+	}
+	defer f.Close()
+
+	rows, res, err := readImport(f)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, CodeValidationFailed, "invalid CSV file",
+			&FieldError{Field: importFileField, Message: err.Error()})
+		return
+	}
+	enqueued, err := s.app.EnqueueMessages(c, rows.inputs)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for _, rejected := range enqueued.Rejected {
+		res.reject(rows.lines[rejected.Index], rejected.Err)
+	}
+	// only the first rejected rows are described
+	slices.SortStableFunc(res.Errors, func(a, b *RowError) int { return a.Row - b.Row })
+	res.Errors = res.Errors[:min(len(res.Errors), maxImportRowErrors)]
+	res.Accepted = len(enqueued.Queued)
+	c.JSON(http.StatusOK, res)
+}
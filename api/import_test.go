@@ -0,0 +1,125 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/application"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newImportRequest returns a multipart upload of csv in the file field.
+func newImportRequest(t *testing.T, csv string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "messages.csv")
+	require.NoError(t, err)
+	_, err = fw.Write([]byte(csv))
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+	req := httptest.NewRequest(http.MethodPost, "/messages/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestImportMessages(t *testing.T) {
+	csv := strings.Join([]string{
+		"Recipient,content",
+		"+905551111111,first",
+		"12345,bad number",
+		"+905552222222,",
+		"+905553333333",
+		" +905554444444 ,second",
+	}, "\n")
+	app := &MockApp{}
+	app.On("EnqueueMessages", mock.Anything, mock.MatchedBy(func(inputs []*application.NewMessageInput) bool {
+		return len(inputs) == 4 &&
+			inputs[0].To == "+905551111111" && inputs[0].Content == "first" &&
+			inputs[3].To == "+905554444444" && inputs[3].Content == "second"
+	})).Return(&application.EnqueueResult{
+		Queued: []*message.Message{{To: "+905551111111"}, {To: "+905554444444"}},
+		Rejected: []*application.InputError{
+			{Index: 1, Err: message.ErrInvalidPhoneNumber},
+			{Index: 2, Err: message.ErrBlankContent},
+		},
+	}, nil)
+	router := newTestRouter(t, app)
+
+	w := serve(router, newImportRequest(t, csv))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp api.ImportResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Accepted)
+	assert.Equal(t, 3, resp.Rejected)
+	require.Len(t, resp.Errors, 3)
+	// rows rejected by the application are reported in file order with the rows lacking columns
+	assert.Equal(t, 3, resp.Errors[0].Row)
+	assert.Equal(t, message.ErrInvalidPhoneNumber.Error(), resp.Errors[0].Message)
+	assert.Equal(t, 4, resp.Errors[1].Row)
+	assert.Equal(t, message.ErrBlankContent.Error(), resp.Errors[1].Message)
+	assert.Equal(t, 5, resp.Errors[2].Row)
+	assert.Equal(t, "missing columns", resp.Errors[2].Message)
+	app.AssertExpectations(t)
+}
+
+func TestImportMessages_InvalidFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		csv         string
+		wantMessage string
+	}{
+		{name: "empty file", csv: "", wantMessage: "file is empty"},
+		{name: "missing content column", csv: "to,body\n+905551111111,hi", wantMessage: `header must contain "to" (or "recipient") and "content" columns`},
+		{name: "malformed CSV", csv: "to,content\n+905551111111,\"unterminated", wantMessage: "extraneous or missing \" in quoted-field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &MockApp{}
+			router := newTestRouter(t, app)
+
+			w := serve(router, newImportRequest(t, tt.csv))
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			resp := decodeError(t, w)
+			assert.Equal(t, "invalid CSV file", resp.Message)
+			require.Len(t, resp.Details, 1)
+			assert.Equal(t, "file", resp.Details[0].Field)
+			assert.Contains(t, resp.Details[0].Message, tt.wantMessage)
+			app.AssertNotCalled(t, "EnqueueMessages", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestImportMessages_MissingFile(t *testing.T) {
+	router := newTestRouter(t, &MockApp{})
+
+	w := serve(router, newJSONRequest(http.MethodPost, "/messages/import", `{}`))
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	resp := decodeError(t, w)
+	require.Len(t, resp.Details, 1)
+	assert.Equal(t, api.FieldError{Field: "file", Message: "is required"}, *resp.Details[0])
+}
+
+func TestImportMessages_StoreError(t *testing.T) {
+	app := &MockApp{}
+	app.On("EnqueueMessages", mock.Anything, mock.Anything).Return(nil, errors.New("enqueueing messages: deadlock detected"))
+	router := newTestRouter(t, app)
+
+	w := serve(router, newImportRequest(t, "to,content\n+905551111111,first"))
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, api.CodeInternal, decodeError(t, w).Code)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/grustamli/insider-msg-sender/metrics"
 	"github.com/rs/zerolog"
 	"time"
 )
@@ -43,3 +44,19 @@ func Logger(logger zerolog.Logger) gin.HandlerFunc {
 		event.Msg("http_request")
 	}
 }
+
+// Metrics returns a Gin middleware that records the duration of each request in the metrics registry.
+// Requests that match no route are grouped under a single "unmatched" route label.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gin-gonic/gin"
+	docs "github.com/grustamli/insider-msg-sender/docs"
+	"github.com/pkg/errors"
+)
+
+// loadOpenAPI parses the OpenAPI 3 document and checks that it is valid.
+func loadOpenAPI(spec []byte) (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing openapi document")
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, errors.Wrap(err, "validating openapi document")
+	}
+	return doc, nil
+}
+
+// RequestValidator returns a Gin middleware that validates requests against the operations of the OpenAPI router.
+// Parameters and bodies that do not match the spec are rejected with a validation_failed ErrorResponse.
+// Requests for paths the spec does not describe pass through unchecked.
+// Install it after the authentication middleware of a route group, so unauthenticated requests are refused
+// before their payload is looked at.
+func RequestValidator(router routers.Router) gin.HandlerFunc {
+	opts := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+	return func(c *gin.Context) {
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+		err = openapi3filter.ValidateRequest(c.Request.Context(), &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    opts,
+		})
+		if err == nil {
+			c.Next()
+			return
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			abortWithError(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
+		abortWithError(c, http.StatusBadRequest, CodeValidationFailed, "request validation failed", describeSpecError(err)...)
+	}
+}
+
+// describeSpecError flattens request validation errors into per-field details.
+func describeSpecError(err error) []*FieldError {
+	var (
+		multi     openapi3.MultiError
+		schemaErr *openapi3.SchemaError
+		reqErr    *openapi3filter.RequestError
+	)
+	switch {
+	case errors.As(err, &multi):
+		var ret []*FieldError
+		for _, e := range multi {
+			ret = append(ret, describeSpecError(e)...)
+		}
+		return ret
+	case errors.As(err, &reqErr):
+		if reqErr.Parameter != nil {
+			return []*FieldError{{Field: reqErr.Parameter.Name, Message: specErrorMessage(reqErr)}}
+		}
+		if errors.As(reqErr.Err, &multi) || errors.As(reqErr.Err, &schemaErr) {
+			return describeSpecError(reqErr.Err)
+		}
+		return []*FieldError{{Field: "body", Message: specErrorMessage(reqErr)}}
+	case errors.As(err, &schemaErr):
+		field := strings.Join(schemaErr.JSONPointer(), ".")
+		if field == "" {
+			field = "body"
+		}
+		return []*FieldError{{Field: field, Message: schemaErr.Reason}}
+	default:
+		return []*FieldError{{Field: "request", Message: err.Error()}}
+	}
+}
+
+// specErrorMessage returns the most specific description of a request error.
+func specErrorMessage(reqErr *openapi3filter.RequestError) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(reqErr.Err, &schemaErr) {
+		return schemaErr.Reason
+	}
+	if reqErr.Reason != "" {
+		return reqErr.Reason
+	}
+	if reqErr.Err != nil {
+		return reqErr.Err.Error()
+	}
+	return "is invalid"
+}
+
+// initOpenAPI loads the embedded OpenAPI document served at /openapi.json and, when request validation is enabled,
+// builds the validator that route groups install after their authentication middleware.
+func (s *Server) initOpenAPI() error {
+	doc, err := loadOpenAPI(docs.OpenAPI)
+	if err != nil {
+		return err
+	}
+	if s.openAPI, err = doc.MarshalJSON(); err != nil {
+		return errors.Wrap(err, "encoding openapi document")
+	}
+	if !s.opts.validation {
+		return nil
+	}
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return errors.Wrap(err, "building openapi router")
+	}
+	s.validator = RequestValidator(router)
+	return nil
+}
+
+// validated returns the route group middleware: the given authentication handlers followed by the request
+// validator, when enabled.
+func (s *Server) validated(auth ...gin.HandlerFunc) []gin.HandlerFunc {
+	if s.validator == nil {
+		return auth
+	}
+	return append(auth, s.validator)
+}
+
+// openAPISpec returns the OpenAPI 3 document describing this API.
+func (s *Server) openAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", s.openAPI)
+}
@@ -0,0 +1,132 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/docs"
+	"github.com/grustamli/insider-msg-sender/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIPath converts a Gin route path to the OpenAPI path template.
+func openAPIPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData(docs.OpenAPI)
+	require.NoError(t, err)
+	router := newTestRouter(t, &MockApp{},
+		api.WithGraphQL(),
+		api.WithMetrics(),
+		api.WithPprof(),
+		api.WithCacheAdmin(stubCache{}),
+		api.WithAuditLog(&memoryAuditLog{}),
+		api.WithHealthChecks(health.NewRegistry()),
+		api.WithDeliveryReports("secret-token"),
+		api.WithRequestValidation(),
+	)
+
+	for _, r := range router.Routes() {
+		// operational endpoints that are not part of the API contract
+		if strings.HasPrefix(r.Path, "/swagger/") || strings.HasPrefix(r.Path, "/debug/") || r.Path == "/metrics" {
+			continue
+		}
+		item := doc.Paths.Find(openAPIPath(r.Path))
+		if assert.NotNil(t, item, "%s %s is not documented", r.Method, r.Path) {
+			assert.NotNil(t, item.GetOperation(r.Method), "%s %s is not documented", r.Method, r.Path)
+		}
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	router := newTestRouter(t, &MockApp{})
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+}
+
+func TestRequestValidation_RunsAfterAdminAuth(t *testing.T) {
+	router := newTestRouter(t, &MockApp{}, api.WithRequestValidation(), api.WithAdminAuth("admin", "secret"))
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPut, "/admin/loglevel", strings.NewReader(`{"level":"LOUD"}`))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	w := serve(router, newRequest())
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := newRequest()
+	req.SetBasicAuth("admin", "secret")
+	w = serve(router, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	resp := decodeError(t, w)
+	assert.Equal(t, api.CodeValidationFailed, resp.Code)
+	require.Len(t, resp.Details, 1)
+	assert.Equal(t, "level", resp.Details[0].Field)
+}
+
+func TestRequestValidation_RunsAfterTenantAuth(t *testing.T) {
+	router := newTestRouter(t, &MockApp{}, api.WithRequestValidation(), api.WithTenantAPIKey("k3y", "acme"))
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/messages", strings.NewReader(`{"to":"+905551234567","channel":"fax","priority":1000}`))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	w := serve(router, newRequest())
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := newRequest()
+	req.Header.Set("X-API-Key", "k3y")
+	w = serve(router, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	resp := decodeError(t, w)
+	assert.Equal(t, api.CodeValidationFailed, resp.Code)
+	fields := make([]string, len(resp.Details))
+	for i, d := range resp.Details {
+		fields[i] = d.Field
+	}
+	assert.ElementsMatch(t, []string{"channel", "priority"}, fields)
+}
+
+func TestOpenAPISpec_DocsAuth(t *testing.T) {
+	router := newTestRouter(t, &MockApp{}, api.WithAdminAuth("admin", "secret"), api.WithDocsAuth())
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = serve(router, httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	req.SetBasicAuth("admin", "secret")
+	w = serve(router, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestOpenAPISpec_Disabled(t *testing.T) {
+	router := newTestRouter(t, &MockApp{}, api.WithoutDocs())
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = serve(router, httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
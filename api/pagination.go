@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// defaultPageSize is the page size of paged listings when only a cursor is given.
+const defaultPageSize = 100
+
+// PageQuery holds the paging parameters of listing endpoints.
+// Listings are paged once either parameter is present.
+type PageQuery struct {
+	Cursor string `form:"cursor" json:"cursor"`                                  // next_cursor of the previous page
+	Limit  int    `form:"limit" json:"limit" binding:"omitempty,min=1,max=1000"` // page size
+}
+
+// paged reports whether q asks for a single page rather than the full listing.
+func (q *PageQuery) paged() bool {
+	return q.Cursor != "" || q.Limit > 0
+}
+
+// encodeCursor returns the opaque cursor continuing a listing after p.
+func encodeCursor(p *message.Position) string {
+	raw := strconv.FormatInt(p.SentAt.UnixNano(), 10) + ":" + p.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor created by encodeCursor.
+// It reports false when cursor was not created by encodeCursor, e.g. when a client built it itself.
+func decodeCursor(cursor string) (*message.Position, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, false
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found || id == "" {
+		return nil, false
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &message.Position{SentAt: time.Unix(0, n).UTC(), ID: id}, true
+}
+
+// nextPageLink returns the URL of the current request with its cursor replaced, fetching the page after cursor.
+func nextPageLink(c *gin.Context, cursor string, limit int) string {
+	query := c.Request.URL.Query()
+	query.Set("cursor", cursor)
+	query.Set("limit", strconv.Itoa(limit))
+	return (&url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}).String()
+}
+
+// pageFilter converts q into a filter selecting the page it asks for.
+// On an invalid cursor it writes an ErrorResponse and returns false; handlers should return immediately.
+func pageFilter(c *gin.Context, q *PageQuery) (message.Filter, bool) {
+	f := message.Filter{Limit: q.Limit}
+	if f.Limit == 0 {
+		f.Limit = defaultPageSize
+	}
+	if q.Cursor == "" {
+		return f, true
+	}
+	after, ok := decodeCursor(q.Cursor)
+	if !ok {
+		abortWithError(c, http.StatusBadRequest, CodeValidationFailed, "request validation failed", &FieldError{
+			Field:   "cursor",
+			Message: "is not a cursor returned by this API",
+		})
+		return f, false
+	}
+	f.After = after
+	return f, true
+}
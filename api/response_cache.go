@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// ResponseStore holds cached responses of read-only endpoints.
+type ResponseStore interface {
+	// Get returns the value stored under key, or nil if there is none or it expired.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// cachedResponse is a response as kept in a ResponseStore.
+type cachedResponse struct {
+	Status      int    `json:"status"`       // HTTP status code
+	ContentType string `json:"content_type"` // Content-Type header
+	ETag        string `json:"etag"`         // ETag header, if any
+	Body        []byte `json:"body"`         // response body
+}
+
+// bodyRecorder is a gin.ResponseWriter keeping a copy of the body written through it.
+// Successful responses are sent with its Cache-Control header.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	cacheControl string       // Cache-Control header of successful responses
+	body         bytes.Buffer // copy of the body
+}
+
+func (w *bodyRecorder) WriteHeader(code int) {
+	if code == http.StatusOK {
+		w.Header().Set("Cache-Control", w.cacheControl)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// cached returns the middleware serving successful responses of a read-only endpoint from the response cache
+// for the configured TTL, keyed by tenant and request URI. Successful responses tell clients they may cache them as long.
+// Cache failures are logged and the request is served as if there was no cache.
+// Without a configured response cache the middleware does nothing.
+func (s *Server) cached() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.opts.responseCache == nil {
+			c.Next()
+			return
+		}
+		tenant, _ := message.TenantFromContext(c)
+		key := fmt.Sprintf("%s:%s", tenant, c.Request.URL.RequestURI())
+		cacheControl := fmt.Sprintf("private, max-age=%d", int(s.opts.responseCacheTTL.Seconds()))
+
+		if resp, ok := s.cachedResponse(c, key); ok {
+			c.Header("X-Cache", "HIT")
+			c.Header("Cache-Control", cacheControl)
+			if resp.ETag != "" {
+				c.Header("ETag", resp.ETag)
+				if etagMatches(c.GetHeader("If-None-Match"), resp.ETag) {
+					c.AbortWithStatus(http.StatusNotModified)
+					return
+				}
+			}
+			c.Data(resp.Status, resp.ContentType, resp.Body)
+			c.Abort()
+			return
+		}
+
+		c.Header("X-Cache", "MISS")
+		w := &bodyRecorder{ResponseWriter: c.Writer, cacheControl: cacheControl}
+		c.Writer = w
+		c.Next()
+		// only complete successful bodies are worth caching; a 304 has none
+		if w.Status() != http.StatusOK || len(c.Errors) > 0 {
+			return
+		}
+		s.cacheResponse(c, key, &cachedResponse{
+			Status:      w.Status(),
+			ContentType: w.Header().Get("Content-Type"),
+			ETag:        w.Header().Get("ETag"),
+			Body:        w.body.Bytes(),
+		})
+	}
+}
+
+// cachedResponse looks key up in the response cache.
+func (s *Server) cachedResponse(c *gin.Context, key string) (*cachedResponse, bool) {
+	data, err := s.opts.responseCache.Get(c, key)
+	if err != nil {
+		s.log.Warn().Err(err).Str("key", key).Msg("Failed to read response cache")
+		return nil, false
+	}
+	if data == nil {
+		return nil, false
+	}
+	var resp cachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		s.log.Warn().Err(err).Str("key", key).Msg("Failed to decode cached response")
+		return nil, false
+	}
+	return &resp, true
+}
+
+// cacheResponse stores resp under key in the response cache for the configured TTL.
+func (s *Server) cacheResponse(c *gin.Context, key string, resp *cachedResponse) {
+	data, err := json.Marshal(resp)
+	if err == nil {
+		err = s.opts.responseCache.Set(c, key, data, s.opts.responseCacheTTL)
+	}
+	if err != nil {
+		s.log.Warn().Err(err).Str("key", key).Msg("Failed to cache response")
+	}
+}
+
+// MemoryResponseStore is an in-process ResponseStore. Expired entries are dropped when they are next read
+// or when the store is written to, so it does not grow beyond the entries of one TTL.
+type MemoryResponseStore struct {
+	mu      sync.Mutex             // protects entries
+	entries map[string]memoryEntry // stored values by key
+}
+
+// memoryEntry is a value of a MemoryResponseStore along with its expiry.
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+var _ ResponseStore = (*MemoryResponseStore)(nil)
+
+// NewMemoryResponseStore constructs an empty MemoryResponseStore.
+func NewMemoryResponseStore() *MemoryResponseStore {
+	return &MemoryResponseStore{entries: make(map[string]memoryEntry)}
+}
+
+// Get returns the unexpired value stored under key, or nil.
+func (m *MemoryResponseStore) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return nil, nil
+	}
+	return e.value, nil
+}
+
+// Set stores value under key for ttl, dropping expired entries.
+func (m *MemoryResponseStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for k, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+	m.entries[key] = memoryEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
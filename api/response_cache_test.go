@@ -0,0 +1,77 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	sent := []*message.SentMessage{{ID: "1", MessageID: "ext-1", SentAt: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}}
+	app := &MockApp{}
+	app.On("ListSentMessages", mock.Anything).Return(sent, nil).Twice()
+	router := newTestRouter(t, app, api.WithResponseCache(api.NewMemoryResponseStore(), time.Minute))
+
+	get := func(tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/messages", nil)
+		req.Header.Set("X-Tenant-ID", tenant)
+		return serve(router, req)
+	}
+
+	first := get("acme")
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+	assert.Equal(t, "private, max-age=60", first.Header().Get("Cache-Control"))
+
+	second := get("acme")
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+	assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+
+	// cached responses are not shared between tenants
+	assert.Equal(t, "MISS", get("globex").Header().Get("X-Cache"))
+
+	// a cached response still honors If-None-Match
+	req := httptest.NewRequest(http.MethodGet, "/messages", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, serve(router, req).Code)
+	app.AssertExpectations(t)
+}
+
+func TestResponseCache_SkipsFailures(t *testing.T) {
+	app := &MockApp{}
+	app.On("Stats", mock.Anything).Return(nil, assert.AnError).Once()
+	app.On("Stats", mock.Anything).Return(&message.Stats{Sent: 3}, nil).Once()
+	router := newTestRouter(t, app, api.WithResponseCache(api.NewMemoryResponseStore(), time.Minute))
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("Cache-Control"), "failures are not cacheable")
+
+	w = serve(router, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	app.AssertExpectations(t)
+}
+
+func TestMemoryResponseStore_Expires(t *testing.T) {
+	store := api.NewMemoryResponseStore()
+	ctx := t.Context()
+
+	require.NoError(t, store.Set(ctx, "k", []byte("v"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	data, err := store.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
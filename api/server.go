@@ -1,29 +1,199 @@
 // Package api defines the HTTP API server for the Insider Message Sender service.
 // It registers endpoints to control scheduling and inspect sent messages,
-// configures middleware, and serves Swagger documentation.
+// configures middleware, and serves the OpenAPI document with Swagger UI.
 package api
 
 import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/grustamli/insider-msg-sender/application"
+	"github.com/grustamli/insider-msg-sender/audit"
 	"github.com/grustamli/insider-msg-sender/daemon"
-	docs "github.com/grustamli/insider-msg-sender/docs"
+	"github.com/grustamli/insider-msg-sender/metrics"
 	"github.com/rs/zerolog"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-// @Title Insider Message Sender API
-// @version 1.0
-// @description API endpoints for the Insider Message Sender that periodically sends messages from DB
-// @contact.name Gadir Rustamli
-// @contact.email gadir.rustamli@outlook.com
-// @host localhost:8000
-// @BasePath /
-// @accept json
-// @produce json
-// @schemes http
-// @tag.name Scheduler
+// OptFunc configures optional behavior on Options.
+type OptFunc func(options *Options)
+
+// Options holds optional API features that are disabled unless explicitly enabled.
+type Options struct {
+	maxBodyBytes     int64             // maximum accepted request body size in bytes
+	graphQL          bool              // expose the /graphql query endpoint
+	metrics          bool              // record request metrics and expose the /metrics endpoint
+	pprof            bool              // expose net/http/pprof handlers under /debug/pprof
+	adminAccounts    gin.Accounts      // basic auth credentials for administrative endpoints
+	cacheAdmin       CacheAdmin        // sent messages cache exposed through admin endpoints
+	validation       bool              // validate requests against the OpenAPI document
+	tenantKeys       map[string]string // tenant owning each API key; when set, message endpoints require an X-API-Key header
+	auditLog         audit.Repository  // records control actions and backs the /audit endpoint
+	health           HealthChecker     // checks dependencies for the /health endpoint
+	readTimeout      time.Duration     // maximum duration for reading a request, headers included; 0 means none
+	writeTimeout     time.Duration     // maximum duration for writing a response; 0 means none
+	idleTimeout      time.Duration     // maximum time to wait for the next request on a keep-alive connection; 0 means none
+	maxHeaderSize    int               // maximum size of request headers in bytes; 0 means http.DefaultMaxHeaderBytes
+	docs             docsAccess        // who may read the OpenAPI document and Swagger UI
+	responseCache    ResponseStore     // caches responses of read-only endpoints; nil disables response caching
+	responseCacheTTL time.Duration     // how long cached responses are served
+	reportToken      string            // token providers authenticate delivery reports with; empty disables /delivery-reports
+}
+
+// docsAccess controls access to the API documentation endpoints.
+type docsAccess int
+
+const (
+	docsPublic   docsAccess = iota // served to everyone
+	docsAdmin                      // served behind admin authentication
+	docsDisabled                   // not served at all
+)
+
+// defaultOpts returns default Options with all optional features disabled.
+func defaultOpts() *Options {
+	return &Options{
+		maxBodyBytes: defaultMaxBodyBytes,
+	}
+}
+
+// WithMaxBodyBytes sets the maximum accepted request body size. Non-positive values keep the default.
+func WithMaxBodyBytes(n int64) OptFunc {
+	return func(options *Options) {
+		if n > 0 {
+			options.maxBodyBytes = n
+		}
+	}
+}
+
+// WithGraphQL enables the /graphql endpoint for querying messages.
+func WithGraphQL() OptFunc {
+	return func(options *Options) {
+		options.graphQL = true
+	}
+}
+
+// WithMetrics enables HTTP request instrumentation and the Prometheus /metrics endpoint.
+func WithMetrics() OptFunc {
+	return func(options *Options) {
+		options.metrics = true
+	}
+}
+
+// WithPprof enables the net/http/pprof profiling endpoints under /debug/pprof.
+// They are guarded by admin authentication, see WithAdminAuth.
+func WithPprof() OptFunc {
+	return func(options *Options) {
+		options.pprof = true
+	}
+}
+
+// WithAdminAuth sets the basic auth credentials required by administrative endpoints.
+func WithAdminAuth(username, password string) OptFunc {
+	return func(options *Options) {
+		if options.adminAccounts == nil {
+			options.adminAccounts = gin.Accounts{}
+		}
+		options.adminAccounts[username] = password
+	}
+}
+
+// WithRequestValidation enables validation of incoming requests against the OpenAPI 3 document,
+// so requests that do not match the documented contract are rejected before reaching handlers.
+func WithRequestValidation() OptFunc {
+	return func(options *Options) {
+		options.validation = true
+	}
+}
+
+// WithCacheAdmin enables the /admin/cache endpoints for flushing and rebuilding the sent messages cache.
+// They are guarded by admin authentication, see WithAdminAuth.
+func WithCacheAdmin(cache CacheAdmin) OptFunc {
+	return func(options *Options) {
+		options.cacheAdmin = cache
+	}
+}
+
+// WithTenantAPIKey registers an API key identifying tenant.
+// Once any key is registered, message endpoints require a known X-API-Key header and ignore X-Tenant-ID.
+func WithTenantAPIKey(key, tenant string) OptFunc {
+	return func(options *Options) {
+		if options.tenantKeys == nil {
+			options.tenantKeys = map[string]string{}
+		}
+		options.tenantKeys[key] = tenant
+	}
+}
+
+// WithAuditLog records control actions, such as starting the sender or changing the log level, in repo
+// and enables the /audit endpoint listing them. The endpoint is guarded by admin authentication, see WithAdminAuth.
+func WithAuditLog(repo audit.Repository) OptFunc {
+	return func(options *Options) {
+		options.auditLog = repo
+	}
+}
+
+// WithHealthChecks enables the /health endpoint reporting the status of the dependencies checker checks.
+func WithHealthChecks(checker HealthChecker) OptFunc {
+	return func(options *Options) {
+		options.health = checker
+	}
+}
+
+// WithTimeouts bounds the time to read a request, to write its response and to wait for the next request on a kept-alive
+// connection. Zero durations mean no limit, which is the default.
+func WithTimeouts(read, write, idle time.Duration) OptFunc {
+	return func(options *Options) {
+		options.readTimeout = read
+		options.writeTimeout = write
+		options.idleTimeout = idle
+	}
+}
+
+// WithMaxHeaderBytes sets the maximum accepted size of request headers. Non-positive values keep the net/http default.
+func WithMaxHeaderBytes(n int) OptFunc {
+	return func(options *Options) {
+		if n > 0 {
+			options.maxHeaderSize = n
+		}
+	}
+}
+
+// WithDocsAuth serves the OpenAPI document and Swagger UI behind admin authentication only, see WithAdminAuth.
+func WithDocsAuth() OptFunc {
+	return func(options *Options) {
+		options.docs = docsAdmin
+	}
+}
+
+// WithoutDocs disables the OpenAPI document and Swagger UI endpoints.
+func WithoutDocs() OptFunc {
+	return func(options *Options) {
+		options.docs = docsDisabled
+	}
+}
+
+// WithResponseCache caches successful responses of the read-only GET /messages and GET /stats endpoints in store
+// for ttl, per tenant and query, to absorb polling load. Responses carry a matching Cache-Control header.
+// Non-positive TTLs leave response caching disabled.
+func WithResponseCache(store ResponseStore, ttl time.Duration) OptFunc {
+	return func(options *Options) {
+		if ttl > 0 {
+			options.responseCache = store
+			options.responseCacheTTL = ttl
+		}
+	}
+}
+
+// WithDeliveryReports enables the POST /delivery-reports endpoint, through which providers report the final delivery
+// status of sent messages. Requests must carry token in the X-Report-Token header. An empty token is ignored.
+func WithDeliveryReports(token string) OptFunc {
+	return func(options *Options) {
+		options.reportToken = token
+	}
+}
 
 // Server orchestrates the Gin router, application logic, and scheduler daemon.
 // It exposes HTTP endpoints to start/stop message scheduling and to list sent messages.
@@ -33,51 +203,165 @@ type Server struct {
 	router    *gin.Engine     // Gin HTTP router
 	port      string          // address and port for the server to bind
 	log       zerolog.Logger  // structured logger for request-level logging
+	opts      *Options        // optional server features
+	openAPI   []byte          // OpenAPI document served at /openapi.json, in JSON
+	validator gin.HandlerFunc // validates requests against the OpenAPI document; nil when validation is disabled
 }
 
 // NewServer constructs a new API server with the provided Gin engine, listening port,
-// application logic, scheduler, and logger. It registers middleware, handlers, and Swagger docs.
-func NewServer(router *gin.Engine, port string, app application.App, scheduler daemon.Daemon, log zerolog.Logger) *Server {
+// application logic, scheduler, and logger. It registers middleware, handlers, and Swagger docs,
+// applying any provided functional options. It fails if the embedded OpenAPI document cannot be loaded.
+func NewServer(router *gin.Engine, port string, app application.App, scheduler daemon.Daemon, log zerolog.Logger, optFuncs ...OptFunc) (*Server, error) {
+	opts := defaultOpts()
+	// apply each configuration option
+	for _, f := range optFuncs {
+		f(opts)
+	}
 	s := &Server{
 		router:    router,
 		app:       app,
 		scheduler: scheduler,
 		port:      port,
 		log:       log,
+		opts:      opts,
+	}
+	if err := s.initOpenAPI(); err != nil {
+		return nil, err
 	}
 	s.initMiddleware()
 	s.initHandlers()
 	s.registerSwagger()
-	return s
+	return s, nil
 }
 
 // Run starts the HTTP server on the configured port.
 // It blocks until the server exits or an error occurs.
 func (s *Server) Run() error {
-	return s.router.Run(s.port)
+	return s.HTTPServer().ListenAndServe()
 }
 
-// initMiddleware installs global Gin middleware: request ID injection, logging, and panic recovery.
+// HTTPServer returns an http.Server serving the API on the configured port, with the configured timeouts and header limit.
+func (s *Server) HTTPServer() *http.Server {
+	return &http.Server{
+		Addr:              s.port,
+		Handler:           s.router,
+		ReadHeaderTimeout: s.opts.readTimeout,
+		ReadTimeout:       s.opts.readTimeout,
+		WriteTimeout:      s.opts.writeTimeout,
+		IdleTimeout:       s.opts.idleTimeout,
+		MaxHeaderBytes:    s.opts.maxHeaderSize,
+	}
+}
+
+// initMiddleware installs global Gin middleware: request ID injection, logging, error rendering,
+// panic recovery and request body size limiting. Request metrics are recorded as well when enabled.
+// Request validation is not global: route groups install it after their authentication middleware.
 func (s *Server) initMiddleware() {
+	useJSONFieldNames()
+	// handlers pass the gin.Context on as a context.Context; let it expose the tenant stored in the request context
+	s.router.ContextWithFallback = true
 	s.router.Use(
 		RequestID(),
 		Logger(s.log),
-		gin.Recovery(),
+		ErrorHandler(),
+		gin.CustomRecovery(recoverPanic),
+		BodyLimit(s.opts.maxBodyBytes),
 	)
+	if s.opts.metrics {
+		s.router.Use(Metrics())
+	}
 }
 
 // initHandlers registers HTTP routes for controlling and querying the scheduler.
 // - POST /start: invoke the scheduler to begin sending messages
 // - POST /stop: signal the scheduler to halt sending
 // - GET /messages: return a list of all sent messages
+// - POST /messages: queue a new message, deduplicated by the Idempotency-Key header
+// - GET /stats: aggregate message statistics
+// - GET /messages/export: stream all sent messages as CSV or NDJSON
+// - POST /messages/import: store new messages from an uploaded CSV file
+// - GET /messages/failed: messages whose delivery was given up, and POST /messages/:id/requeue to send one again
+// - GET /messages/:id: a single message in any state, and GET /messages/:id/attempts: its delivery attempts
+// - POST, GET /subscriptions and DELETE /subscriptions/:id: manage callbacks notified about message events
+// - POST /delivery-reports: record the final delivery status reported by a provider, when a report token is configured
+// - POST /graphql: query messages via GraphQL, when enabled
+// - GET /metrics: Prometheus metrics, when enabled
+// - GET /health: status of each dependency, when health checks are configured
+// - GET /debug/pprof/*: runtime profiling, when enabled and admin auth is configured
+// - /admin/*: administrative operations such as log level and cache management, guarded by admin auth
+// - GET /audit: recorded control actions, when an audit log is configured, guarded by admin auth
+//
+// Starting and stopping the sender, re-queueing messages and administrative changes are recorded in the audit log, when configured.
+// When response caching is enabled, GET /messages and GET /stats are served from the cache for its TTL.
+// Message and subscription endpoints, GraphQL included, only see and create data of the tenant resolved by tenantScope.
+// When enabled, requests to documented endpoints are validated against the OpenAPI document once authenticated.
 func (s *Server) initHandlers() {
-	s.router.POST("/start", s.startSender)
-	s.router.POST("/stop", s.stopSender)
-	s.router.GET("/messages", s.listSentMessages)
+	s.router.NoRoute(notFound)
+	scheduler := s.router.Group("", s.validated()...)
+	scheduler.POST("/start", s.audited(audit.ActionStart), s.startSender)
+	scheduler.POST("/stop", s.audited(audit.ActionStop), s.stopSender)
+	tenant := s.router.Group("", s.validated(s.tenantScope())...)
+	tenant.GET("/messages", s.cached(), s.listSentMessages)
+	tenant.POST("/messages", s.createMessage)
+	tenant.GET("/stats", s.cached(), s.getStats)
+	tenant.GET("/messages/export", s.exportSentMessages)
+	tenant.POST("/messages/import", s.importMessages)
+	tenant.GET("/messages/failed", s.listFailedMessages)
+	tenant.GET("/messages/:id", s.getMessage)
+	tenant.DELETE("/messages/:id", s.audited(audit.ActionMessageDelete), s.deleteMessage)
+	tenant.GET("/messages/:id/attempts", s.listMessageAttempts)
+	tenant.POST("/messages/:id/requeue", s.audited(audit.ActionMessageRequeue), s.requeueMessage)
+	tenant.POST("/messages/:id/cancel", s.audited(audit.ActionMessageCancel), s.cancelMessage)
+	s.registerSubscriptions(tenant)
+	s.registerDeliveryReports()
+	if s.opts.graphQL {
+		s.registerGraphQL(tenant)
+	}
+	if s.opts.metrics {
+		s.router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
+	if s.opts.health != nil {
+		s.router.GET("/health", s.getHealth)
+	}
+	if s.opts.pprof {
+		s.registerPprof()
+	}
+	s.registerAdmin()
+	s.registerAudit()
 }
 
-// registerSwagger configures the Gin route to serve Swagger UI at /swagger/*any.
+// adminAuth returns the middleware guarding administrative endpoints with basic auth.
+// Without configured admin credentials every request is rejected, so such endpoints are never left open.
+func (s *Server) adminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(s.opts.adminAccounts) == 0 {
+			abortWithError(c, http.StatusForbidden, CodeForbidden, "admin access is not configured")
+			return
+		}
+		user, password, ok := c.Request.BasicAuth()
+		expected, known := s.opts.adminAccounts[user]
+		if !ok || !known || subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="admin"`)
+			abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "invalid admin credentials")
+			return
+		}
+		c.Set(gin.AuthUserKey, user)
+		c.Next()
+	}
+}
+
+// registerSwagger serves the OpenAPI 3 document at /openapi.json and Swagger UI, rendering it, at /swagger/*any.
+// Depending on the options, both are public, guarded by admin auth or not served at all.
 func (s *Server) registerSwagger() {
-	docs.SwaggerInfo.BasePath = "/"
-	s.router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
+	var g gin.IRoutes
+	switch s.opts.docs {
+	case docsDisabled:
+		return
+	case docsAdmin:
+		g = s.router.Group("", s.adminAuth())
+	default:
+		g = s.router
+	}
+	g.GET("/openapi.json", s.openAPISpec)
+	g.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler, ginSwagger.URL("/openapi.json")))
 }
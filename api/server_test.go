@@ -0,0 +1,320 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/application"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockApp is a mock implementation of application.App
+type MockApp struct {
+	mock.Mock
+}
+
+func (m *MockApp) SendNext(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockApp) SendAllUnsent(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockApp) SendN(ctx context.Context, n int) error {
+	args := m.Called(ctx, n)
+	return args.Error(0)
+}
+
+func (m *MockApp) ListSentMessages(ctx context.Context) ([]*message.SentMessage, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*message.SentMessage), args.Error(1)
+}
+
+func (m *MockApp) ExportSentMessages(ctx context.Context, fn func(*message.SentMessage) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+func (m *MockApp) FindSentMessages(ctx context.Context, f message.Filter) ([]*message.SentMessage, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]*message.SentMessage), args.Error(1)
+}
+
+func (m *MockApp) FindUnsentMessages(ctx context.Context, f message.Filter) ([]*message.Message, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]*message.Message), args.Error(1)
+}
+
+func (m *MockApp) FindFailedMessages(ctx context.Context, f message.Filter) ([]*message.Message, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]*message.Message), args.Error(1)
+}
+
+func (m *MockApp) CancelMessage(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockApp) DeleteMessage(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockApp) RecordDeliveryReport(ctx context.Context, r *message.DeliveryReport) error {
+	args := m.Called(ctx, r)
+	return args.Error(0)
+}
+
+func (m *MockApp) ArchiveSentMessages(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockApp) RequeueMessage(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockApp) CreateMessage(ctx context.Context, msg *message.Message) (*message.Message, bool, error) {
+	args := m.Called(ctx, msg)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*message.Message), args.Bool(1), args.Error(2)
+}
+
+func (m *MockApp) Stats(ctx context.Context) (*message.Stats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*message.Stats), args.Error(1)
+}
+
+func (m *MockApp) EnqueueMessages(ctx context.Context, inputs []*application.NewMessageInput) (*application.EnqueueResult, error) {
+	args := m.Called(ctx, inputs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*application.EnqueueResult), args.Error(1)
+}
+
+func (m *MockApp) GetMessage(ctx context.Context, id string) (*message.Message, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*message.Message), args.Error(1)
+}
+
+func (m *MockApp) ListAttempts(ctx context.Context, id string) ([]*message.Attempt, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*message.Attempt), args.Error(1)
+}
+
+func (m *MockApp) CreateSubscription(ctx context.Context, sub *message.Subscription) (*message.Subscription, error) {
+	args := m.Called(ctx, sub)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*message.Subscription), args.Error(1)
+}
+
+func (m *MockApp) ListSubscriptions(ctx context.Context) ([]*message.Subscription, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*message.Subscription), args.Error(1)
+}
+
+func (m *MockApp) DeleteSubscription(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// MockDaemon is a mock implementation of daemon.Daemon
+type MockDaemon struct {
+	mock.Mock
+}
+
+func (m *MockDaemon) Start(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockDaemon) Stop(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+type stubCache struct{}
+
+func (stubCache) Flush(context.Context) error { return nil }
+
+func (stubCache) Rebuild(context.Context) (int, error) { return 0, nil }
+
+// newTestRouter builds a server for app on a fresh engine and returns the engine for serving test requests.
+func newTestRouter(t *testing.T, app *MockApp, opts ...api.OptFunc) *gin.Engine {
+	t.Helper()
+	return newTestRouterWithDaemon(t, app, &MockDaemon{}, opts...)
+}
+
+func newTestRouterWithDaemon(t *testing.T, app *MockApp, scheduler *MockDaemon, opts ...api.OptFunc) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	_, err := api.NewServer(router, ":0", app, scheduler, zerolog.Nop(), opts...)
+	require.NoError(t, err)
+	return router
+}
+
+func serve(router *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func newJSONRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) *api.ErrorResponse {
+	t.Helper()
+	var ret api.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ret))
+	return &ret
+}
+
+func TestServer_StartStop(t *testing.T) {
+	scheduler := &MockDaemon{}
+	scheduler.On("Start", mock.Anything).Return(nil)
+	scheduler.On("Stop", mock.Anything).Return(errors.New("daemon stuck"))
+	router := newTestRouterWithDaemon(t, &MockApp{}, scheduler)
+
+	w := serve(router, httptest.NewRequest(http.MethodPost, "/start", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	w = serve(router, httptest.NewRequest(http.MethodPost, "/stop", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, api.CodeInternal, decodeError(t, w).Code)
+	scheduler.AssertExpectations(t)
+}
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{
+			name:        "domain error",
+			err:         message.ErrIdempotencyKeyReused,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    api.CodeIdempotencyReuse,
+			wantMessage: message.ErrIdempotencyKeyReused.Error(),
+		},
+		{
+			name:        "wrapped domain error",
+			err:         errors.Join(errors.New("getting message"), message.ErrMessageNotFound),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    api.CodeNotFound,
+			wantMessage: message.ErrMessageNotFound.Error(),
+		},
+		{
+			name:        "provider failure",
+			err:         &message.SendError{Err: errors.New("provider down")},
+			wantStatus:  http.StatusBadGateway,
+			wantCode:    api.CodeProviderFailure,
+			wantMessage: "provider down",
+		},
+		{
+			name:        "internal error",
+			err:         errors.New("pq: connection refused"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    api.CodeInternal,
+			wantMessage: "internal server error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &MockApp{}
+			app.On("Stats", mock.Anything).Return(nil, tt.err)
+			router := newTestRouter(t, app)
+
+			w := serve(router, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			resp := decodeError(t, w)
+			assert.Equal(t, tt.wantCode, resp.Code)
+			assert.Contains(t, resp.Message, tt.wantMessage)
+			assert.Equal(t, w.Header().Get("X-Request-ID"), resp.RequestID)
+		})
+	}
+}
+
+func TestErrorHandler_UnknownRoute(t *testing.T) {
+	router := newTestRouter(t, &MockApp{})
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, api.CodeNotFound, decodeError(t, w).Code)
+}
+
+func TestErrorHandler_Panic(t *testing.T) {
+	app := &MockApp{}
+	app.On("Stats", mock.Anything).Run(func(mock.Arguments) { panic("boom") })
+	router := newTestRouter(t, app)
+	// keep the recovered stack trace out of the test output
+	errorWriter := gin.DefaultErrorWriter
+	gin.DefaultErrorWriter = io.Discard
+	t.Cleanup(func() { gin.DefaultErrorWriter = errorWriter })
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	resp := decodeError(t, w)
+	assert.Equal(t, api.CodeInternal, resp.Code)
+	assert.Equal(t, "internal server error", resp.Message)
+}
+
+func TestServer_HTTPServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	srv, err := api.NewServer(router, ":9090", &MockApp{}, &MockDaemon{}, zerolog.Nop(),
+		api.WithTimeouts(5*time.Second, 30*time.Second, time.Minute),
+		api.WithMaxHeaderBytes(8192),
+	)
+	require.NoError(t, err)
+
+	httpSrv := srv.HTTPServer()
+
+	assert.Equal(t, ":9090", httpSrv.Addr)
+	assert.Equal(t, router, httpSrv.Handler)
+	assert.Equal(t, 5*time.Second, httpSrv.ReadHeaderTimeout)
+	assert.Equal(t, 5*time.Second, httpSrv.ReadTimeout)
+	assert.Equal(t, 30*time.Second, httpSrv.WriteTimeout)
+	assert.Equal(t, time.Minute, httpSrv.IdleTimeout)
+	assert.Equal(t, 8192, httpSrv.MaxHeaderBytes)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// CreateSubscriptionRequest is the payload for registering a callback URL.
+//
+// swagger:model CreateSubscriptionRequest
+type CreateSubscriptionRequest struct {
+	URL    string   `json:"url" binding:"required,url"`                                             // callback URL events are POSTed to
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=message.sent message.failed"` // event types to receive
+	Secret string   `json:"secret" binding:"omitempty,min=16"`                                      // signing secret, generated when omitted
+}
+
+// SubscriptionResponse represents a registered subscription.
+//
+// swagger:model SubscriptionResponse
+type SubscriptionResponse struct {
+	ID        string    `json:"id"`               // internal subscription identifier
+	URL       string    `json:"url"`              // callback URL events are POSTed to
+	Events    []string  `json:"events"`           // event types the subscriber receives
+	Secret    string    `json:"secret,omitempty"` // signing secret, only returned when the subscription is created
+	Tenant    string    `json:"tenant"`           // tenant owning the subscription
+	CreatedAt time.Time `json:"created_at"`       // registration timestamp
+}
+
+// ListSubscriptionsResponse wraps a list of subscriptions.
+//
+// swagger:model ListSubscriptionsResponse
+type ListSubscriptionsResponse struct {
+	Items []*SubscriptionResponse `json:"items"` // registered subscriptions, oldest first
+}
+
+// newSubscriptionResponse converts a domain Subscription into a SubscriptionResponse, leaving out its secret.
+func newSubscriptionResponse(s *message.Subscription) *SubscriptionResponse {
+	return &SubscriptionResponse{
+		ID:        s.ID,
+		URL:       s.URL,
+		Events:    s.Events,
+		Tenant:    s.Tenant,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+// registerSubscriptions mounts the subscription management endpoints on the tenant scoped group.
+func (s *Server) registerSubscriptions(g *gin.RouterGroup) {
+	g.POST("/subscriptions", s.createSubscription)
+	g.GET("/subscriptions", s.listSubscriptions)
+	g.DELETE("/subscriptions/:id", s.deleteSubscription)
+}
+
+// createSubscription registers a callback URL that is POSTed an event whenever a message of the tenant is sent or fails.
+// Deliveries are signed: X-Signature carries "sha256=" and the hex HMAC-SHA256 of "<X-Signature-Timestamp>.<body>"
+// keyed with the secret, which is only returned in this response.
+func (s *Server) createSubscription(c *gin.Context) {
+	var req CreateSubscriptionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	sub, err := message.NewSubscription(req.URL, req.Secret, req.Events)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	created, err := s.app.CreateSubscription(c, sub)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	resp := newSubscriptionResponse(created)
+	resp.Secret = created.Secret
+	c.JSON(http.StatusCreated, resp)
+}
+
+// listSubscriptions returns the subscriptions of the tenant. Secrets are not included.
+func (s *Server) listSubscriptions(c *gin.Context) {
+	subs, err := s.app.ListSubscriptions(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	items := make([]*SubscriptionResponse, len(subs))
+	for i, sub := range subs {
+		items[i] = newSubscriptionResponse(sub)
+	}
+	c.JSON(http.StatusOK, ListSubscriptionsResponse{Items: items})
+}
+
+// deleteSubscription removes a subscription of the tenant; no further events are delivered to it.
+func (s *Server) deleteSubscription(c *gin.Context) {
+	if err := s.app.DeleteSubscription(c, c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,108 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSubscription(t *testing.T) {
+	createdAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	app := &MockApp{}
+	app.On("CreateSubscription", mock.Anything, &message.Subscription{
+		URL:    "https://example.com/hooks",
+		Events: []string{message.EventMessageFailed, message.EventMessageSent},
+	}).Return(&message.Subscription{
+		ID:        "3",
+		URL:       "https://example.com/hooks",
+		Secret:    "generated-secret",
+		Events:    []string{message.EventMessageFailed, message.EventMessageSent},
+		Tenant:    "acme",
+		CreatedAt: createdAt,
+	}, nil)
+	router := newTestRouter(t, app, api.WithRequestValidation())
+
+	req := newJSONRequest(http.MethodPost, "/subscriptions",
+		`{"url":"https://example.com/hooks","events":["message.sent","message.failed"]}`)
+	req.Header.Set("X-Tenant-ID", "acme")
+	w := serve(router, req)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp api.SubscriptionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, api.SubscriptionResponse{
+		ID:        "3",
+		URL:       "https://example.com/hooks",
+		Events:    []string{message.EventMessageFailed, message.EventMessageSent},
+		Secret:    "generated-secret",
+		Tenant:    "acme",
+		CreatedAt: createdAt,
+	}, resp)
+	app.AssertExpectations(t)
+}
+
+func TestCreateSubscription_Invalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantField string
+	}{
+		{name: "missing url", body: `{"events":["message.sent"]}`, wantField: "url"},
+		{name: "unknown event", body: `{"url":"https://example.com","events":["message.read"]}`, wantField: "events[0]"},
+		{name: "no events", body: `{"url":"https://example.com","events":[]}`, wantField: "events"},
+		{name: "short secret", body: `{"url":"https://example.com","events":["message.sent"],"secret":"short"}`, wantField: "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, &MockApp{})
+
+			w := serve(router, newJSONRequest(http.MethodPost, "/subscriptions", tt.body))
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			resp := decodeError(t, w)
+			assert.Equal(t, api.CodeValidationFailed, resp.Code)
+			require.NotEmpty(t, resp.Details)
+			assert.Equal(t, tt.wantField, resp.Details[0].Field)
+		})
+	}
+}
+
+func TestListSubscriptions_HidesSecrets(t *testing.T) {
+	app := &MockApp{}
+	app.On("ListSubscriptions", mock.Anything).Return([]*message.Subscription{
+		{ID: "1", URL: "https://example.com/hooks", Secret: "s3cret", Events: []string{message.EventMessageSent}, Tenant: "default"},
+	}, nil)
+	router := newTestRouter(t, app)
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/subscriptions", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "s3cret")
+	var resp api.ListSubscriptionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, "https://example.com/hooks", resp.Items[0].URL)
+}
+
+func TestDeleteSubscription(t *testing.T) {
+	app := &MockApp{}
+	app.On("DeleteSubscription", mock.Anything, "1").Return(nil)
+	app.On("DeleteSubscription", mock.Anything, "2").Return(message.ErrSubscriptionNotFound)
+	router := newTestRouter(t, app)
+
+	w := serve(router, httptest.NewRequest(http.MethodDelete, "/subscriptions/1", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serve(router, httptest.NewRequest(http.MethodDelete, "/subscriptions/2", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, api.CodeNotFound, decodeError(t, w).Code)
+	app.AssertExpectations(t)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+const (
+	tenantHeader = "X-Tenant-ID" // request header naming the tenant when no API keys are configured
+	apiKeyHeader = "X-API-Key"   // request header carrying a tenant API key
+)
+
+// tenantIDRegex matches the tenant identifiers accepted in the X-Tenant-ID header.
+// The length bound matches the size of the tenant_id column.
+var tenantIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// tenantScope returns the middleware resolving the tenant a request acts for and scoping the request context to it.
+// With tenant API keys configured the tenant is the one owning the X-API-Key header, and requests without a known key are rejected.
+// Otherwise the tenant is taken from the X-Tenant-ID header, falling back to message.DefaultTenant.
+func (s *Server) tenantScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant, ok := s.resolveTenant(c)
+		if !ok {
+			return
+		}
+		c.Request = c.Request.WithContext(message.WithTenant(c.Request.Context(), tenant))
+		c.Next()
+	}
+}
+
+// resolveTenant determines the tenant of the request.
+// On failure it writes an ErrorResponse and returns false.
+func (s *Server) resolveTenant(c *gin.Context) (string, bool) {
+	if len(s.opts.tenantKeys) > 0 {
+		key := c.GetHeader(apiKeyHeader)
+		for k, tenant := range s.opts.tenantKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+				return tenant, true
+			}
+		}
+		abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "invalid API key")
+		return "", false
+	}
+	tenant := c.GetHeader(tenantHeader)
+	if tenant == "" {
+		return message.DefaultTenant, true
+	}
+	if !tenantIDRegex.MatchString(tenant) {
+		abortWithError(c, http.StatusBadRequest, CodeValidationFailed, "request validation failed", &FieldError{
+			Field:   tenantHeader,
+			Message: "must be 1 to 64 letters, digits, dashes or underscores",
+		})
+		return "", false
+	}
+	return tenant, true
+}
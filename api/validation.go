@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+)
+
+// defaultMaxBodyBytes is the request body limit applied when none is configured.
+const defaultMaxBodyBytes int64 = 1 << 20 // 1 MiB
+
+// FieldError describes a single invalid field in a request payload.
+//
+// swagger:model FieldError
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name of the offending field
+	Message string `json:"message"` // human-readable description of the problem
+}
+
+// registerJSONNamesOnce guards the one-time validator setup in useJSONFieldNames.
+var registerJSONNamesOnce sync.Once
+
+// useJSONFieldNames makes the binding validator report fields by their JSON names instead of Go field names.
+func useJSONFieldNames() {
+	registerJSONNamesOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	})
+}
+
+// BodyLimit returns a Gin middleware that rejects request bodies larger than maxBytes.
+// Requests announcing a larger Content-Length are refused up front with 413;
+// bodies without a length are capped while being read.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			abortWithError(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", maxBytes))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// bindJSON decodes the request body into obj and validates it against its binding tags.
+// On failure it writes an ErrorResponse and returns false; handlers should return immediately.
+func bindJSON(c *gin.Context, obj any) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		abortWithError(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+			fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+		return false
+	}
+	msg, details := describeBindingError(err)
+	abortWithError(c, http.StatusBadRequest, CodeValidationFailed, msg, details...)
+	return false
+}
+
+// bindQuery decodes the query string into obj and validates it against its binding tags.
+// On failure it writes an ErrorResponse and returns false; handlers should return immediately.
+func bindQuery(c *gin.Context, obj any) bool {
+	if err := c.ShouldBindQuery(obj); err != nil {
+		msg, details := describeBindingError(err)
+		abortWithError(c, http.StatusBadRequest, CodeValidationFailed, msg, details...)
+		return false
+	}
+	return true
+}
+
+// describeBindingError converts binding and decoding errors into a message and per-field details.
+func describeBindingError(err error) (string, []*FieldError) {
+	var (
+		validationErrs validator.ValidationErrors
+		typeErr        *json.UnmarshalTypeError
+		syntaxErr      *json.SyntaxError
+	)
+	switch {
+	case errors.As(err, &validationErrs):
+		details := make([]*FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			details[i] = &FieldError{
+				Field:   jsonFieldName(fe),
+				Message: validationMessage(fe),
+			}
+		}
+		return "request validation failed", details
+	case errors.As(err, &typeErr):
+		return "request validation failed", []*FieldError{{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be of type %s", typeErr.Type),
+		}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "request body is not valid JSON", nil
+	default:
+		return err.Error(), nil
+	}
+}
+
+// jsonFieldName returns the field path as seen by clients, without the top-level struct name.
+func jsonFieldName(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		ns = ns[i+1:]
+	}
+	return ns
+}
+
+// validationMessage renders a short description for common validation tags.
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return fmt.Sprintf("is required without %s", strings.ToLower(fe.Param()))
+	case "excluded_with":
+		return fmt.Sprintf("must not be given together with %s", strings.ToLower(fe.Param()))
+	case "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "min":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	case "e164":
+		return "must be an E.164 phone number"
+	default:
+		return fmt.Sprintf("failed %q validation", fe.Tag())
+	}
+}
@@ -0,0 +1,42 @@
+package api_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyLimit(t *testing.T) {
+	body := `{"to":"+905551234567","content":"` + strings.Repeat("x", 64) + `"}`
+	tests := []struct {
+		name          string
+		contentLength int64
+	}{
+		{name: "announced length", contentLength: int64(len(body))},
+		// the length is unknown up front, so the body is cut off while being read
+		{name: "unknown length", contentLength: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &MockApp{}
+			router := newTestRouter(t, app, api.WithMaxBodyBytes(32))
+			req := newJSONRequest(http.MethodPost, "/messages", "")
+			req.Body = io.NopCloser(strings.NewReader(body))
+			req.ContentLength = tt.contentLength
+
+			w := serve(router, req)
+
+			require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			resp := decodeError(t, w)
+			assert.Equal(t, api.CodePayloadTooLarge, resp.Code)
+			assert.Equal(t, "request body exceeds 32 bytes", resp.Message)
+			app.AssertNotCalled(t, "CreateMessage", mock.Anything, mock.Anything)
+		})
+	}
+}
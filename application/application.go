@@ -4,50 +4,395 @@ package application
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/grustamli/insider-msg-sender/message"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 // App defines the operations available for sending messages.
 // - SendNext sends the next unsent message, if one exists.
 // - SendAllUnsent sends all pending unsent messages.
+// - SendN sends up to a given number of unsent messages.
 // - ListSentMessages returns all messages that have already been sent.
+// - ExportSentMessages streams every sent message to a callback.
+// - FindSentMessages returns the sent messages matching a filter.
+// - FindUnsentMessages returns the messages still waiting to be sent that match a filter.
+// - FindFailedMessages returns the messages whose delivery was given up that match a filter.
+// - RequeueMessage queues a message whose delivery was given up for sending again.
+// - CancelMessage withdraws a message that is still waiting to be sent.
+// - CreateMessage stores a single new message, honoring idempotency keys.
+// - Stats returns aggregate message figures.
+// - EnqueueMessages validates new messages and stores the valid ones, all or none.
+// - GetMessage returns a single message by its internal ID.
+// - ListAttempts returns the history of delivery attempts of a message.
+// - RecordDeliveryReport records the final delivery status a provider reported for a sent message.
+// - ArchiveSentMessages moves sent messages older than the retention period out of the way.
+// - CreateSubscription, ListSubscriptions and DeleteSubscription manage callbacks notified about message events.
 type App interface {
-	// SendNext retrieves and sends a single unsent message.
+	// SendNext retrieves and sends a single unsent message, waiting for the send rate limit.
 	// Returns nil if there are no unsent messages.
 	SendNext(ctx context.Context) error
 
-	// SendAllUnsent retrieves and sends all unsent messages.
-	// It pauses for one second between each send to avoid burst traffic.
+	// SendAllUnsent retrieves and sends all unsent messages, several at once when configured with WithWorkers,
+	// in batches when configured with WithBatchSize.
+	// Sends are throttled by the same rate limit as SendNext to avoid burst traffic.
 	SendAllUnsent(ctx context.Context) error
 
+	// SendN retrieves up to n unsent messages in one go and sends them like SendAllUnsent.
+	// Returns nil if there are no unsent messages.
+	SendN(ctx context.Context, n int) error
+
 	// ListSentMessages returns all sent messages recorded in the system.
 	ListSentMessages(ctx context.Context) ([]*message.SentMessage, error)
+
+	// ExportSentMessages calls fn for every sent message, stopping at the first error fn returns.
+	// Unlike ListSentMessages it does not hold all messages in memory.
+	ExportSentMessages(ctx context.Context, fn func(*message.SentMessage) error) error
+
+	// FindSentMessages returns the sent messages matching f.
+	FindSentMessages(ctx context.Context, f message.Filter) ([]*message.SentMessage, error)
+
+	// FindUnsentMessages returns the messages still queued for sending that match f.
+	FindUnsentMessages(ctx context.Context, f message.Filter) ([]*message.Message, error)
+
+	// FindFailedMessages returns the messages whose delivery was given up that match f, most recently failed first.
+	FindFailedMessages(ctx context.Context, f message.Filter) ([]*message.Message, error)
+
+	// RequeueMessage queues the failed message with the given ID for sending again, with its attempts reset.
+	// Returns message.ErrMessageNotFound if no such message exists
+	// and message.ErrMessageNotFailed if its delivery was not given up.
+	RequeueMessage(ctx context.Context, id string) error
+
+	// CancelMessage withdraws the pending message with the given ID, so it is never sent.
+	// Returns message.ErrMessageNotFound if no such message exists, message.ErrMessageNotPending if it was sent,
+	// given up or canceled already, and message.ErrMessageBeingSent if an instance is delivering it right now.
+	CancelMessage(ctx context.Context, id string) error
+
+	// DeleteMessage deletes the message with the given ID that is no longer pending, hiding it from listings,
+	// lookups and stats. Returns message.ErrMessageNotFound if no such message exists and message.ErrMessagePending
+	// if it still waits for delivery.
+	DeleteMessage(ctx context.Context, id string) error
+
+	// CreateMessage stores a single new unsent message and returns it with its ID.
+	// When msg carries an idempotency key that was used before, the original message is returned and created is false.
+	// Returns message.ErrIdempotencyKeyReused if the key was used for a different recipient, content, priority or schedule.
+	CreateMessage(ctx context.Context, msg *message.Message) (stored *message.Message, created bool, err error)
+
+	// Stats returns aggregate figures about sent and unsent messages.
+	Stats(ctx context.Context) (*message.Stats, error)
+
+	// EnqueueMessages validates inputs and stores the valid ones as new unsent messages, reporting the invalid ones
+	// in the result. If storing fails, none of them are stored. Bulk uploads and imports share it.
+	EnqueueMessages(ctx context.Context, inputs []*NewMessageInput) (*EnqueueResult, error)
+
+	// GetMessage returns the message with the given internal ID.
+	// Returns message.ErrMessageNotFound if no such message exists.
+	GetMessage(ctx context.Context, id string) (*message.Message, error)
+
+	// ListAttempts returns the attempts to deliver the message with the given internal ID, oldest first.
+	// Without WithAttemptLog no history is kept and it returns none.
+	// Returns message.ErrMessageNotFound if no such message exists.
+	ListAttempts(ctx context.Context, id string) ([]*message.Attempt, error)
+
+	// RecordDeliveryReport records the final delivery status r reports on the sent message with its provider message ID.
+	// Returns message.ErrMessageNotFound if no sent message has that provider message ID.
+	RecordDeliveryReport(ctx context.Context, r *message.DeliveryReport) error
+
+	// ArchiveSentMessages archives or deletes the messages sent longer ago than the retention configured with
+	// WithRetention and returns how many. Returns ErrRetentionNotConfigured without a retention.
+	ArchiveSentMessages(ctx context.Context) (int64, error)
+
+	// CreateSubscription registers a callback URL notified about the events sub asks for.
+	// A random signing secret is generated when sub has none.
+	CreateSubscription(ctx context.Context, sub *message.Subscription) (*message.Subscription, error)
+
+	// ListSubscriptions returns the registered subscriptions.
+	ListSubscriptions(ctx context.Context) ([]*message.Subscription, error)
+
+	// DeleteSubscription removes the subscription with the given ID.
+	// Returns message.ErrSubscriptionNotFound if no such subscription exists.
+	DeleteSubscription(ctx context.Context, id string) error
+}
+
+// ErrSubscriptionsNotConfigured is returned by the subscription operations of an Application created without WithSubscriptions.
+var ErrSubscriptionsNotConfigured = errors.New("subscriptions are not configured")
+
+// OptFunc configures optional behavior on Options.
+type OptFunc func(options *Options)
+
+// Options holds optional Application collaborators and settings.
+type Options struct {
+	subscriptions message.SubscriptionRepository     // storage of event subscriptions
+	notifier      message.Notifier                   // delivers message events to subscriptions
+	retry         RetryPolicy                        // when failed deliveries are tried again
+	workers       int                                // number of batches SendAllUnsent sends concurrently
+	batchSize     int                                // number of messages SendAllUnsent hands to the sender at once
+	limiter       *rate.Limiter                      // throttles sends of SendNext and SendAllUnsent alike
+	claimLease    time.Duration                      // how long a message is reserved for the instance delivering it
+	claimOnRead   bool                               // SendN claims messages as it reads them
+	dedup         message.Deduplicator               // detects identical messages sent to the same recipient shortly before
+	dedupWindow   time.Duration                      // how long identical messages are suppressed after one is sent
+	sendWindow    SendWindow                         // time of day messages may be sent in
+	onHold        func(held int64)                   // told how many messages wait while sending is outside sendWindow
+	templates     *Templates                         // templates the content of templated messages is rendered from
+	senders       map[message.Channel]message.Sender // senders of further channels, by channel
+	hooks         *message.Hooks                     // lifecycle hooks run as messages are queued, sent and failed
+	quotaCounter  message.QuotaCounter               // counts messages sent per tenant and day against quota
+	quota         DailyQuota                         // how many messages each tenant may send per day
+	validators    []message.Validator                // checks messages pass right before they are sent
+	retention     *Retention                         // when sent messages are archived; nil keeps them
+	throttle      AdaptiveThrottle                   // when sending slows down below the rate limit as the provider struggles
+	onThrottle    func(perSecond float64)            // told the send rate whenever the throttle adjusts it
+	attempts      message.AttemptRepository          // history of delivery attempts; nil keeps none
+}
+
+// defaultSendRate is the number of messages sent per second unless configured otherwise with WithRateLimit.
+const defaultSendRate = 1
+
+// defaultClaimLease is how long a message is reserved for delivery unless configured otherwise with WithClaimLease.
+const defaultClaimLease = time.Minute
+
+// defaultOpts returns default Options retrying failed deliveries with DefaultRetryPolicy,
+// sending up to defaultSendRate messages per second and claiming messages for defaultClaimLease.
+func defaultOpts() *Options {
+	return &Options{
+		retry:      DefaultRetryPolicy,
+		workers:    1,
+		batchSize:  1,
+		limiter:    rate.NewLimiter(defaultSendRate, 1),
+		claimLease: defaultClaimLease,
+		senders:    make(map[message.Channel]message.Sender),
+		hooks:      &message.Hooks{},
+	}
+}
+
+// WithRetryPolicy sets when failed deliveries are tried again.
+// A policy allowing less than one attempt is ignored.
+func WithRetryPolicy(policy RetryPolicy) OptFunc {
+	return func(options *Options) {
+		if policy.MaxAttempts >= 1 {
+			options.retry = policy
+		}
+	}
+}
+
+// WithSubscriptions enables subscription management backed by subscriptions,
+// and the delivery of message events to them through notifier.
+func WithSubscriptions(subscriptions message.SubscriptionRepository, notifier message.Notifier) OptFunc {
+	return func(options *Options) {
+		options.subscriptions = subscriptions
+		options.notifier = notifier
+	}
+}
+
+// WithWorkers makes SendAllUnsent send up to n messages, or batches of messages, concurrently. Workers share the send rate limit,
+// so more workers only speed sending up while the provider is slower than the rate limit. Values below one are ignored.
+func WithWorkers(n int) OptFunc {
+	return func(options *Options) {
+		if n >= 1 {
+			options.workers = n
+		}
+	}
+}
+
+// WithBatchSize makes SendAllUnsent hand up to n messages at once to the sender's SendBatch, for providers that
+// accept bulk payloads. Each worker sends one batch at a time, and every message of a batch still counts against
+// the send rate limit. Values below one are ignored; with the default of one, messages are sent with Send.
+func WithBatchSize(n int) OptFunc {
+	return func(options *Options) {
+		if n >= 1 {
+			options.batchSize = n
+		}
+	}
+}
+
+// WithRateLimit limits sending to perSecond messages per second on average, allowing bursts of up to burst messages
+// after idle periods. The limit is shared by SendNext and SendAllUnsent and all workers.
+// A non-positive perSecond removes the limit; burst is raised to at least one.
+func WithRateLimit(perSecond float64, burst int) OptFunc {
+	return func(options *Options) {
+		limit := rate.Limit(perSecond)
+		if perSecond <= 0 {
+			limit = rate.Inf
+		}
+		options.limiter = rate.NewLimiter(limit, max(burst, 1))
+	}
+}
+
+// WithClaimLease sets how long a message stays reserved for the instance delivering it. Other instances skip the
+// message meanwhile and may only deliver it once the lease expired, e.g. after the instance crashed mid-send,
+// so the lease must comfortably exceed the time a send takes. Non-positive values are ignored.
+func WithClaimLease(lease time.Duration) OptFunc {
+	return func(options *Options) {
+		if lease > 0 {
+			options.claimLease = lease
+		}
+	}
+}
+
+// WithClaimOnRead, if enabled, makes SendN claim the messages it reads with the same repository call, see
+// message.Repository.ClaimUnsent, instead of claiming each message right before sending it, so instances sharing the
+// repository never read the same messages. Messages read but not sent, e.g. as the send window closed, stay claimed
+// until the claim lease expires, so the lease must cover sending all n messages at the send rate.
+func WithClaimOnRead(enabled bool) OptFunc {
+	return func(options *Options) {
+		options.claimOnRead = enabled
+	}
+}
+
+// WithDeduplication suppresses messages carrying the same content to the same recipient as a message sent within
+// window, as detected by dedup. Suppressed messages are given up with message.ErrDuplicateMessage instead of being
+// sent, and can be requeued. A non-positive window or nil dedup is ignored.
+func WithDeduplication(dedup message.Deduplicator, window time.Duration) OptFunc {
+	return func(options *Options) {
+		if dedup != nil && window > 0 {
+			options.dedup = dedup
+			options.dedupWindow = window
+		}
+	}
+}
+
+// WithSendWindow restricts sending to window, e.g. to keep messages from arriving at night. Outside the window
+// SendNext, SendN and SendAllUnsent send nothing and, if onHold is not nil, pass it the number of messages waiting
+// to be sent; a backlog being sent when the window closes is left for the window to open again.
+func WithSendWindow(window SendWindow, onHold func(held int64)) OptFunc {
+	return func(options *Options) {
+		options.sendWindow = window
+		options.onHold = onHold
+	}
+}
+
+// WithTemplates renders the content of templated messages from templates when they are sent.
+// Without templates, templated messages can neither be created nor sent.
+func WithTemplates(templates *Templates) OptFunc {
+	return func(options *Options) {
+		options.templates = templates
+	}
+}
+
+// WithSender delivers the messages of channel ch through sender, e.g. emails through an email provider.
+// The sender given to NewApplication delivers SMS unless this option registers another sender for message.ChannelSMS.
+// Messages on channels without a sender can neither be created nor sent.
+func WithSender(ch message.Channel, sender message.Sender) OptFunc {
+	return func(options *Options) {
+		options.senders[ch] = sender
+	}
+}
+
+// WithHooks runs the hooks registered on hooks as messages move through their send lifecycle, so components such as
+// metrics can follow it without the Application knowing them. A nil hooks is ignored.
+// The notifier of WithSubscriptions is registered on hooks as well, so hooks should be given to one Application only.
+func WithHooks(hooks *message.Hooks) OptFunc {
+	return func(options *Options) {
+		if hooks != nil {
+			options.hooks = hooks
+		}
+	}
+}
+
+// WithDailyQuota limits how many messages each tenant sends per day to quota, counting sent messages with counter.
+// Once the quota of a tenant is used up, its remaining messages are deferred to the start of the next day without
+// counting an attempt, and messages whose delivery failed do not count. A nil counter or unlimited quota is ignored.
+func WithDailyQuota(counter message.QuotaCounter, quota DailyQuota) OptFunc {
+	return func(options *Options) {
+		if counter != nil && quota.limited() {
+			options.quotaCounter = counter
+			options.quota = quota
+		}
+	}
+}
+
+// WithValidators runs validators, in order, on every message right before it is sent, once its content is rendered.
+// A message failing a validator is rejected with the validator's error as the reason instead of being sent, and its
+// delivery is given up. Validators accumulate over repeated uses of this option; nil validators are ignored.
+func WithValidators(validators ...message.Validator) OptFunc {
+	return func(options *Options) {
+		for _, v := range validators {
+			if v != nil {
+				options.validators = append(options.validators, v)
+			}
+		}
+	}
+}
+
+// WithAdaptiveThrottle slows sending down below the rate limit of WithRateLimit while the provider responds slowly or
+// fails, and ramps it back up as it recovers, as configured by throttle. Latency and errors are measured across all
+// channels. If onAdjust is not nil, it is passed the new send rate whenever it changes.
+// A throttle bounding neither latency nor errors is ignored, as is any throttle while the rate is unlimited.
+func WithAdaptiveThrottle(throttle AdaptiveThrottle, onAdjust func(perSecond float64)) OptFunc {
+	return func(options *Options) {
+		options.throttle = throttle
+		options.onThrottle = onAdjust
+	}
+}
+
+// WithAttemptLog records every attempt to deliver a message in attempts, successful or not, with its duration and
+// the status code and error of failed ones.
+func WithAttemptLog(attempts message.AttemptRepository) OptFunc {
+	return func(options *Options) {
+		options.attempts = attempts
+	}
 }
 
 // Application is the default implementation of the App interface.
 // It uses a message.Repository to manage message state and a message.Sender to deliver messages.
 type Application struct {
-	messages message.Repository // repository for message persistence
-	sender   message.Sender     // sender for delivering messages
+	messages  message.Repository                 // repository for message persistence
+	senders   map[message.Channel]message.Sender // senders delivering messages, by channel
+	opts      *Options                           // optional collaborators
+	unsaved   *outbox                            // delivered messages whose sent state is not stored yet
+	backoff   *backoff[message.Channel]          // channels whose provider asked to pause sending
+	exhausted *backoff[string]                   // tenants that used up their daily quota, until it resets
+	throttle  *throttle                          // adjusts the send rate to the provider's health; nil keeps it fixed
 }
 
 var _ App = (*Application)(nil) // assert Application implements App
 
-// NewApplication constructs a new Application with the provided repository and sender.
-func NewApplication(messages message.Repository, sender message.Sender) *Application {
+// NewApplication constructs a new Application with the provided repository and sender of SMS,
+// applying any provided functional options.
+func NewApplication(messages message.Repository, sender message.Sender, optFuncs ...OptFunc) *Application {
+	opts := defaultOpts()
+	// apply each configuration option
+	for _, f := range optFuncs {
+		f(opts)
+	}
+	senders := map[message.Channel]message.Sender{message.ChannelSMS: sender}
+	maps.Copy(senders, opts.senders)
+	if opts.notifier != nil {
+		notifyOn(opts.hooks, opts.notifier)
+	}
 	return &Application{
-		messages: messages,
-		sender:   sender,
+		messages:  messages,
+		senders:   senders,
+		opts:      opts,
+		unsaved:   newOutbox(),
+		backoff:   newBackoff[message.Channel](),
+		exhausted: newBackoff[string](),
+		throttle:  newThrottle(opts.throttle, opts.limiter, opts.onThrottle),
 	}
 }
 
 // SendNext retrieves the next unsent message from the repository and sends it.
-// If no unsent message is found, it returns without error.
+// Delivered messages whose sent state could not be stored before are stored first; nothing is sent until they are.
+// Outside the send window, or if no unsent message is found, it returns without error.
 // Any errors fetching or sending are wrapped and returned.
-func (a *Application) SendNext(ctx context.Context) error {
+func (a *Application) SendNext(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "SendNext")
+	defer func() { endSpan(span, err) }()
+	if err := a.flushOutbox(ctx); err != nil {
+		return err
+	}
+	if held, err := a.hold(ctx); held || err != nil {
+		return err
+	}
 	msg, err := a.messages.GetNextUnsent(ctx)
 	if err != nil {
 		return errors.Wrap(err, "getting next unsent message")
@@ -59,36 +404,439 @@ func (a *Application) SendNext(ctx context.Context) error {
 	return a.sendMessage(ctx, msg)
 }
 
-// SendAllUnsent retrieves all unsent messages and sends them one by one.
-// It sleeps for one second between sends to throttle the rate.
-// Errors during retrieval or send abort the process immediately.
-func (a *Application) SendAllUnsent(ctx context.Context) error {
+// SendAllUnsent retrieves all unsent messages and sends them with the configured number of workers,
+// one by one unless configured otherwise, in batches of the configured size, at the configured rate.
+// Delivered messages whose sent state could not be stored before are stored first; nothing is sent outside the send window.
+// Errors during retrieval abort the process immediately; after a failed send, or once ctx is done,
+// no further messages are sent.
+func (a *Application) SendAllUnsent(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "SendAllUnsent")
+	defer func() { endSpan(span, err) }()
+	if err := a.flushOutbox(ctx); err != nil {
+		return err
+	}
+	if held, err := a.hold(ctx); held || err != nil {
+		return err
+	}
 	msgs, err := a.messages.GetAllUnsent(ctx)
 	if err != nil {
 		return errors.Wrap(err, "getting all unsent messages")
 	}
+	return a.sendAll(ctx, msgs)
+}
+
+// SendN retrieves up to n unsent messages with a single repository call and sends them like SendAllUnsent.
+// Delivered messages whose sent state could not be stored before are stored first.
+// A non-positive n sends nothing, as does calling it outside the send window.
+func (a *Application) SendN(ctx context.Context, n int) (err error) {
+	ctx, span := tracer.Start(ctx, "SendN", trace.WithAttributes(attribute.Int("send.limit", n)))
+	defer func() { endSpan(span, err) }()
+	if err := a.flushOutbox(ctx); err != nil {
+		return err
+	}
+	if n <= 0 {
+		return nil
+	}
+	if held, err := a.hold(ctx); held || err != nil {
+		return err
+	}
+	if a.opts.claimOnRead {
+		token, err := newClaimToken()
+		if err != nil {
+			return err
+		}
+		msgs, err := a.messages.ClaimUnsent(ctx, n, token, time.Now().Add(a.opts.claimLease))
+		if err != nil {
+			return errors.Wrap(err, "claiming unsent messages")
+		}
+		return a.sendAll(ctx, msgs)
+	}
+	msgs, err := a.messages.GetUnsent(ctx, n)
+	if err != nil {
+		return errors.Wrap(err, "getting unsent messages")
+	}
+	return a.sendAll(ctx, msgs)
+}
+
+// hold reports whether sending is deferred because it is outside the send window,
+// passing the number of messages waiting to be sent to the hold handler, if any.
+func (a *Application) hold(ctx context.Context) (bool, error) {
+	if a.opts.sendWindow.Contains(time.Now()) {
+		return false, nil
+	}
+	if a.opts.onHold == nil {
+		return true, nil
+	}
+	held, err := a.messages.CountUnsent(ctx)
+	if err != nil {
+		return true, errors.Wrap(err, "counting held messages")
+	}
+	a.opts.onHold(held)
+	return true, nil
+}
+
+// sendMessage executes the delivery of a single message once the rate limit allows, marks it as sent, and persists the update.
+// Messages past their expiry are marked expired instead of being sent.
+// The message is claimed first; messages another instance is already delivering are skipped without error.
+// A failed delivery is recorded on the message and retried later according to the retry policy; a provider refusing
+// messages for the rate of sending pauses the channel instead, as long as the provider asks.
+// Subscribers are notified once the sent state is stored, or once delivery is given up.
+// Returns any errors encountered during send or save operations.
+func (a *Application) sendMessage(ctx context.Context, msg *message.Message) (err error) {
+	ctx, span := tracer.Start(ctx, "send message", trace.WithAttributes(messageAttributes(msg)...))
+	defer func() { endSpan(span, err) }()
+	ready, err := a.prepare(ctx, msg)
+	if err != nil || !ready {
+		return err
+	}
+	start := time.Now()
+	res, err := a.senders[message.ChannelOf(msg)].Send(ctx, msg)
+	elapsed := time.Since(start)
+	failures := 0
+	if err != nil {
+		failures = 1
+	}
+	a.throttle.observe(elapsed, 1, failures)
+	a.recordAttempts(ctx, message.NewAttempt(msg, start, elapsed, err))
+	return a.complete(ctx, msg, res, err)
+}
+
+// sendBatch delivers msgs like sendMessage, but hands them to the sender of each channel in a single SendBatch call
+// and stores the sent state of the delivered ones with a single repository call.
+// The outcome of every message is recorded before the first error, if any, is returned.
+func (a *Application) sendBatch(ctx context.Context, msgs []*message.Message) (err error) {
+	if len(msgs) == 1 {
+		return a.sendMessage(ctx, msgs[0])
+	}
+	ctx, span := tracer.Start(ctx, "send batch", trace.WithAttributes(attribute.Int("batch.size", len(msgs))))
+	defer func() { endSpan(span, err) }()
+	batch := make([]*message.Message, 0, len(msgs))
 	for _, msg := range msgs {
-		if err := a.sendMessage(ctx, msg); err != nil {
+		ready, err := a.prepare(ctx, msg)
+		if err != nil {
+			// claims taken so far expire with their lease
 			return err
 		}
-		// brief pause to avoid overwhelming sender
-		time.Sleep(time.Second)
+		if ready {
+			batch = append(batch, msg)
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	start := time.Now()
+	results, err := a.sendByChannel(ctx, batch)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	failures := 0
+	attempts := make([]*message.Attempt, len(batch))
+	for i, res := range results {
+		if res.Err != nil {
+			failures++
+		}
+		attempts[i] = message.NewAttempt(batch[i], start, elapsed, res.Err)
+	}
+	a.throttle.observe(elapsed, len(batch), failures)
+	a.recordAttempts(ctx, attempts...)
+	var (
+		firstErr  error
+		delivered []*message.Message
+	)
+	for i, msg := range batch {
+		var err error
+		if results[i].Err != nil {
+			err = a.complete(ctx, msg, nil, results[i].Err)
+		} else if err = msg.SetSentResult(results[i].Result); err == nil {
+			delivered = append(delivered, msg)
+		} else {
+			err = errors.Wrap(err, "setting message sent status")
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := a.completeSent(ctx, delivered); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// sendByChannel hands the messages of batch to the senders of their channels, one SendBatch call per channel,
+// and returns the results in the order of batch.
+func (a *Application) sendByChannel(ctx context.Context, batch []*message.Message) ([]message.BatchResult, error) {
+	// indexes of the messages of each channel, channels in order of their first message
+	var channels []message.Channel
+	indexes := make(map[message.Channel][]int)
+	for i, msg := range batch {
+		ch := message.ChannelOf(msg)
+		if _, ok := indexes[ch]; !ok {
+			channels = append(channels, ch)
+		}
+		indexes[ch] = append(indexes[ch], i)
+	}
+	results := make([]message.BatchResult, len(batch))
+	for _, ch := range channels {
+		msgs := make([]*message.Message, len(indexes[ch]))
+		for j, i := range indexes[ch] {
+			msgs[j] = batch[i]
+		}
+		res := a.senders[ch].SendBatch(ctx, msgs)
+		if len(res) != len(msgs) {
+			return nil, errors.Errorf("sender returned %d results for a batch of %d messages", len(res), len(msgs))
+		}
+		for j, i := range indexes[ch] {
+			results[i] = res[j]
+		}
+	}
+	return results, nil
+}
+
+// prepare readies msg for delivery: it marks messages past their expiry as expired, skips it while the provider of its
+// channel asked to pause sending, defers it to the next day once its tenant used up its daily quota, waits for the
+// send rate limit, skips it once the send window closed, claims the message, gives it up if no sender serves its
+// channel, renders the content of templated messages, giving them up if that fails, rejects it if it fails validation,
+// and, if configured, reserves its content, giving up duplicates of recently sent messages, and counts it against the
+// daily quota of its tenant.
+// It reports whether msg may be sent now.
+func (a *Application) prepare(ctx context.Context, msg *message.Message) (bool, error) {
+	if now := time.Now(); msg.ExpiredBy(now) {
+		msg.SetExpired(now)
+		if err := a.messages.Expire(ctx, msg); err != nil {
+			return false, errors.Wrap(err, "marking message expired")
+		}
+		return false, nil
+	}
+	if a.backoff.paused(message.ChannelOf(msg), time.Now()) {
+		// msg is sent once the provider accepts messages again
+		return false, nil
+	}
+	if a.quotaExhausted(ctx, msg, time.Now()) {
+		// deferred without waiting for the send rate limit, as msg is not sent
+		claimed, err := a.claim(ctx, msg)
+		if err != nil || !claimed {
+			return false, err
+		}
+		return false, a.deferToNextDay(ctx, msg, time.Now())
+	}
+	if err := a.opts.limiter.Wait(ctx); err != nil {
+		return false, errors.Wrap(err, "waiting for send rate limit")
+	}
+	if !a.opts.sendWindow.Contains(time.Now()) {
+		// the send window closed while sending a backlog
+		return false, nil
+	}
+	claimed, err := a.claim(ctx, msg)
+	if err != nil || !claimed {
+		// unclaimed messages were sent meanwhile or are being sent by another instance
+		return false, err
+	}
+	if a.senders[message.ChannelOf(msg)] == nil {
+		return false, a.giveUp(ctx, msg, message.ErrChannelNotConfigured)
+	}
+	if msg.IsTemplated() {
+		content, err := a.opts.templates.Render(msg.Template, msg.Variables)
+		if err != nil {
+			// rendering fails the same way on every attempt
+			return false, a.giveUp(ctx, msg, err)
+		}
+		msg.Content = content
+	}
+	if err := a.validate(ctx, msg); err != nil {
+		return false, a.reject(ctx, msg, err)
+	}
+	if a.opts.dedup != nil {
+		unique, err := a.opts.dedup.Reserve(ctx, msg, a.opts.dedupWindow)
+		if err != nil {
+			// the claim expires with its lease
+			return false, errors.Wrap(err, "checking for duplicate message")
+		}
+		if !unique {
+			return false, a.giveUp(ctx, msg, message.ErrDuplicateMessage)
+		}
+	}
+	now := time.Now()
+	reserved, err := a.reserveQuota(ctx, msg, now)
+	if err != nil || !reserved {
+		if a.opts.dedup != nil {
+			_ = a.opts.dedup.Release(ctx, msg)
+		}
+		if err != nil {
+			return false, err
+		}
+		return false, a.deferToNextDay(ctx, msg, now)
 	}
+	return true, nil
+}
+
+// giveUp gives msg up without sending it for cause, e.g. as identical content was sent to its recipient shortly before.
+func (a *Application) giveUp(ctx context.Context, msg *message.Message, cause error) error {
+	msg.SetFailed(cause, time.Now())
+	if err := a.messages.SaveAttempts(ctx, msg); err != nil {
+		return errors.Wrap(err, "saving given up message")
+	}
+	a.opts.hooks.DeadLettered(ctx, msg, cause)
 	return nil
 }
 
-// sendMessage executes the delivery of a single message, marks it as sent, and persists the update.
-// Returns any errors encountered during send or save operations.
-func (a *Application) sendMessage(ctx context.Context, msg *message.Message) error {
-	res, err := a.sender.Send(ctx, msg)
+// validate runs the configured validators on msg, returning the error of the first one it fails.
+func (a *Application) validate(ctx context.Context, msg *message.Message) error {
+	for _, v := range a.opts.validators {
+		if err := v.Validate(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reject gives msg up without attempting it, as it failed validation for reason.
+func (a *Application) reject(ctx context.Context, msg *message.Message, reason error) error {
+	msg.SetRejected(reason, time.Now())
+	if err := a.messages.Reject(ctx, msg); err != nil {
+		return errors.Wrap(err, "saving rejected message")
+	}
+	a.opts.hooks.DeadLettered(ctx, msg, reason)
+	return nil
+}
+
+// complete records the outcome of sending msg: the sent state on success, or the failed attempt.
+// A delivered message whose sent state cannot be stored is kept in the outbox rather than sent again.
+func (a *Application) complete(ctx context.Context, msg *message.Message, res *message.SendResult, err error) error {
 	if err != nil {
-		return errors.Wrap(err, "sending message")
+		if a.opts.dedup != nil {
+			// a reservation left behind merely expires later, and retries of msg may reserve it again
+			_ = a.opts.dedup.Release(ctx, msg)
+		}
+		a.releaseQuota(ctx, msg, time.Now())
+		if err := a.recordFailedAttempt(ctx, msg, err); err != nil {
+			return err
+		}
+		return errors.Wrap(&message.SendError{Err: err}, "sending message")
 	}
 	// update message state with external ID and timestamp
-	if err := msg.SetSent(res.MessageID, res.SentAt); err != nil {
+	if err := msg.SetSentResult(res); err != nil {
 		return errors.Wrap(err, "setting message sent status")
 	}
-	return a.messages.Save(ctx, msg)
+	if err := a.saveSent(ctx, msg); err != nil {
+		if !errors.Is(err, message.ErrClaimLost) {
+			a.unsaved.add(msg)
+		}
+		return err
+	}
+	a.opts.hooks.Sent(ctx, msg)
+	return nil
+}
+
+// completeSent stores the sent state of the delivered msgs with a single repository call, notifying subscribers of
+// each stored one, like complete does for one message. If their sent state cannot be stored, all of them are kept in
+// the outbox. Messages whose claim was lost were taken over by another instance and are dropped, returning
+// message.ErrClaimLost.
+func (a *Application) completeSent(ctx context.Context, msgs []*message.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	lost, err := a.saveAllSent(ctx, msgs)
+	if err != nil {
+		for _, msg := range msgs {
+			a.unsaved.add(msg)
+		}
+		return errors.Wrap(err, "saving delivered messages")
+	}
+	for _, msg := range msgs {
+		if !slices.Contains(lost, msg) {
+			a.opts.hooks.Sent(ctx, msg)
+		}
+	}
+	if len(lost) > 0 {
+		return message.ErrClaimLost
+	}
+	return nil
+}
+
+// recordAttempts adds attempts to the history of delivery attempts, if one is kept.
+// The history is informational: failing to record it must not hold up storing the outcome of the attempts.
+func (a *Application) recordAttempts(ctx context.Context, attempts ...*message.Attempt) {
+	if a.opts.attempts == nil {
+		return
+	}
+	_ = a.opts.attempts.RecordAttempts(ctx, attempts)
+}
+
+// claimTokenBytes is the number of random bytes in generated claim tokens.
+const claimTokenBytes = 16
+
+// newClaimToken returns a new random claim token.
+func newClaimToken() (string, error) {
+	token := make([]byte, claimTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return "", errors.Wrap(err, "generating claim token")
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// claim reserves msg for delivery by this call under a new random token for the configured lease.
+// Messages claimed as they were read, see WithClaimOnRead, are delivered under their claim.
+// Returns false if the message may not be delivered, because it is already sent or claimed by someone else.
+func (a *Application) claim(ctx context.Context, msg *message.Message) (bool, error) {
+	if msg.ClaimToken != "" {
+		return true, nil
+	}
+	token, err := newClaimToken()
+	if err != nil {
+		return false, err
+	}
+	msg.ClaimToken = token
+	claimed, err := a.messages.Claim(ctx, msg, time.Now().Add(a.opts.claimLease))
+	if err != nil {
+		return false, errors.Wrap(err, "claiming message")
+	}
+	return claimed, nil
+}
+
+// recordFailedAttempt stores the failed delivery attempt of msg, scheduling its next attempt
+// or giving it up once the retry policy is exhausted.
+// If the provider refused msg for the rate of sending, the channel of msg is paused for as long as the provider asked,
+// or the first retry delay if it did not say, and msg is tried again afterwards without counting the attempt.
+// A message the provider rejected permanently, as told by message.ProviderError, is given up right away.
+func (a *Application) recordFailedAttempt(ctx context.Context, msg *message.Message, cause error) error {
+	now := time.Now()
+	var (
+		limited  *message.RateLimitedError
+		rejected *message.ProviderError
+	)
+	switch {
+	case errors.As(cause, &limited):
+		wait := limited.RetryAfter
+		if wait <= 0 {
+			wait = a.opts.retry.Delay(1)
+		}
+		a.backoff.pause(message.ChannelOf(msg), now.Add(wait))
+		msg.SetRateLimited(cause, now.Add(wait))
+	case errors.As(cause, &rejected) && rejected.Permanent(), a.opts.retry.exhausted(msg.Attempts + 1):
+		msg.SetFailed(cause, now)
+	default:
+		msg.SetAttemptFailed(cause, now.Add(a.opts.retry.Delay(msg.Attempts+1)))
+	}
+	if err := a.messages.SaveAttempts(ctx, msg); err != nil {
+		return errors.Wrap(err, "saving failed delivery attempt")
+	}
+	if msg.IsFailed() {
+		a.opts.hooks.DeadLettered(ctx, msg, cause)
+	} else {
+		a.opts.hooks.Failed(ctx, msg, cause)
+	}
+	return nil
+}
+
+// notifyOn hands the events of messages sent and given up, as published on hooks, to notifier.
+func notifyOn(hooks *message.Hooks, notifier message.Notifier) {
+	hooks.OnSent(func(ctx context.Context, msg *message.Message, _ error) {
+		notifier.Notify(ctx, message.NewEvent(message.EventMessageSent, msg, nil))
+	})
+	hooks.OnDeadLettered(func(ctx context.Context, msg *message.Message, cause error) {
+		notifier.Notify(ctx, message.NewEvent(message.EventMessageFailed, msg, cause))
+	})
 }
 
 // ListSentMessages retrieves all messages marked as sent from the repository.
@@ -100,3 +848,235 @@ func (a *Application) ListSentMessages(ctx context.Context) ([]*message.SentMess
 	}
 	return ret, nil
 }
+
+// ExportSentMessages walks all sent messages in the repository, passing each to fn.
+// Errors are wrapped and returned.
+func (a *Application) ExportSentMessages(ctx context.Context, fn func(*message.SentMessage) error) error {
+	if err := a.messages.WalkSent(ctx, fn); err != nil {
+		return errors.Wrap(err, "exporting sent messages")
+	}
+	return nil
+}
+
+// CreateMessage stores msg through the repository.
+// Messages on channels without a sender are rejected with message.ErrChannelNotConfigured, and templated messages must
+// refer to a configured template, or message.ErrUnknownTemplate is returned.
+// A replayed idempotency key must carry the same recipient, content, template, priority and schedule as the original request.
+func (a *Application) CreateMessage(ctx context.Context, msg *message.Message) (*message.Message, bool, error) {
+	if _, ok := a.senders[message.ChannelOf(msg)]; !ok {
+		return nil, false, message.ErrChannelNotConfigured
+	}
+	if msg.IsTemplated() && !a.opts.templates.Has(msg.Template) {
+		return nil, false, message.ErrUnknownTemplate
+	}
+	stored, created, err := a.messages.Create(ctx, msg)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "creating message")
+	}
+	if !created {
+		if !sameRequest(stored, msg) {
+			return nil, false, message.ErrIdempotencyKeyReused
+		}
+		return stored, false, nil
+	}
+	a.opts.hooks.Queued(ctx, stored)
+	return stored, true, nil
+}
+
+// sameRequest reports whether a message stored under an idempotency key was created from the same request as msg.
+func sameRequest(stored, msg *message.Message) bool {
+	return stored.To == msg.To &&
+		stored.Template == msg.Template &&
+		// the content of templated messages is only stored once rendered
+		(msg.IsTemplated() || stored.Content == msg.Content) &&
+		maps.Equal(stored.Variables, msg.Variables) &&
+		stored.Priority == msg.Priority &&
+		stored.ScheduledAt.Equal(msg.ScheduledAt)
+}
+
+// Stats retrieves aggregate message figures from the repository, along with the daily quota usage of the tenant
+// ctx is scoped to, DefaultTenant if unscoped, when that tenant has a daily quota.
+// Errors during retrieval are wrapped and returned.
+func (a *Application) Stats(ctx context.Context) (*message.Stats, error) {
+	ret, err := a.messages.GetStats(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting stats")
+	}
+	if ret.SentByHour, err = a.messages.CountSentByHour(ctx, time.Now().Add(-23*time.Hour)); err != nil {
+		return nil, errors.Wrap(err, "counting sent messages by hour")
+	}
+	if ret.Quota, err = a.quotaUsage(ctx, time.Now()); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// FindSentMessages retrieves the sent messages matching f from the repository.
+// Errors during retrieval are wrapped and returned.
+func (a *Application) FindSentMessages(ctx context.Context, f message.Filter) ([]*message.SentMessage, error) {
+	ret, err := a.messages.FindSent(ctx, f)
+	if err != nil {
+		return nil, errors.Wrap(err, "finding sent messages")
+	}
+	return ret, nil
+}
+
+// FindUnsentMessages retrieves the unsent messages matching f from the repository.
+// Errors during retrieval are wrapped and returned.
+func (a *Application) FindUnsentMessages(ctx context.Context, f message.Filter) ([]*message.Message, error) {
+	ret, err := a.messages.FindUnsent(ctx, f)
+	if err != nil {
+		return nil, errors.Wrap(err, "finding unsent messages")
+	}
+	return ret, nil
+}
+
+// FindFailedMessages retrieves the messages whose delivery was given up that match f from the repository.
+// Errors during retrieval are wrapped and returned.
+func (a *Application) FindFailedMessages(ctx context.Context, f message.Filter) ([]*message.Message, error) {
+	ret, err := a.messages.FindFailed(ctx, f)
+	if err != nil {
+		return nil, errors.Wrap(err, "finding failed messages")
+	}
+	return ret, nil
+}
+
+// RequeueMessage resets a failed message in the repository so the sender picks it up again.
+// Messages that were sent or are still being retried are left untouched.
+func (a *Application) RequeueMessage(ctx context.Context, id string) error {
+	msg, err := a.GetMessage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !msg.IsFailed() {
+		return message.ErrMessageNotFailed
+	}
+	if err := a.messages.Requeue(ctx, id); err != nil {
+		return errors.Wrap(err, "requeueing message")
+	}
+	return nil
+}
+
+// CancelMessage marks a pending message in the repository as canceled.
+// The repository only cancels messages no instance has claimed, and claims are only granted to uncanceled
+// messages, so either the cancellation wins and the message is never sent, or the send does and
+// message.ErrMessageBeingSent is returned.
+func (a *Application) CancelMessage(ctx context.Context, id string) error {
+	msg, err := a.GetMessage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !msg.IsPending() {
+		return message.ErrMessageNotPending
+	}
+	msg.SetCanceled(time.Now())
+	canceled, err := a.messages.Cancel(ctx, msg)
+	if err != nil {
+		return errors.Wrap(err, "canceling message")
+	}
+	if !canceled {
+		return message.ErrMessageBeingSent
+	}
+	return nil
+}
+
+// DeleteMessage deletes a message that is no longer pending, so it no longer shows up in listings, lookups and stats.
+// Pending messages are refused with message.ErrMessagePending, they are canceled instead.
+func (a *Application) DeleteMessage(ctx context.Context, id string) error {
+	msg, err := a.GetMessage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if msg.IsPending() {
+		return message.ErrMessagePending
+	}
+	if err := a.messages.Delete(ctx, id); err != nil {
+		return errors.Wrap(err, "deleting message")
+	}
+	return nil
+}
+
+// GetMessage retrieves a single message by its internal ID.
+// Returns message.ErrMessageNotFound if the repository has no such message.
+func (a *Application) GetMessage(ctx context.Context, id string) (*message.Message, error) {
+	msg, err := a.messages.GetByID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting message")
+	}
+	if msg == nil {
+		return nil, message.ErrMessageNotFound
+	}
+	return msg, nil
+}
+
+// ListAttempts retrieves the delivery attempts of the message with the given internal ID from the attempt log.
+// Errors are wrapped and returned.
+func (a *Application) ListAttempts(ctx context.Context, id string) ([]*message.Attempt, error) {
+	if _, err := a.GetMessage(ctx, id); err != nil {
+		return nil, err
+	}
+	if a.opts.attempts == nil {
+		return nil, nil
+	}
+	ret, err := a.opts.attempts.ListAttempts(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing attempts")
+	}
+	return ret, nil
+}
+
+// RecordDeliveryReport stores the delivery status reported by r through the repository.
+// Errors are wrapped and returned.
+func (a *Application) RecordDeliveryReport(ctx context.Context, r *message.DeliveryReport) error {
+	if err := a.messages.SaveDeliveryReport(ctx, r); err != nil {
+		return errors.Wrap(err, "recording delivery report")
+	}
+	return nil
+}
+
+// subscriptionSecretBytes is the number of random bytes in generated subscription secrets.
+const subscriptionSecretBytes = 32
+
+// CreateSubscription stores sub, generating a signing secret if it has none.
+func (a *Application) CreateSubscription(ctx context.Context, sub *message.Subscription) (*message.Subscription, error) {
+	if a.opts.subscriptions == nil {
+		return nil, ErrSubscriptionsNotConfigured
+	}
+	if sub.Secret == "" {
+		secret := make([]byte, subscriptionSecretBytes)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, errors.Wrap(err, "generating subscription secret")
+		}
+		sub.Secret = hex.EncodeToString(secret)
+	}
+	ret, err := a.opts.subscriptions.CreateSubscription(ctx, sub)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating subscription")
+	}
+	return ret, nil
+}
+
+// ListSubscriptions retrieves the stored subscriptions.
+// Errors during retrieval are wrapped and returned.
+func (a *Application) ListSubscriptions(ctx context.Context) ([]*message.Subscription, error) {
+	if a.opts.subscriptions == nil {
+		return nil, ErrSubscriptionsNotConfigured
+	}
+	ret, err := a.opts.subscriptions.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing subscriptions")
+	}
+	return ret, nil
+}
+
+// DeleteSubscription removes a stored subscription.
+// Returns message.ErrSubscriptionNotFound if the repository has no such subscription.
+func (a *Application) DeleteSubscription(ctx context.Context, id string) error {
+	if a.opts.subscriptions == nil {
+		return ErrSubscriptionsNotConfigured
+	}
+	if err := a.opts.subscriptions.DeleteSubscription(ctx, id); err != nil {
+		return errors.Wrap(err, "deleting subscription")
+	}
+	return nil
+}
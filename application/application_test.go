@@ -3,6 +3,9 @@ package application_test
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -12,6 +15,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // Mock implementations for testing
@@ -27,21 +35,166 @@ func (m *MockRepository) GetNextUnsent(ctx context.Context) (*message.Message, e
 	return args.Get(0).(*message.Message), args.Error(1)
 }
 
+func (m *MockRepository) Claim(ctx context.Context, msg *message.Message, until time.Time) (bool, error) {
+	args := m.Called(ctx, msg, until)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) ClaimUnsent(ctx context.Context, n int, token string, until time.Time) ([]*message.Message, error) {
+	args := m.Called(ctx, n, token, until)
+	return args.Get(0).([]*message.Message), args.Error(1)
+}
+
+func (m *MockRepository) Expire(ctx context.Context, msg *message.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
 func (m *MockRepository) GetAllUnsent(ctx context.Context) ([]*message.Message, error) {
 	args := m.Called(ctx)
 	return args.Get(0).([]*message.Message), args.Error(1)
 }
 
+func (m *MockRepository) GetUnsent(ctx context.Context, n int) ([]*message.Message, error) {
+	args := m.Called(ctx, n)
+	return args.Get(0).([]*message.Message), args.Error(1)
+}
+
 func (m *MockRepository) GetAllSent(ctx context.Context) ([]*message.SentMessage, error) {
 	args := m.Called(ctx)
 	return args.Get(0).([]*message.SentMessage), args.Error(1)
 }
 
+func (m *MockRepository) FindSent(ctx context.Context, f message.Filter) ([]*message.SentMessage, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]*message.SentMessage), args.Error(1)
+}
+
+func (m *MockRepository) FindUnsent(ctx context.Context, f message.Filter) ([]*message.Message, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]*message.Message), args.Error(1)
+}
+
+func (m *MockRepository) FindFailed(ctx context.Context, f message.Filter) ([]*message.Message, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]*message.Message), args.Error(1)
+}
+
+func (m *MockRepository) Cancel(ctx context.Context, msg *message.Message) (bool, error) {
+	args := m.Called(ctx, msg)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) SaveDeliveryReport(ctx context.Context, r *message.DeliveryReport) error {
+	args := m.Called(ctx, r)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Requeue(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) WalkSent(ctx context.Context, fn func(*message.SentMessage) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Create(ctx context.Context, msg *message.Message) (*message.Message, bool, error) {
+	args := m.Called(ctx, msg)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*message.Message), args.Bool(1), args.Error(2)
+}
+
+func (m *MockRepository) GetStats(ctx context.Context) (*message.Stats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*message.Stats), args.Error(1)
+}
+
+func (m *MockRepository) CountUnsent(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) CountSent(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) CountFailed(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) CountSentByHour(ctx context.Context, since time.Time) ([]message.HourCount, error) {
+	args := m.Called(ctx, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]message.HourCount), args.Error(1)
+}
+
+// WithTx calls fn with the mock itself, as if it were bound to a transaction.
+func (m *MockRepository) WithTx(_ context.Context, fn func(message.Repository) error) error {
+	return fn(m)
+}
+
+func (m *MockRepository) InsertMany(ctx context.Context, msgs []*message.Message) error {
+	args := m.Called(ctx, msgs)
+	return args.Error(0)
+}
+
 func (m *MockRepository) Save(ctx context.Context, msg *message.Message) error {
 	args := m.Called(ctx, msg)
 	return args.Error(0)
 }
 
+func (m *MockRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Reject(ctx context.Context, msg *message.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ArchiveSent(ctx context.Context, olderThan time.Duration, n int) (int64, error) {
+	args := m.Called(ctx, olderThan, n)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) DeleteSent(ctx context.Context, olderThan time.Duration, n int) (int64, error) {
+	args := m.Called(ctx, olderThan, n)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) SaveAll(ctx context.Context, msgs []*message.Message) ([]*message.Message, error) {
+	args := m.Called(ctx, msgs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*message.Message), args.Error(1)
+}
+
+func (m *MockRepository) SaveAttempts(ctx context.Context, msg *message.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetByID(ctx context.Context, id string) (*message.Message, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*message.Message), args.Error(1)
+}
+
 type MockSender struct {
 	mock.Mock
 }
@@ -54,6 +207,88 @@ func (m *MockSender) Send(ctx context.Context, msg *message.Message) (*message.S
 	return args.Get(0).(*message.SendResult), args.Error(1)
 }
 
+func (m *MockSender) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	args := m.Called(ctx, msgs)
+	return args.Get(0).([]message.BatchResult)
+}
+
+type MockSubscriptionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSubscriptionRepository) CreateSubscription(ctx context.Context, sub *message.Subscription) (*message.Subscription, error) {
+	args := m.Called(ctx, sub)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*message.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ListSubscriptions(ctx context.Context) ([]*message.Subscription, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*message.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) DeleteSubscription(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+type MockAttemptRepository struct {
+	mock.Mock
+}
+
+func (m *MockAttemptRepository) RecordAttempts(ctx context.Context, attempts []*message.Attempt) error {
+	args := m.Called(ctx, attempts)
+	return args.Error(0)
+}
+
+func (m *MockAttemptRepository) ListAttempts(ctx context.Context, id string) ([]*message.Attempt, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]*message.Attempt), args.Error(1)
+}
+
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, e *message.Event) {
+	m.Called(ctx, e)
+}
+
+type MockDeduplicator struct {
+	mock.Mock
+}
+
+func (m *MockDeduplicator) Reserve(ctx context.Context, msg *message.Message, window time.Duration) (bool, error) {
+	args := m.Called(ctx, msg, window)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDeduplicator) Release(ctx context.Context, msg *message.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+type MockQuotaCounter struct {
+	mock.Mock
+}
+
+func (m *MockQuotaCounter) Reserve(ctx context.Context, tenant string, day time.Time, limit int64) (bool, error) {
+	args := m.Called(ctx, tenant, day, limit)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockQuotaCounter) Release(ctx context.Context, tenant string, day time.Time) error {
+	args := m.Called(ctx, tenant, day)
+	return args.Error(0)
+}
+
+func (m *MockQuotaCounter) Used(ctx context.Context, tenant string, day time.Time) (int64, error) {
+	args := m.Called(ctx, tenant, day)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // Helper function to create a test message
 func createTestMessage(id string, content string) *message.Message {
 	// This assumes Message has these fields - adjust based on actual Message struct
@@ -86,6 +321,7 @@ func TestApplication_SendNext(t *testing.T) {
 				sendResult := createSendResult("sent-msg-1")
 
 				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg).Return(sendResult, nil)
 
 				// Mock the SetSent method call on the message
@@ -118,7 +354,9 @@ func TestApplication_SendNext(t *testing.T) {
 				msg := createTestMessage("msg-1", "Hello World")
 
 				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg).Return(nil, errors.New("network timeout"))
+				repo.On("SaveAttempts", mock.Anything, msg).Return(nil)
 			},
 			expectedError: "sending message: network timeout",
 			description:   "Should wrap and return sender errors",
@@ -130,6 +368,7 @@ func TestApplication_SendNext(t *testing.T) {
 				sendResult := createSendResult("sent-msg-1")
 
 				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg).Return(sendResult, nil)
 				repo.On("Save", mock.Anything, msg).Return(errors.New("save failed"))
 			},
@@ -169,6 +408,11 @@ func TestApplication_SendNext(t *testing.T) {
 	}
 }
 
+// canceled reports whether ctx is done, matching the canceled context of a test or a context derived from it.
+func canceled(ctx context.Context) bool {
+	return ctx.Err() != nil
+}
+
 func TestApplication_SendNext_ContextCancellation(t *testing.T) {
 	mockRepo := &MockRepository{}
 	mockSender := &MockSender{}
@@ -177,8 +421,8 @@ func TestApplication_SendNext_ContextCancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	// Mock should be called with the cancelled context
-	mockRepo.On("GetNextUnsent", ctx).Return(nil, context.Canceled)
+	// Mock should be called with the cancelled context, or one derived from it to carry the trace
+	mockRepo.On("GetNextUnsent", mock.MatchedBy(canceled)).Return(nil, context.Canceled)
 
 	app := application.NewApplication(mockRepo, mockSender)
 
@@ -192,6 +436,81 @@ func TestApplication_SendNext_ContextCancellation(t *testing.T) {
 	mockSender.AssertExpectations(t)
 }
 
+func TestApplication_SendNext_SenderErrorIsSendError(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+
+	msg := createTestMessage("msg-1", "Hello World")
+	senderErr := errors.New("network timeout")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(nil, senderErr)
+	mockRepo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+
+	app := application.NewApplication(mockRepo, mockSender)
+
+	err := app.SendNext(context.Background())
+
+	// sender failures are marked so callers can tell them apart from storage errors
+	var sendErr *message.SendError
+	require.ErrorAs(t, err, &sendErr)
+	assert.ErrorIs(t, err, senderErr)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestApplication_SendNext_SkipsMessageClaimedElsewhere(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(false, nil)
+	app := application.NewApplication(mockRepo, mockSender)
+
+	err := app.SendNext(context.Background())
+
+	assert.NoError(t, err)
+	mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestApplication_SendNext_ClaimsForLease(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.MatchedBy(func(until time.Time) bool {
+		return time.Until(until) > 4*time.Minute && time.Until(until) <= 5*time.Minute
+	})).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil)
+	mockRepo.On("Save", mock.Anything, msg).Return(message.ErrClaimLost)
+	app := application.NewApplication(mockRepo, mockSender, application.WithClaimLease(5*time.Minute))
+
+	err := app.SendNext(context.Background())
+
+	// the message is saved under the token it was claimed with
+	assert.ErrorIs(t, err, message.ErrClaimLost)
+	assert.Len(t, msg.ClaimToken, 32)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_SendNext_ExpiresStaleMessage(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Your code is 123456")
+	msg.ExpiresAt = time.Now().Add(-time.Minute)
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Expire", mock.Anything, msg).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender)
+
+	err := app.SendNext(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, msg.IsExpired())
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Claim", mock.Anything, mock.Anything, mock.Anything)
+	mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
 func TestApplication_SendNext_Integration(t *testing.T) {
 	// This test verifies the complete flow without mocking internal calls
 	mockRepo := &MockRepository{}
@@ -202,6 +521,7 @@ func TestApplication_SendNext_Integration(t *testing.T) {
 
 	// Setup the complete flow
 	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 	mockSender.On("Send", mock.Anything, msg).Return(sendResult, nil)
 	mockRepo.On("Save", mock.Anything, msg).Return(nil)
 
@@ -232,6 +552,7 @@ func BenchmarkApplication_SendNext(b *testing.B) {
 
 	// Setup mocks for multiple calls
 	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 	mockSender.On("Send", mock.Anything, msg).Return(sendResult, nil)
 	mockRepo.On("Save", mock.Anything, msg).Return(nil)
 
@@ -273,6 +594,7 @@ func TestApplication_SendAllUnsent(t *testing.T) {
 				sendResult := createSendResult("sent-msg-1")
 
 				repo.On("GetAllUnsent", mock.Anything).Return([]*message.Message{msg}, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg).Return(sendResult, nil)
 				repo.On("Save", mock.Anything, msg).Return(nil)
 			},
@@ -293,8 +615,11 @@ func TestApplication_SendAllUnsent(t *testing.T) {
 
 				repo.On("GetAllUnsent", mock.Anything).Return([]*message.Message{msg1, msg2, msg3}, nil)
 
+				repo.On("Claim", mock.Anything, msg1, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg1).Return(sendResult1, nil)
+				repo.On("Claim", mock.Anything, msg2, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg2).Return(sendResult2, nil)
+				repo.On("Claim", mock.Anything, msg3, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg3).Return(sendResult3, nil)
 
 				repo.On("Save", mock.Anything, msg1).Return(nil)
@@ -303,7 +628,7 @@ func TestApplication_SendAllUnsent(t *testing.T) {
 			},
 			expectedError: "",
 			description:   "Should successfully send multiple messages with delays",
-			expectedDelay: 3 * time.Second, // 3 messages, 1 second delay after each
+			expectedDelay: 2 * time.Second, // 3 messages at one per second, the first right away
 		},
 		{
 			name: "success_no_messages_to_send",
@@ -331,7 +656,9 @@ func TestApplication_SendAllUnsent(t *testing.T) {
 				msg2 := createTestMessage("msg-2", "Second message")
 
 				repo.On("GetAllUnsent", mock.Anything).Return([]*message.Message{msg1, msg2}, nil)
+				repo.On("Claim", mock.Anything, msg1, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg1).Return(nil, errors.New("network timeout"))
+				repo.On("SaveAttempts", mock.Anything, msg1).Return(nil)
 				// Second message should not be processed due to early return
 			},
 			expectedError: "sending message: network timeout",
@@ -347,13 +674,16 @@ func TestApplication_SendAllUnsent(t *testing.T) {
 				sendResult1 := createSendResult("sent-msg-1")
 
 				repo.On("GetAllUnsent", mock.Anything).Return([]*message.Message{msg1, msg2}, nil)
+				repo.On("Claim", mock.Anything, msg1, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg1).Return(sendResult1, nil)
 				repo.On("Save", mock.Anything, msg1).Return(nil)
+				repo.On("Claim", mock.Anything, msg2, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg2).Return(nil, errors.New("rate limit exceeded"))
+				repo.On("SaveAttempts", mock.Anything, msg2).Return(nil)
 			},
 			expectedError: "sending message: rate limit exceeded",
 			description:   "Should return error when second message fails after first succeeds",
-			expectedDelay: time.Second, // One delay after first successful message
+			expectedDelay: time.Second, // the second message waits a second for the rate limit
 		},
 		{
 			name: "save_error_after_successful_send",
@@ -362,6 +692,7 @@ func TestApplication_SendAllUnsent(t *testing.T) {
 				sendResult := createSendResult("sent-msg-1")
 
 				repo.On("GetAllUnsent", mock.Anything).Return([]*message.Message{msg}, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 				sender.On("Send", mock.Anything, msg).Return(sendResult, nil)
 				repo.On("Save", mock.Anything, msg).Return(errors.New("save failed"))
 			},
@@ -425,7 +756,7 @@ func TestApplication_SendAllUnsent_ContextCancellation(t *testing.T) {
 	cancel()
 
 	// Mock should be called with the cancelled context
-	mockRepo.On("GetAllUnsent", ctx).Return(([]*message.Message)(nil), context.Canceled)
+	mockRepo.On("GetAllUnsent", mock.MatchedBy(canceled)).Return(([]*message.Message)(nil), context.Canceled)
 
 	app := application.NewApplication(mockRepo, mockSender)
 
@@ -454,6 +785,7 @@ func TestApplication_SendAllUnsent_ContextTimeout(t *testing.T) {
 	// Mock successful sends for all messages
 	for _, msg := range messages {
 		sendResult := createSendResult(fmt.Sprintf("sent-%s", msg.ID))
+		mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 		mockSender.On("Send", mock.Anything, msg).Return(sendResult, nil)
 		mockRepo.On("Save", mock.Anything, msg).Return(nil)
 	}
@@ -468,10 +800,11 @@ func TestApplication_SendAllUnsent_ContextTimeout(t *testing.T) {
 	err := app.SendAllUnsent(ctx)
 	executionTime := time.Since(startTime)
 
-	// The method should complete successfully since it doesn't check context during execution
-	// This test documents current behavior - context is only checked at the beginning
-	assert.NoError(t, err)
-	assert.Greater(t, executionTime, 4*time.Second) // Should take ~5 seconds with delays
+	// the rate limiter gives up as soon as the next send would not fit before the deadline
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "waiting for send rate limit")
+	assert.Less(t, executionTime, 2*time.Second)
+	mockSender.AssertNumberOfCalls(t, "Send", 2)
 }
 
 func TestApplication_SendAllUnsent_LargeNumberOfMessages(t *testing.T) {
@@ -491,6 +824,7 @@ func TestApplication_SendAllUnsent_LargeNumberOfMessages(t *testing.T) {
 	// Mock successful sends for all messages
 	for _, msg := range messages {
 		sendResult := createSendResult(fmt.Sprintf("sent-%s", msg.ID))
+		mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 		mockSender.On("Send", mock.Anything, msg).Return(sendResult, nil)
 		mockRepo.On("Save", mock.Anything, msg).Return(nil)
 	}
@@ -507,8 +841,8 @@ func TestApplication_SendAllUnsent_LargeNumberOfMessages(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 	mockSender.AssertExpectations(t)
 
-	// Verify timing includes delays (messageCount * 1 second)
-	expectedMinTime := time.Duration(messageCount) * time.Second
+	// Verify timing follows the rate limit of one message per second, the first sent right away
+	expectedMinTime := time.Duration(messageCount-1) * time.Second
 	assert.GreaterOrEqual(t, executionTime, expectedMinTime-100*time.Millisecond,
 		"Should include delays between messages")
 }
@@ -529,6 +863,7 @@ func TestApplication_SendAllUnsent_Integration(t *testing.T) {
 	// Setup successful flow for all messages
 	for _, msg := range messages {
 		sendResult := createSendResult(fmt.Sprintf("sent-%s", msg.ID))
+		mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 		mockSender.On("Send", mock.Anything, msg).Return(sendResult, nil)
 		mockRepo.On("Save", mock.Anything, msg).Return(nil)
 	}
@@ -569,6 +904,7 @@ func BenchmarkApplication_SendAllUnsent(b *testing.B) {
 	// Mock successful sends for all messages
 	for _, msg := range messages {
 		sendResult := createSendResult(fmt.Sprintf("sent-%s", msg.ID))
+		mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
 		mockSender.On("Send", mock.Anything, msg).Return(sendResult, nil)
 		mockRepo.On("Save", mock.Anything, msg).Return(nil)
 	}
@@ -947,3 +1283,1875 @@ func BenchmarkApplication_ListSentMessages(b *testing.B) {
 		_, _ = app.ListSentMessages(ctx)
 	}
 }
+
+func TestApplication_CreateMessage(t *testing.T) {
+	newMsg := func(content string) *message.Message {
+		return &message.Message{To: "+905551234567", Content: content, IdempotencyKey: "key-1"}
+	}
+	stored := &message.Message{ID: "42", To: "+905551234567", Content: "hello", IdempotencyKey: "key-1"}
+
+	tests := []struct {
+		name            string
+		msg             *message.Message
+		setupMocks      func(*MockRepository)
+		expectedCreated bool
+		expectedError   error
+		errorContains   string
+	}{
+		{
+			name: "new message",
+			msg:  newMsg("hello"),
+			setupMocks: func(repo *MockRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(stored, true, nil)
+			},
+			expectedCreated: true,
+		},
+		{
+			name: "replayed key with same payload",
+			msg:  newMsg("hello"),
+			setupMocks: func(repo *MockRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(stored, false, nil)
+			},
+			expectedCreated: false,
+		},
+		{
+			name: "replayed key with different payload",
+			msg:  newMsg("something else"),
+			setupMocks: func(repo *MockRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(stored, false, nil)
+			},
+			expectedError: message.ErrIdempotencyKeyReused,
+		},
+		{
+			name: "replayed key with different priority",
+			msg:  &message.Message{To: "+905551234567", Content: "hello", IdempotencyKey: "key-1", Priority: 10},
+			setupMocks: func(repo *MockRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(stored, false, nil)
+			},
+			expectedError: message.ErrIdempotencyKeyReused,
+		},
+		{
+			name: "replayed key with different schedule",
+			msg:  &message.Message{To: "+905551234567", Content: "hello", IdempotencyKey: "key-1", ScheduledAt: time.Now().Add(time.Hour)},
+			setupMocks: func(repo *MockRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(stored, false, nil)
+			},
+			expectedError: message.ErrIdempotencyKeyReused,
+		},
+		{
+			name: "repository error",
+			msg:  newMsg("hello"),
+			setupMocks: func(repo *MockRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil, false, errors.New("database connection failed"))
+			},
+			errorContains: "creating message: database connection failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			mockSender := &MockSender{}
+			tt.setupMocks(mockRepo)
+
+			app := application.NewApplication(mockRepo, mockSender)
+
+			ret, created, err := app.CreateMessage(context.Background(), tt.msg)
+
+			switch {
+			case tt.expectedError != nil:
+				require.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, ret)
+			case tt.errorContains != "":
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorContains)
+				assert.Nil(t, ret)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, stored, ret)
+				assert.Equal(t, tt.expectedCreated, created)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_EnqueueMessages(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockRepo.On("InsertMany", mock.Anything, mock.Anything).Return(nil)
+	templates, err := application.NewTemplates(map[string]string{"welcome": "Hi {{.name}}"})
+	require.NoError(t, err)
+	app := application.NewApplication(mockRepo, &MockSender{}, application.WithTemplates(templates))
+	sendAt := time.Now().Add(time.Hour)
+
+	res, err := app.EnqueueMessages(context.Background(), []*application.NewMessageInput{
+		{To: "+905551111111", Content: "Hello", Priority: 5, SendAt: sendAt},
+		{To: "12345", Content: "bad number"},
+		{To: "+905552222222"},
+		{To: "+905553333333", Template: "welcome", Variables: map[string]string{"name": "Ada"}},
+		{To: "+905554444444", Template: "goodbye"},
+		{Channel: message.ChannelEmail, To: "ada@example.com", Content: "Hello"},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, res.Queued, 2)
+	assert.Equal(t, "+905551111111", res.Queued[0].To)
+	assert.Equal(t, 5, res.Queued[0].Priority)
+	assert.Equal(t, sendAt, res.Queued[0].ScheduledAt)
+	assert.Equal(t, "welcome", res.Queued[1].Template)
+	require.Len(t, res.Rejected, 4)
+	for i, want := range []struct {
+		index int
+		err   error
+	}{
+		{1, message.ErrInvalidPhoneNumber},
+		{2, message.ErrBlankContent},
+		{4, message.ErrUnknownTemplate},
+		{5, message.ErrChannelNotConfigured},
+	} {
+		assert.Equal(t, want.index, res.Rejected[i].Index)
+		assert.ErrorIs(t, res.Rejected[i], want.err)
+	}
+	// valid messages are stored together
+	mockRepo.AssertNumberOfCalls(t, "InsertMany", 1)
+	mockRepo.AssertCalled(t, "InsertMany", mock.Anything, res.Queued)
+}
+
+func TestApplication_EnqueueMessages_NoneValid(t *testing.T) {
+	mockRepo := &MockRepository{}
+	app := application.NewApplication(mockRepo, &MockSender{})
+
+	res, err := app.EnqueueMessages(context.Background(), []*application.NewMessageInput{{To: "12345", Content: "Hello"}})
+
+	require.NoError(t, err)
+	assert.Empty(t, res.Queued)
+	assert.Len(t, res.Rejected, 1)
+	mockRepo.AssertNotCalled(t, "InsertMany", mock.Anything, mock.Anything)
+}
+
+func TestApplication_EnqueueMessages_StoreError(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockRepo.On("InsertMany", mock.Anything, mock.Anything).Return(errors.New("deadlock detected"))
+	app := application.NewApplication(mockRepo, &MockSender{})
+
+	_, err := app.EnqueueMessages(context.Background(), []*application.NewMessageInput{{To: "+905551111111", Content: "Hello"}})
+
+	assert.EqualError(t, err, "enqueueing messages: deadlock detected")
+}
+
+func TestApplication_GetMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMocks    func(*MockRepository)
+		expectedID    string
+		expectedError error
+		errorContains string
+	}{
+		{
+			name: "found",
+			setupMocks: func(repo *MockRepository) {
+				repo.On("GetByID", mock.Anything, "msg-1").Return(createTestMessage("msg-1", "Hello"), nil)
+			},
+			expectedID: "msg-1",
+		},
+		{
+			name: "not_found",
+			setupMocks: func(repo *MockRepository) {
+				repo.On("GetByID", mock.Anything, "msg-1").Return(nil, nil)
+			},
+			expectedError: message.ErrMessageNotFound,
+		},
+		{
+			name: "repository_error",
+			setupMocks: func(repo *MockRepository) {
+				repo.On("GetByID", mock.Anything, "msg-1").Return(nil, errors.New("query timeout"))
+			},
+			errorContains: "getting message: query timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			mockSender := &MockSender{}
+			tt.setupMocks(mockRepo)
+
+			app := application.NewApplication(mockRepo, mockSender)
+
+			msg, err := app.GetMessage(context.Background(), "msg-1")
+
+			switch {
+			case tt.expectedError != nil:
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, msg)
+			case tt.errorContains != "":
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorContains)
+				assert.Nil(t, msg)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedID, msg.ID)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_ListAttempts(t *testing.T) {
+	attempts := []*message.Attempt{{MessageID: "msg-1", StartedAt: time.Now(), Error: "provider down"}}
+	mockRepo := &MockRepository{}
+	mockAttempts := &MockAttemptRepository{}
+	mockRepo.On("GetByID", mock.Anything, "msg-1").Return(createTestMessage("msg-1", "Hello"), nil)
+	mockRepo.On("GetByID", mock.Anything, "msg-2").Return(nil, nil)
+	mockAttempts.On("ListAttempts", mock.Anything, "msg-1").Return(attempts, nil)
+
+	got, err := application.NewApplication(mockRepo, &MockSender{}, application.WithAttemptLog(mockAttempts)).
+		ListAttempts(context.Background(), "msg-1")
+	require.NoError(t, err)
+	assert.Equal(t, attempts, got)
+
+	// unknown messages are not found rather than without attempts
+	_, err = application.NewApplication(mockRepo, &MockSender{}, application.WithAttemptLog(mockAttempts)).
+		ListAttempts(context.Background(), "msg-2")
+	assert.ErrorIs(t, err, message.ErrMessageNotFound)
+
+	// without an attempt log no history is kept
+	got, err = application.NewApplication(mockRepo, &MockSender{}).ListAttempts(context.Background(), "msg-1")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	mockAttempts.AssertNumberOfCalls(t, "ListAttempts", 1)
+}
+
+func TestApplication_RecordDeliveryReport(t *testing.T) {
+	report := &message.DeliveryReport{MessageID: "ext-1", Status: message.DeliveryDelivered, ReportedAt: time.Now()}
+	tests := []struct {
+		name          string
+		repoErr       error
+		expectedError error
+		errorContains string
+	}{
+		{name: "recorded"},
+		{name: "unknown_message", repoErr: message.ErrMessageNotFound, expectedError: message.ErrMessageNotFound},
+		{name: "repository_error", repoErr: errors.New("query timeout"), errorContains: "recording delivery report: query timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			mockRepo.On("SaveDeliveryReport", mock.Anything, report).Return(tt.repoErr)
+			app := application.NewApplication(mockRepo, &MockSender{})
+
+			err := app.RecordDeliveryReport(context.Background(), report)
+
+			switch {
+			case tt.expectedError != nil:
+				assert.ErrorIs(t, err, tt.expectedError)
+			case tt.errorContains != "":
+				assert.ErrorContains(t, err, tt.errorContains)
+			default:
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_SendNext_NotifiesSubscribers(t *testing.T) {
+	isEvent := func(eventType, errMsg string) any {
+		return mock.MatchedBy(func(e *message.Event) bool {
+			return e.Type == eventType && e.Message.ID == "msg-1" && e.Error == errMsg
+		})
+	}
+	tests := []struct {
+		name       string
+		setupMocks func(*MockRepository, *MockSender, *MockNotifier)
+	}{
+		{
+			name: "sent",
+			setupMocks: func(repo *MockRepository, sender *MockSender, notifier *MockNotifier) {
+				msg := createTestMessage("msg-1", "Hello World")
+				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+				sender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil)
+				repo.On("Save", mock.Anything, msg).Return(nil)
+				notifier.On("Notify", mock.Anything, isEvent(message.EventMessageSent, "")).Once()
+			},
+		},
+		{
+			name: "send_failed_retry_scheduled",
+			setupMocks: func(repo *MockRepository, sender *MockSender, notifier *MockNotifier) {
+				// nothing is announced while the message is still tried again
+				msg := createTestMessage("msg-1", "Hello World")
+				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+				sender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider down"))
+				repo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+			},
+		},
+		{
+			name: "send_failed_given_up",
+			setupMocks: func(repo *MockRepository, sender *MockSender, notifier *MockNotifier) {
+				msg := createTestMessage("msg-1", "Hello World")
+				msg.Attempts = application.DefaultRetryPolicy.MaxAttempts - 1
+				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+				sender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider down"))
+				repo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+				notifier.On("Notify", mock.Anything, isEvent(message.EventMessageFailed, "provider down")).Once()
+			},
+		},
+		{
+			name: "save_failed",
+			setupMocks: func(repo *MockRepository, sender *MockSender, notifier *MockNotifier) {
+				// nothing is announced until the sent state is stored
+				msg := createTestMessage("msg-1", "Hello World")
+				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+				sender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil)
+				repo.On("Save", mock.Anything, msg).Return(errors.New("database connection failed"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			mockSender := &MockSender{}
+			mockNotifier := &MockNotifier{}
+			tt.setupMocks(mockRepo, mockSender, mockNotifier)
+
+			app := application.NewApplication(mockRepo, mockSender,
+				application.WithSubscriptions(&MockSubscriptionRepository{}, mockNotifier),
+			)
+
+			_ = app.SendNext(context.Background())
+
+			mockNotifier.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_RunsLifecycleHooks(t *testing.T) {
+	tests := []struct {
+		name       string
+		run        func(*application.Application) error
+		setupMocks func(*MockRepository, *MockSender)
+		expected   []string
+	}{
+		{
+			name: "created",
+			run: func(app *application.Application) error {
+				_, _, err := app.CreateMessage(context.Background(), &message.Message{To: "+905551234567", Content: "Hello World"})
+				return err
+			},
+			setupMocks: func(repo *MockRepository, sender *MockSender) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(createTestMessage("msg-1", "Hello World"), true, nil)
+			},
+			expected: []string{"queued msg-1"},
+		},
+		{
+			name: "idempotent_replay",
+			run: func(app *application.Application) error {
+				_, _, err := app.CreateMessage(context.Background(), createTestMessage("", "Hello World"))
+				return err
+			},
+			setupMocks: func(repo *MockRepository, sender *MockSender) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(createTestMessage("msg-1", "Hello World"), false, nil)
+			},
+		},
+		{
+			name: "imported",
+			run: func(app *application.Application) error {
+				_, err := app.EnqueueMessages(context.Background(), []*application.NewMessageInput{
+					{To: "+905551111111", Content: "Hello"},
+					{To: "+905552222222", Content: "World"},
+				})
+				return err
+			},
+			setupMocks: func(repo *MockRepository, sender *MockSender) {
+				repo.On("InsertMany", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+					for i, msg := range args.Get(1).([]*message.Message) {
+						msg.ID = fmt.Sprintf("msg-%d", i+1)
+					}
+				})
+			},
+			expected: []string{"queued msg-1", "queued msg-2"},
+		},
+		{
+			name: "sent",
+			run: func(app *application.Application) error {
+				return app.SendNext(context.Background())
+			},
+			setupMocks: func(repo *MockRepository, sender *MockSender) {
+				msg := createTestMessage("msg-1", "Hello World")
+				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+				sender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil)
+				repo.On("Save", mock.Anything, msg).Return(nil)
+			},
+			expected: []string{"sent msg-1"},
+		},
+		{
+			name: "send_failed_retry_scheduled",
+			run: func(app *application.Application) error {
+				return app.SendNext(context.Background())
+			},
+			setupMocks: func(repo *MockRepository, sender *MockSender) {
+				msg := createTestMessage("msg-1", "Hello World")
+				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+				sender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider down"))
+				repo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+			},
+			expected: []string{"failed msg-1: provider down"},
+		},
+		{
+			name: "send_failed_given_up",
+			run: func(app *application.Application) error {
+				return app.SendNext(context.Background())
+			},
+			setupMocks: func(repo *MockRepository, sender *MockSender) {
+				msg := createTestMessage("msg-1", "Hello World")
+				msg.Attempts = application.DefaultRetryPolicy.MaxAttempts - 1
+				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+				sender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider down"))
+				repo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+			},
+			expected: []string{"dead-lettered msg-1: provider down"},
+		},
+		{
+			name: "unsent_save_failed",
+			run: func(app *application.Application) error {
+				return app.SendNext(context.Background())
+			},
+			setupMocks: func(repo *MockRepository, sender *MockSender) {
+				// nothing is published until the sent state is stored
+				msg := createTestMessage("msg-1", "Hello World")
+				repo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+				repo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+				sender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil)
+				repo.On("Save", mock.Anything, msg).Return(errors.New("database connection failed"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			mockSender := &MockSender{}
+			tt.setupMocks(mockRepo, mockSender)
+			var got []string
+			record := func(stage string) message.Hook {
+				return func(_ context.Context, msg *message.Message, cause error) {
+					if cause != nil {
+						got = append(got, stage+" "+msg.ID+": "+cause.Error())
+						return
+					}
+					got = append(got, stage+" "+msg.ID)
+				}
+			}
+			hooks := &message.Hooks{}
+			hooks.OnQueued(record("queued"))
+			hooks.OnSent(record("sent"))
+			hooks.OnFailed(record("failed"))
+			hooks.OnDeadLettered(record("dead-lettered"))
+
+			app := application.NewApplication(mockRepo, mockSender,
+				application.WithHooks(hooks),
+				application.WithRateLimit(0, 1),
+			)
+
+			_ = tt.run(app)
+
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestApplication_CreateSubscription(t *testing.T) {
+	mockSubs := &MockSubscriptionRepository{}
+	mockSubs.On("CreateSubscription", mock.Anything, mock.Anything).Return(&message.Subscription{ID: "1"}, nil)
+
+	app := application.NewApplication(&MockRepository{}, &MockSender{},
+		application.WithSubscriptions(mockSubs, &MockNotifier{}),
+	)
+
+	_, err := app.CreateSubscription(context.Background(), &message.Subscription{URL: "https://example.com"})
+	require.NoError(t, err)
+	_, err = app.CreateSubscription(context.Background(), &message.Subscription{URL: "https://example.com", Secret: "my-own-secret-value"})
+	require.NoError(t, err)
+
+	require.Len(t, mockSubs.Calls, 2)
+	generated := mockSubs.Calls[0].Arguments.Get(1).(*message.Subscription)
+	assert.Len(t, generated.Secret, 64, "a random 32 byte secret is generated")
+	given := mockSubs.Calls[1].Arguments.Get(1).(*message.Subscription)
+	assert.Equal(t, "my-own-secret-value", given.Secret)
+}
+
+func TestApplication_Subscriptions_NotConfigured(t *testing.T) {
+	app := application.NewApplication(&MockRepository{}, &MockSender{})
+
+	_, err := app.CreateSubscription(context.Background(), &message.Subscription{})
+	assert.ErrorIs(t, err, application.ErrSubscriptionsNotConfigured)
+	_, err = app.ListSubscriptions(context.Background())
+	assert.ErrorIs(t, err, application.ErrSubscriptionsNotConfigured)
+	assert.ErrorIs(t, app.DeleteSubscription(context.Background(), "1"), application.ErrSubscriptionsNotConfigured)
+}
+
+func TestApplication_SendNext_BacksOffWhenRateLimited(t *testing.T) {
+	mockRepo := &MockRepository{}
+	smsSender := &MockSender{}
+	emailSender := &MockSender{}
+	limited := createTestMessage("msg-1", "First")
+	limited.Attempts = application.DefaultRetryPolicy.MaxAttempts - 1
+	waiting := createTestMessage("msg-2", "Second")
+	email := &message.Message{ID: "msg-3", To: "ada@example.com", Content: "Third", Channel: message.ChannelEmail}
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(limited, nil).Once()
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(waiting, nil).Once()
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(email, nil).Once()
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	smsSender.On("Send", mock.Anything, limited).Return(nil, &message.RateLimitedError{
+		RetryAfter: 2 * time.Minute,
+		Err:        errors.New("received status 429"),
+	})
+	emailSender.On("Send", mock.Anything, email).Return(createSendResult("sent-msg-3"), nil)
+	mockRepo.On("SaveAttempts", mock.Anything, limited).Return(nil)
+	mockRepo.On("Save", mock.Anything, email).Return(nil)
+	app := application.NewApplication(mockRepo, smsSender,
+		application.WithSender(message.ChannelEmail, emailSender),
+		application.WithRateLimit(0, 1),
+	)
+
+	start := time.Now()
+	err := app.SendNext(context.Background())
+	var rateLimited *message.RateLimitedError
+	require.ErrorAs(t, err, &rateLimited)
+	// the refusal does not count as a failed attempt
+	assert.Equal(t, application.DefaultRetryPolicy.MaxAttempts-1, limited.Attempts)
+	assert.False(t, limited.IsFailed())
+	assert.Equal(t, "received status 429", limited.LastError)
+	assert.WithinDuration(t, start.Add(2*time.Minute), limited.NextAttemptAt, time.Second)
+
+	// further SMS wait for the provider to accept messages again, other channels are sent meanwhile
+	require.NoError(t, app.SendNext(context.Background()))
+	require.NoError(t, app.SendNext(context.Background()))
+	smsSender.AssertNotCalled(t, "Send", mock.Anything, waiting)
+	mockRepo.AssertNotCalled(t, "Claim", mock.Anything, waiting, mock.Anything)
+	assert.True(t, email.IsSent())
+}
+
+func TestApplication_SendNext_RateLimitedWithoutRetryAfter(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(nil, &message.RateLimitedError{Err: errors.New("received status 429")})
+	mockRepo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+	policy := application.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithRetryPolicy(policy),
+		application.WithRateLimit(0, 1),
+	)
+
+	start := time.Now()
+	require.Error(t, app.SendNext(context.Background()))
+
+	assert.Equal(t, 0, msg.Attempts)
+	assert.WithinDuration(t, start.Add(time.Minute), msg.NextAttemptAt, time.Second)
+}
+
+func TestApplication_SendNext_SchedulesRetry(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider down"))
+	mockRepo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+	policy := application.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithRetryPolicy(policy),
+		application.WithRateLimit(0, 1),
+	)
+
+	start := time.Now()
+	require.Error(t, app.SendNext(context.Background()))
+	assert.Equal(t, 1, msg.Attempts)
+	assert.Equal(t, "provider down", msg.LastError)
+	assert.WithinDuration(t, start.Add(time.Minute), msg.NextAttemptAt, time.Second)
+	assert.False(t, msg.IsFailed())
+
+	require.Error(t, app.SendNext(context.Background()))
+	assert.Equal(t, 2, msg.Attempts)
+	assert.WithinDuration(t, start.Add(2*time.Minute), msg.NextAttemptAt, time.Second)
+
+	require.Error(t, app.SendNext(context.Background()))
+	assert.Equal(t, 3, msg.Attempts)
+	assert.True(t, msg.IsFailed())
+	assert.True(t, msg.NextAttemptAt.IsZero())
+	mockRepo.AssertNumberOfCalls(t, "SaveAttempts", 3)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestApplication_SendNext_RecordsAttempt(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	mockAttempts := &MockAttemptRepository{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider down")).Once()
+	mockSender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil).Once()
+	mockRepo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+	mockRepo.On("Save", mock.Anything, msg).Return(nil)
+	var recorded []*message.Attempt
+	mockAttempts.On("RecordAttempts", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		recorded = append(recorded, args.Get(1).([]*message.Attempt)...)
+	}).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithRateLimit(0, 1), application.WithAttemptLog(mockAttempts))
+
+	start := time.Now()
+	require.Error(t, app.SendNext(context.Background()))
+	require.NoError(t, app.SendNext(context.Background()))
+
+	require.Len(t, recorded, 2)
+	assert.Equal(t, "msg-1", recorded[0].MessageID)
+	assert.Equal(t, "provider down", recorded[0].Error)
+	assert.WithinDuration(t, start, recorded[0].StartedAt, time.Second)
+	assert.True(t, recorded[1].Succeeded())
+}
+
+func TestApplication_SendNext_GivesUpPermanentRejection(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		failed bool
+	}{
+		{name: "rejected", status: http.StatusBadRequest, failed: true},
+		{name: "request_timeout", status: http.StatusRequestTimeout, failed: false},
+		{name: "server_error", status: http.StatusBadGateway, failed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			mockSender := &MockSender{}
+			msg := createTestMessage("msg-1", "Hello World")
+			mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+			mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+			mockSender.On("Send", mock.Anything, msg).Return(nil, &message.ProviderError{StatusCode: tt.status, Code: "21211"})
+			mockRepo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+			app := application.NewApplication(mockRepo, mockSender, application.WithRateLimit(0, 1))
+
+			require.Error(t, app.SendNext(context.Background()))
+
+			assert.Equal(t, 1, msg.Attempts)
+			assert.Equal(t, tt.failed, msg.IsFailed())
+		})
+	}
+}
+
+func TestApplication_SendNext_SaveAttemptsError(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider down"))
+	mockRepo.On("SaveAttempts", mock.Anything, msg).Return(errors.New("database connection failed"))
+	app := application.NewApplication(mockRepo, mockSender)
+
+	err := app.SendNext(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "saving failed delivery attempt: database connection failed")
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := application.RetryPolicy{MaxAttempts: 10, BaseDelay: 30 * time.Second, MaxDelay: 5 * time.Minute}
+
+	assert.Equal(t, 30*time.Second, policy.Delay(1))
+	assert.Equal(t, time.Minute, policy.Delay(2))
+	assert.Equal(t, 4*time.Minute, policy.Delay(4))
+	assert.Equal(t, 5*time.Minute, policy.Delay(5), "delays are capped")
+	assert.Equal(t, 5*time.Minute, policy.Delay(60), "large attempt counts do not overflow")
+
+	policy.Jitter = 0.5
+	for range 100 {
+		delay := policy.Delay(1)
+		assert.GreaterOrEqual(t, delay, 15*time.Second)
+		assert.LessOrEqual(t, delay, 45*time.Second)
+	}
+}
+
+func TestApplication_RequeueMessage(t *testing.T) {
+	failedMessage := func() *message.Message {
+		msg := createTestMessage("msg-1", "Hello")
+		msg.SetFailed(errors.New("provider down"), time.Now())
+		return msg
+	}
+	tests := []struct {
+		name          string
+		setupMocks    func(*MockRepository)
+		expectedError error
+	}{
+		{
+			name: "failed_message",
+			setupMocks: func(repo *MockRepository) {
+				repo.On("GetByID", mock.Anything, "msg-1").Return(failedMessage(), nil)
+				repo.On("Requeue", mock.Anything, "msg-1").Return(nil)
+			},
+		},
+		{
+			name: "not_found",
+			setupMocks: func(repo *MockRepository) {
+				repo.On("GetByID", mock.Anything, "msg-1").Return(nil, nil)
+			},
+			expectedError: message.ErrMessageNotFound,
+		},
+		{
+			name: "not_failed",
+			setupMocks: func(repo *MockRepository) {
+				repo.On("GetByID", mock.Anything, "msg-1").Return(createTestMessage("msg-1", "Hello"), nil)
+			},
+			expectedError: message.ErrMessageNotFailed,
+		},
+		{
+			name: "requeued_meanwhile",
+			setupMocks: func(repo *MockRepository) {
+				repo.On("GetByID", mock.Anything, "msg-1").Return(failedMessage(), nil)
+				repo.On("Requeue", mock.Anything, "msg-1").Return(message.ErrMessageNotFound)
+			},
+			expectedError: message.ErrMessageNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			tt.setupMocks(mockRepo)
+			app := application.NewApplication(mockRepo, &MockSender{})
+
+			err := app.RequeueMessage(context.Background(), "msg-1")
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_SendAllUnsent_Workers(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	messages := make([]*message.Message, 8)
+	for i := range messages {
+		messages[i] = createTestMessage(fmt.Sprintf("msg-%d", i), fmt.Sprintf("Message %d", i))
+	}
+	mockRepo.On("GetAllUnsent", mock.Anything).Return(messages, nil)
+	// messages are matched by any argument, as the mocks would otherwise print messages other workers are updating
+	// a slow provider is where concurrent sends pay off
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, mock.Anything).After(500*time.Millisecond).Return(createSendResult("sent-msg"), nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender, application.WithWorkers(4), application.WithRateLimit(0, 1))
+
+	startTime := time.Now()
+	err := app.SendAllUnsent(context.Background())
+	executionTime := time.Since(startTime)
+
+	require.NoError(t, err)
+	// two rounds of four concurrent sends
+	assert.Less(t, executionTime, 1500*time.Millisecond)
+	mockSender.AssertNumberOfCalls(t, "Send", len(messages))
+	mockRepo.AssertNumberOfCalls(t, "Save", len(messages))
+}
+
+func TestApplication_SendAllUnsent_WorkersStopAfterError(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	messages := make([]*message.Message, 6)
+	for i := range messages {
+		messages[i] = createTestMessage(fmt.Sprintf("msg-%d", i), fmt.Sprintf("Message %d", i))
+	}
+	mockRepo.On("GetAllUnsent", mock.Anything).Return(messages, nil)
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, mock.Anything).Return(nil, errors.New("provider down"))
+	mockRepo.On("SaveAttempts", mock.Anything, mock.Anything).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender, application.WithWorkers(2))
+
+	err := app.SendAllUnsent(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sending message: provider down")
+	// only the messages taken by a worker before the first failure are tried
+	assert.LessOrEqual(t, len(mockSender.Calls), 3)
+}
+
+func TestApplication_RateLimitIsShared(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	messages := []*message.Message{createTestMessage("msg-1", "First"), createTestMessage("msg-2", "Second")}
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(createTestMessage("msg-0", "Next"), nil)
+	mockRepo.On("GetAllUnsent", mock.Anything).Return(messages, nil)
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, mock.Anything).Return(createSendResult("sent-msg"), nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender, application.WithRateLimit(10, 1))
+
+	startTime := time.Now()
+	require.NoError(t, app.SendNext(context.Background()))
+	require.NoError(t, app.SendAllUnsent(context.Background()))
+	executionTime := time.Since(startTime)
+
+	// three sends at ten per second: the first right away, then one every 100ms
+	assert.GreaterOrEqual(t, executionTime, 190*time.Millisecond)
+	assert.Less(t, executionTime, time.Second)
+}
+
+func TestApplication_SendAllUnsent_StopsWhenContextIsCancelled(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	messages := []*message.Message{
+		createTestMessage("msg-1", "First"),
+		createTestMessage("msg-2", "Second"),
+		createTestMessage("msg-3", "Third"),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mockRepo.On("GetAllUnsent", mock.Anything).Return(messages, nil)
+	// shutdown begins while the first message is being sent
+	mockRepo.On("Claim", mock.Anything, messages[0], mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, messages[0]).Run(func(mock.Arguments) { cancel() }).
+		Return(createSendResult("sent-msg-1"), nil)
+	mockRepo.On("Save", mock.Anything, messages[0]).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender, application.WithRateLimit(0, 1))
+
+	err := app.SendAllUnsent(ctx)
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "stopped sending unsent messages")
+	mockSender.AssertNumberOfCalls(t, "Send", 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_SendAllUnsent_Batches(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	messages := []*message.Message{
+		createTestMessage("msg-1", "First"),
+		createTestMessage("msg-2", "Second"),
+		createTestMessage("msg-3", "Third"),
+	}
+	mockRepo.On("GetAllUnsent", mock.Anything).Return(messages, nil)
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	// the first batch is sent at once, with a failure for its second message
+	mockSender.On("SendBatch", mock.Anything, messages[:2]).Return([]message.BatchResult{
+		{Result: createSendResult("sent-msg-1")},
+		{Err: errors.New("invalid recipient")},
+	})
+	mockRepo.On("SaveAll", mock.Anything, messages[:1]).Return(nil, nil)
+	mockRepo.On("SaveAttempts", mock.Anything, messages[1]).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithBatchSize(2), application.WithRateLimit(0, 1))
+
+	err := app.SendAllUnsent(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sending message: invalid recipient")
+	assert.True(t, messages[0].IsSent())
+	assert.Equal(t, 1, messages[1].Attempts)
+	// the failure stops handing out the remaining batch
+	mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_SendAllUnsent_RecordsBatchAttempts(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	mockAttempts := &MockAttemptRepository{}
+	messages := []*message.Message{
+		createTestMessage("msg-1", "First"),
+		createTestMessage("msg-2", "Second"),
+	}
+	mockRepo.On("GetAllUnsent", mock.Anything).Return(messages, nil)
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	mockSender.On("SendBatch", mock.Anything, messages).Return([]message.BatchResult{
+		{Result: createSendResult("sent-msg-1")},
+		{Err: &message.ProviderError{StatusCode: 503}},
+	})
+	mockRepo.On("SaveAll", mock.Anything, messages[:1]).Return(nil, nil)
+	mockRepo.On("SaveAttempts", mock.Anything, messages[1]).Return(nil)
+	var recorded []*message.Attempt
+	// failing to record the history does not hold up storing the outcome
+	mockAttempts.On("RecordAttempts", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).([]*message.Attempt)
+	}).Return(errors.New("connection reset"))
+	app := application.NewApplication(mockRepo, mockSender, application.WithBatchSize(2),
+		application.WithRateLimit(0, 1), application.WithAttemptLog(mockAttempts))
+
+	err := app.SendAllUnsent(context.Background())
+
+	require.Error(t, err)
+	require.Len(t, recorded, 2)
+	assert.Equal(t, "msg-1", recorded[0].MessageID)
+	assert.True(t, recorded[0].Succeeded())
+	assert.Equal(t, "msg-2", recorded[1].MessageID)
+	assert.Equal(t, 503, recorded[1].StatusCode)
+	assert.Equal(t, recorded[0].StartedAt, recorded[1].StartedAt)
+	mockAttempts.AssertNumberOfCalls(t, "RecordAttempts", 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_SendAllUnsent_BatchSavesSentStateOnce(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	messages := []*message.Message{
+		createTestMessage("msg-1", "First"),
+		createTestMessage("msg-2", "Second"),
+	}
+	mockRepo.On("GetAllUnsent", mock.Anything).Return(messages, nil)
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	mockSender.On("SendBatch", mock.Anything, messages).Return([]message.BatchResult{
+		{Result: createSendResult("sent-msg-1")},
+		{Result: createSendResult("sent-msg-2")},
+	})
+	// the first attempt fails transiently, the second finds msg-2 taken over by another instance
+	mockRepo.On("SaveAll", mock.Anything, messages).Return(nil, errors.New("connection reset")).Once()
+	mockRepo.On("SaveAll", mock.Anything, messages).Return(messages[1:], nil).Once()
+	var notified []string
+	hooks := &message.Hooks{}
+	hooks.OnSent(func(_ context.Context, msg *message.Message, _ error) {
+		notified = append(notified, msg.ID)
+	})
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithBatchSize(2), application.WithRateLimit(0, 1), application.WithHooks(hooks))
+
+	err := app.SendAllUnsent(context.Background())
+
+	assert.ErrorIs(t, err, message.ErrClaimLost)
+	assert.Equal(t, []string{"msg-1"}, notified)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_SendNext_RetriesSavingDeliveredMessage(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil).Once()
+	mockRepo.On("Save", mock.Anything, msg).Return(errors.New("connection reset")).Once()
+	mockRepo.On("Save", mock.Anything, msg).Return(nil).Once()
+	app := application.NewApplication(mockRepo, mockSender)
+
+	err := app.SendNext(context.Background())
+
+	require.NoError(t, err)
+	mockRepo.AssertNumberOfCalls(t, "Save", 2)
+}
+
+func TestApplication_SendNext_KeepsUnsavedDeliveryInOutbox(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil).Once()
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil).Once()
+	mockRepo.On("Save", mock.Anything, msg).Return(errors.New("database connection failed")).Times(4)
+	app := application.NewApplication(mockRepo, mockSender, application.WithRateLimit(0, 1))
+
+	err := app.SendNext(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database connection failed")
+
+	// nothing else is sent while the delivery cannot be stored
+	err = app.SendNext(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "saving delivered message msg-1")
+
+	mockRepo.On("Save", mock.Anything, msg).Return(nil).Once()
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(nil, nil).Once()
+	require.NoError(t, app.SendNext(context.Background()))
+
+	mockRepo.AssertNumberOfCalls(t, "Save", 5)
+	mockRepo.AssertNumberOfCalls(t, "GetNextUnsent", 2)
+	mockSender.AssertNumberOfCalls(t, "Send", 1)
+	assert.True(t, msg.IsSent())
+}
+
+func TestApplication_SendN(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	messages := []*message.Message{
+		createTestMessage("msg-1", "First"),
+		createTestMessage("msg-2", "Second"),
+	}
+	mockRepo.On("GetUnsent", mock.Anything, 3).Return(messages, nil).Once()
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, messages[0]).Return(createSendResult("sent-msg-1"), nil)
+	mockSender.On("Send", mock.Anything, messages[1]).Return(createSendResult("sent-msg-2"), nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender, application.WithRateLimit(0, 1))
+
+	err := app.SendN(context.Background(), 3)
+
+	require.NoError(t, err)
+	assert.True(t, messages[0].IsSent())
+	assert.True(t, messages[1].IsSent())
+	mockRepo.AssertNotCalled(t, "GetNextUnsent", mock.Anything)
+	mockRepo.AssertExpectations(t)
+	mockSender.AssertExpectations(t)
+}
+
+func TestApplication_SendN_NonPositive(t *testing.T) {
+	mockRepo := &MockRepository{}
+	app := application.NewApplication(mockRepo, &MockSender{})
+
+	require.NoError(t, app.SendN(context.Background(), 0))
+
+	mockRepo.AssertNotCalled(t, "GetUnsent", mock.Anything, mock.Anything)
+}
+
+func TestApplication_SendN_RepositoryError(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockRepo.On("GetUnsent", mock.Anything, 2).Return(([]*message.Message)(nil), errors.New("database connection failed"))
+	app := application.NewApplication(mockRepo, &MockSender{})
+
+	err := app.SendN(context.Background(), 2)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "getting unsent messages: database connection failed")
+}
+
+func TestApplication_SendN_ClaimOnRead(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "First")
+	msg.ClaimToken = "t0k3n"
+	start := time.Now()
+	mockRepo.On("ClaimUnsent", mock.Anything, 3, mock.MatchedBy(func(token string) bool { return len(token) == 32 }),
+		mock.MatchedBy(func(until time.Time) bool { return !until.Before(start.Add(5 * time.Minute)) })).
+		Return([]*message.Message{msg}, nil).Once()
+	mockSender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil)
+	mockRepo.On("Save", mock.Anything, msg).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender, application.WithRateLimit(0, 1),
+		application.WithClaimLease(5*time.Minute), application.WithClaimOnRead(true))
+
+	err := app.SendN(context.Background(), 3)
+
+	require.NoError(t, err)
+	assert.True(t, msg.IsSent())
+	assert.Equal(t, "t0k3n", msg.ClaimToken, "messages are delivered under the claim they were read with")
+	mockRepo.AssertNotCalled(t, "GetUnsent", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Claim", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+	mockSender.AssertExpectations(t)
+}
+
+func TestApplication_SendN_ClaimOnRead_ExpiresStaleMessage(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Your code is 123456")
+	msg.ExpiresAt = time.Now().Add(-time.Minute)
+	msg.ClaimToken = "t0k3n"
+	mockRepo.On("ClaimUnsent", mock.Anything, 3, mock.Anything, mock.Anything).Return([]*message.Message{msg}, nil).Once()
+	// the message is expired under the claim it was read with, so the claim does not keep it pending
+	mockRepo.On("Expire", mock.Anything, mock.MatchedBy(func(m *message.Message) bool {
+		return m == msg && m.ClaimToken == "t0k3n"
+	})).Return(nil).Once()
+	app := application.NewApplication(mockRepo, mockSender, application.WithClaimOnRead(true))
+
+	err := app.SendN(context.Background(), 3)
+
+	require.NoError(t, err)
+	assert.True(t, msg.IsExpired())
+	mockRepo.AssertExpectations(t)
+	mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestApplication_CancelMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		stored        func() *message.Message
+		canceled      bool
+		expectedError error
+	}{
+		{
+			name:     "pending_message",
+			stored:   func() *message.Message { return createTestMessage("msg-1", "Hello") },
+			canceled: true,
+		},
+		{
+			name:          "not_found",
+			stored:        func() *message.Message { return nil },
+			expectedError: message.ErrMessageNotFound,
+		},
+		{
+			name: "already_sent",
+			stored: func() *message.Message {
+				msg := createTestMessage("msg-1", "Hello")
+				_ = msg.SetSent("sent-msg-1", time.Now())
+				return msg
+			},
+			expectedError: message.ErrMessageNotPending,
+		},
+		{
+			name: "already_canceled",
+			stored: func() *message.Message {
+				msg := createTestMessage("msg-1", "Hello")
+				msg.SetCanceled(time.Now())
+				return msg
+			},
+			expectedError: message.ErrMessageNotPending,
+		},
+		{
+			// the sender claimed the message between reading and canceling it
+			name:          "claimed_meanwhile",
+			stored:        func() *message.Message { return createTestMessage("msg-1", "Hello") },
+			canceled:      false,
+			expectedError: message.ErrMessageBeingSent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			stored := tt.stored()
+			mockRepo.On("GetByID", mock.Anything, "msg-1").Return(stored, nil)
+			if stored != nil && stored.IsPending() {
+				mockRepo.On("Cancel", mock.Anything, stored).Return(tt.canceled, nil)
+			}
+			app := application.NewApplication(mockRepo, &MockSender{})
+
+			err := app.CancelMessage(context.Background(), "msg-1")
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.True(t, stored.IsCanceled())
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_DeleteMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		stored        func() *message.Message
+		expectedError error
+	}{
+		{
+			name: "sent_message",
+			stored: func() *message.Message {
+				msg := createTestMessage("msg-1", "Hello")
+				_ = msg.SetSent("sent-msg-1", time.Now())
+				return msg
+			},
+		},
+		{
+			name:          "not_found",
+			stored:        func() *message.Message { return nil },
+			expectedError: message.ErrMessageNotFound,
+		},
+		{
+			name:          "pending_message",
+			stored:        func() *message.Message { return createTestMessage("msg-1", "Hello") },
+			expectedError: message.ErrMessagePending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			stored := tt.stored()
+			mockRepo.On("GetByID", mock.Anything, "msg-1").Return(stored, nil)
+			if stored != nil && !stored.IsPending() {
+				mockRepo.On("Delete", mock.Anything, "msg-1").Return(nil)
+			}
+			app := application.NewApplication(mockRepo, &MockSender{})
+
+			err := app.DeleteMessage(context.Background(), "msg-1")
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_SendNext_Deduplication(t *testing.T) {
+	window := 10 * time.Minute
+	tests := []struct {
+		name       string
+		setupMocks func(*message.Message, *MockRepository, *MockSender, *MockDeduplicator)
+		wantErr    string
+		check      func(*testing.T, *message.Message)
+	}{
+		{
+			name: "unique",
+			setupMocks: func(msg *message.Message, repo *MockRepository, sender *MockSender, dedup *MockDeduplicator) {
+				dedup.On("Reserve", mock.Anything, msg, window).Return(true, nil)
+				sender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil)
+				repo.On("Save", mock.Anything, msg).Return(nil)
+			},
+			check: func(t *testing.T, msg *message.Message) {
+				assert.True(t, msg.IsSent())
+			},
+		},
+		{
+			name: "duplicate",
+			setupMocks: func(msg *message.Message, repo *MockRepository, sender *MockSender, dedup *MockDeduplicator) {
+				dedup.On("Reserve", mock.Anything, msg, window).Return(false, nil)
+				repo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+			},
+			check: func(t *testing.T, msg *message.Message) {
+				assert.True(t, msg.IsFailed())
+				assert.Equal(t, message.ErrDuplicateMessage.Error(), msg.LastError)
+			},
+		},
+		{
+			name: "send_failed_releases_reservation",
+			setupMocks: func(msg *message.Message, repo *MockRepository, sender *MockSender, dedup *MockDeduplicator) {
+				dedup.On("Reserve", mock.Anything, msg, window).Return(true, nil)
+				sender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider down"))
+				dedup.On("Release", mock.Anything, msg).Return(nil)
+				repo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+			},
+			wantErr: "provider down",
+			check: func(t *testing.T, msg *message.Message) {
+				assert.Equal(t, 1, msg.Attempts)
+				assert.False(t, msg.IsFailed())
+			},
+		},
+		{
+			name: "reservation_failed",
+			setupMocks: func(msg *message.Message, repo *MockRepository, sender *MockSender, dedup *MockDeduplicator) {
+				dedup.On("Reserve", mock.Anything, msg, window).Return(false, errors.New("redis down"))
+			},
+			wantErr: "checking for duplicate message: redis down",
+			check: func(t *testing.T, msg *message.Message) {
+				assert.False(t, msg.IsSent())
+				assert.False(t, msg.IsFailed())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			mockSender := &MockSender{}
+			mockDedup := &MockDeduplicator{}
+			msg := createTestMessage("msg-1", "Hello World")
+			mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+			mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+			tt.setupMocks(msg, mockRepo, mockSender, mockDedup)
+			app := application.NewApplication(mockRepo, mockSender, application.WithDeduplication(mockDedup, window))
+
+			err := app.SendNext(context.Background())
+
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			tt.check(t, msg)
+			mockRepo.AssertExpectations(t)
+			mockSender.AssertExpectations(t)
+			mockDedup.AssertExpectations(t)
+		})
+	}
+}
+
+func TestParseSendWindow(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		spec     string
+		timezone string
+		want     application.SendWindow
+		wantErr  bool
+	}{
+		{name: "empty", spec: "", timezone: "UTC", want: application.SendWindow{}},
+		{name: "daytime", spec: "09:00-21:00", timezone: "Europe/Istanbul",
+			want: application.SendWindow{Start: 9 * time.Hour, End: 21 * time.Hour, Location: istanbul}},
+		{name: "spaces", spec: "09:30 - 17:45", timezone: "UTC",
+			want: application.SendWindow{Start: 9*time.Hour + 30*time.Minute, End: 17*time.Hour + 45*time.Minute, Location: time.UTC}},
+		{name: "no separator", spec: "09:00", timezone: "UTC", wantErr: true},
+		{name: "invalid time", spec: "9am-9pm", timezone: "UTC", wantErr: true},
+		{name: "unknown time zone", spec: "09:00-21:00", timezone: "Mars/Olympus", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := application.ParseSendWindow(tt.spec, tt.timezone)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSendWindow_Contains(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	require.NoError(t, err)
+	daytime := application.SendWindow{Start: 9 * time.Hour, End: 21 * time.Hour, Location: istanbul}
+	overnight := application.SendWindow{Start: 21 * time.Hour, End: 6 * time.Hour, Location: time.UTC}
+	tests := []struct {
+		name   string
+		window application.SendWindow
+		at     time.Time
+		want   bool
+	}{
+		{name: "zero window", window: application.SendWindow{}, at: time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC), want: true},
+		// 06:30 UTC is 09:30 in Istanbul
+		{name: "inside in time zone", window: daytime, at: time.Date(2026, 10, 16, 6, 30, 0, 0, time.UTC), want: true},
+		{name: "before start", window: daytime, at: time.Date(2026, 10, 16, 5, 59, 59, 0, time.UTC), want: false},
+		{name: "end is exclusive", window: daytime, at: time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC), want: false},
+		{name: "overnight before midnight", window: overnight, at: time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC), want: true},
+		{name: "overnight after midnight", window: overnight, at: time.Date(2026, 10, 16, 5, 0, 0, 0, time.UTC), want: true},
+		{name: "overnight daytime", window: overnight, at: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.window.Contains(tt.at))
+		})
+	}
+}
+
+// closedSendWindow returns a send window that opens an hour from now.
+func closedSendWindow() application.SendWindow {
+	now := time.Now().UTC()
+	offset := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	return application.SendWindow{
+		Start:    (offset + time.Hour) % (24 * time.Hour),
+		End:      (offset + 2*time.Hour) % (24 * time.Hour),
+		Location: time.UTC,
+	}
+}
+
+func TestApplication_SendN_HoldsMessagesOutsideSendWindow(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	mockRepo.On("CountUnsent", mock.Anything).Return(int64(12), nil)
+	var held int64
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithSendWindow(closedSendWindow(), func(n int64) { held = n }))
+
+	err := app.SendN(context.Background(), 5)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), held)
+	mockRepo.AssertNotCalled(t, "GetUnsent", mock.Anything, mock.Anything)
+	mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestApplication_SendNext_SendsInsideSendWindow(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil)
+	mockRepo.On("Save", mock.Anything, msg).Return(nil)
+	window := closedSendWindow()
+	// the complement of a closed window is open
+	window.Start, window.End = window.End, window.Start
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithSendWindow(window, func(int64) { t.Error("messages held inside the send window") }))
+
+	require.NoError(t, app.SendNext(context.Background()))
+
+	assert.True(t, msg.IsSent())
+}
+
+func TestTemplates_Render(t *testing.T) {
+	templates, err := application.NewTemplates(map[string]string{"welcome": "Hi {{.name}}, your code is {{.code}}"})
+	require.NoError(t, err)
+
+	content, err := templates.Render("welcome", map[string]string{"name": "Ada", "code": "1234"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi Ada, your code is 1234", content)
+
+	_, err = templates.Render("welcome", map[string]string{"name": "Ada"})
+	assert.ErrorContains(t, err, `rendering template "welcome"`)
+
+	_, err = templates.Render("missing", nil)
+	assert.ErrorIs(t, err, message.ErrUnknownTemplate)
+
+	var none *application.Templates
+	assert.False(t, none.Has("welcome"))
+}
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "welcome.tmpl"), []byte("Hi {{.name}}\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a template"), 0o600))
+
+	templates, err := application.LoadTemplates(dir)
+	require.NoError(t, err)
+
+	assert.True(t, templates.Has("welcome"))
+	assert.False(t, templates.Has("notes"))
+	content, err := templates.Render("welcome", map[string]string{"name": "Ada"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi Ada", content)
+}
+
+func TestApplication_SendNext_RendersTemplate(t *testing.T) {
+	templates, err := application.NewTemplates(map[string]string{"welcome": "Hi {{.name}}"})
+	require.NoError(t, err)
+	tests := []struct {
+		name       string
+		variables  map[string]string
+		setupMocks func(*message.Message, *MockRepository, *MockSender)
+		check      func(*testing.T, *message.Message)
+	}{
+		{
+			name:      "rendered",
+			variables: map[string]string{"name": "Ada"},
+			setupMocks: func(msg *message.Message, repo *MockRepository, sender *MockSender) {
+				sender.On("Send", mock.Anything, mock.MatchedBy(func(m *message.Message) bool {
+					return m.Content == "Hi Ada"
+				})).Return(createSendResult("sent-msg-1"), nil)
+				repo.On("Save", mock.Anything, msg).Return(nil)
+			},
+			check: func(t *testing.T, msg *message.Message) {
+				assert.True(t, msg.IsSent())
+				assert.Equal(t, "Hi Ada", msg.Content)
+			},
+		},
+		{
+			name:      "missing variable",
+			variables: map[string]string{},
+			setupMocks: func(msg *message.Message, repo *MockRepository, sender *MockSender) {
+				repo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+			},
+			check: func(t *testing.T, msg *message.Message) {
+				assert.True(t, msg.IsFailed())
+				assert.Contains(t, msg.LastError, `rendering template "welcome"`)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			mockSender := &MockSender{}
+			msg := &message.Message{ID: "msg-1", To: "+905551234567", Template: "welcome", Variables: tt.variables}
+			mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+			mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+			tt.setupMocks(msg, mockRepo, mockSender)
+			app := application.NewApplication(mockRepo, mockSender, application.WithTemplates(templates))
+
+			require.NoError(t, app.SendNext(context.Background()))
+
+			tt.check(t, msg)
+			mockRepo.AssertExpectations(t)
+			mockSender.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_CreateMessage_Templated(t *testing.T) {
+	templates, err := application.NewTemplates(map[string]string{"welcome": "Hi {{.name}}"})
+	require.NoError(t, err)
+	mockRepo := &MockRepository{}
+	msg := &message.Message{To: "+905551234567", Template: "welcome", Variables: map[string]string{"name": "Ada"}, IdempotencyKey: "key-1"}
+	// the replayed message was sent meanwhile, so its content is rendered
+	stored := &message.Message{ID: "42", To: msg.To, Content: "Hi Ada", Template: "welcome", Variables: msg.Variables}
+	mockRepo.On("Create", mock.Anything, msg).Return(stored, false, nil)
+	app := application.NewApplication(mockRepo, nil, application.WithTemplates(templates))
+
+	got, created, err := app.CreateMessage(context.Background(), msg)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, stored, got)
+
+	_, _, err = app.CreateMessage(context.Background(), &message.Message{To: msg.To, Template: "missing"})
+	assert.ErrorIs(t, err, message.ErrUnknownTemplate)
+	mockRepo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestApplication_SendAllUnsent_RoutesByChannel(t *testing.T) {
+	mockRepo := &MockRepository{}
+	smsSender := &MockSender{}
+	emailSender := &MockSender{}
+	sms1 := createTestMessage("msg-1", "First")
+	email := &message.Message{ID: "msg-2", To: "ada@example.com", Content: "Second", Channel: message.ChannelEmail}
+	sms2 := createTestMessage("msg-3", "Third")
+	mockRepo.On("GetAllUnsent", mock.Anything).Return([]*message.Message{sms1, email, sms2}, nil)
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	smsSender.On("SendBatch", mock.Anything, []*message.Message{sms1, sms2}).Return([]message.BatchResult{
+		{Result: createSendResult("sms-1")},
+		{Result: createSendResult("sms-3")},
+	})
+	emailSender.On("SendBatch", mock.Anything, []*message.Message{email}).Return([]message.BatchResult{
+		{Result: createSendResult("email-2")},
+	})
+	mockRepo.On("SaveAll", mock.Anything, []*message.Message{sms1, email, sms2}).Return(nil, nil).Once()
+	app := application.NewApplication(mockRepo, smsSender, application.WithSender(message.ChannelEmail, emailSender),
+		application.WithBatchSize(3), application.WithRateLimit(0, 1))
+
+	require.NoError(t, app.SendAllUnsent(context.Background()))
+
+	assert.Equal(t, "sms-1", sms1.MessageID)
+	assert.Equal(t, "email-2", email.MessageID)
+	assert.Equal(t, "sms-3", sms2.MessageID)
+	smsSender.AssertExpectations(t)
+	emailSender.AssertExpectations(t)
+}
+
+func TestApplication_SendNext_ChannelNotConfigured(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := &message.Message{ID: "msg-1", To: "device-token", Content: "hello", Channel: message.ChannelPush}
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockRepo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender)
+
+	require.NoError(t, app.SendNext(context.Background()))
+
+	assert.True(t, msg.IsFailed())
+	assert.Equal(t, message.ErrChannelNotConfigured.Error(), msg.LastError)
+	mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_CreateMessage_ChannelNotConfigured(t *testing.T) {
+	mockRepo := &MockRepository{}
+	app := application.NewApplication(mockRepo, &MockSender{})
+
+	_, _, err := app.CreateMessage(context.Background(),
+		&message.Message{To: "ada@example.com", Content: "hello", Channel: message.ChannelEmail})
+
+	assert.ErrorIs(t, err, message.ErrChannelNotConfigured)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestApplication_SendNext_DefersOverDailyQuota(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	quota := &MockQuotaCounter{}
+	first := &message.Message{ID: "msg-1", Content: "First", Tenant: "acme"}
+	second := &message.Message{ID: "msg-2", Content: "Second", Tenant: "acme"}
+	third := &message.Message{ID: "msg-3", Content: "Third", Tenant: "acme"}
+	unlimited := &message.Message{ID: "msg-4", Content: "Fourth", Tenant: "globex"}
+	for _, msg := range []*message.Message{first, second, third, unlimited} {
+		mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil).Once()
+	}
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	quota.On("Reserve", mock.Anything, "acme", mock.Anything, int64(1)).Return(true, nil).Once()
+	quota.On("Reserve", mock.Anything, "acme", mock.Anything, int64(1)).Return(false, nil).Once()
+	mockSender.On("Send", mock.Anything, first).Return(createSendResult("sent-msg-1"), nil)
+	mockSender.On("Send", mock.Anything, unlimited).Return(createSendResult("sent-msg-4"), nil)
+	mockRepo.On("Save", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("SaveAttempts", mock.Anything, mock.Anything).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithDailyQuota(quota, application.DailyQuota{Tenants: map[string]int64{"acme": 1}}),
+		application.WithRateLimit(0, 1),
+	)
+
+	for range 4 {
+		require.NoError(t, app.SendNext(context.Background()))
+	}
+
+	assert.True(t, first.IsSent())
+	tomorrow := application.DailyQuota{}.NextDay(time.Now())
+	for _, msg := range []*message.Message{second, third} {
+		// deferred messages wait for the quota to reset without counting an attempt
+		assert.False(t, msg.IsSent())
+		assert.Equal(t, 0, msg.Attempts)
+		assert.Equal(t, message.ErrDailyQuotaExceeded.Error(), msg.LastError)
+		assert.Equal(t, tomorrow, msg.NextAttemptAt)
+	}
+	// once used up, the quota is not asked again until the next day
+	quota.AssertNumberOfCalls(t, "Reserve", 2)
+	assert.True(t, unlimited.IsSent(), "tenants without a limit are not counted")
+	mockSender.AssertNotCalled(t, "Send", mock.Anything, second)
+	mockSender.AssertNotCalled(t, "Send", mock.Anything, third)
+}
+
+func TestApplication_SendNext_ReleasesQuotaOfFailedDelivery(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	quota := &MockQuotaCounter{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	quota.On("Reserve", mock.Anything, message.DefaultTenant, mock.Anything, int64(100)).Return(true, nil)
+	quota.On("Release", mock.Anything, message.DefaultTenant, mock.Anything).Return(nil)
+	mockSender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider down"))
+	mockRepo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithDailyQuota(quota, application.DailyQuota{Limit: 100}),
+		application.WithRateLimit(0, 1),
+	)
+
+	require.Error(t, app.SendNext(context.Background()))
+
+	quota.AssertExpectations(t)
+	assert.Equal(t, 1, msg.Attempts)
+}
+
+func TestApplication_Stats_ReportsQuotaUsage(t *testing.T) {
+	mockRepo := &MockRepository{}
+	quota := &MockQuotaCounter{}
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	daily := application.DailyQuota{Limit: 100, Tenants: map[string]int64{"acme": 500}, Location: loc}
+	mockRepo.On("GetStats", mock.Anything).Return(&message.Stats{Sent: 42}, nil)
+	hour := time.Now().Truncate(time.Hour)
+	mockRepo.On("CountSentByHour", mock.Anything, mock.AnythingOfType("time.Time")).
+		Return([]message.HourCount{{Hour: hour, Count: 7}}, nil)
+	quota.On("Used", mock.Anything, "acme", daily.Day(time.Now())).Return(int64(120), nil)
+	app := application.NewApplication(mockRepo, &MockSender{}, application.WithDailyQuota(quota, daily))
+
+	stats, err := app.Stats(message.WithTenant(context.Background(), "acme"))
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), stats.Sent)
+	assert.Equal(t, []message.HourCount{{Hour: hour, Count: 7}}, stats.SentByHour)
+	require.NotNil(t, stats.Quota)
+	assert.Equal(t, "acme", stats.Quota.Tenant)
+	assert.Equal(t, int64(500), stats.Quota.Limit)
+	assert.Equal(t, int64(120), stats.Quota.Used)
+	assert.Equal(t, int64(380), stats.Quota.Remaining())
+	assert.Equal(t, daily.NextDay(time.Now()), stats.Quota.ResetsAt)
+}
+
+func TestDailyQuota_Days(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	quota := application.DailyQuota{Limit: 10, Location: loc}
+	// 22:30 UTC is already the next day in UTC+3
+	now := time.Date(2026, 10, 16, 22, 30, 0, 0, time.UTC)
+
+	assert.True(t, time.Date(2026, 10, 17, 0, 0, 0, 0, loc).Equal(quota.Day(now)))
+	assert.True(t, time.Date(2026, 10, 18, 0, 0, 0, 0, loc).Equal(quota.NextDay(now)))
+	assert.True(t, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC).Equal(application.DailyQuota{}.NextDay(now)))
+}
+
+func TestApplication_SendNext_RejectsInvalidMessage(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	blocked := &message.Message{ID: "msg-1", To: "+19005551234", Content: "hello"}
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(blocked, nil)
+	mockRepo.On("Claim", mock.Anything, blocked, mock.Anything).Return(true, nil)
+	mockRepo.On("Reject", mock.Anything, blocked).Return(nil)
+	hooks := &message.Hooks{}
+	var deadLettered error
+	hooks.OnDeadLettered(func(_ context.Context, _ *message.Message, cause error) {
+		deadLettered = cause
+	})
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithValidators(message.BlockTerms("casino"), message.BlockPrefixes("+1900")),
+		application.WithHooks(hooks),
+		application.WithRateLimit(0, 1),
+	)
+
+	require.NoError(t, app.SendNext(context.Background()))
+
+	assert.True(t, blocked.IsRejected())
+	assert.Equal(t, 0, blocked.Attempts, "rejected messages are never attempted")
+	assert.Contains(t, blocked.LastError, message.ErrBlockedRecipient.Error())
+	assert.ErrorIs(t, deadLettered, message.ErrBlockedRecipient)
+	mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestApplication_SendNext_ValidatesRenderedContent(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	templates, err := application.NewTemplates(map[string]string{"promo": "Visit our {{.place}}"})
+	require.NoError(t, err)
+	msg := &message.Message{ID: "msg-1", To: "+905551234567", Template: "promo", Variables: map[string]string{"place": "Casino"}}
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockRepo.On("Reject", mock.Anything, msg).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithTemplates(templates),
+		application.WithValidators(message.BlockTerms("casino")),
+		application.WithRateLimit(0, 1),
+	)
+
+	require.NoError(t, app.SendNext(context.Background()))
+
+	assert.True(t, msg.IsRejected())
+	mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestApplication_ArchiveSentMessages(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockRepo.On("ArchiveSent", mock.Anything, 30*24*time.Hour, 100).Return(int64(100), nil).Twice()
+	mockRepo.On("ArchiveSent", mock.Anything, 30*24*time.Hour, 100).Return(int64(42), nil).Once()
+	app := application.NewApplication(mockRepo, &MockSender{},
+		application.WithRetention(application.Retention{After: 30 * 24 * time.Hour, BatchSize: 100}))
+
+	archived, err := app.ArchiveSentMessages(context.Background())
+
+	require.NoError(t, err)
+	// batches are archived until one comes back short
+	assert.Equal(t, int64(242), archived)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "DeleteSent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestApplication_ArchiveSentMessages_Deletes(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockRepo.On("DeleteSent", mock.Anything, time.Hour, 1000).Return(int64(7), nil)
+	app := application.NewApplication(mockRepo, &MockSender{},
+		application.WithRetention(application.Retention{After: time.Hour, Delete: true}))
+
+	archived, err := app.ArchiveSentMessages(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), archived)
+	mockRepo.AssertNotCalled(t, "ArchiveSent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestApplication_ArchiveSentMessages_ReportsArchivedBeforeError(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockRepo.On("ArchiveSent", mock.Anything, time.Hour, 10).Return(int64(10), nil).Once()
+	mockRepo.On("ArchiveSent", mock.Anything, time.Hour, 10).Return(int64(0), errors.New("connection reset")).Once()
+	app := application.NewApplication(mockRepo, &MockSender{},
+		application.WithRetention(application.Retention{After: time.Hour, BatchSize: 10}))
+
+	archived, err := app.ArchiveSentMessages(context.Background())
+
+	require.Error(t, err)
+	assert.Equal(t, int64(10), archived)
+}
+
+func TestApplication_ArchiveSentMessages_NotConfigured(t *testing.T) {
+	app := application.NewApplication(&MockRepository{}, &MockSender{})
+
+	_, err := app.ArchiveSentMessages(context.Background())
+
+	assert.ErrorIs(t, err, application.ErrRetentionNotConfigured)
+}
+
+func TestApplication_SendNext_AdaptiveThrottle(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	var msgs []*message.Message
+	for i := range 6 {
+		msg := createTestMessage(fmt.Sprintf("msg-%d", i), "Hello World")
+		msgs = append(msgs, msg)
+		mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil).Once()
+		mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	}
+	// the first window of two sends fails, the following ones succeed
+	for _, msg := range msgs[:2] {
+		mockSender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider unavailable"))
+		mockRepo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+	}
+	for _, msg := range msgs[2:] {
+		mockSender.On("Send", mock.Anything, msg).Return(createSendResult("sent-"+msg.ID), nil)
+		mockRepo.On("Save", mock.Anything, msg).Return(nil)
+	}
+	var rates []float64
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithRateLimit(1000, 1),
+		application.WithAdaptiveThrottle(application.AdaptiveThrottle{MaxErrorRate: 0.5, Window: 2, MinRate: 300},
+			func(perSecond float64) { rates = append(rates, perSecond) }),
+	)
+
+	for range msgs {
+		_ = app.SendNext(context.Background())
+	}
+
+	// halved after the failing window, then ramped up by a tenth of the rate limit per healthy window
+	assert.Equal(t, []float64{500, 600, 700}, rates)
+}
+
+func TestApplication_SendNext_AdaptiveThrottleOnLatency(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(createSendResult("sent-msg-1"), nil).
+		After(20 * time.Millisecond)
+	mockRepo.On("Save", mock.Anything, msg).Return(nil)
+	var rates []float64
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithRateLimit(100, 1),
+		application.WithAdaptiveThrottle(application.AdaptiveThrottle{MaxLatency: 10 * time.Millisecond, Window: 1},
+			func(perSecond float64) { rates = append(rates, perSecond) }),
+	)
+
+	for range 5 {
+		require.NoError(t, app.SendNext(context.Background()))
+	}
+
+	// slow sends halve the rate down to a tenth of the rate limit
+	assert.Equal(t, []float64{50, 25, 12.5}, rates[:3])
+	assert.Equal(t, 10.0, rates[len(rates)-1])
+}
+
+func TestApplication_AdaptiveThrottle_IgnoredWithoutRateLimit(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	msg := createTestMessage("msg-1", "Hello World")
+	mockRepo.On("GetNextUnsent", mock.Anything).Return(msg, nil)
+	mockRepo.On("Claim", mock.Anything, msg, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, msg).Return(nil, errors.New("provider unavailable"))
+	mockRepo.On("SaveAttempts", mock.Anything, msg).Return(nil)
+	adjusted := false
+	app := application.NewApplication(mockRepo, mockSender,
+		application.WithRateLimit(0, 1),
+		application.WithAdaptiveThrottle(application.AdaptiveThrottle{MaxErrorRate: 0.1, Window: 1},
+			func(float64) { adjusted = true }),
+	)
+
+	_ = app.SendNext(context.Background())
+
+	assert.False(t, adjusted)
+}
+
+func TestApplication_SendN_Traces(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	mockRepo := &MockRepository{}
+	mockSender := &MockSender{}
+	sent := createTestMessage("msg-1", "Hello World")
+	failed := createTestMessage("msg-2", "Hello World")
+	mockRepo.On("GetUnsent", mock.Anything, 2).Return([]*message.Message{sent, failed}, nil)
+	mockRepo.On("Claim", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	mockSender.On("Send", mock.Anything, sent).Return(createSendResult("sent-msg-1"), nil)
+	mockSender.On("Send", mock.Anything, failed).Return(nil, errors.New("provider unavailable"))
+	mockRepo.On("Save", mock.Anything, sent).Return(nil)
+	mockRepo.On("SaveAttempts", mock.Anything, failed).Return(nil)
+	app := application.NewApplication(mockRepo, mockSender, application.WithRateLimit(0, 1))
+
+	err := app.SendN(context.Background(), 2)
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	root := spans[2]
+	assert.Equal(t, "SendN", root.Name())
+	assert.Equal(t, codes.Error, root.Status().Code)
+	// every message is sent in a span of its own within the trace of the send
+	for i, msg := range []*message.Message{sent, failed} {
+		assert.Equal(t, "send message", spans[i].Name())
+		assert.Equal(t, root.SpanContext().SpanID(), spans[i].Parent().SpanID())
+		assert.Contains(t, spans[i].Attributes(), attribute.String("message.id", msg.ID))
+	}
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
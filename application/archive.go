@@ -0,0 +1,64 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRetentionNotConfigured is returned by ArchiveSentMessages of an Application created without WithRetention.
+var ErrRetentionNotConfigured = errors.New("retention of sent messages is not configured")
+
+// defaultArchiveBatchSize is the number of messages archived per statement unless configured otherwise.
+const defaultArchiveBatchSize = 1000
+
+// Retention is how long sent messages are kept with the messages being sent before they are archived or deleted,
+// keeping the message table and the cache of sent messages small.
+type Retention struct {
+	After     time.Duration // age of sent messages, counted from their delivery, at which they are archived
+	Delete    bool          // delete old messages instead of moving them to the archive
+	BatchSize int           // messages archived per repository call; defaultArchiveBatchSize if not positive
+}
+
+// WithRetention archives, or deletes, sent messages once they are older than retention.After when
+// ArchiveSentMessages runs, e.g. from a daemon. A retention with a non-positive After is ignored.
+func WithRetention(retention Retention) OptFunc {
+	return func(options *Options) {
+		if retention.After <= 0 {
+			return
+		}
+		if retention.BatchSize <= 0 {
+			retention.BatchSize = defaultArchiveBatchSize
+		}
+		options.retention = &retention
+	}
+}
+
+// ArchiveSentMessages moves the messages sent longer ago than the configured retention to the archive, or deletes
+// them if so configured, a batch at a time so no statement holds locks for long, until none is left or ctx is done.
+// It returns how many messages it archived, including those archived before an error.
+func (a *Application) ArchiveSentMessages(ctx context.Context) (int64, error) {
+	retention := a.opts.retention
+	if retention == nil {
+		return 0, ErrRetentionNotConfigured
+	}
+	archive := a.messages.ArchiveSent
+	if retention.Delete {
+		archive = a.messages.DeleteSent
+	}
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := archive(ctx, retention.After, retention.BatchSize)
+		total += n
+		if err != nil {
+			return total, errors.Wrap(err, "archiving sent messages")
+		}
+		if n < int64(retention.BatchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package application
+
+import (
+	"sync"
+	"time"
+)
+
+// backoff remembers until when sending is paused for each key, e.g. for the channels whose provider asked not to be
+// sent messages after it refused messages for the rate of sending. Other keys are sent as usual meanwhile.
+type backoff[K comparable] struct {
+	mu    sync.Mutex      // protects until
+	until map[K]time.Time // end of the pause, by key
+}
+
+// newBackoff returns a backoff pausing no key.
+func newBackoff[K comparable]() *backoff[K] {
+	return &backoff[K]{until: make(map[K]time.Time)}
+}
+
+// pause stops sending for key until the given time, unless it is paused longer already.
+func (b *backoff[K]) pause(key K, until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until.After(b.until[key]) {
+		b.until[key] = until
+	}
+}
+
+// paused reports whether sending for key is paused at now.
+func (b *backoff[K]) paused(key K, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Before(b.until[key])
+}
@@ -0,0 +1,102 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// NewMessageInput is a message a client asks to queue for sending, e.g. a row of an uploaded file.
+// The content of messages naming a Template is rendered from it when they are sent, Content is ignored then.
+type NewMessageInput struct {
+	Channel   message.Channel   // medium the message is delivered through; message.ChannelSMS if empty
+	To        string            // recipient on Channel
+	Content   string            // message payload of messages without a template
+	Template  string            // name of the template the content is rendered from; empty for fixed content
+	Variables map[string]string // values the template is rendered with
+	Priority  int               // messages with a higher priority are sent first
+	SendAt    time.Time         // earliest time the message may be delivered; zero means right away
+	ExpiresAt time.Time         // time after which the message is no longer delivered; zero means never
+}
+
+// InputError is the reason the input at Index of an EnqueueMessages call was not queued.
+type InputError struct {
+	Index int   // position of the input among the enqueued inputs
+	Err   error // why the input is invalid
+}
+
+// Error returns the reason the input is invalid, prefixed with its position.
+func (e *InputError) Error() string {
+	return fmt.Sprintf("input %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the reason the input is invalid.
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// EnqueueResult is the outcome of an EnqueueMessages call.
+type EnqueueResult struct {
+	Queued   []*message.Message // messages stored for sending, in the order of their inputs
+	Rejected []*InputError      // inputs that failed validation, in order
+}
+
+// EnqueueMessages validates inputs and stores the valid ones as new unsent messages with a single atomic repository
+// call, so a failed call can be retried as a whole. Invalid inputs are reported in the result instead of failing the
+// call: recipients that are invalid on their channel, messages without content or template, channels without a
+// sender and unknown templates. Messages are stored for the tenant ctx is scoped to.
+// Storage errors are wrapped and returned.
+func (a *Application) EnqueueMessages(ctx context.Context, inputs []*NewMessageInput) (*EnqueueResult, error) {
+	res := &EnqueueResult{}
+	for i, in := range inputs {
+		msg, err := a.newMessage(in)
+		if err != nil {
+			res.Rejected = append(res.Rejected, &InputError{Index: i, Err: err})
+			continue
+		}
+		res.Queued = append(res.Queued, msg)
+	}
+	if len(res.Queued) == 0 {
+		return res, nil
+	}
+	if err := a.messages.InsertMany(ctx, res.Queued); err != nil {
+		return nil, errors.Wrap(err, "enqueueing messages")
+	}
+	for _, msg := range res.Queued {
+		a.opts.hooks.Queued(ctx, msg)
+	}
+	return res, nil
+}
+
+// newMessage constructs the unsent message requested by in, checking it can be sent by this Application.
+func (a *Application) newMessage(in *NewMessageInput) (*message.Message, error) {
+	ch := in.Channel
+	if ch == "" {
+		ch = message.ChannelSMS
+	}
+	var (
+		msg *message.Message
+		err error
+	)
+	if in.Template != "" {
+		msg, err = message.NewTemplatedMessage(ch, in.To, in.Template, in.Variables)
+	} else {
+		msg, err = message.NewUnsentChannelMessage(ch, in.To, in.Content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := a.senders[ch]; !ok {
+		return nil, message.ErrChannelNotConfigured
+	}
+	if msg.IsTemplated() && !a.opts.templates.Has(msg.Template) {
+		return nil, message.ErrUnknownTemplate
+	}
+	msg.Priority = in.Priority
+	msg.ScheduledAt = in.SendAt
+	msg.ExpiresAt = in.ExpiresAt
+	return msg, nil
+}
@@ -0,0 +1,114 @@
+package application
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+const (
+	// saveAttempts is the number of times the sent state of a delivered message is stored before it is kept in the outbox.
+	saveAttempts = 3
+	// saveRetryDelay is the wait before storing the sent state again, doubled for every further attempt.
+	saveRetryDelay = 100 * time.Millisecond
+)
+
+// outbox holds delivered messages whose sent state could not be stored yet.
+// The provider already accepted them, so they must be stored rather than sent again: their claim keeps other
+// instances from picking them up until it expires, and this instance stores them before fetching further messages.
+type outbox struct {
+	mu   sync.Mutex                  // protects msgs
+	msgs map[string]*message.Message // delivered messages by ID
+}
+
+// newOutbox returns an empty outbox.
+func newOutbox() *outbox {
+	return &outbox{msgs: make(map[string]*message.Message)}
+}
+
+// add keeps msg until its sent state is stored.
+func (o *outbox) add(msg *message.Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.msgs[msg.ID] = msg
+}
+
+// remove forgets the message with the given ID.
+func (o *outbox) remove(id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.msgs, id)
+}
+
+// pending returns the messages in the outbox.
+func (o *outbox) pending() []*message.Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ret := make([]*message.Message, 0, len(o.msgs))
+	for _, msg := range o.msgs {
+		ret = append(ret, msg)
+	}
+	return ret
+}
+
+// saveSent stores the sent state of a delivered message, retrying transient failures.
+// The message was delivered, so saving continues even if ctx is canceled meanwhile.
+// A lost claim is not retried: the message was taken over by another instance.
+func (a *Application) saveSent(ctx context.Context, msg *message.Message) error {
+	ctx = context.WithoutCancel(ctx)
+	delay := saveRetryDelay
+	var err error
+	for attempt := 1; attempt <= saveAttempts; attempt++ {
+		if err = a.messages.Save(ctx, msg); err == nil || errors.Is(err, message.ErrClaimLost) {
+			return err
+		}
+		if attempt < saveAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return err
+}
+
+// saveAllSent stores the sent state of delivered messages with a single repository call, retrying transient failures
+// like saveSent, and returns the messages whose claim was lost.
+func (a *Application) saveAllSent(ctx context.Context, msgs []*message.Message) ([]*message.Message, error) {
+	ctx = context.WithoutCancel(ctx)
+	delay := saveRetryDelay
+	var (
+		lost []*message.Message
+		err  error
+	)
+	for attempt := 1; attempt <= saveAttempts; attempt++ {
+		if lost, err = a.messages.SaveAll(ctx, msgs); err == nil {
+			return lost, nil
+		}
+		if attempt < saveAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return nil, err
+}
+
+// flushOutbox stores the sent state of the messages in the outbox, running the sent hooks for each stored one.
+// Messages whose sent state still cannot be stored stay in the outbox and the first error is returned.
+func (a *Application) flushOutbox(ctx context.Context) error {
+	var firstErr error
+	for _, msg := range a.unsaved.pending() {
+		err := a.messages.Save(ctx, msg)
+		switch {
+		case err == nil:
+			a.unsaved.remove(msg.ID)
+			a.opts.hooks.Sent(ctx, msg)
+		case errors.Is(err, message.ErrClaimLost):
+			a.unsaved.remove(msg.ID)
+		case firstErr == nil:
+			firstErr = errors.Wrapf(err, "saving delivered message %s", msg.ID)
+		}
+	}
+	return firstErr
+}
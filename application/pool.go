@@ -0,0 +1,64 @@
+package application
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// sendAll sends msgs in batches of the configured size with the configured number of workers,
+// each taking the next batch once done with its previous one.
+// Batches are handed out in order, so with a single worker they are sent one after another.
+// After the first error, or once ctx is done, no further batches are handed out; batches already being sent
+// are completed, then the error is returned.
+func (a *Application) sendAll(ctx context.Context, msgs []*message.Message) error {
+	batches := slices.Collect(slices.Chunk(msgs, a.opts.batchSize))
+	queue := make(chan []*message.Message)
+	stop := make(chan struct{})
+	var (
+		wg       sync.WaitGroup
+		stopOnce sync.Once
+		firstErr error
+	)
+	// fail records the first error and stops handing out batches
+	fail := func(err error) {
+		stopOnce.Do(func() {
+			firstErr = err
+			close(stop)
+		})
+	}
+	for range min(a.opts.workers, len(batches)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range queue {
+				if err := a.sendBatch(ctx, batch); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+dispatch:
+	for _, batch := range batches {
+		// a ready worker must not win over cancellation
+		if ctx.Err() != nil {
+			fail(errors.Wrap(ctx.Err(), "stopped sending unsent messages"))
+			break
+		}
+		select {
+		case queue <- batch:
+		case <-stop:
+			break dispatch
+		case <-ctx.Done():
+			fail(errors.Wrap(ctx.Err(), "stopped sending unsent messages"))
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,121 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// DailyQuota is how many messages each tenant may send per day, with days starting at midnight in a given time zone.
+// The zero DailyQuota is unlimited.
+type DailyQuota struct {
+	Limit    int64            // messages a tenant may send per day; non-positive means unlimited
+	Tenants  map[string]int64 // limits of tenants differing from Limit; non-positive means unlimited
+	Location *time.Location   // time zone days start in; UTC if nil
+}
+
+// LimitOf returns the number of messages tenant may send per day, non-positive if unlimited.
+func (q DailyQuota) LimitOf(tenant string) int64 {
+	if limit, ok := q.Tenants[tenant]; ok {
+		return limit
+	}
+	return q.Limit
+}
+
+// limited reports whether any tenant has a limit.
+func (q DailyQuota) limited() bool {
+	if q.Limit > 0 {
+		return true
+	}
+	for _, limit := range q.Tenants {
+		if limit > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Day returns the start of the day t falls on.
+func (q DailyQuota) Day(t time.Time) time.Time {
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// NextDay returns the start of the day after the one t falls on.
+func (q DailyQuota) NextDay(t time.Time) time.Time {
+	day := q.Day(t)
+	return time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+}
+
+// reserveQuota counts msg against the daily quota of its tenant and reports whether the quota allows sending it today.
+// Messages of tenants without a limit are always allowed. Once the quota of a tenant is used up, its messages are
+// turned away without asking the counter again until the next day.
+func (a *Application) reserveQuota(ctx context.Context, msg *message.Message, now time.Time) (bool, error) {
+	tenant := message.TenantOf(ctx, msg)
+	limit := a.opts.quota.LimitOf(tenant)
+	if a.opts.quotaCounter == nil || limit <= 0 {
+		return true, nil
+	}
+	reserved, err := a.opts.quotaCounter.Reserve(ctx, tenant, a.opts.quota.Day(now), limit)
+	if err != nil {
+		return false, errors.Wrap(err, "reserving daily quota")
+	}
+	if !reserved {
+		a.exhausted.pause(tenant, a.opts.quota.NextDay(now))
+	}
+	return reserved, nil
+}
+
+// releaseQuota uncounts msg, reserved on the day now falls on, from the daily quota of its tenant,
+// e.g. because its delivery failed. A count left behind merely lowers the quota of the day.
+func (a *Application) releaseQuota(ctx context.Context, msg *message.Message, now time.Time) {
+	tenant := message.TenantOf(ctx, msg)
+	if a.opts.quotaCounter == nil || a.opts.quota.LimitOf(tenant) <= 0 {
+		return
+	}
+	_ = a.opts.quotaCounter.Release(ctx, tenant, a.opts.quota.Day(now))
+}
+
+// quotaExhausted reports whether the tenant of msg used up its daily quota at now, as far as this instance knows.
+func (a *Application) quotaExhausted(ctx context.Context, msg *message.Message, now time.Time) bool {
+	return a.exhausted.paused(message.TenantOf(ctx, msg), now)
+}
+
+// deferToNextDay defers the claimed msg to the start of the next day, when the daily quota of its tenant resets,
+// without counting an attempt.
+func (a *Application) deferToNextDay(ctx context.Context, msg *message.Message, now time.Time) error {
+	msg.SetDeferred(message.ErrDailyQuotaExceeded, a.opts.quota.NextDay(now))
+	if err := a.messages.SaveAttempts(ctx, msg); err != nil {
+		return errors.Wrap(err, "deferring message to the next day")
+	}
+	return nil
+}
+
+// quotaUsage returns the daily quota usage of the tenant ctx is scoped to, DefaultTenant if unscoped,
+// or nil if that tenant has no limit.
+func (a *Application) quotaUsage(ctx context.Context, now time.Time) (*message.QuotaUsage, error) {
+	tenant, ok := message.TenantFromContext(ctx)
+	if !ok {
+		tenant = message.DefaultTenant
+	}
+	limit := a.opts.quota.LimitOf(tenant)
+	if a.opts.quotaCounter == nil || limit <= 0 {
+		return nil, nil
+	}
+	used, err := a.opts.quotaCounter.Used(ctx, tenant, a.opts.quota.Day(now))
+	if err != nil {
+		return nil, errors.Wrap(err, "getting daily quota usage")
+	}
+	return &message.QuotaUsage{
+		Tenant:   tenant,
+		Limit:    limit,
+		Used:     used,
+		ResetsAt: a.opts.quota.NextDay(now),
+	}, nil
+}
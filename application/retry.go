@@ -0,0 +1,42 @@
+package application
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy decides how often and how late failed deliveries are tried again.
+// The delay before a retry doubles with every failed attempt, starting at BaseDelay and capped at MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int           // delivery attempts before a message is given up; 1 disables retries
+	BaseDelay   time.Duration // delay before the first retry
+	MaxDelay    time.Duration // upper bound of the delay before a retry
+	Jitter      float64       // fraction of the delay randomly added or taken off, from 0 to 1
+}
+
+// DefaultRetryPolicy tries a message five times, waiting from 30 seconds up to an hour between attempts.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    time.Hour,
+	Jitter:      0.2,
+}
+
+// Delay returns how long to wait before trying a message again after its attempts-th failed attempt.
+func (p RetryPolicy) Delay(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	if p.Jitter > 0 {
+		// spread retries of messages that failed together, e.g. during a provider outage
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+	return max(delay, 0)
+}
+
+// exhausted reports whether a message with the given number of failed attempts is not tried again.
+func (p RetryPolicy) exhausted(attempts int) bool {
+	return attempts >= p.MaxAttempts
+}
@@ -0,0 +1,67 @@
+package application
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// templateExt is the file extension of the templates LoadTemplates reads.
+const templateExt = ".tmpl"
+
+// Templates is a set of named text/template templates the content of templated messages is rendered from.
+// A variable a template refers to but a message does not carry fails rendering, rather than rendering as "<no value>".
+type Templates struct {
+	set *template.Template // parsed templates, associated by name
+}
+
+// NewTemplates parses texts, a map of template names to template texts, into Templates.
+func NewTemplates(texts map[string]string) (*Templates, error) {
+	set := template.New("").Option("missingkey=error")
+	for name, text := range texts {
+		if _, err := set.New(name).Parse(text); err != nil {
+			return nil, errors.Wrapf(err, "parsing template %q", name)
+		}
+	}
+	return &Templates{set: set}, nil
+}
+
+// LoadTemplates parses every *.tmpl file in dir into Templates, each named after its file without the extension,
+// e.g. welcome.tmpl becomes the template "welcome". Trailing line breaks of the files are not part of the templates.
+func LoadTemplates(dir string) (*Templates, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+templateExt))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing templates")
+	}
+	texts := make(map[string]string, len(paths))
+	for _, path := range paths {
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading template")
+		}
+		texts[strings.TrimSuffix(filepath.Base(path), templateExt)] = strings.TrimRight(string(text), "\r\n")
+	}
+	return NewTemplates(texts)
+}
+
+// Has reports whether the named template exists. Nil Templates have none.
+func (t *Templates) Has(name string) bool {
+	return t != nil && name != "" && t.set.Lookup(name) != nil
+}
+
+// Render executes the named template with variables.
+// Returns message.ErrUnknownTemplate if the template does not exist.
+func (t *Templates) Render(name string, variables map[string]string) (string, error) {
+	if !t.Has(name) {
+		return "", message.ErrUnknownTemplate
+	}
+	var b strings.Builder
+	if err := t.set.ExecuteTemplate(&b, name, variables); err != nil {
+		return "", errors.Wrapf(err, "rendering template %q", name)
+	}
+	return b.String(), nil
+}
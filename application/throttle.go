@@ -0,0 +1,106 @@
+package application
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// defaultThrottleWindow is the number of sends the latency and error rate are measured over unless configured otherwise.
+const defaultThrottleWindow = 20
+
+// throttleRampSteps is the number of healthy windows it takes to ramp the send rate from its minimum back up to the
+// configured rate limit.
+const throttleRampSteps = 10
+
+// AdaptiveThrottle is when sending slows down below the configured rate limit because the provider struggles:
+// whenever the average latency of the sends of a window exceeds MaxLatency, or the fraction of them that failed
+// exceeds MaxErrorRate, the send rate is halved, down to MinRate. After each window within both bounds it is raised
+// by a tenth of the rate limit again, until it is back at the rate limit.
+type AdaptiveThrottle struct {
+	MaxLatency   time.Duration // average send latency above which the provider counts as slow; latency is ignored if not positive
+	MaxErrorRate float64       // fraction of failed sends above which the provider counts as failing; errors are ignored if not positive
+	Window       int           // sends the latency and error rate are measured over; defaultThrottleWindow if not positive
+	MinRate      float64       // messages per second sending never slows below; a tenth of the rate limit if not positive
+}
+
+// enabled reports whether t slows sending down on either latency or errors.
+func (t AdaptiveThrottle) enabled() bool {
+	return t.MaxLatency > 0 || t.MaxErrorRate > 0
+}
+
+// throttle adjusts the limit of a rate limiter to the latency and error rate of recent sends, as configured by an
+// AdaptiveThrottle. It is safe for concurrent use.
+type throttle struct {
+	cfg      AdaptiveThrottle        // when sending slows down
+	limiter  *rate.Limiter           // limiter whose limit is adjusted
+	max      rate.Limit              // configured rate limit, the limit sending ramps back up to
+	min      rate.Limit              // limit sending never slows below
+	onAdjust func(perSecond float64) // told the new limit whenever it changes; may be nil
+	mu       sync.Mutex              // protects the fields below
+	calls    int                     // sender calls observed in the current window
+	latency  time.Duration           // total latency of the calls of the current window
+	sends    int                     // messages sent in the current window
+	failures int                     // messages of the current window whose send failed
+}
+
+// newThrottle returns a throttle adjusting the limit of limiter, or nil if cfg is disabled or limiter does not limit
+// the rate of sending, as an unlimited rate cannot be slowed down gradually.
+func newThrottle(cfg AdaptiveThrottle, limiter *rate.Limiter, onAdjust func(perSecond float64)) *throttle {
+	limit := limiter.Limit()
+	if !cfg.enabled() || limit == rate.Inf {
+		return nil
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultThrottleWindow
+	}
+	low := rate.Limit(cfg.MinRate)
+	if low <= 0 {
+		low = limit / throttleRampSteps
+	}
+	return &throttle{
+		cfg:      cfg,
+		limiter:  limiter,
+		max:      limit,
+		min:      min(low, limit),
+		onAdjust: onAdjust,
+	}
+}
+
+// observe records a sender call of the given latency that sent sends messages, failures of them unsuccessfully,
+// and adjusts the limit once a window of sends is complete. A nil throttle observes nothing.
+func (t *throttle) observe(latency time.Duration, sends, failures int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls++
+	t.latency += latency
+	t.sends += sends
+	t.failures += failures
+	if t.sends < t.cfg.Window {
+		return
+	}
+	limit := t.next(t.limiter.Limit())
+	t.calls, t.latency, t.sends, t.failures = 0, 0, 0, 0
+	if limit == t.limiter.Limit() {
+		return
+	}
+	t.limiter.SetLimit(limit)
+	if t.onAdjust != nil {
+		t.onAdjust(float64(limit))
+	}
+}
+
+// next returns the limit following limit given the sends of the completed window: halved if the provider was slow or
+// failing, raised by a tenth of the rate limit otherwise.
+func (t *throttle) next(limit rate.Limit) rate.Limit {
+	slow := t.cfg.MaxLatency > 0 && t.latency/time.Duration(t.calls) > t.cfg.MaxLatency
+	failing := t.cfg.MaxErrorRate > 0 && float64(t.failures)/float64(t.sends) > t.cfg.MaxErrorRate
+	if slow || failing {
+		return max(limit/2, t.min)
+	}
+	return min(limit+t.max/throttleRampSteps, t.max)
+}
@@ -0,0 +1,31 @@
+package application
+
+import (
+	"github.com/grustamli/insider-msg-sender/message"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the spans of sends. It is a no-op until a TracerProvider is installed, e.g. by tracing.Setup.
+var tracer = otel.Tracer("github.com/grustamli/insider-msg-sender/application")
+
+// messageAttributes returns the span attributes identifying msg.
+func messageAttributes(msg *message.Message) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("message.id", msg.ID),
+		attribute.String("message.channel", string(message.ChannelOf(msg))),
+		attribute.String("message.tenant", msg.Tenant),
+		attribute.Int("message.attempts", msg.Attempts),
+	}
+}
+
+// endSpan records err, if not nil, as the error of span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
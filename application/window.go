@@ -0,0 +1,71 @@
+package application
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SendWindow is the time of day messages may be sent in, e.g. from 09:00 to 21:00 in a given time zone.
+// A window whose end lies before its start spans midnight. The zero SendWindow is always open.
+type SendWindow struct {
+	Start    time.Duration  // time of day the window opens, as offset from midnight
+	End      time.Duration  // time of day the window closes, as offset from midnight
+	Location *time.Location // time zone the times of day are in; UTC if nil
+}
+
+// sendWindowLayout is the layout of the times of day in a send window specification.
+const sendWindowLayout = "15:04"
+
+// ParseSendWindow parses a window specification like "09:00-21:00" with times of day in the named time zone,
+// e.g. "Europe/Istanbul". An empty specification returns the zero SendWindow, which is always open.
+func ParseSendWindow(spec, timezone string) (SendWindow, error) {
+	if spec == "" {
+		return SendWindow{}, nil
+	}
+	from, to, ok := strings.Cut(spec, "-")
+	if !ok {
+		return SendWindow{}, errors.Errorf("send window %q is not of the form HH:MM-HH:MM", spec)
+	}
+	start, err := parseTimeOfDay(from)
+	if err != nil {
+		return SendWindow{}, errors.Wrapf(err, "parsing start of send window %q", spec)
+	}
+	end, err := parseTimeOfDay(to)
+	if err != nil {
+		return SendWindow{}, errors.Wrapf(err, "parsing end of send window %q", spec)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return SendWindow{}, errors.Wrap(err, "loading send window time zone")
+	}
+	return SendWindow{Start: start, End: end, Location: loc}, nil
+}
+
+// parseTimeOfDay parses s as HH:MM into an offset from midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse(sendWindowLayout, strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether messages may be sent at t.
+func (w SendWindow) Contains(t time.Time) bool {
+	if w.Start == w.End {
+		return true
+	}
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	// the window spans midnight
+	return offset >= w.Start || offset < w.End
+}
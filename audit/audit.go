@@ -0,0 +1,61 @@
+// Package audit defines the record of control actions taken on the service,
+// such as starting or stopping the sender, kept for compliance review.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionStart          = "scheduler.start" // the sender daemon was started
+	ActionStop           = "scheduler.stop"  // the sender daemon was stopped
+	ActionLogLevelChange = "loglevel.change" // the log level was changed at runtime
+	ActionCacheFlush     = "cache.flush"     // the sent messages cache was flushed
+	ActionCacheRebuild   = "cache.rebuild"   // the sent messages cache was rebuilt
+	ActionMessageRequeue = "message.requeue" // a message whose delivery was given up was queued again
+	ActionMessageCancel  = "message.cancel"  // a message waiting to be sent was canceled
+	ActionMessageDelete  = "message.delete"  // a message no longer pending was deleted
+)
+
+// Entry is a single recorded control action.
+type Entry struct {
+	ID         string    // internal entry identifier
+	Action     string    // one of the Action* constants
+	Actor      string    // authenticated user that took the action, empty when anonymous
+	APIKey     string    // fingerprint of the API key the request carried, see KeyFingerprint
+	RequestID  string    // ID of the request that took the action, as returned in X-Request-ID
+	RemoteAddr string    // client IP address
+	Details    string    // action specific description, e.g. the old and new log level
+	CreatedAt  time.Time // timestamp when the action was taken
+}
+
+// Filter narrows down a listing of entries. Zero-valued fields do not restrict the result.
+type Filter struct {
+	Action   string // exact action match
+	BeforeID string // only entries older than this one, for paging backwards
+	Limit    int    // maximum number of results, 0 means unlimited
+}
+
+// Repository stores audit entries. Entries are never changed once recorded.
+type Repository interface {
+	// Record stores e and fills in its ID and, unless set, its creation time.
+	Record(ctx context.Context, e *Entry) error
+
+	// List returns the entries matching f, newest first.
+	List(ctx context.Context, f Filter) ([]*Entry, error)
+}
+
+// KeyFingerprint returns a short, non-reversible identifier of an API key,
+// so entries tell keys apart without the log ever holding a usable secret.
+// It is empty for an empty key.
+func KeyFingerprint(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
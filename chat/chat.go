@@ -0,0 +1,132 @@
+// Package chat implements message.Sender posting the messages of message.ChannelChat to Slack or Microsoft Teams
+// incoming webhooks.
+package chat
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// Format is the payload format of an incoming webhook.
+type Format string
+
+const (
+	FormatSlack Format = "slack" // Slack incoming webhook, posting {"text": ...}
+	FormatTeams Format = "teams" // Microsoft Teams incoming webhook, posting an Adaptive Card
+)
+
+// ErrUnknownRoom is returned when sending to a room no incoming webhook is configured for.
+var ErrUnknownRoom = errors.New("unknown chat room")
+
+// Sender posts messages to the incoming webhooks of chat rooms, the room being the recipient of the message.
+// Incoming webhooks return no identifier of the post, so the provider message ID is generated.
+type Sender struct {
+	client *http.Client      // HTTP client for executing requests
+	rooms  map[string]string // incoming webhook URLs by room name
+	format Format            // payload format of the webhooks
+}
+
+// Ensure Sender implements the message.Sender interface.
+var _ message.Sender = (*Sender)(nil)
+
+// NewSender constructs a Sender posting with client in format to the incoming webhooks of rooms, keyed by room name.
+// Returns an error if format is unknown.
+func NewSender(client *http.Client, format Format, rooms map[string]string) (*Sender, error) {
+	switch format {
+	case FormatSlack, FormatTeams:
+	default:
+		return nil, errors.Errorf("creating chat sender: unknown format %q", format)
+	}
+	return &Sender{client: client, rooms: rooms, format: format}, nil
+}
+
+// Send posts the content of msg to the room it is addressed to.
+// Returns ErrUnknownRoom if no webhook is configured for the room, or a message.RateLimitedError honoring the
+// Retry-After header of a 429 Too Many Requests response.
+func (s *Sender) Send(ctx context.Context, msg *message.Message) (*message.SendResult, error) {
+	url, ok := s.rooms[msg.To]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownRoom, "posting to %q", msg.To)
+	}
+	body, err := json.Marshal(s.payload(msg.Content))
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling payload")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	sentAt := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "sending request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &message.RateLimitedError{
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+			Err:        errors.Errorf("sending request: received status %d", resp.StatusCode),
+		}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, errors.Errorf("sending request: received status %d", resp.StatusCode)
+	}
+	id, err := postID()
+	if err != nil {
+		return nil, err
+	}
+	return &message.SendResult{MessageID: id, SentAt: sentAt}, nil
+}
+
+// SendBatch posts msgs one by one, as incoming webhooks accept a single post per request.
+func (s *Sender) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	return message.SendEach(ctx, s, msgs)
+}
+
+// payload returns the request body posting text in the format of the webhooks.
+func (s *Sender) payload(text string) any {
+	if s.format == FormatTeams {
+		return map[string]any{
+			"type": "message",
+			"attachments": []any{map[string]any{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    []any{map[string]any{"type": "TextBlock", "text": text, "wrap": true}},
+				},
+			}},
+		}
+	}
+	return map[string]string{"text": text}
+}
+
+// postID returns a new unique identifier of a post.
+func postID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generating post id")
+	}
+	return fmt.Sprintf("chat-%s", hex.EncodeToString(b)), nil
+}
+
+// retryAfter returns the wait a Retry-After header value in seconds asks for, or zero if it is missing or malformed.
+func retryAfter(value string) time.Duration {
+	secs, err := strconv.Atoi(value)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
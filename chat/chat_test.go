@@ -0,0 +1,86 @@
+package chat_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/chat"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSender_Send(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   chat.Format
+		status   int
+		expected string
+	}{
+		{name: "slack", format: chat.FormatSlack, status: http.StatusOK, expected: `{"text":"disk almost full"}`},
+		{
+			name:   "teams",
+			format: chat.FormatTeams,
+			status: http.StatusAccepted,
+			expected: `{"type":"message","attachments":[{"contentType":"application/vnd.microsoft.card.adaptive","content":{
+				"$schema":"http://adaptivecards.io/schemas/adaptive-card.json","type":"AdaptiveCard","version":"1.4",
+				"body":[{"type":"TextBlock","text":"disk almost full","wrap":true}]}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			t.Cleanup(srv.Close)
+			sender, err := chat.NewSender(srv.Client(), tt.format, map[string]string{"alerts": srv.URL})
+			require.NoError(t, err)
+
+			res, err := sender.Send(context.Background(), &message.Message{ID: "1", Channel: message.ChannelChat, To: "alerts", Content: "disk almost full"})
+
+			require.NoError(t, err)
+			assert.NotEmpty(t, res.MessageID)
+			assert.JSONEq(t, tt.expected, string(body))
+		})
+	}
+}
+
+func TestSender_Send_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/limited":
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := chat.NewSender(srv.Client(), chat.FormatSlack, map[string]string{
+		"limited": srv.URL + "/limited",
+		"gone":    srv.URL + "/gone",
+	})
+	require.NoError(t, err)
+	send := func(room string) error {
+		_, err := sender.Send(context.Background(), &message.Message{ID: "1", Channel: message.ChannelChat, To: room, Content: "hi"})
+		return err
+	}
+
+	require.ErrorIs(t, send("unknown"), chat.ErrUnknownRoom)
+	assert.ErrorContains(t, send("gone"), "received status 404")
+	var limited *message.RateLimitedError
+	require.ErrorAs(t, send("limited"), &limited)
+	assert.Equal(t, 7*time.Second, limited.RetryAfter)
+}
+
+func TestNewSender_UnknownFormat(t *testing.T) {
+	_, err := chat.NewSender(http.DefaultClient, "irc", nil)
+
+	require.Error(t, err)
+}
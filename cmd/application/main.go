@@ -3,12 +3,19 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
+	"net/smtp"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	_ "time/tzdata" // SEND_WINDOW_TIMEZONE must resolve in images without a zoneinfo database
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
@@ -17,13 +24,19 @@ import (
 
 	"github.com/grustamli/insider-msg-sender/api"
 	"github.com/grustamli/insider-msg-sender/application"
+	"github.com/grustamli/insider-msg-sender/chat"
 	"github.com/grustamli/insider-msg-sender/config"
 	"github.com/grustamli/insider-msg-sender/daemon"
+	"github.com/grustamli/insider-msg-sender/email"
+	"github.com/grustamli/insider-msg-sender/fake"
+	"github.com/grustamli/insider-msg-sender/health"
 	"github.com/grustamli/insider-msg-sender/logging"
 	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/metrics"
 	"github.com/grustamli/insider-msg-sender/postgres"
-	"github.com/grustamli/insider-msg-sender/postgres/gen"
 	redisint "github.com/grustamli/insider-msg-sender/redis"
+	"github.com/grustamli/insider-msg-sender/tracing"
+	"github.com/grustamli/insider-msg-sender/twilio"
 	"github.com/grustamli/insider-msg-sender/webhook"
 )
 
@@ -50,32 +63,218 @@ func run() error {
 	log := initLogger(cfg)
 	cfg.Log(log)
 
-	// set up message repository (DB + Redis cache)
-	messages, err := initMessageRepository(cfg)
+	// export traces of sends, if enabled
+	if cfg.Tracing.Enabled {
+		shutdown, err := tracing.Setup(ctx, tracing.Config{
+			ServiceName: cfg.Tracing.ServiceName,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := shutdown(context.Background()); err != nil {
+				log.Error().Err(err).Msg("Failed to flush traces")
+			}
+		}()
+	}
+
+	// open Postgres connection
+	db, err := initDB(cfg, cfg.Postgres.DBURL)
 	if err != nil {
 		return err
 	}
+	// and the read replica's, if configured
+	var replica *sql.DB
+	if cfg.Postgres.ReplicaDBURL != "" {
+		if replica, err = initDB(cfg, cfg.Postgres.ReplicaDBURL); err != nil {
+			return err
+		}
+	}
+
+	// bring the schema up to date, if enabled, before anything reads the database
+	if cfg.Postgres.Migrate {
+		if err := migrateDB(ctx, db, log); err != nil {
+			return err
+		}
+	}
+
+	// set up message repository (DB + Redis cache)
+	rdb := initRedis(cfg)
+	messages := initMessageRepository(cfg, db, replica, rdb)
+
+	// set up subscriptions and the notifier delivering message events to them
+	subscriptions := postgres.NewSubscriptionRepository(db)
+	notifier := initEventNotifier(cfg, subscriptions, log)
 
 	// set up HTTP-based webhook sender
-	sender, err := initMessageSender(cfg)
+	sender, err := initMessageSender(cfg, log)
 	if err != nil {
 		return err
 	}
 
-	// wrap application with logging middleware
-	app := logging.LogApplicationAccess(application.NewApplication(messages, sender), log)
+	// register health checks of each dependency, the webhook provider being judged by real sends
+	checks := health.NewRegistry()
+	checks.Register("postgres", health.Ping(db.PingContext))
+	if replica != nil {
+		checks.Register("postgres_replica", health.Ping(replica.PingContext))
+	}
+	checks.Register("redis", health.Ping(func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	}))
+	monitoredSender := health.MonitorSender(sender)
+	checks.Register("webhook", monitoredSender.Check)
+	if cfg.SenderType == config.SenderWebhook && cfg.Webhook.HealthPath != "" {
+		probe, err := initWebhookProbe(cfg, log)
+		if err != nil {
+			return err
+		}
+		checks.Register("webhook_endpoint", health.Ping(probe.Ping))
+	}
+
+	// restrict sending to the configured time of day
+	sendWindow, err := application.ParseSendWindow(cfg.SendWindow, cfg.SendWindowTimezone)
+	if err != nil {
+		return err
+	}
+
+	// limit the messages each tenant sends per day
+	quotaLocation, err := time.LoadLocation(cfg.DailyQuotaTimezone)
+	if err != nil {
+		return errors.Wrap(err, "loading daily quota time zone")
+	}
+	dailyQuota := application.DailyQuota{
+		Limit:    cfg.DailyQuota,
+		Tenants:  cfg.DailyQuotaTenants,
+		Location: quotaLocation,
+	}
 
-	// send any unsent messages immediately
-	go sendAllUnsentMessages(ctx, app, log)
+	// load the templates of templated messages, if any
+	var templates *application.Templates
+	if cfg.TemplateDir != "" {
+		if templates, err = application.LoadTemplates(cfg.TemplateDir); err != nil {
+			return err
+		}
+	}
 
-	// start periodic daemon to send messages
+	// set up the senders of channels other than SMS
+	channelSenders, err := initChannelSenders(cfg, log)
+	if err != nil {
+		return err
+	}
+	if cfg.SMTP.Host != "" {
+		emailSender, err := initEmailSender(cfg, log)
+		if err != nil {
+			return err
+		}
+		channelSenders = append(channelSenders, emailSender)
+	}
+	if len(cfg.Chat.RoomURLs) > 0 {
+		chatSender, err := initChatSender(cfg, log)
+		if err != nil {
+			return err
+		}
+		channelSenders = append(channelSenders, chatSender)
+	}
+
+	// reject messages failing the configured checks before they are sent
+	validators, err := initValidators(cfg)
+	if err != nil {
+		return err
+	}
+
+	// report the configured send rate, if limited, until adaptive throttling adjusts it
+	if cfg.SendRatePerSecond > 0 {
+		metrics.ObserveSendRate(cfg.SendRatePerSecond)
+	}
+
+	// count messages through their send lifecycle
+	hooks := &message.Hooks{}
+	metrics.ObserveLifecycle(hooks)
+
+	// wrap application with logging middleware
+	app := logging.LogApplicationAccess(application.NewApplication(messages, monitoredSender, append(channelSenders,
+		application.WithSubscriptions(subscriptions, notifier),
+		application.WithAttemptLog(postgres.NewAttemptRepository(db)),
+		application.WithRetryPolicy(application.RetryPolicy{
+			MaxAttempts: cfg.Retry.MaxAttempts,
+			BaseDelay:   time.Duration(cfg.Retry.BaseDelaySeconds) * time.Second,
+			MaxDelay:    time.Duration(cfg.Retry.MaxDelaySeconds) * time.Second,
+			Jitter:      cfg.Retry.Jitter,
+		}),
+		application.WithWorkers(cfg.SendWorkers),
+		application.WithBatchSize(cfg.SendBatchSize),
+		application.WithRateLimit(cfg.SendRatePerSecond, cfg.SendRateBurst),
+		application.WithAdaptiveThrottle(application.AdaptiveThrottle{
+			MaxLatency:   time.Duration(cfg.SendThrottleMaxLatencyMs) * time.Millisecond,
+			MaxErrorRate: cfg.SendThrottleMaxErrorRate,
+			Window:       cfg.SendThrottleWindow,
+			MinRate:      cfg.SendThrottleMinRate,
+		}, func(perSecond float64) {
+			metrics.ObserveSendRate(perSecond)
+			log.Info().Float64("rate", perSecond).Msg("Adjusted send rate to provider health")
+		}),
+		application.WithClaimLease(time.Duration(cfg.ClaimLeaseSeconds)*time.Second),
+		application.WithClaimOnRead(cfg.ClaimOnRead),
+		application.WithDeduplication(redisint.NewDeduplicator(rdb, cfg.Redis.CacheKey+"-dedup"),
+			time.Duration(cfg.DedupWindowSeconds)*time.Second),
+		application.WithSendWindow(sendWindow, func(held int64) {
+			log.Info().Int64("held", held).Str("window", cfg.SendWindow).Msg("Outside send window, holding messages")
+		}),
+		application.WithTemplates(templates),
+		application.WithHooks(hooks),
+		application.WithDailyQuota(redisint.NewQuotaCounter(rdb, cfg.Redis.CacheKey+"-quota"), dailyQuota),
+		application.WithValidators(validators...),
+		application.WithRetention(application.Retention{
+			After:     time.Duration(cfg.Archive.AfterDays) * 24 * time.Hour,
+			Delete:    cfg.Archive.Delete,
+			BatchSize: cfg.Archive.BatchSize,
+		}),
+	)...), log)
+
+	// start periodic daemon to send messages, unless an operator paused it before the restart,
+	// on the elected leader only if leader election is configured
 	msgSenderDaemon := initMessageSenderDaemon(cfg, app, log)
-	if err := msgSenderDaemon.Start(ctx); err != nil {
+	sendDaemon, err := initLeaderElection(cfg, rdb, msgSenderDaemon, log)
+	if err != nil {
 		return err
 	}
+	scheduler := daemon.RememberState(sendDaemon, "MessageSender", redisint.NewStateStore(rdb, cfg.Redis.CacheKey+"-scheduler"))
+	running, err := resumeScheduler(ctx, scheduler, sendDaemon, log)
+	if err != nil {
+		return err
+	}
+	checks.Register("scheduler", health.Worker(msgSenderDaemon))
+
+	// send newly inserted messages right away instead of at the next tick, if enabled; the nudge does nothing on
+	// instances not running the scheduler
+	if cfg.Postgres.Listen {
+		go listenForInserts(ctx, cfg, msgSenderDaemon, log)
+	}
+
+	// archive old sent messages periodically, if a retention period is configured; every replica may run it,
+	// as concurrent runs skip the messages another one is archiving
+	if cfg.Archive.AfterDays > 0 {
+		if err := initArchiveDaemon(cfg, app, log).Start(ctx); err != nil {
+			return err
+		}
+	}
+
+	// send any unsent messages immediately, unless that is left to the leader
+	if running && cfg.LeaderLeaseSeconds <= 0 {
+		go sendAllUnsentMessages(ctx, app, log)
+	}
 
 	// initialize and run HTTP API server
-	srv := initAPIServer(app, msgSenderDaemon, log)
+	srv, err := initAPIServer(cfg, app, scheduler, log,
+		api.WithCacheAdmin(messages),
+		api.WithAuditLog(postgres.NewAuditRepository(db)),
+		api.WithHealthChecks(checks),
+		initResponseCache(cfg, rdb),
+	)
+	if err != nil {
+		return err
+	}
 	return srv.Run()
 }
 
@@ -86,6 +285,31 @@ func sendAllUnsentMessages(ctx context.Context, app *logging.Application, log ze
 	}
 }
 
+// listenForInserts runs the sender daemon d whenever Postgres notifies that messages were inserted, until ctx is done.
+func listenForInserts(ctx context.Context, cfg *config.AppConfig, d *daemon.TimerDaemon, log zerolog.Logger) {
+	listener := postgres.NewInsertListener(cfg.Postgres.DBURL, func(err error) {
+		log.Error().Err(err).Msg("Lost connection listening for inserted messages")
+	})
+	if err := listener.Run(ctx, d.Nudge); err != nil {
+		log.Error().Err(err).Msg("Failed to listen for inserted messages")
+	}
+}
+
+// resumeScheduler starts the scheduler unless it was paused before the restart, and reports whether it runs.
+// If the saved state cannot be loaded, the scheduler is started, as it was before its state was saved.
+func resumeScheduler(ctx context.Context, scheduler *daemon.StatefulDaemon, d daemon.Daemon, log zerolog.Logger) (bool, error) {
+	state, err := scheduler.Resume(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to restore scheduler state, starting it")
+		return true, d.Start(ctx)
+	}
+	if state == daemon.StatePaused {
+		log.Info().Msg("Scheduler stays paused, as it was stopped before the restart")
+		return false, nil
+	}
+	return true, nil
+}
+
 // initLogger configures zerolog.Logger based on application settings.
 func initLogger(cfg *config.AppConfig) zerolog.Logger {
 	return logging.New(logging.LogConfig{
@@ -94,71 +318,408 @@ func initLogger(cfg *config.AppConfig) zerolog.Logger {
 	})
 }
 
-// initMessageRepository combines PostgreSQL storage and Redis caching for messages.
-func initMessageRepository(cfg *config.AppConfig) (message.Repository, error) {
-	// open Postgres connection
-	db, err := initDB(cfg)
-	if err != nil {
-		return nil, err
-	}
-
-	// create Redis client
+// initRedis creates the Redis client, recording a span for every command.
+func initRedis(cfg *config.AppConfig) *redis.Client {
 	rdb := redis.NewClient(&redis.Options{
 		Addr: cfg.Redis.Address,
 		DB:   cfg.Redis.DB,
 	})
+	redisint.Trace(rdb)
+	return rdb
+}
 
-	// wrap the Postgres repo with Redis cache
-	return redisint.NewCacheRepository(rdb, cfg.Redis.CacheKey,
-		postgres.NewMessageRepository(gen.New(db)),
-	), nil
+// initMessageRepository combines PostgreSQL storage and Redis caching for messages.
+// If replica is not nil, pages of sent messages and stats are read from it; the cache is filled from db.
+func initMessageRepository(cfg *config.AppConfig, db, replica *sql.DB, rdb *redis.Client) *redisint.CacheRepository {
+	var repo message.Repository = postgres.NewMessageRepository(db)
+	if replica != nil {
+		repo = postgres.NewReplicaRepository(repo, postgres.NewMessageRepository(replica))
+	}
+	// record the latency of the calls reaching Postgres, then wrap the Postgres repo with Redis cache
+	repo = message.RepositoryWithMiddleware(repo, metrics.RepositoryMiddleware)
+	return redisint.NewCacheRepository(rdb, cfg.Redis.CacheKey, repo)
 }
 
-// initDB opens a database/sql.DB connection to Postgres.
-func initDB(cfg *config.AppConfig) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.Postgres.DBURL)
+// initDB opens a database/sql.DB connection pool to the Postgres database at dsn, sized and recycled according to cfg.
+func initDB(cfg *config.AppConfig, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, errors.Wrap(err, "connecting to postgres db")
 	}
+	db.SetMaxOpenConns(cfg.Postgres.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Postgres.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.Postgres.ConnMaxLifetimeSeconds) * time.Second)
 	return db, nil
 }
 
-// initMessageSender constructs a webhook.MessageSender with timeouts and headers.
-func initMessageSender(cfg *config.AppConfig) (*webhook.MessageSender, error) {
-	client := &http.Client{Timeout: time.Duration(cfg.Webhook.TimeoutSeconds) * time.Second}
-	sender, err := webhook.NewWebhookSender(client, cfg.Webhook.URL, buildWebhookOpts(&cfg.Webhook)...)
+// migrateDB applies the schema migrations embedded in the binary that db lacks.
+func migrateDB(ctx context.Context, db *sql.DB, log zerolog.Logger) error {
+	applied, err := postgres.Migrate(ctx, db)
+	if err != nil {
+		return errors.Wrap(err, "migrating postgres db")
+	}
+	log.Info().Strs("versions", applied).Msg("Applied schema migrations")
+	return nil
+}
+
+// defaultRoute names the SMS sender of SENDER_TYPE, WEBHOOK_URL by default, among the senders WEBHOOK_ROUTES select from.
+const defaultRoute = "default"
+
+// initMessageSender constructs a webhook.MessageSender with timeouts and headers, a twilio.Sender with SENDER_TYPE
+// twilio or a fake.Sender with SENDER_TYPE fake, decorated with send logging and metrics. With WEBHOOK_ROUTES configured,
+// messages are routed by recipient prefix to the senders of WEBHOOK_ROUTE_URLS through a message.Router, others are
+// sent through the sender of SENDER_TYPE.
+func initMessageSender(cfg *config.AppConfig, log zerolog.Logger) (message.Sender, error) {
+	client, err := initWebhookClient(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	var primary message.Sender
+	switch cfg.SenderType {
+	case config.SenderTwilio:
+		primary, err = initTwilioSender(cfg, log)
+	case config.SenderFake:
+		primary = fake.NewSender(
+			fake.WithLatency(time.Duration(cfg.Fake.MinLatencyMS)*time.Millisecond, time.Duration(cfg.Fake.MaxLatencyMS)*time.Millisecond),
+			fake.WithFailureRate(cfg.Fake.FailureRate),
+		)
+	default:
+		primary, err = initPrimarySender(client, &cfg.Webhook)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Webhook.Routes) == 0 {
+		return decorateSender(primary, log), nil
+	}
+	senders := map[string]message.Sender{defaultRoute: primary}
+	for name, url := range cfg.Webhook.RouteURLs {
+		sender, err := webhook.NewWebhookSender(client, url, buildWebhookOpts(&cfg.Webhook)...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating webhook sender of route %q", name)
+		}
+		senders[name] = sender
+	}
+	routes := make([]message.Route, 0, len(cfg.Webhook.Routes))
+	for prefix, name := range cfg.Webhook.Routes {
+		routes = append(routes, message.Route{Prefix: prefix, Sender: name})
+	}
+	// the most specific prefix wins, e.g. +9012 over +90
+	slices.SortFunc(routes, func(a, b message.Route) int {
+		return cmp.Or(len(b.Prefix)-len(a.Prefix), strings.Compare(a.Prefix, b.Prefix))
+	})
+	router, err := message.NewRouter(senders, routes, defaultRoute)
+	if err != nil {
+		return nil, errors.Wrap(err, "configuring webhook routes")
+	}
+	return decorateSender(router, log), nil
+}
+
+// initTwilioSender constructs a twilio.Sender sending SMS from the configured Twilio account instead of WEBHOOK_URL.
+func initTwilioSender(cfg *config.AppConfig, log zerolog.Logger) (message.Sender, error) {
+	client := &http.Client{Timeout: time.Duration(cfg.Twilio.TimeoutSeconds) * time.Second, Transport: senderTransport(cfg, log, nil)}
+	sender, err := twilio.NewSender(client, cfg.Twilio.AccountSID, cfg.Twilio.AuthToken, cfg.Twilio.FromNumber,
+		twilio.WithBaseURL(cfg.Twilio.BaseURL),
+		twilio.WithCharacterLimit(cfg.Webhook.CharacterLimit),
+		twilio.WithSegmentLimit(cfg.Webhook.SegmentLimit),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating twilio sender")
+	}
+	return sender, nil
+}
+
+// initPrimarySender constructs the sender of WEBHOOK_URL. With WEBHOOK_FAILOVER_URLS configured, sends fail over
+// from WEBHOOK_URL to them through a webhook.FailoverSender, recording which endpoint served each send.
+func initPrimarySender(client *http.Client, cfg *config.WebhookConfig) (message.Sender, error) {
+	urls := append([]string{cfg.URL}, cfg.FailoverURLs...)
+	senders := make([]*webhook.MessageSender, len(urls))
+	for i, url := range urls {
+		sender, err := webhook.NewWebhookSender(client, url, buildWebhookOpts(cfg)...)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating webhook sender")
+		}
+		senders[i] = sender
+	}
+	if len(senders) == 1 {
+		return senders[0], nil
+	}
+	sender, err := webhook.NewFailoverSender(senders,
+		webhook.WithCircuitBreaker(cfg.CircuitThreshold, time.Duration(cfg.CircuitCooldownSeconds)*time.Second),
+		webhook.WithEndpointObserver(metrics.ObserveEndpointSend),
+	)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating webhook sender")
 	}
 	return sender, nil
 }
 
+// initWebhookProbe constructs a webhook.MessageSender of WEBHOOK_URL used only to probe the provider at
+// WEBHOOK_HEALTH_PATH, reporting its availability before sends fail.
+func initWebhookProbe(cfg *config.AppConfig, log zerolog.Logger) (*webhook.MessageSender, error) {
+	client, err := initWebhookClient(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	probe, err := webhook.NewWebhookSender(client, cfg.Webhook.URL, buildWebhookOpts(&cfg.Webhook)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating webhook probe")
+	}
+	return probe, nil
+}
+
+// initWebhookClient constructs the HTTP client of the webhook senders, presenting the configured client certificate
+// to providers that require mutual TLS and going through the configured proxy.
+func initWebhookClient(appCfg *config.AppConfig, log zerolog.Logger) (*http.Client, error) {
+	cfg := &appCfg.Webhook
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" || cfg.TLSCAFile != "" {
+		tlsConfig, err := webhook.ClientTLSConfig(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "configuring webhook tls")
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	if cfg.ProxyURL != "" {
+		proxy, err := webhook.ProxyFunc(cfg.ProxyURL, cfg.NoProxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "configuring webhook proxy")
+		}
+		transport.Proxy = proxy
+	}
+	return &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second, Transport: senderTransport(appCfg, log, transport, cfg.AuthHeader)}, nil
+}
+
+// senderTransport returns the transport of the HTTP clients of senders, tracing requests made through base and, with
+// LOG_PAYLOADS set, logging their sanitized payloads and responses at trace level, the values of the redact headers
+// hidden.
+func senderTransport(cfg *config.AppConfig, log zerolog.Logger, base http.RoundTripper, redact ...string) http.RoundTripper {
+	if cfg.LogPayloads {
+		base = logging.Transport(base, log, redact...)
+	}
+	return tracing.Transport(base)
+}
+
+// initChannelSenders constructs a webhook.MessageSender, decorated with send logging and metrics, for each further channel
+// configured in WEBHOOK_CHANNEL_URLS, returning the options registering them with the application.
+// Their content is not truncated, as the character limit applies to SMS only.
+func initChannelSenders(cfg *config.AppConfig, log zerolog.Logger) ([]application.OptFunc, error) {
+	client, err := initWebhookClient(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	var opts []application.OptFunc
+	for name, url := range cfg.Webhook.ChannelURLs {
+		ch, err := message.ParseChannel(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "configuring webhook of channel %q", name)
+		}
+		sender, err := webhook.NewWebhookSender(client, url, channelWebhookOpts(&cfg.Webhook)...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating webhook sender of channel %q", name)
+		}
+		opts = append(opts, application.WithSender(ch, decorateSender(sender, log)))
+	}
+	return opts, nil
+}
+
+// initEmailSender constructs an email.SMTPSender, decorated with send logging and metrics, delivering emails through the
+// configured SMTP server, returning the option registering it with the application.
+func initEmailSender(cfg *config.AppConfig, log zerolog.Logger) (application.OptFunc, error) {
+	opts := []email.OptFunc{email.WithSubject(cfg.SMTP.Subject)}
+	if cfg.SMTP.Username != "" {
+		opts = append(opts, email.WithAuth(smtp.PlainAuth("", cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.Host)))
+	}
+	addr := net.JoinHostPort(cfg.SMTP.Host, strconv.Itoa(cfg.SMTP.Port))
+	sender, err := email.NewSMTPSender(addr, cfg.SMTP.From, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating email sender")
+	}
+	return application.WithSender(message.ChannelEmail, decorateSender(sender, log)), nil
+}
+
+// initChatSender constructs a chat.Sender, decorated with send logging and metrics, posting to the incoming webhooks of the
+// configured chat rooms, returning the option registering it with the application.
+func initChatSender(cfg *config.AppConfig, log zerolog.Logger) (application.OptFunc, error) {
+	client := &http.Client{Timeout: time.Duration(cfg.Chat.TimeoutSeconds) * time.Second, Transport: senderTransport(cfg, log, nil)}
+	sender, err := chat.NewSender(client, chat.Format(cfg.Chat.Format), cfg.Chat.RoomURLs)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating chat sender")
+	}
+	return application.WithSender(message.ChannelChat, decorateSender(sender, log)), nil
+}
+
+// decorateSender wraps sender with the middleware every sender is composed with: logging of send outcomes and send
+// metrics.
+func decorateSender(sender message.Sender, log zerolog.Logger) message.Sender {
+	return message.SenderWithMiddleware(sender, logging.LogSends(log), metrics.SenderMiddleware)
+}
+
+// initValidators builds the validators messages must pass before they are sent: blocklisted recipient prefixes,
+// blocked terms and the longest content of each channel.
+func initValidators(cfg *config.AppConfig) ([]message.Validator, error) {
+	var validators []message.Validator
+	if len(cfg.BlockedPrefixes) > 0 {
+		validators = append(validators, message.BlockPrefixes(cfg.BlockedPrefixes...))
+	}
+	if len(cfg.BlockedTerms) > 0 {
+		validators = append(validators, message.BlockTerms(cfg.BlockedTerms...))
+	}
+	for name, limit := range cfg.MaxContentLength {
+		ch, err := message.ParseChannel(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "configuring content length of channel %q", name)
+		}
+		validators = append(validators, message.MaxContentLength(ch, limit))
+	}
+	return validators, nil
+}
+
 // buildWebhookOpts assembles functional options for the webhook sender.
 func buildWebhookOpts(cfg *config.WebhookConfig) []webhook.OptFunc {
-	var opts []webhook.OptFunc
+	opts := channelWebhookOpts(cfg)
 	if cfg.CharacterLimit > 0 {
 		opts = append(opts, webhook.WithCharacterLimit(cfg.CharacterLimit))
 	}
+	if cfg.SegmentLimit > 0 {
+		opts = append(opts, webhook.WithSegmentLimit(cfg.SegmentLimit))
+	}
+	if cfg.BatchSize > 1 {
+		opts = append(opts, webhook.WithBatchSize(cfg.BatchSize))
+	}
+	if cfg.GzipMinBytes > 0 {
+		opts = append(opts, webhook.WithGzip(cfg.GzipMinBytes))
+	}
+	if cfg.HealthPath != "" {
+		opts = append(opts, webhook.WithHealthProbe(cfg.HealthMethod, cfg.HealthPath))
+	}
+	return opts
+}
+
+// channelWebhookOpts assembles the functional options shared by the webhook senders of all channels:
+// retries, the auth header, request signing, the request body and the responses providers accept messages with.
+func channelWebhookOpts(cfg *config.WebhookConfig) []webhook.OptFunc {
+	opts := []webhook.OptFunc{
+		webhook.WithRetries(cfg.RetryAttempts, time.Duration(cfg.RetryBackoffMS)*time.Millisecond),
+		webhook.WithRequestTimeout(time.Duration(cfg.SendTimeoutSeconds) * time.Second),
+		webhook.WithHMACSignature(cfg.SigningSecret, cfg.SignatureHeader),
+		webhook.WithAcceptedStatus(cfg.AcceptedStatuses...),
+		webhook.WithResponseFields(webhook.ResponseFields{
+			MessageID:   cfg.ResponseIDField,
+			Status:      cfg.ResponseStatusField,
+			StatusValue: cfg.ResponseStatusValue,
+		}),
+		webhook.WithPayloadTemplate(cfg.PayloadTemplate, cfg.PayloadFields),
+		webhook.WithMethod(cfg.Method),
+		webhook.WithContentType(webhook.ContentType(cfg.ContentType)),
+		webhook.WithRequestObserver(metrics.ObserveWebhookRequest),
+	}
 	if cfg.AuthKey != "" {
 		opts = append(opts, webhook.WithHeader(cfg.AuthHeader, cfg.AuthKey))
 	}
 	return opts
 }
 
+// initEventNotifier constructs a webhook.EventNotifier delivering message events to subscriptions,
+// logging deliveries that fail all attempts.
+func initEventNotifier(cfg *config.AppConfig, subscriptions message.SubscriptionRepository, log zerolog.Logger) *webhook.EventNotifier {
+	client := &http.Client{Timeout: time.Duration(cfg.Notify.TimeoutSeconds) * time.Second, Transport: tracing.Transport(nil)}
+	return webhook.NewEventNotifier(client, subscriptions,
+		webhook.WithAttempts(cfg.Notify.Attempts),
+		webhook.WithBackoff(time.Duration(cfg.Notify.BackoffSeconds)*time.Second),
+		webhook.WithErrorHandler(func(err error) {
+			log.Error().Err(err).Msg("Failed to deliver message event")
+		}),
+	)
+}
+
 // initMessageSenderDaemon creates a TimerDaemon that sends a configured number
 // of messages at regular intervals.
 func initMessageSenderDaemon(cfg *config.AppConfig, app application.App, log zerolog.Logger) *daemon.TimerDaemon {
-	return daemon.NewTimerDaemon("MessageSender", func(ctx context.Context) error {
-		for i := 0; i < cfg.MessageCountPerInterval; i++ {
-			if err := app.SendNext(ctx); err != nil {
-				return err
-			}
-		}
-		return nil
-	}, time.Duration(cfg.SendIntervalSeconds)*time.Second, &log)
+	return daemon.NewTimerDaemon("MessageSender", metrics.InstrumentJob("MessageSender", func(ctx context.Context) error {
+		return app.SendN(ctx, cfg.MessageCountPerInterval)
+	}), time.Duration(cfg.SendIntervalSeconds)*time.Second, &log)
+}
+
+// initArchiveDaemon creates a TimerDaemon that archives sent messages past the retention period at regular intervals.
+func initArchiveDaemon(cfg *config.AppConfig, app application.App, log zerolog.Logger) *daemon.TimerDaemon {
+	return daemon.NewTimerDaemon("MessageArchiver", metrics.InstrumentJob("MessageArchiver", func(ctx context.Context) error {
+		n, err := app.ArchiveSentMessages(ctx)
+		metrics.ObserveRetention(n, cfg.Archive.Delete)
+		return err
+	}), time.Duration(cfg.Archive.IntervalSeconds)*time.Second, &log)
+}
+
+// initLeaderElection wraps d to run only on the replica holding the leader lease in Redis, if leader election is
+// configured; otherwise every replica runs d.
+func initLeaderElection(cfg *config.AppConfig, rdb *redis.Client, d daemon.Daemon, log zerolog.Logger) (daemon.Daemon, error) {
+	if cfg.LeaderLeaseSeconds <= 0 {
+		return d, nil
+	}
+	lease, err := redisint.NewLease(rdb, cfg.Redis.CacheKey+"-leader")
+	if err != nil {
+		return nil, err
+	}
+	return daemon.RunAsLeader(d, lease, time.Duration(cfg.LeaderLeaseSeconds)*time.Second, &log), nil
 }
 
 // initAPIServer constructs and returns the HTTP API server instance.
-func initAPIServer(app application.App, msgSenderDaemon daemon.Daemon, log zerolog.Logger) *api.Server {
-	return api.NewServer(gin.Default(), ":8000", app, msgSenderDaemon, log)
+// extra options wire in dependencies created by run, such as the cache and the audit log.
+func initAPIServer(cfg *config.AppConfig, app application.App, msgSenderDaemon daemon.Daemon, log zerolog.Logger, extra ...api.OptFunc) (*api.Server, error) {
+	opts := append(buildAPIOpts(&cfg.API), extra...)
+	return api.NewServer(gin.Default(), fmt.Sprintf(":%d", cfg.API.Port), app, msgSenderDaemon, log, opts...)
+}
+
+// initResponseCache returns the API option caching read-only responses where configured, if anywhere.
+func initResponseCache(cfg *config.AppConfig, rdb *redis.Client) api.OptFunc {
+	ttl := time.Duration(cfg.API.ResponseCacheTTL) * time.Second
+	switch cfg.API.ResponseCache {
+	case config.ResponseCacheMemory:
+		return api.WithResponseCache(api.NewMemoryResponseStore(), ttl)
+	case config.ResponseCacheRedis:
+		return api.WithResponseCache(redisint.NewResponseStore(rdb, cfg.Redis.CacheKey+"-responses"), ttl)
+	default:
+		return func(*api.Options) {}
+	}
+}
+
+// buildAPIOpts assembles functional options for the API server.
+func buildAPIOpts(cfg *config.APIConfig) []api.OptFunc {
+	opts := []api.OptFunc{
+		api.WithMaxBodyBytes(cfg.MaxBodyBytes),
+		api.WithMaxHeaderBytes(cfg.MaxHeaderBytes),
+		api.WithTimeouts(
+			time.Duration(cfg.ReadTimeoutSeconds)*time.Second,
+			time.Duration(cfg.WriteTimeoutSeconds)*time.Second,
+			time.Duration(cfg.IdleTimeoutSeconds)*time.Second,
+		),
+	}
+	if cfg.GraphQLEnabled {
+		opts = append(opts, api.WithGraphQL())
+	}
+	if cfg.MetricsEnabled {
+		opts = append(opts, api.WithMetrics())
+	}
+	if cfg.PprofEnabled {
+		opts = append(opts, api.WithPprof())
+	}
+	switch cfg.Docs {
+	case config.DocsAdmin:
+		opts = append(opts, api.WithDocsAuth())
+	case config.DocsDisabled:
+		opts = append(opts, api.WithoutDocs())
+	}
+	if cfg.RequestValidation {
+		opts = append(opts, api.WithRequestValidation())
+	}
+	if cfg.AdminPassword != "" {
+		opts = append(opts, api.WithAdminAuth(cfg.AdminUsername, cfg.AdminPassword))
+	}
+	for key, tenant := range cfg.TenantKeys {
+		opts = append(opts, api.WithTenantAPIKey(key, tenant))
+	}
+	if cfg.DeliveryReportToken != "" {
+		opts = append(opts, api.WithDeliveryReports(cfg.DeliveryReportToken))
+	}
+	return opts
 }
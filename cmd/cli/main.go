@@ -1,4 +1,5 @@
-// Package main implements the CLI tool for seeding the Insider Message Sender database.
+// Package main implements the CLI tool for seeding the Insider Message Sender database, reporting its statistics,
+// inspecting single messages and migrating its schema.
 package main
 
 import (
@@ -10,28 +11,35 @@ import (
 
 	"github.com/alecthomas/kong"
 	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grustamli/insider-msg-sender/application"
 	"github.com/grustamli/insider-msg-sender/daemon"
 	"github.com/grustamli/insider-msg-sender/logging"
 	"github.com/grustamli/insider-msg-sender/message"
 	"github.com/grustamli/insider-msg-sender/postgres"
-	"github.com/grustamli/insider-msg-sender/postgres/gen"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
-// MessageRepository defines an interface for inserting Message entities.
-type MessageRepository interface {
-	// Insert adds a new message to the repository.
-	Insert(ctx context.Context, message *message.Message) error
-}
-
 // cli holds the top-level command definitions parsed by Kong.
 var cli struct {
 	Seed struct {
 		DBURL    string `help:"Postgres Database URL (or set $DATABASE_URL)" env:"DATABASE_URL" name:"db-url"`
 		Interval int    `short:"i" help:"Interval in seconds between seed runs. 0 = run once." default:"0"`
 		Count    int    `short:"c" help:"Number of messages to insert each run. Default is 1" default:"1"`
-	} `cmd help:"Seed the database with initial data."`
+		Tenant   string `short:"t" help:"Tenant the seeded messages belong to." default:"default"`
+	} `cmd:"" help:"Seed the database with initial data."`
+	Stats struct {
+		DBURL  string `help:"Postgres Database URL (or set $DATABASE_URL)" env:"DATABASE_URL" name:"db-url"`
+		Tenant string `short:"t" help:"Tenant to report on. Empty = all tenants."`
+	} `cmd:"" help:"Print message statistics."`
+	Show struct {
+		DBURL  string `help:"Postgres Database URL (or set $DATABASE_URL)" env:"DATABASE_URL" name:"db-url"`
+		Tenant string `short:"t" help:"Tenant the message belongs to. Empty = any tenant."`
+		ID     string `arg:"" help:"Internal ID of the message."`
+	} `cmd:"" help:"Print a message and its delivery attempts."`
+	Migrate struct {
+		DBURL string `help:"Postgres Database URL (or set $DATABASE_URL)" env:"DATABASE_URL" name:"db-url"`
+	} `cmd:"" help:"Apply pending schema migrations."`
 }
 
 // main parses CLI arguments and dispatches to the appropriate command handler.
@@ -57,6 +65,21 @@ func run() error {
 		if err := runSeed(); err != nil {
 			return err
 		}
+	case "stats":
+		// Execute the stats command
+		if err := runStats(); err != nil {
+			return err
+		}
+	case "show <id>":
+		// Execute the show command
+		if err := runShow(); err != nil {
+			return err
+		}
+	case "migrate":
+		// Execute the migrate command
+		if err := runMigrate(); err != nil {
+			return err
+		}
 	default:
 		// Print usage for unknown commands
 		return ctx.PrintUsage(false)
@@ -78,21 +101,149 @@ func runSeed() error {
 	if err != nil {
 		return err
 	}
-	ctx := context.Background()
+	ctx := message.WithTenant(context.Background(), cli.Seed.Tenant)
+	// messages are enqueued like uploads to the API, so no sender is needed
+	app := application.NewApplication(messages, nil)
 
 	// Decide between single-run or periodic seeding
 	if cli.Seed.Interval > 0 {
-		return seedInIntervals(ctx, messages, cli.Seed.Interval, cli.Seed.Count, logger)
+		return seedInIntervals(ctx, app, cli.Seed.Interval, cli.Seed.Count, logger)
+	}
+	return seedMessages(ctx, app, cli.Seed.Count)
+}
+
+// runStats prints the message statistics the /stats endpoint reports, for one tenant or all of them.
+func runStats() error {
+	if cli.Stats.DBURL == "" {
+		return errors.New("no database URL provided: set --db-url or $DATABASE_URL")
+	}
+	messages, err := initMessageRepository(cli.Stats.DBURL)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if cli.Stats.Tenant != "" {
+		ctx = message.WithTenant(ctx, cli.Stats.Tenant)
+	}
+	// statistics are only read, so no sender is needed
+	stats, err := application.NewApplication(messages, nil).Stats(ctx)
+	if err != nil {
+		return err
+	}
+	printStats(stats)
+	return nil
+}
+
+// runShow prints the message the GET /messages/:id endpoint returns along with its delivery attempts.
+func runShow() error {
+	if cli.Show.DBURL == "" {
+		return errors.New("no database URL provided: set --db-url or $DATABASE_URL")
+	}
+	db, err := sql.Open("postgres", cli.Show.DBURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if cli.Show.Tenant != "" {
+		ctx = message.WithTenant(ctx, cli.Show.Tenant)
+	}
+	// messages are only read, so no sender is needed
+	app := application.NewApplication(postgres.NewMessageRepository(db), nil,
+		application.WithAttemptLog(postgres.NewAttemptRepository(db)))
+	msg, err := app.GetMessage(ctx, cli.Show.ID)
+	if err != nil {
+		return err
+	}
+	attempts, err := app.ListAttempts(ctx, cli.Show.ID)
+	if err != nil {
+		return err
+	}
+	printMessage(msg, attempts)
+	return nil
+}
+
+// runMigrate applies the schema migrations embedded in the binary that the database lacks.
+func runMigrate() error {
+	if cli.Migrate.DBURL == "" {
+		return errors.New("no database URL provided: set --db-url or $DATABASE_URL")
+	}
+	db, err := sql.Open("postgres", cli.Migrate.DBURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	applied, err := postgres.Migrate(context.Background(), db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Applied %d migrations\n", len(applied))
+	for _, version := range applied {
+		fmt.Printf("  %s\n", version)
+	}
+	return nil
+}
+
+// printStats writes stats to stdout, one figure per line.
+func printStats(stats *message.Stats) {
+	fmt.Printf("sent:           %d\n", stats.Sent)
+	fmt.Printf("unsent:         %d\n", stats.Unsent)
+	fmt.Printf("failed:         %d\n", stats.Failed)
+	fmt.Printf("expired:        %d\n", stats.Expired)
+	fmt.Printf("sent last hour: %d\n", stats.SentLastHour)
+	fmt.Printf("sent last day:  %d\n", stats.SentLastDay)
+	fmt.Printf("failure rate:   %.2f%%\n", stats.FailureRate()*100)
+	fmt.Printf("avg latency:    %s\n", stats.AvgLatency)
+}
+
+// printMessage writes msg to stdout, one field per line, followed by its delivery attempts, one per line.
+// Timestamps of states the message never reached are left out.
+func printMessage(msg *message.Message, attempts []*message.Attempt) {
+	fmt.Printf("id:          %s\n", msg.ID)
+	fmt.Printf("status:      %s\n", msg.Status())
+	fmt.Printf("tenant:      %s\n", msg.Tenant)
+	fmt.Printf("channel:     %s\n", message.ChannelOf(msg))
+	fmt.Printf("to:          %s\n", msg.To)
+	fmt.Printf("content:     %s\n", msg.Content)
+	printTime("created at:  ", msg.CreatedAt)
+	printTime("send at:     ", msg.ScheduledAt)
+	printTime("sent at:     ", msg.SentAt)
+	if msg.MessageID != "" {
+		fmt.Printf("provider id: %s\n", msg.MessageID)
+	}
+	printTime("failed at:   ", msg.FailedAt)
+	printTime("rejected at: ", msg.RejectedAt)
+	printTime("canceled at: ", msg.CanceledAt)
+	printTime("expired at:  ", msg.ExpiredAt)
+	if msg.LastError != "" {
+		fmt.Printf("last error:  %s\n", msg.LastError)
+	}
+	if msg.DeliveryStatus != "" {
+		fmt.Printf("delivery:    %s\n", msg.DeliveryStatus)
+	}
+	fmt.Printf("attempts:    %d\n", len(attempts))
+	for _, a := range attempts {
+		outcome := "ok"
+		if !a.Succeeded() {
+			outcome = a.Error
+		}
+		fmt.Printf("  %s  %6dms  %s\n", a.StartedAt.Format(time.RFC3339), a.Duration.Milliseconds(), outcome)
+	}
+}
+
+// printTime writes label followed by t to stdout, unless t is zero.
+func printTime(label string, t time.Time) {
+	if !t.IsZero() {
+		fmt.Printf("%s%s\n", label, t.Format(time.RFC3339))
 	}
-	return seedMessages(ctx, messages, cli.Seed.Count)
 }
 
 // seedInIntervals starts a TimerDaemon that seeds messages at regular intervals.
 // It blocks until the context is canceled, then stops the daemon gracefully.
-func seedInIntervals(ctx context.Context, messages *postgres.MessageRepository, interval, count int, logger zerolog.Logger) error {
+func seedInIntervals(ctx context.Context, app application.App, interval, count int, logger zerolog.Logger) error {
 	// Create a new TimerDaemon for seeding
 	d := daemon.NewTimerDaemon("MessageSeeder", func(ctx context.Context) error {
-		return seedMessages(ctx, messages, count)
+		return seedMessages(ctx, app, count)
 	}, time.Duration(interval)*time.Second, &logger)
 
 	// Start the daemon
@@ -107,13 +258,13 @@ func seedInIntervals(ctx context.Context, messages *postgres.MessageRepository,
 	return d.Stop(context.Background())
 }
 
-// seedMessages inserts the specified number of fake messages into the repository.
-func seedMessages(ctx context.Context, repo MessageRepository, count int) error {
-	// Generate and insert messages
-	if err := insertMessages(ctx, repo, createSeedMessages(count)); err != nil {
+// seedMessages enqueues the specified number of fake messages in one transaction.
+func seedMessages(ctx context.Context, app application.App, count int) error {
+	res, err := app.EnqueueMessages(ctx, createSeedMessages(count))
+	if err != nil {
 		return err
 	}
-	fmt.Println("Finished seeding messages")
+	fmt.Printf("Finished seeding messages: %d queued, %d rejected\n", len(res.Queued), len(res.Rejected))
 	return nil
 }
 
@@ -124,33 +275,18 @@ func initMessageRepository(dsn string) (*postgres.MessageRepository, error) {
 		return nil, err
 	}
 	// Create a new Postgres-backed repository
-	return postgres.NewMessageRepository(gen.New(db)), nil
+	return postgres.NewMessageRepository(db), nil
 }
 
-// createSeedMessages generates a slice of fake Message objects for seeding.
+// createSeedMessages generates the inputs of fake messages for seeding.
 // Each message has a randomized phone number and sentence content.
-func createSeedMessages(count int) []*message.Message {
-	ret := make([]*message.Message, count)
+func createSeedMessages(count int) []*application.NewMessageInput {
+	ret := make([]*application.NewMessageInput, count)
 	for i := 0; i < count; i++ {
-		ret[i] = &message.Message{
+		ret[i] = &application.NewMessageInput{
 			To:      gofakeit.Numerify("+994#########"),
 			Content: gofakeit.Sentence(6),
 		}
 	}
 	return ret
 }
-
-// insertMessages writes each Message to the repository, returning on the first error.
-func insertMessages(ctx context.Context, repo MessageRepository, messages []*message.Message) error {
-	for _, msg := range messages {
-		if err := insertMessage(ctx, repo, msg); err != nil {
-			return errors.Wrap(err, "inserting message")
-		}
-	}
-	return nil
-}
-
-// insertMessage writes individual message to the repository, returning error if failed
-func insertMessage(ctx context.Context, repo MessageRepository, msg *message.Message) error {
-	return repo.Insert(ctx, msg)
-}
@@ -20,30 +20,220 @@ const (
 	Production Environment = "PROD"
 )
 
+// DocsAccess controls who may read the API documentation.
+type DocsAccess string
+
+const (
+	// DocsPublic serves the documentation to everyone
+	DocsPublic DocsAccess = "public"
+	// DocsAdmin serves the documentation behind admin authentication
+	DocsAdmin DocsAccess = "admin"
+	// DocsDisabled does not serve the documentation
+	DocsDisabled DocsAccess = "disabled"
+)
+
+// ResponseCache selects where responses of read-only endpoints are cached.
+type ResponseCache string
+
+const (
+	// ResponseCacheNone disables response caching
+	ResponseCacheNone ResponseCache = "none"
+	// ResponseCacheMemory caches responses in process
+	ResponseCacheMemory ResponseCache = "memory"
+	// ResponseCacheRedis caches responses in Redis, shared by every replica
+	ResponseCacheRedis ResponseCache = "redis"
+)
+
+// SenderType selects the provider SMS are sent through.
+type SenderType string
+
+const (
+	// SenderWebhook posts SMS to the generic webhook of WEBHOOK_URL
+	SenderWebhook SenderType = "webhook"
+	// SenderTwilio sends SMS through the Twilio Messages API
+	SenderTwilio SenderType = "twilio"
+	// SenderFake pretends to send SMS without any provider, for local development
+	SenderFake SenderType = "fake"
+)
+
 // AppConfig holds all application configuration settings sourced from environment variables.
 // Fields include runtime environment, logging level, send intervals, and nested service configs.
 type AppConfig struct {
-	Environment             Environment    `env:"ENVIRONMENT, default=DEV"`              // run mode: DEV or PROD
-	LogLevel                string         `env:"LOG_LEVEL, default=DEBUG"`              // verbosity level for logging
-	SendIntervalSeconds     int            `env:"SEND_INTERVAL_SECONDS, default=120"`    // interval between send daemon runs
-	MessageCountPerInterval int            `env:"MESSAGE_COUNT_PER_INTERVAL, default=2"` // messages to send per interval
-	Postgres                PostgresConfig `env:", prefix=POSTGRES_"`                    // Postgres connection settings
-	Webhook                 WebhookConfig  `env:", prefix=WEBHOOK_"`                     // Webhook sender settings
-	Redis                   RedisConfig    `env:", prefix=REDIS_"`                       // Redis cache settings
+	Environment              Environment      `env:"ENVIRONMENT, default=DEV"`                // run mode: DEV or PROD
+	LogLevel                 string           `env:"LOG_LEVEL, default=DEBUG"`                // verbosity level for logging
+	LogPayloads              bool             `env:"LOG_PAYLOADS"`                            // log sanitized provider requests and responses at trace level
+	SendIntervalSeconds      int              `env:"SEND_INTERVAL_SECONDS, default=120"`      // interval between send daemon runs
+	MessageCountPerInterval  int              `env:"MESSAGE_COUNT_PER_INTERVAL, default=2"`   // messages to send per interval
+	SendWorkers              int              `env:"SEND_WORKERS, default=1"`                 // messages sent concurrently when draining the backlog
+	SendRatePerSecond        float64          `env:"SEND_RATE_PER_SECOND, default=1"`         // average messages sent per second; 0 means unlimited
+	SendRateBurst            int              `env:"SEND_RATE_BURST, default=1"`              // messages that may be sent at once after idle periods
+	SendThrottleMaxLatencyMs int              `env:"SEND_THROTTLE_MAX_LATENCY_MS, default=0"` // average send latency above which sending slows down; 0 ignores latency
+	SendThrottleMaxErrorRate float64          `env:"SEND_THROTTLE_MAX_ERROR_RATE, default=0"` // fraction of failed sends above which sending slows down; 0 ignores errors
+	SendThrottleWindow       int              `env:"SEND_THROTTLE_WINDOW, default=20"`        // sends the throttle measures latency and errors over
+	SendThrottleMinRate      float64          `env:"SEND_THROTTLE_MIN_RATE, default=0"`       // messages per second sending never slows below; 0 means a tenth of the rate
+	SendBatchSize            int              `env:"SEND_BATCH_SIZE, default=1"`              // messages handed to the sender at once when draining the backlog
+	ClaimLeaseSeconds        int              `env:"CLAIM_LEASE_SECONDS, default=60"`         // how long a message is reserved for the instance sending it
+	ClaimOnRead              bool             `env:"CLAIM_ON_READ"`                           // claim messages with FOR UPDATE SKIP LOCKED as they are read
+	DedupWindowSeconds       int              `env:"DEDUP_WINDOW_SECONDS, default=0"`         // identical messages to a recipient within it are not sent; 0 disables
+	SendWindow               string           `env:"SEND_WINDOW"`                             // time of day messages are sent in, e.g. 09:00-21:00; empty means always
+	SendWindowTimezone       string           `env:"SEND_WINDOW_TIMEZONE, default=UTC"`       // time zone of SEND_WINDOW, e.g. Europe/Istanbul
+	TemplateDir              string           `env:"TEMPLATE_DIR"`                            // directory of *.tmpl message templates; empty means none
+	LeaderLeaseSeconds       int              `env:"LEADER_LEASE_SECONDS, default=0"`         // lease of the replica running the send daemon; 0 runs it on every replica
+	DailyQuota               int64            `env:"DAILY_QUOTA, default=0"`                  // messages a tenant may send per day; 0 means unlimited
+	DailyQuotaTenants        map[string]int64 `env:"DAILY_QUOTA_TENANTS"`                     // daily quotas of tenants differing from DAILY_QUOTA, as tenant:limit pairs
+	DailyQuotaTimezone       string           `env:"DAILY_QUOTA_TIMEZONE, default=UTC"`       // time zone days of the daily quota start in
+	BlockedPrefixes          []string         `env:"BLOCKED_PREFIXES"`                        // recipient prefixes whose messages are rejected, e.g. +1900
+	BlockedTerms             []string         `env:"BLOCKED_TERMS"`                           // terms whose messages are rejected, regardless of case
+	MaxContentLength         map[string]int   `env:"MAX_CONTENT_LENGTH"`                      // longest content sent per channel, as channel:characters pairs
+	SenderType               SenderType       `env:"SENDER_TYPE, default=webhook"`            // provider SMS are sent through: webhook, twilio or fake
+	Fake                     FakeConfig       `env:", prefix=FAKE_"`                          // simulated provider settings of SENDER_TYPE fake
+	Postgres                 PostgresConfig   `env:", prefix=POSTGRES_"`                      // Postgres connection settings
+	Webhook                  WebhookConfig    `env:", prefix=WEBHOOK_"`                       // Webhook sender settings
+	Redis                    RedisConfig      `env:", prefix=REDIS_"`                         // Redis cache settings
+	API                      APIConfig        `env:", prefix=API_"`                           // HTTP API settings
+	Notify                   NotifyConfig     `env:", prefix=NOTIFY_"`                        // subscription event delivery settings
+	Retry                    RetryConfig      `env:", prefix=RETRY_"`                         // retry settings of failed message deliveries
+	Archive                  ArchiveConfig    `env:", prefix=ARCHIVE_"`                       // archiving of old sent messages
+	Tracing                  TracingConfig    `env:", prefix=TRACING_"`                       // OpenTelemetry tracing settings
+	Twilio                   TwilioConfig     `env:", prefix=TWILIO_"`                        // Twilio sender settings
+	SMTP                     SMTPConfig       `env:", prefix=SMTP_"`                          // SMTP sender settings of emails
+	Chat                     ChatConfig       `env:", prefix=CHAT_"`                          // chat sender settings
+}
+
+// APIConfig holds HTTP API server settings and optional endpoint toggles.
+type APIConfig struct {
+	Port                int               `env:"PORT, default=8000"`                    // port the HTTP server listens on
+	ReadTimeoutSeconds  int               `env:"READ_TIMEOUT_SECONDS, default=15"`      // time allowed to read a request, headers included; 0 means none
+	WriteTimeoutSeconds int               `env:"WRITE_TIMEOUT_SECONDS, default=0"`      // time allowed to write a response; 0 means none, as exports stream for long
+	IdleTimeoutSeconds  int               `env:"IDLE_TIMEOUT_SECONDS, default=120"`     // time a kept-alive connection may wait for the next request; 0 means none
+	MaxHeaderBytes      int               `env:"MAX_HEADER_BYTES, default=1048576"`     // maximum accepted size of request headers
+	MaxBodyBytes        int64             `env:"MAX_BODY_BYTES, default=1048576"`       // maximum accepted request body size
+	GraphQLEnabled      bool              `env:"GRAPHQL_ENABLED, default=false"`        // expose the /graphql query endpoint
+	MetricsEnabled      bool              `env:"METRICS_ENABLED, default=false"`        // expose Prometheus metrics at /metrics
+	PprofEnabled        bool              `env:"PPROF_ENABLED, default=false"`          // expose net/http/pprof under /debug/pprof
+	AdminUsername       string            `env:"ADMIN_USERNAME, default=admin"`         // basic auth user for administrative endpoints
+	AdminPassword       string            `env:"ADMIN_PASSWORD"`                        // basic auth password for administrative endpoints
+	RequestValidation   bool              `env:"REQUEST_VALIDATION, default=true"`      // validate requests against the OpenAPI document
+	Docs                DocsAccess        `env:"DOCS, default=public"`                  // access to the OpenAPI document and Swagger UI: public, admin or disabled
+	ResponseCache       ResponseCache     `env:"RESPONSE_CACHE, default=none"`          // where to cache responses of read-only listings: none, memory or redis
+	ResponseCacheTTL    int               `env:"RESPONSE_CACHE_TTL_SECONDS, default=5"` // how long cached responses are served
+	TenantKeys          map[string]string `env:"TENANT_KEYS"`                           // API keys and the tenants they identify, as key:tenant pairs
+	DeliveryReportToken string            `env:"DELIVERY_REPORT_TOKEN"`                 // token providers authenticate delivery reports with; reports are disabled while unset
 }
 
 // WebhookConfig holds HTTP webhook sender configuration options.
 type WebhookConfig struct {
-	URL            string `env:"URL"`                          // target webhook URL
-	AuthHeader     string `env:"AUTH_HEADER"`                  // HTTP header name for auth key
-	AuthKey        string `env:"AUTH_KEY"`                     // authentication key for webhook
-	CharacterLimit int    `env:"CHARACTER_LIMIT, default=160"` // max message chars before truncation
-	TimeoutSeconds int    `env:"TIMEOUT_SECONDS, default=20"`  // HTTP client timeout in seconds
+	URL                    string            `env:"URL"`                                  // target webhook URL of SMS
+	AuthHeader             string            `env:"AUTH_HEADER"`                          // HTTP header name for auth key
+	AuthKey                string            `env:"AUTH_KEY"`                             // authentication key for webhook
+	CharacterLimit         int               `env:"CHARACTER_LIMIT, default=160"`         // max message chars before truncation, SMS only
+	SegmentLimit           int               `env:"SEGMENT_LIMIT"`                        // max SMS segments before truncation, GSM-7/UCS-2 aware; overrides CharacterLimit if set
+	BatchSize              int               `env:"BATCH_SIZE, default=1"`                // messages posted together in one request when the provider accepts bulk payloads
+	GzipMinBytes           int               `env:"GZIP_MIN_BYTES"`                       // size from which SMS request bodies are gzip compressed; 0 disables compression
+	TimeoutSeconds         int               `env:"TIMEOUT_SECONDS, default=20"`          // HTTP client timeout in seconds
+	SendTimeoutSeconds     int               `env:"SEND_TIMEOUT_SECONDS"`                 // deadline of each send, retries included; 0 means none
+	TLSCertFile            string            `env:"TLS_CERT_FILE"`                        // PEM client certificate presented to providers requiring mutual TLS
+	TLSKeyFile             string            `env:"TLS_KEY_FILE"`                         // PEM private key of the client certificate
+	TLSCAFile              string            `env:"TLS_CA_FILE"`                          // PEM bundle of the CAs provider certificates are verified with; system roots if empty
+	ProxyURL               string            `env:"PROXY_URL"`                            // HTTP(S) proxy webhook requests go through; HTTPS_PROXY and HTTP_PROXY apply if empty
+	NoProxy                string            `env:"NO_PROXY"`                             // hosts reached without PROXY_URL, in the format of NO_PROXY
+	ChannelURLs            map[string]string `env:"CHANNEL_URLS"`                         // webhook URLs of further channels, as channel:url pairs
+	RouteURLs              map[string]string `env:"ROUTE_URLS"`                           // webhook URLs of SMS routes, as name:url pairs
+	Routes                 map[string]string `env:"ROUTES"`                               // routes of SMS by recipient prefix, as prefix:name pairs naming a route URL or default
+	FailoverURLs           []string          `env:"FAILOVER_URLS"`                        // backup webhook URLs of SMS tried in order when URL fails
+	CircuitThreshold       int               `env:"CIRCUIT_THRESHOLD, default=3"`         // consecutive failures skipping a failover endpoint
+	CircuitCooldownSeconds int               `env:"CIRCUIT_COOLDOWN_SECONDS, default=30"` // seconds a failing failover endpoint is skipped
+	HealthPath             string            `env:"HEALTH_PATH"`                          // path or URL the SMS provider is probed at, resolved against URL; unprobed if empty
+	HealthMethod           string            `env:"HEALTH_METHOD, default=HEAD"`          // HTTP method of health probes
+	RetryAttempts          int               `env:"RETRY_ATTEMPTS, default=1"`            // requests tried per message on connection errors and 5xx responses
+	RetryBackoffMS         int               `env:"RETRY_BACKOFF_MS, default=200"`        // wait before the first retry in milliseconds, doubled for every further one
+	SigningSecret          string            `env:"SIGNING_SECRET"`                       // secret request bodies are signed with; unsigned if empty
+	SignatureHeader        string            `env:"SIGNATURE_HEADER"`                     // HTTP header carrying the signature; X-Signature if empty
+	AcceptedStatuses       []int             `env:"ACCEPTED_STATUSES"`                    // response status codes providers accept messages with; 202 if empty
+	ResponseIDField        string            `env:"RESPONSE_ID_FIELD"`                    // path of the response field carrying the provider message ID; the default fields if empty
+	ResponseStatusField    string            `env:"RESPONSE_STATUS_FIELD"`                // path of the response field carrying the acceptance status; unchecked if empty
+	ResponseStatusValue    string            `env:"RESPONSE_STATUS_VALUE"`                // value of the status field meaning the message was accepted
+	PayloadTemplate        string            `env:"PAYLOAD_TEMPLATE"`                     // Go template request bodies are rendered from; the default body if empty
+	PayloadFields          map[string]string `env:"PAYLOAD_FIELDS"`                       // static fields available to the payload template, as key:value pairs
+	Method                 string            `env:"METHOD, default=POST"`                 // HTTP method of webhook requests
+	ContentType            string            `env:"CONTENT_TYPE, default=json"`           // encoding of request bodies: json or form
+}
+
+// NotifyConfig holds settings for delivering message events to subscriptions.
+type NotifyConfig struct {
+	Attempts       int `env:"ATTEMPTS, default=3"`         // deliveries tried per event and subscription
+	BackoffSeconds int `env:"BACKOFF_SECONDS, default=1"`  // wait before the first retry, doubled for every further one
+	TimeoutSeconds int `env:"TIMEOUT_SECONDS, default=10"` // HTTP client timeout in seconds
+}
+
+// RetryConfig holds settings for retrying failed message deliveries.
+type RetryConfig struct {
+	MaxAttempts      int     `env:"MAX_ATTEMPTS, default=5"`         // delivery attempts before a message is given up
+	BaseDelaySeconds int     `env:"BASE_DELAY_SECONDS, default=30"`  // wait before the first retry, doubled for every further one
+	MaxDelaySeconds  int     `env:"MAX_DELAY_SECONDS, default=3600"` // upper bound of the wait before a retry
+	Jitter           float64 `env:"JITTER, default=0.2"`             // fraction of the wait randomly added or taken off
+}
+
+// ArchiveConfig holds settings for archiving sent messages past their retention period.
+type ArchiveConfig struct {
+	AfterDays       int  `env:"AFTER_DAYS, default=0"`          // days sent messages are kept in the messages table; 0 keeps them forever
+	Delete          bool `env:"DELETE, default=false"`          // delete old sent messages instead of moving them to the archive table
+	BatchSize       int  `env:"BATCH_SIZE, default=1000"`       // messages archived per statement
+	IntervalSeconds int  `env:"INTERVAL_SECONDS, default=3600"` // interval between archive daemon runs
 }
 
-// PostgresConfig holds the Postgres database connection URL.
+// TwilioConfig holds the Twilio account SMS are sent from when SENDER_TYPE is twilio.
+type TwilioConfig struct {
+	AccountSID     string `env:"ACCOUNT_SID"`                              // account the messages are sent from
+	AuthToken      string `env:"AUTH_TOKEN"`                               // secret authenticating the account
+	FromNumber     string `env:"FROM_NUMBER"`                              // phone number messages are sent from, in E.164 format
+	BaseURL        string `env:"BASE_URL, default=https://api.twilio.com"` // base URL of the Twilio REST API
+	TimeoutSeconds int    `env:"TIMEOUT_SECONDS, default=20"`              // HTTP client timeout in seconds
+}
+
+// SMTPConfig holds the SMTP server emails are sent through; emails are sent over SMTP only while Host is set.
+type SMTPConfig struct {
+	Host     string `env:"HOST"`                          // host name of the SMTP server
+	Port     int    `env:"PORT, default=587"`             // port of the SMTP server
+	Username string `env:"USERNAME"`                      // user authenticating with the server; unauthenticated if empty
+	Password string `env:"PASSWORD"`                      // password of Username
+	From     string `env:"FROM"`                          // address emails are sent from, optionally with a display name
+	Subject  string `env:"SUBJECT, default=Notification"` // subject of emails whose template variables set none
+}
+
+// ChatConfig holds the incoming webhooks of the chat rooms messages are posted to; chat is enabled only while rooms
+// are configured.
+type ChatConfig struct {
+	Format         string            `env:"FORMAT, default=slack"`       // payload format of the webhooks: slack or teams
+	RoomURLs       map[string]string `env:"ROOM_URLS"`                   // incoming webhook URLs of the rooms, as room:url pairs
+	TimeoutSeconds int               `env:"TIMEOUT_SECONDS, default=10"` // HTTP client timeout in seconds
+}
+
+// FakeConfig holds the behavior of the provider simulated with SENDER_TYPE fake.
+type FakeConfig struct {
+	MinLatencyMS int     `env:"MIN_LATENCY_MS, default=50"`  // shortest time a simulated send takes in milliseconds
+	MaxLatencyMS int     `env:"MAX_LATENCY_MS, default=200"` // longest time a simulated send takes in milliseconds
+	FailureRate  float64 `env:"FAILURE_RATE, default=0"`     // fraction of simulated sends failing, between 0 and 1
+}
+
+// TracingConfig holds settings for exporting OpenTelemetry traces. The collector is configured through the
+// standard OTEL_EXPORTER_OTLP_* environment variables.
+type TracingConfig struct {
+	Enabled     bool    `env:"ENABLED, default=false"`                   // export traces of sends to an OTLP collector
+	ServiceName string  `env:"SERVICE_NAME, default=insider-msg-sender"` // service name spans are reported under
+	SampleRatio float64 `env:"SAMPLE_RATIO, default=1"`                  // fraction of traces recorded
+}
+
+// PostgresConfig holds the Postgres database connection URLs, connection pool limits and whether the schema is migrated
+// on startup.
 type PostgresConfig struct {
-	DBURL string `env:"DB_URL, required"` // Postgres DSN
+	DBURL                  string `env:"DB_URL, required"`                       // Postgres DSN
+	ReplicaDBURL           string `env:"REPLICA_DB_URL"`                         // DSN of a read replica serving sent message reads and stats; empty for none
+	Migrate                bool   `env:"MIGRATE"`                                // apply pending schema migrations embedded in the binary on startup
+	Listen                 bool   `env:"LISTEN"`                                 // send messages as soon as they are inserted, notified by Postgres
+	MaxOpenConns           int    `env:"MAX_OPEN_CONNS, default=25"`             // connections open at once, in use or idle; 0 means unlimited
+	MaxIdleConns           int    `env:"MAX_IDLE_CONNS, default=25"`             // idle connections kept for reuse; 0 means none
+	ConnMaxLifetimeSeconds int    `env:"CONN_MAX_LIFETIME_SECONDS, default=300"` // time a connection is reused before it is closed; 0 means forever
 }
 
 // RedisConfig holds Redis client settings and cache key for message storage.
@@ -69,9 +259,57 @@ func Load(ctx context.Context) (*AppConfig, error) {
 	}); err != nil {
 		return nil, errors.Wrap(err, "load config")
 	}
+	if err := ret.validate(); err != nil {
+		return nil, errors.Wrap(err, "load config")
+	}
 	return &ret, nil
 }
 
+// validate rejects settings that would otherwise be silently ignored.
+func (c *AppConfig) validate() error {
+	switch c.API.Docs {
+	case DocsPublic, DocsAdmin, DocsDisabled:
+	default:
+		return errors.Errorf("API_DOCS must be %s, %s or %s, got %q", DocsPublic, DocsAdmin, DocsDisabled, c.API.Docs)
+	}
+	switch c.SenderType {
+	case SenderWebhook, SenderFake:
+	case SenderTwilio:
+		if c.Twilio.AccountSID == "" || c.Twilio.AuthToken == "" || c.Twilio.FromNumber == "" {
+			return errors.New("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM_NUMBER are required with SENDER_TYPE twilio")
+		}
+	default:
+		return errors.Errorf("SENDER_TYPE must be %s, %s or %s, got %q", SenderWebhook, SenderTwilio, SenderFake, c.SenderType)
+	}
+	if c.SMTP.Host != "" {
+		if c.SMTP.From == "" {
+			return errors.New("SMTP_FROM is required with SMTP_HOST")
+		}
+		if _, ok := c.Webhook.ChannelURLs["email"]; ok {
+			return errors.New("emails are sent either over SMTP or to a webhook: set SMTP_HOST or the email URL of WEBHOOK_CHANNEL_URLS")
+		}
+	}
+	if _, ok := c.Webhook.ChannelURLs["chat"]; ok && len(c.Chat.RoomURLs) > 0 {
+		return errors.New("chat posts go either to rooms or to a webhook: set CHAT_ROOM_URLS or the chat URL of WEBHOOK_CHANNEL_URLS")
+	}
+	switch c.Webhook.ContentType {
+	case "json":
+	case "form":
+		if c.Webhook.BatchSize > 1 {
+			return errors.New("WEBHOOK_BATCH_SIZE requires WEBHOOK_CONTENT_TYPE json")
+		}
+	default:
+		return errors.Errorf("WEBHOOK_CONTENT_TYPE must be json or form, got %q", c.Webhook.ContentType)
+	}
+	switch c.API.ResponseCache {
+	case ResponseCacheNone, ResponseCacheMemory, ResponseCacheRedis:
+	default:
+		return errors.Errorf("API_RESPONSE_CACHE must be %s, %s or %s, got %q",
+			ResponseCacheNone, ResponseCacheMemory, ResponseCacheRedis, c.API.ResponseCache)
+	}
+	return nil
+}
+
 // trimConfigValue is an envconfig mutator that trims whitespace from values.
 func trimConfigValue(_ context.Context, _, _, _, resolvedValue string) (string, bool, error) {
 	return strings.TrimSpace(resolvedValue), false, nil
@@ -34,6 +34,7 @@ type TimerDaemon struct {
 	job     ScheduledJobFunc // function to execute periodically
 	period  time.Duration    // interval between job executions
 	stop    chan struct{}    // channel to signal stop
+	nudge   chan struct{}    // pending request to run the job ahead of the next tick, buffered so requests coalesce
 	logger  *zerolog.Logger  // logger for lifecycle and job events
 	running bool             // indicates if the daemon is active
 	mu      sync.Mutex       // protects running and stop fields
@@ -50,6 +51,7 @@ func NewTimerDaemon(jobName string, job ScheduledJobFunc, period time.Duration,
 		job:     job,
 		period:  period,
 		stop:    make(chan struct{}),
+		nudge:   make(chan struct{}, 1),
 		logger:  logger,
 	}
 }
@@ -68,7 +70,7 @@ func (t *TimerDaemon) Start(ctx context.Context) error {
 	t.logger.Debug().Msgf("Starting daemon for: %s", t.jobName)
 	t.running = true
 
-	go t.runJob(ctx)
+	go t.runJob(ctx, t.stop)
 
 	return nil
 }
@@ -86,17 +88,43 @@ func (t *TimerDaemon) Stop(ctx context.Context) error {
 	close(t.stop)
 	// prepare channel for potential future restarts
 	t.stop = make(chan struct{})
+	t.running = false
 	t.logger.Debug().Msgf("Stopped daemon for: %s", t.jobName)
 	return nil
 }
 
+// Nudge runs the job right away instead of at the next tick, e.g. once new work arrived.
+// Nudges received while a nudged run is in progress coalesce into a single run after it.
+// If the daemon is not running, Nudge does nothing.
+func (t *TimerDaemon) Nudge() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.running {
+		return
+	}
+	select {
+	case t.nudge <- struct{}{}:
+	default:
+		// a run is pending already
+	}
+}
+
+// Running reports whether the job loop is active.
+func (t *TimerDaemon) Running() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running
+}
+
 // runJob contains the main loop that triggers the job at each tick.
-// It listens for context cancellation or stop signals to exit cleanly.
-func (t *TimerDaemon) runJob(ctx context.Context) {
-	// ensure running flag is cleared when this goroutine exits
+// It listens for context cancellation or the given stop channel being closed to exit cleanly.
+func (t *TimerDaemon) runJob(ctx context.Context, stop <-chan struct{}) {
+	// ensure running flag is cleared when this goroutine exits, unless Stop already did and the daemon was restarted since
 	defer func() {
 		t.mu.Lock()
-		t.running = false
+		if t.stop == stop {
+			t.running = false
+		}
 		t.mu.Unlock()
 	}()
 
@@ -108,18 +136,24 @@ func (t *TimerDaemon) runJob(ctx context.Context) {
 		case <-ctx.Done():
 			// context canceled, exit
 			return
-		case <-t.stop:
+		case <-stop:
 			// explicit stop signal, exit
 			return
 		case <-ticker.C:
 			// trigger the job asynchronously to avoid blocking
-			go func() {
-				t.logger.Debug().Msgf("running job: %s", t.jobName)
-				if err := t.job(ctx); err != nil {
-					t.logger.Error().Err(err).Msgf("job failed: %s", t.jobName)
-				}
-				t.logger.Debug().Msgf("finished job: %s", t.jobName)
-			}()
+			go t.run(ctx)
+		case <-t.nudge:
+			// run nudged jobs one at a time, so a burst of nudges does not start a burst of runs
+			t.run(ctx)
 		}
 	}
 }
+
+// run executes the job once, logging its failure.
+func (t *TimerDaemon) run(ctx context.Context) {
+	t.logger.Debug().Msgf("running job: %s", t.jobName)
+	if err := t.job(ctx); err != nil {
+		t.logger.Error().Err(err).Msgf("job failed: %s", t.jobName)
+	}
+	t.logger.Debug().Msgf("finished job: %s", t.jobName)
+}
@@ -126,3 +126,59 @@ func TestTimerDaemon_MultipleStartCallsDoNothing(t *testing.T) {
 	// clean up
 	_ = td.Stop(context.Background())
 }
+
+func TestTimerDaemon_Running(t *testing.T) {
+	logger := zerolog.New(io.Discard)
+	td := daemon.NewTimerDaemon("test-job", func(context.Context) error { return nil }, time.Hour, &logger)
+
+	if td.Running() {
+		t.Fatal("daemon reports running before Start")
+	}
+	if err := td.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if !td.Running() {
+		t.Error("daemon does not report running after Start")
+	}
+	if err := td.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	// the job loop exits asynchronously
+	deadline := time.Now().Add(time.Second)
+	for td.Running() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if td.Running() {
+		t.Error("daemon still reports running after Stop")
+	}
+}
+
+func TestTimerDaemon_NudgeRunsJobAheadOfTick(t *testing.T) {
+	runs := make(chan struct{}, 10)
+	logger := zerolog.New(io.Discard)
+	td := daemon.NewTimerDaemon("test-job", func(context.Context) error {
+		runs <- struct{}{}
+		return nil
+	}, time.Hour, &logger)
+
+	// nudging a stopped daemon does nothing
+	td.Nudge()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := td.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	select {
+	case <-runs:
+		t.Fatal("job ran for a nudge received before Start")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	td.Nudge()
+
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		t.Fatal("job did not run after Nudge")
+	}
+}
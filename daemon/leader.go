@@ -0,0 +1,154 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Lease is a lock held by at most one instance at a time, e.g. a key in a store shared by all replicas.
+// It expires unless its holder renews it in time, so another instance can take over from a holder that died.
+type Lease interface {
+	// Acquire takes the lease for ttl if nobody holds it, or extends it to ttl from now if this instance holds it
+	// already, and reports whether this instance holds it.
+	Acquire(ctx context.Context, ttl time.Duration) (bool, error)
+
+	// Release gives the lease up if this instance holds it, so another instance can take over right away.
+	Release(ctx context.Context) error
+}
+
+// LeaderDaemon runs a wrapped Daemon only while this instance holds a Lease, so among replicas sharing the lease
+// only one, the leader, runs it. Followers stand by, trying to acquire the lease at a third of its TTL, and start
+// the daemon once the leader stopped or died and its lease expired.
+type LeaderDaemon struct {
+	daemon  Daemon          // daemon run by the leader
+	lease   Lease           // lease held by the leader
+	ttl     time.Duration   // how long the lease is held without being renewed
+	logger  *zerolog.Logger // logger for leadership changes
+	mu      sync.Mutex      // protects stop, done and running
+	stop    chan struct{}   // channel to signal stop
+	done    chan struct{}   // closed once the campaign loop stepped down and exited
+	running bool            // indicates if the campaign loop is active
+	leader  bool            // whether this instance holds the lease; only accessed by the campaign loop
+}
+
+// Ensure LeaderDaemon implements the Daemon interface.
+var _ Daemon = (*LeaderDaemon)(nil)
+
+// RunAsLeader returns a LeaderDaemon running d only while holding lease, which is acquired for ttl at a time.
+func RunAsLeader(d Daemon, lease Lease, ttl time.Duration, logger *zerolog.Logger) *LeaderDaemon {
+	return &LeaderDaemon{
+		daemon: d,
+		lease:  lease,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// Start begins campaigning for the lease, starting the wrapped daemon whenever this instance becomes the leader.
+// Subsequent calls to Start while running have no effect.
+func (l *LeaderDaemon) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running {
+		return nil
+	}
+	l.running = true
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	go l.campaign(ctx, l.stop, l.done)
+	return nil
+}
+
+// Stop ends campaigning. A leader stops the wrapped daemon and releases the lease before Stop returns,
+// so another instance can take over right away. If not running, Stop returns immediately.
+func (l *LeaderDaemon) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.running {
+		l.mu.Unlock()
+		return nil
+	}
+	close(l.stop)
+	l.running = false
+	done := l.done
+	l.mu.Unlock()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Running reports whether the campaign loop is active, whether this instance leads or stands by.
+func (l *LeaderDaemon) Running() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.running
+}
+
+// campaign acquires or renews the lease right away and then at a third of its TTL, starting the wrapped daemon when
+// the lease is won and stopping it when it is lost. It exits once ctx is done or stop is closed, stepping down first.
+func (l *LeaderDaemon) campaign(ctx context.Context, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	defer func() {
+		// the wrapped daemon and the lease are given up even if ctx is canceled
+		l.stepDown(context.WithoutCancel(ctx))
+		l.mu.Lock()
+		if l.stop == stop {
+			l.running = false
+		}
+		l.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(max(l.ttl/3, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		l.elect(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// elect tries to acquire the lease, starting the wrapped daemon if this instance just became the leader,
+// or stepping down if it lost the lease. A leader that cannot renew its lease steps down too, as another
+// instance takes over once the lease expires.
+func (l *LeaderDaemon) elect(ctx context.Context) {
+	held, err := l.lease.Acquire(ctx, l.ttl)
+	if err != nil {
+		l.logger.Error().Err(err).Msg("Failed to acquire leader lease")
+	}
+	switch {
+	case held && !l.leader:
+		if err := l.daemon.Start(ctx); err != nil {
+			l.logger.Error().Err(err).Msg("Failed to start daemon after becoming leader")
+			return
+		}
+		l.leader = true
+		l.logger.Info().Msg("Became leader, started daemon")
+	case !held && l.leader:
+		l.stepDown(ctx)
+		l.logger.Info().Msg("Lost leadership, stopped daemon")
+	}
+}
+
+// stepDown stops the wrapped daemon and releases the lease, if this instance is the leader.
+func (l *LeaderDaemon) stepDown(ctx context.Context) {
+	if !l.leader {
+		return
+	}
+	l.leader = false
+	if err := l.daemon.Stop(ctx); err != nil {
+		l.logger.Error().Err(err).Msg("Failed to stop daemon when stepping down")
+	}
+	if err := l.lease.Release(ctx); err != nil {
+		l.logger.Error().Err(err).Msg("Failed to release leader lease")
+	}
+}
@@ -0,0 +1,128 @@
+package daemon_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/daemon"
+	"github.com/rs/zerolog"
+)
+
+// memoryLease is a daemon.Lease shared by several holders in memory; it does not expire.
+type memoryLease struct {
+	mu     *sync.Mutex
+	owner  *string
+	holder string
+}
+
+// shareLease returns one lease per holder, all on the same lock.
+func shareLease(holders ...string) []*memoryLease {
+	mu, owner := &sync.Mutex{}, new(string)
+	ret := make([]*memoryLease, len(holders))
+	for i, h := range holders {
+		ret[i] = &memoryLease{mu: mu, owner: owner, holder: h}
+	}
+	return ret
+}
+
+func (m *memoryLease) Acquire(context.Context, time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if *m.owner == "" {
+		*m.owner = m.holder
+	}
+	return *m.owner == m.holder, nil
+}
+
+func (m *memoryLease) Release(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if *m.owner == m.holder {
+		*m.owner = ""
+	}
+	return nil
+}
+
+// switchDaemon records whether it was started last.
+type switchDaemon struct {
+	on atomic.Bool
+}
+
+func (s *switchDaemon) Start(context.Context) error {
+	s.on.Store(true)
+	return nil
+}
+
+func (s *switchDaemon) Stop(context.Context) error {
+	s.on.Store(false)
+	return nil
+}
+
+// eventually fails the test unless cond holds within a second.
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLeaderDaemon_SingleLeaderTakesOver(t *testing.T) {
+	logger := zerolog.Nop()
+	leases := shareLease("a", "b")
+	first, second := &switchDaemon{}, &switchDaemon{}
+	a := daemon.RunAsLeader(first, leases[0], 30*time.Millisecond, &logger)
+	b := daemon.RunAsLeader(second, leases[1], 30*time.Millisecond, &logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := a.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	eventually(t, first.on.Load, "first instance did not start its daemon as leader")
+	if err := b.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if second.on.Load() {
+		t.Fatal("follower started its daemon while the leader holds the lease")
+	}
+
+	if err := a.Stop(ctx); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if first.on.Load() {
+		t.Error("leader did not stop its daemon when stopped")
+	}
+	eventually(t, second.on.Load, "follower did not take over after the leader stopped")
+	if a.Running() || !b.Running() {
+		t.Errorf("Running() = %v and %v, want false and true", a.Running(), b.Running())
+	}
+}
+
+func TestLeaderDaemon_StepsDownOnCancel(t *testing.T) {
+	logger := zerolog.Nop()
+	leases := shareLease("a")
+	inner := &switchDaemon{}
+	d := daemon.RunAsLeader(inner, leases[0], 30*time.Millisecond, &logger)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	eventually(t, inner.on.Load, "daemon was not started")
+	cancel()
+
+	eventually(t, func() bool { return !inner.on.Load() && !d.Running() }, "daemon was not stopped after cancellation")
+	leases[0].mu.Lock()
+	defer leases[0].mu.Unlock()
+	if *leases[0].owner != "" {
+		t.Errorf("lease still held by %q after stepping down", *leases[0].owner)
+	}
+}
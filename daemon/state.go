@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// State is the desired state of a daemon, as chosen through Start and Stop.
+type State string
+
+const (
+	StateRunning State = "running" // the daemon was started and should run
+	StatePaused  State = "paused"  // the daemon was stopped on purpose and should stay stopped
+)
+
+// StateStore persists the desired State of daemons by name, so it outlives the process.
+type StateStore interface {
+	// LoadState returns the State saved for the named daemon, or an empty State if none was saved.
+	LoadState(ctx context.Context, name string) (State, error)
+
+	// SaveState saves the State of the named daemon.
+	SaveState(ctx context.Context, name string, state State) error
+}
+
+// StatefulDaemon wraps a Daemon, saving whether it was last started or stopped,
+// so a daemon paused by an operator stays paused after a restart.
+type StatefulDaemon struct {
+	Daemon            // wrapped daemon
+	name   string     // name the state is saved under
+	store  StateStore // storage of the desired state
+}
+
+// Ensure StatefulDaemon implements the Daemon interface.
+var _ Daemon = (*StatefulDaemon)(nil)
+
+// RememberState returns a StatefulDaemon saving the desired state of d in store under name.
+func RememberState(d Daemon, name string, store StateStore) *StatefulDaemon {
+	return &StatefulDaemon{
+		Daemon: d,
+		name:   name,
+		store:  store,
+	}
+}
+
+// Start saves the running state, then starts the wrapped daemon.
+// If the state cannot be saved, the daemon is left as it is.
+func (s *StatefulDaemon) Start(ctx context.Context) error {
+	if err := s.store.SaveState(ctx, s.name, StateRunning); err != nil {
+		return errors.Wrap(err, "saving daemon state")
+	}
+	return s.Daemon.Start(ctx)
+}
+
+// Stop saves the paused state, then stops the wrapped daemon.
+// If the state cannot be saved, the daemon is left as it is.
+func (s *StatefulDaemon) Stop(ctx context.Context) error {
+	if err := s.store.SaveState(ctx, s.name, StatePaused); err != nil {
+		return errors.Wrap(err, "saving daemon state")
+	}
+	return s.Daemon.Stop(ctx)
+}
+
+// Resume starts the wrapped daemon on boot unless it was paused before, without saving any state.
+// Daemons without a saved state are started. The daemon is not started if the state cannot be loaded.
+func (s *StatefulDaemon) Resume(ctx context.Context) (State, error) {
+	state, err := s.store.LoadState(ctx, s.name)
+	if err != nil {
+		return "", errors.Wrap(err, "loading daemon state")
+	}
+	if state == StatePaused {
+		return StatePaused, nil
+	}
+	return StateRunning, s.Daemon.Start(ctx)
+}
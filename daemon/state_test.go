@@ -0,0 +1,124 @@
+package daemon_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/daemon"
+)
+
+// memoryStateStore keeps daemon states in memory, failing every call with err if set.
+type memoryStateStore struct {
+	states map[string]daemon.State
+	err    error
+}
+
+func (m *memoryStateStore) LoadState(_ context.Context, name string) (daemon.State, error) {
+	return m.states[name], m.err
+}
+
+func (m *memoryStateStore) SaveState(_ context.Context, name string, state daemon.State) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.states[name] = state
+	return nil
+}
+
+// recordingDaemon counts Start and Stop calls.
+type recordingDaemon struct {
+	starts, stops int
+}
+
+func (r *recordingDaemon) Start(context.Context) error {
+	r.starts++
+	return nil
+}
+
+func (r *recordingDaemon) Stop(context.Context) error {
+	r.stops++
+	return nil
+}
+
+func TestStatefulDaemon_SavesStartAndStop(t *testing.T) {
+	store := &memoryStateStore{states: map[string]daemon.State{}}
+	inner := &recordingDaemon{}
+	d := daemon.RememberState(inner, "sender", store)
+
+	if err := d.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if got := store.states["sender"]; got != daemon.StatePaused {
+		t.Errorf("state after Stop = %q, want %q", got, daemon.StatePaused)
+	}
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if got := store.states["sender"]; got != daemon.StateRunning {
+		t.Errorf("state after Start = %q, want %q", got, daemon.StateRunning)
+	}
+	if inner.starts != 1 || inner.stops != 1 {
+		t.Errorf("inner daemon started %d and stopped %d times, want 1 and 1", inner.starts, inner.stops)
+	}
+}
+
+func TestStatefulDaemon_LeavesDaemonAloneWhenSavingFails(t *testing.T) {
+	store := &memoryStateStore{states: map[string]daemon.State{}, err: errors.New("connection refused")}
+	inner := &recordingDaemon{}
+	d := daemon.RememberState(inner, "sender", store)
+
+	if err := d.Stop(context.Background()); err == nil {
+		t.Fatal("Stop returned no error")
+	}
+	if inner.stops != 0 {
+		t.Errorf("inner daemon stopped %d times, want 0", inner.stops)
+	}
+}
+
+func TestStatefulDaemon_Resume(t *testing.T) {
+	tests := []struct {
+		name       string
+		saved      daemon.State
+		wantState  daemon.State
+		wantStarts int
+	}{
+		{name: "nothing saved", saved: "", wantState: daemon.StateRunning, wantStarts: 1},
+		{name: "running", saved: daemon.StateRunning, wantState: daemon.StateRunning, wantStarts: 1},
+		{name: "paused", saved: daemon.StatePaused, wantState: daemon.StatePaused, wantStarts: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryStateStore{states: map[string]daemon.State{"sender": tt.saved}}
+			inner := &recordingDaemon{}
+			d := daemon.RememberState(inner, "sender", store)
+
+			state, err := d.Resume(context.Background())
+
+			if err != nil {
+				t.Fatalf("Resume returned error: %v", err)
+			}
+			if state != tt.wantState {
+				t.Errorf("Resume() = %q, want %q", state, tt.wantState)
+			}
+			if inner.starts != tt.wantStarts {
+				t.Errorf("inner daemon started %d times, want %d", inner.starts, tt.wantStarts)
+			}
+			if store.states["sender"] != tt.saved {
+				t.Errorf("Resume changed the saved state to %q", store.states["sender"])
+			}
+		})
+	}
+}
+
+func TestStatefulDaemon_ResumeFailsWithoutState(t *testing.T) {
+	inner := &recordingDaemon{}
+	d := daemon.RememberState(inner, "sender", &memoryStateStore{err: errors.New("connection refused")})
+
+	if _, err := d.Resume(context.Background()); err == nil {
+		t.Fatal("Resume returned no error")
+	}
+	if inner.starts != 0 {
+		t.Errorf("inner daemon started %d times, want 0", inner.starts)
+	}
+}
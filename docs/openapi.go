@@ -0,0 +1,11 @@
+// Package docs holds the OpenAPI 3 document of the HTTP API.
+// The document is maintained by hand and is the source of truth for the API contract: requests are validated
+// against it and Swagger UI renders it, so update it together with the handlers.
+package docs
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 document of the API in YAML.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
@@ -0,0 +1,200 @@
+// Package email implements message.Sender delivering the messages of message.ChannelEmail over SMTP.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// SubjectVariable is the template variable whose value is the subject of a templated email.
+const SubjectVariable = "subject"
+
+// OptFunc configures optional behavior on Options.
+type OptFunc func(options *Options)
+
+// Options holds sender customization settings.
+type Options struct {
+	auth      smtp.Auth   // authentication with the server; none if nil
+	subject   string      // subject of emails not setting one
+	tlsConfig *tls.Config // configuration of STARTTLS; verifies the server host name if nil
+}
+
+// defaultOpts returns default Options sending unauthenticated emails with an empty subject.
+func defaultOpts() *Options {
+	return &Options{}
+}
+
+// WithAuth authenticates with the SMTP server with auth, e.g. smtp.PlainAuth.
+func WithAuth(auth smtp.Auth) OptFunc {
+	return func(options *Options) {
+		options.auth = auth
+	}
+}
+
+// WithSubject sets the subject of emails whose message does not set one through SubjectVariable.
+func WithSubject(subject string) OptFunc {
+	return func(options *Options) {
+		options.subject = subject
+	}
+}
+
+// WithTLSConfig sets the TLS configuration connections are upgraded with when the server supports STARTTLS.
+func WithTLSConfig(cfg *tls.Config) OptFunc {
+	return func(options *Options) {
+		options.tlsConfig = cfg
+	}
+}
+
+// SMTPSender delivers messages as plain text emails through an SMTP server, one connection per message.
+// The recipient is the To address of the message, the subject the SubjectVariable of templated messages or the
+// configured default subject otherwise. Connections are upgraded with STARTTLS when the server supports it.
+type SMTPSender struct {
+	addr     string   // host:port of the SMTP server
+	from     string   // From header of the emails, e.g. "Insider <noreply@example.com>"
+	envelope string   // plain address emails are sent from
+	opts     *Options // sender configuration options
+}
+
+// Ensure SMTPSender implements the message.Sender interface.
+var _ message.Sender = (*SMTPSender)(nil)
+
+// NewSMTPSender constructs an SMTPSender sending emails from the address from, optionally with a display name, through
+// the SMTP server at addr, given as host:port, applying any provided functional options.
+// Returns an error if addr or from is invalid.
+func NewSMTPSender(addr, from string, optFuncs ...OptFunc) (*SMTPSender, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, errors.Wrap(err, "creating smtp sender")
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating smtp sender: parsing from address")
+	}
+	opts := defaultOpts()
+	for _, f := range optFuncs {
+		f(opts)
+	}
+	return &SMTPSender{addr: addr, from: sender.String(), envelope: sender.Address, opts: opts}, nil
+}
+
+// Send delivers msg as an email, returning the Message-ID header it was sent with as the provider message ID.
+// The connection is closed when ctx is done.
+func (s *SMTPSender) Send(ctx context.Context, msg *message.Message) (*message.SendResult, error) {
+	id, err := messageID(s.envelope)
+	if err != nil {
+		return nil, err
+	}
+	sentAt := time.Now()
+	body, err := s.compose(msg, id, sentAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.deliver(ctx, msg.To, body); err != nil {
+		return nil, errors.Wrap(err, "sending email")
+	}
+	return &message.SendResult{MessageID: id, SentAt: sentAt}, nil
+}
+
+// SendBatch sends msgs one by one, each over its own connection.
+func (s *SMTPSender) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	return message.SendEach(ctx, s, msgs)
+}
+
+// deliver transmits the email body to the recipient to through the SMTP server.
+func (s *SMTPSender) deliver(ctx context.Context, to string, body []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return errors.Wrap(err, "connecting")
+	}
+	// unblock the conversation once ctx is done
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	host, _, _ := net.SplitHostPort(s.addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return errors.Wrap(err, "greeting")
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		cfg := s.opts.tlsConfig
+		if cfg == nil {
+			cfg = &tls.Config{ServerName: host}
+		}
+		if err := c.StartTLS(cfg); err != nil {
+			return errors.Wrap(err, "starting tls")
+		}
+	}
+	if s.opts.auth != nil {
+		if err := c.Auth(s.opts.auth); err != nil {
+			return errors.Wrap(err, "authenticating")
+		}
+	}
+	if err := c.Mail(s.envelope); err != nil {
+		return errors.Wrap(err, "setting sender")
+	}
+	if err := c.Rcpt(to); err != nil {
+		return errors.Wrap(err, "setting recipient")
+	}
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, "starting data")
+	}
+	if _, err := w.Write(body); err != nil {
+		return errors.Wrap(err, "writing data")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "writing data")
+	}
+	return c.Quit()
+}
+
+// compose returns the email delivering msg with the Message-ID id, sent at sentAt.
+func (s *SMTPSender) compose(msg *message.Message, id string, sentAt time.Time) ([]byte, error) {
+	subject := s.opts.subject
+	if v, ok := msg.Variables[SubjectVariable]; ok {
+		subject = v
+	}
+	// line breaks would end the header early
+	subject = strings.Join(strings.Fields(subject), " ")
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", sentAt.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s>\r\n", id)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(msg.Content)); err != nil {
+		return nil, errors.Wrap(err, "encoding content")
+	}
+	if err := qp.Close(); err != nil {
+		return nil, errors.Wrap(err, "encoding content")
+	}
+	return buf.Bytes(), nil
+}
+
+// messageID returns a new unique Message-ID, without angle brackets, in the domain of the plain address from.
+func messageID(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generating message id")
+	}
+	return hex.EncodeToString(b) + from[strings.LastIndex(from, "@"):], nil
+}
@@ -0,0 +1,155 @@
+package email_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/email"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// session records what an SMTP client sent to a fakeServer.
+type session struct {
+	auth string
+	from string
+	rcpt string
+	data string
+}
+
+// fakeServer starts an SMTP server accepting a single session, rejecting recipients starting with "reject",
+// and returns its address along with a channel receiving the session once it ended.
+func fakeServer(t *testing.T) (string, <-chan session) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	done := make(chan session, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var s session
+		defer func() { done <- s }()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch {
+			case cmd == "EHLO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case cmd == "AUTH":
+				creds, _ := base64.StdEncoding.DecodeString(strings.Fields(line)[2])
+				s.auth = string(creds)
+				reply("235 Authenticated")
+			case cmd == "MAIL":
+				s.from = line
+				reply("250 OK")
+			case cmd == "RCPT":
+				s.rcpt = line
+				if strings.Contains(line, "<reject") {
+					reply("550 No such user")
+					continue
+				}
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 Go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				s.data = data.String()
+				reply("250 Queued")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), done
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         *message.Message
+		wantSubject string
+	}{
+		{
+			name:        "default_subject",
+			msg:         &message.Message{ID: "1", Channel: message.ChannelEmail, To: "jane@example.com", Content: "Your code is 1234"},
+			wantSubject: "Subject: Notification",
+		},
+		{
+			name: "template_subject",
+			msg: &message.Message{ID: "1", Channel: message.ChannelEmail, To: "jane@example.com", Content: "Your code is 1234",
+				Template: "otp", Variables: map[string]string{email.SubjectVariable: "Your code\r\nBcc: evil@example.com"}},
+			wantSubject: "Subject: Your code Bcc: evil@example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, done := fakeServer(t)
+			sender, err := email.NewSMTPSender(addr, "Insider <noreply@insider.test>",
+				email.WithSubject("Notification"),
+				email.WithAuth(smtp.PlainAuth("", "user", "pass", "127.0.0.1")),
+			)
+			require.NoError(t, err)
+
+			res, err := sender.Send(context.Background(), tt.msg)
+
+			require.NoError(t, err)
+			assert.True(t, strings.HasSuffix(res.MessageID, "@insider.test"))
+			s := <-done
+			assert.Equal(t, "\x00user\x00pass", s.auth)
+			assert.Equal(t, "MAIL FROM:<noreply@insider.test>", s.from)
+			assert.Equal(t, "RCPT TO:<jane@example.com>", s.rcpt)
+			assert.Contains(t, s.data, "From: \"Insider\" <noreply@insider.test>\r\n")
+			assert.Contains(t, s.data, "To: jane@example.com\r\n")
+			assert.Contains(t, s.data, tt.wantSubject+"\r\n")
+			assert.Contains(t, s.data, "Message-ID: <"+res.MessageID+">\r\n")
+			assert.Contains(t, s.data, "\r\n\r\nYour code is 1234")
+		})
+	}
+}
+
+func TestSMTPSender_Send_RejectedRecipient(t *testing.T) {
+	addr, _ := fakeServer(t)
+	sender, err := email.NewSMTPSender(addr, "noreply@insider.test")
+	require.NoError(t, err)
+
+	_, err = sender.Send(context.Background(), &message.Message{ID: "1", Channel: message.ChannelEmail, To: "reject@example.com", Content: "hi"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "setting recipient")
+	assert.Contains(t, err.Error(), "No such user")
+}
+
+func TestNewSMTPSender_Invalid(t *testing.T) {
+	_, err := email.NewSMTPSender("localhost", "noreply@insider.test")
+	require.Error(t, err)
+
+	_, err = email.NewSMTPSender("localhost:25", "not an address")
+	require.Error(t, err)
+}
@@ -0,0 +1,90 @@
+// Package fake implements a message.Sender simulating a provider, so the service runs locally without one.
+package fake
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	mathrand "math/rand/v2"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// ErrSimulatedFailure is returned for the sends a Sender fails on purpose.
+var ErrSimulatedFailure = errors.New("simulated send failure")
+
+// OptFunc configures optional behavior on Options.
+type OptFunc func(options *Options)
+
+// Options holds the simulated provider behavior.
+type Options struct {
+	minLatency  time.Duration // shortest time a send takes
+	maxLatency  time.Duration // longest time a send takes
+	failureRate float64       // fraction of sends failing with ErrSimulatedFailure
+}
+
+// defaultOpts returns default Options succeeding every send right away.
+func defaultOpts() *Options {
+	return &Options{}
+}
+
+// WithLatency makes every send take a random time between minLatency and maxLatency.
+func WithLatency(minLatency, maxLatency time.Duration) OptFunc {
+	return func(options *Options) {
+		options.minLatency = minLatency
+		options.maxLatency = max(minLatency, maxLatency)
+	}
+}
+
+// WithFailureRate fails the given fraction of sends, between 0 and 1, with ErrSimulatedFailure.
+func WithFailureRate(rate float64) OptFunc {
+	return func(options *Options) {
+		options.failureRate = min(max(rate, 0), 1)
+	}
+}
+
+// Sender pretends to deliver messages: it waits for the simulated latency, then fails randomly at the configured
+// rate or returns a generated provider message ID. It is safe for concurrent use.
+type Sender struct {
+	opts *Options // simulated provider behavior
+}
+
+// Ensure Sender implements the message.Sender interface.
+var _ message.Sender = (*Sender)(nil)
+
+// NewSender constructs a Sender applying any provided functional options.
+func NewSender(optFuncs ...OptFunc) *Sender {
+	opts := defaultOpts()
+	for _, f := range optFuncs {
+		f(opts)
+	}
+	return &Sender{opts: opts}
+}
+
+// Send simulates delivering msg. Returns the error of ctx if it is done before the simulated latency has passed.
+func (s *Sender) Send(ctx context.Context, _ *message.Message) (*message.SendResult, error) {
+	latency := s.opts.minLatency
+	if spread := s.opts.maxLatency - s.opts.minLatency; spread > 0 {
+		latency += mathrand.N(spread)
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(latency):
+	}
+	if s.opts.failureRate > 0 && mathrand.Float64() < s.opts.failureRate {
+		return nil, ErrSimulatedFailure
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrap(err, "generating message id")
+	}
+	return &message.SendResult{MessageID: "fake-" + hex.EncodeToString(b), SentAt: time.Now()}, nil
+}
+
+// SendBatch simulates delivering msgs one by one.
+func (s *Sender) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	return message.SendEach(ctx, s, msgs)
+}
@@ -0,0 +1,67 @@
+package fake_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/fake"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSender_Send(t *testing.T) {
+	sender := fake.NewSender(fake.WithLatency(10*time.Millisecond, 20*time.Millisecond))
+	start := time.Now()
+
+	res, err := sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	assert.Regexp(t, `^fake-[0-9a-f]{16}$`, res.MessageID)
+	other, err := sender.Send(context.Background(), &message.Message{ID: "2", To: "+905551234567", Content: "hello"})
+	require.NoError(t, err)
+	assert.NotEqual(t, res.MessageID, other.MessageID)
+}
+
+func TestSender_Send_FailureRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		failures int
+	}{
+		{name: "never", rate: 0, failures: 0},
+		{name: "always", rate: 1, failures: 50},
+		{name: "clamped", rate: 3, failures: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fake.NewSender(fake.WithFailureRate(tt.rate))
+			msgs := make([]*message.Message, 50)
+			for i := range msgs {
+				msgs[i] = &message.Message{ID: "1", To: "+905551234567", Content: "hello"}
+			}
+
+			var failures int
+			for _, r := range sender.SendBatch(context.Background(), msgs) {
+				if r.Err != nil {
+					assert.ErrorIs(t, r.Err, fake.ErrSimulatedFailure)
+					failures++
+				}
+			}
+
+			assert.Equal(t, tt.failures, failures)
+		})
+	}
+}
+
+func TestSender_Send_ContextCanceled(t *testing.T) {
+	sender := fake.NewSender(fake.WithLatency(time.Hour, time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := sender.Send(ctx, &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.ErrorIs(t, err, context.Canceled)
+}
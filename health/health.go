@@ -0,0 +1,156 @@
+// Package health reports the status of the service's dependencies, such as the database, the cache,
+// the webhook provider and the scheduler. Components register a check with a Registry, which runs them all on demand.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultTimeout bounds each check unless configured otherwise with WithTimeout.
+const defaultTimeout = 2 * time.Second
+
+// Status reports the health of a single component. Fields that do not apply to a component are left zero.
+type Status struct {
+	OK          bool          // whether the component is usable
+	Error       string        // why the component is not usable
+	Latency     time.Duration // round trip time of the probe, for probed components
+	LastSuccess time.Time     // last time the component was used successfully, for passively monitored components
+	Running     *bool         // whether the component is running, for background workers
+}
+
+// CheckFunc reports the current status of a component. It should return once ctx is done.
+type CheckFunc func(ctx context.Context) Status
+
+// Report is the outcome of running every registered check.
+type Report struct {
+	OK         bool              // whether every component is OK
+	Components map[string]Status // status of each component by name
+}
+
+// OptFunc configures optional behavior on Options.
+type OptFunc func(options *Options)
+
+// Options holds Registry settings.
+type Options struct {
+	timeout time.Duration // upper bound of a single check
+}
+
+// defaultOpts returns default Options bounding checks by defaultTimeout.
+func defaultOpts() *Options {
+	return &Options{
+		timeout: defaultTimeout,
+	}
+}
+
+// WithTimeout bounds the duration of each check. A check still running after d reports its component as not OK.
+// Non-positive values keep the default.
+func WithTimeout(d time.Duration) OptFunc {
+	return func(options *Options) {
+		if d > 0 {
+			options.timeout = d
+		}
+	}
+}
+
+// Registry holds the checks of all components and runs them concurrently.
+// It is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex         // protects checks
+	checks map[string]CheckFunc // registered checks by component name
+	opts   *Options             // registry configuration
+}
+
+// NewRegistry constructs an empty Registry, applying any provided functional options.
+func NewRegistry(optFuncs ...OptFunc) *Registry {
+	opts := defaultOpts()
+	// apply each configuration option
+	for _, f := range optFuncs {
+		f(opts)
+	}
+	return &Registry{
+		checks: make(map[string]CheckFunc),
+		opts:   opts,
+	}
+}
+
+// Register adds the check of the component called name, replacing any check registered under that name before.
+func (r *Registry) Register(name string, check CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = check
+}
+
+// Check runs every registered check concurrently, each bounded by the configured timeout.
+func (r *Registry) Check(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := make(map[string]CheckFunc, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	report := &Report{OK: true, Components: make(map[string]Status, len(checks))}
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := r.run(ctx, check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[name] = status
+			report.OK = report.OK && status.OK
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// run executes check, reporting a timeout if it does not return in time.
+func (r *Registry) run(ctx context.Context, check CheckFunc) Status {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.timeout)
+	defer cancel()
+	done := make(chan Status, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+	select {
+	case status := <-done:
+		return status
+	case <-ctx.Done():
+		return Status{Error: "check timed out"}
+	}
+}
+
+// Ping returns a CheckFunc probing a component with ping and reporting the round trip time.
+// The component is OK when ping returns no error, e.g. for sql.DB.PingContext.
+func Ping(ping func(ctx context.Context) error) CheckFunc {
+	return func(ctx context.Context) Status {
+		start := time.Now()
+		err := ping(ctx)
+		status := Status{OK: err == nil, Latency: time.Since(start)}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		return status
+	}
+}
+
+// Runner is a background worker that can report whether it is running, such as daemon.TimerDaemon.
+type Runner interface {
+	Running() bool
+}
+
+// Worker returns a CheckFunc reporting whether r is running. A stopped worker is still OK,
+// as stopping it is a deliberate choice.
+func Worker(r Runner) CheckFunc {
+	return func(context.Context) Status {
+		running := r.Running()
+		return Status{OK: true, Running: &running}
+	}
+}
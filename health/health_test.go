@@ -0,0 +1,85 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/health"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRunner bool
+
+func (r stubRunner) Running() bool { return bool(r) }
+
+type stubSender struct {
+	err error
+}
+
+func (s *stubSender) Send(context.Context, *message.Message) (*message.SendResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &message.SendResult{MessageID: "ext-1", SentAt: time.Now()}, nil
+}
+
+func TestRegistry_Check(t *testing.T) {
+	registry := health.NewRegistry()
+	registry.Register("postgres", health.Ping(func(context.Context) error { return nil }))
+	registry.Register("redis", health.Ping(func(context.Context) error { return errors.New("connection refused") }))
+	registry.Register("scheduler", health.Worker(stubRunner(false)))
+
+	report := registry.Check(context.Background())
+
+	assert.False(t, report.OK)
+	require.Len(t, report.Components, 3)
+	assert.True(t, report.Components["postgres"].OK)
+	assert.Positive(t, report.Components["postgres"].Latency)
+	assert.Equal(t, "connection refused", report.Components["redis"].Error)
+	scheduler := report.Components["scheduler"]
+	assert.True(t, scheduler.OK, "a stopped worker is healthy")
+	require.NotNil(t, scheduler.Running)
+	assert.False(t, *scheduler.Running)
+}
+
+func TestRegistry_Check_TimesOut(t *testing.T) {
+	registry := health.NewRegistry(health.WithTimeout(10 * time.Millisecond))
+	registry.Register("stuck", func(context.Context) health.Status {
+		time.Sleep(time.Second)
+		return health.Status{OK: true}
+	})
+
+	start := time.Now()
+	report := registry.Check(context.Background())
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.False(t, report.OK)
+	assert.Equal(t, "check timed out", report.Components["stuck"].Error)
+}
+
+func TestSenderMonitor(t *testing.T) {
+	sender := &stubSender{}
+	monitor := health.MonitorSender(message.SendOneByOne(sender))
+	ctx := context.Background()
+
+	status := monitor.Check(ctx)
+	assert.True(t, status.OK, "a sender that was not used yet is assumed healthy")
+	assert.True(t, status.LastSuccess.IsZero())
+
+	_, err := monitor.Send(ctx, &message.Message{})
+	require.NoError(t, err)
+	lastSuccess := monitor.Check(ctx).LastSuccess
+	assert.False(t, lastSuccess.IsZero())
+
+	sender.err = errors.New("provider down")
+	_, err = monitor.Send(ctx, &message.Message{})
+	require.Error(t, err)
+	status = monitor.Check(ctx)
+	assert.False(t, status.OK)
+	assert.Equal(t, "provider down", status.Error)
+	assert.Equal(t, lastSuccess, status.LastSuccess)
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// SenderMonitor decorates a message.Sender, tracking the outcome of its sends.
+// Since probing the provider would mean sending a message, its health is derived from real traffic instead.
+type SenderMonitor struct {
+	message.Sender
+	mu          sync.Mutex // protects the fields below
+	lastSuccess time.Time  // time of the last successful send
+	lastErr     error      // error of the last send, nil if it succeeded
+}
+
+var _ message.Sender = (*SenderMonitor)(nil) // ensure interface compliance
+
+// MonitorSender returns a SenderMonitor tracking the sends of sender.
+func MonitorSender(sender message.Sender) *SenderMonitor {
+	return &SenderMonitor{Sender: sender}
+}
+
+// Send delegates to the underlying sender and records the outcome.
+func (s *SenderMonitor) Send(ctx context.Context, msg *message.Message) (*message.SendResult, error) {
+	res, err := s.Sender.Send(ctx, msg)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+	if err == nil {
+		s.lastSuccess = time.Now()
+	}
+	return res, err
+}
+
+// SendBatch delegates to the underlying sender and records the outcome of the batch:
+// it counts as failed if any of its messages failed.
+func (s *SenderMonitor) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	results := s.Sender.SendBatch(ctx, msgs)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = nil
+	for _, r := range results {
+		if r.Err != nil {
+			s.lastErr = r.Err
+		} else {
+			s.lastSuccess = time.Now()
+		}
+	}
+	return results
+}
+
+// Check reports the sender as OK unless its last send failed. Before any send it is assumed to be OK.
+func (s *SenderMonitor) Check(context.Context) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := Status{OK: s.lastErr == nil, LastSuccess: s.lastSuccess}
+	if s.lastErr != nil {
+		status.Error = s.lastErr.Error()
+	}
+	return status
+}
@@ -9,7 +9,7 @@ import (
 )
 
 // Application wraps an application.App instance with logging middleware.
-// It logs calls to the SendNext, SendAllUnsent, and ListSentMessages methods.
+// It logs calls to the SendNext, SendAllUnsent, SendN, ListSentMessages, ExportSentMessages, FindSentMessages, FindUnsentMessages, FindFailedMessages, RequeueMessage, CancelMessage, DeleteMessage, CreateMessage, Stats, EnqueueMessages, GetMessage, ListAttempts, RecordDeliveryReport, ArchiveSentMessages, CreateSubscription, ListSubscriptions and DeleteSubscription methods.
 type Application struct {
 	application.App                // embedded application interface
 	logger          zerolog.Logger // logger to record method invocations
@@ -40,6 +40,14 @@ func (a *Application) SendAllUnsent(ctx context.Context) (err error) {
 	return a.App.SendAllUnsent(ctx)
 }
 
+// SendN logs entry and exit for the SendN method and delegates to the underlying App.
+// It logs an info message before and after the call, including the requested count and any error.
+func (a *Application) SendN(ctx context.Context, n int) (err error) {
+	a.logger.Info().Int("n", n).Msg("--> Application.SendN")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.SendN") }()
+	return a.App.SendN(ctx, n)
+}
+
 // ListSentMessages logs entry and exit for the ListSentMessages method and delegates to the underlying App.
 // It logs an info message before and after the call, including returned messages and any error.
 func (a *Application) ListSentMessages(ctx context.Context) (msgs []*message.SentMessage, err error) {
@@ -47,3 +55,148 @@ func (a *Application) ListSentMessages(ctx context.Context) (msgs []*message.Sen
 	defer func() { a.logger.Info().Err(err).Msg("<-- Application.ListSentMessages") }()
 	return a.App.ListSentMessages(ctx)
 }
+
+// ExportSentMessages logs entry and exit for the ExportSentMessages method and delegates to the underlying App.
+// It logs an info message before and after the call, including any error.
+func (a *Application) ExportSentMessages(ctx context.Context, fn func(*message.SentMessage) error) (err error) {
+	a.logger.Info().Msg("--> Application.ExportSentMessages")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.ExportSentMessages") }()
+	return a.App.ExportSentMessages(ctx, fn)
+}
+
+// FindSentMessages logs entry and exit for the FindSentMessages method and delegates to the underlying App.
+// It logs an info message before and after the call, including any error.
+func (a *Application) FindSentMessages(ctx context.Context, f message.Filter) (msgs []*message.SentMessage, err error) {
+	a.logger.Info().Msg("--> Application.FindSentMessages")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.FindSentMessages") }()
+	return a.App.FindSentMessages(ctx, f)
+}
+
+// FindUnsentMessages logs entry and exit for the FindUnsentMessages method and delegates to the underlying App.
+// It logs an info message before and after the call, including any error.
+func (a *Application) FindUnsentMessages(ctx context.Context, f message.Filter) (msgs []*message.Message, err error) {
+	a.logger.Info().Msg("--> Application.FindUnsentMessages")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.FindUnsentMessages") }()
+	return a.App.FindUnsentMessages(ctx, f)
+}
+
+// CreateMessage logs entry and exit for the CreateMessage method and delegates to the underlying App.
+// It logs an info message before and after the call, including whether a new message was stored and any error.
+func (a *Application) CreateMessage(ctx context.Context, msg *message.Message) (stored *message.Message, created bool, err error) {
+	a.logger.Info().Bool("idempotent", msg.IdempotencyKey != "").Msg("--> Application.CreateMessage")
+	defer func() { a.logger.Info().Bool("created", created).Err(err).Msg("<-- Application.CreateMessage") }()
+	return a.App.CreateMessage(ctx, msg)
+}
+
+// Stats logs entry and exit for the Stats method and delegates to the underlying App.
+// It logs an info message before and after the call, including any error.
+func (a *Application) Stats(ctx context.Context) (stats *message.Stats, err error) {
+	a.logger.Info().Msg("--> Application.Stats")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.Stats") }()
+	return a.App.Stats(ctx)
+}
+
+// EnqueueMessages logs entry and exit for the EnqueueMessages method and delegates to the underlying App.
+// It logs an info message before and after the call, including the input count, the queued and rejected counts
+// and any error.
+func (a *Application) EnqueueMessages(ctx context.Context, inputs []*application.NewMessageInput) (res *application.EnqueueResult, err error) {
+	a.logger.Info().Int("count", len(inputs)).Msg("--> Application.EnqueueMessages")
+	defer func() {
+		event := a.logger.Info()
+		if res != nil {
+			event = event.Int("queued", len(res.Queued)).Int("rejected", len(res.Rejected))
+		}
+		event.Err(err).Msg("<-- Application.EnqueueMessages")
+	}()
+	return a.App.EnqueueMessages(ctx, inputs)
+}
+
+// FindFailedMessages logs entry and exit for the FindFailedMessages method and delegates to the underlying App.
+// It logs an info message before and after the call, including any error.
+func (a *Application) FindFailedMessages(ctx context.Context, f message.Filter) (msgs []*message.Message, err error) {
+	a.logger.Info().Msg("--> Application.FindFailedMessages")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.FindFailedMessages") }()
+	return a.App.FindFailedMessages(ctx, f)
+}
+
+// RequeueMessage logs entry and exit for the RequeueMessage method and delegates to the underlying App.
+// It logs an info message before and after the call, including any error.
+func (a *Application) RequeueMessage(ctx context.Context, id string) (err error) {
+	a.logger.Info().Str("id", id).Msg("--> Application.RequeueMessage")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.RequeueMessage") }()
+	return a.App.RequeueMessage(ctx, id)
+}
+
+// CancelMessage logs entry and exit for the CancelMessage method and delegates to the underlying App.
+// It logs an info message before and after the call, including the message ID and any error.
+func (a *Application) CancelMessage(ctx context.Context, id string) (err error) {
+	a.logger.Info().Str("id", id).Msg("--> Application.CancelMessage")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.CancelMessage") }()
+	return a.App.CancelMessage(ctx, id)
+}
+
+// DeleteMessage logs entry and exit for the DeleteMessage method and delegates to the underlying App.
+// It logs an info message before and after the call, including the message ID and any error.
+func (a *Application) DeleteMessage(ctx context.Context, id string) (err error) {
+	a.logger.Info().Str("id", id).Msg("--> Application.DeleteMessage")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.DeleteMessage") }()
+	return a.App.DeleteMessage(ctx, id)
+}
+
+// RecordDeliveryReport logs entry and exit for the RecordDeliveryReport method and delegates to the underlying App.
+// It logs an info message before and after the call, including the provider message ID, the status and any error.
+func (a *Application) RecordDeliveryReport(ctx context.Context, r *message.DeliveryReport) (err error) {
+	a.logger.Info().Str("message_id", r.MessageID).Str("status", string(r.Status)).Msg("--> Application.RecordDeliveryReport")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.RecordDeliveryReport") }()
+	return a.App.RecordDeliveryReport(ctx, r)
+}
+
+// ArchiveSentMessages logs entry and exit for the ArchiveSentMessages method and delegates to the underlying App.
+// It logs an info message before and after the call, including the number of archived messages and any error.
+func (a *Application) ArchiveSentMessages(ctx context.Context) (archived int64, err error) {
+	a.logger.Info().Msg("--> Application.ArchiveSentMessages")
+	defer func() {
+		a.logger.Info().Int64("archived", archived).Err(err).Msg("<-- Application.ArchiveSentMessages")
+	}()
+	return a.App.ArchiveSentMessages(ctx)
+}
+
+// GetMessage logs entry and exit for the GetMessage method and delegates to the underlying App.
+// It logs an info message before and after the call, including the requested ID and any error.
+func (a *Application) GetMessage(ctx context.Context, id string) (msg *message.Message, err error) {
+	a.logger.Info().Str("id", id).Msg("--> Application.GetMessage")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.GetMessage") }()
+	return a.App.GetMessage(ctx, id)
+}
+
+// ListAttempts logs entry and exit for the ListAttempts method and delegates to the underlying App.
+// It logs an info message before and after the call, including the requested ID, the number of attempts and any error.
+func (a *Application) ListAttempts(ctx context.Context, id string) (attempts []*message.Attempt, err error) {
+	a.logger.Info().Str("id", id).Msg("--> Application.ListAttempts")
+	defer func() { a.logger.Info().Int("count", len(attempts)).Err(err).Msg("<-- Application.ListAttempts") }()
+	return a.App.ListAttempts(ctx, id)
+}
+
+// CreateSubscription logs entry and exit for the CreateSubscription method and delegates to the underlying App.
+// It logs an info message before and after the call, including the subscribed events and any error.
+func (a *Application) CreateSubscription(ctx context.Context, sub *message.Subscription) (ret *message.Subscription, err error) {
+	a.logger.Info().Strs("events", sub.Events).Msg("--> Application.CreateSubscription")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.CreateSubscription") }()
+	return a.App.CreateSubscription(ctx, sub)
+}
+
+// ListSubscriptions logs entry and exit for the ListSubscriptions method and delegates to the underlying App.
+// It logs an info message before and after the call, including any error.
+func (a *Application) ListSubscriptions(ctx context.Context) (subs []*message.Subscription, err error) {
+	a.logger.Info().Msg("--> Application.ListSubscriptions")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.ListSubscriptions") }()
+	return a.App.ListSubscriptions(ctx)
+}
+
+// DeleteSubscription logs entry and exit for the DeleteSubscription method and delegates to the underlying App.
+// It logs an info message before and after the call, including the requested ID and any error.
+func (a *Application) DeleteSubscription(ctx context.Context, id string) (err error) {
+	a.logger.Info().Str("id", id).Msg("--> Application.DeleteSubscription")
+	defer func() { a.logger.Info().Err(err).Msg("<-- Application.DeleteSubscription") }()
+	return a.App.DeleteSubscription(ctx, id)
+}
@@ -3,6 +3,7 @@
 package logging
 
 import (
+	"fmt"
 	"os"
 	"time"
 
@@ -34,40 +35,69 @@ const (
 // New creates and returns a configured zerolog.Logger based on the provided LogConfig.
 // It sets timestamp formatting to Unix milliseconds and attaches stack trace marshaling for errors.
 // For production, it returns a JSON logger; for development, a human-friendly console logger.
+//
+// The minimum level is applied process-wide rather than to the returned logger,
+// so it can later be changed with SetLevel without rebuilding loggers.
 func New(cfg LogConfig) zerolog.Logger {
 	// use Unix ms timestamps for all loggers
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnixMs
 	// enable pkg/errors stack trace marshaling
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+	zerolog.SetGlobalLevel(logLevelToZero(cfg.Level))
 
 	if cfg.IsProduction {
-		return prodLogger(cfg.Level)
+		return prodLogger()
 	}
-	return devLogger(cfg.Level)
+	return devLogger()
 }
 
-// prodLogger returns a zerolog.Logger that writes JSON-formatted logs to stdout
-// at the specified log level, including timestamps.
-func prodLogger(level Level) zerolog.Logger {
+// SetLevel atomically changes the minimum level of every logger created by New.
+// Returns an error if level is not one of the supported levels.
+func SetLevel(level Level) error {
+	if !level.valid() {
+		return fmt.Errorf("unknown log level %q", level)
+	}
+	zerolog.SetGlobalLevel(logLevelToZero(level))
+	return nil
+}
+
+// CurrentLevel returns the minimum level currently applied to loggers created by New.
+func CurrentLevel() Level {
+	return logLevelFromZero(zerolog.GlobalLevel())
+}
+
+// prodLogger returns a zerolog.Logger that writes JSON-formatted logs to stdout, including timestamps.
+// Filtering is left to the global level, see SetLevel.
+func prodLogger() zerolog.Logger {
 	return zerolog.New(os.Stdout).
-		Level(logLevelToZero(level)).
+		Level(zerolog.TraceLevel).
 		With().
 		Timestamp().
 		Logger()
 }
 
-// devLogger returns a zerolog.Logger that writes human-readable console logs to stdout
-// at the specified log level, including RFC3339 timestamps.
-func devLogger(level Level) zerolog.Logger {
+// devLogger returns a zerolog.Logger that writes human-readable console logs to stdout,
+// including RFC3339 timestamps. Filtering is left to the global level, see SetLevel.
+func devLogger() zerolog.Logger {
 	return zerolog.New(zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) {
 		w.TimeFormat = time.RFC3339
 	})).
-		Level(logLevelToZero(level)).
+		Level(zerolog.TraceLevel).
 		With().
 		Timestamp().
 		Logger()
 }
 
+// valid reports whether l is one of the supported levels.
+func (l Level) valid() bool {
+	switch l {
+	case TRACE, DEBUG, INFO, WARN, ERROR, PANIC:
+		return true
+	default:
+		return false
+	}
+}
+
 // logLevelToZero maps our Level type to zerolog.Level constants.
 // If the provided level is unrecognized, INFO is used as the default.
 func logLevelToZero(level Level) zerolog.Level {
@@ -88,3 +118,22 @@ func logLevelToZero(level Level) zerolog.Level {
 		return zerolog.InfoLevel
 	}
 }
+
+// logLevelFromZero maps zerolog.Level constants back to our Level type.
+// Levels without a counterpart are reported by their nearest supported level.
+func logLevelFromZero(level zerolog.Level) Level {
+	switch {
+	case level >= zerolog.PanicLevel:
+		return PANIC
+	case level >= zerolog.ErrorLevel:
+		return ERROR
+	case level == zerolog.WarnLevel:
+		return WARN
+	case level == zerolog.InfoLevel:
+		return INFO
+	case level == zerolog.DebugLevel:
+		return DEBUG
+	default:
+		return TRACE
+	}
+}
@@ -0,0 +1,69 @@
+package logging_test
+
+import (
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/logging"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// restoreLevel resets the process-wide level changed by a test.
+func restoreLevel(t *testing.T) {
+	t.Helper()
+	level := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+}
+
+func TestSetLevel_RoundTrip(t *testing.T) {
+	restoreLevel(t)
+	for _, level := range []logging.Level{logging.TRACE, logging.DEBUG, logging.INFO, logging.WARN, logging.ERROR, logging.PANIC} {
+		t.Run(string(level), func(t *testing.T) {
+			require.NoError(t, logging.SetLevel(level))
+			assert.Equal(t, level, logging.CurrentLevel())
+		})
+	}
+}
+
+func TestSetLevel_RejectsUnknownLevel(t *testing.T) {
+	restoreLevel(t)
+	require.NoError(t, logging.SetLevel(logging.WARN))
+
+	for _, level := range []logging.Level{"", "debug", "VERBOSE", "FATAL"} {
+		t.Run(string(level), func(t *testing.T) {
+			err := logging.SetLevel(level)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "unknown log level")
+			assert.Equal(t, logging.WARN, logging.CurrentLevel(), "a rejected level must not change the current one")
+		})
+	}
+}
+
+func TestCurrentLevel_ReportsNearestSupportedLevel(t *testing.T) {
+	restoreLevel(t)
+	tests := []struct {
+		zero zerolog.Level
+		want logging.Level
+	}{
+		{zero: zerolog.TraceLevel, want: logging.TRACE},
+		{zero: zerolog.FatalLevel, want: logging.ERROR},
+		{zero: zerolog.NoLevel, want: logging.PANIC},
+		{zero: zerolog.Disabled, want: logging.PANIC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zero.String(), func(t *testing.T) {
+			zerolog.SetGlobalLevel(tt.zero)
+			assert.Equal(t, tt.want, logging.CurrentLevel())
+		})
+	}
+}
+
+func TestNew_AppliesConfiguredLevel(t *testing.T) {
+	restoreLevel(t)
+
+	logging.New(logging.LogConfig{IsProduction: true, Level: logging.DEBUG})
+
+	assert.Equal(t, logging.DEBUG, logging.CurrentLevel())
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/rs/zerolog"
+)
+
+// Sender wraps a message.Sender with logging middleware.
+// It logs the outcome of every call to the Send and SendBatch methods at debug level, and failures at warn level.
+type Sender struct {
+	message.Sender                // embedded sender performing delivery
+	logger         zerolog.Logger // logger to record send outcomes
+}
+
+var _ message.Sender = (*Sender)(nil) // ensure interface compliance
+
+// LogSends returns a message.SenderMiddleware wrapping senders in a logging.Sender
+// that emits log entries using the provided zerolog.Logger.
+func LogSends(logger zerolog.Logger) message.SenderMiddleware {
+	return func(sender message.Sender) message.Sender {
+		return &Sender{Sender: sender, logger: logger}
+	}
+}
+
+// Send delegates to the underlying sender and logs the provider message ID, or the error, of the attempt.
+func (s *Sender) Send(ctx context.Context, msg *message.Message) (*message.SendResult, error) {
+	res, err := s.Sender.Send(ctx, msg)
+	if err != nil {
+		s.logger.Warn().Err(err).Str("id", msg.ID).Msg("Failed to send message")
+		return nil, err
+	}
+	s.logger.Debug().Str("id", msg.ID).Str("messageId", res.MessageID).Msg("Sent message")
+	return res, nil
+}
+
+// SendBatch delegates to the underlying sender and logs the outcome of every message of the batch.
+func (s *Sender) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	results := s.Sender.SendBatch(ctx, msgs)
+	for i, r := range results {
+		if r.Err != nil {
+			s.logger.Warn().Err(r.Err).Str("id", msgs[i].ID).Msg("Failed to send message")
+			continue
+		}
+		s.logger.Debug().Str("id", msgs[i].ID).Str("messageId", r.Result.MessageID).Msg("Sent message")
+	}
+	return results
+}
@@ -0,0 +1,185 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// maxLoggedBody is the number of bytes of a request or response body logged by Transport.
+const maxLoggedBody = 4 << 10
+
+// redacted replaces the values of sensitive headers and fields in logged payloads.
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are the headers whose values Transport always redacts.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// sensitiveFields are the names, compared case-insensitively without separators, of the JSON and form fields whose
+// values Transport redacts.
+var sensitiveFields = map[string]bool{"password": true, "secret": true, "token": true, "apikey": true, "authtoken": true}
+
+// loggingTransport is an http.RoundTripper logging requests and responses along with their bodies.
+type loggingTransport struct {
+	base    http.RoundTripper // transport executing the requests
+	logger  zerolog.Logger    // logger to record requests and responses
+	headers []string          // headers whose values are redacted
+}
+
+// Transport returns an http.RoundTripper that logs every request made through base and the response to it at trace
+// level, so provider integrations can be debugged from the exact payloads exchanged. Both entries carry a request_id
+// generated per request. Logged payloads are sanitized: URLs lose their query and credentials, the values of
+// authorization headers, of the headers named in redact, e.g. a custom auth header, and of password, secret and token
+// fields are replaced, and bodies are cut at 4 KiB. Nothing is read or logged unless trace level is enabled.
+// A nil base uses http.DefaultTransport.
+func Transport(base http.RoundTripper, logger zerolog.Logger, redact ...string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &loggingTransport{base: base, logger: logger, headers: slices.Concat(redact, sensitiveHeaders)}
+}
+
+// RoundTrip logs req, executes it with the base transport and logs the response or error.
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.logger.Trace().Enabled() {
+		return t.base.RoundTrip(req)
+	}
+	id := uuid.New().String()
+	// the request must not be modified, so its body is passed on through a clone
+	req = req.Clone(req.Context())
+	body, err := peekRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	t.logger.Trace().
+		Str("request_id", id).
+		Str("method", req.Method).
+		Str("url", sanitizeURL(req.URL)).
+		Interface("headers", t.sanitizeHeaders(req.Header)).
+		Str("body", sanitizeBody(body, req.Header.Get("Content-Type"))).
+		Msg("--> outbound request")
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.logger.Trace().Str("request_id", id).Err(err).Dur("latency_ms", time.Since(start)).Msg("<-- outbound request failed")
+		return nil, err
+	}
+	body = peekResponse(resp)
+	t.logger.Trace().
+		Str("request_id", id).
+		Int("status", resp.StatusCode).
+		Dur("latency_ms", time.Since(start)).
+		Interface("headers", t.sanitizeHeaders(resp.Header)).
+		Str("body", sanitizeBody(body, resp.Header.Get("Content-Type"))).
+		Msg("<-- outbound response")
+	return resp, nil
+}
+
+// peekRequest returns the body of req, replacing it with a copy to be sent.
+func peekRequest(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// peekResponse returns the start of the body of resp, leaving the body to be read in full by the caller.
+func peekResponse(resp *http.Response) []byte {
+	prefix, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), resp.Body), resp.Body}
+	return prefix
+}
+
+// sanitizeURL returns u without its query and user info, which may carry credentials.
+func sanitizeURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	clean.RawQuery = ""
+	clean.Fragment = ""
+	return clean.String()
+}
+
+// sanitizeHeaders returns the headers of h with the values of sensitive ones redacted.
+func (t *loggingTransport) sanitizeHeaders(h http.Header) http.Header {
+	clean := h.Clone()
+	for _, name := range t.headers {
+		if clean.Get(name) != "" {
+			clean.Set(name, redacted)
+		}
+	}
+	return clean
+}
+
+// sanitizeBody returns body with the values of sensitive fields redacted, for JSON and form bodies, cut at
+// maxLoggedBody bytes. Other bodies are returned as they are.
+func sanitizeBody(body []byte, contentType string) string {
+	clean := redactBody(body, contentType)
+	return clean[:min(len(clean), maxLoggedBody)]
+}
+
+// redactBody returns body with the values of sensitive fields redacted.
+func redactBody(body []byte, contentType string) string {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return string(body)
+		}
+		for key := range form {
+			if sensitive(key) {
+				form.Set(key, redacted)
+			}
+		}
+		return form.Encode()
+	}
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return string(body)
+	}
+	clean, err := json.Marshal(redactFields(doc))
+	if err != nil {
+		return string(body)
+	}
+	return string(clean)
+}
+
+// redactFields replaces the values of sensitive fields anywhere in the decoded JSON doc.
+func redactFields(doc any) any {
+	switch v := doc.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if sensitive(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactFields(val)
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = redactFields(val)
+		}
+	}
+	return doc
+}
+
+// sensitive reports whether the field name carries a secret.
+func sensitive(name string) bool {
+	name = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+	return sensitiveFields[name]
+}
@@ -0,0 +1,104 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/logging"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// entries decodes the JSON log entries written to buf.
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var ret []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var e map[string]any
+		require.NoError(t, dec.Decode(&e))
+		ret = append(ret, e)
+	}
+	return ret
+}
+
+func TestTransport_LogsSanitizedExchange(t *testing.T) {
+	restoreLevel(t)
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"messageId":"ext-1","token":"issued"}`))
+	}))
+	t.Cleanup(srv.Close)
+	var buf bytes.Buffer
+	client := &http.Client{Transport: logging.Transport(nil, zerolog.New(&buf), "X-Provider-Key")}
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/send?token=secret",
+		strings.NewReader(`{"to":"+905551234567","auth":{"password":"hunter2"}}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Provider-Key", "key-1")
+	req.Header.Set("Authorization", "Bearer abc")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, `{"to":"+905551234567","auth":{"password":"hunter2"}}`, received, "the request is sent unchanged")
+	assert.Equal(t, `{"messageId":"ext-1","token":"issued"}`, string(body), "the response is read in full")
+	logged := entries(t, &buf)
+	require.Len(t, logged, 2)
+	request, response := logged[0], logged[1]
+	assert.Equal(t, "trace", request["level"])
+	assert.Equal(t, request["request_id"], response["request_id"])
+	assert.Equal(t, srv.URL+"/send", request["url"])
+	assert.JSONEq(t, `{"to":"+905551234567","auth":{"password":"[REDACTED]"}}`, request["body"].(string))
+	headers := request["headers"].(map[string]any)
+	assert.Equal(t, []any{"[REDACTED]"}, headers["X-Provider-Key"])
+	assert.Equal(t, []any{"[REDACTED]"}, headers["Authorization"])
+	assert.Equal(t, float64(http.StatusAccepted), response["status"])
+	assert.JSONEq(t, `{"messageId":"ext-1","token":"[REDACTED]"}`, response["body"].(string))
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestTransport_SanitizesFormBodies(t *testing.T) {
+	restoreLevel(t)
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+	var buf bytes.Buffer
+	client := &http.Client{Transport: logging.Transport(nil, zerolog.New(&buf))}
+
+	resp, err := client.Post(srv.URL, "application/x-www-form-urlencoded", strings.NewReader("To=%2B905551234567&Auth_Token=abc"))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	logged := entries(t, &buf)
+	require.NotEmpty(t, logged)
+	assert.Equal(t, "Auth_Token=%5BREDACTED%5D&To=%2B905551234567", logged[0]["body"])
+}
+
+func TestTransport_SilentBelowTrace(t *testing.T) {
+	restoreLevel(t)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+	var buf bytes.Buffer
+	client := &http.Client{Transport: logging.Transport(nil, zerolog.New(&buf))}
+
+	resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Zero(t, buf.Len())
+}
@@ -0,0 +1,781 @@
+// Package memory implements message.Repository in memory, so tests, examples and dry runs work without a database.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// MessageRepository implements message.Repository over messages held in memory.
+// It is safe for concurrent use and follows the semantics of the PostgreSQL repository: messages are scoped to the
+// tenant of the context, claims are honored and IDs are assigned in insertion order.
+// Nothing survives the process, and every read and write holds a single lock, so it suits tests and demos rather
+// than production traffic.
+type MessageRepository struct {
+	mu   sync.Mutex // guards s
+	s    *store     // stored messages
+	inTx bool       // whether the repository is bound to a transaction by WithTx
+}
+
+var _ message.Repository = (*MessageRepository)(nil)
+
+// store holds the messages of a MessageRepository.
+type store struct {
+	nextID   int       // ID of the next stored message
+	records  []*record // messages in ID order
+	archived []*record // messages moved out by ArchiveSent, like the message_archive table
+}
+
+// record is a stored message along with its claim.
+type record struct {
+	id           int             // numeric form of msg.ID
+	msg          message.Message // stored state; ClaimToken is kept in claimToken instead
+	claimToken   string          // token of the claim the message is being delivered under, empty if none
+	claimedUntil time.Time       // end of the claim; zero if the message was never claimed
+	deletedAt    time.Time       // when the message was deleted; zero if it was not
+}
+
+// NewMessageRepository constructs an empty in-memory implementation of message.Repository.
+func NewMessageRepository() *MessageRepository {
+	return &MessageRepository{
+		s: &store{nextID: 1},
+	}
+}
+
+// clone returns a deep copy of s, which a transaction works on.
+func (s *store) clone() *store {
+	c := &store{
+		nextID:   s.nextID,
+		records:  make([]*record, len(s.records)),
+		archived: slices.Clone(s.archived),
+	}
+	for i, r := range s.records {
+		rc := *r
+		rc.msg.Variables = maps.Clone(r.msg.Variables)
+		c.records[i] = &rc
+	}
+	return c
+}
+
+// find returns the record of the message with the given ID, nil if there is none.
+func (s *store) find(id string) *record {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return nil
+	}
+	i, ok := slices.BinarySearchFunc(s.records, n, func(r *record, id int) int { return cmp.Compare(r.id, id) })
+	if !ok {
+		return nil
+	}
+	return s.records[i]
+}
+
+// insert stores a new pending message with the fields msg was created with, under the tenant ctx assigns it, and
+// returns its record.
+func (s *store) insert(ctx context.Context, msg *message.Message, now time.Time) *record {
+	r := &record{
+		id: s.nextID,
+		msg: message.Message{
+			ID:          strconv.Itoa(s.nextID),
+			To:          msg.To,
+			Content:     msg.Content,
+			Tenant:      message.TenantOf(ctx, msg),
+			CreatedAt:   now,
+			Priority:    msg.Priority,
+			ScheduledAt: msg.ScheduledAt,
+			ExpiresAt:   msg.ExpiresAt,
+			Template:    msg.Template,
+			Variables:   maps.Clone(msg.Variables),
+			Channel:     message.ChannelOf(msg),
+		},
+	}
+	s.nextID++
+	s.records = append(s.records, r)
+	return r
+}
+
+// remove drops the given records from the stored messages.
+func (s *store) remove(drop []*record) {
+	s.records = slices.DeleteFunc(s.records, func(r *record) bool { return slices.Contains(drop, r) })
+}
+
+// copyMsg returns a copy of the message of r that shares no state with the store.
+func (r *record) copyMsg() *message.Message {
+	msg := r.msg
+	msg.Variables = maps.Clone(r.msg.Variables)
+	return &msg
+}
+
+// claimed reports whether r is claimed by an instance delivering it at now.
+func (r *record) claimed(now time.Time) bool {
+	return r.claimedUntil.After(now)
+}
+
+// due reports whether r waits for delivery and may be attempted at now, see message.Repository.GetNextUnsent.
+func (r *record) due(now time.Time) bool {
+	return r.msg.IsPending() &&
+		!r.msg.ScheduledAt.After(now) &&
+		!r.msg.NextAttemptAt.After(now) &&
+		!r.claimed(now)
+}
+
+// release clears the claim of r.
+func (r *record) release() {
+	r.claimToken = ""
+	r.claimedUntil = time.Time{}
+}
+
+// owns reports whether r belongs to the tenant ctx is scoped to; unscoped contexts own every tenant.
+func owns(ctx context.Context, r *record) bool {
+	tenant, ok := message.TenantFromContext(ctx)
+	return !ok || r.msg.Tenant == tenant
+}
+
+// visible reports whether r belongs to the tenant ctx is scoped to and was not deleted.
+func visible(ctx context.Context, r *record) bool {
+	return owns(ctx, r) && r.deletedAt.IsZero()
+}
+
+// matches reports whether the message of r has the recipient and content f asks for.
+func matches(f message.Filter, r *record) bool {
+	if f.To != "" && r.msg.To != f.To {
+		return false
+	}
+	return f.Contains == "" || strings.Contains(strings.ToLower(r.msg.Content), strings.ToLower(f.Contains))
+}
+
+// byPriority orders records like unsent messages are sent: highest priority first, oldest first among equal ones.
+func byPriority(a, b *record) int {
+	return cmp.Or(cmp.Compare(b.msg.Priority, a.msg.Priority), a.msg.CreatedAt.Compare(b.msg.CreatedAt), cmp.Compare(a.id, b.id))
+}
+
+// retainedSince returns when the retention period of r started: when it was sent or, if it never was, deleted.
+func (r *record) retainedSince() time.Time {
+	if r.msg.IsSent() {
+		return r.msg.SentAt
+	}
+	return r.deletedAt
+}
+
+// bySentAt orders records in delivery order, see message.Position.
+func bySentAt(a, b *record) int {
+	return cmp.Or(a.msg.SentAt.Compare(b.msg.SentAt), cmp.Compare(a.id, b.id))
+}
+
+// limited returns the first limit elements of s, all of them if limit is not positive.
+func limited[T any](s []T, limit int) []T {
+	if limit > 0 && len(s) > limit {
+		return s[:limit]
+	}
+	return s
+}
+
+// sentMessage converts r into a message.SentMessage.
+func sentMessage(r *record) *message.SentMessage {
+	return &message.SentMessage{
+		ID:        r.msg.ID,
+		To:        r.msg.To,
+		Content:   r.msg.Content,
+		MessageID: r.msg.MessageID,
+		SentAt:    r.msg.SentAt,
+		Tenant:    r.msg.Tenant,
+	}
+}
+
+// locked calls fn with the stored messages and the current time while holding the lock.
+func (m *MessageRepository) locked(fn func(s *store, now time.Time)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(m.s, time.Now())
+}
+
+// dueRecords returns the records of the tenant ctx is scoped to that are due for a delivery attempt at now, in the
+// order they are sent.
+func dueRecords(ctx context.Context, s *store, now time.Time) []*record {
+	var ret []*record
+	for _, r := range s.records {
+		if visible(ctx, r) && r.due(now) {
+			ret = append(ret, r)
+		}
+	}
+	slices.SortStableFunc(ret, byPriority)
+	return ret
+}
+
+// copies copies the messages of records.
+func copies(records []*record) []*message.Message {
+	if len(records) == 0 {
+		return nil
+	}
+	ret := make([]*message.Message, len(records))
+	for i, r := range records {
+		ret[i] = r.copyMsg()
+	}
+	return ret
+}
+
+// GetNextUnsent returns the next message due for a delivery attempt, highest priority first.
+// Returns nil, nil if no unsent message is due.
+func (m *MessageRepository) GetNextUnsent(ctx context.Context) (*message.Message, error) {
+	var ret *message.Message
+	m.locked(func(s *store, now time.Time) {
+		if due := dueRecords(ctx, s, now); len(due) > 0 {
+			ret = due[0].copyMsg()
+		}
+	})
+	return ret, nil
+}
+
+// GetAllUnsent returns all messages due for a delivery attempt, highest priority first.
+func (m *MessageRepository) GetAllUnsent(ctx context.Context) ([]*message.Message, error) {
+	var ret []*message.Message
+	m.locked(func(s *store, now time.Time) {
+		ret = copies(dueRecords(ctx, s, now))
+	})
+	return ret, nil
+}
+
+// GetUnsent returns up to n messages due for a delivery attempt, highest priority first.
+func (m *MessageRepository) GetUnsent(ctx context.Context, n int) ([]*message.Message, error) {
+	var ret []*message.Message
+	m.locked(func(s *store, now time.Time) {
+		if n > 0 {
+			ret = copies(limited(dueRecords(ctx, s, now), n))
+		}
+	})
+	return ret, nil
+}
+
+// GetAllSent returns all sent messages in the order they were created.
+func (m *MessageRepository) GetAllSent(ctx context.Context) ([]*message.SentMessage, error) {
+	var ret []*message.SentMessage
+	m.locked(func(s *store, _ time.Time) {
+		for _, r := range s.records {
+			if visible(ctx, r) && r.msg.IsSent() {
+				ret = append(ret, sentMessage(r))
+			}
+		}
+	})
+	return ret, nil
+}
+
+// FindSent returns the sent messages matching f, ordered by sent timestamp and ID.
+func (m *MessageRepository) FindSent(ctx context.Context, f message.Filter) ([]*message.SentMessage, error) {
+	after := &record{}
+	if f.After != nil {
+		id, err := strconv.Atoi(f.After.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing position message ID")
+		}
+		after.id, after.msg.SentAt = id, f.After.SentAt
+	}
+	var ret []*message.SentMessage
+	m.locked(func(s *store, _ time.Time) {
+		var found []*record
+		for _, r := range s.records {
+			if !visible(ctx, r) || !r.msg.IsSent() || !matches(f, r) {
+				continue
+			}
+			if !f.SentAfter.IsZero() && !r.msg.SentAt.After(f.SentAfter) ||
+				!f.SentBefore.IsZero() && !r.msg.SentAt.Before(f.SentBefore) ||
+				f.After != nil && bySentAt(r, after) <= 0 {
+				continue
+			}
+			found = append(found, r)
+		}
+		slices.SortFunc(found, bySentAt)
+		for _, r := range limited(found, f.Limit) {
+			ret = append(ret, sentMessage(r))
+		}
+	})
+	return ret, nil
+}
+
+// FindUnsent returns the pending messages matching f, oldest first, whether they are due or not.
+func (m *MessageRepository) FindUnsent(ctx context.Context, f message.Filter) ([]*message.Message, error) {
+	if f.After != nil {
+		if _, err := strconv.Atoi(f.After.ID); err != nil {
+			return nil, errors.Wrap(err, "parsing position message ID")
+		}
+	}
+	var ret []*message.Message
+	m.locked(func(s *store, _ time.Time) {
+		var after *record
+		if f.After != nil {
+			if after = s.find(f.After.ID); after == nil {
+				// there is no position to page past, like the comparison with a missing row in SQL
+				return
+			}
+		}
+		var found []*record
+		for _, r := range s.records {
+			if visible(ctx, r) && r.msg.IsPending() && matches(f, r) && (after == nil || byCreatedAt(r, after) > 0) {
+				found = append(found, r)
+			}
+		}
+		slices.SortFunc(found, byCreatedAt)
+		ret = copies(limited(found, f.Limit))
+	})
+	return ret, nil
+}
+
+// byCreatedAt orders records by creation, ties broken by ID.
+func byCreatedAt(a, b *record) int {
+	return cmp.Or(a.msg.CreatedAt.Compare(b.msg.CreatedAt), cmp.Compare(a.id, b.id))
+}
+
+// FindFailed returns the messages whose delivery was given up that match f, most recently failed first.
+func (m *MessageRepository) FindFailed(ctx context.Context, f message.Filter) ([]*message.Message, error) {
+	var ret []*message.Message
+	m.locked(func(s *store, _ time.Time) {
+		var found []*record
+		for _, r := range s.records {
+			if visible(ctx, r) && r.msg.Status() == message.StatusFailed && matches(f, r) {
+				found = append(found, r)
+			}
+		}
+		slices.SortFunc(found, func(a, b *record) int {
+			return cmp.Or(b.msg.FailedAt.Compare(a.msg.FailedAt), cmp.Compare(b.id, a.id))
+		})
+		ret = copies(limited(found, f.Limit))
+	})
+	return ret, nil
+}
+
+// Requeue resets the attempts and failed state of a failed message, making it due for sending right away.
+// Returns message.ErrMessageNotFound if no failed message of the tenant has the given ID.
+func (m *MessageRepository) Requeue(ctx context.Context, id string) error {
+	err := message.ErrMessageNotFound
+	m.locked(func(s *store, _ time.Time) {
+		r := s.find(id)
+		if r == nil || !visible(ctx, r) || r.msg.Status() != message.StatusFailed {
+			return
+		}
+		r.msg.Attempts = 0
+		r.msg.LastError = ""
+		r.msg.NextAttemptAt = time.Time{}
+		r.msg.FailedAt = time.Time{}
+		err = nil
+	})
+	return err
+}
+
+// WalkSent calls fn for every sent message in ID order.
+// The messages are copied before fn is called, so fn may use the repository.
+func (m *MessageRepository) WalkSent(ctx context.Context, fn func(*message.SentMessage) error) error {
+	msgs, err := m.GetAllSent(ctx)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByID returns the message of the tenant with the given ID, sent or not.
+// Returns nil, nil if there is no such message.
+func (m *MessageRepository) GetByID(ctx context.Context, id string) (*message.Message, error) {
+	var ret *message.Message
+	m.locked(func(s *store, _ time.Time) {
+		if r := s.find(id); r != nil && visible(ctx, r) {
+			ret = r.copyMsg()
+		}
+	})
+	return ret, nil
+}
+
+// Create stores a new pending message and returns it with its assigned ID.
+// If the tenant already stored a message under msg.IdempotencyKey, that message is returned instead.
+func (m *MessageRepository) Create(ctx context.Context, msg *message.Message) (*message.Message, bool, error) {
+	var (
+		ret     *message.Message
+		created bool
+		err     error
+	)
+	m.locked(func(s *store, now time.Time) {
+		tenant := message.TenantOf(ctx, msg)
+		if msg.IdempotencyKey != "" {
+			for _, r := range s.records {
+				if r.msg.Tenant != tenant || r.msg.IdempotencyKey != msg.IdempotencyKey {
+					continue
+				}
+				if !r.deletedAt.IsZero() {
+					err = message.ErrMessageDeleted
+					return
+				}
+				ret = r.copyMsg()
+				return
+			}
+		}
+		r := s.insert(ctx, msg, now)
+		r.msg.IdempotencyKey = msg.IdempotencyKey
+		ret, created = r.copyMsg(), true
+	})
+	return ret, created, err
+}
+
+// GetStats computes aggregate message figures over the messages of the tenant.
+func (m *MessageRepository) GetStats(ctx context.Context) (*message.Stats, error) {
+	ret := &message.Stats{}
+	m.locked(func(s *store, now time.Time) {
+		var latency time.Duration
+		for _, r := range s.records {
+			if !visible(ctx, r) {
+				continue
+			}
+			msg := &r.msg
+			if msg.IsSent() {
+				ret.Sent++
+				latency += msg.SentAt.Sub(msg.CreatedAt)
+				if !msg.SentAt.Before(now.Add(-time.Hour)) {
+					ret.SentLastHour++
+				}
+				if !msg.SentAt.Before(now.Add(-24 * time.Hour)) {
+					ret.SentLastDay++
+				}
+			}
+			if msg.IsPending() {
+				ret.Unsent++
+			}
+			if msg.IsFailed() {
+				ret.Failed++
+			}
+			if msg.IsExpired() {
+				ret.Expired++
+			}
+			if msg.IsCanceled() {
+				ret.Canceled++
+			}
+			if msg.IsRejected() {
+				ret.Rejected++
+			}
+		}
+		if ret.Sent > 0 {
+			ret.AvgLatency = latency / time.Duration(ret.Sent)
+		}
+	})
+	return ret, nil
+}
+
+// count returns the number of messages of the tenant with the given status.
+func (m *MessageRepository) count(ctx context.Context, status message.Status) int64 {
+	var n int64
+	m.locked(func(s *store, _ time.Time) {
+		for _, r := range s.records {
+			if visible(ctx, r) && r.msg.Status() == status {
+				n++
+			}
+		}
+	})
+	return n
+}
+
+// CountUnsent counts the messages of the tenant waiting for delivery.
+func (m *MessageRepository) CountUnsent(ctx context.Context) (int64, error) {
+	return m.count(ctx, message.StatusPending), nil
+}
+
+// CountSent counts the delivered messages of the tenant.
+func (m *MessageRepository) CountSent(ctx context.Context) (int64, error) {
+	return m.count(ctx, message.StatusSent), nil
+}
+
+// CountFailed counts the messages of the tenant whose delivery was given up.
+func (m *MessageRepository) CountFailed(ctx context.Context) (int64, error) {
+	return m.count(ctx, message.StatusFailed), nil
+}
+
+// CountSentByHour counts the messages of the tenant delivered in each hour since the one since falls in.
+func (m *MessageRepository) CountSentByHour(ctx context.Context, since time.Time) ([]message.HourCount, error) {
+	var ret []message.HourCount
+	m.locked(func(s *store, now time.Time) {
+		start := since.Truncate(time.Hour)
+		for hour := start; !hour.After(now); hour = hour.Add(time.Hour) {
+			ret = append(ret, message.HourCount{Hour: hour})
+		}
+		for _, r := range s.records {
+			if !visible(ctx, r) || !r.msg.IsSent() || r.msg.SentAt.Before(start) {
+				continue
+			}
+			if i := int(r.msg.SentAt.Sub(start) / time.Hour); i < len(ret) {
+				ret[i].Count++
+			}
+		}
+	})
+	return ret, nil
+}
+
+// InsertMany stores msgs as new pending messages, all under the same lock so either all or none are seen.
+func (m *MessageRepository) InsertMany(ctx context.Context, msgs []*message.Message) error {
+	m.locked(func(s *store, now time.Time) {
+		for _, msg := range msgs {
+			s.insert(ctx, msg, now)
+		}
+	})
+	return nil
+}
+
+// Claim marks a pending message as being delivered under msg.ClaimToken until the given time.
+// Returns false if the message is not pending or holds a claim that has not expired yet.
+func (m *MessageRepository) Claim(_ context.Context, msg *message.Message, until time.Time) (bool, error) {
+	var claimed bool
+	m.locked(func(s *store, now time.Time) {
+		r := s.find(msg.ID)
+		if r == nil || !r.msg.IsPending() || r.claimed(now) {
+			return
+		}
+		r.claimToken, r.claimedUntil = msg.ClaimToken, until
+		claimed = true
+	})
+	return claimed, nil
+}
+
+// ClaimUnsent claims up to n messages due for a delivery attempt under token until the given time and returns them,
+// highest priority first.
+func (m *MessageRepository) ClaimUnsent(ctx context.Context, n int, token string, until time.Time) ([]*message.Message, error) {
+	var ret []*message.Message
+	m.locked(func(s *store, now time.Time) {
+		if n <= 0 {
+			return
+		}
+		due := limited(dueRecords(ctx, s, now), n)
+		for _, r := range due {
+			r.claimToken, r.claimedUntil = token, until
+		}
+		ret = copies(due)
+	})
+	for _, msg := range ret {
+		msg.ClaimToken = token
+	}
+	return ret, nil
+}
+
+// owned returns the record of msg if it is claimed under msg.ClaimToken, or not claimed if msg carries no token.
+func owned(s *store, msg *message.Message) *record {
+	r := s.find(msg.ID)
+	if r == nil || r.claimToken != msg.ClaimToken {
+		return nil
+	}
+	return r
+}
+
+// setSent stores the sent state of msg on r and releases its claim.
+func setSent(r *record, msg *message.Message) {
+	r.msg.MessageID = msg.MessageID
+	r.msg.SentAt = msg.SentAt
+	r.msg.Content = msg.Content
+	r.msg.ProviderStatusCode = msg.ProviderStatusCode
+	r.msg.ProviderResponse = msg.ProviderResponse
+	r.release()
+}
+
+// Save stores the sent state of msg and releases its claim. Does nothing if SentAt is zero.
+// Returns message.ErrClaimLost if the message is claimed under a token other than msg.ClaimToken.
+func (m *MessageRepository) Save(_ context.Context, msg *message.Message) error {
+	if msg.SentAt.IsZero() {
+		return nil
+	}
+	if msg.MessageID == "" {
+		return errors.New("message ID is empty")
+	}
+	err := message.ErrClaimLost
+	m.locked(func(s *store, _ time.Time) {
+		if r := owned(s, msg); r != nil {
+			setSent(r, msg)
+			err = nil
+		}
+	})
+	return err
+}
+
+// SaveAll stores the sent state of msgs at once and returns those claimed under a token other than their ClaimToken,
+// which are left as they were. Messages not marked sent are ignored.
+func (m *MessageRepository) SaveAll(_ context.Context, msgs []*message.Message) ([]*message.Message, error) {
+	for _, msg := range msgs {
+		if !msg.SentAt.IsZero() && msg.MessageID == "" {
+			return nil, errors.Errorf("message ID of message %s is empty", msg.ID)
+		}
+	}
+	var lost []*message.Message
+	m.locked(func(s *store, _ time.Time) {
+		for _, msg := range msgs {
+			if msg.SentAt.IsZero() {
+				continue
+			}
+			if r := owned(s, msg); r != nil {
+				setSent(r, msg)
+			} else {
+				lost = append(lost, msg)
+			}
+		}
+	})
+	return lost, nil
+}
+
+// Expire stores when a pending message was found expired and releases its claim, if msg holds it.
+// Messages sent, given up or canceled meanwhile and messages claimed by another instance are left alone.
+func (m *MessageRepository) Expire(_ context.Context, msg *message.Message) error {
+	m.locked(func(s *store, now time.Time) {
+		r := s.find(msg.ID)
+		if r == nil || r.msg.IsSent() || r.msg.IsExpired() || r.msg.IsRejected() || r.msg.IsCanceled() {
+			return
+		}
+		if r.claimed(now) && owned(s, msg) == nil {
+			return
+		}
+		r.msg.ExpiredAt = msg.ExpiredAt
+		r.release()
+	})
+	return nil
+}
+
+// Cancel stores when a pending message of the tenant was canceled.
+// Returns false if the message is not pending anymore, belongs to another tenant or is claimed.
+func (m *MessageRepository) Cancel(ctx context.Context, msg *message.Message) (bool, error) {
+	var canceled bool
+	m.locked(func(s *store, now time.Time) {
+		r := s.find(msg.ID)
+		if r == nil || !visible(ctx, r) || !r.msg.IsPending() || r.claimed(now) {
+			return
+		}
+		r.msg.CanceledAt = msg.CanceledAt
+		canceled = true
+	})
+	return canceled, nil
+}
+
+// Delete marks a message of the tenant that is no longer pending as deleted, hiding it from every read.
+// Returns message.ErrMessageNotFound if there is no such message, it is pending or it was already deleted.
+func (m *MessageRepository) Delete(ctx context.Context, id string) error {
+	err := message.ErrMessageNotFound
+	m.locked(func(s *store, now time.Time) {
+		if r := s.find(id); r != nil && visible(ctx, r) && !r.msg.IsPending() {
+			r.deletedAt = now
+			err = nil
+		}
+	})
+	return err
+}
+
+// Reject stores when and why a claimed message was rejected by validation, releasing its claim.
+// Returns message.ErrClaimLost if the message is claimed under a token other than msg.ClaimToken.
+func (m *MessageRepository) Reject(_ context.Context, msg *message.Message) error {
+	err := message.ErrClaimLost
+	m.locked(func(s *store, _ time.Time) {
+		if r := owned(s, msg); r != nil {
+			r.msg.RejectedAt = msg.RejectedAt
+			r.msg.LastError = msg.LastError
+			r.release()
+			err = nil
+		}
+	})
+	return err
+}
+
+// SaveAttempts stores the failed delivery attempts of a message and releases its claim.
+// Returns message.ErrClaimLost if the message is claimed under a token other than msg.ClaimToken.
+func (m *MessageRepository) SaveAttempts(_ context.Context, msg *message.Message) error {
+	err := message.ErrClaimLost
+	m.locked(func(s *store, _ time.Time) {
+		if r := owned(s, msg); r != nil {
+			r.msg.Attempts = msg.Attempts
+			r.msg.LastError = msg.LastError
+			r.msg.NextAttemptAt = msg.NextAttemptAt
+			r.msg.FailedAt = msg.FailedAt
+			r.release()
+			err = nil
+		}
+	})
+	return err
+}
+
+// oldSent returns up to n records of the tenant whose retention period started before cutoff, oldest first: those
+// sent before cutoff, deleted or not, and those deleted before cutoff without being sent.
+func oldSent(ctx context.Context, s *store, cutoff time.Time, n int) []*record {
+	var ret []*record
+	for _, r := range s.records {
+		if owns(ctx, r) && (r.msg.IsSent() || !r.deletedAt.IsZero()) && r.retainedSince().Before(cutoff) {
+			ret = append(ret, r)
+		}
+	}
+	slices.SortFunc(ret, func(a, b *record) int {
+		return cmp.Or(a.retainedSince().Compare(b.retainedSince()), cmp.Compare(a.id, b.id))
+	})
+	return ret[:min(max(n, 0), len(ret))]
+}
+
+// ArchiveSent moves up to n messages of the tenant sent more than olderThan ago out of the stored messages.
+// Deleted messages among them are dropped instead of archived, as are messages deleted more than olderThan ago
+// without being sent, and counted alike.
+func (m *MessageRepository) ArchiveSent(ctx context.Context, olderThan time.Duration, n int) (int64, error) {
+	var archived []*record
+	m.locked(func(s *store, now time.Time) {
+		archived = oldSent(ctx, s, now.Add(-olderThan), n)
+		s.remove(archived)
+		for _, r := range archived {
+			if r.deletedAt.IsZero() {
+				s.archived = append(s.archived, r)
+			}
+		}
+	})
+	return int64(len(archived)), nil
+}
+
+// DeleteSent deletes up to n messages of the tenant sent more than olderThan ago, and those deleted more than
+// olderThan ago without being sent.
+func (m *MessageRepository) DeleteSent(ctx context.Context, olderThan time.Duration, n int) (int64, error) {
+	var deleted []*record
+	m.locked(func(s *store, now time.Time) {
+		deleted = oldSent(ctx, s, now.Add(-olderThan), n)
+		s.remove(deleted)
+	})
+	return int64(len(deleted)), nil
+}
+
+// SaveDeliveryReport stores the final delivery status reported by r on the sent message with its provider message ID,
+// whatever its tenant. Returns message.ErrMessageNotFound if no sent message has that provider message ID.
+func (m *MessageRepository) SaveDeliveryReport(_ context.Context, r *message.DeliveryReport) error {
+	err := message.ErrMessageNotFound
+	m.locked(func(s *store, _ time.Time) {
+		for _, rec := range s.records {
+			if rec.msg.IsSent() && rec.msg.MessageID == r.MessageID {
+				rec.msg.SetDelivery(r)
+				err = nil
+			}
+		}
+	})
+	return err
+}
+
+// WithTx calls fn with a MessageRepository working on a copy of the stored messages, which replaces them if fn
+// returns nil and is dropped otherwise. Other callers wait until fn returns, so transactions are serializable; fn must
+// therefore only use the repository it is handed. Called on that repository, it calls fn with it.
+// Copying the messages makes a transaction take time proportional to their number.
+func (m *MessageRepository) WithTx(_ context.Context, fn func(message.Repository) error) error {
+	if m.inTx {
+		return fn(m)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tx := &MessageRepository{
+		s:    m.s.clone(),
+		inTx: true,
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	m.s = tx.s
+	return nil
+}
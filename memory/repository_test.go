@@ -0,0 +1,353 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/memory"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// create stores a new pending message to the given recipient and returns it.
+func create(t *testing.T, repo *memory.MessageRepository, ctx context.Context, to, content string) *message.Message {
+	t.Helper()
+	msg, err := message.NewUnsentMessage(to, content)
+	require.NoError(t, err)
+	stored, created, err := repo.Create(ctx, msg)
+	require.NoError(t, err)
+	require.True(t, created)
+	return stored
+}
+
+func TestMessageRepository_Create(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := message.WithTenant(context.Background(), "acme")
+	msg, err := message.NewUnsentMessage("+905551234567", "hello")
+	require.NoError(t, err)
+	msg.IdempotencyKey = "order-1"
+
+	stored, created, err := repo.Create(ctx, msg)
+
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "1", stored.ID)
+	assert.Equal(t, "acme", stored.Tenant)
+	assert.Equal(t, message.ChannelSMS, stored.Channel)
+	assert.False(t, stored.CreatedAt.IsZero())
+
+	// the same key returns the stored message instead of a new one
+	again, created, err := repo.Create(ctx, msg)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, stored, again)
+
+	// keys are unique per tenant
+	other, created, err := repo.Create(message.WithTenant(context.Background(), "globex"), msg)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "2", other.ID)
+}
+
+func TestMessageRepository_GetUnsent_OrdersByPriorityAndSkipsMessagesNotDue(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := context.Background()
+	now := time.Now()
+	msgs := make([]*message.Message, 4)
+	for i := range msgs {
+		msgs[i], _ = message.NewUnsentMessage("+905551234567", "hello")
+	}
+	msgs[1].Priority = 5
+	msgs[2].ScheduledAt = now.Add(time.Hour)
+	require.NoError(t, repo.InsertMany(ctx, msgs))
+	// the fourth message is being delivered by another instance
+	claimed, err := repo.Claim(ctx, &message.Message{ID: "4", ClaimToken: "other"}, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	unsent, err := repo.GetUnsent(ctx, 10)
+
+	require.NoError(t, err)
+	require.Len(t, unsent, 2)
+	assert.Equal(t, []string{"2", "1"}, []string{unsent[0].ID, unsent[1].ID})
+	next, err := repo.GetNextUnsent(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "2", next.ID)
+	none, err := repo.GetUnsent(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func TestMessageRepository_ClaimUnsent_NeverHandsOutAMessageTwice(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := context.Background()
+	for range 50 {
+		create(t, repo, ctx, "+905551234567", "hello")
+	}
+
+	var (
+		mu   sync.Mutex
+		seen = map[string]int{}
+		wg   sync.WaitGroup
+	)
+	for _, token := range []string{"a", "b", "c", "d", "e"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				msgs, err := repo.ClaimUnsent(ctx, 3, token, time.Now().Add(time.Minute))
+				if err != nil || len(msgs) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, msg := range msgs {
+					seen[msg.ID]++
+					assert.Equal(t, token, msg.ClaimToken)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, 50)
+	for id, n := range seen {
+		assert.Equal(t, 1, n, "message %s claimed %d times", id, n)
+	}
+}
+
+func TestMessageRepository_Save_ChecksClaimToken(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := context.Background()
+	create(t, repo, ctx, "+905551234567", "hello")
+	msgs, err := repo.ClaimUnsent(ctx, 1, "mine", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	msg := msgs[0]
+	require.NoError(t, msg.SetSent("ext-1", time.Now()))
+
+	stale := *msg
+	stale.ClaimToken = "theirs"
+	assert.ErrorIs(t, repo.Save(ctx, &stale), message.ErrClaimLost)
+	require.NoError(t, repo.Save(ctx, msg))
+
+	stored, err := repo.GetByID(ctx, msg.ID)
+	require.NoError(t, err)
+	assert.Equal(t, message.StatusSent, stored.Status())
+	assert.Equal(t, "ext-1", stored.MessageID)
+	assert.Empty(t, stored.ClaimToken)
+}
+
+func TestMessageRepository_SaveAttempts_AndRequeue(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := message.WithTenant(context.Background(), "acme")
+	msg := create(t, repo, ctx, "+905551234567", "hello")
+	msg.SetFailed(errors.New("provider down"), time.Now())
+
+	require.NoError(t, repo.SaveAttempts(ctx, msg))
+
+	failed, err := repo.FindFailed(ctx, message.Filter{})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, "provider down", failed[0].LastError)
+	n, err := repo.CountFailed(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	assert.ErrorIs(t, repo.Requeue(message.WithTenant(context.Background(), "globex"), msg.ID), message.ErrMessageNotFound)
+	require.NoError(t, repo.Requeue(ctx, msg.ID))
+	n, err = repo.CountUnsent(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestMessageRepository_Expire_ReleasesOwnClaim(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := context.Background()
+	create(t, repo, ctx, "+905551234567", "hello")
+	msgs, err := repo.ClaimUnsent(ctx, 1, "mine", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	// a claim held by another instance keeps the message from expiring
+	theirs := *msgs[0]
+	theirs.ClaimToken = "theirs"
+	theirs.SetExpired(time.Now())
+	require.NoError(t, repo.Expire(ctx, &theirs))
+	n, err := repo.CountUnsent(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	msgs[0].SetExpired(time.Now())
+	require.NoError(t, repo.Expire(ctx, msgs[0]))
+	got, err := repo.GetByID(ctx, msgs[0].ID)
+	require.NoError(t, err)
+	assert.True(t, got.IsExpired())
+	assert.Empty(t, got.ClaimToken)
+}
+
+func TestMessageRepository_ScopesReadsToTenant(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	acme := message.WithTenant(context.Background(), "acme")
+	msg := create(t, repo, acme, "+905551234567", "hello")
+	create(t, repo, message.WithTenant(context.Background(), "globex"), "+905551234567", "hello")
+	globex := message.WithTenant(context.Background(), "globex")
+
+	got, err := repo.GetByID(globex, msg.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	canceled, err := repo.Cancel(globex, &message.Message{ID: msg.ID, CanceledAt: time.Now()})
+	require.NoError(t, err)
+	assert.False(t, canceled)
+
+	unsent, err := repo.GetAllUnsent(acme)
+	require.NoError(t, err)
+	assert.Len(t, unsent, 1)
+	all, err := repo.GetAllUnsent(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestMessageRepository_FindSent_PagesByPosition(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := context.Background()
+	sentAt := time.Now().Add(-time.Hour)
+	for i := range 3 {
+		msg := create(t, repo, ctx, "+905551234567", "Hello")
+		require.NoError(t, msg.SetSent("ext", sentAt.Add(time.Duration(2-i)*time.Minute)))
+		require.NoError(t, repo.Save(ctx, msg))
+	}
+
+	page, err := repo.FindSent(ctx, message.Filter{Contains: "hello", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, []string{"3", "2"}, []string{page[0].ID, page[1].ID})
+
+	rest, err := repo.FindSent(ctx, message.Filter{After: message.PositionOf(page[1])})
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Equal(t, "1", rest[0].ID)
+
+	_, err = repo.FindSent(ctx, message.Filter{After: &message.Position{ID: "x"}})
+	assert.Error(t, err)
+}
+
+func TestMessageRepository_StatsAndArchive(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := context.Background()
+	old := create(t, repo, ctx, "+905551234567", "hello")
+	require.NoError(t, old.SetSent("ext-1", time.Now().Add(-48*time.Hour)))
+	require.NoError(t, repo.Save(ctx, old))
+	recent := create(t, repo, ctx, "+905551234567", "hello")
+	require.NoError(t, recent.SetSent("ext-2", time.Now()))
+	require.NoError(t, repo.Save(ctx, recent))
+	create(t, repo, ctx, "+905551234567", "hello")
+
+	stats, err := repo.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Sent)
+	assert.Equal(t, int64(1), stats.Unsent)
+	assert.Equal(t, int64(1), stats.SentLastDay)
+	hours, err := repo.CountSentByHour(ctx, time.Now().Add(-23*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, hours, 24)
+	assert.Equal(t, int64(1), hours[23].Count)
+
+	require.NoError(t, repo.SaveDeliveryReport(ctx, &message.DeliveryReport{MessageID: "ext-1", Status: message.DeliveryDelivered}))
+	archived, err := repo.ArchiveSent(ctx, 24*time.Hour, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), archived)
+	got, err := repo.GetByID(ctx, old.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	assert.ErrorIs(t, repo.SaveDeliveryReport(ctx, &message.DeliveryReport{MessageID: "ext-1"}), message.ErrMessageNotFound)
+}
+
+func TestMessageRepository_Delete(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := context.Background()
+	pending := create(t, repo, ctx, "+905551234567", "hello")
+	sent := create(t, repo, ctx, "+905551234567", "hello")
+	require.NoError(t, sent.SetSent("ext-1", time.Now().Add(-48*time.Hour)))
+	require.NoError(t, repo.Save(ctx, sent))
+
+	assert.ErrorIs(t, repo.Delete(ctx, pending.ID), message.ErrMessageNotFound)
+	require.NoError(t, repo.Delete(ctx, sent.ID))
+	assert.ErrorIs(t, repo.Delete(ctx, sent.ID), message.ErrMessageNotFound)
+
+	got, err := repo.GetByID(ctx, sent.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	// nor is it returned for a replayed idempotency key
+	keyed, err := message.NewUnsentMessage("+905551234567", "hello")
+	require.NoError(t, err)
+	keyed.IdempotencyKey = "order-1"
+	stored, _, err := repo.Create(ctx, keyed)
+	require.NoError(t, err)
+	require.NoError(t, stored.SetSent("ext-2", time.Now()))
+	require.NoError(t, repo.Save(ctx, stored))
+	require.NoError(t, repo.Delete(ctx, stored.ID))
+	_, _, err = repo.Create(ctx, keyed)
+	assert.ErrorIs(t, err, message.ErrMessageDeleted)
+	n, err := repo.CountSent(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	// deleted messages are dropped by the archive job but still counted
+	archived, err := repo.ArchiveSent(ctx, 24*time.Hour, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), archived)
+}
+
+func TestMessageRepository_ArchiveSent_DropsDeletedUnsentMessages(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := context.Background()
+	failed := create(t, repo, ctx, "+905551234567", "hello")
+	failed.SetFailed(errors.New("provider down"), time.Now())
+	require.NoError(t, repo.SaveAttempts(ctx, failed))
+	require.NoError(t, repo.Delete(ctx, failed.ID))
+
+	// the retention period of messages never sent starts when they are deleted
+	archived, err := repo.ArchiveSent(ctx, time.Hour, 10)
+	require.NoError(t, err)
+	assert.Zero(t, archived)
+	deleted, err := repo.DeleteSent(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestMessageRepository_WithTx(t *testing.T) {
+	repo := memory.NewMessageRepository()
+	ctx := context.Background()
+	rollback := errors.New("rolled back")
+
+	err := repo.WithTx(ctx, func(tx message.Repository) error {
+		msg, _ := message.NewUnsentMessage("+905551234567", "hello")
+		if _, _, err := tx.Create(ctx, msg); err != nil {
+			return err
+		}
+		return rollback
+	})
+
+	require.ErrorIs(t, err, rollback)
+	n, err := repo.CountUnsent(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	err = repo.WithTx(ctx, func(tx message.Repository) error {
+		msg, _ := message.NewUnsentMessage("+905551234567", "hello")
+		// a nested transaction joins the running one
+		return tx.WithTx(ctx, func(tx message.Repository) error {
+			_, _, err := tx.Create(ctx, msg)
+			return err
+		})
+	})
+
+	require.NoError(t, err)
+	n, err = repo.CountUnsent(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
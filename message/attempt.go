@@ -0,0 +1,54 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Attempt records one try to deliver a Message through its provider, successful or not.
+type Attempt struct {
+	MessageID  string        // internal identifier of the attempted message
+	StartedAt  time.Time     // when the message was handed to the sender
+	Duration   time.Duration // how long the sender took to deliver or fail
+	StatusCode int           // HTTP status code of a failed attempt the provider answered; 0 if unknown
+	Error      string        // why the attempt failed; empty for successful attempts
+}
+
+// NewAttempt returns the Attempt to deliver msg that started at start, took d and failed with err, if not nil.
+// The status code is taken from a ProviderError or RateLimitedError in the chain of err.
+func NewAttempt(msg *Message, start time.Time, d time.Duration, err error) *Attempt {
+	a := &Attempt{MessageID: msg.ID, StartedAt: start, Duration: d}
+	if err == nil {
+		return a
+	}
+	a.Error = err.Error()
+	var (
+		perr *ProviderError
+		rerr *RateLimitedError
+	)
+	switch {
+	case errors.As(err, &perr):
+		a.StatusCode = perr.StatusCode
+	case errors.As(err, &rerr):
+		a.StatusCode = http.StatusTooManyRequests
+	}
+	return a
+}
+
+// Succeeded reports whether the message was delivered by the attempt.
+func (a *Attempt) Succeeded() bool {
+	return a.Error == ""
+}
+
+// AttemptRepository stores the history of delivery attempts of each message.
+// Like Repository, it only reads attempts of messages of the tenant a context is scoped to, see WithTenant.
+type AttemptRepository interface {
+	// RecordAttempts stores attempts with a single operation.
+	RecordAttempts(ctx context.Context, attempts []*Attempt) error
+
+	// ListAttempts returns the attempts to deliver the message with the given internal id, oldest first.
+	// Returns an empty slice or nil if the message was never attempted or does not exist.
+	ListAttempts(ctx context.Context, id string) ([]*Attempt, error)
+}
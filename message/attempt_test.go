@@ -0,0 +1,42 @@
+package message_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+func TestNewAttempt(t *testing.T) {
+	msg := &message.Message{ID: "7"}
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "delivered"},
+		{name: "provider error", err: fmt.Errorf("sending: %w", &message.ProviderError{StatusCode: 503}), status: 503},
+		{name: "rate limited", err: &message.RateLimitedError{Err: errors.New("slow down")}, status: 429},
+		{name: "network error", err: errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := message.NewAttempt(msg, start, time.Second, tt.err)
+			if a.MessageID != "7" || !a.StartedAt.Equal(start) || a.Duration != time.Second {
+				t.Errorf("NewAttempt() = %+v, want message 7 started at %v taking 1s", a, start)
+			}
+			if a.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", a.StatusCode, tt.status)
+			}
+			if got := a.Succeeded(); got != (tt.err == nil) {
+				t.Errorf("Succeeded() = %v, want %v", got, tt.err == nil)
+			}
+			if tt.err != nil && a.Error != tt.err.Error() {
+				t.Errorf("Error = %q, want %q", a.Error, tt.err.Error())
+			}
+		})
+	}
+}
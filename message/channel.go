@@ -0,0 +1,68 @@
+package message
+
+import (
+	"errors"
+	"net/mail"
+)
+
+// Channel is the medium a Message is delivered through.
+type Channel string
+
+const (
+	ChannelSMS   Channel = "sms"   // text message to an E.164 phone number; the default channel
+	ChannelEmail Channel = "email" // email to an address
+	ChannelPush  Channel = "push"  // push notification to a device token
+	ChannelChat  Channel = "chat"  // post to a named chat room, e.g. a Slack channel for internal alerts
+)
+
+var (
+	// ErrUnknownChannel is returned for a channel other than ChannelSMS, ChannelEmail, ChannelPush and ChannelChat.
+	ErrUnknownChannel = errors.New("unknown channel")
+
+	// ErrChannelNotConfigured is returned when creating or sending a Message on a channel no sender is configured for.
+	ErrChannelNotConfigured = errors.New("no sender is configured for the channel")
+
+	// ErrInvalidEmailAddress is returned when the recipient of an email is not a plain email address.
+	ErrInvalidEmailAddress = errors.New("invalid email address")
+
+	// ErrBlankRecipient is returned when the recipient of a push notification or chat post is blank.
+	ErrBlankRecipient = errors.New("recipient can't be blank")
+)
+
+// ParseChannel returns the Channel named s, or ErrUnknownChannel if there is none.
+func ParseChannel(s string) (Channel, error) {
+	switch ch := Channel(s); ch {
+	case ChannelSMS, ChannelEmail, ChannelPush, ChannelChat:
+		return ch, nil
+	default:
+		return "", ErrUnknownChannel
+	}
+}
+
+// ChannelOf returns the channel msg is delivered through, ChannelSMS if it does not name one.
+func ChannelOf(msg *Message) Channel {
+	if msg.Channel == "" {
+		return ChannelSMS
+	}
+	return msg.Channel
+}
+
+// validateRecipient ensures to is a valid recipient on channel ch.
+func validateRecipient(ch Channel, to string) error {
+	switch ch {
+	case ChannelSMS:
+		return validatePhone(to)
+	case ChannelEmail:
+		if addr, err := mail.ParseAddress(to); err != nil || addr.Address != to {
+			return ErrInvalidEmailAddress
+		}
+		return nil
+	case ChannelPush, ChannelChat:
+		if to == "" {
+			return ErrBlankRecipient
+		}
+		return nil
+	default:
+		return ErrUnknownChannel
+	}
+}
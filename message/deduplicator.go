@@ -0,0 +1,18 @@
+package message
+
+import (
+	"context"
+	"time"
+)
+
+// Deduplicator detects messages carrying the same content to the same recipient as one sent shortly before.
+// Reservations are shared by every instance using the same Deduplicator backend.
+type Deduplicator interface {
+	// Reserve records that msg is about to be sent and keeps identical messages of the same tenant from being
+	// reserved for window. It returns false if an identical message other than msg holds a reservation.
+	Reserve(ctx context.Context, msg *Message, window time.Duration) (bool, error)
+
+	// Release drops the reservation msg holds, e.g. because its delivery failed, so it can be sent later.
+	// Reservations held by other messages are left alone.
+	Release(ctx context.Context, msg *Message) error
+}
@@ -0,0 +1,61 @@
+package message
+
+import (
+	"errors"
+	"time"
+)
+
+// DeliveryStatus is the final outcome of a sent Message, as reported by its provider.
+type DeliveryStatus string
+
+const (
+	DeliveryDelivered   DeliveryStatus = "delivered"   // the message reached the recipient
+	DeliveryUndelivered DeliveryStatus = "undelivered" // the provider could not get the message to the recipient
+)
+
+// ErrUnknownDeliveryStatus is returned for a delivery status other than DeliveryDelivered and DeliveryUndelivered.
+var ErrUnknownDeliveryStatus = errors.New("unknown delivery status")
+
+// DeliveryReport is a delivery receipt (DLR): a provider reporting the final status of a message it accepted earlier.
+// The message is identified by the provider message ID returned when it was sent, see Message.MessageID.
+type DeliveryReport struct {
+	MessageID  string         // provider message identifier
+	Status     DeliveryStatus // final delivery outcome
+	ReportedAt time.Time      // when the provider observed the status
+	Reason     string         // why the message was not delivered, if the provider says
+}
+
+// NewDeliveryReport constructs a DeliveryReport of the message the provider knows as messageID.
+// A zero reportedAt is taken as now.
+// Returns ErrBlankMessageID if messageID is empty, or ErrUnknownDeliveryStatus if status is unknown.
+func NewDeliveryReport(messageID string, status DeliveryStatus, reportedAt time.Time, reason string) (*DeliveryReport, error) {
+	if messageID == "" {
+		return nil, ErrBlankMessageID
+	}
+	switch status {
+	case DeliveryDelivered, DeliveryUndelivered:
+	default:
+		return nil, ErrUnknownDeliveryStatus
+	}
+	if reportedAt.IsZero() {
+		reportedAt = time.Now()
+	}
+	return &DeliveryReport{
+		MessageID:  messageID,
+		Status:     status,
+		ReportedAt: reportedAt,
+		Reason:     reason,
+	}, nil
+}
+
+// SetDelivery records the final delivery status of the sent Message reported by r.
+func (m *Message) SetDelivery(r *DeliveryReport) {
+	m.DeliveryStatus = r.Status
+	m.DeliveryReportedAt = r.ReportedAt
+	m.DeliveryError = r.Reason
+}
+
+// IsDelivered reports whether the provider reported the Message as delivered to the recipient.
+func (m *Message) IsDelivered() bool {
+	return m.DeliveryStatus == DeliveryDelivered
+}
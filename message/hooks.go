@@ -0,0 +1,81 @@
+package message
+
+import (
+	"context"
+	"sync"
+)
+
+// Hook handles a message reaching a stage of its send lifecycle.
+// cause is the reason of the failure for failed and dead-lettered messages, nil otherwise.
+type Hook func(ctx context.Context, msg *Message, cause error)
+
+// Hooks is an in-process event bus of the send lifecycle of messages. Components such as metrics or notifications
+// subscribe with the On methods, and the code moving messages through their lifecycle publishes the stages with
+// the matching methods, so neither needs to know the other.
+// Hooks run synchronously in registration order on the goroutine publishing the stage, so they must return quickly
+// and hand slow work, like HTTP calls, to the background. The zero Hooks has no subscribers and is ready to use.
+// Hooks are safe for concurrent use.
+type Hooks struct {
+	mu           sync.RWMutex // guards the hook lists
+	queued       []Hook       // hooks run when a message is stored for sending
+	sent         []Hook       // hooks run when a message was delivered
+	failed       []Hook       // hooks run when a delivery attempt failed and is retried later
+	deadLettered []Hook       // hooks run when delivery of a message is given up
+}
+
+// OnQueued registers fn to run whenever a message was stored for sending.
+func (h *Hooks) OnQueued(fn Hook) {
+	h.register(&h.queued, fn)
+}
+
+// OnSent registers fn to run whenever a message was delivered and its sent state stored.
+func (h *Hooks) OnSent(fn Hook) {
+	h.register(&h.sent, fn)
+}
+
+// OnFailed registers fn to run whenever a delivery attempt failed and the message is tried again later.
+func (h *Hooks) OnFailed(fn Hook) {
+	h.register(&h.failed, fn)
+}
+
+// OnDeadLettered registers fn to run whenever delivery of a message is given up, e.g. after its last attempt failed.
+func (h *Hooks) OnDeadLettered(fn Hook) {
+	h.register(&h.deadLettered, fn)
+}
+
+// Queued runs the OnQueued hooks for msg.
+func (h *Hooks) Queued(ctx context.Context, msg *Message) {
+	h.run(&h.queued, ctx, msg, nil)
+}
+
+// Sent runs the OnSent hooks for msg.
+func (h *Hooks) Sent(ctx context.Context, msg *Message) {
+	h.run(&h.sent, ctx, msg, nil)
+}
+
+// Failed runs the OnFailed hooks for msg, whose delivery attempt failed with cause.
+func (h *Hooks) Failed(ctx context.Context, msg *Message, cause error) {
+	h.run(&h.failed, ctx, msg, cause)
+}
+
+// DeadLettered runs the OnDeadLettered hooks for msg, whose delivery was given up for cause.
+func (h *Hooks) DeadLettered(ctx context.Context, msg *Message, cause error) {
+	h.run(&h.deadLettered, ctx, msg, cause)
+}
+
+// register appends fn to the hook list at hooks.
+func (h *Hooks) register(hooks *[]Hook, fn Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*hooks = append(*hooks, fn)
+}
+
+// run calls every hook of the list at hooks with the given arguments.
+func (h *Hooks) run(hooks *[]Hook, ctx context.Context, msg *Message, cause error) {
+	h.mu.RLock()
+	fns := *hooks
+	h.mu.RUnlock()
+	for _, fn := range fns {
+		fn(ctx, msg, cause)
+	}
+}
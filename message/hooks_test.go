@@ -0,0 +1,45 @@
+package message_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+func TestHooks(t *testing.T) {
+	var got []string
+	record := func(name string) message.Hook {
+		return func(_ context.Context, msg *message.Message, cause error) {
+			if cause != nil {
+				name += " " + cause.Error()
+			}
+			got = append(got, name+" "+msg.ID)
+		}
+	}
+	var hooks message.Hooks
+	hooks.OnQueued(record("queued"))
+	hooks.OnSent(record("sent first"))
+	hooks.OnSent(record("sent second"))
+	hooks.OnFailed(record("failed"))
+	hooks.OnDeadLettered(record("dead-lettered"))
+	msg := &message.Message{ID: "1"}
+
+	hooks.Queued(context.Background(), msg)
+	hooks.Sent(context.Background(), msg)
+	hooks.Failed(context.Background(), msg, errors.New("timeout"))
+	hooks.DeadLettered(context.Background(), msg, errors.New("rejected"))
+
+	want := []string{"queued 1", "sent first 1", "sent second 1", "failed timeout 1", "dead-lettered rejected 1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("hooks ran %q, want %q", got, want)
+	}
+}
+
+func TestHooks_NoSubscribers(t *testing.T) {
+	var hooks message.Hooks
+	hooks.Queued(context.Background(), &message.Message{ID: "1"})
+	hooks.DeadLettered(context.Background(), &message.Message{ID: "1"}, errors.New("rejected"))
+}
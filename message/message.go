@@ -2,6 +2,7 @@
 package message
 
 import (
+	"encoding/json"
 	"errors"
 	"regexp"
 	"time"
@@ -27,6 +28,56 @@ var (
 
 	// ErrNegativeCharacterLimit is returned when truncating content with a negative limit.
 	ErrNegativeCharacterLimit = errors.New("negative character limit")
+
+	// ErrBlankContent is returned when creating a new Message without content.
+	ErrBlankContent = errors.New("content can't be blank")
+
+	// ErrBlankTemplate is returned when creating a new templated Message without a template name.
+	ErrBlankTemplate = errors.New("template can't be blank")
+
+	// ErrUnknownTemplate is returned when creating or sending a Message rendered from a template that does not exist.
+	ErrUnknownTemplate = errors.New("unknown template")
+
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent again with a different message.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different message")
+
+	// ErrMessageNotFound is returned when a message with the requested ID does not exist.
+	ErrMessageNotFound = errors.New("message not found")
+
+	// ErrMessageNotFailed is returned when re-queueing a message whose delivery was not given up.
+	ErrMessageNotFailed = errors.New("message delivery has not failed")
+
+	// ErrMessageNotPending is returned when canceling a message that was sent, given up or canceled already.
+	ErrMessageNotPending = errors.New("message is no longer pending")
+
+	// ErrMessageBeingSent is returned when canceling a message that an instance is delivering right now.
+	ErrMessageBeingSent = errors.New("message is being sent")
+
+	// ErrMessageDeleted is returned when an idempotency key is sent again after the message created with it was deleted.
+	ErrMessageDeleted = errors.New("message created with the idempotency key was deleted")
+
+	// ErrMessagePending is returned when deleting a message that still waits for delivery; it must be canceled first.
+	ErrMessagePending = errors.New("message is still pending")
+
+	// ErrDuplicateMessage is the reason recorded for messages not sent because identical content was sent to the same
+	// recipient shortly before.
+	ErrDuplicateMessage = errors.New("identical message was sent to the recipient recently")
+
+	// ErrClaimLost is returned when saving the delivery state of a message whose claim expired and was taken over
+	// by another instance.
+	ErrClaimLost = errors.New("message claim was taken over by another instance")
+)
+
+// Status is the state of a Message in its delivery lifecycle, see Message.Status.
+type Status string
+
+const (
+	StatusPending  Status = "pending"  // waiting for delivery, possibly scheduled for later or for a retry
+	StatusSent     Status = "sent"     // delivered to the provider
+	StatusFailed   Status = "failed"   // delivery was given up after failed attempts
+	StatusRejected Status = "rejected" // a Validator rejected the message before sending
+	StatusCanceled Status = "canceled" // canceled before being sent
+	StatusExpired  Status = "expired"  // given up because it expired before being sent
 )
 
 // validatePhone ensures the given number matches E.164 format.
@@ -38,31 +89,107 @@ func validatePhone(num string) error {
 }
 
 // Message represents an outbound message with recipient information and send metadata.
-// ID is the internal identifier, To is the E.164 phone number, Content is the message body.
+// ID is the internal identifier, To is the recipient on the Channel, e.g. an E.164 phone number for SMS,
+// Content is the message body.
 type Message struct {
-	ID        string    // internal message identifier
-	To        string    // recipient phone number in E.164 format
-	Content   string    // message payload
-	MessageID string    // external message provider ID after sending
-	SentAt    time.Time // timestamp when the message was sent
+	ID                 string            // internal message identifier
+	To                 string            // recipient: E.164 phone number, email address or device token, depending on Channel
+	Content            string            // message payload
+	MessageID          string            // external message provider ID after sending
+	SentAt             time.Time         // timestamp when the message was sent
+	IdempotencyKey     string            // optional client-supplied key that deduplicates creation requests
+	Tenant             string            // customer the message belongs to, DefaultTenant if not set
+	CreatedAt          time.Time         // timestamp when the message was stored; zero until it is
+	Attempts           int               // failed delivery attempts so far
+	LastError          string            // reason the last delivery attempt failed
+	NextAttemptAt      time.Time         // earliest time of the next delivery attempt after a failure; zero means any time
+	FailedAt           time.Time         // timestamp when delivery was given up; zero while it is still tried
+	ClaimToken         string            // token of the claim under which the message is being delivered, see Repository.Claim
+	Priority           int               // messages with a higher priority are sent first, 0 by default
+	ScheduledAt        time.Time         // earliest time the message may be delivered; zero means right away
+	ExpiresAt          time.Time         // time after which the message is no longer delivered; zero means never
+	ExpiredAt          time.Time         // timestamp when the message was found expired and given up; zero while it is still sent
+	CanceledAt         time.Time         // timestamp when the message was canceled before being sent; zero unless canceled
+	RejectedAt         time.Time         // timestamp when a Validator rejected the message before sending; zero unless rejected
+	Template           string            // name of the template Content is rendered from at send time; empty for fixed content
+	Variables          map[string]string // values the template is rendered with
+	Channel            Channel           // medium the message is delivered through, ChannelSMS if not set
+	DeliveryStatus     DeliveryStatus    // final delivery outcome reported by the provider; empty until reported
+	DeliveryReportedAt time.Time         // when the provider observed DeliveryStatus
+	DeliveryError      string            // why the provider could not deliver the message, if it said
+	ProviderStatusCode int               // HTTP status code the provider accepted the message with; 0 if unknown
+	ProviderResponse   json.RawMessage   // JSON body the provider accepted the message with; nil if unknown
 }
 
-// NewMessage constructs a new Message with the given id, recipient, and content.
+// NewMessage constructs a new SMS Message with the given id, recipient, and content.
 // Returns ErrBlankID if id is empty, or ErrInvalidPhoneNumber if To is invalid.
 func NewMessage(id, to, content string) (*Message, error) {
+	return NewChannelMessage(id, ChannelSMS, to, content)
+}
+
+// NewChannelMessage constructs a new Message delivered through ch with the given id, recipient, and content.
+// Returns ErrBlankID if id is empty, ErrUnknownChannel if ch is unknown, or an error if to is no recipient on ch.
+func NewChannelMessage(id string, ch Channel, to, content string) (*Message, error) {
 	if id == "" {
 		return nil, ErrBlankID
 	}
-	if err := validatePhone(to); err != nil {
+	if err := validateRecipient(ch, to); err != nil {
 		return nil, err
 	}
 	return &Message{
 		ID:      id,
 		To:      to,
 		Content: content,
+		Channel: ch,
+	}, nil
+}
+
+// NewUnsentMessage constructs an SMS Message that has not been stored yet, so it has no ID.
+// The repository assigns the ID on insert.
+// Returns ErrInvalidPhoneNumber if to is invalid, or ErrBlankContent if content is empty.
+func NewUnsentMessage(to, content string) (*Message, error) {
+	return NewUnsentChannelMessage(ChannelSMS, to, content)
+}
+
+// NewUnsentChannelMessage constructs a Message delivered through ch that has not been stored yet.
+// Returns ErrUnknownChannel if ch is unknown, an error if to is no recipient on ch, or ErrBlankContent if content is empty.
+func NewUnsentChannelMessage(ch Channel, to, content string) (*Message, error) {
+	if err := validateRecipient(ch, to); err != nil {
+		return nil, err
+	}
+	if content == "" {
+		return nil, ErrBlankContent
+	}
+	return &Message{
+		To:      to,
+		Content: content,
+		Channel: ch,
+	}, nil
+}
+
+// NewTemplatedMessage constructs a Message delivered through ch that has not been stored yet, whose content is
+// rendered from the named template with variables when it is sent.
+// Returns ErrUnknownChannel if ch is unknown, an error if to is no recipient on ch, or ErrBlankTemplate if template is empty.
+func NewTemplatedMessage(ch Channel, to, template string, variables map[string]string) (*Message, error) {
+	if err := validateRecipient(ch, to); err != nil {
+		return nil, err
+	}
+	if template == "" {
+		return nil, ErrBlankTemplate
+	}
+	return &Message{
+		To:        to,
+		Template:  template,
+		Variables: variables,
+		Channel:   ch,
 	}, nil
 }
 
+// IsTemplated reports whether the Content of the Message is rendered from a template at send time.
+func (m *Message) IsTemplated() bool {
+	return m.Template != ""
+}
+
 // SetSent marks the Message as sent by providing an external messageID and sentAt timestamp.
 // Returns ErrBlankMessageID if messageID is empty, or ErrInvalidSentDatetime if sentAt is zero.
 func (m *Message) SetSent(messageID string, sentAt time.Time) error {
@@ -77,15 +204,122 @@ func (m *Message) SetSent(messageID string, sentAt time.Time) error {
 	return nil
 }
 
-// TruncatedContent returns the Content truncated to at most limit characters.
+// SetSentResult marks the message sent like SetSent, with the provider message ID and send time of res, and records
+// the status code and response body res carries, so whether the provider accepted the message can be proven later.
+func (m *Message) SetSentResult(res *SendResult) error {
+	if err := m.SetSent(res.MessageID, res.SentAt); err != nil {
+		return err
+	}
+	m.ProviderStatusCode = res.StatusCode
+	m.ProviderResponse = res.Response
+	return nil
+}
+
+// SetAttemptFailed records a failed delivery attempt and defers the next one until nextAttemptAt.
+func (m *Message) SetAttemptFailed(cause error, nextAttemptAt time.Time) {
+	m.Attempts++
+	m.LastError = cause.Error()
+	m.NextAttemptAt = nextAttemptAt
+}
+
+// SetRateLimited defers the next delivery attempt until nextAttemptAt, as the provider refused the Message for the
+// rate of sending with cause. Unlike SetAttemptFailed, this does not count as a failed attempt.
+func (m *Message) SetRateLimited(cause error, nextAttemptAt time.Time) {
+	m.LastError = cause.Error()
+	m.NextAttemptAt = nextAttemptAt
+}
+
+// SetFailed records a failed delivery attempt after which delivery is given up, at failedAt.
+func (m *Message) SetFailed(cause error, failedAt time.Time) {
+	m.Attempts++
+	m.LastError = cause.Error()
+	m.NextAttemptAt = time.Time{}
+	m.FailedAt = failedAt
+}
+
+// IsFailed reports whether delivery of the Message was given up.
+func (m *Message) IsFailed() bool {
+	return !m.FailedAt.IsZero()
+}
+
+// SetExpired gives up the Message at expiredAt, because it passed its ExpiresAt before being sent.
+func (m *Message) SetExpired(expiredAt time.Time) {
+	m.ExpiredAt = expiredAt
+}
+
+// IsExpired reports whether the Message was given up because it expired.
+func (m *Message) IsExpired() bool {
+	return !m.ExpiredAt.IsZero()
+}
+
+// ExpiredBy reports whether the Message may no longer be delivered at now, because it passed its ExpiresAt.
+func (m *Message) ExpiredBy(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
+
+// SetCanceled withdraws the Message at canceledAt, so it is no longer sent.
+func (m *Message) SetCanceled(canceledAt time.Time) {
+	m.CanceledAt = canceledAt
+}
+
+// IsCanceled reports whether the Message was canceled before being sent.
+func (m *Message) IsCanceled() bool {
+	return !m.CanceledAt.IsZero()
+}
+
+// SetRejected gives up the Message at rejectedAt without attempting it, because it failed validation with reason.
+func (m *Message) SetRejected(reason error, rejectedAt time.Time) {
+	m.LastError = reason.Error()
+	m.RejectedAt = rejectedAt
+}
+
+// IsRejected reports whether the Message was rejected by a Validator before being sent.
+func (m *Message) IsRejected() bool {
+	return !m.RejectedAt.IsZero()
+}
+
+// IsPending reports whether the Message still waits for delivery: it was neither sent nor given up nor canceled.
+func (m *Message) IsPending() bool {
+	return !m.IsSent() && !m.IsFailed() && !m.IsExpired() && !m.IsCanceled() && !m.IsRejected()
+}
+
+// IsSent reports whether the Message has been marked as sent.
+func (m *Message) IsSent() bool {
+	return !m.SentAt.IsZero()
+}
+
+// Status returns the state of the Message in its delivery lifecycle, the first of sent, failed, rejected, canceled and
+// expired that applies, like the status column of the message table, or pending if none does.
+func (m *Message) Status() Status {
+	switch {
+	case m.IsSent():
+		return StatusSent
+	case m.IsFailed():
+		return StatusFailed
+	case m.IsRejected():
+		return StatusRejected
+	case m.IsCanceled():
+		return StatusCanceled
+	case m.IsExpired():
+		return StatusExpired
+	}
+	return StatusPending
+}
+
+// TruncatedContent returns the Content truncated to at most limit characters, counted in runes so multi-byte
+// UTF-8 characters are never split.
 // If limit is negative, returns ErrNegativeCharacterLimit.
-// If limit >= len(Content), returns the full Content.
+// If Content has at most limit characters, returns the full Content.
 func (m *Message) TruncatedContent(limit int) (string, error) {
 	if limit < 0 {
 		return "", ErrNegativeCharacterLimit
 	}
-	if limit >= len(m.Content) {
-		return m.Content, nil
+	n := 0
+	for i := range m.Content {
+		if n == limit {
+			return m.Content[:i], nil
+		}
+		n++
 	}
-	return m.Content[:limit], nil
+	return m.Content, nil
 }
@@ -1,6 +1,7 @@
 package message_test
 
 import (
+	"errors"
 	"github.com/grustamli/insider-msg-sender/message"
 	"testing"
 	"time"
@@ -78,6 +79,30 @@ func TestMessage_SetSent(t *testing.T) {
 	}
 }
 
+func TestMessage_SetSentResult(t *testing.T) {
+	msg, err := message.NewMessage("test-id", "+994123456789", "test content")
+	if err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	sentAt := time.Now()
+
+	err = msg.SetSentResult(&message.SendResult{MessageID: "ext-1", SentAt: sentAt, StatusCode: 202,
+		Response: []byte(`{"messageId":"ext-1"}`)})
+
+	if err != nil {
+		t.Fatalf("SetSentResult() error = %v", err)
+	}
+	if !msg.IsSent() || msg.MessageID != "ext-1" || !msg.SentAt.Equal(sentAt) {
+		t.Errorf("message not marked sent by ext-1 at %v: %+v", sentAt, msg)
+	}
+	if msg.ProviderStatusCode != 202 || string(msg.ProviderResponse) != `{"messageId":"ext-1"}` {
+		t.Errorf("provider response = %d %s, want 202 {\"messageId\":\"ext-1\"}", msg.ProviderStatusCode, msg.ProviderResponse)
+	}
+	if err := msg.SetSentResult(&message.SendResult{SentAt: sentAt}); !errors.Is(err, message.ErrBlankMessageID) {
+		t.Errorf("SetSentResult() without message ID error = %v, want %v", err, message.ErrBlankMessageID)
+	}
+}
+
 func TestMessage_SetSent_StateChanges(t *testing.T) {
 	msg, err := message.NewMessage("test-id", "+994123456789", "test content")
 	if err != nil {
@@ -197,6 +222,20 @@ func TestMessage_TruncatedContent(t *testing.T) {
 			expectedResult: "",
 			expectError:    nil,
 		},
+		{
+			name:           "multi-byte characters are not split",
+			content:        "Çağrı merkezi",
+			limit:          4,
+			expectedResult: "Çağr",
+			expectError:    nil,
+		},
+		{
+			name:           "limit counts characters, not bytes",
+			content:        "Привет",
+			limit:          6,
+			expectedResult: "Привет",
+			expectError:    nil,
+		},
 		{
 			name:           "large limit",
 			content:        "Short",
@@ -281,3 +320,254 @@ func BenchmarkMessage_TruncatedContent(b *testing.B) {
 		msg.TruncatedContent(20)
 	}
 }
+
+func TestNewUnsentMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		to          string
+		content     string
+		expectError error
+	}{
+		{
+			name:        "valid recipient and content",
+			to:          "+994123456789",
+			content:     "hello",
+			expectError: nil,
+		},
+		{
+			name:        "invalid phone number",
+			to:          "994123456789",
+			content:     "hello",
+			expectError: message.ErrInvalidPhoneNumber,
+		},
+		{
+			name:        "blank content",
+			to:          "+994123456789",
+			content:     "",
+			expectError: message.ErrBlankContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := message.NewUnsentMessage(tt.to, tt.content)
+
+			if tt.expectError != nil {
+				if err != tt.expectError {
+					t.Errorf("Expected error %v, got %v", tt.expectError, err)
+				}
+				if msg != nil {
+					t.Errorf("Expected nil message on error, got %+v", msg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if msg.ID != "" || msg.IsSent() {
+				t.Errorf("Expected new message without ID and sent state, got %+v", msg)
+			}
+			if msg.To != tt.to || msg.Content != tt.content {
+				t.Errorf("Expected to=%q content=%q, got to=%q content=%q", tt.to, tt.content, msg.To, msg.Content)
+			}
+		})
+	}
+}
+
+func TestNewTemplatedMessage(t *testing.T) {
+	vars := map[string]string{"name": "Ada"}
+
+	msg, err := message.NewTemplatedMessage(message.ChannelSMS, "+994123456789", "welcome", vars)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !msg.IsTemplated() || msg.Template != "welcome" || msg.Variables["name"] != "Ada" || msg.Content != "" {
+		t.Errorf("Expected templated message without content, got %+v", msg)
+	}
+
+	if _, err := message.NewTemplatedMessage(message.ChannelSMS, "+994123456789", "", vars); err != message.ErrBlankTemplate {
+		t.Errorf("Expected error %v, got %v", message.ErrBlankTemplate, err)
+	}
+	if _, err := message.NewTemplatedMessage(message.ChannelSMS, "994123456789", "welcome", vars); err != message.ErrInvalidPhoneNumber {
+		t.Errorf("Expected error %v, got %v", message.ErrInvalidPhoneNumber, err)
+	}
+}
+
+func TestNewUnsentChannelMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		channel     message.Channel
+		to          string
+		expectError error
+	}{
+		{name: "sms", channel: message.ChannelSMS, to: "+994123456789"},
+		{name: "sms to email address", channel: message.ChannelSMS, to: "ada@example.com", expectError: message.ErrInvalidPhoneNumber},
+		{name: "email", channel: message.ChannelEmail, to: "ada@example.com"},
+		{name: "email to phone number", channel: message.ChannelEmail, to: "+994123456789", expectError: message.ErrInvalidEmailAddress},
+		{name: "email with display name", channel: message.ChannelEmail, to: "Ada <ada@example.com>", expectError: message.ErrInvalidEmailAddress},
+		{name: "push", channel: message.ChannelPush, to: "device-token-1"},
+		{name: "push without token", channel: message.ChannelPush, to: "", expectError: message.ErrBlankRecipient},
+		{name: "chat", channel: message.ChannelChat, to: "alerts"},
+		{name: "chat without room", channel: message.ChannelChat, to: "", expectError: message.ErrBlankRecipient},
+		{name: "unknown channel", channel: "fax", to: "+994123456789", expectError: message.ErrUnknownChannel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := message.NewUnsentChannelMessage(tt.channel, tt.to, "hello")
+
+			if err != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if err == nil && (msg.Channel != tt.channel || msg.To != tt.to) {
+				t.Errorf("Expected channel=%q to=%q, got %+v", tt.channel, tt.to, msg)
+			}
+		})
+	}
+}
+
+func TestParseChannel(t *testing.T) {
+	if ch, err := message.ParseChannel("email"); err != nil || ch != message.ChannelEmail {
+		t.Errorf("Expected %q, got %q and error %v", message.ChannelEmail, ch, err)
+	}
+	if _, err := message.ParseChannel("fax"); err != message.ErrUnknownChannel {
+		t.Errorf("Expected error %v, got %v", message.ErrUnknownChannel, err)
+	}
+	if ch := message.ChannelOf(&message.Message{}); ch != message.ChannelSMS {
+		t.Errorf("Expected messages without a channel to be sent by %q, got %q", message.ChannelSMS, ch)
+	}
+}
+
+func TestMessage_FailedAttempts(t *testing.T) {
+	msg := &message.Message{ID: "1", To: "+905551234567", Content: "hello"}
+	next := time.Now().Add(time.Minute)
+
+	msg.SetAttemptFailed(errors.New("timeout"), next)
+	if msg.Attempts != 1 || msg.LastError != "timeout" || !msg.NextAttemptAt.Equal(next) {
+		t.Errorf("after a failed attempt got attempts=%d last_error=%q next=%v", msg.Attempts, msg.LastError, msg.NextAttemptAt)
+	}
+	if msg.IsFailed() {
+		t.Error("IsFailed() = true while the message is still retried")
+	}
+
+	later := next.Add(time.Minute)
+	msg.SetRateLimited(errors.New("too many requests"), later)
+	if msg.Attempts != 1 || msg.LastError != "too many requests" || !msg.NextAttemptAt.Equal(later) {
+		t.Errorf("after being rate limited got attempts=%d last_error=%q next=%v", msg.Attempts, msg.LastError, msg.NextAttemptAt)
+	}
+
+	failedAt := time.Now()
+	msg.SetFailed(errors.New("provider down"), failedAt)
+	if msg.Attempts != 2 || msg.LastError != "provider down" {
+		t.Errorf("after giving up got attempts=%d last_error=%q", msg.Attempts, msg.LastError)
+	}
+	if !msg.IsFailed() || !msg.FailedAt.Equal(failedAt) {
+		t.Errorf("IsFailed() = %v, FailedAt = %v; want given up at %v", msg.IsFailed(), msg.FailedAt, failedAt)
+	}
+	if !msg.NextAttemptAt.IsZero() {
+		t.Errorf("NextAttemptAt = %v after giving up, want zero", msg.NextAttemptAt)
+	}
+}
+
+func TestMessage_Expiry(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      bool
+	}{
+		{name: "no expiry", want: false},
+		{name: "expires later", expiresAt: now.Add(time.Minute), want: false},
+		{name: "expires now", expiresAt: now, want: true},
+		{name: "expired before", expiresAt: now.Add(-time.Minute), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &message.Message{ID: "1", To: "+905551234567", Content: "123456", ExpiresAt: tt.expiresAt}
+			if got := msg.ExpiredBy(now); got != tt.want {
+				t.Errorf("ExpiredBy() = %v, want %v", got, tt.want)
+			}
+			if msg.IsExpired() {
+				t.Error("IsExpired() = true before SetExpired")
+			}
+			msg.SetExpired(now)
+			if !msg.IsExpired() || !msg.ExpiredAt.Equal(now) {
+				t.Errorf("IsExpired() = %v, ExpiredAt = %v; want expired at %v", msg.IsExpired(), msg.ExpiredAt, now)
+			}
+		})
+	}
+}
+
+func TestStats_FailureRate(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats message.Stats
+		want  float64
+	}{
+		{name: "no finished deliveries", stats: message.Stats{Unsent: 5}, want: 0},
+		{name: "only sent", stats: message.Stats{Sent: 4}, want: 0},
+		{name: "failed and sent", stats: message.Stats{Sent: 3, Failed: 1, Expired: 6}, want: 0.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.FailureRate(); got != tt.want {
+				t.Errorf("FailureRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessage_IsPending(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		update func(*message.Message)
+		want   bool
+	}{
+		{name: "queued", update: func(*message.Message) {}, want: true},
+		{name: "retrying", update: func(m *message.Message) { m.SetAttemptFailed(errors.New("provider down"), now) }, want: true},
+		{name: "sent", update: func(m *message.Message) { _ = m.SetSent("ext-1", now) }, want: false},
+		{name: "failed", update: func(m *message.Message) { m.SetFailed(errors.New("provider down"), now) }, want: false},
+		{name: "expired", update: func(m *message.Message) { m.SetExpired(now) }, want: false},
+		{name: "canceled", update: func(m *message.Message) { m.SetCanceled(now) }, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &message.Message{ID: "1", To: "+905551234567", Content: "hello"}
+			tt.update(msg)
+			if got := msg.IsPending(); got != tt.want {
+				t.Errorf("IsPending() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessage_Status(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		update func(*message.Message)
+		want   message.Status
+	}{
+		{name: "queued", update: func(*message.Message) {}, want: message.StatusPending},
+		{name: "retrying", update: func(m *message.Message) { m.SetAttemptFailed(errors.New("provider down"), now) }, want: message.StatusPending},
+		{name: "sent", update: func(m *message.Message) { _ = m.SetSent("ext-1", now) }, want: message.StatusSent},
+		{name: "failed", update: func(m *message.Message) { m.SetFailed(errors.New("provider down"), now) }, want: message.StatusFailed},
+		{name: "rejected", update: func(m *message.Message) { m.SetRejected(errors.New("blocked"), now) }, want: message.StatusRejected},
+		{name: "canceled", update: func(m *message.Message) { m.SetCanceled(now) }, want: message.StatusCanceled},
+		{name: "expired", update: func(m *message.Message) { m.SetExpired(now) }, want: message.StatusExpired},
+		{name: "failed then expired", update: func(m *message.Message) {
+			m.SetFailed(errors.New("provider down"), now)
+			m.SetExpired(now)
+		}, want: message.StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &message.Message{ID: "1", To: "+905551234567", Content: "hello"}
+			tt.update(msg)
+			if got := msg.Status(); got != tt.want {
+				t.Errorf("Status() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDailyQuotaExceeded is recorded on messages deferred to the next day because their tenant sent its daily quota.
+var ErrDailyQuotaExceeded = errors.New("daily quota exceeded")
+
+// QuotaCounter counts the messages each tenant sent per day against its daily quota.
+// Counts are shared by every instance using the same QuotaCounter backend.
+type QuotaCounter interface {
+	// Reserve counts one more message of tenant on the day starting at day, unless limit messages are counted
+	// already, and reports whether it did.
+	Reserve(ctx context.Context, tenant string, day time.Time, limit int64) (bool, error)
+
+	// Release uncounts a message Reserve counted for tenant on the day starting at day, e.g. because its delivery failed.
+	Release(ctx context.Context, tenant string, day time.Time) error
+
+	// Used returns the number of messages counted for tenant on the day starting at day.
+	Used(ctx context.Context, tenant string, day time.Time) (int64, error)
+}
+
+// QuotaUsage is how much of its daily quota a tenant used today.
+type QuotaUsage struct {
+	Tenant   string    // tenant the quota belongs to
+	Limit    int64     // messages the tenant may send per day
+	Used     int64     // messages the tenant sent today
+	ResetsAt time.Time // start of the next day, when the count starts over
+}
+
+// Remaining returns the number of messages the tenant may still send today.
+func (u *QuotaUsage) Remaining() int64 {
+	return max(u.Limit-u.Used, 0)
+}
+
+// SetDeferred defers the next delivery attempt until nextAttemptAt for cause, e.g. as the daily quota of the tenant
+// is used up. Like SetRateLimited, this does not count as a failed attempt.
+func (m *Message) SetDeferred(cause error, nextAttemptAt time.Time) {
+	m.LastError = cause.Error()
+	m.NextAttemptAt = nextAttemptAt
+}
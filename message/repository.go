@@ -8,29 +8,209 @@ import (
 // SentMessage represents a record of a successfully sent message.
 // It includes the external provider's message ID and the timestamp when it was sent.
 type SentMessage struct {
+	ID        string    `json:"id"`         // internal message identifier
+	To        string    `json:"to"`         // recipient phone number in E.164 format
+	Content   string    `json:"content"`    // message payload
 	MessageID string    `json:"message_id"` // external provider message identifier
 	SentAt    time.Time `json:"sent_at"`    // timestamp when the message was sent
+	Tenant    string    `json:"tenant"`     // customer the message belongs to
+}
+
+// Position locates a sent message in delivery order: by sent timestamp, ties broken by ID.
+// Messages sent later always come after every existing position, so paging by position neither skips nor repeats
+// messages while new ones are being sent.
+type Position struct {
+	SentAt time.Time // timestamp when the message was sent
+	ID     string    // internal message identifier
+}
+
+// PositionOf returns the position of m in delivery order.
+func PositionOf(m *SentMessage) *Position {
+	return &Position{SentAt: m.SentAt, ID: m.ID}
+}
+
+// Filter narrows down a listing of messages. Zero-valued fields do not restrict the result.
+type Filter struct {
+	To         string    // exact recipient match
+	Contains   string    // case-insensitive content substring
+	SentAfter  time.Time // lower bound for the sent timestamp (exclusive), sent messages only
+	SentBefore time.Time // upper bound for the sent timestamp (exclusive), sent messages only
+	After      *Position // only messages after this position, for paging forwards; unsent messages only use its ID
+	Limit      int       // maximum number of results, 0 means unlimited
+}
+
+// Stats holds aggregate figures over all stored messages.
+type Stats struct {
+	Sent         int64         `json:"sent"`            // number of delivered messages
+	Unsent       int64         `json:"unsent"`          // number of messages waiting for delivery
+	Failed       int64         `json:"failed"`          // number of messages whose delivery was given up
+	Expired      int64         `json:"expired"`         // number of messages given up because they expired unsent
+	Canceled     int64         `json:"canceled"`        // number of messages canceled before being sent
+	Rejected     int64         `json:"rejected"`        // number of messages rejected by validation before being sent
+	SentLastHour int64         `json:"sent_last_hour"`  // messages delivered within the last hour
+	SentLastDay  int64         `json:"sent_last_day"`   // messages delivered within the last 24 hours
+	AvgLatency   time.Duration `json:"avg_latency"`     // average time from creation to delivery of sent messages
+	SentByHour   []HourCount   `json:"sent_by_hour"`    // messages delivered in each of the last 24 hours, oldest first
+	Quota        *QuotaUsage   `json:"quota,omitempty"` // daily quota usage of the tenant, nil without a daily quota
+}
+
+// HourCount is the number of messages counted within one hour.
+type HourCount struct {
+	Hour  time.Time `json:"hour"`  // start of the hour
+	Count int64     `json:"count"` // number of messages within the hour
+}
+
+// FailureRate returns the share of finished deliveries that were given up, between 0 and 1.
+// Expired messages were never attempted to their end and do not count; without finished deliveries it returns 0.
+func (s *Stats) FailureRate() float64 {
+	finished := s.Sent + s.Failed
+	if finished == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(finished)
 }
 
 // Repository provides methods to store and retrieve messages from a data store.
 // It supports fetching unsent and sent messages, as well as updating send status.
 type Repository interface {
-	// GetNextUnsent returns the next Message that has not yet been sent.
-	// If there are no unsent messages, it returns (nil, nil).
+	// GetNextUnsent returns the next Message that has not yet been sent and is due for a delivery attempt,
+	// skipping messages whose ScheduledAt or NextAttemptAt is still ahead, messages that failed for good, expired or were canceled
+	// and messages claimed by an instance delivering them. Messages past their ExpiresAt are returned until
+	// they are marked with Expire.
+	// Messages with a higher Priority come first, older messages first among equal priorities.
+	// If there are no such messages, it returns (nil, nil).
 	GetNextUnsent(ctx context.Context) (*Message, error)
 
-	// GetAllUnsent returns all Messages that are not yet sent.
+	// GetAllUnsent returns all Messages that are not yet sent and are due for a delivery attempt, like GetNextUnsent,
+	// in the order GetNextUnsent would return them.
 	// Returns an empty slice or nil if no unsent messages exist.
 	GetAllUnsent(ctx context.Context) ([]*Message, error)
 
+	// GetUnsent returns up to n Messages that are not yet sent and are due for a delivery attempt, like GetNextUnsent,
+	// in the order GetNextUnsent would return them.
+	// Returns an empty slice or nil if no unsent messages exist.
+	GetUnsent(ctx context.Context, n int) ([]*Message, error)
+
 	// GetAllSent returns all SentMessage records for messages that have been sent.
 	// Returns an empty slice or nil if no sent messages exist.
 	GetAllSent(ctx context.Context) ([]*SentMessage, error)
 
-	// Save updates the repository with the provided Message's sent state.
+	// FindSent returns the sent messages matching f in delivery order, see Position.
+	FindSent(ctx context.Context, f Filter) ([]*SentMessage, error)
+
+	// FindUnsent returns the unsent messages matching f, oldest first, including those scheduled for later
+	// and those waiting for a retry, but not those that failed for good, expired or were canceled.
+	// SentAfter and SentBefore are ignored, After pages past the unsent message with the ID of the position.
+	FindUnsent(ctx context.Context, f Filter) ([]*Message, error)
+
+	// FindFailed returns the messages whose delivery was given up that match f, most recently failed first.
+	// SentAfter and SentBefore are ignored.
+	FindFailed(ctx context.Context, f Filter) ([]*Message, error)
+
+	// Requeue clears the attempts and failed state of the failed message with the given ID, so it is sent again.
+	// Returns ErrMessageNotFound if there is no failed message with that ID.
+	Requeue(ctx context.Context, id string) error
+
+	// WalkSent calls fn for every sent message, stopping at the first error fn returns.
+	// Implementations should not load all messages into memory at once.
+	WalkSent(ctx context.Context, fn func(*SentMessage) error) error
+
+	// GetByID returns the Message with the given internal id, sent or not.
+	// If no such message exists or it was deleted, it returns (nil, nil).
+	GetByID(ctx context.Context, id string) (*Message, error)
+
+	// Create stores a new unsent Message and returns it with its assigned ID.
+	// If msg carries an IdempotencyKey that is already stored, nothing is inserted:
+	// the existing Message is returned and created is false, or ErrMessageDeleted if it was deleted.
+	Create(ctx context.Context, msg *Message) (stored *Message, created bool, err error)
+
+	// GetStats returns aggregate figures over all stored messages.
+	GetStats(ctx context.Context) (*Stats, error)
+
+	// CountUnsent returns the number of messages waiting for delivery, without loading them.
+	CountUnsent(ctx context.Context) (int64, error)
+
+	// CountSent returns the number of delivered messages, without loading them.
+	CountSent(ctx context.Context) (int64, error)
+
+	// CountFailed returns the number of messages whose delivery was given up, without loading them.
+	CountFailed(ctx context.Context) (int64, error)
+
+	// CountSentByHour returns the number of messages delivered in each hour from the one since falls in up to the
+	// current one, oldest first. Hours without deliveries are included with a zero count.
+	CountSentByHour(ctx context.Context, since time.Time) ([]HourCount, error)
+
+	// InsertMany stores new unsent Messages atomically: either all of them are stored or none.
+	// IDs of the given messages are ignored.
+	InsertMany(ctx context.Context, msgs []*Message) error
+
+	// Claim reserves the unsent Message for delivery by the caller until the given time, recording msg.ClaimToken.
+	// It returns false without claiming it if the message was sent, failed for good, was canceled or is claimed by someone
+	// else whose claim has not expired yet, so instances sharing the repository never deliver a message twice.
+	Claim(ctx context.Context, msg *Message, until time.Time) (bool, error)
+
+	// ClaimUnsent reads up to n Messages that are not yet sent and are due for a delivery attempt, like GetUnsent, and
+	// claims them under token until the given time, atomically, so instances reading concurrently never get the same
+	// messages. Messages being claimed by another instance at the same time are skipped rather than waited for.
+	// The returned messages carry token as their ClaimToken.
+	// Returns an empty slice or nil if no unsent messages exist.
+	ClaimUnsent(ctx context.Context, n int, token string, until time.Time) ([]*Message, error)
+
+	// Save updates the repository with the provided Message's sent state and releases its claim.
 	// It should persist the MessageID and SentAt timestamp.
-	// Returns an error if the update fails.
+	// Returns ErrClaimLost if the message is claimed under a token other than msg.ClaimToken,
+	// or an error if the update fails.
 	Save(ctx context.Context, msg *Message) error
+
+	// SaveAll updates the repository with the sent state of all provided Messages and releases their claims with a
+	// single operation, like Save does for one message. Messages not marked sent are ignored.
+	// It returns the messages left as they were because they are claimed under a token other than their ClaimToken,
+	// or an error if the update fails, in which case none of the messages is updated.
+	SaveAll(ctx context.Context, msgs []*Message) ([]*Message, error)
+
+	// Expire persists the ExpiredAt timestamp of the unsent Message, so it is no longer returned as unsent.
+	// Does nothing if the message was sent meanwhile or is claimed by an instance delivering it.
+	Expire(ctx context.Context, msg *Message) error
+
+	// Cancel persists the CanceledAt timestamp of the pending Message, so it is never returned as unsent nor claimed again.
+	// It returns false without canceling it if the message is no longer pending or is claimed by an instance delivering it.
+	Cancel(ctx context.Context, msg *Message) (bool, error)
+
+	// Delete soft-deletes the Message of the tenant with the given ID that is no longer pending: it stays in storage
+	// until ArchiveSent or DeleteSent reaches it, but no other method returns it or counts it anymore.
+	// Returns ErrMessageNotFound if the tenant has no such message, it is still pending or it was deleted already.
+	Delete(ctx context.Context, id string) error
+
+	// Reject persists the RejectedAt timestamp and LastError of the claimed Message and releases its claim,
+	// so it is never returned as unsent nor claimed again.
+	// Returns ErrClaimLost if the message is claimed under a token other than msg.ClaimToken.
+	Reject(ctx context.Context, msg *Message) error
+
+	// SaveAttempts updates the repository with the provided Message's failed delivery attempts and releases its claim.
+	// It should persist Attempts, LastError, NextAttemptAt and FailedAt.
+	// Returns ErrClaimLost if the message is claimed under a token other than msg.ClaimToken.
+	SaveAttempts(ctx context.Context, msg *Message) error
+
+	// ArchiveSent moves up to n Messages sent more than olderThan ago, oldest first, to an archive kept apart from the
+	// messages being sent, and returns how many it moved. Archived messages are no longer returned by any method.
+	// Deleted messages among them are dropped instead of archived, as are messages deleted more than olderThan ago
+	// without being sent, and counted alike.
+	ArchiveSent(ctx context.Context, olderThan time.Duration, n int) (int64, error)
+
+	// DeleteSent deletes up to n Messages sent more than olderThan ago, oldest first, and returns how many it deleted.
+	// Messages deleted more than olderThan ago without being sent are deleted along with them.
+	DeleteSent(ctx context.Context, olderThan time.Duration, n int) (int64, error)
+
+	// SaveDeliveryReport records the final delivery status reported by r on the sent Message with the provider
+	// message ID r.MessageID, whatever its tenant. A later report of the same message replaces an earlier one.
+	// Returns ErrMessageNotFound if no sent message has that provider message ID.
+	SaveDeliveryReport(ctx context.Context, r *DeliveryReport) error
+
+	// WithTx calls fn with a Repository whose operations all run in one transaction, committed if fn returns nil and
+	// rolled back otherwise, so sequences like a claim followed by an update are stored atomically.
+	// Called on a Repository handed to fn, it joins the transaction already running instead of starting another.
+	// Returns the error of fn, or of committing the transaction.
+	WithTx(ctx context.Context, fn func(Repository) error) error
 }
 
 // RepositoryMiddleware defines a decorator that wraps a Repository with additional behavior.
@@ -0,0 +1,109 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoRoute is returned by a Router for messages matching none of its routes when it has no default sender.
+var ErrNoRoute = errors.New("no sender routes the message")
+
+// Route selects the sender of the messages matching all of its conditions; empty conditions match every message.
+type Route struct {
+	Prefix  string  // prefix of the recipient, e.g. the country code "+90" of phone numbers
+	Channel Channel // channel of the message
+	Sender  string  // name of the sender delivering matching messages
+}
+
+// matches reports whether msg meets the conditions of r.
+func (r Route) matches(msg *Message) bool {
+	if r.Prefix != "" && !strings.HasPrefix(msg.To, r.Prefix) {
+		return false
+	}
+	if r.Channel != "" && ChannelOf(msg) != r.Channel {
+		return false
+	}
+	return true
+}
+
+// Router is a Sender delivering each message through one of several named senders, selected by the first of its
+// routes the message matches, or through the default sender if it matches none.
+type Router struct {
+	senders  map[string]Sender // senders by name
+	routes   []Route           // routes in order of precedence
+	fallback string            // name of the sender of messages matching no route; empty fails them with ErrNoRoute
+}
+
+// Ensure Router implements the Sender interface.
+var _ Sender = (*Router)(nil)
+
+// NewRouter constructs a Router selecting among senders by name with routes, delivering messages matching no route
+// through the sender named fallback, or failing them with ErrNoRoute if fallback is empty.
+// Returns an error if a route or fallback names an unknown sender.
+func NewRouter(senders map[string]Sender, routes []Route, fallback string) (*Router, error) {
+	for _, r := range routes {
+		if _, ok := senders[r.Sender]; !ok {
+			return nil, fmt.Errorf("route to unknown sender %q", r.Sender)
+		}
+	}
+	if _, ok := senders[fallback]; fallback != "" && !ok {
+		return nil, fmt.Errorf("unknown default sender %q", fallback)
+	}
+	return &Router{senders: senders, routes: routes, fallback: fallback}, nil
+}
+
+// Send delivers msg through the sender it is routed to.
+func (r *Router) Send(ctx context.Context, msg *Message) (*SendResult, error) {
+	s, err := r.route(msg)
+	if err != nil {
+		return nil, err
+	}
+	return s.Send(ctx, msg)
+}
+
+// SendBatch delivers msgs with one SendBatch call per sender they are routed to,
+// returning their results in the order of msgs.
+func (r *Router) SendBatch(ctx context.Context, msgs []*Message) []BatchResult {
+	ret := make([]BatchResult, len(msgs))
+	var (
+		order   []Sender                 // senders in order of their first message
+		batches = make(map[Sender][]int) // positions of the messages routed to each sender
+	)
+	for i, msg := range msgs {
+		s, err := r.route(msg)
+		if err != nil {
+			ret[i].Err = err
+			continue
+		}
+		if _, ok := batches[s]; !ok {
+			order = append(order, s)
+		}
+		batches[s] = append(batches[s], i)
+	}
+	for _, s := range order {
+		idx := batches[s]
+		batch := make([]*Message, len(idx))
+		for j, i := range idx {
+			batch[j] = msgs[i]
+		}
+		for j, res := range s.SendBatch(ctx, batch) {
+			ret[idx[j]] = res
+		}
+	}
+	return ret
+}
+
+// route returns the sender msg is routed to.
+func (r *Router) route(msg *Message) (Sender, error) {
+	for _, route := range r.routes {
+		if route.matches(msg) {
+			return r.senders[route.Sender], nil
+		}
+	}
+	if r.fallback == "" {
+		return nil, ErrNoRoute
+	}
+	return r.senders[r.fallback], nil
+}
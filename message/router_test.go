@@ -0,0 +1,108 @@
+package message_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// namedSender is a Sender prefixing the provider message IDs it returns with its name, counting its batches.
+type namedSender struct {
+	name    string
+	batches int
+}
+
+func (s *namedSender) Send(_ context.Context, msg *message.Message) (*message.SendResult, error) {
+	return &message.SendResult{MessageID: s.name + "-" + msg.ID, SentAt: time.Now()}, nil
+}
+
+func (s *namedSender) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	s.batches++
+	return message.SendEach(ctx, s, msgs)
+}
+
+func TestRouter_Send(t *testing.T) {
+	senders := map[string]message.Sender{
+		"tr":    &namedSender{name: "tr"},
+		"mail":  &namedSender{name: "mail"},
+		"world": &namedSender{name: "world"},
+	}
+	routes := []message.Route{
+		{Channel: message.ChannelEmail, Sender: "mail"},
+		{Prefix: "+90", Sender: "tr"},
+	}
+	tests := []struct {
+		name     string
+		fallback string
+		msg      *message.Message
+		want     string
+		wantErr  error
+	}{
+		{name: "prefix", fallback: "world", msg: &message.Message{ID: "1", To: "+905551234567"}, want: "tr-1"},
+		{name: "channel", fallback: "world", msg: &message.Message{ID: "2", To: "a@example.com", Channel: message.ChannelEmail}, want: "mail-2"},
+		{name: "fallback", fallback: "world", msg: &message.Message{ID: "3", To: "+14155550100"}, want: "world-3"},
+		{name: "no_route", msg: &message.Message{ID: "4", To: "+14155550100"}, wantErr: message.ErrNoRoute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, err := message.NewRouter(senders, routes, tt.fallback)
+			if err != nil {
+				t.Fatalf("NewRouter: %v", err)
+			}
+
+			res, err := router.Send(context.Background(), tt.msg)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Send error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if res.MessageID != tt.want {
+				t.Errorf("message ID = %q, want %q", res.MessageID, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouter_SendBatch(t *testing.T) {
+	tr, world := &namedSender{name: "tr"}, &namedSender{name: "world"}
+	router, err := message.NewRouter(map[string]message.Sender{"tr": tr, "world": world},
+		[]message.Route{{Prefix: "+90", Sender: "tr"}}, "world")
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	msgs := []*message.Message{
+		{ID: "1", To: "+905551234567"},
+		{ID: "2", To: "+14155550100"},
+		{ID: "3", To: "+905557654321"},
+	}
+
+	results := router.SendBatch(context.Background(), msgs)
+
+	for i, want := range []string{"tr-1", "world-2", "tr-3"} {
+		if r := results[i]; r.Err != nil || r.Result.MessageID != want {
+			t.Errorf("result %d = %+v, want message ID %q", i, r, want)
+		}
+	}
+	if tr.batches != 1 || world.batches != 1 {
+		t.Errorf("batches = %d and %d, want one per sender", tr.batches, world.batches)
+	}
+}
+
+func TestNewRouter_UnknownSender(t *testing.T) {
+	senders := map[string]message.Sender{"tr": &namedSender{name: "tr"}}
+
+	if _, err := message.NewRouter(senders, []message.Route{{Prefix: "+1", Sender: "us"}}, ""); err == nil {
+		t.Error("route to an unknown sender accepted")
+	}
+	if _, err := message.NewRouter(senders, nil, "us"); err == nil {
+		t.Error("unknown default sender accepted")
+	}
+}
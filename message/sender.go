@@ -2,6 +2,9 @@ package message
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"time"
 )
 
@@ -9,16 +12,139 @@ import (
 // MessageID is the external provider's identifier for the message,
 // SentAt is the timestamp when the message was sent.
 type SendResult struct {
-	MessageID string    // external provider message identifier
-	SentAt    time.Time // timestamp when the message was sent
+	MessageID  string          // external provider message identifier
+	SentAt     time.Time       // timestamp when the message was sent
+	StatusCode int             // HTTP status code the provider accepted the message with; 0 if not sent over HTTP
+	Response   json.RawMessage // JSON the provider accepted the message with; nil if it answered none
 }
 
-// Sender represents a service capable of sending Message entities.
-// Implementations should handle delivery via an external provider and
-// return a SendResult containing the provider-assigned ID and send time.
-type Sender interface {
+// SendError wraps an error returned by a Sender while delivering a message,
+// allowing callers to tell provider failures apart from storage or validation errors.
+type SendError struct {
+	Err error // underlying error reported by the sender
+}
+
+// Error returns the message of the underlying sender error.
+func (e *SendError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying sender error.
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitedError is returned by a Sender whose provider refused a message because too many messages were sent,
+// e.g. with HTTP status 429 Too Many Requests. The message itself is fine and is delivered once the provider
+// accepts messages again, RetryAfter from now.
+type RateLimitedError struct {
+	RetryAfter time.Duration // how long the provider asks to wait before sending again; zero if it did not say
+	Err        error         // underlying error reported by the sender
+}
+
+// Error returns the message of the underlying sender error.
+func (e *RateLimitedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying sender error.
+func (e *RateLimitedError) Unwrap() error {
+	return e.Err
+}
+
+// ProviderError is returned by a Sender whose provider answered a message with an error response, carrying what the
+// provider said about the failure so permanent rejections can be told apart from transient failures.
+type ProviderError struct {
+	StatusCode int    // HTTP status code of the response
+	Code       string // provider specific error code; empty if the provider did not send one
+	Message    string // error description of the provider; empty if it did not send one
+}
+
+// Error returns the status code along with the error code and description of the provider, if any.
+func (e *ProviderError) Error() string {
+	msg := fmt.Sprintf("received status %d", e.StatusCode)
+	if e.Code != "" {
+		msg += ": error " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Permanent reports whether the provider rejected the message itself, so sending it again cannot succeed:
+// any 4xx status other than 408 Request Timeout and 429 Too Many Requests.
+func (e *ProviderError) Permanent() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// BatchResult holds the outcome of sending one message of a batch: either Result or Err is set.
+type BatchResult struct {
+	Result *SendResult // provider-assigned ID and send time, on success
+	Err    error       // reason the message was not delivered, on failure
+}
+
+// SingleSender delivers messages one at a time. SendOneByOne turns it into a Sender.
+type SingleSender interface {
 	// Send attempts to deliver the provided Message.
 	// On success, it returns a SendResult and a nil error.
 	// On failure, it returns a non-nil error.
 	Send(ctx context.Context, msg *Message) (*SendResult, error)
 }
+
+// Sender represents a service capable of sending Message entities.
+// Implementations should handle delivery via an external provider and
+// return a SendResult containing the provider-assigned ID and send time.
+// Providers that accept bulk payloads implement SendBatch with a single request;
+// others can use SendEach or wrap a SingleSender with SendOneByOne.
+type Sender interface {
+	SingleSender
+
+	// SendBatch attempts to deliver all msgs, e.g. with a single provider request.
+	// It returns one BatchResult per message in the order of msgs; when the whole batch fails,
+	// every message carries the error.
+	SendBatch(ctx context.Context, msgs []*Message) []BatchResult
+}
+
+// SendEach sends msgs through s one after another, returning their results in the order of msgs.
+func SendEach(ctx context.Context, s SingleSender, msgs []*Message) []BatchResult {
+	ret := make([]BatchResult, len(msgs))
+	for i, msg := range msgs {
+		ret[i].Result, ret[i].Err = s.Send(ctx, msg)
+	}
+	return ret
+}
+
+// SendOneByOne adapts s into a Sender whose SendBatch sends the messages of a batch one after another.
+func SendOneByOne(s SingleSender) Sender {
+	return oneByOne{SingleSender: s}
+}
+
+// oneByOne is a Sender falling back to per-message sends for batches.
+type oneByOne struct {
+	SingleSender // sender delivering each message of a batch
+}
+
+// SendBatch sends msgs one by one with SendEach.
+func (o oneByOne) SendBatch(ctx context.Context, msgs []*Message) []BatchResult {
+	return SendEach(ctx, o.SingleSender, msgs)
+}
+
+// SenderMiddleware defines a decorator that wraps a Sender with additional behavior,
+// such as logging, metrics, retries or circuit breaking.
+type SenderMiddleware func(Sender) Sender
+
+// SenderWithMiddleware applies one or more SenderMiddleware decorators to a base Sender.
+// Middleware is applied in reverse order, so the first argument wraps the second, and so on.
+func SenderWithMiddleware(sender Sender, mws ...SenderMiddleware) Sender {
+	s := sender
+	// apply middleware in reverse to ensure correct ordering
+	for i := len(mws) - 1; i >= 0; i-- {
+		s = mws[i](s)
+	}
+	return s
+}
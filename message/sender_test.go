@@ -0,0 +1,117 @@
+package message_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// failingFor is a SingleSender failing for messages with the given content.
+type failingFor string
+
+func (f failingFor) Send(_ context.Context, msg *message.Message) (*message.SendResult, error) {
+	if msg.Content == string(f) {
+		return nil, errors.New("rejected")
+	}
+	return &message.SendResult{MessageID: "ext-" + msg.ID, SentAt: time.Now()}, nil
+}
+
+func TestProviderError(t *testing.T) {
+	tests := []struct {
+		err       *message.ProviderError
+		msg       string
+		permanent bool
+	}{
+		{err: &message.ProviderError{StatusCode: 400, Code: "21211", Message: "invalid number"}, msg: "received status 400: error 21211: invalid number", permanent: true},
+		{err: &message.ProviderError{StatusCode: 404}, msg: "received status 404", permanent: true},
+		{err: &message.ProviderError{StatusCode: 408, Message: "timeout"}, msg: "received status 408: timeout"},
+		{err: &message.ProviderError{StatusCode: 429}, msg: "received status 429"},
+		{err: &message.ProviderError{StatusCode: 503, Code: "DOWN"}, msg: "received status 503: error DOWN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.msg {
+				t.Errorf("Error() = %q, want %q", got, tt.msg)
+			}
+			if got := tt.err.Permanent(); got != tt.permanent {
+				t.Errorf("Permanent() = %v, want %v", got, tt.permanent)
+			}
+		})
+	}
+}
+
+func TestSendOneByOne(t *testing.T) {
+	sender := message.SendOneByOne(failingFor("bad"))
+	msgs := []*message.Message{
+		{ID: "1", Content: "good"},
+		{ID: "2", Content: "bad"},
+		{ID: "3", Content: "good"},
+	}
+
+	results := sender.SendBatch(context.Background(), msgs)
+
+	if len(results) != len(msgs) {
+		t.Fatalf("got %d results, want %d", len(results), len(msgs))
+	}
+	for i, want := range []string{"ext-1", "", "ext-3"} {
+		r := results[i]
+		if want == "" {
+			if r.Err == nil || r.Result != nil {
+				t.Errorf("result %d = %+v, want an error", i, r)
+			}
+			continue
+		}
+		if r.Err != nil || r.Result == nil || r.Result.MessageID != want {
+			t.Errorf("result %d = %+v, want message ID %q", i, r, want)
+		}
+	}
+}
+
+// tagging is a SenderMiddleware appending its tag to the provider message IDs of the sender it wraps.
+type tagging string
+
+func (t tagging) wrap(s message.Sender) message.Sender {
+	return message.SendOneByOne(taggedSender{Sender: s, tag: string(t)})
+}
+
+// taggedSender is a SingleSender appending tag to the results of Sender.
+type taggedSender struct {
+	message.Sender
+	tag string
+}
+
+func (s taggedSender) Send(ctx context.Context, msg *message.Message) (*message.SendResult, error) {
+	res, err := s.Sender.Send(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	res.MessageID += "+" + s.tag
+	return res, nil
+}
+
+func TestSenderWithMiddleware_FirstMiddlewareIsOutermost(t *testing.T) {
+	sender := message.SenderWithMiddleware(message.SendOneByOne(failingFor("bad")),
+		tagging("outer").wrap,
+		tagging("inner").wrap,
+	)
+
+	res, err := sender.Send(context.Background(), &message.Message{ID: "1", Content: "good"})
+
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if want := "ext-1+inner+outer"; res.MessageID != want {
+		t.Errorf("MessageID = %q, want %q", res.MessageID, want)
+	}
+}
+
+func TestSenderWithMiddleware_NoMiddleware(t *testing.T) {
+	base := message.SendOneByOne(failingFor("bad"))
+
+	if got := message.SenderWithMiddleware(base); got != base {
+		t.Errorf("SenderWithMiddleware() = %v, want the base sender", got)
+	}
+}
@@ -0,0 +1,104 @@
+package message
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNegativeSegmentLimit is returned when truncating content to a negative number of SMS segments.
+var ErrNegativeSegmentLimit = errors.New("negative segment limit")
+
+// Sizes of SMS segments: a single message carries 160 GSM-7 septets or 70 UCS-2 code units, every part of a
+// concatenated message loses 7 septets or 3 code units to the header joining the parts.
+const (
+	gsm7SingleSegment    = 160
+	gsm7MultipartSegment = 153
+	ucs2SingleSegment    = 70
+	ucs2MultipartSegment = 67
+)
+
+const (
+	// gsm7Basic holds the characters of the GSM 03.38 default alphabet, each encoded in a single septet.
+	gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+	// gsm7Extended holds the characters of the GSM 03.38 extension table, each encoded in two septets.
+	gsm7Extended = "\f^{}\\[~]|€"
+)
+
+// isGSM7 reports whether content can be encoded in the GSM-7 alphabet.
+// Content with any other character is sent as UCS-2.
+func isGSM7(content string) bool {
+	for _, r := range content {
+		if !strings.ContainsRune(gsm7Basic, r) && !strings.ContainsRune(gsm7Extended, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// smsUnits returns the septets r takes in GSM-7 if gsm7 is true, or its UCS-2 code units otherwise.
+func smsUnits(r rune, gsm7 bool) int {
+	if gsm7 {
+		if strings.ContainsRune(gsm7Extended, r) {
+			return 2
+		}
+		return 1
+	}
+	if r > 0xFFFF {
+		// encoded as a surrogate pair
+		return 2
+	}
+	return 1
+}
+
+// segmentSizes returns the units of a single segment and of each part of a concatenated message in the encoding of
+// content.
+func segmentSizes(content string) (gsm7 bool, single, multipart int) {
+	if isGSM7(content) {
+		return true, gsm7SingleSegment, gsm7MultipartSegment
+	}
+	return false, ucs2SingleSegment, ucs2MultipartSegment
+}
+
+// SMSSegments returns the number of SMS segments content is sent in: GSM-7 encoded if all its characters are in the
+// GSM 03.38 alphabet, UCS-2 encoded otherwise. Empty content takes no segment.
+func SMSSegments(content string) int {
+	gsm7, single, multipart := segmentSizes(content)
+	units := 0
+	for _, r := range content {
+		units += smsUnits(r, gsm7)
+	}
+	if units <= single {
+		if units == 0 {
+			return 0
+		}
+		return 1
+	}
+	return (units + multipart - 1) / multipart
+}
+
+// TruncatedSegments returns the Content truncated to fit in at most segments SMS segments, as counted by SMSSegments,
+// so a character limit matches what providers bill. Characters are never split.
+// If segments is negative, returns ErrNegativeSegmentLimit.
+func (m *Message) TruncatedSegments(segments int) (string, error) {
+	if segments < 0 {
+		return "", ErrNegativeSegmentLimit
+	}
+	if SMSSegments(m.Content) <= segments {
+		return m.Content, nil
+	}
+	gsm7, single, multipart := segmentSizes(m.Content)
+	capacity := segments * multipart
+	if segments == 1 {
+		capacity = single
+	}
+	units := 0
+	for i, r := range m.Content {
+		units += smsUnits(r, gsm7)
+		if units > capacity {
+			return m.Content[:i], nil
+		}
+	}
+	return m.Content, nil
+}
@@ -0,0 +1,76 @@
+package message_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+func TestSMSSegments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{name: "empty", content: "", want: 0},
+		{name: "gsm-7 single segment", content: strings.Repeat("a", 160), want: 1},
+		{name: "gsm-7 concatenated", content: strings.Repeat("a", 161), want: 2},
+		{name: "gsm-7 three parts", content: strings.Repeat("a", 307), want: 3},
+		{name: "extension characters take two septets", content: strings.Repeat("€", 80), want: 1},
+		{name: "extension characters overflow", content: strings.Repeat("€", 81), want: 2},
+		{name: "ucs-2 single segment", content: strings.Repeat("ş", 70), want: 1},
+		{name: "ucs-2 concatenated", content: strings.Repeat("ş", 71), want: 2},
+		{name: "one non-gsm character switches to ucs-2", content: strings.Repeat("a", 70) + "ğ", want: 2},
+		{name: "surrogate pairs take two code units", content: strings.Repeat("😀", 35), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := message.SMSSegments(tt.content); got != tt.want {
+				t.Errorf("SMSSegments() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessage_TruncatedSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		segments int
+		want     string
+	}{
+		{name: "fits", content: "Hello", segments: 1, want: "Hello"},
+		{name: "gsm-7 single segment", content: strings.Repeat("a", 200), segments: 1, want: strings.Repeat("a", 160)},
+		{name: "gsm-7 two parts", content: strings.Repeat("a", 400), segments: 2, want: strings.Repeat("a", 306)},
+		{name: "ucs-2 single segment", content: strings.Repeat("ş", 100), segments: 1, want: strings.Repeat("ş", 70)},
+		{name: "extension character is not split", content: strings.Repeat("a", 159) + "€", segments: 1, want: strings.Repeat("a", 159)},
+		{name: "surrogate pair is not split", content: strings.Repeat("ş", 69) + "😀", segments: 1, want: strings.Repeat("ş", 69)},
+		{name: "zero segments", content: "Hello", segments: 0, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &message.Message{Content: tt.content}
+
+			got, err := msg.TruncatedSegments(tt.segments)
+
+			if err != nil {
+				t.Fatalf("TruncatedSegments() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TruncatedSegments() = %q, want %q", got, tt.want)
+			}
+			if n := message.SMSSegments(got); n > tt.segments {
+				t.Errorf("truncated content takes %d segments, want at most %d", n, tt.segments)
+			}
+		})
+	}
+}
+
+func TestMessage_TruncatedSegments_NegativeLimit(t *testing.T) {
+	msg := &message.Message{Content: "Hello"}
+
+	if _, err := msg.TruncatedSegments(-1); err != message.ErrNegativeSegmentLimit {
+		t.Errorf("TruncatedSegments() error = %v, want %v", err, message.ErrNegativeSegmentLimit)
+	}
+}
@@ -0,0 +1,133 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Event types delivered to subscriptions.
+const (
+	// EventMessageSent is emitted after a message was delivered and its sent state stored.
+	EventMessageSent = "message.sent"
+	// EventMessageFailed is emitted when delivery of a message is given up after its last attempt failed.
+	EventMessageFailed = "message.failed"
+)
+
+// EventTypes lists every event type a Subscription can ask for.
+var EventTypes = []string{EventMessageSent, EventMessageFailed}
+
+var (
+	// ErrInvalidCallbackURL is returned when a subscription URL is not an absolute http or https URL.
+	ErrInvalidCallbackURL = errors.New("callback URL must be an absolute http or https URL")
+
+	// ErrUnknownEventType is returned when a subscription asks for an event type that is never emitted.
+	ErrUnknownEventType = errors.New("unknown event type")
+
+	// ErrNoEventTypes is returned when a subscription does not ask for any event type.
+	ErrNoEventTypes = errors.New("at least one event type is required")
+
+	// ErrSubscriptionNotFound is returned when a subscription with the requested ID does not exist.
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+// Subscription is a consumer callback URL that is notified about message lifecycle events.
+type Subscription struct {
+	ID        string    // internal subscription identifier
+	URL       string    // callback URL events are POSTed to
+	Secret    string    // key signing the delivered payloads
+	Events    []string  // event types the subscriber receives
+	Tenant    string    // customer the subscription belongs to, only notified about its own messages
+	CreatedAt time.Time // timestamp when the subscription was registered
+}
+
+// NewSubscription constructs a Subscription to the given events at callbackURL.
+// Returns ErrInvalidCallbackURL, ErrNoEventTypes or ErrUnknownEventType if the arguments are invalid.
+func NewSubscription(callbackURL, secret string, events []string) (*Subscription, error) {
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidCallbackURL
+	}
+	if len(events) == 0 {
+		return nil, ErrNoEventTypes
+	}
+	for _, e := range events {
+		if !slices.Contains(EventTypes, e) {
+			return nil, ErrUnknownEventType
+		}
+	}
+	return &Subscription{
+		URL:    callbackURL,
+		Secret: secret,
+		Events: slices.Compact(slices.Sorted(slices.Values(events))),
+	}, nil
+}
+
+// Wants reports whether the subscription receives events of the given type.
+func (s *Subscription) Wants(eventType string) bool {
+	return slices.Contains(s.Events, eventType)
+}
+
+// Event describes something that happened to a message, as delivered to subscriptions.
+type Event struct {
+	Type       string       `json:"type"`            // one of the Event* constants
+	OccurredAt time.Time    `json:"occurred_at"`     // timestamp of the event
+	Message    EventMessage `json:"message"`         // message the event is about
+	Error      string       `json:"error,omitempty"` // failure reason, for EventMessageFailed
+}
+
+// EventMessage is the message snapshot carried by an Event.
+type EventMessage struct {
+	ID        string     `json:"id"`                   // internal message identifier
+	To        string     `json:"to"`                   // recipient phone number
+	MessageID string     `json:"message_id,omitempty"` // provider message ID, once sent
+	SentAt    *time.Time `json:"sent_at,omitempty"`    // delivery timestamp, once sent
+	Tenant    string     `json:"tenant"`               // tenant owning the message
+	Attempts  int        `json:"attempts,omitempty"`   // failed delivery attempts, for EventMessageFailed
+}
+
+// NewEvent returns an event of the given type about msg, occurring now.
+// A non-nil cause is recorded as the failure reason.
+func NewEvent(eventType string, msg *Message, cause error) *Event {
+	e := &Event{
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		Message: EventMessage{
+			ID:       msg.ID,
+			To:       msg.To,
+			Tenant:   msg.Tenant,
+			Attempts: msg.Attempts,
+		},
+	}
+	if msg.IsSent() {
+		e.Message.MessageID = msg.MessageID
+		e.Message.SentAt = &msg.SentAt
+	}
+	if cause != nil {
+		e.Error = cause.Error()
+	}
+	return e
+}
+
+// SubscriptionRepository stores the subscriptions of each tenant.
+// Like Repository, it only reads and writes subscriptions of the tenant a context is scoped to, see WithTenant.
+type SubscriptionRepository interface {
+	// CreateSubscription stores a new Subscription and returns it with its assigned ID and creation time.
+	CreateSubscription(ctx context.Context, sub *Subscription) (*Subscription, error)
+
+	// ListSubscriptions returns all stored subscriptions, oldest first.
+	ListSubscriptions(ctx context.Context) ([]*Subscription, error)
+
+	// DeleteSubscription removes the subscription with the given ID.
+	// Returns ErrSubscriptionNotFound if no such subscription exists.
+	DeleteSubscription(ctx context.Context, id string) error
+}
+
+// Notifier delivers events to the subscriptions asking for them.
+type Notifier interface {
+	// Notify hands e over for delivery. Delivery happens in the background,
+	// so a slow or failing subscriber never holds up sending messages.
+	Notify(ctx context.Context, e *Event)
+}
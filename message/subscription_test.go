@@ -0,0 +1,79 @@
+package message_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+func TestNewSubscription(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		events      []string
+		wantEvents  []string
+		expectError error
+	}{
+		{
+			name:       "valid subscription",
+			url:        "https://example.com/hooks",
+			events:     []string{message.EventMessageSent},
+			wantEvents: []string{message.EventMessageSent},
+		},
+		{
+			name:       "duplicate events are collapsed",
+			url:        "http://example.com/hooks",
+			events:     []string{message.EventMessageSent, message.EventMessageFailed, message.EventMessageSent},
+			wantEvents: []string{message.EventMessageFailed, message.EventMessageSent},
+		},
+		{
+			name:        "relative URL",
+			url:         "/hooks",
+			events:      []string{message.EventMessageSent},
+			expectError: message.ErrInvalidCallbackURL,
+		},
+		{
+			name:        "unsupported scheme",
+			url:         "ftp://example.com/hooks",
+			events:      []string{message.EventMessageSent},
+			expectError: message.ErrInvalidCallbackURL,
+		},
+		{
+			name:        "no events",
+			url:         "https://example.com/hooks",
+			expectError: message.ErrNoEventTypes,
+		},
+		{
+			name:        "unknown event",
+			url:         "https://example.com/hooks",
+			events:      []string{"message.read"},
+			expectError: message.ErrUnknownEventType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := message.NewSubscription(tt.url, "s3cret", tt.events)
+
+			if tt.expectError != nil {
+				if !errors.Is(err, tt.expectError) {
+					t.Fatalf("expected error %v, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(sub.Events, tt.wantEvents) {
+				t.Errorf("expected events %v, got %v", tt.wantEvents, sub.Events)
+			}
+			for _, e := range tt.wantEvents {
+				if !sub.Wants(e) {
+					t.Errorf("expected subscription to want %s", e)
+				}
+			}
+		})
+	}
+}
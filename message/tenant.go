@@ -0,0 +1,34 @@
+package message
+
+import "context"
+
+// DefaultTenant is the tenant used for messages created without one.
+const DefaultTenant = "default"
+
+// tenantKey is the context key under which the current tenant is stored.
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx scoped to the given tenant.
+// Repositories only read and write messages of that tenant while handling the returned context.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to.
+// The second result is false for unscoped contexts, which see messages of every tenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// TenantOf returns the tenant a new message should be stored under:
+// the message's own tenant, else the one ctx is scoped to, else DefaultTenant.
+func TenantOf(ctx context.Context, msg *Message) string {
+	if msg.Tenant != "" {
+		return msg.Tenant
+	}
+	if tenant, ok := TenantFromContext(ctx); ok {
+		return tenant
+	}
+	return DefaultTenant
+}
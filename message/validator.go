@@ -0,0 +1,80 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrBlockedRecipient is returned when the recipient of a message starts with a blocklisted prefix.
+	ErrBlockedRecipient = errors.New("recipient is blocklisted")
+
+	// ErrBlockedContent is returned when the content of a message contains a blocked term.
+	ErrBlockedContent = errors.New("content contains a blocked term")
+
+	// ErrContentTooLong is returned when the content of a message exceeds the length allowed on its channel.
+	ErrContentTooLong = errors.New("content is too long")
+)
+
+// Validator checks a Message right before it is sent, e.g. against a blocklist of recipients.
+// Messages failing a Validator are rejected instead of being sent, with the returned error as the reason.
+type Validator interface {
+	// Validate returns why msg must not be sent, or nil if it may be.
+	Validate(ctx context.Context, msg *Message) error
+}
+
+// ValidatorFunc adapts an ordinary function to the Validator interface.
+type ValidatorFunc func(ctx context.Context, msg *Message) error
+
+// Validate calls f(ctx, msg).
+func (f ValidatorFunc) Validate(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// BlockPrefixes returns a Validator rejecting messages whose recipient starts with one of prefixes,
+// e.g. "+1900" for premium rate numbers, with ErrBlockedRecipient.
+func BlockPrefixes(prefixes ...string) Validator {
+	return ValidatorFunc(func(_ context.Context, msg *Message) error {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(msg.To, prefix) {
+				return fmt.Errorf("%w: prefix %q", ErrBlockedRecipient, prefix)
+			}
+		}
+		return nil
+	})
+}
+
+// BlockTerms returns a Validator rejecting messages whose content contains one of terms, regardless of case,
+// with ErrBlockedContent.
+func BlockTerms(terms ...string) Validator {
+	lower := make([]string, len(terms))
+	for i, term := range terms {
+		lower[i] = strings.ToLower(term)
+	}
+	return ValidatorFunc(func(_ context.Context, msg *Message) error {
+		content := strings.ToLower(msg.Content)
+		for i, term := range lower {
+			if strings.Contains(content, term) {
+				return fmt.Errorf("%w: %q", ErrBlockedContent, terms[i])
+			}
+		}
+		return nil
+	})
+}
+
+// MaxContentLength returns a Validator rejecting messages on ch whose content is longer than limit characters
+// with ErrContentTooLong. Messages on other channels pass.
+func MaxContentLength(ch Channel, limit int) Validator {
+	return ValidatorFunc(func(_ context.Context, msg *Message) error {
+		if ChannelOf(msg) != ch {
+			return nil
+		}
+		if n := utf8.RuneCountInString(msg.Content); n > limit {
+			return fmt.Errorf("%w: %d characters, at most %d allowed on %s", ErrContentTooLong, n, limit, ch)
+		}
+		return nil
+	})
+}
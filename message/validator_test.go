@@ -0,0 +1,82 @@
+package message_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator message.Validator
+		msg       *message.Message
+		wantErr   error
+	}{
+		{
+			name:      "blocked prefix",
+			validator: message.BlockPrefixes("+1900", "+90850"),
+			msg:       &message.Message{To: "+908501234567", Content: "hello"},
+			wantErr:   message.ErrBlockedRecipient,
+		},
+		{
+			name:      "allowed prefix",
+			validator: message.BlockPrefixes("+1900", "+90850"),
+			msg:       &message.Message{To: "+905551234567", Content: "hello"},
+		},
+		{
+			name:      "blocked term in any case",
+			validator: message.BlockTerms("casino"),
+			msg:       &message.Message{To: "+905551234567", Content: "Win big at our CASINO"},
+			wantErr:   message.ErrBlockedContent,
+		},
+		{
+			name:      "clean content",
+			validator: message.BlockTerms("casino"),
+			msg:       &message.Message{To: "+905551234567", Content: "Your code is 1234"},
+		},
+		{
+			name:      "content too long for its channel",
+			validator: message.MaxContentLength(message.ChannelSMS, 5),
+			msg:       &message.Message{To: "+905551234567", Content: "hello!"},
+			wantErr:   message.ErrContentTooLong,
+		},
+		{
+			name:      "length counts characters, not bytes",
+			validator: message.MaxContentLength(message.ChannelSMS, 5),
+			msg:       &message.Message{To: "+905551234567", Content: "şğüöç"},
+		},
+		{
+			name:      "length of other channels is not limited",
+			validator: message.MaxContentLength(message.ChannelSMS, 5),
+			msg:       &message.Message{To: "ada@example.com", Content: "hello!", Channel: message.ChannelEmail},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validator.Validate(context.Background(), tt.msg)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMessage_SetRejected(t *testing.T) {
+	msg := &message.Message{To: "+908501234567", Content: "hello"}
+
+	msg.SetRejected(message.ErrBlockedRecipient, time.Now())
+
+	if !msg.IsRejected() {
+		t.Error("expected message to be rejected")
+	}
+	if msg.IsPending() {
+		t.Error("expected rejected message not to be pending")
+	}
+	if msg.LastError != message.ErrBlockedRecipient.Error() {
+		t.Errorf("LastError = %q, want the rejection reason", msg.LastError)
+	}
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/grustamli/insider-msg-sender/daemon"
+)
+
+// InstrumentJob wraps a daemon.ScheduledJobFunc so that each run is counted under jobName
+// with a success or failure outcome.
+func InstrumentJob(jobName string, job daemon.ScheduledJobFunc) daemon.ScheduledJobFunc {
+	return func(ctx context.Context) error {
+		err := job(ctx)
+		daemonRuns.WithLabelValues(jobName, outcome(err)).Inc()
+		return err
+	}
+}
+
+// ObserveRetention counts the n sent messages a run of the archive job moved to the archive, or deleted if deleted is
+// true, including those it handled before failing.
+func ObserveRetention(n int64, deleted bool) {
+	action := "archive"
+	if deleted {
+		action = "delete"
+	}
+	retainedMessages.WithLabelValues(action).Add(float64(n))
+}
@@ -0,0 +1,12 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+)
+
+// ObserveHTTPRequest records the duration of a served HTTP request.
+// route should be the matched route template rather than the raw path to keep label cardinality bounded.
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// ObserveLifecycle subscribes to hooks, counting the messages reaching each stage of their send lifecycle.
+func ObserveLifecycle(hooks *message.Hooks) {
+	hooks.OnQueued(countStage("queued"))
+	hooks.OnSent(countStage("sent"))
+	hooks.OnFailed(countStage("failed"))
+	hooks.OnDeadLettered(countStage("dead_lettered"))
+}
+
+// countStage returns a message.Hook counting the messages reaching stage.
+func countStage(stage string) message.Hook {
+	counter := lifecycleEvents.WithLabelValues(stage)
+	return func(context.Context, *message.Message, error) {
+		counter.Inc()
+	}
+}
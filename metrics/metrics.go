@@ -0,0 +1,157 @@
+// Package metrics defines the Prometheus collectors exposed by the service
+// and decorators that record them around senders, scheduled jobs, HTTP requests, repository calls and the message lifecycle.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name exposed by the service.
+const namespace = "insider"
+
+// Registry is the Prometheus registry that all service collectors are registered with.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// messagesSent counts messages successfully delivered by a sender.
+	messagesSent = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "Total number of messages successfully sent.",
+	})
+
+	// sendFailures counts failed delivery attempts.
+	sendFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "message_send_failures_total",
+		Help:      "Total number of failed message send attempts.",
+	})
+
+	// sendDuration observes how long each delivery attempt takes, regardless of outcome.
+	sendDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "message_send_duration_seconds",
+		Help:      "Duration of message send attempts in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// sendRate reports the rate messages are currently sent at, as adjusted by the adaptive throttle.
+	sendRate = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "send_rate_per_second",
+		Help:      "Messages per second the sender currently allows, as adjusted by adaptive throttling.",
+	})
+
+	// endpointSends counts send attempts at each webhook endpoint of a failover sender by outcome.
+	endpointSends = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_endpoint_sends_total",
+		Help:      "Total number of send attempts at each webhook endpoint.",
+	}, []string{"endpoint", "outcome"})
+
+	// webhookRequestDuration observes the latency of each webhook request until its response headers arrived.
+	webhookRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_request_duration_seconds",
+		Help:      "Duration of webhook requests in seconds, retries included.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	// webhookResponses counts webhook requests by endpoint and response status code, "none" if there was no response.
+	webhookResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_responses_total",
+		Help:      "Total number of webhook requests by response status code.",
+	}, []string{"endpoint", "status"})
+
+	// webhookPayloadSize observes the size of each webhook request body.
+	webhookPayloadSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_request_payload_bytes",
+		Help:      "Size of webhook request bodies in bytes.",
+		Buckets:   prometheus.ExponentialBuckets(64, 2, 10),
+	}, []string{"endpoint"})
+
+	// daemonRuns counts scheduled job executions by job name and outcome.
+	daemonRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "daemon_runs_total",
+		Help:      "Total number of scheduled job runs.",
+	}, []string{"job", "outcome"})
+
+	// httpRequestDuration observes HTTP request latency by method, route and status code.
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// lifecycleEvents counts messages reaching a stage of their send lifecycle, by stage.
+	lifecycleEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "message_lifecycle_events_total",
+		Help:      "Total number of messages queued, sent, failed and dead-lettered.",
+	}, []string{"stage"})
+
+	// retainedMessages counts sent messages past the retention period by whether they were archived or deleted.
+	retainedMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retention_messages_total",
+		Help:      "Total number of sent messages archived or deleted past the retention period.",
+	}, []string{"action"})
+
+	// repositoryCallDuration observes the latency of message repository calls by method and outcome.
+	repositoryCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_call_duration_seconds",
+		Help:      "Duration of message repository calls in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "outcome"})
+
+	// repositoryRows observes the number of messages read or written by message repository calls, by method.
+	repositoryRows = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_call_rows",
+		Help:      "Number of messages read or written by message repository calls.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+	}, []string{"method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		messagesSent,
+		sendFailures,
+		sendDuration,
+		sendRate,
+		endpointSends,
+		webhookRequestDuration,
+		webhookResponses,
+		webhookPayloadSize,
+		daemonRuns,
+		httpRequestDuration,
+		lifecycleEvents,
+		retainedMessages,
+		repositoryCallDuration,
+		repositoryRows,
+	)
+}
+
+// Handler returns an http.Handler that serves all metrics in Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// outcome returns the label value describing the result of an operation.
+func outcome(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
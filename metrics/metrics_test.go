@@ -0,0 +1,320 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/memory"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/metrics"
+)
+
+// sample is the current state of a single metric series.
+type sample struct {
+	value float64 // counter or gauge value
+	count uint64  // histogram sample count
+}
+
+// read gathers the registry and returns the series of the named metric whose labels include the given ones.
+// Collectors are package globals shared by all tests, so callers compare values before and after an action.
+func read(t *testing.T, name string, labels map[string]string) sample {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("gathering metrics: %v", err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+	series:
+		for _, m := range mf.GetMetric() {
+			for k, v := range labels {
+				found := false
+				for _, l := range m.GetLabel() {
+					if l.GetName() == k && l.GetValue() == v {
+						found = true
+					}
+				}
+				if !found {
+					continue series
+				}
+			}
+			return sample{value: m.GetCounter().GetValue() + m.GetGauge().GetValue(), count: m.GetHistogram().GetSampleCount()}
+		}
+	}
+	return sample{}
+}
+
+type stubSender struct {
+	err error
+}
+
+func (s *stubSender) Send(_ context.Context, _ *message.Message) (*message.SendResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &message.SendResult{MessageID: "provider-1", SentAt: time.Now()}, nil
+}
+
+func TestInstrumentSender(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantSent     float64
+		wantFailures float64
+	}{
+		{name: "success", wantSent: 1},
+		{name: "failure", err: errors.New("provider down"), wantFailures: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentBefore := read(t, "insider_messages_sent_total", nil)
+			failuresBefore := read(t, "insider_message_send_failures_total", nil)
+			durationBefore := read(t, "insider_message_send_duration_seconds", nil)
+
+			sender := metrics.InstrumentSender(message.SendOneByOne(&stubSender{err: tt.err}))
+			_, err := sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567"})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Send returned %v, want %v", err, tt.err)
+			}
+
+			if got := read(t, "insider_messages_sent_total", nil).value - sentBefore.value; got != tt.wantSent {
+				t.Errorf("messages sent increased by %v, want %v", got, tt.wantSent)
+			}
+			if got := read(t, "insider_message_send_failures_total", nil).value - failuresBefore.value; got != tt.wantFailures {
+				t.Errorf("send failures increased by %v, want %v", got, tt.wantFailures)
+			}
+			if got := read(t, "insider_message_send_duration_seconds", nil).count - durationBefore.count; got != 1 {
+				t.Errorf("send duration observed %d times, want 1", got)
+			}
+		})
+	}
+}
+
+func TestInstrumentJob(t *testing.T) {
+	success := map[string]string{"job": "test-job", "outcome": "success"}
+	failure := map[string]string{"job": "test-job", "outcome": "failure"}
+	successBefore := read(t, "insider_daemon_runs_total", success)
+	failureBefore := read(t, "insider_daemon_runs_total", failure)
+
+	jobErr := errors.New("job failed")
+	ok := metrics.InstrumentJob("test-job", func(context.Context) error { return nil })
+	failing := metrics.InstrumentJob("test-job", func(context.Context) error { return jobErr })
+	if err := ok(context.Background()); err != nil {
+		t.Fatalf("job returned %v", err)
+	}
+	if err := ok(context.Background()); err != nil {
+		t.Fatalf("job returned %v", err)
+	}
+	if err := failing(context.Background()); !errors.Is(err, jobErr) {
+		t.Fatalf("job returned %v, want %v", err, jobErr)
+	}
+
+	if got := read(t, "insider_daemon_runs_total", success).value - successBefore.value; got != 2 {
+		t.Errorf("successful runs increased by %v, want 2", got)
+	}
+	if got := read(t, "insider_daemon_runs_total", failure).value - failureBefore.value; got != 1 {
+		t.Errorf("failed runs increased by %v, want 1", got)
+	}
+}
+
+func TestObserveRetention(t *testing.T) {
+	archived := map[string]string{"action": "archive"}
+	deleted := map[string]string{"action": "delete"}
+	archivedBefore := read(t, "insider_retention_messages_total", archived)
+	deletedBefore := read(t, "insider_retention_messages_total", deleted)
+
+	metrics.ObserveRetention(1500, false)
+	metrics.ObserveRetention(3, true)
+	metrics.ObserveRetention(0, true)
+
+	if got := read(t, "insider_retention_messages_total", archived).value - archivedBefore.value; got != 1500 {
+		t.Errorf("archived messages increased by %v, want 1500", got)
+	}
+	if got := read(t, "insider_retention_messages_total", deleted).value - deletedBefore.value; got != 3 {
+		t.Errorf("deleted messages increased by %v, want 3", got)
+	}
+}
+
+func TestObserveHTTPRequest(t *testing.T) {
+	labels := map[string]string{"method": "GET", "route": "/messages", "status": "200"}
+	before := read(t, "insider_http_request_duration_seconds", labels)
+
+	metrics.ObserveHTTPRequest(http.MethodGet, "/messages", http.StatusOK, 15*time.Millisecond)
+
+	if got := read(t, "insider_http_request_duration_seconds", labels).count - before.count; got != 1 {
+		t.Errorf("request duration observed %d times, want 1", got)
+	}
+}
+
+func TestObserveSendRate(t *testing.T) {
+	metrics.ObserveSendRate(12.5)
+
+	if got := read(t, "insider_send_rate_per_second", nil).value; got != 12.5 {
+		t.Errorf("send rate = %v, want 12.5", got)
+	}
+}
+
+func TestObserveEndpointSend(t *testing.T) {
+	success := map[string]string{"endpoint": "https://backup.example.com/send", "outcome": "success"}
+	failure := map[string]string{"endpoint": "https://backup.example.com/send", "outcome": "failure"}
+	successBefore := read(t, "insider_webhook_endpoint_sends_total", success)
+	failureBefore := read(t, "insider_webhook_endpoint_sends_total", failure)
+
+	metrics.ObserveEndpointSend("https://backup.example.com/send", nil)
+	metrics.ObserveEndpointSend("https://backup.example.com/send", errors.New("provider down"))
+
+	if got := read(t, "insider_webhook_endpoint_sends_total", success).value - successBefore.value; got != 1 {
+		t.Errorf("successful endpoint sends increased by %v, want 1", got)
+	}
+	if got := read(t, "insider_webhook_endpoint_sends_total", failure).value - failureBefore.value; got != 1 {
+		t.Errorf("failed endpoint sends increased by %v, want 1", got)
+	}
+}
+
+func TestObserveWebhookRequest(t *testing.T) {
+	const endpoint = "https://observed.example.com/send"
+	accepted := map[string]string{"endpoint": endpoint, "status": "202"}
+	unanswered := map[string]string{"endpoint": endpoint, "status": "none"}
+	acceptedBefore := read(t, "insider_webhook_responses_total", accepted)
+	unansweredBefore := read(t, "insider_webhook_responses_total", unanswered)
+	durationBefore := read(t, "insider_webhook_request_duration_seconds", map[string]string{"endpoint": endpoint})
+	sizeBefore := read(t, "insider_webhook_request_payload_bytes", map[string]string{"endpoint": endpoint})
+
+	metrics.ObserveWebhookRequest(endpoint, http.StatusAccepted, 20*time.Millisecond, 128)
+	metrics.ObserveWebhookRequest(endpoint, 0, time.Second, 128)
+
+	if got := read(t, "insider_webhook_responses_total", accepted).value - acceptedBefore.value; got != 1 {
+		t.Errorf("202 responses increased by %v, want 1", got)
+	}
+	if got := read(t, "insider_webhook_responses_total", unanswered).value - unansweredBefore.value; got != 1 {
+		t.Errorf("unanswered requests increased by %v, want 1", got)
+	}
+	if got := read(t, "insider_webhook_request_duration_seconds", map[string]string{"endpoint": endpoint}).count - durationBefore.count; got != 2 {
+		t.Errorf("observed request durations increased by %d, want 2", got)
+	}
+	if got := read(t, "insider_webhook_request_payload_bytes", map[string]string{"endpoint": endpoint}).count - sizeBefore.count; got != 2 {
+		t.Errorf("observed payload sizes increased by %d, want 2", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	metrics.ObserveHTTPRequest(http.MethodPost, "/start", http.StatusOK, time.Millisecond)
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	for _, name := range []string{"insider_http_request_duration_seconds", "go_goroutines"} {
+		if !strings.Contains(w.Body.String(), name) {
+			t.Errorf("exposition does not contain %s", name)
+		}
+	}
+}
+
+func TestInstrumentSender_SendBatch(t *testing.T) {
+	sentBefore := read(t, "insider_messages_sent_total", nil)
+	failuresBefore := read(t, "insider_message_send_failures_total", nil)
+	durationBefore := read(t, "insider_message_send_duration_seconds", nil)
+
+	sender := metrics.InstrumentSender(message.SendOneByOne(&stubSender{}))
+	results := sender.SendBatch(context.Background(), []*message.Message{{ID: "1"}, {ID: "2"}})
+	if len(results) != 2 {
+		t.Fatalf("SendBatch returned %d results, want 2", len(results))
+	}
+
+	if got := read(t, "insider_messages_sent_total", nil).value - sentBefore.value; got != 2 {
+		t.Errorf("messages sent increased by %v, want 2", got)
+	}
+	if got := read(t, "insider_message_send_failures_total", nil).value - failuresBefore.value; got != 0 {
+		t.Errorf("send failures increased by %v, want 0", got)
+	}
+	if got := read(t, "insider_message_send_duration_seconds", nil).count - durationBefore.count; got != 1 {
+		t.Errorf("send duration observed %d times, want once per batch", got)
+	}
+}
+
+func TestObserveLifecycle(t *testing.T) {
+	stages := []string{"queued", "sent", "failed", "dead_lettered"}
+	before := make(map[string]float64, len(stages))
+	for _, stage := range stages {
+		before[stage] = read(t, "insider_message_lifecycle_events_total", map[string]string{"stage": stage}).value
+	}
+
+	hooks := &message.Hooks{}
+	metrics.ObserveLifecycle(hooks)
+	msg := &message.Message{ID: "1"}
+	hooks.Queued(context.Background(), msg)
+	hooks.Queued(context.Background(), msg)
+	hooks.Sent(context.Background(), msg)
+	hooks.Failed(context.Background(), msg, errors.New("timeout"))
+	hooks.DeadLettered(context.Background(), msg, errors.New("rejected"))
+
+	want := map[string]float64{"queued": 2, "sent": 1, "failed": 1, "dead_lettered": 1}
+	for _, stage := range stages {
+		got := read(t, "insider_message_lifecycle_events_total", map[string]string{"stage": stage}).value - before[stage]
+		if got != want[stage] {
+			t.Errorf("%s messages increased by %v, want %v", stage, got, want[stage])
+		}
+	}
+}
+
+// failingRepository fails every GetStats call and panics on the other methods.
+type failingRepository struct {
+	message.Repository
+}
+
+func (failingRepository) GetStats(context.Context) (*message.Stats, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestInstrumentRepository(t *testing.T) {
+	ctx := context.Background()
+	claimed := map[string]string{"method": "ClaimUnsent", "outcome": "success"}
+	notFound := map[string]string{"method": "Delete", "outcome": "success"}
+	failed := map[string]string{"method": "GetStats", "outcome": "failure"}
+	claimedBefore := read(t, "insider_repository_call_duration_seconds", claimed)
+	rowsBefore := read(t, "insider_repository_call_rows", map[string]string{"method": "ClaimUnsent"})
+	notFoundBefore := read(t, "insider_repository_call_duration_seconds", notFound)
+	failedBefore := read(t, "insider_repository_call_duration_seconds", failed)
+
+	repo := metrics.InstrumentRepository(memory.NewMessageRepository())
+	msgs := make([]*message.Message, 2)
+	for i := range msgs {
+		msgs[i], _ = message.NewUnsentMessage("+905551234567", "hello")
+	}
+	if err := repo.InsertMany(ctx, msgs); err != nil {
+		t.Fatalf("inserting messages: %v", err)
+	}
+	if got, err := repo.ClaimUnsent(ctx, 10, "token", time.Now().Add(time.Minute)); err != nil || len(got) != 2 {
+		t.Fatalf("ClaimUnsent() = %d messages, %v; want 2, nil", len(got), err)
+	}
+	// a missing message is an answer of the repository, not a failure
+	if err := repo.Delete(ctx, "42"); !errors.Is(err, message.ErrMessageNotFound) {
+		t.Fatalf("Delete() error = %v, want ErrMessageNotFound", err)
+	}
+	if _, err := metrics.InstrumentRepository(failingRepository{}).GetStats(ctx); err == nil {
+		t.Fatal("GetStats() error = nil, want the error of the underlying repository")
+	}
+
+	if got := read(t, "insider_repository_call_duration_seconds", claimed).count - claimedBefore.count; got != 1 {
+		t.Errorf("successful ClaimUnsent calls increased by %d, want 1", got)
+	}
+	if got := read(t, "insider_repository_call_rows", map[string]string{"method": "ClaimUnsent"}).count - rowsBefore.count; got != 1 {
+		t.Errorf("ClaimUnsent row observations increased by %d, want 1", got)
+	}
+	if got := read(t, "insider_repository_call_duration_seconds", notFound).count - notFoundBefore.count; got != 1 {
+		t.Errorf("successful Delete calls increased by %d, want 1", got)
+	}
+	if got := read(t, "insider_repository_call_duration_seconds", failed).count - failedBefore.count; got != 1 {
+		t.Errorf("failed GetStats calls increased by %d, want 1", got)
+	}
+}
@@ -0,0 +1,291 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records a span per repository call. It is a no-op until a TracerProvider is installed, e.g. by tracing.Setup.
+var tracer = otel.Tracer("github.com/grustamli/insider-msg-sender/metrics")
+
+// Repository wraps a message.Repository and records the latency, outcome and row count of every call,
+// each in a span named after the method, e.g. "Repository.ClaimUnsent", that the spans of its queries are children of.
+type Repository struct {
+	message.Repository // underlying repository performing the calls
+}
+
+var _ message.Repository = (*Repository)(nil) // ensure interface compliance
+
+// InstrumentRepository returns a Repository that records metrics and spans for every call to the given repository.
+func InstrumentRepository(repo message.Repository) *Repository {
+	return &Repository{Repository: repo}
+}
+
+// RepositoryMiddleware is a message.RepositoryMiddleware instrumenting the repository it wraps with InstrumentRepository.
+func RepositoryMiddleware(repo message.Repository) message.Repository {
+	return InstrumentRepository(repo)
+}
+
+// noRows is passed to the function returned by observe for calls whose row count is not known.
+const noRows = -1
+
+// observe starts the span of a call to method and returns its context, along with the function recording the
+// row count and error of the call once it returned. rows is noRows if the call does not tell.
+func observe(ctx context.Context, method string) (context.Context, func(rows int, err error)) {
+	ctx, span := tracer.Start(ctx, "Repository."+method, trace.WithAttributes(attribute.String("repository.method", method)))
+	start := time.Now()
+	return ctx, func(rows int, err error) {
+		repositoryCallDuration.WithLabelValues(method, repositoryOutcome(err)).Observe(time.Since(start).Seconds())
+		if rows != noRows {
+			repositoryRows.WithLabelValues(method).Observe(float64(rows))
+			span.SetAttributes(attribute.Int("repository.rows", rows))
+		}
+		if repositoryOutcome(err) == "failure" {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// repositoryOutcome returns the outcome label of a repository call. Missing or deleted messages and lost claims are
+// answers of the repository rather than failures to reach it, so they count as successes.
+func repositoryOutcome(err error) string {
+	if errors.Is(err, message.ErrMessageNotFound) || errors.Is(err, message.ErrMessageDeleted) ||
+		errors.Is(err, message.ErrClaimLost) {
+		return "success"
+	}
+	return outcome(err)
+}
+
+// one returns 1 if v is not nil and 0 otherwise, the row count of calls returning a single message.
+func one[T any](v *T) int {
+	if v == nil {
+		return 0
+	}
+	return 1
+}
+
+// GetNextUnsent delegates to the underlying repository and records the call.
+func (r *Repository) GetNextUnsent(ctx context.Context) (msg *message.Message, err error) {
+	ctx, done := observe(ctx, "GetNextUnsent")
+	defer func() { done(one(msg), err) }()
+	return r.Repository.GetNextUnsent(ctx)
+}
+
+// GetAllUnsent delegates to the underlying repository and records the call.
+func (r *Repository) GetAllUnsent(ctx context.Context) (msgs []*message.Message, err error) {
+	ctx, done := observe(ctx, "GetAllUnsent")
+	defer func() { done(len(msgs), err) }()
+	return r.Repository.GetAllUnsent(ctx)
+}
+
+// GetUnsent delegates to the underlying repository and records the call.
+func (r *Repository) GetUnsent(ctx context.Context, n int) (msgs []*message.Message, err error) {
+	ctx, done := observe(ctx, "GetUnsent")
+	defer func() { done(len(msgs), err) }()
+	return r.Repository.GetUnsent(ctx, n)
+}
+
+// GetAllSent delegates to the underlying repository and records the call.
+func (r *Repository) GetAllSent(ctx context.Context) (msgs []*message.SentMessage, err error) {
+	ctx, done := observe(ctx, "GetAllSent")
+	defer func() { done(len(msgs), err) }()
+	return r.Repository.GetAllSent(ctx)
+}
+
+// FindSent delegates to the underlying repository and records the call.
+func (r *Repository) FindSent(ctx context.Context, f message.Filter) (msgs []*message.SentMessage, err error) {
+	ctx, done := observe(ctx, "FindSent")
+	defer func() { done(len(msgs), err) }()
+	return r.Repository.FindSent(ctx, f)
+}
+
+// FindUnsent delegates to the underlying repository and records the call.
+func (r *Repository) FindUnsent(ctx context.Context, f message.Filter) (msgs []*message.Message, err error) {
+	ctx, done := observe(ctx, "FindUnsent")
+	defer func() { done(len(msgs), err) }()
+	return r.Repository.FindUnsent(ctx, f)
+}
+
+// FindFailed delegates to the underlying repository and records the call.
+func (r *Repository) FindFailed(ctx context.Context, f message.Filter) (msgs []*message.Message, err error) {
+	ctx, done := observe(ctx, "FindFailed")
+	defer func() { done(len(msgs), err) }()
+	return r.Repository.FindFailed(ctx, f)
+}
+
+// Requeue delegates to the underlying repository and records the call.
+func (r *Repository) Requeue(ctx context.Context, id string) (err error) {
+	ctx, done := observe(ctx, "Requeue")
+	defer func() { done(noRows, err) }()
+	return r.Repository.Requeue(ctx, id)
+}
+
+// WalkSent delegates to the underlying repository and records the call, counting the messages fn was called with.
+func (r *Repository) WalkSent(ctx context.Context, fn func(*message.SentMessage) error) (err error) {
+	ctx, done := observe(ctx, "WalkSent")
+	var rows int
+	defer func() { done(rows, err) }()
+	return r.Repository.WalkSent(ctx, func(msg *message.SentMessage) error {
+		rows++
+		return fn(msg)
+	})
+}
+
+// GetByID delegates to the underlying repository and records the call.
+func (r *Repository) GetByID(ctx context.Context, id string) (msg *message.Message, err error) {
+	ctx, done := observe(ctx, "GetByID")
+	defer func() { done(one(msg), err) }()
+	return r.Repository.GetByID(ctx, id)
+}
+
+// Create delegates to the underlying repository and records the call.
+func (r *Repository) Create(ctx context.Context, msg *message.Message) (stored *message.Message, created bool, err error) {
+	ctx, done := observe(ctx, "Create")
+	defer func() { done(noRows, err) }()
+	return r.Repository.Create(ctx, msg)
+}
+
+// GetStats delegates to the underlying repository and records the call.
+func (r *Repository) GetStats(ctx context.Context) (stats *message.Stats, err error) {
+	ctx, done := observe(ctx, "GetStats")
+	defer func() { done(noRows, err) }()
+	return r.Repository.GetStats(ctx)
+}
+
+// CountUnsent delegates to the underlying repository and records the call.
+func (r *Repository) CountUnsent(ctx context.Context) (n int64, err error) {
+	ctx, done := observe(ctx, "CountUnsent")
+	defer func() { done(noRows, err) }()
+	return r.Repository.CountUnsent(ctx)
+}
+
+// CountSent delegates to the underlying repository and records the call.
+func (r *Repository) CountSent(ctx context.Context) (n int64, err error) {
+	ctx, done := observe(ctx, "CountSent")
+	defer func() { done(noRows, err) }()
+	return r.Repository.CountSent(ctx)
+}
+
+// CountFailed delegates to the underlying repository and records the call.
+func (r *Repository) CountFailed(ctx context.Context) (n int64, err error) {
+	ctx, done := observe(ctx, "CountFailed")
+	defer func() { done(noRows, err) }()
+	return r.Repository.CountFailed(ctx)
+}
+
+// CountSentByHour delegates to the underlying repository and records the call.
+func (r *Repository) CountSentByHour(ctx context.Context, since time.Time) (hours []message.HourCount, err error) {
+	ctx, done := observe(ctx, "CountSentByHour")
+	defer func() { done(len(hours), err) }()
+	return r.Repository.CountSentByHour(ctx, since)
+}
+
+// InsertMany delegates to the underlying repository and records the call.
+func (r *Repository) InsertMany(ctx context.Context, msgs []*message.Message) (err error) {
+	ctx, done := observe(ctx, "InsertMany")
+	defer func() { done(len(msgs), err) }()
+	return r.Repository.InsertMany(ctx, msgs)
+}
+
+// Claim delegates to the underlying repository and records the call.
+func (r *Repository) Claim(ctx context.Context, msg *message.Message, until time.Time) (claimed bool, err error) {
+	ctx, done := observe(ctx, "Claim")
+	defer func() { done(noRows, err) }()
+	return r.Repository.Claim(ctx, msg, until)
+}
+
+// ClaimUnsent delegates to the underlying repository and records the call.
+func (r *Repository) ClaimUnsent(ctx context.Context, n int, token string, until time.Time) (msgs []*message.Message, err error) {
+	ctx, done := observe(ctx, "ClaimUnsent")
+	defer func() { done(len(msgs), err) }()
+	return r.Repository.ClaimUnsent(ctx, n, token, until)
+}
+
+// Save delegates to the underlying repository and records the call.
+func (r *Repository) Save(ctx context.Context, msg *message.Message) (err error) {
+	ctx, done := observe(ctx, "Save")
+	defer func() { done(noRows, err) }()
+	return r.Repository.Save(ctx, msg)
+}
+
+// SaveAll delegates to the underlying repository and records the call, counting the messages it was handed.
+func (r *Repository) SaveAll(ctx context.Context, msgs []*message.Message) (lost []*message.Message, err error) {
+	ctx, done := observe(ctx, "SaveAll")
+	defer func() { done(len(msgs), err) }()
+	return r.Repository.SaveAll(ctx, msgs)
+}
+
+// Expire delegates to the underlying repository and records the call.
+func (r *Repository) Expire(ctx context.Context, msg *message.Message) (err error) {
+	ctx, done := observe(ctx, "Expire")
+	defer func() { done(noRows, err) }()
+	return r.Repository.Expire(ctx, msg)
+}
+
+// Cancel delegates to the underlying repository and records the call.
+func (r *Repository) Cancel(ctx context.Context, msg *message.Message) (canceled bool, err error) {
+	ctx, done := observe(ctx, "Cancel")
+	defer func() { done(noRows, err) }()
+	return r.Repository.Cancel(ctx, msg)
+}
+
+// Delete delegates to the underlying repository and records the call.
+func (r *Repository) Delete(ctx context.Context, id string) (err error) {
+	ctx, done := observe(ctx, "Delete")
+	defer func() { done(noRows, err) }()
+	return r.Repository.Delete(ctx, id)
+}
+
+// Reject delegates to the underlying repository and records the call.
+func (r *Repository) Reject(ctx context.Context, msg *message.Message) (err error) {
+	ctx, done := observe(ctx, "Reject")
+	defer func() { done(noRows, err) }()
+	return r.Repository.Reject(ctx, msg)
+}
+
+// SaveAttempts delegates to the underlying repository and records the call.
+func (r *Repository) SaveAttempts(ctx context.Context, msg *message.Message) (err error) {
+	ctx, done := observe(ctx, "SaveAttempts")
+	defer func() { done(noRows, err) }()
+	return r.Repository.SaveAttempts(ctx, msg)
+}
+
+// ArchiveSent delegates to the underlying repository and records the call, counting the messages archived.
+func (r *Repository) ArchiveSent(ctx context.Context, olderThan time.Duration, n int) (archived int64, err error) {
+	ctx, done := observe(ctx, "ArchiveSent")
+	defer func() { done(int(archived), err) }()
+	return r.Repository.ArchiveSent(ctx, olderThan, n)
+}
+
+// DeleteSent delegates to the underlying repository and records the call, counting the messages deleted.
+func (r *Repository) DeleteSent(ctx context.Context, olderThan time.Duration, n int) (deleted int64, err error) {
+	ctx, done := observe(ctx, "DeleteSent")
+	defer func() { done(int(deleted), err) }()
+	return r.Repository.DeleteSent(ctx, olderThan, n)
+}
+
+// SaveDeliveryReport delegates to the underlying repository and records the call.
+func (r *Repository) SaveDeliveryReport(ctx context.Context, report *message.DeliveryReport) (err error) {
+	ctx, done := observe(ctx, "SaveDeliveryReport")
+	defer func() { done(noRows, err) }()
+	return r.Repository.SaveDeliveryReport(ctx, report)
+}
+
+// WithTx delegates to the underlying repository and records the whole transaction, calling fn with an instrumented
+// Repository so the calls made in the transaction are recorded as well.
+func (r *Repository) WithTx(ctx context.Context, fn func(message.Repository) error) (err error) {
+	ctx, done := observe(ctx, "WithTx")
+	defer func() { done(noRows, err) }()
+	return r.Repository.WithTx(ctx, func(tx message.Repository) error {
+		return fn(InstrumentRepository(tx))
+	})
+}
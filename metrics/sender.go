@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// Sender wraps a message.Sender and records send counts, failures and latency.
+type Sender struct {
+	message.Sender // underlying sender performing delivery
+}
+
+var _ message.Sender = (*Sender)(nil) // ensure interface compliance
+
+// InstrumentSender returns a Sender that records metrics for every call to the given sender.
+func InstrumentSender(sender message.Sender) *Sender {
+	return &Sender{Sender: sender}
+}
+
+// SenderMiddleware is a message.SenderMiddleware instrumenting the sender it wraps with InstrumentSender.
+func SenderMiddleware(sender message.Sender) message.Sender {
+	return InstrumentSender(sender)
+}
+
+// Send delegates to the underlying sender and records the outcome and duration of the attempt.
+func (s *Sender) Send(ctx context.Context, msg *message.Message) (*message.SendResult, error) {
+	start := time.Now()
+	res, err := s.Sender.Send(ctx, msg)
+	sendDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		sendFailures.Inc()
+		return nil, err
+	}
+	messagesSent.Inc()
+	return res, nil
+}
+
+// SendBatch delegates to the underlying sender and records the outcome of every message
+// and the duration of the whole batch.
+func (s *Sender) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	start := time.Now()
+	results := s.Sender.SendBatch(ctx, msgs)
+	sendDuration.Observe(time.Since(start).Seconds())
+	for _, r := range results {
+		if r.Err != nil {
+			sendFailures.Inc()
+		} else {
+			messagesSent.Inc()
+		}
+	}
+	return results
+}
+
+// ObserveEndpointSend records the outcome of a send attempt at a webhook endpoint, counting the sends each endpoint
+// served when failing over between several.
+func ObserveEndpointSend(endpoint string, err error) {
+	endpointSends.WithLabelValues(endpoint, outcome(err)).Inc()
+}
+
+// ObserveWebhookRequest records the latency, response status code and payload size of a single webhook request,
+// status being zero if the request got no response. It matches webhook.RequestObserver.
+func ObserveWebhookRequest(endpoint string, status int, duration time.Duration, payloadBytes int64) {
+	label := "none"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	webhookRequestDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+	webhookResponses.WithLabelValues(endpoint, label).Inc()
+	if payloadBytes >= 0 {
+		webhookPayloadSize.WithLabelValues(endpoint).Observe(float64(payloadBytes))
+	}
+}
+
+// ObserveSendRate records the rate messages are currently sent at, whenever adaptive throttling adjusts it.
+func ObserveSendRate(perSecond float64) {
+	sendRate.Set(perSecond)
+}
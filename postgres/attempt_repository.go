@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/postgres/gen"
+	"github.com/pkg/errors"
+)
+
+// AttemptRepository implements message.AttemptRepository for PostgreSQL storage.
+// Attempts are stored in the message_attempt table and deleted along with their message.
+type AttemptRepository struct {
+	queries *gen.Queries // queries bound to the connection pool
+}
+
+var _ message.AttemptRepository = (*AttemptRepository)(nil)
+
+// NewAttemptRepository constructs a new PostgreSQL implementation of message.AttemptRepository
+func NewAttemptRepository(db *sql.DB) *AttemptRepository {
+	return &AttemptRepository{
+		queries: traced(db),
+	}
+}
+
+// RecordAttempts inserts attempts with a single statement.
+func (r *AttemptRepository) RecordAttempts(ctx context.Context, attempts []*message.Attempt) error {
+	if len(attempts) == 0 {
+		return nil
+	}
+	var params gen.InsertAttemptsParams
+	for _, a := range attempts {
+		id, err := strconv.Atoi(a.MessageID)
+		if err != nil {
+			return errors.Wrap(err, "converting message ID to int")
+		}
+		params.MessageIds = append(params.MessageIds, int32(id))
+		params.StartedAts = append(params.StartedAts, a.StartedAt)
+		params.Durations = append(params.Durations, int32(a.Duration.Milliseconds()))
+		params.StatusCodes = append(params.StatusCodes, int32(a.StatusCode))
+		params.Errors = append(params.Errors, a.Error)
+	}
+	return errors.Wrap(r.queries.InsertAttempts(ctx, params), "recording attempts")
+}
+
+// ListAttempts retrieves the attempts to deliver a message of the tenant ctx is scoped to, oldest first.
+func (r *AttemptRepository) ListAttempts(ctx context.Context, id string) ([]*message.Attempt, error) {
+	intID, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		// non-numeric or out of range IDs can never match a message
+		return nil, nil
+	}
+	res, err := r.queries.ListAttempts(ctx, gen.ListAttemptsParams{
+		MessageID: int32(intID),
+		TenantID:  tenantFilter(ctx),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing attempts")
+	}
+	ret := make([]*message.Attempt, len(res))
+	for i, a := range res {
+		ret[i] = &message.Attempt{
+			MessageID:  id,
+			StartedAt:  a.StartedAt,
+			Duration:   time.Duration(a.DurationMs) * time.Millisecond,
+			StatusCode: int(a.StatusCode.Int32),
+			Error:      a.Error.String,
+		}
+	}
+	return ret, nil
+}
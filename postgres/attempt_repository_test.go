@@ -0,0 +1,62 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/postgres"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockAttemptRepository returns an AttemptRepository backed by sqlmock.
+func newMockAttemptRepository(t *testing.T) (*postgres.AttemptRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return postgres.NewAttemptRepository(db), mock
+}
+
+func TestAttemptRepository_RecordAttempts(t *testing.T) {
+	repo, mock := newMockAttemptRepository(t)
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("INSERT INTO message_attempt").
+		WithArgs(pq.Array([]int32{7, 8}), pq.Array([]time.Time{start, start}), pq.Array([]int32{1500, 1500}),
+			pq.Array([]int32{0, 503}), pq.Array([]string{"", "received status 503"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err := repo.RecordAttempts(context.Background(), []*message.Attempt{
+		{MessageID: "7", StartedAt: start, Duration: 1500 * time.Millisecond},
+		{MessageID: "8", StartedAt: start, Duration: 1500 * time.Millisecond, StatusCode: 503, Error: "received status 503"},
+	})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAttemptRepository_ListAttempts(t *testing.T) {
+	repo, mock := newMockAttemptRepository(t)
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("FROM message_attempt").
+		WithArgs(int32(7), sql.NullString{String: "acme", Valid: true}).
+		WillReturnRows(sqlmock.NewRows([]string{"started_at", "duration_ms", "status_code", "error"}).
+			AddRow(start, int32(200), int32(503), "received status 503").
+			AddRow(start.Add(time.Minute), int32(150), nil, nil))
+
+	attempts, err := repo.ListAttempts(message.WithTenant(context.Background(), "acme"), "7")
+
+	require.NoError(t, err)
+	assert.Equal(t, []*message.Attempt{
+		{MessageID: "7", StartedAt: start, Duration: 200 * time.Millisecond, StatusCode: 503, Error: "received status 503"},
+		{MessageID: "7", StartedAt: start.Add(time.Minute), Duration: 150 * time.Millisecond},
+	}, attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/audit"
+	"github.com/grustamli/insider-msg-sender/postgres/gen"
+	"github.com/pkg/errors"
+)
+
+// AuditRepository implements audit.Repository for PostgreSQL storage.
+type AuditRepository struct {
+	queries *gen.Queries // queries bound to the connection pool
+}
+
+var _ audit.Repository = (*AuditRepository)(nil)
+
+// NewAuditRepository constructs a new PostgreSQL implementation of audit.Repository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{
+		queries: traced(db),
+	}
+}
+
+// Record inserts e into the audit_log table and sets its ID.
+func (r *AuditRepository) Record(ctx context.Context, e *audit.Entry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	id, err := r.queries.InsertAuditEntry(ctx, gen.InsertAuditEntryParams{
+		Action:     e.Action,
+		Actor:      e.Actor,
+		ApiKey:     e.APIKey,
+		RequestID:  e.RequestID,
+		RemoteAddr: e.RemoteAddr,
+		Details:    e.Details,
+		CreatedAt:  e.CreatedAt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "recording audit entry")
+	}
+	e.ID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// List retrieves the audit entries matching f, newest first.
+func (r *AuditRepository) List(ctx context.Context, f audit.Filter) ([]*audit.Entry, error) {
+	beforeID := sql.NullInt64{}
+	if f.BeforeID != "" {
+		id, err := strconv.ParseInt(f.BeforeID, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing audit entry ID")
+		}
+		beforeID = sql.NullInt64{Int64: id, Valid: true}
+	}
+	res, err := r.queries.ListAuditEntries(ctx, gen.ListAuditEntriesParams{
+		Action:     sql.NullString{String: f.Action, Valid: f.Action != ""},
+		BeforeID:   beforeID,
+		MaxResults: limitParam(f.Limit),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing audit entries")
+	}
+	ret := make([]*audit.Entry, len(res))
+	for i, e := range res {
+		ret[i] = &audit.Entry{
+			ID:         strconv.FormatInt(e.ID, 10),
+			Action:     e.Action,
+			Actor:      e.Actor,
+			APIKey:     e.ApiKey,
+			RequestID:  e.RequestID,
+			RemoteAddr: e.RemoteAddr,
+			Details:    e.Details,
+			CreatedAt:  e.CreatedAt,
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,62 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grustamli/insider-msg-sender/audit"
+	"github.com/grustamli/insider-msg-sender/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockAuditRepository returns an AuditRepository backed by sqlmock.
+func newMockAuditRepository(t *testing.T) (*postgres.AuditRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return postgres.NewAuditRepository(db), mock
+}
+
+func TestAuditRepository_Record(t *testing.T) {
+	repo, mock := newMockAuditRepository(t)
+	createdAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("INSERT INTO audit_log").
+		WithArgs(audit.ActionStart, "admin", "0123456789abcdef", "req-1", "10.0.0.1", "", createdAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(12)))
+
+	e := &audit.Entry{
+		Action:     audit.ActionStart,
+		Actor:      "admin",
+		APIKey:     "0123456789abcdef",
+		RequestID:  "req-1",
+		RemoteAddr: "10.0.0.1",
+		CreatedAt:  createdAt,
+	}
+	require.NoError(t, repo.Record(context.Background(), e))
+	assert.Equal(t, "12", e.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditRepository_List(t *testing.T) {
+	repo, mock := newMockAuditRepository(t)
+	createdAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	columns := []string{"id", "action", "actor", "api_key", "request_id", "remote_addr", "details", "created_at"}
+
+	mock.ExpectQuery("FROM audit_log").
+		WithArgs(sql.NullString{String: audit.ActionStop, Valid: true}, sql.NullInt64{Int64: 10, Valid: true}, sql.NullInt32{Int32: 50, Valid: true}).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(int64(9), audit.ActionStop, "", "", "req-9", "10.0.0.1", "", createdAt))
+
+	entries, err := repo.List(context.Background(), audit.Filter{Action: audit.ActionStop, BeforeID: "10", Limit: 50})
+
+	require.NoError(t, err)
+	assert.Equal(t, []*audit.Entry{
+		{ID: "9", Action: audit.ActionStop, RequestID: "req-9", RemoteAddr: "10.0.0.1", CreatedAt: createdAt},
+	}, entries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
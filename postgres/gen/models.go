@@ -6,13 +6,89 @@ package gen
 
 import (
 	"database/sql"
+	"encoding/json"
+	"time"
 )
 
+type AuditLog struct {
+	ID         int64
+	Action     string
+	Actor      string
+	ApiKey     string
+	RequestID  string
+	RemoteAddr string
+	Details    string
+	CreatedAt  time.Time
+}
+
 type Message struct {
+	ID                 int32
+	Recipient          string
+	Content            string
+	MessageID          sql.NullString
+	CreatedAt          sql.NullTime
+	SentAt             sql.NullTime
+	IdempotencyKey     sql.NullString
+	TenantID           string
+	Attempts           int32
+	LastError          sql.NullString
+	NextAttemptAt      sql.NullTime
+	FailedAt           sql.NullTime
+	ClaimToken         sql.NullString
+	ClaimedUntil       sql.NullTime
+	Priority           int32
+	SendAt             sql.NullTime
+	ExpiresAt          sql.NullTime
+	ExpiredAt          sql.NullTime
+	CanceledAt         sql.NullTime
+	TemplateName       sql.NullString
+	TemplateVars       json.RawMessage
+	Channel            string
+	DeliveryStatus     sql.NullString
+	DeliveryReportedAt sql.NullTime
+	DeliveryError      sql.NullString
+	RejectedAt         sql.NullTime
+	Status             string
+	DeletedAt          sql.NullTime
+	ProviderStatusCode sql.NullInt32
+	ProviderResponse   sql.NullString
+}
+
+type MessageArchive struct {
+	ID                 int32
+	Recipient          string
+	Content            string
+	MessageID          sql.NullString
+	CreatedAt          sql.NullTime
+	SentAt             time.Time
+	TenantID           string
+	Attempts           int32
+	Priority           int32
+	TemplateName       sql.NullString
+	TemplateVars       json.RawMessage
+	Channel            string
+	DeliveryStatus     sql.NullString
+	DeliveryReportedAt sql.NullTime
+	DeliveryError      sql.NullString
+	ArchivedAt         time.Time
+	ProviderStatusCode sql.NullInt32
+	ProviderResponse   sql.NullString
+}
+
+type MessageAttempt struct {
+	ID         int64
+	MessageID  int32
+	StartedAt  time.Time
+	DurationMs int32
+	StatusCode sql.NullInt32
+	Error      sql.NullString
+}
+
+type Subscription struct {
 	ID        int32
-	Recipient string
-	Content   string
-	MessageID sql.NullString
+	Url       string
+	Secret    string
+	Events    []string
+	TenantID  string
 	CreatedAt sql.NullTime
-	SentAt    sql.NullTime
 }
@@ -8,22 +8,677 @@ package gen
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+const archiveSent = `-- name: ArchiveSent :one
+WITH archived AS (
+    DELETE
+    FROM message
+    WHERE id IN (SELECT id
+                 FROM message
+                 WHERE COALESCE(sent_at, deleted_at) < LOCALTIMESTAMP - make_interval(secs => $1::float8)
+                   AND ($2::varchar IS NULL OR tenant_id = $2)
+                 ORDER BY COALESCE(sent_at, deleted_at)
+                 LIMIT $3 FOR UPDATE SKIP LOCKED)
+    RETURNING id, recipient, content, message_id, created_at, sent_at, tenant_id, attempts, priority, template_name,
+        template_vars, channel, delivery_status, delivery_reported_at, delivery_error, provider_status_code,
+        provider_response, deleted_at),
+     moved AS (
+         INSERT
+         INTO message_archive (id, recipient, content, message_id, created_at, sent_at, tenant_id, attempts,
+                               priority, template_name, template_vars, channel, delivery_status,
+                               delivery_reported_at, delivery_error, provider_status_code, provider_response)
+         SELECT id, recipient, content, message_id, created_at, sent_at, tenant_id, attempts, priority,
+                template_name, template_vars, channel, delivery_status, delivery_reported_at, delivery_error,
+                provider_status_code, provider_response
+         FROM archived
+         WHERE deleted_at IS NULL)
+SELECT COUNT(*)
+FROM archived
+`
+
+type ArchiveSentParams struct {
+	RetentionSeconds float64
+	TenantID         sql.NullString
+	MaxResults       int32
+}
+
+func (q *Queries) ArchiveSent(ctx context.Context, arg ArchiveSentParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, archiveSent, arg.RetentionSeconds, arg.TenantID, arg.MaxResults)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const cancelMessage = `-- name: CancelMessage :execrows
+UPDATE message
+SET canceled_at = $1
+WHERE id = $2
+  AND ($3::varchar IS NULL OR tenant_id = $3)
+  AND status = 'pending'
+  AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+`
+
+type CancelMessageParams struct {
+	CanceledAt sql.NullTime
+	ID         int32
+	TenantID   sql.NullString
+}
+
+func (q *Queries) CancelMessage(ctx context.Context, arg CancelMessageParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelMessage, arg.CanceledAt, arg.ID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const claimMessage = `-- name: ClaimMessage :execrows
+UPDATE message
+SET claim_token   = $2,
+    claimed_until = $3
+WHERE id = $1
+  AND status = 'pending'
+  AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+`
+
+type ClaimMessageParams struct {
+	ID           int32
+	ClaimToken   sql.NullString
+	ClaimedUntil sql.NullTime
+}
+
+func (q *Queries) ClaimMessage(ctx context.Context, arg ClaimMessageParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, claimMessage, arg.ID, arg.ClaimToken, arg.ClaimedUntil)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const claimUnsent = `-- name: ClaimUnsent :many
+WITH claimed AS (
+    UPDATE message
+    SET claim_token   = $1,
+        claimed_until = $2
+    WHERE id IN (SELECT id
+                 FROM message
+                 WHERE status = 'pending'
+                   AND ($3::varchar IS NULL OR tenant_id = $3)
+                   AND (send_at IS NULL OR send_at <= LOCALTIMESTAMP)
+                   AND (next_attempt_at IS NULL OR next_attempt_at <= LOCALTIMESTAMP)
+                   AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+                 ORDER BY priority DESC, created_at
+                 LIMIT $4 FOR UPDATE SKIP LOCKED)
+    RETURNING id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+              template_name, template_vars, channel, created_at)
+SELECT id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+       template_name, template_vars, channel
+FROM claimed
+ORDER BY priority DESC, created_at;
+`
+
+type ClaimUnsentParams struct {
+	ClaimToken   sql.NullString
+	ClaimedUntil sql.NullTime
+	TenantID     sql.NullString
+	MaxResults   int32
+}
+
+type ClaimUnsentRow struct {
+	ID           int32
+	Recipient    string
+	Content      string
+	TenantID     string
+	Attempts     int32
+	LastError    sql.NullString
+	Priority     int32
+	SendAt       sql.NullTime
+	ExpiresAt    sql.NullTime
+	TemplateName sql.NullString
+	TemplateVars json.RawMessage
+	Channel      string
+}
+
+func (q *Queries) ClaimUnsent(ctx context.Context, arg ClaimUnsentParams) ([]ClaimUnsentRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimUnsent,
+		arg.ClaimToken,
+		arg.ClaimedUntil,
+		arg.TenantID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimUnsentRow
+	for rows.Next() {
+		var i ClaimUnsentRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recipient,
+			&i.Content,
+			&i.TenantID,
+			&i.Attempts,
+			&i.LastError,
+			&i.Priority,
+			&i.SendAt,
+			&i.ExpiresAt,
+			&i.TemplateName,
+			&i.TemplateVars,
+			&i.Channel,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countFailed = `-- name: CountFailed :one
+SELECT COUNT(*)
+FROM message
+WHERE status = 'failed'
+  AND deleted_at IS NULL
+  AND ($1::varchar IS NULL OR tenant_id = $1)
+`
+
+func (q *Queries) CountFailed(ctx context.Context, tenantID sql.NullString) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFailed, tenantID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSent = `-- name: CountSent :one
+SELECT COUNT(*)
+FROM message
+WHERE sent_at NOTNULL
+  AND deleted_at IS NULL
+  AND ($1::varchar IS NULL OR tenant_id = $1)
+`
+
+func (q *Queries) CountSent(ctx context.Context, tenantID sql.NullString) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSent, tenantID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSentByHour = `-- name: CountSentByHour :many
+SELECT hour.start::timestamp AS hour, COUNT(message.id) AS sent_count
+FROM generate_series(date_trunc('hour', $1::timestamp), date_trunc('hour', LOCALTIMESTAMP),
+                     INTERVAL '1 hour') AS hour(start)
+         LEFT JOIN message ON message.sent_at >= hour.start
+    AND message.sent_at < hour.start + INTERVAL '1 hour'
+    AND message.deleted_at IS NULL
+    AND ($2::varchar IS NULL OR message.tenant_id = $2)
+GROUP BY hour.start
+ORDER BY hour.start
+`
+
+type CountSentByHourParams struct {
+	Since    time.Time
+	TenantID sql.NullString
+}
+
+type CountSentByHourRow struct {
+	Hour      time.Time
+	SentCount int64
+}
+
+func (q *Queries) CountSentByHour(ctx context.Context, arg CountSentByHourParams) ([]CountSentByHourRow, error) {
+	rows, err := q.db.QueryContext(ctx, countSentByHour, arg.Since, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountSentByHourRow
+	for rows.Next() {
+		var i CountSentByHourRow
+		if err := rows.Scan(&i.Hour, &i.SentCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countUnsent = `-- name: CountUnsent :one
+SELECT COUNT(*)
+FROM message
+WHERE status = 'pending'
+  AND ($1::varchar IS NULL OR tenant_id = $1)
+`
+
+func (q *Queries) CountUnsent(ctx context.Context, tenantID sql.NullString) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUnsent, tenantID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createMessage = `-- name: CreateMessage :one
+INSERT INTO message (recipient, content, idempotency_key, tenant_id, priority, send_at, expires_at, template_name,
+                     template_vars, channel)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (tenant_id, idempotency_key) DO NOTHING
+RETURNING id
+`
+
+type CreateMessageParams struct {
+	Recipient      string
+	Content        string
+	IdempotencyKey sql.NullString
+	TenantID       string
+	Priority       int32
+	SendAt         sql.NullTime
+	ExpiresAt      sql.NullTime
+	TemplateName   sql.NullString
+	TemplateVars   json.RawMessage
+	Channel        string
+}
+
+func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, createMessage,
+		arg.Recipient,
+		arg.Content,
+		arg.IdempotencyKey,
+		arg.TenantID,
+		arg.Priority,
+		arg.SendAt,
+		arg.ExpiresAt,
+		arg.TemplateName,
+		arg.TemplateVars,
+		arg.Channel,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const createSubscription = `-- name: CreateSubscription :one
+INSERT INTO subscription (url, secret, events, tenant_id)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at
+`
+
+type CreateSubscriptionParams struct {
+	Url      string
+	Secret   string
+	Events   []string
+	TenantID string
+}
+
+type CreateSubscriptionRow struct {
+	ID        int32
+	CreatedAt sql.NullTime
+}
+
+func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscriptionParams) (CreateSubscriptionRow, error) {
+	row := q.db.QueryRowContext(ctx, createSubscription,
+		arg.Url,
+		arg.Secret,
+		pq.Array(arg.Events),
+		arg.TenantID,
+	)
+	var i CreateSubscriptionRow
+	err := row.Scan(&i.ID, &i.CreatedAt)
+	return i, err
+}
+
+const deleteMessage = `-- name: DeleteMessage :execrows
+UPDATE message
+SET deleted_at = LOCALTIMESTAMP
+WHERE id = $1
+  AND ($2::varchar IS NULL OR tenant_id = $2)
+  AND status <> 'pending'
+  AND deleted_at IS NULL
+`
+
+type DeleteMessageParams struct {
+	ID       int32
+	TenantID sql.NullString
+}
+
+func (q *Queries) DeleteMessage(ctx context.Context, arg DeleteMessageParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteMessage, arg.ID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteSent = `-- name: DeleteSent :execrows
+DELETE
+FROM message
+WHERE id IN (SELECT id
+             FROM message
+             WHERE COALESCE(sent_at, deleted_at) < LOCALTIMESTAMP - make_interval(secs => $1::float8)
+               AND ($2::varchar IS NULL OR tenant_id = $2)
+             ORDER BY COALESCE(sent_at, deleted_at)
+             LIMIT $3 FOR UPDATE SKIP LOCKED)
+`
+
+type DeleteSentParams struct {
+	RetentionSeconds float64
+	TenantID         sql.NullString
+	MaxResults       int32
+}
+
+func (q *Queries) DeleteSent(ctx context.Context, arg DeleteSentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSent, arg.RetentionSeconds, arg.TenantID, arg.MaxResults)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteSubscription = `-- name: DeleteSubscription :execrows
+DELETE
+FROM subscription
+WHERE id = $1
+  AND ($2::varchar IS NULL OR tenant_id = $2)
+`
+
+type DeleteSubscriptionParams struct {
+	ID       int32
+	TenantID sql.NullString
+}
+
+func (q *Queries) DeleteSubscription(ctx context.Context, arg DeleteSubscriptionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSubscription, arg.ID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const expireMessage = `-- name: ExpireMessage :execrows
+UPDATE message
+SET expired_at    = $2,
+    claim_token   = NULL,
+    claimed_until = NULL
+WHERE id = $1
+  AND status = 'pending'
+  AND expired_at IS NULL
+  AND rejected_at IS NULL
+  AND canceled_at IS NULL
+  AND (claim_token IS NOT DISTINCT FROM $3
+    OR claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+`
+
+type ExpireMessageParams struct {
+	ID         int32
+	ExpiredAt  sql.NullTime
+	ClaimToken sql.NullString
+}
+
+func (q *Queries) ExpireMessage(ctx context.Context, arg ExpireMessageParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, expireMessage, arg.ID, arg.ExpiredAt, arg.ClaimToken)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const findFailed = `-- name: FindFailed :many
+SELECT id, recipient, content, tenant_id, attempts, last_error, failed_at, channel
+FROM message
+WHERE status = 'failed'
+  AND deleted_at IS NULL
+  AND ($1::varchar IS NULL OR tenant_id = $1)
+  AND ($2::varchar IS NULL OR recipient = $2)
+  AND ($3::text IS NULL OR strpos(lower(content), lower($3)) > 0)
+ORDER BY failed_at DESC, id DESC
+LIMIT $4::integer
+`
+
+type FindFailedParams struct {
+	TenantID   sql.NullString
+	Recipient  sql.NullString
+	Contains   sql.NullString
+	MaxResults sql.NullInt32
+}
+
+type FindFailedRow struct {
+	ID        int32
+	Recipient string
+	Content   string
+	TenantID  string
+	Attempts  int32
+	LastError sql.NullString
+	FailedAt  sql.NullTime
+	Channel   string
+}
+
+func (q *Queries) FindFailed(ctx context.Context, arg FindFailedParams) ([]FindFailedRow, error) {
+	rows, err := q.db.QueryContext(ctx, findFailed,
+		arg.TenantID,
+		arg.Recipient,
+		arg.Contains,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindFailedRow
+	for rows.Next() {
+		var i FindFailedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recipient,
+			&i.Content,
+			&i.TenantID,
+			&i.Attempts,
+			&i.LastError,
+			&i.FailedAt,
+			&i.Channel,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findSent = `-- name: FindSent :many
+SELECT id, recipient, content, message_id, sent_at, tenant_id
+FROM message
+WHERE sent_at NOTNULL
+  AND deleted_at IS NULL
+  AND ($1::varchar IS NULL OR tenant_id = $1)
+  AND ($2::varchar IS NULL OR recipient = $2)
+  AND ($3::text IS NULL OR content ILIKE $3)
+  AND ($4::timestamp IS NULL OR sent_at > $4)
+  AND ($5::timestamp IS NULL OR sent_at < $5)
+  AND ($6::timestamp IS NULL
+    OR (sent_at, id) > ($6, $7::integer))
+ORDER BY sent_at, id
+LIMIT $8::integer
+`
+
+type FindSentParams struct {
+	TenantID       sql.NullString
+	Recipient      sql.NullString
+	ContentPattern sql.NullString
+	SentAfter      sql.NullTime
+	SentBefore     sql.NullTime
+	AfterSentAt    sql.NullTime
+	AfterID        sql.NullInt32
+	MaxResults     sql.NullInt32
+}
+
+type FindSentRow struct {
+	ID        int32
+	Recipient string
+	Content   string
+	MessageID sql.NullString
+	SentAt    sql.NullTime
+	TenantID  string
+}
+
+func (q *Queries) FindSent(ctx context.Context, arg FindSentParams) ([]FindSentRow, error) {
+	rows, err := q.db.QueryContext(ctx, findSent,
+		arg.TenantID,
+		arg.Recipient,
+		arg.ContentPattern,
+		arg.SentAfter,
+		arg.SentBefore,
+		arg.AfterSentAt,
+		arg.AfterID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindSentRow
+	for rows.Next() {
+		var i FindSentRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recipient,
+			&i.Content,
+			&i.MessageID,
+			&i.SentAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findUnsent = `-- name: FindUnsent :many
+SELECT id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+       template_name, template_vars, channel
+FROM message
+WHERE status = 'pending'
+  AND ($1::varchar IS NULL OR tenant_id = $1)
+  AND ($2::varchar IS NULL OR recipient = $2)
+  AND ($3::text IS NULL OR strpos(lower(content), lower($3)) > 0)
+  AND ($4::integer IS NULL
+    OR (created_at, id) > (SELECT a.created_at, a.id FROM message a WHERE a.id = $4))
+ORDER BY created_at, id
+LIMIT $5::integer
+`
+
+type FindUnsentParams struct {
+	TenantID   sql.NullString
+	Recipient  sql.NullString
+	Contains   sql.NullString
+	AfterID    sql.NullInt32
+	MaxResults sql.NullInt32
+}
+
+type FindUnsentRow struct {
+	ID           int32
+	Recipient    string
+	Content      string
+	TenantID     string
+	Attempts     int32
+	LastError    sql.NullString
+	Priority     int32
+	SendAt       sql.NullTime
+	ExpiresAt    sql.NullTime
+	TemplateName sql.NullString
+	TemplateVars json.RawMessage
+	Channel      string
+}
+
+func (q *Queries) FindUnsent(ctx context.Context, arg FindUnsentParams) ([]FindUnsentRow, error) {
+	rows, err := q.db.QueryContext(ctx, findUnsent,
+		arg.TenantID,
+		arg.Recipient,
+		arg.Contains,
+		arg.AfterID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUnsentRow
+	for rows.Next() {
+		var i FindUnsentRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recipient,
+			&i.Content,
+			&i.TenantID,
+			&i.Attempts,
+			&i.LastError,
+			&i.Priority,
+			&i.SendAt,
+			&i.ExpiresAt,
+			&i.TemplateName,
+			&i.TemplateVars,
+			&i.Channel,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAllSent = `-- name: GetAllSent :many
-SELECT message_id, sent_at
+SELECT id, recipient, content, message_id, sent_at, tenant_id
 FROM message
 WHERE sent_at NOTNULL
+  AND deleted_at IS NULL
+  AND ($1::varchar IS NULL OR tenant_id = $1)
 ORDER BY created_at
 `
 
 type GetAllSentRow struct {
+	ID        int32
+	Recipient string
+	Content   string
 	MessageID sql.NullString
 	SentAt    sql.NullTime
+	TenantID  string
 }
 
-func (q *Queries) GetAllSent(ctx context.Context) ([]GetAllSentRow, error) {
-	rows, err := q.db.QueryContext(ctx, getAllSent)
+func (q *Queries) GetAllSent(ctx context.Context, tenantID sql.NullString) ([]GetAllSentRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllSent, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -31,7 +686,14 @@ func (q *Queries) GetAllSent(ctx context.Context) ([]GetAllSentRow, error) {
 	var items []GetAllSentRow
 	for rows.Next() {
 		var i GetAllSentRow
-		if err := rows.Scan(&i.MessageID, &i.SentAt); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recipient,
+			&i.Content,
+			&i.MessageID,
+			&i.SentAt,
+			&i.TenantID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -46,20 +708,34 @@ func (q *Queries) GetAllSent(ctx context.Context) ([]GetAllSentRow, error) {
 }
 
 const getAllUnsent = `-- name: GetAllUnsent :many
-SELECT id, recipient, content
+SELECT id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+       template_name, template_vars, channel
 FROM message
-WHERE sent_at IS NULL
-ORDER BY created_at
+WHERE status = 'pending'
+  AND ($1::varchar IS NULL OR tenant_id = $1)
+  AND (send_at IS NULL OR send_at <= LOCALTIMESTAMP)
+  AND (next_attempt_at IS NULL OR next_attempt_at <= LOCALTIMESTAMP)
+  AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+ORDER BY priority DESC, created_at
 `
 
 type GetAllUnsentRow struct {
-	ID        int32
-	Recipient string
-	Content   string
+	ID           int32
+	Recipient    string
+	Content      string
+	TenantID     string
+	Attempts     int32
+	LastError    sql.NullString
+	Priority     int32
+	SendAt       sql.NullTime
+	ExpiresAt    sql.NullTime
+	TemplateName sql.NullString
+	TemplateVars json.RawMessage
+	Channel      string
 }
 
-func (q *Queries) GetAllUnsent(ctx context.Context) ([]GetAllUnsentRow, error) {
-	rows, err := q.db.QueryContext(ctx, getAllUnsent)
+func (q *Queries) GetAllUnsent(ctx context.Context, tenantID sql.NullString) ([]GetAllUnsentRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllUnsent, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +743,20 @@ func (q *Queries) GetAllUnsent(ctx context.Context) ([]GetAllUnsentRow, error) {
 	var items []GetAllUnsentRow
 	for rows.Next() {
 		var i GetAllUnsentRow
-		if err := rows.Scan(&i.ID, &i.Recipient, &i.Content); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recipient,
+			&i.Content,
+			&i.TenantID,
+			&i.Attempts,
+			&i.LastError,
+			&i.Priority,
+			&i.SendAt,
+			&i.ExpiresAt,
+			&i.TemplateName,
+			&i.TemplateVars,
+			&i.Channel,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -81,56 +770,809 @@ func (q *Queries) GetAllUnsent(ctx context.Context) ([]GetAllUnsentRow, error) {
 	return items, nil
 }
 
+const getMessageByID = `-- name: GetMessageByID :one
+SELECT id, recipient, content, message_id, sent_at, tenant_id, attempts, last_error, failed_at, priority, send_at, expires_at,
+       expired_at, canceled_at, template_name, template_vars, channel, delivery_status, delivery_reported_at, delivery_error,
+       rejected_at, created_at, next_attempt_at, idempotency_key, provider_status_code, provider_response
+FROM message
+WHERE id = $1
+  AND ($2::varchar IS NULL OR tenant_id = $2)
+  AND deleted_at IS NULL
+`
+
+type GetMessageByIDParams struct {
+	ID       int32
+	TenantID sql.NullString
+}
+
+type GetMessageByIDRow struct {
+	ID                 int32
+	Recipient          string
+	Content            string
+	MessageID          sql.NullString
+	SentAt             sql.NullTime
+	TenantID           string
+	Attempts           int32
+	LastError          sql.NullString
+	FailedAt           sql.NullTime
+	Priority           int32
+	SendAt             sql.NullTime
+	ExpiresAt          sql.NullTime
+	ExpiredAt          sql.NullTime
+	CanceledAt         sql.NullTime
+	TemplateName       sql.NullString
+	TemplateVars       json.RawMessage
+	Channel            string
+	DeliveryStatus     sql.NullString
+	DeliveryReportedAt sql.NullTime
+	DeliveryError      sql.NullString
+	RejectedAt         sql.NullTime
+	CreatedAt          sql.NullTime
+	NextAttemptAt      sql.NullTime
+	IdempotencyKey     sql.NullString
+	ProviderStatusCode sql.NullInt32
+	ProviderResponse   sql.NullString
+}
+
+func (q *Queries) GetMessageByID(ctx context.Context, arg GetMessageByIDParams) (GetMessageByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getMessageByID, arg.ID, arg.TenantID)
+	var i GetMessageByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.Recipient,
+		&i.Content,
+		&i.MessageID,
+		&i.SentAt,
+		&i.TenantID,
+		&i.Attempts,
+		&i.LastError,
+		&i.FailedAt,
+		&i.Priority,
+		&i.SendAt,
+		&i.ExpiresAt,
+		&i.ExpiredAt,
+		&i.CanceledAt,
+		&i.TemplateName,
+		&i.TemplateVars,
+		&i.Channel,
+		&i.DeliveryStatus,
+		&i.DeliveryReportedAt,
+		&i.DeliveryError,
+		&i.RejectedAt,
+		&i.CreatedAt,
+		&i.NextAttemptAt,
+		&i.IdempotencyKey,
+		&i.ProviderStatusCode,
+		&i.ProviderResponse,
+	)
+	return i, err
+}
+
+const getMessageByIdempotencyKey = `-- name: GetMessageByIdempotencyKey :one
+SELECT id, recipient, content, message_id, sent_at, tenant_id, attempts, last_error, failed_at, priority, send_at, expires_at,
+       expired_at, canceled_at, template_name, template_vars, channel, delivery_status, delivery_reported_at, delivery_error,
+       rejected_at, created_at, next_attempt_at, idempotency_key, provider_status_code, provider_response
+FROM message
+WHERE tenant_id = $1
+  AND idempotency_key = $2
+  AND deleted_at IS NULL
+`
+
+type GetMessageByIdempotencyKeyParams struct {
+	TenantID       string
+	IdempotencyKey sql.NullString
+}
+
+type GetMessageByIdempotencyKeyRow struct {
+	ID                 int32
+	Recipient          string
+	Content            string
+	MessageID          sql.NullString
+	SentAt             sql.NullTime
+	TenantID           string
+	Attempts           int32
+	LastError          sql.NullString
+	FailedAt           sql.NullTime
+	Priority           int32
+	SendAt             sql.NullTime
+	ExpiresAt          sql.NullTime
+	ExpiredAt          sql.NullTime
+	CanceledAt         sql.NullTime
+	TemplateName       sql.NullString
+	TemplateVars       json.RawMessage
+	Channel            string
+	DeliveryStatus     sql.NullString
+	DeliveryReportedAt sql.NullTime
+	DeliveryError      sql.NullString
+	RejectedAt         sql.NullTime
+	CreatedAt          sql.NullTime
+	NextAttemptAt      sql.NullTime
+	IdempotencyKey     sql.NullString
+	ProviderStatusCode sql.NullInt32
+	ProviderResponse   sql.NullString
+}
+
+func (q *Queries) GetMessageByIdempotencyKey(ctx context.Context, arg GetMessageByIdempotencyKeyParams) (GetMessageByIdempotencyKeyRow, error) {
+	row := q.db.QueryRowContext(ctx, getMessageByIdempotencyKey, arg.TenantID, arg.IdempotencyKey)
+	var i GetMessageByIdempotencyKeyRow
+	err := row.Scan(
+		&i.ID,
+		&i.Recipient,
+		&i.Content,
+		&i.MessageID,
+		&i.SentAt,
+		&i.TenantID,
+		&i.Attempts,
+		&i.LastError,
+		&i.FailedAt,
+		&i.Priority,
+		&i.SendAt,
+		&i.ExpiresAt,
+		&i.ExpiredAt,
+		&i.CanceledAt,
+		&i.TemplateName,
+		&i.TemplateVars,
+		&i.Channel,
+		&i.DeliveryStatus,
+		&i.DeliveryReportedAt,
+		&i.DeliveryError,
+		&i.RejectedAt,
+		&i.CreatedAt,
+		&i.NextAttemptAt,
+		&i.IdempotencyKey,
+		&i.ProviderStatusCode,
+		&i.ProviderResponse,
+	)
+	return i, err
+}
+
 const getNextUnsent = `-- name: GetNextUnsent :one
-SELECT id, recipient, content
+SELECT id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+       template_name, template_vars, channel
 FROM message
-WHERE sent_at IS NULL
-ORDER BY created_at
+WHERE status = 'pending'
+  AND ($1::varchar IS NULL OR tenant_id = $1)
+  AND (send_at IS NULL OR send_at <= LOCALTIMESTAMP)
+  AND (next_attempt_at IS NULL OR next_attempt_at <= LOCALTIMESTAMP)
+  AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+ORDER BY priority DESC, created_at
 LIMIT 1
 `
 
 type GetNextUnsentRow struct {
+	ID           int32
+	Recipient    string
+	Content      string
+	TenantID     string
+	Attempts     int32
+	LastError    sql.NullString
+	Priority     int32
+	SendAt       sql.NullTime
+	ExpiresAt    sql.NullTime
+	TemplateName sql.NullString
+	TemplateVars json.RawMessage
+	Channel      string
+}
+
+func (q *Queries) GetNextUnsent(ctx context.Context, tenantID sql.NullString) (GetNextUnsentRow, error) {
+	row := q.db.QueryRowContext(ctx, getNextUnsent, tenantID)
+	var i GetNextUnsentRow
+	err := row.Scan(
+		&i.ID,
+		&i.Recipient,
+		&i.Content,
+		&i.TenantID,
+		&i.Attempts,
+		&i.LastError,
+		&i.Priority,
+		&i.SendAt,
+		&i.ExpiresAt,
+		&i.TemplateName,
+		&i.TemplateVars,
+		&i.Channel,
+	)
+	return i, err
+}
+
+const getSentPage = `-- name: GetSentPage :many
+SELECT id, recipient, content, message_id, sent_at, tenant_id
+FROM message
+WHERE sent_at NOTNULL
+  AND deleted_at IS NULL
+  AND ($1::varchar IS NULL OR tenant_id = $1)
+  AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type GetSentPageParams struct {
+	TenantID sql.NullString
+	AfterID  int32
+	PageSize int32
+}
+
+type GetSentPageRow struct {
 	ID        int32
 	Recipient string
 	Content   string
+	MessageID sql.NullString
+	SentAt    sql.NullTime
+	TenantID  string
 }
 
-func (q *Queries) GetNextUnsent(ctx context.Context) (GetNextUnsentRow, error) {
-	row := q.db.QueryRowContext(ctx, getNextUnsent)
-	var i GetNextUnsentRow
-	err := row.Scan(&i.ID, &i.Recipient, &i.Content)
+func (q *Queries) GetSentPage(ctx context.Context, arg GetSentPageParams) ([]GetSentPageRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSentPage, arg.TenantID, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSentPageRow
+	for rows.Next() {
+		var i GetSentPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recipient,
+			&i.Content,
+			&i.MessageID,
+			&i.SentAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStats = `-- name: GetStats :one
+SELECT COUNT(*) FILTER (WHERE sent_at NOTNULL)                                 AS sent_count,
+       COUNT(*) FILTER (WHERE status = 'pending')                              AS unsent_count,
+       COUNT(*) FILTER (WHERE failed_at NOTNULL)                               AS failed_count,
+       COUNT(*) FILTER (WHERE expired_at NOTNULL)                              AS expired_count,
+       COUNT(*) FILTER (WHERE canceled_at NOTNULL)                             AS canceled_count,
+       COUNT(*) FILTER (WHERE rejected_at NOTNULL)                             AS rejected_count,
+       COUNT(*) FILTER (WHERE sent_at >= LOCALTIMESTAMP - INTERVAL '1 hour')   AS sent_last_hour,
+       COUNT(*) FILTER (WHERE sent_at >= LOCALTIMESTAMP - INTERVAL '1 day')    AS sent_last_day,
+       COALESCE(AVG(EXTRACT(EPOCH FROM sent_at - created_at)), 0)::float8 AS avg_latency_seconds
+FROM message
+WHERE ($1::varchar IS NULL OR tenant_id = $1)
+  AND deleted_at IS NULL
+`
+
+type GetStatsRow struct {
+	SentCount         int64
+	UnsentCount       int64
+	FailedCount       int64
+	ExpiredCount      int64
+	CanceledCount     int64
+	RejectedCount     int64
+	SentLastHour      int64
+	SentLastDay       int64
+	AvgLatencySeconds float64
+}
+
+func (q *Queries) GetStats(ctx context.Context, tenantID sql.NullString) (GetStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getStats, tenantID)
+	var i GetStatsRow
+	err := row.Scan(
+		&i.SentCount,
+		&i.UnsentCount,
+		&i.FailedCount,
+		&i.ExpiredCount,
+		&i.CanceledCount,
+		&i.RejectedCount,
+		&i.SentLastHour,
+		&i.SentLastDay,
+		&i.AvgLatencySeconds,
+	)
 	return i, err
 }
 
+const getUnsent = `-- name: GetUnsent :many
+SELECT id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+       template_name, template_vars, channel
+FROM message
+WHERE sent_at IS NULL
+  AND ($1::varchar IS NULL OR tenant_id = $1)
+  AND (send_at IS NULL OR send_at <= LOCALTIMESTAMP)
+  AND (next_attempt_at IS NULL OR next_attempt_at <= LOCALTIMESTAMP)
+  AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+ORDER BY priority DESC, created_at
+LIMIT $2
+`
+
+type GetUnsentParams struct {
+	TenantID   sql.NullString
+	MaxResults int32
+}
+
+type GetUnsentRow struct {
+	ID           int32
+	Recipient    string
+	Content      string
+	TenantID     string
+	Attempts     int32
+	LastError    sql.NullString
+	Priority     int32
+	SendAt       sql.NullTime
+	ExpiresAt    sql.NullTime
+	TemplateName sql.NullString
+	TemplateVars json.RawMessage
+	Channel      string
+}
+
+func (q *Queries) GetUnsent(ctx context.Context, arg GetUnsentParams) ([]GetUnsentRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnsent, arg.TenantID, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnsentRow
+	for rows.Next() {
+		var i GetUnsentRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recipient,
+			&i.Content,
+			&i.TenantID,
+			&i.Attempts,
+			&i.LastError,
+			&i.Priority,
+			&i.SendAt,
+			&i.ExpiresAt,
+			&i.TemplateName,
+			&i.TemplateVars,
+			&i.Channel,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertAttempts = `-- name: InsertAttempts :exec
+INSERT INTO message_attempt (message_id, started_at, duration_ms, status_code, error)
+SELECT message_id, started_at, duration_ms, NULLIF(status_code, 0), NULLIF(error, '')
+FROM unnest($1::integer[], $2::timestamp[], $3::integer[], $4::integer[],
+            $5::text[]) AS attempt(message_id, started_at, duration_ms, status_code, error)
+`
+
+type InsertAttemptsParams struct {
+	MessageIds  []int32
+	StartedAts  []time.Time
+	Durations   []int32
+	StatusCodes []int32
+	Errors      []string
+}
+
+func (q *Queries) InsertAttempts(ctx context.Context, arg InsertAttemptsParams) error {
+	_, err := q.db.ExecContext(ctx, insertAttempts,
+		pq.Array(arg.MessageIds),
+		pq.Array(arg.StartedAts),
+		pq.Array(arg.Durations),
+		pq.Array(arg.StatusCodes),
+		pq.Array(arg.Errors),
+	)
+	return err
+}
+
+const insertAuditEntry = `-- name: InsertAuditEntry :one
+INSERT INTO audit_log (action, actor, api_key, request_id, remote_addr, details, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id
+`
+
+type InsertAuditEntryParams struct {
+	Action     string
+	Actor      string
+	ApiKey     string
+	RequestID  string
+	RemoteAddr string
+	Details    string
+	CreatedAt  time.Time
+}
+
+func (q *Queries) InsertAuditEntry(ctx context.Context, arg InsertAuditEntryParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertAuditEntry,
+		arg.Action,
+		arg.Actor,
+		arg.ApiKey,
+		arg.RequestID,
+		arg.RemoteAddr,
+		arg.Details,
+		arg.CreatedAt,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
 const insertMessage = `-- name: InsertMessage :exec
-INSERT INTO message (recipient, content)
-VALUES ($1, $2)
+INSERT INTO message (recipient, content, tenant_id, priority, send_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
 `
 
 type InsertMessageParams struct {
 	Recipient string
 	Content   string
+	TenantID  string
+	Priority  int32
+	SendAt    sql.NullTime
+	ExpiresAt sql.NullTime
 }
 
 func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) error {
-	_, err := q.db.ExecContext(ctx, insertMessage, arg.Recipient, arg.Content)
+	_, err := q.db.ExecContext(ctx, insertMessage,
+		arg.Recipient,
+		arg.Content,
+		arg.TenantID,
+		arg.Priority,
+		arg.SendAt,
+		arg.ExpiresAt,
+	)
 	return err
 }
 
-const setMessageSent = `-- name: SetMessageSent :exec
+const listAttempts = `-- name: ListAttempts :many
+SELECT a.started_at, a.duration_ms, a.status_code, a.error
+FROM message_attempt a
+         JOIN message m ON m.id = a.message_id
+WHERE a.message_id = $1
+  AND ($2::varchar IS NULL OR m.tenant_id = $2)
+  AND m.deleted_at IS NULL
+ORDER BY a.started_at, a.id
+`
+
+type ListAttemptsParams struct {
+	MessageID int32
+	TenantID  sql.NullString
+}
+
+type ListAttemptsRow struct {
+	StartedAt  time.Time
+	DurationMs int32
+	StatusCode sql.NullInt32
+	Error      sql.NullString
+}
+
+func (q *Queries) ListAttempts(ctx context.Context, arg ListAttemptsParams) ([]ListAttemptsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAttempts, arg.MessageID, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAttemptsRow
+	for rows.Next() {
+		var i ListAttemptsRow
+		if err := rows.Scan(
+			&i.StartedAt,
+			&i.DurationMs,
+			&i.StatusCode,
+			&i.Error,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAuditEntries = `-- name: ListAuditEntries :many
+SELECT id, action, actor, api_key, request_id, remote_addr, details, created_at
+FROM audit_log
+WHERE ($1::varchar IS NULL OR action = $1)
+  AND ($2::bigint IS NULL OR id < $2)
+ORDER BY id DESC
+LIMIT $3::integer
+`
+
+type ListAuditEntriesParams struct {
+	Action     sql.NullString
+	BeforeID   sql.NullInt64
+	MaxResults sql.NullInt32
+}
+
+func (q *Queries) ListAuditEntries(ctx context.Context, arg ListAuditEntriesParams) ([]AuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, listAuditEntries, arg.Action, arg.BeforeID, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Action,
+			&i.Actor,
+			&i.ApiKey,
+			&i.RequestID,
+			&i.RemoteAddr,
+			&i.Details,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptions = `-- name: ListSubscriptions :many
+SELECT id, url, secret, events, tenant_id, created_at
+FROM subscription
+WHERE ($1::varchar IS NULL OR tenant_id = $1)
+ORDER BY id
+`
+
+func (q *Queries) ListSubscriptions(ctx context.Context, tenantID sql.NullString) ([]Subscription, error) {
+	rows, err := q.db.QueryContext(ctx, listSubscriptions, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscription
+	for rows.Next() {
+		var i Subscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Secret,
+			pq.Array(&i.Events),
+			&i.TenantID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rejectMessage = `-- name: RejectMessage :execrows
 UPDATE message
-SET message_id = $2,
-    sent_at    = $3
+SET rejected_at   = $2,
+    last_error    = $3,
+    claim_token   = NULL,
+    claimed_until = NULL
 WHERE id = $1
+  AND claim_token IS NOT DISTINCT FROM $4
+`
+
+type RejectMessageParams struct {
+	ID         int32
+	RejectedAt sql.NullTime
+	LastError  sql.NullString
+	ClaimToken sql.NullString
+}
+
+func (q *Queries) RejectMessage(ctx context.Context, arg RejectMessageParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rejectMessage,
+		arg.ID,
+		arg.RejectedAt,
+		arg.LastError,
+		arg.ClaimToken,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const requeueMessage = `-- name: RequeueMessage :execrows
+UPDATE message
+SET attempts        = 0,
+    last_error      = NULL,
+    next_attempt_at = NULL,
+    failed_at       = NULL
+WHERE id = $1
+  AND status = 'failed'
+  AND deleted_at IS NULL
+  AND ($2::varchar IS NULL OR tenant_id = $2)
+`
+
+type RequeueMessageParams struct {
+	ID       int32
+	TenantID sql.NullString
+}
+
+func (q *Queries) RequeueMessage(ctx context.Context, arg RequeueMessageParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requeueMessage, arg.ID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const saveAttempts = `-- name: SaveAttempts :execrows
+UPDATE message
+SET attempts        = $2,
+    last_error      = $3,
+    next_attempt_at = $4,
+    failed_at       = $5,
+    claim_token     = NULL,
+    claimed_until   = NULL
+WHERE id = $1
+  AND claim_token IS NOT DISTINCT FROM $6
+`
+
+type SaveAttemptsParams struct {
+	ID            int32
+	Attempts      int32
+	LastError     sql.NullString
+	NextAttemptAt sql.NullTime
+	FailedAt      sql.NullTime
+	ClaimToken    sql.NullString
+}
+
+func (q *Queries) SaveAttempts(ctx context.Context, arg SaveAttemptsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, saveAttempts,
+		arg.ID,
+		arg.Attempts,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.FailedAt,
+		arg.ClaimToken,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const saveDeliveryReport = `-- name: SaveDeliveryReport :execrows
+UPDATE message
+SET delivery_status      = $1,
+    delivery_reported_at = $2,
+    delivery_error       = $3
+WHERE message_id = $4
+  AND sent_at NOTNULL
+`
+
+type SaveDeliveryReportParams struct {
+	DeliveryStatus     sql.NullString
+	DeliveryReportedAt sql.NullTime
+	DeliveryError      sql.NullString
+	MessageID          sql.NullString
+}
+
+func (q *Queries) SaveDeliveryReport(ctx context.Context, arg SaveDeliveryReportParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, saveDeliveryReport,
+		arg.DeliveryStatus,
+		arg.DeliveryReportedAt,
+		arg.DeliveryError,
+		arg.MessageID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setMessageSent = `-- name: SetMessageSent :execrows
+UPDATE message
+SET message_id           = $2,
+    sent_at              = $3,
+    content              = $4,
+    provider_status_code = $5,
+    provider_response    = $6,
+    claim_token          = NULL,
+    claimed_until        = NULL
+WHERE id = $1
+  AND claim_token IS NOT DISTINCT FROM $7
 `
 
 type SetMessageSentParams struct {
-	ID        int32
-	MessageID sql.NullString
-	SentAt    sql.NullTime
+	ID                 int32
+	MessageID          sql.NullString
+	SentAt             sql.NullTime
+	Content            string
+	ProviderStatusCode sql.NullInt32
+	ProviderResponse   sql.NullString
+	ClaimToken         sql.NullString
 }
 
-func (q *Queries) SetMessageSent(ctx context.Context, arg SetMessageSentParams) error {
-	_, err := q.db.ExecContext(ctx, setMessageSent, arg.ID, arg.MessageID, arg.SentAt)
-	return err
+func (q *Queries) SetMessageSent(ctx context.Context, arg SetMessageSentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setMessageSent,
+		arg.ID,
+		arg.MessageID,
+		arg.SentAt,
+		arg.Content,
+		arg.ProviderStatusCode,
+		arg.ProviderResponse,
+		arg.ClaimToken,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setMessagesSent = `-- name: SetMessagesSent :many
+UPDATE message
+SET message_id           = sent.message_id,
+    sent_at              = sent.sent_at,
+    content              = sent.content,
+    provider_status_code = NULLIF(sent.provider_status_code, 0),
+    provider_response    = NULLIF(sent.provider_response, ''),
+    claim_token          = NULL,
+    claimed_until        = NULL
+FROM unnest($1::integer[], $2::varchar[], $3::timestamp[], $4::text[], $5::varchar[],
+            $6::integer[], $7::text[])
+         AS sent(id, message_id, sent_at, content, claim_token, provider_status_code, provider_response)
+WHERE message.id = sent.id
+  AND message.claim_token IS NOT DISTINCT FROM NULLIF(sent.claim_token, '')
+RETURNING message.id
+`
+
+type SetMessagesSentParams struct {
+	Ids                 []int32
+	MessageIds          []string
+	SentAts             []time.Time
+	Contents            []string
+	ClaimTokens         []string
+	ProviderStatusCodes []int32
+	ProviderResponses   []string
+}
+
+func (q *Queries) SetMessagesSent(ctx context.Context, arg SetMessagesSentParams) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, setMessagesSent,
+		pq.Array(arg.Ids),
+		pq.Array(arg.MessageIds),
+		pq.Array(arg.SentAts),
+		pq.Array(arg.Contents),
+		pq.Array(arg.ClaimTokens),
+		pq.Array(arg.ProviderStatusCodes),
+		pq.Array(arg.ProviderResponses),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// insertChannel is the channel the message_inserted trigger notifies once per statement inserting messages.
+const insertChannel = "message_inserted"
+
+// listenerPingInterval is how often an idle InsertListener checks its connection, so a silently dropped one is
+// noticed and reestablished rather than waited on forever.
+const listenerPingInterval = 90 * time.Second
+
+// InsertListener listens for the notifications Postgres sends whenever messages are inserted, so they can be sent
+// right away instead of at the next scheduled run.
+type InsertListener struct {
+	listener *pq.Listener // dedicated connection listening on insertChannel
+}
+
+// NewInsertListener returns an InsertListener with a connection of its own to the database at dsn, reestablished
+// whenever it is lost. Failures to connect are reported to onError, which may be nil.
+func NewInsertListener(dsn string, onError func(error)) *InsertListener {
+	return &InsertListener{
+		listener: pq.NewListener(dsn, time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}),
+	}
+}
+
+// Run calls fn whenever messages were inserted, and whenever the connection was reestablished after a loss, as
+// notifications sent meanwhile are lost. It blocks until ctx is done and closes the connection before returning.
+func (l *InsertListener) Run(ctx context.Context, fn func()) error {
+	defer l.listener.Close()
+	listened := make(chan error, 1)
+	go func() { listened <- l.listener.Listen(insertChannel) }()
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-listened:
+		if err != nil {
+			return errors.Wrap(err, "listening for inserted messages")
+		}
+	}
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-l.listener.Notify:
+			// a nil notification tells the connection was reestablished
+			fn()
+		case <-ticker.C:
+			go l.listener.Ping()
+		}
+	}
+}
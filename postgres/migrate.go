@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// migrationFiles holds the schema migrations, the same files atlas applies with `atlas migrate apply`.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the key of the advisory lock serializing migrations of instances starting at the same time.
+const migrationLockID = 20250619145955
+
+// migration is a schema migration embedded in the binary.
+type migration struct {
+	version string // version the file name starts with, e.g. 20250619145955
+	sql     string // statements of the migration
+}
+
+// Migrate applies the embedded schema migrations that have not been applied to db yet, oldest first, and returns the
+// versions it applied. Every migration runs in its own transaction recording its version in the schema_migrations
+// table, so a failed migration leaves the schema as the previous one left it. Migrations applied with atlas, as by the
+// migrator service of docker-compose, count as applied. Instances migrating concurrently wait for each other.
+func Migrate(ctx context.Context, db *sql.DB) ([]string, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	// the advisory lock is held by the session, so every statement must run on the same connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "connecting to migrate")
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, errors.Wrap(err, "locking migrations")
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}()
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    varchar(14) PRIMARY KEY,
+    applied_at timestamp   NOT NULL DEFAULT LOCALTIMESTAMP
+)`); err != nil {
+		return nil, errors.Wrap(err, "creating migrations table")
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return ret, err
+		}
+		ret = append(ret, m.version)
+	}
+	return ret, nil
+}
+
+// embeddedMigrations returns the embedded migrations in the order they are applied.
+func embeddedMigrations() ([]migration, error) {
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, errors.Wrap(err, "listing migrations")
+	}
+	slices.Sort(names)
+	ret := make([]migration, len(names))
+	for i, name := range names {
+		stmts, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading migration %s", name)
+		}
+		version, _, _ := strings.Cut(path.Base(name), "_")
+		ret[i] = migration{version: version, sql: string(stmts)}
+	}
+	return ret, nil
+}
+
+// appliedMigrations returns the versions of the migrations applied by Migrate or by atlas.
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[string]bool, error) {
+	ret := make(map[string]bool)
+	if err := readVersions(ctx, conn, `SELECT version FROM schema_migrations`, ret); err != nil {
+		return nil, err
+	}
+	var atlas bool
+	err := conn.QueryRowContext(ctx, `SELECT to_regclass('atlas_schema_revisions.atlas_schema_revisions') IS NOT NULL`).Scan(&atlas)
+	if err != nil {
+		return nil, errors.Wrap(err, "looking up atlas revisions")
+	}
+	if !atlas {
+		return ret, nil
+	}
+	if err := readVersions(ctx, conn, `SELECT version FROM atlas_schema_revisions.atlas_schema_revisions`, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// readVersions adds the migration versions query returns to versions.
+func readVersions(ctx context.Context, conn *sql.Conn, query string, versions map[string]bool) error {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "reading applied migrations")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return errors.Wrap(err, "reading applied migrations")
+		}
+		versions[version] = true
+	}
+	return errors.Wrap(rows.Err(), "reading applied migrations")
+}
+
+// applyMigration runs the statements of m and records its version in one transaction.
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "starting migration %s", m.version)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return errors.Wrapf(err, "applying migration %s", m.version)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.version); err != nil {
+		return errors.Wrapf(err, "recording migration %s", m.version)
+	}
+	return errors.Wrapf(tx.Commit(), "committing migration %s", m.version)
+}
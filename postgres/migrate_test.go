@@ -0,0 +1,108 @@
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grustamli/insider-msg-sender/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// migrationVersions returns the versions of the migration files, oldest first.
+func migrationVersions(t *testing.T) []string {
+	t.Helper()
+	names, err := filepath.Glob("migrations/*.sql")
+	require.NoError(t, err)
+	ret := make([]string, len(names))
+	for i, name := range names {
+		ret[i], _, _ = strings.Cut(filepath.Base(name), "_")
+	}
+	return ret
+}
+
+func TestMigrate(t *testing.T) {
+	versions := migrationVersions(t)
+	require.Greater(t, len(versions), 2)
+	latest := versions[len(versions)-1]
+	tests := []struct {
+		name    string
+		applied []string // versions recorded by Migrate
+		atlas   []string // versions recorded by atlas; no atlas table if nil
+		want    []string
+	}{
+		{name: "fresh database", want: versions},
+		{name: "up to date", applied: versions},
+		{name: "pending migration", applied: versions[:len(versions)-1], want: []string{latest}},
+		{name: "migrated by atlas", applied: versions[:1], atlas: versions[1 : len(versions)-1], want: []string{latest}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			t.Cleanup(func() { db.Close() })
+
+			mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+			rows := sqlmock.NewRows([]string{"version"})
+			for _, v := range tt.applied {
+				rows.AddRow(v)
+			}
+			mock.ExpectQuery(`SELECT version FROM schema_migrations`).WillReturnRows(rows)
+			mock.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.atlas != nil))
+			if tt.atlas != nil {
+				rows := sqlmock.NewRows([]string{"version"})
+				for _, v := range tt.atlas {
+					rows.AddRow(v)
+				}
+				mock.ExpectQuery(`SELECT version FROM atlas_schema_revisions.atlas_schema_revisions`).WillReturnRows(rows)
+			}
+			for _, v := range tt.want {
+				mock.ExpectBegin()
+				mock.ExpectExec(`.+`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`INSERT INTO schema_migrations`).WithArgs(v).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+			mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			applied, err := postgres.Migrate(context.Background(), db)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, applied)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigrate_FailedMigrationRollsBack(t *testing.T) {
+	versions := migrationVersions(t)
+	initial, err := os.ReadFile(filepath.Join("migrations", versions[0]+"_Initial.sql"))
+	require.NoError(t, err)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectExec(`SELECT pg_advisory_lock($1)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    varchar(14) PRIMARY KEY,
+    applied_at timestamp   NOT NULL DEFAULT LOCALTIMESTAMP
+)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT version FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectQuery(`SELECT to_regclass('atlas_schema_revisions.atlas_schema_revisions') IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectExec(string(initial)).WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+	mock.ExpectExec(`SELECT pg_advisory_unlock($1)`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := postgres.Migrate(context.Background(), db)
+
+	require.ErrorIs(t, err, assert.AnError)
+	assert.ErrorContains(t, err, "applying migration "+versions[0])
+	assert.Empty(t, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,4 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "idempotency_key" character varying(255) NULL;
+-- Create index "message_idempotency_key_key" to table: "message"
+CREATE UNIQUE INDEX "message_idempotency_key_key" ON "public"."message" ("idempotency_key");
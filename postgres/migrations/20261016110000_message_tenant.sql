@@ -0,0 +1,8 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "tenant_id" character varying(64) NOT NULL DEFAULT 'default';
+-- Drop index "message_idempotency_key_key" from table: "message"
+DROP INDEX "public"."message_idempotency_key_key";
+-- Create index "message_tenant_id_idempotency_key_key" to table: "message"
+CREATE UNIQUE INDEX "message_tenant_id_idempotency_key_key" ON "public"."message" ("tenant_id", "idempotency_key");
+-- Create index "message_tenant_id_sent_at_idx" to table: "message"
+CREATE INDEX "message_tenant_id_sent_at_idx" ON "public"."message" ("tenant_id", "sent_at");
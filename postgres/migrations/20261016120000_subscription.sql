@@ -0,0 +1,4 @@
+-- Create "subscription" table
+CREATE TABLE "public"."subscription" ("id" serial NOT NULL, "url" character varying NOT NULL, "secret" character varying NOT NULL, "events" character varying[] NOT NULL, "tenant_id" character varying(64) NOT NULL DEFAULT 'default', "created_at" timestamp NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY ("id"));
+-- Create index "subscription_tenant_id_idx" to table: "subscription"
+CREATE INDEX "subscription_tenant_id_idx" ON "public"."subscription" ("tenant_id");
@@ -0,0 +1,4 @@
+-- Create "audit_log" table
+CREATE TABLE "public"."audit_log" ("id" bigserial NOT NULL, "action" character varying(64) NOT NULL, "actor" character varying(255) NOT NULL DEFAULT '', "api_key" character varying(16) NOT NULL DEFAULT '', "request_id" character varying(64) NOT NULL DEFAULT '', "remote_addr" character varying(64) NOT NULL DEFAULT '', "details" text NOT NULL DEFAULT '', "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY ("id"));
+-- Create index "audit_log_action_id_idx" to table: "audit_log"
+CREATE INDEX "audit_log_action_id_idx" ON "public"."audit_log" ("action", "id");
@@ -0,0 +1,2 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "attempts" integer NOT NULL DEFAULT 0, ADD COLUMN "last_error" text NULL, ADD COLUMN "next_attempt_at" timestamp NULL, ADD COLUMN "failed_at" timestamp NULL;
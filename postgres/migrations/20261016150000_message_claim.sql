@@ -0,0 +1,2 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "claim_token" character varying(64) NULL, ADD COLUMN "claimed_until" timestamp NULL;
@@ -0,0 +1,4 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "priority" integer NOT NULL DEFAULT 0;
+-- Create index "message_unsent_priority_idx" to table: "message"
+CREATE INDEX "message_unsent_priority_idx" ON "public"."message" ("priority" DESC, "created_at") WHERE (sent_at IS NULL);
@@ -0,0 +1,2 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "send_at" timestamp NULL;
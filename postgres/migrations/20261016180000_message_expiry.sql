@@ -0,0 +1,2 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "expires_at" timestamp NULL, ADD COLUMN "expired_at" timestamp NULL;
@@ -0,0 +1,2 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "template_name" character varying(100) NULL, ADD COLUMN "template_vars" jsonb NOT NULL DEFAULT '{}';
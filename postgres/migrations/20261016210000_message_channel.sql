@@ -0,0 +1,2 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "channel" character varying(16) NOT NULL DEFAULT 'sms';
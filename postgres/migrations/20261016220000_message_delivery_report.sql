@@ -0,0 +1,4 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "delivery_status" character varying(16) NULL, ADD COLUMN "delivery_reported_at" timestamp NULL, ADD COLUMN "delivery_error" text NULL;
+-- Create index "message_message_id_idx" to table: "message"
+CREATE INDEX "message_message_id_idx" ON "public"."message" ("message_id") WHERE (message_id IS NOT NULL);
@@ -0,0 +1,4 @@
+-- Create "message_archive" table
+CREATE TABLE "public"."message_archive" ("id" integer NOT NULL, "recipient" character varying NOT NULL, "content" text NOT NULL, "message_id" character varying(100) NULL, "created_at" timestamp NULL, "sent_at" timestamp NOT NULL, "tenant_id" character varying(64) NOT NULL, "attempts" integer NOT NULL, "priority" integer NOT NULL, "template_name" character varying(100) NULL, "template_vars" jsonb NOT NULL, "channel" character varying(16) NOT NULL, "delivery_status" character varying(16) NULL, "delivery_reported_at" timestamp NULL, "delivery_error" text NULL, "archived_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY ("id"));
+-- Create index "message_sent_at_idx" to table: "message"
+CREATE INDEX "message_sent_at_idx" ON "public"."message" ("sent_at") WHERE (sent_at IS NOT NULL);
@@ -0,0 +1,6 @@
+-- Drop index "message_sent_at_idx" from table: "message"
+DROP INDEX "public"."message_sent_at_idx";
+-- Create index "message_sent_at_id_idx" to table: "message"
+CREATE INDEX "message_sent_at_id_idx" ON "public"."message" ("sent_at", "id") WHERE (sent_at IS NOT NULL);
+-- Create index "message_unsent_created_at_id_idx" to table: "message"
+CREATE INDEX "message_unsent_created_at_id_idx" ON "public"."message" ("created_at", "id") WHERE (sent_at IS NULL);
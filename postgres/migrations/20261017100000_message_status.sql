@@ -0,0 +1,12 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "status" character varying(16) NOT NULL GENERATED ALWAYS AS (CASE WHEN (sent_at IS NOT NULL) THEN 'sent'::text WHEN (failed_at IS NOT NULL) THEN 'failed'::text WHEN (rejected_at IS NOT NULL) THEN 'rejected'::text WHEN (canceled_at IS NOT NULL) THEN 'canceled'::text WHEN (expired_at IS NOT NULL) THEN 'expired'::text ELSE 'pending'::text END) STORED;
+-- Drop index "message_unsent_priority_idx" from table: "message"
+DROP INDEX "public"."message_unsent_priority_idx";
+-- Drop index "message_unsent_created_at_id_idx" from table: "message"
+DROP INDEX "public"."message_unsent_created_at_id_idx";
+-- Create index "message_pending_priority_idx" to table: "message"
+CREATE INDEX "message_pending_priority_idx" ON "public"."message" ("priority" DESC, "created_at") WHERE ((status)::text = 'pending'::text);
+-- Create index "message_pending_created_at_id_idx" to table: "message"
+CREATE INDEX "message_pending_created_at_id_idx" ON "public"."message" ("created_at", "id") WHERE ((status)::text = 'pending'::text);
+-- Create index "message_failed_at_id_idx" to table: "message"
+CREATE INDEX "message_failed_at_id_idx" ON "public"."message" ("failed_at" DESC, "id" DESC) WHERE ((status)::text = 'failed'::text);
@@ -0,0 +1,4 @@
+-- Create "message_attempt" table
+CREATE TABLE "public"."message_attempt" ("id" bigserial NOT NULL, "message_id" integer NOT NULL, "started_at" timestamp NOT NULL, "duration_ms" integer NOT NULL, "status_code" integer NULL, "error" text NULL, PRIMARY KEY ("id"), CONSTRAINT "message_attempt_message_id_fkey" FOREIGN KEY ("message_id") REFERENCES "public"."message" ("id") ON UPDATE NO ACTION ON DELETE CASCADE);
+-- Create index "message_attempt_message_id_started_at_idx" to table: "message_attempt"
+CREATE INDEX "message_attempt_message_id_started_at_idx" ON "public"."message_attempt" ("message_id", "started_at");
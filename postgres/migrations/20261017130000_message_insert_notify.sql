@@ -0,0 +1,9 @@
+-- Create "notify_message_inserted" function
+CREATE FUNCTION "public"."notify_message_inserted" () RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_notify('message_inserted', '');
+    RETURN NULL;
+END;
+$$;
+-- Create trigger "message_inserted"
+CREATE TRIGGER "message_inserted" AFTER INSERT ON "public"."message" FOR EACH STATEMENT EXECUTE FUNCTION "public"."notify_message_inserted"();
@@ -0,0 +1,4 @@
+-- Modify "message" table
+ALTER TABLE "public"."message" ADD COLUMN "provider_status_code" integer NULL, ADD COLUMN "provider_response" text NULL;
+-- Modify "message_archive" table
+ALTER TABLE "public"."message_archive" ADD COLUMN "provider_status_code" integer NULL, ADD COLUMN "provider_response" text NULL;
@@ -0,0 +1,6 @@
+-- Create extension "pg_trgm"
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+-- Create index "message_sent_recipient_idx" to table: "message"
+CREATE INDEX "message_sent_recipient_idx" ON "public"."message" ("recipient", "sent_at", "id") WHERE (sent_at IS NOT NULL);
+-- Create index "message_sent_content_trgm_idx" to table: "message"
+CREATE INDEX "message_sent_content_trgm_idx" ON "public"."message" USING gin ("content" gin_trgm_ops) WHERE (sent_at IS NOT NULL);
@@ -0,0 +1,2 @@
+-- Create index "message_retention_idx" to table: "message"
+CREATE INDEX "message_retention_idx" ON "public"."message" ((COALESCE(sent_at, deleted_at))) WHERE ((sent_at IS NOT NULL) OR (deleted_at IS NOT NULL));
@@ -1,28 +1,373 @@
+-- Reads take an optional tenant_id: NULL matches every tenant, which the background sender relies on.
+
 -- name: GetAllUnsent :many
-SELECT id, recipient, content
+SELECT id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+       template_name, template_vars, channel
 FROM message
-WHERE sent_at IS NULL
-ORDER BY created_at;
+WHERE status = 'pending'
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND (send_at IS NULL OR send_at <= LOCALTIMESTAMP)
+  AND (next_attempt_at IS NULL OR next_attempt_at <= LOCALTIMESTAMP)
+  AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+ORDER BY priority DESC, created_at;
 
 -- name: GetNextUnsent :one
-SELECT id, recipient, content
+SELECT id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+       template_name, template_vars, channel
 FROM message
-WHERE sent_at IS NULL
-ORDER BY created_at
+WHERE status = 'pending'
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND (send_at IS NULL OR send_at <= LOCALTIMESTAMP)
+  AND (next_attempt_at IS NULL OR next_attempt_at <= LOCALTIMESTAMP)
+  AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+ORDER BY priority DESC, created_at
 LIMIT 1;
 
+-- name: GetUnsent :many
+SELECT id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+       template_name, template_vars, channel
+FROM message
+WHERE status = 'pending'
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND (send_at IS NULL OR send_at <= LOCALTIMESTAMP)
+  AND (next_attempt_at IS NULL OR next_attempt_at <= LOCALTIMESTAMP)
+  AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+ORDER BY priority DESC, created_at
+LIMIT sqlc.arg('max_results');
+
 -- name: GetAllSent :many
-SELECT message_id, sent_at
+SELECT id, recipient, content, message_id, sent_at, tenant_id
 FROM message
 WHERE sent_at NOTNULL
+  AND deleted_at IS NULL
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
 ORDER BY created_at;
 
--- name: SetMessageSent :exec
+-- name: FindSent :many
+SELECT id, recipient, content, message_id, sent_at, tenant_id
+FROM message
+WHERE sent_at NOTNULL
+  AND deleted_at IS NULL
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND (sqlc.narg('recipient')::varchar IS NULL OR recipient = sqlc.narg('recipient'))
+  AND (sqlc.narg('content_pattern')::text IS NULL OR content ILIKE sqlc.narg('content_pattern'))
+  AND (sqlc.narg('sent_after')::timestamp IS NULL OR sent_at > sqlc.narg('sent_after'))
+  AND (sqlc.narg('sent_before')::timestamp IS NULL OR sent_at < sqlc.narg('sent_before'))
+  AND (sqlc.narg('after_sent_at')::timestamp IS NULL
+    OR (sent_at, id) > (sqlc.narg('after_sent_at'), sqlc.narg('after_id')::integer))
+ORDER BY sent_at, id
+LIMIT sqlc.narg('max_results')::integer;
+
+-- name: FindUnsent :many
+SELECT id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+       template_name, template_vars, channel
+FROM message
+WHERE status = 'pending'
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND (sqlc.narg('recipient')::varchar IS NULL OR recipient = sqlc.narg('recipient'))
+  AND (sqlc.narg('contains')::text IS NULL OR strpos(lower(content), lower(sqlc.narg('contains'))) > 0)
+  AND (sqlc.narg('after_id')::integer IS NULL
+    OR (created_at, id) > (SELECT a.created_at, a.id FROM message a WHERE a.id = sqlc.narg('after_id')))
+ORDER BY created_at, id
+LIMIT sqlc.narg('max_results')::integer;
+
+-- name: DeleteMessage :execrows
+UPDATE message
+SET deleted_at = LOCALTIMESTAMP
+WHERE id = sqlc.arg('id')
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND status <> 'pending'
+  AND deleted_at IS NULL;
+
+-- name: CancelMessage :execrows
+UPDATE message
+SET canceled_at = sqlc.arg('canceled_at')
+WHERE id = sqlc.arg('id')
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND status = 'pending'
+  AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP);
+
+-- name: SaveDeliveryReport :execrows
+UPDATE message
+SET delivery_status      = sqlc.arg('delivery_status'),
+    delivery_reported_at = sqlc.arg('delivery_reported_at'),
+    delivery_error       = sqlc.narg('delivery_error')
+WHERE message_id = sqlc.arg('message_id')
+  AND sent_at NOTNULL;
+
+-- name: ClaimMessage :execrows
+UPDATE message
+SET claim_token   = $2,
+    claimed_until = $3
+WHERE id = $1
+  AND status = 'pending'
+  AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP);
+
+-- name: ClaimUnsent :many
+WITH claimed AS (
+    UPDATE message
+    SET claim_token   = sqlc.arg('claim_token'),
+        claimed_until = sqlc.arg('claimed_until')
+    WHERE id IN (SELECT id
+                 FROM message
+                 WHERE status = 'pending'
+                   AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+                   AND (send_at IS NULL OR send_at <= LOCALTIMESTAMP)
+                   AND (next_attempt_at IS NULL OR next_attempt_at <= LOCALTIMESTAMP)
+                   AND (claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP)
+                 ORDER BY priority DESC, created_at
+                 LIMIT sqlc.arg('max_results') FOR UPDATE SKIP LOCKED)
+    RETURNING id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+              template_name, template_vars, channel, created_at)
+SELECT id, recipient, content, tenant_id, attempts, last_error, priority, send_at, expires_at,
+       template_name, template_vars, channel
+FROM claimed
+ORDER BY priority DESC, created_at;
+
+-- name: ExpireMessage :execrows
+UPDATE message
+SET expired_at    = $2,
+    claim_token   = NULL,
+    claimed_until = NULL
+WHERE id = $1
+  AND status = 'pending'
+  AND expired_at IS NULL
+  AND rejected_at IS NULL
+  AND canceled_at IS NULL
+  AND (claim_token IS NOT DISTINCT FROM sqlc.narg('claim_token')
+    OR claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP);
+
+-- name: FindFailed :many
+SELECT id, recipient, content, tenant_id, attempts, last_error, failed_at, channel
+FROM message
+WHERE status = 'failed'
+  AND deleted_at IS NULL
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND (sqlc.narg('recipient')::varchar IS NULL OR recipient = sqlc.narg('recipient'))
+  AND (sqlc.narg('contains')::text IS NULL OR strpos(lower(content), lower(sqlc.narg('contains'))) > 0)
+ORDER BY failed_at DESC, id DESC
+LIMIT sqlc.narg('max_results')::integer;
+
+-- name: RequeueMessage :execrows
+UPDATE message
+SET attempts        = 0,
+    last_error      = NULL,
+    next_attempt_at = NULL,
+    failed_at       = NULL
+WHERE id = sqlc.arg('id')
+  AND status = 'failed'
+  AND deleted_at IS NULL
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'));
+
+-- name: RejectMessage :execrows
+UPDATE message
+SET rejected_at   = $2,
+    last_error    = $3,
+    claim_token   = NULL,
+    claimed_until = NULL
+WHERE id = $1
+  AND claim_token IS NOT DISTINCT FROM sqlc.narg('claim_token');
+
+-- name: SaveAttempts :execrows
+UPDATE message
+SET attempts        = $2,
+    last_error      = $3,
+    next_attempt_at = $4,
+    failed_at       = $5,
+    claim_token     = NULL,
+    claimed_until   = NULL
+WHERE id = $1
+  AND claim_token IS NOT DISTINCT FROM sqlc.narg('claim_token');
+
+-- name: SetMessageSent :execrows
+UPDATE message
+SET message_id           = $2,
+    sent_at              = $3,
+    content              = $4,
+    provider_status_code = $5,
+    provider_response    = $6,
+    claim_token          = NULL,
+    claimed_until        = NULL
+WHERE id = $1
+  AND claim_token IS NOT DISTINCT FROM sqlc.narg('claim_token');
+
+-- name: SetMessagesSent :many
 UPDATE message
-SET message_id = $2,
-    sent_at    = $3
-WHERE id = $1;
+SET message_id           = sent.message_id,
+    sent_at              = sent.sent_at,
+    content              = sent.content,
+    provider_status_code = NULLIF(sent.provider_status_code, 0),
+    provider_response    = NULLIF(sent.provider_response, ''),
+    claim_token          = NULL,
+    claimed_until        = NULL
+FROM unnest(@ids::integer[], @message_ids::varchar[], @sent_ats::timestamp[], @contents::text[], @claim_tokens::varchar[],
+            @provider_status_codes::integer[], @provider_responses::text[])
+         AS sent(id, message_id, sent_at, content, claim_token, provider_status_code, provider_response)
+WHERE message.id = sent.id
+  AND message.claim_token IS NOT DISTINCT FROM NULLIF(sent.claim_token, '')
+RETURNING message.id;
+
+-- name: ArchiveSent :one
+WITH archived AS (
+    DELETE
+    FROM message
+    WHERE id IN (SELECT id
+                 FROM message
+                 WHERE COALESCE(sent_at, deleted_at) < LOCALTIMESTAMP - make_interval(secs => sqlc.arg('retention_seconds')::float8)
+                   AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+                 ORDER BY COALESCE(sent_at, deleted_at)
+                 LIMIT sqlc.arg('max_results') FOR UPDATE SKIP LOCKED)
+    RETURNING id, recipient, content, message_id, created_at, sent_at, tenant_id, attempts, priority, template_name,
+        template_vars, channel, delivery_status, delivery_reported_at, delivery_error, provider_status_code,
+        provider_response, deleted_at),
+     moved AS (
+         INSERT
+         INTO message_archive (id, recipient, content, message_id, created_at, sent_at, tenant_id, attempts,
+                               priority, template_name, template_vars, channel, delivery_status,
+                               delivery_reported_at, delivery_error, provider_status_code, provider_response)
+         SELECT id, recipient, content, message_id, created_at, sent_at, tenant_id, attempts, priority,
+                template_name, template_vars, channel, delivery_status, delivery_reported_at, delivery_error,
+                provider_status_code, provider_response
+         FROM archived
+         WHERE deleted_at IS NULL)
+SELECT COUNT(*)
+FROM archived;
+
+-- name: DeleteSent :execrows
+DELETE
+FROM message
+WHERE id IN (SELECT id
+             FROM message
+             WHERE COALESCE(sent_at, deleted_at) < LOCALTIMESTAMP - make_interval(secs => sqlc.arg('retention_seconds')::float8)
+               AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+             ORDER BY COALESCE(sent_at, deleted_at)
+             LIMIT sqlc.arg('max_results') FOR UPDATE SKIP LOCKED);
 
 -- name: InsertMessage :exec
-INSERT INTO message (recipient, content)
-VALUES ($1, $2);
\ No newline at end of file
+INSERT INTO message (recipient, content, tenant_id, priority, send_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetMessageByID :one
+SELECT id, recipient, content, message_id, sent_at, tenant_id, attempts, last_error, failed_at, priority, send_at, expires_at,
+       expired_at, canceled_at, template_name, template_vars, channel, delivery_status, delivery_reported_at, delivery_error,
+       rejected_at, created_at, next_attempt_at, idempotency_key, provider_status_code, provider_response
+FROM message
+WHERE id = sqlc.arg('id')
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND deleted_at IS NULL;
+
+-- name: GetSentPage :many
+SELECT id, recipient, content, message_id, sent_at, tenant_id
+FROM message
+WHERE sent_at NOTNULL
+  AND deleted_at IS NULL
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND id > sqlc.arg('after_id')
+ORDER BY id
+LIMIT sqlc.arg('page_size');
+
+-- name: CreateMessage :one
+INSERT INTO message (recipient, content, idempotency_key, tenant_id, priority, send_at, expires_at, template_name,
+                     template_vars, channel)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (tenant_id, idempotency_key) DO NOTHING
+RETURNING id;
+
+-- name: GetMessageByIdempotencyKey :one
+SELECT id, recipient, content, message_id, sent_at, tenant_id, attempts, last_error, failed_at, priority, send_at, expires_at,
+       expired_at, canceled_at, template_name, template_vars, channel, delivery_status, delivery_reported_at, delivery_error,
+       rejected_at, created_at, next_attempt_at, idempotency_key, provider_status_code, provider_response
+FROM message
+WHERE tenant_id = $1
+  AND idempotency_key = $2
+  AND deleted_at IS NULL;
+
+-- name: GetStats :one
+SELECT COUNT(*) FILTER (WHERE sent_at NOTNULL)                                 AS sent_count,
+       COUNT(*) FILTER (WHERE status = 'pending')                              AS unsent_count,
+       COUNT(*) FILTER (WHERE failed_at NOTNULL)                               AS failed_count,
+       COUNT(*) FILTER (WHERE expired_at NOTNULL)                              AS expired_count,
+       COUNT(*) FILTER (WHERE canceled_at NOTNULL)                             AS canceled_count,
+       COUNT(*) FILTER (WHERE rejected_at NOTNULL)                             AS rejected_count,
+       COUNT(*) FILTER (WHERE sent_at >= LOCALTIMESTAMP - INTERVAL '1 hour')   AS sent_last_hour,
+       COUNT(*) FILTER (WHERE sent_at >= LOCALTIMESTAMP - INTERVAL '1 day')    AS sent_last_day,
+       COALESCE(AVG(EXTRACT(EPOCH FROM sent_at - created_at)), 0)::float8 AS avg_latency_seconds
+FROM message
+WHERE (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+  AND deleted_at IS NULL;
+
+-- name: CountUnsent :one
+SELECT COUNT(*)
+FROM message
+WHERE status = 'pending'
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'));
+
+-- name: CountSent :one
+SELECT COUNT(*)
+FROM message
+WHERE sent_at NOTNULL
+  AND deleted_at IS NULL
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'));
+
+-- name: CountFailed :one
+SELECT COUNT(*)
+FROM message
+WHERE status = 'failed'
+  AND deleted_at IS NULL
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'));
+
+-- name: CountSentByHour :many
+SELECT hour.start::timestamp AS hour, COUNT(message.id) AS sent_count
+FROM generate_series(date_trunc('hour', @since::timestamp), date_trunc('hour', LOCALTIMESTAMP),
+                     INTERVAL '1 hour') AS hour(start)
+         LEFT JOIN message ON message.sent_at >= hour.start
+    AND message.sent_at < hour.start + INTERVAL '1 hour'
+    AND message.deleted_at IS NULL
+    AND (sqlc.narg('tenant_id')::varchar IS NULL OR message.tenant_id = sqlc.narg('tenant_id'))
+GROUP BY hour.start
+ORDER BY hour.start;
+
+-- name: CreateSubscription :one
+INSERT INTO subscription (url, secret, events, tenant_id)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at;
+
+-- name: ListSubscriptions :many
+SELECT id, url, secret, events, tenant_id, created_at
+FROM subscription
+WHERE (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'))
+ORDER BY id;
+
+-- name: DeleteSubscription :execrows
+DELETE
+FROM subscription
+WHERE id = sqlc.arg('id')
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR tenant_id = sqlc.narg('tenant_id'));
+
+-- name: InsertAuditEntry :one
+INSERT INTO audit_log (action, actor, api_key, request_id, remote_addr, details, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id;
+
+-- name: ListAuditEntries :many
+SELECT id, action, actor, api_key, request_id, remote_addr, details, created_at
+FROM audit_log
+WHERE (sqlc.narg('action')::varchar IS NULL OR action = sqlc.narg('action'))
+  AND (sqlc.narg('before_id')::bigint IS NULL OR id < sqlc.narg('before_id'))
+ORDER BY id DESC
+LIMIT sqlc.narg('max_results')::integer;
+
+-- name: InsertAttempts :exec
+INSERT INTO message_attempt (message_id, started_at, duration_ms, status_code, error)
+SELECT message_id, started_at, duration_ms, NULLIF(status_code, 0), NULLIF(error, '')
+FROM unnest(@message_ids::integer[], @started_ats::timestamp[], @durations::integer[], @status_codes::integer[],
+            @errors::text[]) AS attempt(message_id, started_at, duration_ms, status_code, error);
+
+-- name: ListAttempts :many
+SELECT a.started_at, a.duration_ms, a.status_code, a.error
+FROM message_attempt a
+         JOIN message m ON m.id = a.message_id
+WHERE a.message_id = sqlc.arg('message_id')
+  AND (sqlc.narg('tenant_id')::varchar IS NULL OR m.tenant_id = sqlc.narg('tenant_id'))
+  AND m.deleted_at IS NULL
+ORDER BY a.started_at, a.id;
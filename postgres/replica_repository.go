@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+)
+
+// ReplicaRepository wraps the message.Repository of a primary database and sends the reads of sent messages and stats
+// to the repository of a read replica, taking their load off the primary. Everything else, writes and queue claims
+// included, goes to the primary, as does every read made in a transaction.
+// GetAllSent stays on the primary too, as it fills the cache of sent messages, which is kept until it is flushed:
+// filled from the replica, it would lack the messages sent within the replication lag for that long.
+// Messages sent within the replication lag may be missing from the reads served by the replica.
+type ReplicaRepository struct {
+	message.Repository                    // primary repository for writes, claims and reads of unsent messages
+	replica            message.Repository // repository of the read replica
+}
+
+var _ message.Repository = (*ReplicaRepository)(nil) // ensure interface compliance
+
+// NewReplicaRepository constructs a ReplicaRepository reading sent messages and stats from replica and delegating
+// other operations to primary.
+func NewReplicaRepository(primary, replica message.Repository) *ReplicaRepository {
+	return &ReplicaRepository{
+		Repository: primary,
+		replica:    replica,
+	}
+}
+
+// FindSent returns a page of the sent messages of the tenant matching f from the replica.
+func (r *ReplicaRepository) FindSent(ctx context.Context, f message.Filter) ([]*message.SentMessage, error) {
+	return r.replica.FindSent(ctx, f)
+}
+
+// WalkSent calls fn with every sent message of the tenant read from the replica.
+func (r *ReplicaRepository) WalkSent(ctx context.Context, fn func(*message.SentMessage) error) error {
+	return r.replica.WalkSent(ctx, fn)
+}
+
+// GetStats returns the message statistics of the tenant from the replica.
+func (r *ReplicaRepository) GetStats(ctx context.Context) (*message.Stats, error) {
+	return r.replica.GetStats(ctx)
+}
+
+// CountSent returns the number of sent messages of the tenant from the replica.
+func (r *ReplicaRepository) CountSent(ctx context.Context) (int64, error) {
+	return r.replica.CountSent(ctx)
+}
+
+// CountSentByHour returns the messages of the tenant sent in each hour since since from the replica.
+func (r *ReplicaRepository) CountSentByHour(ctx context.Context, since time.Time) ([]message.HourCount, error) {
+	return r.replica.CountSentByHour(ctx, since)
+}
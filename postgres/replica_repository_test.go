@@ -0,0 +1,68 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/memory"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaRepository_ReadsSentMessagesFromReplica(t *testing.T) {
+	primary, replica := memory.NewMessageRepository(), memory.NewMessageRepository()
+	repo := postgres.NewReplicaRepository(primary, replica)
+	ctx := context.Background()
+	// the replica has caught up with one sent message, the primary holds it and a pending one
+	for _, r := range []message.Repository{primary, replica} {
+		msg, err := message.NewUnsentMessage("+905551234567", "hello")
+		require.NoError(t, err)
+		stored, _, err := r.Create(ctx, msg)
+		require.NoError(t, err)
+		require.NoError(t, stored.SetSent("ext-1", time.Now()))
+		require.NoError(t, r.Save(ctx, stored))
+	}
+	msg, err := message.NewUnsentMessage("+905551234567", "hello")
+	require.NoError(t, err)
+	_, _, err = repo.Create(ctx, msg)
+	require.NoError(t, err)
+
+	sent, err := repo.FindSent(ctx, message.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, sent, 1)
+	stats, err := repo.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Unsent, "stats are read from the replica")
+
+	// unsent messages are read from the primary
+	unsent, err := repo.GetAllUnsent(ctx)
+	require.NoError(t, err)
+	assert.Len(t, unsent, 1)
+	n, err := replica.CountUnsent(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n, "writes go to the primary")
+}
+
+func TestReplicaRepository_GetAllSent_ReadsPrimary(t *testing.T) {
+	primary, replica := memory.NewMessageRepository(), memory.NewMessageRepository()
+	repo := postgres.NewReplicaRepository(primary, replica)
+	ctx := context.Background()
+	// the message has not reached the replica yet
+	msg, err := message.NewUnsentMessage("+905551234567", "hello")
+	require.NoError(t, err)
+	stored, _, err := repo.Create(ctx, msg)
+	require.NoError(t, err)
+	require.NoError(t, stored.SetSent("ext-1", time.Now()))
+	require.NoError(t, repo.Save(ctx, stored))
+
+	// the list filling the cache, and rebuilding it, is read from the primary, so it does not lag behind
+	sent, err := repo.GetAllSent(ctx)
+	require.NoError(t, err)
+	assert.Len(t, sent, 1)
+	found, err := repo.FindSent(ctx, message.Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
@@ -4,31 +4,42 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/grustamli/insider-msg-sender/message"
 	"github.com/grustamli/insider-msg-sender/postgres/gen"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
+	"math"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// sentPageSize is the number of sent messages fetched per query when walking all sent messages.
+const sentPageSize = 500
+
 type MessageRepository struct {
-	queries *gen.Queries
+	db      *sql.DB      // connection pool, used to begin transactions
+	tx      *sql.Tx      // transaction the repository is bound to by WithTx, nil outside one
+	queries *gen.Queries // queries bound to tx if set, to db otherwise
 }
 
 var _ message.Repository = (*MessageRepository)(nil)
 
 // NewMessageRepository constructs a new PostgreSQL implementation of message.Repository
-func NewMessageRepository(queries *gen.Queries) *MessageRepository {
+func NewMessageRepository(db *sql.DB) *MessageRepository {
 	return &MessageRepository{
-		queries: queries,
+		db:      db,
+		queries: traced(db),
 	}
 }
 
-// GetNextUnsent retrieves the next unsent message from the database.
+// GetNextUnsent retrieves the next unsent message from the database, highest priority first.
 // Returns nil, nil if no unsent message is found.
 func (m *MessageRepository) GetNextUnsent(ctx context.Context) (*message.Message, error) {
-	res, err := m.queries.GetNextUnsent(ctx)
+	res, err := m.queries.GetNextUnsent(ctx, tenantFilter(ctx))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -40,7 +51,20 @@ func (m *MessageRepository) GetNextUnsent(ctx context.Context) (*message.Message
 
 // messageFromRow converts a GetNextUnsentRow to a message.Message.
 func messageFromRow(res gen.GetNextUnsentRow) (*message.Message, error) {
-	return message.NewMessage(strID(res.ID), res.Recipient, res.Content)
+	msg, err := message.NewChannelMessage(strID(res.ID), message.Channel(res.Channel), res.Recipient, res.Content)
+	if err != nil {
+		return nil, err
+	}
+	msg.Tenant = res.TenantID
+	msg.Attempts = int(res.Attempts)
+	msg.LastError = res.LastError.String
+	msg.Priority = int(res.Priority)
+	msg.ScheduledAt = res.SendAt.Time
+	msg.ExpiresAt = res.ExpiresAt.Time
+	if err := setTemplate(msg, res.TemplateName, res.TemplateVars); err != nil {
+		return nil, err
+	}
+	return msg, nil
 }
 
 // strID formats an integer ID as its string representation.
@@ -48,6 +72,13 @@ func strID(id int32) string {
 	return fmt.Sprintf("%d", id)
 }
 
+// tenantFilter returns the tenant_id query argument for the tenant ctx is scoped to.
+// It is NULL for unscoped contexts, which matches messages of every tenant.
+func tenantFilter(ctx context.Context) sql.NullString {
+	tenant, ok := message.TenantFromContext(ctx)
+	return sql.NullString{String: tenant, Valid: ok}
+}
+
 // Save updates the sent status of a message in the database including message_id and sent_at.
 // Does nothing if SentAt is zero. Returns an error if the ID is missing or update fails.
 func (m *MessageRepository) Save(ctx context.Context, msg *message.Message) error {
@@ -63,21 +94,310 @@ func (m *MessageRepository) Save(ctx context.Context, msg *message.Message) erro
 	if err != nil {
 		return errors.Wrap(err, "converting message ID to int")
 	}
-	err = m.queries.SetMessageSent(ctx, gen.SetMessageSentParams{
-		ID:        int32(id),
-		SentAt:    sql.NullTime{Time: msg.SentAt, Valid: true},
-		MessageID: sql.NullString{String: msg.MessageID, Valid: true},
+	n, err := m.queries.SetMessageSent(ctx, gen.SetMessageSentParams{
+		ID:                 int32(id),
+		SentAt:             sql.NullTime{Time: msg.SentAt, Valid: true},
+		MessageID:          sql.NullString{String: msg.MessageID, Valid: true},
+		Content:            msg.Content,
+		ClaimToken:         claimToken(msg),
+		ProviderStatusCode: sql.NullInt32{Int32: int32(msg.ProviderStatusCode), Valid: msg.ProviderStatusCode != 0},
+		ProviderResponse:   sql.NullString{String: string(msg.ProviderResponse), Valid: len(msg.ProviderResponse) > 0},
 	})
 	if err != nil {
 		return errors.Wrap(err, "setting message sent")
 	}
+	if n == 0 {
+		return message.ErrClaimLost
+	}
+	return nil
+}
+
+// SaveAll updates the sent state of msgs in the database with a single statement, unnesting their fields into rows
+// joined with the messages by ID. Messages not marked sent are ignored; messages claimed under a token other than their
+// ClaimToken are not updated and returned.
+func (m *MessageRepository) SaveAll(ctx context.Context, msgs []*message.Message) ([]*message.Message, error) {
+	var (
+		params gen.SetMessagesSentParams
+		sent   []*message.Message // messages of params, in the same order
+	)
+	for _, msg := range msgs {
+		if msg.SentAt.IsZero() {
+			continue
+		}
+		if msg.MessageID == "" {
+			return nil, errors.Errorf("message ID of message %s is empty", msg.ID)
+		}
+		id, err := strconv.Atoi(msg.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting message ID to int")
+		}
+		params.Ids = append(params.Ids, int32(id))
+		params.MessageIds = append(params.MessageIds, msg.MessageID)
+		params.SentAts = append(params.SentAts, msg.SentAt)
+		params.Contents = append(params.Contents, msg.Content)
+		params.ClaimTokens = append(params.ClaimTokens, msg.ClaimToken)
+		params.ProviderStatusCodes = append(params.ProviderStatusCodes, int32(msg.ProviderStatusCode))
+		params.ProviderResponses = append(params.ProviderResponses, string(msg.ProviderResponse))
+		sent = append(sent, msg)
+	}
+	if len(sent) == 0 {
+		return nil, nil
+	}
+	saved, err := m.queries.SetMessagesSent(ctx, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "setting messages sent")
+	}
+	var lost []*message.Message
+	for i, msg := range sent {
+		if !slices.Contains(saved, params.Ids[i]) {
+			lost = append(lost, msg)
+		}
+	}
+	return lost, nil
+}
+
+// SaveAttempts updates the failed delivery attempts of a message in the database,
+// including when it may be tried next and whether delivery was given up.
+func (m *MessageRepository) SaveAttempts(ctx context.Context, msg *message.Message) error {
+	id, err := strconv.Atoi(msg.ID)
+	if err != nil {
+		return errors.Wrap(err, "converting message ID to int")
+	}
+	n, err := m.queries.SaveAttempts(ctx, gen.SaveAttemptsParams{
+		ID:            int32(id),
+		Attempts:      int32(msg.Attempts),
+		LastError:     sql.NullString{String: msg.LastError, Valid: msg.LastError != ""},
+		NextAttemptAt: sql.NullTime{Time: msg.NextAttemptAt, Valid: !msg.NextAttemptAt.IsZero()},
+		FailedAt:      sql.NullTime{Time: msg.FailedAt, Valid: !msg.FailedAt.IsZero()},
+		ClaimToken:    claimToken(msg),
+	})
+	if err != nil {
+		return errors.Wrap(err, "saving message attempts")
+	}
+	if n == 0 {
+		return message.ErrClaimLost
+	}
+	return nil
+}
+
+// ArchiveSent moves up to n messages of the tenant sent more than olderThan ago into the message_archive table in a
+// single statement, so each message is either still in message or archived. Deleted messages are removed without
+// being archived, including those deleted more than olderThan ago without being sent. Rows locked by other transactions, e.g. another instance archiving at the same time, are skipped.
+func (m *MessageRepository) ArchiveSent(ctx context.Context, olderThan time.Duration, n int) (int64, error) {
+	archived, err := m.queries.ArchiveSent(ctx, gen.ArchiveSentParams{
+		RetentionSeconds: olderThan.Seconds(),
+		TenantID:         tenantFilter(ctx),
+		MaxResults:       int32(n),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "archiving sent messages")
+	}
+	return archived, nil
+}
+
+// DeleteSent deletes up to n messages of the tenant sent more than olderThan ago, and those deleted more than
+// olderThan ago without being sent, skipping rows locked by other transactions.
+func (m *MessageRepository) DeleteSent(ctx context.Context, olderThan time.Duration, n int) (int64, error) {
+	deleted, err := m.queries.DeleteSent(ctx, gen.DeleteSentParams{
+		RetentionSeconds: olderThan.Seconds(),
+		TenantID:         tenantFilter(ctx),
+		MaxResults:       int32(n),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "deleting sent messages")
+	}
+	return deleted, nil
+}
+
+// Reject stores when and why a claimed message was rejected by validation, releasing its claim.
+func (m *MessageRepository) Reject(ctx context.Context, msg *message.Message) error {
+	id, err := strconv.Atoi(msg.ID)
+	if err != nil {
+		return errors.Wrap(err, "converting message ID to int")
+	}
+	n, err := m.queries.RejectMessage(ctx, gen.RejectMessageParams{
+		ID:         int32(id),
+		RejectedAt: sql.NullTime{Time: msg.RejectedAt, Valid: true},
+		LastError:  sql.NullString{String: msg.LastError, Valid: msg.LastError != ""},
+		ClaimToken: claimToken(msg),
+	})
+	if err != nil {
+		return errors.Wrap(err, "rejecting message")
+	}
+	if n == 0 {
+		return message.ErrClaimLost
+	}
+	return nil
+}
+
+// SaveDeliveryReport stores the final delivery status reported by r on the sent message with its provider message ID.
+// Returns message.ErrMessageNotFound if no sent message has that provider message ID.
+func (m *MessageRepository) SaveDeliveryReport(ctx context.Context, r *message.DeliveryReport) error {
+	n, err := m.queries.SaveDeliveryReport(ctx, gen.SaveDeliveryReportParams{
+		DeliveryStatus:     sql.NullString{String: string(r.Status), Valid: true},
+		DeliveryReportedAt: sql.NullTime{Time: r.ReportedAt, Valid: true},
+		DeliveryError:      sql.NullString{String: r.Reason, Valid: r.Reason != ""},
+		MessageID:          sql.NullString{String: r.MessageID, Valid: true},
+	})
+	if err != nil {
+		return errors.Wrap(err, "saving delivery report")
+	}
+	if n == 0 {
+		return message.ErrMessageNotFound
+	}
+	return nil
+}
+
+// Expire stores when an unsent message was found expired and releases its claim, if msg holds it.
+// Messages sent meanwhile or claimed by another instance are left alone.
+func (m *MessageRepository) Expire(ctx context.Context, msg *message.Message) error {
+	id, err := strconv.Atoi(msg.ID)
+	if err != nil {
+		return errors.Wrap(err, "converting message ID to int")
+	}
+	if _, err := m.queries.ExpireMessage(ctx, gen.ExpireMessageParams{
+		ID:         int32(id),
+		ExpiredAt:  sql.NullTime{Time: msg.ExpiredAt, Valid: true},
+		ClaimToken: claimToken(msg),
+	}); err != nil {
+		return errors.Wrap(err, "expiring message")
+	}
 	return nil
 }
 
+// Cancel stores when a pending message of the tenant was canceled.
+// Returns false if the message is not pending anymore, belongs to another tenant or holds a claim that has not expired yet.
+func (m *MessageRepository) Cancel(ctx context.Context, msg *message.Message) (bool, error) {
+	id, err := strconv.Atoi(msg.ID)
+	if err != nil {
+		return false, errors.Wrap(err, "converting message ID to int")
+	}
+	n, err := m.queries.CancelMessage(ctx, gen.CancelMessageParams{
+		CanceledAt: sql.NullTime{Time: msg.CanceledAt, Valid: true},
+		ID:         int32(id),
+		TenantID:   tenantFilter(ctx),
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "canceling message")
+	}
+	return n == 1, nil
+}
+
+// Delete sets the deleted_at timestamp of a message of the tenant that is no longer pending, which every read skips.
+// Returns message.ErrMessageNotFound if no such message exists.
+func (m *MessageRepository) Delete(ctx context.Context, id string) error {
+	intID, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		// non-numeric or out of range IDs can never match a row
+		return message.ErrMessageNotFound
+	}
+	n, err := m.queries.DeleteMessage(ctx, gen.DeleteMessageParams{
+		ID:       int32(intID),
+		TenantID: tenantFilter(ctx),
+	})
+	if err != nil {
+		return errors.Wrap(err, "deleting message")
+	}
+	if n == 0 {
+		return message.ErrMessageNotFound
+	}
+	return nil
+}
+
+// Claim marks an unsent message as being delivered under msg.ClaimToken until the given time.
+// Returns false if the message was sent, failed for good, was canceled or holds a claim that has not expired yet.
+func (m *MessageRepository) Claim(ctx context.Context, msg *message.Message, until time.Time) (bool, error) {
+	id, err := strconv.Atoi(msg.ID)
+	if err != nil {
+		return false, errors.Wrap(err, "converting message ID to int")
+	}
+	n, err := m.queries.ClaimMessage(ctx, gen.ClaimMessageParams{
+		ID:           int32(id),
+		ClaimToken:   claimToken(msg),
+		ClaimedUntil: sql.NullTime{Time: until, Valid: true},
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "claiming message")
+	}
+	return n > 0, nil
+}
+
+// ClaimUnsent claims up to n unsent messages under token until the given time in a single statement, highest priority
+// first, and returns them. Rows locked by a concurrent claim are skipped with FOR UPDATE SKIP LOCKED, so instances
+// neither block on nor claim the same rows. Returns nil, nil if no unsent messages are found.
+func (m *MessageRepository) ClaimUnsent(ctx context.Context, n int, token string, until time.Time) ([]*message.Message, error) {
+	res, err := m.queries.ClaimUnsent(ctx, gen.ClaimUnsentParams{
+		ClaimToken:   sql.NullString{String: token, Valid: token != ""},
+		ClaimedUntil: sql.NullTime{Time: until, Valid: true},
+		TenantID:     tenantFilter(ctx),
+		MaxResults:   int32(min(max(n, 0), math.MaxInt32)),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "claiming unsent messages")
+	}
+	rows := make([]gen.GetAllUnsentRow, len(res))
+	for i, r := range res {
+		rows[i] = gen.GetAllUnsentRow(r)
+	}
+	msgs, err := unsentMessagesFromRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		msg.ClaimToken = token
+	}
+	return msgs, nil
+}
+
+// sendAt returns the send_at query argument of msg, NULL for messages to be sent right away.
+// The column has no time zone, so the time is stored in local time, like the other timestamps and LOCALTIMESTAMP.
+func sendAt(msg *message.Message) sql.NullTime {
+	return sql.NullTime{Time: msg.ScheduledAt.Local(), Valid: !msg.ScheduledAt.IsZero()}
+}
+
+// expiresAt returns the expires_at query argument of msg, NULL for messages that never expire.
+func expiresAt(msg *message.Message) sql.NullTime {
+	return sql.NullTime{Time: msg.ExpiresAt.Local(), Valid: !msg.ExpiresAt.IsZero()}
+}
+
+// templateName returns the template_name query argument of msg, NULL for messages with fixed content.
+func templateName(msg *message.Message) sql.NullString {
+	return sql.NullString{String: msg.Template, Valid: msg.IsTemplated()}
+}
+
+// templateVars returns the template_vars query argument of msg, an empty JSON object if it has no variables.
+func templateVars(msg *message.Message) (json.RawMessage, error) {
+	if len(msg.Variables) == 0 {
+		return json.RawMessage("{}"), nil
+	}
+	vars, err := json.Marshal(msg.Variables)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding template variables")
+	}
+	return vars, nil
+}
+
+// setTemplate sets the template of msg from the template_name and template_vars columns of its row.
+func setTemplate(msg *message.Message, name sql.NullString, vars json.RawMessage) error {
+	if !name.Valid {
+		return nil
+	}
+	msg.Template = name.String
+	if err := json.Unmarshal(vars, &msg.Variables); err != nil {
+		return errors.Wrap(err, "decoding template variables")
+	}
+	return nil
+}
+
+// claimToken returns the claim_token query argument of msg, NULL for messages delivered without a claim.
+func claimToken(msg *message.Message) sql.NullString {
+	return sql.NullString{String: msg.ClaimToken, Valid: msg.ClaimToken != ""}
+}
+
 // GetAllSent retrieves all sent messages from the database.
 // Returns nil, nil if no sent messages are found.
 func (m *MessageRepository) GetAllSent(ctx context.Context) ([]*message.SentMessage, error) {
-	res, err := m.queries.GetAllSent(ctx)
+	res, err := m.queries.GetAllSent(ctx, tenantFilter(ctx))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -87,17 +407,355 @@ func (m *MessageRepository) GetAllSent(ctx context.Context) ([]*message.SentMess
 	return sentMessagesFromRows(res)
 }
 
+// FindSent retrieves the sent messages matching f from the database, ordered by sent_at and id.
+// Filtering and the limit are applied by the query, so only matching rows are read.
+func (m *MessageRepository) FindSent(ctx context.Context, f message.Filter) ([]*message.SentMessage, error) {
+	params := gen.FindSentParams{
+		TenantID:       tenantFilter(ctx),
+		Recipient:      sql.NullString{String: f.To, Valid: f.To != ""},
+		ContentPattern: containsPattern(f.Contains),
+		SentAfter:      sql.NullTime{Time: f.SentAfter, Valid: !f.SentAfter.IsZero()},
+		SentBefore:     sql.NullTime{Time: f.SentBefore, Valid: !f.SentBefore.IsZero()},
+		MaxResults:     limitParam(f.Limit),
+	}
+	if f.After != nil {
+		afterID, err := strconv.ParseInt(f.After.ID, 10, 32)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing position message ID")
+		}
+		params.AfterSentAt = sql.NullTime{Time: f.After.SentAt, Valid: true}
+		params.AfterID = sql.NullInt32{Int32: int32(afterID), Valid: true}
+	}
+	res, err := m.queries.FindSent(ctx, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "finding sent messages")
+	}
+	ret := make([]*message.SentMessage, len(res))
+	for i, r := range res {
+		msg, err := sentMessageFromRow(gen.GetAllSentRow(r))
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = msg
+	}
+	return ret, nil
+}
+
+// FindUnsent retrieves the unsent messages matching f from the database.
+func (m *MessageRepository) FindUnsent(ctx context.Context, f message.Filter) ([]*message.Message, error) {
+	params := gen.FindUnsentParams{
+		TenantID:   tenantFilter(ctx),
+		Recipient:  sql.NullString{String: f.To, Valid: f.To != ""},
+		Contains:   sql.NullString{String: f.Contains, Valid: f.Contains != ""},
+		MaxResults: limitParam(f.Limit),
+	}
+	if f.After != nil {
+		afterID, err := strconv.ParseInt(f.After.ID, 10, 32)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing position message ID")
+		}
+		params.AfterID = sql.NullInt32{Int32: int32(afterID), Valid: true}
+	}
+	res, err := m.queries.FindUnsent(ctx, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "finding unsent messages")
+	}
+	rows := make([]gen.GetAllUnsentRow, len(res))
+	for i, r := range res {
+		rows[i] = gen.GetAllUnsentRow(r)
+	}
+	return unsentMessagesFromRows(rows)
+}
+
+// FindFailed retrieves the messages whose delivery was given up that match f from the database, most recently failed first.
+func (m *MessageRepository) FindFailed(ctx context.Context, f message.Filter) ([]*message.Message, error) {
+	res, err := m.queries.FindFailed(ctx, gen.FindFailedParams{
+		TenantID:   tenantFilter(ctx),
+		Recipient:  sql.NullString{String: f.To, Valid: f.To != ""},
+		Contains:   sql.NullString{String: f.Contains, Valid: f.Contains != ""},
+		MaxResults: limitParam(f.Limit),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "finding failed messages")
+	}
+	ret := make([]*message.Message, len(res))
+	for i, r := range res {
+		msg, err := message.NewChannelMessage(strID(r.ID), message.Channel(r.Channel), r.Recipient, r.Content)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating message from row")
+		}
+		msg.Tenant = r.TenantID
+		msg.Attempts = int(r.Attempts)
+		msg.LastError = r.LastError.String
+		msg.FailedAt = r.FailedAt.Time
+		ret[i] = msg
+	}
+	return ret, nil
+}
+
+// Requeue resets the attempts and failed state of a failed message, making it due for sending right away.
+// Returns message.ErrMessageNotFound if no failed message with the given ID exists.
+func (m *MessageRepository) Requeue(ctx context.Context, id string) error {
+	intID, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		// non-numeric or out of range IDs can never match a row
+		return message.ErrMessageNotFound
+	}
+	n, err := m.queries.RequeueMessage(ctx, gen.RequeueMessageParams{
+		ID:       int32(intID),
+		TenantID: tenantFilter(ctx),
+	})
+	if err != nil {
+		return errors.Wrap(err, "requeueing message")
+	}
+	if n == 0 {
+		return message.ErrMessageNotFound
+	}
+	return nil
+}
+
+// likeEscaper escapes the LIKE wildcards and their escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern converts a search text into an ILIKE pattern matching content containing it. Unlike a substring
+// search the pattern can be served by the trigram index on content. An empty text means no filter.
+func containsPattern(text string) sql.NullString {
+	return sql.NullString{String: "%" + likeEscaper.Replace(text) + "%", Valid: text != ""}
+}
+
+// limitParam converts a result limit into a LIMIT query argument. Non-positive limits mean no limit.
+func limitParam(limit int) sql.NullInt32 {
+	return sql.NullInt32{Int32: int32(min(limit, math.MaxInt32)), Valid: limit > 0}
+}
+
+// WalkSent calls fn for every sent message in ID order.
+// Messages are fetched in pages of sentPageSize, so memory use does not grow with the table.
+// Iteration stops at the first error returned by fn, which is passed through unwrapped.
+func (m *MessageRepository) WalkSent(ctx context.Context, fn func(*message.SentMessage) error) error {
+	var lastID int32
+	for {
+		rows, err := m.queries.GetSentPage(ctx, gen.GetSentPageParams{
+			TenantID: tenantFilter(ctx),
+			AfterID:  lastID,
+			PageSize: sentPageSize,
+		})
+		if err != nil {
+			return errors.Wrap(err, "getting sent messages page")
+		}
+		for _, r := range rows {
+			msg, err := sentMessageFromRow(gen.GetAllSentRow(r))
+			if err != nil {
+				return err
+			}
+			if err := fn(msg); err != nil {
+				return err
+			}
+			lastID = r.ID
+		}
+		if len(rows) < sentPageSize {
+			return nil
+		}
+	}
+}
+
 // Insert adds a new unsent message record to the database.
 func (m *MessageRepository) Insert(ctx context.Context, msg *message.Message) error {
 	if err := m.queries.InsertMessage(ctx, gen.InsertMessageParams{
 		Recipient: msg.To,
 		Content:   msg.Content,
+		TenantID:  message.TenantOf(ctx, msg),
+		Priority:  int32(msg.Priority),
+		SendAt:    sendAt(msg),
+		ExpiresAt: expiresAt(msg),
 	}); err != nil {
 		return errors.Wrap(err, "inserting message")
 	}
 	return nil
 }
 
+// Create inserts a new unsent message and returns it with its database ID.
+// A conflicting idempotency key makes the insert a no-op; the message already stored under that key is returned instead.
+func (m *MessageRepository) Create(ctx context.Context, msg *message.Message) (*message.Message, bool, error) {
+	key := sql.NullString{String: msg.IdempotencyKey, Valid: msg.IdempotencyKey != ""}
+	tenant := message.TenantOf(ctx, msg)
+	vars, err := templateVars(msg)
+	if err != nil {
+		return nil, false, err
+	}
+	id, err := m.queries.CreateMessage(ctx, gen.CreateMessageParams{
+		Recipient:      msg.To,
+		Content:        msg.Content,
+		IdempotencyKey: key,
+		TenantID:       tenant,
+		Priority:       int32(msg.Priority),
+		SendAt:         sendAt(msg),
+		ExpiresAt:      expiresAt(msg),
+		TemplateName:   templateName(msg),
+		TemplateVars:   vars,
+		Channel:        string(message.ChannelOf(msg)),
+	})
+	if err == nil {
+		created := *msg
+		created.ID = strID(id)
+		created.Tenant = tenant
+		created.Channel = message.ChannelOf(msg)
+		return &created, true, nil
+	}
+	// no row is returned when the idempotency key already exists
+	if !errors.Is(err, sql.ErrNoRows) || !key.Valid {
+		return nil, false, errors.Wrap(err, "creating message")
+	}
+	res, err := m.queries.GetMessageByIdempotencyKey(ctx, gen.GetMessageByIdempotencyKeyParams{
+		TenantID:       tenant,
+		IdempotencyKey: key,
+	})
+	// the key stays taken by a deleted message until the retention job purges it
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, message.ErrMessageDeleted
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "getting message by idempotency key")
+	}
+	existing, err := messageFromByIDRow(gen.GetMessageByIDRow(res))
+	if err != nil {
+		return nil, false, err
+	}
+	existing.IdempotencyKey = msg.IdempotencyKey
+	return existing, false, nil
+}
+
+// GetStats computes aggregate message figures in a single query.
+func (m *MessageRepository) GetStats(ctx context.Context) (*message.Stats, error) {
+	res, err := m.queries.GetStats(ctx, tenantFilter(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "getting stats")
+	}
+	return &message.Stats{
+		Sent:         res.SentCount,
+		Unsent:       res.UnsentCount,
+		Failed:       res.FailedCount,
+		Expired:      res.ExpiredCount,
+		Canceled:     res.CanceledCount,
+		Rejected:     res.RejectedCount,
+		SentLastHour: res.SentLastHour,
+		SentLastDay:  res.SentLastDay,
+		AvgLatency:   time.Duration(res.AvgLatencySeconds * float64(time.Second)),
+	}, nil
+}
+
+// CountUnsent counts the messages waiting for delivery using the partial index on pending messages.
+func (m *MessageRepository) CountUnsent(ctx context.Context) (int64, error) {
+	n, err := m.queries.CountUnsent(ctx, tenantFilter(ctx))
+	return n, errors.Wrap(err, "counting unsent messages")
+}
+
+// CountSent counts the delivered messages using the index on sent_at.
+func (m *MessageRepository) CountSent(ctx context.Context) (int64, error) {
+	n, err := m.queries.CountSent(ctx, tenantFilter(ctx))
+	return n, errors.Wrap(err, "counting sent messages")
+}
+
+// CountFailed counts the messages whose delivery was given up using the partial index on failed messages.
+func (m *MessageRepository) CountFailed(ctx context.Context) (int64, error) {
+	n, err := m.queries.CountFailed(ctx, tenantFilter(ctx))
+	return n, errors.Wrap(err, "counting failed messages")
+}
+
+// CountSentByHour counts the messages delivered in each hour since the one since falls in, in a single query.
+func (m *MessageRepository) CountSentByHour(ctx context.Context, since time.Time) ([]message.HourCount, error) {
+	res, err := m.queries.CountSentByHour(ctx, gen.CountSentByHourParams{
+		Since:    since,
+		TenantID: tenantFilter(ctx),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "counting sent messages by hour")
+	}
+	ret := make([]message.HourCount, len(res))
+	for i, h := range res {
+		ret[i] = message.HourCount{Hour: h.Hour, Count: h.SentCount}
+	}
+	return ret, nil
+}
+
+// insertColumns are the columns of the message table InsertMany copies new messages into.
+var insertColumns = []string{
+	"recipient", "content", "tenant_id", "priority", "send_at", "expires_at", "template_name", "template_vars", "channel",
+}
+
+// InsertMany adds new unsent message records to the database in a single transaction.
+// Messages are streamed to the database with COPY rather than inserted statement by statement, so tens of thousands of
+// messages load in seconds; if any of them fails, none of the messages are stored.
+func (m *MessageRepository) InsertMany(ctx context.Context, msgs []*message.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	ctx, span := startSpan(ctx, "CopyMessages")
+	err := m.copyMessages(ctx, msgs)
+	endQuerySpan(span, err)
+	return err
+}
+
+// WithTx calls fn with a MessageRepository bound to a new transaction, or to the one m is bound to already.
+func (m *MessageRepository) WithTx(ctx context.Context, fn func(message.Repository) error) error {
+	return m.inTx(ctx, func(tx *sql.Tx) error {
+		return fn(&MessageRepository{
+			db:      m.db,
+			tx:      tx,
+			queries: traced(tx),
+		})
+	})
+}
+
+// inTx calls fn with the transaction m is bound to, or with a new one it commits if fn returns nil and rolls back
+// otherwise.
+func (m *MessageRepository) inTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	if m.tx != nil {
+		return fn(m.tx)
+	}
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	// rolling back a committed transaction is a no-op
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "committing transaction")
+}
+
+// copyMessages copies msgs into the message table with COPY FROM STDIN in a transaction of its own, or in the one m is
+// bound to.
+func (m *MessageRepository) copyMessages(ctx context.Context, msgs []*message.Message) error {
+	return m.inTx(ctx, func(tx *sql.Tx) error {
+		return copyMessagesTx(ctx, tx, msgs)
+	})
+}
+
+// copyMessagesTx copies msgs into the message table with COPY FROM STDIN in tx.
+func copyMessagesTx(ctx context.Context, tx *sql.Tx, msgs []*message.Message) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("message", insertColumns...))
+	if err != nil {
+		return errors.Wrap(err, "preparing copy")
+	}
+	defer stmt.Close()
+	for i, msg := range msgs {
+		vars, err := templateVars(msg)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, msg.To, msg.Content, message.TenantOf(ctx, msg), int64(msg.Priority),
+			sendAt(msg), expiresAt(msg), templateName(msg), string(vars), string(message.ChannelOf(msg))); err != nil {
+			return errors.Wrapf(err, "copying message %d", i+1)
+		}
+	}
+	// the rows are buffered until the copy is flushed, where errors of any of them surface
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "copying %d messages", len(msgs))
+	}
+	return errors.Wrap(stmt.Close(), "finishing copy")
+}
+
 // sentMessagesFromRows maps a slice of GetAllSentRow to domain message.SentMessage objects.
 func sentMessagesFromRows(res []gen.GetAllSentRow) ([]*message.SentMessage, error) {
 	ret := make([]*message.SentMessage, len(res))
@@ -121,15 +779,82 @@ func sentMessageFromRow(r gen.GetAllSentRow) (*message.SentMessage, error) {
 		return nil, fmt.Errorf("invalid message ID, %s", r.MessageID.String)
 	}
 	return &message.SentMessage{
+		ID:        strID(r.ID),
+		To:        r.Recipient,
+		Content:   r.Content,
 		MessageID: r.MessageID.String,
 		SentAt:    r.SentAt.Time,
+		Tenant:    r.TenantID,
 	}, nil
 }
 
-// GetAllUnsent retrieves all unsent messages from the database.
+// GetByID retrieves a single message, sent or unsent, by its internal ID.
+// Returns nil, nil if no message with the given ID exists.
+func (m *MessageRepository) GetByID(ctx context.Context, id string) (*message.Message, error) {
+	intID, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		// non-numeric or out of range IDs can never match a row
+		return nil, nil
+	}
+	res, err := m.queries.GetMessageByID(ctx, gen.GetMessageByIDParams{
+		ID:       int32(intID),
+		TenantID: tenantFilter(ctx),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "getting message by id")
+	}
+	return messageFromByIDRow(res)
+}
+
+// messageFromByIDRow converts a GetMessageByIDRow to a message.Message, including sent and delivery state if present.
+func messageFromByIDRow(res gen.GetMessageByIDRow) (*message.Message, error) {
+	msg, err := message.NewChannelMessage(strID(res.ID), message.Channel(res.Channel), res.Recipient, res.Content)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating message from row")
+	}
+	msg.Tenant = res.TenantID
+	msg.IdempotencyKey = res.IdempotencyKey.String
+	msg.CreatedAt = res.CreatedAt.Time
+	msg.Attempts = int(res.Attempts)
+	msg.LastError = res.LastError.String
+	msg.NextAttemptAt = res.NextAttemptAt.Time
+	msg.FailedAt = res.FailedAt.Time
+	msg.Priority = int(res.Priority)
+	msg.ScheduledAt = res.SendAt.Time
+	msg.ExpiresAt = res.ExpiresAt.Time
+	msg.ExpiredAt = res.ExpiredAt.Time
+	msg.CanceledAt = res.CanceledAt.Time
+	msg.RejectedAt = res.RejectedAt.Time
+	if err := setTemplate(msg, res.TemplateName, res.TemplateVars); err != nil {
+		return nil, err
+	}
+	if res.SentAt.Valid {
+		if err := msg.SetSent(res.MessageID.String, res.SentAt.Time); err != nil {
+			return nil, errors.Wrap(err, "setting message sent state from row")
+		}
+		msg.ProviderStatusCode = int(res.ProviderStatusCode.Int32)
+		if res.ProviderResponse.Valid {
+			msg.ProviderResponse = json.RawMessage(res.ProviderResponse.String)
+		}
+	}
+	if res.DeliveryStatus.Valid {
+		msg.SetDelivery(&message.DeliveryReport{
+			MessageID:  res.MessageID.String,
+			Status:     message.DeliveryStatus(res.DeliveryStatus.String),
+			ReportedAt: res.DeliveryReportedAt.Time,
+			Reason:     res.DeliveryError.String,
+		})
+	}
+	return msg, nil
+}
+
+// GetAllUnsent retrieves all unsent messages from the database, highest priority first.
 // Returns nil, nil if no unsent messages are found.
 func (m *MessageRepository) GetAllUnsent(ctx context.Context) ([]*message.Message, error) {
-	res, err := m.queries.GetAllUnsent(ctx)
+	res, err := m.queries.GetAllUnsent(ctx, tenantFilter(ctx))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -139,14 +864,40 @@ func (m *MessageRepository) GetAllUnsent(ctx context.Context) ([]*message.Messag
 	return unsentMessagesFromRows(res)
 }
 
+// GetUnsent retrieves up to n unsent messages from the database in one query, highest priority first.
+// Returns nil, nil if no unsent messages are found.
+func (m *MessageRepository) GetUnsent(ctx context.Context, n int) ([]*message.Message, error) {
+	res, err := m.queries.GetUnsent(ctx, gen.GetUnsentParams{
+		TenantID:   tenantFilter(ctx),
+		MaxResults: int32(min(max(n, 0), math.MaxInt32)),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting unsent messages")
+	}
+	rows := make([]gen.GetAllUnsentRow, len(res))
+	for i, r := range res {
+		rows[i] = gen.GetAllUnsentRow(r)
+	}
+	return unsentMessagesFromRows(rows)
+}
+
 // unsentMessagesFromRows maps a slice of GetAllUnsentRow to domain Message objects.
 func unsentMessagesFromRows(res []gen.GetAllUnsentRow) ([]*message.Message, error) {
 	ret := make([]*message.Message, len(res))
 	for i, r := range res {
-		msg, err := message.NewMessage(strID(r.ID), r.Recipient, r.Content)
+		msg, err := message.NewChannelMessage(strID(r.ID), message.Channel(r.Channel), r.Recipient, r.Content)
 		if err != nil {
 			return nil, errors.Wrap(err, "creating message from row")
 		}
+		msg.Tenant = r.TenantID
+		msg.Attempts = int(r.Attempts)
+		msg.LastError = r.LastError.String
+		msg.Priority = int(r.Priority)
+		msg.ScheduledAt = r.SendAt.Time
+		msg.ExpiresAt = r.ExpiresAt.Time
+		if err := setTemplate(msg, r.TemplateName, r.TemplateVars); err != nil {
+			return nil, err
+		}
 		ret[i] = msg
 	}
 	return ret, nil
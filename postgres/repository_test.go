@@ -0,0 +1,875 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/postgres"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newMockRepository returns a MessageRepository backed by sqlmock.
+func newMockRepository(t *testing.T) (*postgres.MessageRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return postgres.NewMessageRepository(db), mock
+}
+
+func newMessages(n int) []*message.Message {
+	msgs := make([]*message.Message, n)
+	for i := range msgs {
+		msgs[i] = &message.Message{To: "+905551234567", Content: fmt.Sprintf("message %d", i)}
+	}
+	return msgs
+}
+
+// copyMessages is the COPY statement InsertMany streams messages with.
+const copyMessages = `COPY "message" \("recipient", "content", "tenant_id", "priority", "send_at", "expires_at", "template_name", "template_vars", "channel"\) FROM STDIN`
+
+func TestMessageRepository_InsertMany_CopiesInOneTransaction(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	copyIn := mock.ExpectPrepare(copyMessages).WillBeClosed()
+	for range 3 {
+		copyIn.ExpectExec().
+			WithArgs("+905551234567", sqlmock.AnyArg(), message.DefaultTenant, int64(0), nil, nil, nil, "{}", "sms").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	copyIn.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	err := repo.InsertMany(context.Background(), newMessages(3))
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_InsertMany_RollsBackOnFailure(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	copyIn := mock.ExpectPrepare(copyMessages)
+	copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn.ExpectExec().WithoutArgs().WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	err := repo.InsertMany(context.Background(), newMessages(2))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "copying 2 messages: connection reset")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_WithTx_RunsOperationsInOneTransaction(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WHERE status = 'pending'`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	copyIn := mock.ExpectPrepare(copyMessages)
+	copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.WithTx(context.Background(), func(tx message.Repository) error {
+		if _, err := tx.CountUnsent(context.Background()); err != nil {
+			return err
+		}
+		// a nested transaction joins the running one instead of beginning another
+		return tx.WithTx(context.Background(), func(tx message.Repository) error {
+			return tx.InsertMany(context.Background(), newMessages(1))
+		})
+	})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_WithTx_RollsBackOnError(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	failed := errors.New("claim lost")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WHERE status = 'pending'`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectRollback()
+
+	err := repo.WithTx(context.Background(), func(tx message.Repository) error {
+		if _, err := tx.CountUnsent(context.Background()); err != nil {
+			return err
+		}
+		return failed
+	})
+
+	require.ErrorIs(t, err, failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_InsertMany_StoresTenantOfEachMessage(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	msgs := newMessages(3)
+	msgs[1].Tenant = "acme"
+	ctx := message.WithTenant(context.Background(), "globex")
+
+	mock.ExpectBegin()
+	copyIn := mock.ExpectPrepare(copyMessages)
+	for _, tenant := range []string{"globex", "acme", "globex"} {
+		copyIn.ExpectExec().
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), tenant, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+				sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	copyIn.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	err := repo.InsertMany(ctx, msgs)
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_InsertMany_StoresChannelAndTemplate(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	sms, err := message.NewUnsentMessage("+905551234567", "Hello")
+	require.NoError(t, err)
+	sms.Priority = 50
+	email, err := message.NewTemplatedMessage(message.ChannelEmail, "ada@example.com", "welcome",
+		map[string]string{"name": "Ada"})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	copyIn := mock.ExpectPrepare(copyMessages)
+	copyIn.ExpectExec().
+		WithArgs("+905551234567", "Hello", message.DefaultTenant, int64(50), nil, nil, nil, "{}", "sms").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn.ExpectExec().
+		WithArgs("ada@example.com", "", message.DefaultTenant, int64(0), nil, nil, "welcome", `{"name":"Ada"}`, "email").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err = repo.InsertMany(context.Background(), []*message.Message{sms, email})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_GetStats(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	columns := []string{"sent_count", "unsent_count", "failed_count", "expired_count", "canceled_count", "rejected_count",
+		"sent_last_hour", "sent_last_day", "avg_latency_seconds"}
+
+	// sent_at is stored without a time zone, so recent deliveries are compared against LOCALTIMESTAMP
+	mock.ExpectQuery(`LOCALTIMESTAMP - INTERVAL '1 hour'`).
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(10, 4, 1, 3, 5, 6, 2, 7, 1.5))
+
+	stats, err := repo.GetStats(message.WithTenant(context.Background(), "acme"))
+
+	require.NoError(t, err)
+	assert.Equal(t, &message.Stats{
+		Sent:         10,
+		Unsent:       4,
+		Failed:       1,
+		Expired:      3,
+		Canceled:     5,
+		Rejected:     6,
+		SentLastHour: 2,
+		SentLastDay:  7,
+		AvgLatency:   1500 * time.Millisecond,
+	}, stats)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Counts(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	ctx := message.WithTenant(context.Background(), "acme")
+
+	mock.ExpectQuery(`WHERE status = 'pending'`).WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	mock.ExpectQuery(`WHERE sent_at NOTNULL`).WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+	mock.ExpectQuery(`WHERE status = 'failed'`).WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	unsent, err := repo.CountUnsent(ctx)
+	require.NoError(t, err)
+	sent, err := repo.CountSent(ctx)
+	require.NoError(t, err)
+	failed, err := repo.CountFailed(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []int64{4, 10, 1}, []int64{unsent, sent, failed})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_CountSentByHour(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	since := time.Date(2026, 10, 16, 8, 30, 0, 0, time.UTC)
+	hour := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`generate_series`).
+		WithArgs(since, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"hour", "sent_count"}).
+			AddRow(hour, 3).
+			AddRow(hour.Add(time.Hour), 0))
+
+	counts, err := repo.CountSentByHour(message.WithTenant(context.Background(), "acme"), since)
+
+	require.NoError(t, err)
+	assert.Equal(t, []message.HourCount{{Hour: hour, Count: 3}, {Hour: hour.Add(time.Hour), Count: 0}}, counts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Create(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	ctx := message.WithTenant(context.Background(), "acme")
+
+	mock.ExpectQuery("INSERT INTO message").
+		WithArgs("+905551234567", "hello", "key-1", "acme", int32(0), sql.NullTime{}, sql.NullTime{},
+			sql.NullString{}, json.RawMessage("{}"), "sms").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+	stored, created, err := repo.Create(ctx, &message.Message{To: "+905551234567", Content: "hello", IdempotencyKey: "key-1"})
+
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "42", stored.ID)
+	assert.Equal(t, "acme", stored.Tenant)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Create_Scheduled(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	sendAt := time.Date(2026, 10, 17, 9, 0, 0, 0, time.Local)
+
+	mock.ExpectQuery("INSERT INTO message").
+		WithArgs("+905551234567", "hello", sql.NullString{}, message.DefaultTenant, int32(0),
+			sql.NullTime{Time: sendAt, Valid: true}, sql.NullTime{}, sql.NullString{}, json.RawMessage("{}"), "sms").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+	stored, _, err := repo.Create(context.Background(), &message.Message{To: "+905551234567", Content: "hello", ScheduledAt: sendAt})
+
+	require.NoError(t, err)
+	assert.Equal(t, sendAt, stored.ScheduledAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Create_Templated(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery("INSERT INTO message").
+		WithArgs("+905551234567", "", sql.NullString{}, message.DefaultTenant, int32(0), sql.NullTime{}, sql.NullTime{},
+			sql.NullString{String: "welcome", Valid: true}, json.RawMessage(`{"name":"Ada"}`), "sms").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+	stored, _, err := repo.Create(context.Background(),
+		&message.Message{To: "+905551234567", Template: "welcome", Variables: map[string]string{"name": "Ada"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "welcome", stored.Template)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Create_IdempotencyKeyConflict(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	ctx := message.WithTenant(context.Background(), "acme")
+	sentAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	// ON CONFLICT DO NOTHING returns no row, so the message stored under the key is looked up
+	mock.ExpectQuery("INSERT INTO message").
+		WithArgs("+905551234567", "hello", "key-1", "acme", int32(0), sql.NullTime{}, sql.NullTime{},
+			sql.NullString{}, json.RawMessage("{}"), "sms").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT (.+) FROM message WHERE tenant_id = \\$1\\s+AND idempotency_key = \\$2").
+		WithArgs("acme", "key-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "message_id", "sent_at", "tenant_id",
+			"attempts", "last_error", "failed_at", "priority", "send_at", "expires_at", "expired_at", "canceled_at", "template_name", "template_vars", "channel",
+			"delivery_status", "delivery_reported_at", "delivery_error", "rejected_at", "created_at", "next_attempt_at", "idempotency_key",
+			"provider_status_code", "provider_response"}).
+			AddRow(7, "+905551234567", "hello", "ext-7", sentAt, "acme", 0, nil, nil, 0, nil, nil, nil, nil, nil, []byte("{}"), "sms",
+				"delivered", sentAt.Add(time.Minute), nil, nil, sentAt.Add(-time.Minute), nil, "key-1", 202, `{"messageId":"ext-7"}`))
+
+	stored, created, err := repo.Create(ctx, &message.Message{To: "+905551234567", Content: "hello", IdempotencyKey: "key-1"})
+
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "7", stored.ID)
+	assert.Equal(t, "key-1", stored.IdempotencyKey)
+	assert.Equal(t, "ext-7", stored.MessageID)
+	assert.True(t, stored.IsSent())
+	assert.True(t, stored.IsDelivered())
+	assert.Equal(t, sentAt.Add(time.Minute), stored.DeliveryReportedAt)
+	assert.Equal(t, sentAt.Add(-time.Minute), stored.CreatedAt)
+	assert.Equal(t, 202, stored.ProviderStatusCode)
+	assert.JSONEq(t, `{"messageId":"ext-7"}`, string(stored.ProviderResponse))
+	assert.Equal(t, message.StatusSent, stored.Status())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Create_KeyOfDeletedMessage(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	ctx := message.WithTenant(context.Background(), "acme")
+
+	// the message stored under the key was deleted, so the lookup finds nothing
+	mock.ExpectQuery("INSERT INTO message").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("AND deleted_at IS NULL").
+		WithArgs("acme", "key-1").
+		WillReturnError(sql.ErrNoRows)
+
+	_, _, err := repo.Create(ctx, &message.Message{To: "+905551234567", Content: "hello", IdempotencyKey: "key-1"})
+
+	assert.ErrorIs(t, err, message.ErrMessageDeleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Create_NoRowsWithoutKey(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	// without a key there is nothing to conflict on, so a missing row is an error rather than a replay
+	mock.ExpectQuery("INSERT INTO message").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, _, err := repo.Create(context.Background(), &message.Message{To: "+905551234567", Content: "hello"})
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_FindSent_AfterPosition(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	after := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	sentAt := after.Add(time.Second)
+	null := sql.NullString{}
+	noTime := sql.NullTime{}
+
+	mock.ExpectQuery("FROM message").
+		WithArgs(null, null, null, noTime, noTime,
+			sql.NullTime{Time: after, Valid: true}, sql.NullInt32{Int32: 41, Valid: true}, sql.NullInt32{Int32: 2, Valid: true}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "message_id", "sent_at", "tenant_id"}).
+			AddRow(int32(7), "+905551234567", "hello", "ext-7", sentAt, "default"))
+
+	msgs, err := repo.FindSent(context.Background(), message.Filter{
+		After: &message.Position{SentAt: after, ID: "41"},
+		Limit: 2,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "7", msgs[0].ID)
+	assert.Equal(t, &message.Position{SentAt: sentAt, ID: "7"}, message.PositionOf(msgs[0]))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_FindSent_Search(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	noTime := sql.NullTime{}
+
+	// wildcards in the text are matched literally
+	mock.ExpectQuery("content ILIKE").
+		WithArgs(sql.NullString{}, sql.NullString{String: "+905551234567", Valid: true},
+			sql.NullString{String: `%50\% off\_now%`, Valid: true}, noTime, noTime, noTime, sql.NullInt32{},
+			sql.NullInt32{Int32: 10, Valid: true}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "message_id", "sent_at", "tenant_id"}))
+
+	msgs, err := repo.FindSent(context.Background(), message.Filter{To: "+905551234567", Contains: "50% off_now", Limit: 10})
+
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_FindUnsent_AfterPosition(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	null := sql.NullString{}
+
+	mock.ExpectQuery("FROM message").
+		WithArgs(null, null, null, sql.NullInt32{Int32: 41, Valid: true}, sql.NullInt32{Int32: 2, Valid: true}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "tenant_id", "attempts", "last_error",
+			"priority", "send_at", "expires_at", "template_name", "template_vars", "channel"}).
+			AddRow(int32(42), "+905551234567", "hello", "default", int32(0), nil, int32(0), nil, nil, nil, []byte("{}"), "sms"))
+
+	msgs, err := repo.FindUnsent(context.Background(), message.Filter{After: &message.Position{ID: "41"}, Limit: 2})
+
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "42", msgs[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_FindUnsent_InvalidPosition(t *testing.T) {
+	repo, _ := newMockRepository(t)
+
+	_, err := repo.FindUnsent(context.Background(), message.Filter{After: &message.Position{ID: "abc"}})
+
+	assert.Error(t, err)
+}
+
+func TestMessageRepository_SaveAttempts(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	next := time.Date(2026, 10, 16, 9, 0, 30, 0, time.UTC)
+	msg := &message.Message{ID: "7", Attempts: 2, LastError: "provider down", NextAttemptAt: next}
+
+	mock.ExpectExec("UPDATE message").
+		WithArgs(int32(7), int32(2), sql.NullString{String: "provider down", Valid: true},
+			sql.NullTime{Time: next, Valid: true}, sql.NullTime{}, sql.NullString{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.SaveAttempts(context.Background(), msg))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_ArchiveSent(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	// the rows are moved by a single statement, so a message is never lost between the tables
+	mock.ExpectQuery(`WITH archived AS \(\s*DELETE\s+FROM message(.+)FOR UPDATE SKIP LOCKED(.+)INSERT\s+INTO message_archive`).
+		WithArgs(float64(30*24*60*60), sql.NullString{}, int32(500)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(120)))
+
+	archived, err := repo.ArchiveSent(context.Background(), 30*24*time.Hour, 500)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), archived)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_DeleteSent(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(`DELETE\s+FROM message\s+WHERE id IN \(SELECT id\s+FROM message\s+WHERE COALESCE\(sent_at, deleted_at\) < LOCALTIMESTAMP`).
+		WithArgs(float64(60*60), sql.NullString{String: "acme", Valid: true}, int32(100)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	deleted, err := repo.DeleteSent(message.WithTenant(context.Background(), "acme"), time.Hour, 100)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Reject(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	rejectedAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	msg := &message.Message{ID: "7", ClaimToken: "token-1"}
+	msg.SetRejected(message.ErrBlockedRecipient, rejectedAt)
+
+	mock.ExpectExec("UPDATE message SET rejected_at").
+		WithArgs(int32(7), sql.NullTime{Time: rejectedAt, Valid: true},
+			sql.NullString{String: "recipient is blocklisted", Valid: true}, sql.NullString{String: "token-1", Valid: true}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.ErrorIs(t, repo.Reject(context.Background(), msg), message.ErrClaimLost)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Claim(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected int64
+		want     bool
+	}{
+		{name: "unclaimed message", affected: 1, want: true},
+		{name: "claimed by another instance", affected: 0, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepository(t)
+			until := time.Date(2026, 10, 16, 9, 1, 0, 0, time.UTC)
+			msg := &message.Message{ID: "7", ClaimToken: "t0k3n"}
+
+			mock.ExpectExec(`claimed_until IS NULL OR claimed_until <= LOCALTIMESTAMP`).
+				WithArgs(int32(7), sql.NullString{String: "t0k3n", Valid: true}, sql.NullTime{Time: until, Valid: true}).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			claimed, err := repo.Claim(context.Background(), msg, until)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, claimed)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMessageRepository_ClaimUnsent(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	until := time.Date(2026, 10, 16, 9, 1, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`LIMIT \$4 FOR UPDATE SKIP LOCKED\)`).
+		WithArgs(sql.NullString{String: "t0k3n", Valid: true}, sql.NullTime{Time: until, Valid: true}, "acme", int32(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "tenant_id", "attempts", "last_error", "priority", "send_at",
+			"expires_at", "template_name", "template_vars", "channel"}).
+			AddRow(int32(9), "+905551234567", "urgent", "acme", int32(0), nil, int32(50), nil, nil, nil, []byte("{}"), "sms").
+			AddRow(int32(7), "+905551234568", "hello", "acme", int32(1), nil, int32(0), nil, nil, nil, []byte("{}"), "sms"))
+
+	msgs, err := repo.ClaimUnsent(message.WithTenant(context.Background(), "acme"), 2, "t0k3n", until)
+
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "9", msgs[0].ID)
+	for _, msg := range msgs {
+		assert.Equal(t, "t0k3n", msg.ClaimToken)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_SaveAll(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	sentAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	msgs := []*message.Message{
+		{ID: "7", Content: "first", MessageID: "provider-7", SentAt: sentAt, ClaimToken: "t0k3n", ProviderStatusCode: 202,
+			ProviderResponse: json.RawMessage(`{"messageId":"provider-7"}`)},
+		{ID: "8", Content: "unsent"},
+		{ID: "9", Content: "third", MessageID: "provider-9", SentAt: sentAt},
+	}
+
+	mock.ExpectQuery(`FROM unnest\(\$1::integer\[\], \$2::varchar\[\], \$3::timestamp\[\], \$4::text\[\], \$5::varchar\[\],\s+\$6::integer\[\], \$7::text\[\]\)`).
+		WithArgs(pq.Array([]int32{7, 9}), pq.Array([]string{"provider-7", "provider-9"}), pq.Array([]time.Time{sentAt, sentAt}),
+			pq.Array([]string{"first", "third"}), pq.Array([]string{"t0k3n", ""}), pq.Array([]int32{202, 0}),
+			pq.Array([]string{`{"messageId":"provider-7"}`, ""})).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int32(7)))
+
+	lost, err := repo.SaveAll(context.Background(), msgs)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*message.Message{msgs[2]}, lost, "the claim of message 9 was taken over")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_SaveAll_NothingSent(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	lost, err := repo.SaveAll(context.Background(), []*message.Message{{ID: "8", Content: "unsent"}})
+
+	require.NoError(t, err)
+	assert.Empty(t, lost)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Save_ClaimLost(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	sentAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	msg := &message.Message{ID: "7", MessageID: "provider-1", SentAt: sentAt, ClaimToken: "t0k3n", ProviderStatusCode: 202,
+		ProviderResponse: json.RawMessage(`{"messageId":"provider-1"}`)}
+
+	mock.ExpectExec(`claim_token IS NOT DISTINCT FROM \$7`).
+		WithArgs(int32(7), sql.NullString{String: "provider-1", Valid: true}, sql.NullTime{Time: sentAt, Valid: true}, "",
+			sql.NullInt32{Int32: 202, Valid: true}, sql.NullString{String: `{"messageId":"provider-1"}`, Valid: true},
+			sql.NullString{String: "t0k3n", Valid: true}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.Save(context.Background(), msg)
+
+	assert.ErrorIs(t, err, message.ErrClaimLost)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_GetNextUnsent_SkipsMessagesNotDue(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(`status = 'pending'\s+AND \(\$1::varchar IS NULL OR tenant_id = \$1\)\s+AND \(send_at IS NULL OR send_at <= LOCALTIMESTAMP\)\s+` +
+		`AND \(next_attempt_at IS NULL OR next_attempt_at <= LOCALTIMESTAMP\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "tenant_id", "attempts", "last_error", "priority", "send_at",
+			"expires_at", "template_name", "template_vars", "channel"}).
+			AddRow(int32(7), "+905551234567", "hello", "default", int32(1), "provider down", int32(0), nil, nil, nil, []byte("{}"), "sms"))
+
+	msg, err := repo.GetNextUnsent(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, 1, msg.Attempts)
+	assert.Equal(t, "provider down", msg.LastError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_GetNextUnsent_HighestPriorityFirst(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(`ORDER BY priority DESC, created_at`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "tenant_id", "attempts", "last_error", "priority", "send_at",
+			"expires_at", "template_name", "template_vars", "channel"}).
+			AddRow(int32(9), "+905551234567", "urgent", "default", int32(0), nil, int32(50), nil, nil, nil, []byte("{}"), "sms"))
+
+	msg, err := repo.GetNextUnsent(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, 50, msg.Priority)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Expire(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	expiredAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	msg := &message.Message{ID: "7", ExpiredAt: expiredAt, ClaimToken: "mine"}
+
+	// the claim held by the expiring instance does not keep the message from expiring
+	mock.ExpectExec(`SET expired_at    = \$2`).
+		WithArgs(int32(7), sql.NullTime{Time: expiredAt, Valid: true}, sql.NullString{String: "mine", Valid: true}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.Expire(context.Background(), msg))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_FindFailed(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	failedAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("WHERE status = 'failed'").
+		WithArgs("acme", sql.NullString{}, sql.NullString{}, sql.NullInt32{Int32: 10, Valid: true}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "tenant_id", "attempts", "last_error", "failed_at", "channel"}).
+			AddRow(int32(7), "+905551234567", "hello", "acme", int32(5), "provider down", failedAt, "sms"))
+
+	msgs, err := repo.FindFailed(message.WithTenant(context.Background(), "acme"), message.Filter{Limit: 10})
+
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, 5, msgs[0].Attempts)
+	assert.Equal(t, "provider down", msgs[0].LastError)
+	assert.True(t, msgs[0].IsFailed())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_Requeue(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		setup   func(sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{
+			name: "failed message",
+			id:   "7",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE message").WithArgs(int32(7), sql.NullString{}).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "no failed message with the ID",
+			id:   "8",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE message").WithArgs(int32(8), sql.NullString{}).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: message.ErrMessageNotFound,
+		},
+		{
+			name:    "non-numeric ID",
+			id:      "abc",
+			setup:   func(sqlmock.Sqlmock) {},
+			wantErr: message.ErrMessageNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepository(t)
+			tt.setup(mock)
+
+			err := repo.Requeue(context.Background(), tt.id)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMessageRepository_Delete(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		setup   func(sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{
+			name: "sent message",
+			id:   "7",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`SET deleted_at = LOCALTIMESTAMP`).
+					WithArgs(int32(7), sql.NullString{String: "acme", Valid: true}).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "pending, deleted or missing message",
+			id:   "8",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`SET deleted_at = LOCALTIMESTAMP`).
+					WithArgs(int32(8), sql.NullString{String: "acme", Valid: true}).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: message.ErrMessageNotFound,
+		},
+		{
+			name:    "non-numeric ID",
+			id:      "abc",
+			setup:   func(sqlmock.Sqlmock) {},
+			wantErr: message.ErrMessageNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepository(t)
+			tt.setup(mock)
+
+			err := repo.Delete(message.WithTenant(context.Background(), "acme"), tt.id)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMessageRepository_GetNextUnsent_Templated(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(`template_name, template_vars`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "tenant_id", "attempts", "last_error", "priority", "send_at",
+			"expires_at", "template_name", "template_vars", "channel"}).
+			AddRow(int32(7), "+905551234567", "", "default", int32(0), nil, int32(0), nil, nil, "welcome", []byte(`{"name":"Ada"}`), "sms"))
+
+	msg, err := repo.GetNextUnsent(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, "welcome", msg.Template)
+	assert.Equal(t, map[string]string{"name": "Ada"}, msg.Variables)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_GetNextUnsent_Channel(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(`template_name, template_vars, channel`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "tenant_id", "attempts", "last_error", "priority", "send_at",
+			"expires_at", "template_name", "template_vars", "channel"}).
+			AddRow(int32(7), "ada@example.com", "hello", "default", int32(0), nil, int32(0), nil, nil, nil, []byte("{}"), "email"))
+
+	msg, err := repo.GetNextUnsent(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	assert.Equal(t, message.ChannelEmail, msg.Channel)
+	assert.Equal(t, "ada@example.com", msg.To)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_GetUnsent(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(`ORDER BY priority DESC, created_at\s+LIMIT \$2`).
+		WithArgs("acme", int32(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "content", "tenant_id", "attempts", "last_error", "priority", "send_at",
+			"expires_at", "template_name", "template_vars", "channel"}).
+			AddRow(int32(9), "+905551234567", "urgent", "acme", int32(0), nil, int32(50), nil, nil, nil, []byte("{}"), "sms").
+			AddRow(int32(7), "+905551234568", "hello", "acme", int32(1), "provider down", int32(0), nil, nil, nil, []byte("{}"), "sms"))
+
+	msgs, err := repo.GetUnsent(message.WithTenant(context.Background(), "acme"), 2)
+
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "9", msgs[0].ID)
+	assert.Equal(t, 50, msgs[0].Priority)
+	assert.Equal(t, "provider down", msgs[1].LastError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMessageRepository_SaveDeliveryReport(t *testing.T) {
+	reportedAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		expected     error
+	}{
+		{name: "sent message", rowsAffected: 1},
+		{name: "unknown provider message ID", rowsAffected: 0, expected: message.ErrMessageNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepository(t)
+
+			mock.ExpectExec(`SET delivery_status(.|\n)+WHERE message_id = \$4\s+AND sent_at NOTNULL`).
+				WithArgs(sql.NullString{String: "undelivered", Valid: true}, sql.NullTime{Time: reportedAt, Valid: true},
+					sql.NullString{String: "absent subscriber", Valid: true}, sql.NullString{String: "ext-7", Valid: true}).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			err := repo.SaveDeliveryReport(context.Background(), &message.DeliveryReport{
+				MessageID:  "ext-7",
+				Status:     message.DeliveryUndelivered,
+				ReportedAt: reportedAt,
+				Reason:     "absent subscriber",
+			})
+
+			assert.ErrorIs(t, err, tt.expected)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMessageRepository_Cancel(t *testing.T) {
+	canceledAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		want         bool
+	}{
+		{name: "pending", rowsAffected: 1, want: true},
+		{name: "claimed or no longer pending", rowsAffected: 0, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepository(t)
+			msg := &message.Message{ID: "7", CanceledAt: canceledAt}
+
+			mock.ExpectExec(`SET canceled_at = \$1(.|\n)+claimed_until <= LOCALTIMESTAMP`).
+				WithArgs(sql.NullTime{Time: canceledAt, Valid: true}, int32(7), "acme").
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			canceled, err := repo.Cancel(message.WithTenant(context.Background(), "acme"), msg)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, canceled)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMessageRepository_TracesQueries(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	repo, mock := newMockRepository(t)
+	columns := []string{"sent_count", "unsent_count", "failed_count", "expired_count", "canceled_count", "rejected_count",
+		"sent_last_hour", "sent_last_day", "avg_latency_seconds"}
+	mock.ExpectQuery(`LOCALTIMESTAMP - INTERVAL '1 hour'`).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(10, 4, 1, 3, 5, 6, 2, 7, 1.5))
+	mock.ExpectExec("UPDATE message").WillReturnError(errors.New("connection reset"))
+
+	_, err := repo.GetStats(context.Background())
+	require.NoError(t, err)
+	_, err = repo.Cancel(context.Background(), &message.Message{ID: "42", CanceledAt: time.Now()})
+	require.Error(t, err)
+
+	// spans are named after the sqlc query
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "GetStats", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.system", "postgresql"))
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, "CancelMessage", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
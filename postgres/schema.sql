@@ -1,3 +1,6 @@
+-- trigram indexes serve the content search of sent messages
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 CREATE TABLE IF NOT EXISTS message
 (
     id         SERIAL PRIMARY KEY,
@@ -5,6 +8,124 @@ CREATE TABLE IF NOT EXISTS message
     content    TEXT    NOT NULL,
     message_id VARCHAR(100),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    sent_at    TIMESTAMP
+    sent_at    TIMESTAMP,
+    idempotency_key VARCHAR(255),
+    tenant_id       VARCHAR(64) NOT NULL DEFAULT 'default',
+    attempts        INT         NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMP,
+    failed_at       TIMESTAMP,
+    claim_token     VARCHAR(64),
+    claimed_until   TIMESTAMP,
+    priority        INT         NOT NULL DEFAULT 0,
+    send_at         TIMESTAMP,
+    expires_at      TIMESTAMP,
+    expired_at      TIMESTAMP,
+    canceled_at     TIMESTAMP,
+    template_name   VARCHAR(100),
+    template_vars   JSONB       NOT NULL DEFAULT '{}',
+    channel         VARCHAR(16) NOT NULL DEFAULT 'sms',
+    delivery_status      VARCHAR(16),
+    delivery_reported_at TIMESTAMP,
+    delivery_error       TEXT,
+    rejected_at          TIMESTAMP,
+    -- derived from the timestamps above, so the queue can be indexed by state without every update maintaining it
+    status               VARCHAR(16) NOT NULL GENERATED ALWAYS AS (CASE
+        WHEN sent_at IS NOT NULL THEN 'sent'
+        WHEN failed_at IS NOT NULL THEN 'failed'
+        WHEN rejected_at IS NOT NULL THEN 'rejected'
+        WHEN canceled_at IS NOT NULL THEN 'canceled'
+        WHEN expired_at IS NOT NULL THEN 'expired'
+        ELSE 'pending' END) STORED,
+    -- set when a finished message is deleted; reads skip it and the retention job drops it instead of archiving it,
+    -- once it was sent or, if it never was, deleted longer ago than the retention period
+    deleted_at           TIMESTAMP,
+    -- what the provider answered when it accepted the message, to settle disputes over whether it did
+    provider_status_code INTEGER,
+    provider_response    TEXT,
+    UNIQUE (tenant_id, idempotency_key)
+
+);
+
+CREATE INDEX IF NOT EXISTS message_pending_priority_idx ON message (priority DESC, created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS message_message_id_idx ON message (message_id) WHERE message_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS message_sent_at_id_idx ON message (sent_at, id) WHERE sent_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS message_pending_created_at_id_idx ON message (created_at, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS message_failed_at_id_idx ON message (failed_at DESC, id DESC) WHERE status = 'failed';
+CREATE INDEX IF NOT EXISTS message_retention_idx ON message ((COALESCE(sent_at, deleted_at))) WHERE sent_at IS NOT NULL OR deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS message_sent_recipient_idx ON message (recipient, sent_at, id) WHERE sent_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS message_sent_content_trgm_idx ON message USING gin (content gin_trgm_ops) WHERE sent_at IS NOT NULL;
+
+-- sent messages moved out of message once older than the retention period
+CREATE TABLE IF NOT EXISTS message_archive
+(
+    id                   INTEGER PRIMARY KEY,
+    recipient            VARCHAR     NOT NULL,
+    content              TEXT        NOT NULL,
+    message_id           VARCHAR(100),
+    created_at           TIMESTAMP,
+    sent_at              TIMESTAMP   NOT NULL,
+    tenant_id            VARCHAR(64) NOT NULL,
+    attempts             INT         NOT NULL,
+    priority             INT         NOT NULL,
+    template_name        VARCHAR(100),
+    template_vars        JSONB       NOT NULL,
+    channel              VARCHAR(16) NOT NULL,
+    delivery_status      VARCHAR(16),
+    delivery_reported_at TIMESTAMP,
+    delivery_error       TEXT,
+    archived_at          TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    provider_status_code INTEGER,
+    provider_response    TEXT
+);
+
+CREATE TABLE IF NOT EXISTS subscription
+(
+    id         SERIAL PRIMARY KEY,
+    url        VARCHAR     NOT NULL,
+    secret     VARCHAR     NOT NULL,
+    events     VARCHAR[]   NOT NULL,
+    tenant_id  VARCHAR(64) NOT NULL DEFAULT 'default',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS subscription_tenant_id_idx ON subscription (tenant_id);
+
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id          BIGSERIAL PRIMARY KEY,
+    action      VARCHAR(64)  NOT NULL,
+    actor       VARCHAR(255) NOT NULL DEFAULT '',
+    api_key     VARCHAR(16)  NOT NULL DEFAULT '',
+    request_id  VARCHAR(64)  NOT NULL DEFAULT '',
+    remote_addr VARCHAR(64)  NOT NULL DEFAULT '',
+    details     TEXT         NOT NULL DEFAULT '',
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_action_id_idx ON audit_log (action, id);
+
+-- every try to deliver a message, removed along with the message
+CREATE TABLE IF NOT EXISTS message_attempt
+(
+    id          BIGSERIAL PRIMARY KEY,
+    message_id  INTEGER   NOT NULL REFERENCES message (id) ON DELETE CASCADE,
+    started_at  TIMESTAMP NOT NULL,
+    duration_ms INTEGER   NOT NULL,
+    status_code INTEGER,
+    error       TEXT
+);
+
+CREATE INDEX IF NOT EXISTS message_attempt_message_id_started_at_idx ON message_attempt (message_id, started_at);
+
+-- wakes the instances listening on message_inserted, once per statement however many rows it inserted
+CREATE OR REPLACE FUNCTION notify_message_inserted() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('message_inserted', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
 
-);
\ No newline at end of file
+CREATE OR REPLACE TRIGGER message_inserted
+    AFTER INSERT ON message
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_message_inserted();
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/postgres/gen"
+	"github.com/pkg/errors"
+)
+
+// SubscriptionRepository implements message.SubscriptionRepository for PostgreSQL storage.
+type SubscriptionRepository struct {
+	queries *gen.Queries // queries bound to the connection pool
+}
+
+var _ message.SubscriptionRepository = (*SubscriptionRepository)(nil)
+
+// NewSubscriptionRepository constructs a new PostgreSQL implementation of message.SubscriptionRepository
+func NewSubscriptionRepository(db *sql.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{
+		queries: traced(db),
+	}
+}
+
+// CreateSubscription inserts a new subscription for the tenant ctx is scoped to
+// and returns it with its database ID and creation time.
+func (r *SubscriptionRepository) CreateSubscription(ctx context.Context, sub *message.Subscription) (*message.Subscription, error) {
+	tenant := sub.Tenant
+	if tenant == "" {
+		tenant = message.DefaultTenant
+		if t, ok := message.TenantFromContext(ctx); ok {
+			tenant = t
+		}
+	}
+	res, err := r.queries.CreateSubscription(ctx, gen.CreateSubscriptionParams{
+		Url:      sub.URL,
+		Secret:   sub.Secret,
+		Events:   sub.Events,
+		TenantID: tenant,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating subscription")
+	}
+	created := *sub
+	created.ID = strID(res.ID)
+	created.Tenant = tenant
+	created.CreatedAt = res.CreatedAt.Time
+	return &created, nil
+}
+
+// ListSubscriptions retrieves the subscriptions of the tenant ctx is scoped to, or of every tenant for unscoped contexts.
+func (r *SubscriptionRepository) ListSubscriptions(ctx context.Context) ([]*message.Subscription, error) {
+	res, err := r.queries.ListSubscriptions(ctx, tenantFilter(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing subscriptions")
+	}
+	ret := make([]*message.Subscription, len(res))
+	for i, s := range res {
+		ret[i] = &message.Subscription{
+			ID:        strID(s.ID),
+			URL:       s.Url,
+			Secret:    s.Secret,
+			Events:    s.Events,
+			Tenant:    s.TenantID,
+			CreatedAt: s.CreatedAt.Time,
+		}
+	}
+	return ret, nil
+}
+
+// DeleteSubscription removes a subscription of the tenant ctx is scoped to.
+// Returns message.ErrSubscriptionNotFound if no such subscription exists.
+func (r *SubscriptionRepository) DeleteSubscription(ctx context.Context, id string) error {
+	intID, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		// non-numeric or out of range IDs can never match a row
+		return message.ErrSubscriptionNotFound
+	}
+	n, err := r.queries.DeleteSubscription(ctx, gen.DeleteSubscriptionParams{
+		ID:       int32(intID),
+		TenantID: tenantFilter(ctx),
+	})
+	if err != nil {
+		return errors.Wrap(err, "deleting subscription")
+	}
+	if n == 0 {
+		return message.ErrSubscriptionNotFound
+	}
+	return nil
+}
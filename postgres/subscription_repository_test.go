@@ -0,0 +1,61 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/postgres"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockSubscriptionRepository returns a SubscriptionRepository backed by sqlmock.
+func newMockSubscriptionRepository(t *testing.T) (*postgres.SubscriptionRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return postgres.NewSubscriptionRepository(db), mock
+}
+
+func TestSubscriptionRepository_CreateSubscription(t *testing.T) {
+	repo, mock := newMockSubscriptionRepository(t)
+	createdAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	events := []string{message.EventMessageSent}
+
+	mock.ExpectQuery("INSERT INTO subscription").
+		WithArgs("https://example.com/hooks", "s3cret", pq.Array(events), "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, createdAt))
+
+	sub, err := repo.CreateSubscription(message.WithTenant(context.Background(), "acme"),
+		&message.Subscription{URL: "https://example.com/hooks", Secret: "s3cret", Events: events})
+
+	require.NoError(t, err)
+	assert.Equal(t, &message.Subscription{
+		ID:        "5",
+		URL:       "https://example.com/hooks",
+		Secret:    "s3cret",
+		Events:    events,
+		Tenant:    "acme",
+		CreatedAt: createdAt,
+	}, sub)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscriptionRepository_DeleteSubscription(t *testing.T) {
+	repo, mock := newMockSubscriptionRepository(t)
+	ctx := message.WithTenant(context.Background(), "acme")
+
+	mock.ExpectExec("DELETE FROM subscription").WithArgs(int32(5), "acme").WillReturnResult(sqlmock.NewResult(0, 1))
+	// another tenant's subscription is not deleted
+	mock.ExpectExec("DELETE FROM subscription").WithArgs(int32(6), "acme").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.DeleteSubscription(ctx, "5"))
+	assert.ErrorIs(t, repo.DeleteSubscription(ctx, "6"), message.ErrSubscriptionNotFound)
+	assert.ErrorIs(t, repo.DeleteSubscription(ctx, "not-a-number"), message.ErrSubscriptionNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/grustamli/insider-msg-sender/postgres/gen"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records a span per query. It is a no-op until a TracerProvider is installed, e.g. by tracing.Setup.
+var tracer = otel.Tracer("github.com/grustamli/insider-msg-sender/postgres")
+
+// tracedDB wraps a connection pool or transaction, recording a client span for every query run on it,
+// named after the sqlc query, e.g. "GetNextUnsent".
+type tracedDB struct {
+	db gen.DBTX // connection pool or transaction running the queries
+}
+
+var _ gen.DBTX = tracedDB{}
+
+// traced returns queries bound to db that record a span per query.
+func traced(db gen.DBTX) *gen.Queries {
+	return gen.New(tracedDB{db: db})
+}
+
+// ExecContext runs query in a span.
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	res, err := t.db.ExecContext(ctx, query, args...)
+	endQuerySpan(span, err)
+	return res, err
+}
+
+// PrepareContext prepares query in a span.
+func (t tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	stmt, err := t.db.PrepareContext(ctx, query)
+	endQuerySpan(span, err)
+	return stmt, err
+}
+
+// QueryContext runs query in a span that ends once the query returned, before its rows are read.
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	endQuerySpan(span, err)
+	return rows, err
+}
+
+// QueryRowContext runs query in a span. Errors only surfacing when the row is scanned are not recorded.
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	row := t.db.QueryRowContext(ctx, query, args...)
+	endQuerySpan(span, row.Err())
+	return row
+}
+
+// startQuerySpan starts the client span of query, named after the sqlc query it is.
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	return startSpan(ctx, queryName(query))
+}
+
+// startSpan starts the client span of the database operation name, e.g. a statement sqlc does not generate.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation.name", name),
+	))
+}
+
+// endQuerySpan records err, unless nil or sql.ErrNoRows, as the error of span and ends it.
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// queryName returns the name sqlc gave query in its leading "-- name: <Name> :<kind>" comment,
+// or "query" if it has none.
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return "query"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
@@ -5,6 +5,8 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"slices"
+	"time"
 
 	"github.com/grustamli/insider-msg-sender/message"
 	"github.com/pkg/errors"
@@ -12,12 +14,12 @@ import (
 )
 
 // CacheRepository wraps a message.Repository and adds Redis-based caching
-// for sent messages under a specified key.
+// for sent messages, keeping one list per tenant under "<key>:<tenant>".
 // It delegates unsent operations to the underlying repository.
 type CacheRepository struct {
 	message.Repository               // underlying repository for persistence
 	rdb                *redis.Client // Redis client instance
-	key                string        // Redis list key for caching sent messages
+	key                string        // prefix of the Redis list keys caching sent messages
 }
 
 var _ message.Repository = (*CacheRepository)(nil) // ensure interface compliance
@@ -41,11 +43,79 @@ func (c *CacheRepository) Save(ctx context.Context, msg *message.Message) error
 	return c.saveMessageToCache(ctx, msg)
 }
 
-// GetAllSent returns all sent messages from cache if present;
+// SaveAll persists the sent status of msgs via the underlying repository and then caches the sent messages that were
+// stored, leaving out those not marked sent and those whose claim was lost.
+func (c *CacheRepository) SaveAll(ctx context.Context, msgs []*message.Message) ([]*message.Message, error) {
+	lost, err := c.Repository.SaveAll(ctx, msgs)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		if !msg.IsSent() || slices.Contains(lost, msg) {
+			continue
+		}
+		if err := c.saveMessageToCache(ctx, msg); err != nil {
+			return lost, err
+		}
+	}
+	return lost, nil
+}
+
+// ArchiveSent archives old sent messages via the underlying repository and, if any were archived, drops the cached
+// sent messages, so the cache is repopulated with the remaining ones on the next read.
+func (c *CacheRepository) ArchiveSent(ctx context.Context, olderThan time.Duration, n int) (int64, error) {
+	archived, err := c.Repository.ArchiveSent(ctx, olderThan, n)
+	if err != nil || archived == 0 {
+		return archived, err
+	}
+	return archived, c.Flush(ctx)
+}
+
+// Delete deletes a message via the underlying repository and drops the cached sent messages, which may include it.
+func (c *CacheRepository) Delete(ctx context.Context, id string) error {
+	if err := c.Repository.Delete(ctx, id); err != nil {
+		return err
+	}
+	return c.Flush(ctx)
+}
+
+// WithTx runs fn in a transaction of the underlying repository, caching sent messages saved through the Repository
+// handed to fn like Save does. The writes are cached before the transaction is committed, so if it is rolled back the
+// cache is flushed rather than left with messages that were never stored.
+func (c *CacheRepository) WithTx(ctx context.Context, fn func(message.Repository) error) error {
+	err := c.Repository.WithTx(ctx, func(tx message.Repository) error {
+		return fn(NewCacheRepository(c.rdb, c.key, tx))
+	})
+	if err == nil {
+		return nil
+	}
+	if ferr := c.Flush(ctx); ferr != nil {
+		return errors.Wrapf(ferr, "flushing cache after %v", err)
+	}
+	return err
+}
+
+// DeleteSent deletes old sent messages via the underlying repository and, if any were deleted, drops the cached
+// sent messages like ArchiveSent.
+func (c *CacheRepository) DeleteSent(ctx context.Context, olderThan time.Duration, n int) (int64, error) {
+	deleted, err := c.Repository.DeleteSent(ctx, olderThan, n)
+	if err != nil || deleted == 0 {
+		return deleted, err
+	}
+	return deleted, c.Flush(ctx)
+}
+
+// GetAllSent returns the sent messages of the tenant ctx is scoped to from cache if present;
 // otherwise, it falls back to the underlying repository, caches the results, then returns them.
+// Unscoped reads span every tenant and always go to the underlying repository.
 func (c *CacheRepository) GetAllSent(ctx context.Context) ([]*message.SentMessage, error) {
+	tenant, ok := message.TenantFromContext(ctx)
+	if !ok {
+		return c.Repository.GetAllSent(ctx)
+	}
+	key := c.tenantKey(tenant)
 	// attempt to read from cache
-	msgs, err := c.getMessagesFromCache(ctx)
+	msgs, err := c.getMessagesFromCache(ctx, key)
 	if err != nil {
 		return nil, err
 	}
@@ -58,45 +128,127 @@ func (c *CacheRepository) GetAllSent(ctx context.Context) ([]*message.SentMessag
 		return nil, err
 	}
 	// populate cache for future calls
-	if err := c.saveAllToCache(ctx, msgs); err != nil {
+	if err := c.saveAllToCache(ctx, key, msgs); err != nil {
 		return nil, err
 	}
 	return msgs, nil
 }
 
+// Flush removes all cached sent messages of every tenant. The next read repopulates the cache from the underlying repository.
+func (c *CacheRepository) Flush(ctx context.Context) error {
+	keys, err := c.tenantKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := c.rdb.Del(ctx, keys...).Err(); err != nil {
+		return errors.Wrap(err, "flushing cache")
+	}
+	return nil
+}
+
+// Rebuild replaces the cached sent messages with the current contents of the underlying repository.
+// The old entries are dropped and the new ones pushed in a single transaction, so readers never see a partial list.
+// It returns the number of cached messages.
+func (c *CacheRepository) Rebuild(ctx context.Context) (int, error) {
+	msgs, err := c.Repository.GetAllSent(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byKey := make(map[string][]*message.SentMessage)
+	for _, m := range msgs {
+		key := c.tenantKey(m.Tenant)
+		byKey[key] = append(byKey[key], m)
+	}
+	stale, err := c.tenantKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+	_, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(stale) > 0 {
+			pipe.Del(ctx, stale...)
+		}
+		for key, group := range byKey {
+			items, err := marshalMessages(group)
+			if err != nil {
+				return err
+			}
+			pipe.LPush(ctx, key, items...)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "rebuilding cache")
+	}
+	return len(msgs), nil
+}
+
 // saveMessageToCache serializes a single SentMessage and pushes it onto the Redis list.
 func (c *CacheRepository) saveMessageToCache(ctx context.Context, msg *message.Message) error {
-	data, err := json.Marshal(&message.SentMessage{MessageID: msg.MessageID, SentAt: msg.SentAt})
+	data, err := json.Marshal(&message.SentMessage{
+		ID:        msg.ID,
+		To:        msg.To,
+		Content:   msg.Content,
+		MessageID: msg.MessageID,
+		SentAt:    msg.SentAt,
+		Tenant:    msg.Tenant,
+	})
 	if err != nil {
 		return err
 	}
-	if err := c.rdb.LPush(ctx, c.key, data).Err(); err != nil {
+	if err := c.rdb.LPush(ctx, c.tenantKey(msg.Tenant), data).Err(); err != nil {
 		return errors.Wrap(err, "adding message to cache")
 	}
 	return nil
 }
 
-// saveAllToCache serializes multiple SentMessage entries and pushes them all onto the Redis list.
-func (c *CacheRepository) saveAllToCache(ctx context.Context, msgs []*message.SentMessage) error {
+// saveAllToCache serializes multiple SentMessage entries and pushes them all onto the Redis list at key.
+func (c *CacheRepository) saveAllToCache(ctx context.Context, key string, msgs []*message.SentMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
 	items, err := marshalMessages(msgs)
 	if err != nil {
 		return err
 	}
-	if err := c.rdb.LPush(ctx, c.key, items...).Err(); err != nil {
+	if err := c.rdb.LPush(ctx, key, items...).Err(); err != nil {
 		return errors.Wrap(err, "adding messages to cache")
 	}
 	return nil
 }
 
-// getMessagesFromCache reads all entries from the Redis list and deserializes them into SentMessage objects.
-func (c *CacheRepository) getMessagesFromCache(ctx context.Context) ([]*message.SentMessage, error) {
-	entries, err := c.rdb.LRange(ctx, c.key, 0, -1).Result()
+// getMessagesFromCache reads all entries from the Redis list at key and deserializes them into SentMessage objects.
+func (c *CacheRepository) getMessagesFromCache(ctx context.Context, key string) ([]*message.SentMessage, error) {
+	entries, err := c.rdb.LRange(ctx, key, 0, -1).Result()
 	if err != nil {
 		return nil, errors.Wrap(err, "getting sent messages from cache")
 	}
 	return unmarshalMessageStrings(entries)
 }
 
+// tenantKey returns the Redis list key caching the sent messages of tenant.
+func (c *CacheRepository) tenantKey(tenant string) string {
+	if tenant == "" {
+		tenant = message.DefaultTenant
+	}
+	return c.key + ":" + tenant
+}
+
+// tenantKeys lists the cache keys of every tenant, found by scanning for the key prefix.
+func (c *CacheRepository) tenantKeys(ctx context.Context) ([]string, error) {
+	var keys []string
+	iter := c.rdb.Scan(ctx, 0, c.key+":*", 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, errors.Wrap(err, "listing cache keys")
+	}
+	return keys, nil
+}
+
 // marshalMessages serializes each SentMessage into JSON for Redis storage.
 func marshalMessages(msgs []*message.SentMessage) ([]any, error) {
 	ret := make([]any, len(msgs))
@@ -0,0 +1,251 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubRepository serves sent messages from memory and counts reads.
+// Methods the cache does not decorate are left to the embedded nil interface.
+type stubRepository struct {
+	message.Repository
+	sent    []*message.SentMessage
+	reads   int
+	saveErr error
+	lost    []*message.Message // messages SaveAll reports as claimed elsewhere
+}
+
+func (s *stubRepository) GetAllSent(ctx context.Context) ([]*message.SentMessage, error) {
+	s.reads++
+	tenant, ok := message.TenantFromContext(ctx)
+	if !ok {
+		return s.sent, nil
+	}
+	var ret []*message.SentMessage
+	for _, m := range s.sent {
+		if m.Tenant == tenant {
+			ret = append(ret, m)
+		}
+	}
+	return ret, nil
+}
+
+// ArchiveSent archives the first n sent messages, whatever their age.
+func (s *stubRepository) ArchiveSent(_ context.Context, _ time.Duration, n int) (int64, error) {
+	n = min(n, len(s.sent))
+	s.sent = s.sent[n:]
+	return int64(n), nil
+}
+
+func (s *stubRepository) Save(_ context.Context, _ *message.Message) error {
+	return s.saveErr
+}
+
+func (s *stubRepository) SaveAll(_ context.Context, _ []*message.Message) ([]*message.Message, error) {
+	return s.lost, s.saveErr
+}
+
+// WithTx calls fn with the stub itself, as if it were bound to a transaction.
+func (s *stubRepository) WithTx(_ context.Context, fn func(message.Repository) error) error {
+	return fn(s)
+}
+
+// newTestCache returns a CacheRepository over repo backed by an in-memory Redis server.
+func newTestCache(t *testing.T, repo message.Repository) (*redis.CacheRepository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return redis.NewCacheRepository(rdb, "sent", repo), mr
+}
+
+func sentMessage(id, tenant string) *message.SentMessage {
+	return &message.SentMessage{
+		ID:        id,
+		To:        "+905551234567",
+		Content:   "hello " + id,
+		MessageID: "ext-" + id,
+		SentAt:    time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+		Tenant:    tenant,
+	}
+}
+
+func TestCacheRepository_GetAllSent_CachesPerTenant(t *testing.T) {
+	repo := &stubRepository{sent: []*message.SentMessage{sentMessage("1", "acme"), sentMessage("2", "globex")}}
+	cache, mr := newTestCache(t, repo)
+	ctx := message.WithTenant(context.Background(), "acme")
+
+	first, err := cache.GetAllSent(ctx)
+	require.NoError(t, err)
+	second, err := cache.GetAllSent(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*message.SentMessage{sentMessage("1", "acme")}, first)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, repo.reads, "the second read is served from the cache")
+	assert.True(t, mr.Exists("sent:acme"))
+	assert.False(t, mr.Exists("sent:globex"))
+}
+
+func TestCacheRepository_GetAllSent_UnscopedBypassesCache(t *testing.T) {
+	repo := &stubRepository{sent: []*message.SentMessage{sentMessage("1", "acme"), sentMessage("2", "globex")}}
+	cache, mr := newTestCache(t, repo)
+
+	msgs, err := cache.GetAllSent(context.Background())
+
+	require.NoError(t, err)
+	assert.Len(t, msgs, 2)
+	assert.Empty(t, mr.Keys())
+}
+
+func TestCacheRepository_Save(t *testing.T) {
+	repo := &stubRepository{}
+	cache, mr := newTestCache(t, repo)
+	msg := &message.Message{ID: "1", To: "+905551234567", Content: "hello 1", Tenant: "acme"}
+	require.NoError(t, msg.SetSent("ext-1", time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+
+	require.NoError(t, cache.Save(context.Background(), msg))
+
+	msgs, err := cache.GetAllSent(message.WithTenant(context.Background(), "acme"))
+	require.NoError(t, err)
+	assert.Equal(t, []*message.SentMessage{sentMessage("1", "acme")}, msgs)
+	assert.Zero(t, repo.reads)
+
+	// nothing is cached when persisting fails
+	repo.saveErr = errors.New("database connection failed")
+	mr.FlushAll()
+	require.ErrorIs(t, cache.Save(context.Background(), msg), repo.saveErr)
+	assert.Empty(t, mr.Keys())
+}
+
+func TestCacheRepository_SaveAll(t *testing.T) {
+	repo := &stubRepository{}
+	cache, mr := newTestCache(t, repo)
+	sentAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	msgs := make([]*message.Message, 4)
+	for i := range msgs {
+		id := strconv.Itoa(i + 1)
+		msgs[i] = &message.Message{ID: id, To: "+905551234567", Content: "hello " + id, Tenant: "acme"}
+	}
+	require.NoError(t, msgs[0].SetSent("ext-1", sentAt))
+	require.NoError(t, msgs[1].SetSent("ext-2", sentAt))
+	require.NoError(t, msgs[2].SetSent("ext-3", sentAt))
+	// the third message was claimed elsewhere and the fourth was not sent
+	repo.lost = []*message.Message{msgs[2]}
+
+	lost, err := cache.SaveAll(context.Background(), msgs)
+
+	require.NoError(t, err)
+	assert.Equal(t, repo.lost, lost)
+	cached, err := cache.GetAllSent(message.WithTenant(context.Background(), "acme"))
+	require.NoError(t, err)
+	assert.Equal(t, []*message.SentMessage{sentMessage("2", "acme"), sentMessage("1", "acme")}, cached)
+	assert.Zero(t, repo.reads)
+
+	// nothing is cached when persisting fails
+	repo.saveErr = errors.New("database connection failed")
+	mr.FlushAll()
+	_, err = cache.SaveAll(context.Background(), msgs)
+	require.ErrorIs(t, err, repo.saveErr)
+	assert.Empty(t, mr.Keys())
+}
+
+func TestCacheRepository_WithTx(t *testing.T) {
+	repo := &stubRepository{}
+	cache, mr := newTestCache(t, repo)
+	msg := &message.Message{ID: "1", To: "+905551234567", Content: "hello 1", Tenant: "acme"}
+	require.NoError(t, msg.SetSent("ext-1", time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+
+	err := cache.WithTx(context.Background(), func(tx message.Repository) error {
+		return tx.Save(context.Background(), msg)
+	})
+
+	require.NoError(t, err)
+	msgs, err := cache.GetAllSent(message.WithTenant(context.Background(), "acme"))
+	require.NoError(t, err)
+	assert.Equal(t, []*message.SentMessage{sentMessage("1", "acme")}, msgs)
+
+	// messages cached within a transaction that is rolled back are dropped
+	rollback := errors.New("rolled back")
+	err = cache.WithTx(context.Background(), func(tx message.Repository) error {
+		require.NoError(t, tx.Save(context.Background(), msg))
+		return rollback
+	})
+
+	require.ErrorIs(t, err, rollback)
+	assert.Empty(t, mr.Keys())
+}
+
+func TestCacheRepository_Flush(t *testing.T) {
+	repo := &stubRepository{sent: []*message.SentMessage{sentMessage("1", "acme"), sentMessage("2", "globex")}}
+	cache, mr := newTestCache(t, repo)
+	for _, tenant := range []string{"acme", "globex"} {
+		_, err := cache.GetAllSent(message.WithTenant(context.Background(), tenant))
+		require.NoError(t, err)
+	}
+	require.NoError(t, mr.Set("unrelated", "kept"))
+
+	require.NoError(t, cache.Flush(context.Background()))
+
+	assert.Equal(t, []string{"unrelated"}, mr.Keys())
+	// the next read repopulates the cache from the repository
+	_, err := cache.GetAllSent(message.WithTenant(context.Background(), "acme"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, repo.reads)
+}
+
+func TestCacheRepository_ArchiveSent_DropsCache(t *testing.T) {
+	repo := &stubRepository{sent: []*message.SentMessage{sentMessage("1", "acme"), sentMessage("2", "acme")}}
+	cache, _ := newTestCache(t, repo)
+	ctx := message.WithTenant(context.Background(), "acme")
+	_, err := cache.GetAllSent(ctx)
+	require.NoError(t, err)
+
+	archived, err := cache.ArchiveSent(context.Background(), 24*time.Hour, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), archived)
+
+	msgs, err := cache.GetAllSent(ctx)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1, "archived messages are no longer served from the cache")
+	assert.Equal(t, "2", msgs[0].ID)
+}
+
+func TestCacheRepository_Flush_Empty(t *testing.T) {
+	cache, _ := newTestCache(t, &stubRepository{})
+
+	assert.NoError(t, cache.Flush(context.Background()))
+}
+
+func TestCacheRepository_Rebuild(t *testing.T) {
+	repo := &stubRepository{sent: []*message.SentMessage{sentMessage("1", "acme")}}
+	cache, mr := newTestCache(t, repo)
+	_, err := cache.GetAllSent(message.WithTenant(context.Background(), "acme"))
+	require.NoError(t, err)
+	// a tenant whose messages are no longer in the repository
+	_, err = mr.Lpush("sent:gone", `{"id":"9"}`)
+	require.NoError(t, err)
+	repo.sent = []*message.SentMessage{sentMessage("1", "acme"), sentMessage("2", "acme"), sentMessage("3", "globex")}
+
+	n, err := cache.Rebuild(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.ElementsMatch(t, []string{"sent:acme", "sent:globex"}, mr.Keys())
+	acme, err := mr.List("sent:acme")
+	require.NoError(t, err)
+	assert.Len(t, acme, 2)
+	globex, err := mr.List("sent:globex")
+	require.NoError(t, err)
+	assert.Len(t, globex, 1)
+}
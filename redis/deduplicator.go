@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// reserveScript sets KEYS[1] to the message ID in ARGV[1] for ARGV[2] milliseconds unless another message holds it,
+// returning 1 if the key now belongs to the message.
+var reserveScript = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if holder == false then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+if holder == ARGV[1] then
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes KEYS[1] if it is held by the message ID in ARGV[1].
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Deduplicator reserves message contents in Redis under "<prefix>:<tenant>:<hash of recipient and content>",
+// letting Redis expire the reservations, so every replica of the service detects the same duplicates.
+type Deduplicator struct {
+	rdb    *redis.Client // Redis client instance
+	prefix string        // prefix of the keys holding reservations
+}
+
+// Ensure Deduplicator implements the message.Deduplicator interface.
+var _ message.Deduplicator = (*Deduplicator)(nil)
+
+// NewDeduplicator constructs a Deduplicator keeping reservations under keys starting with prefix.
+// The prefix must differ from the key of a CacheRepository, whose flush would remove the reservations otherwise.
+func NewDeduplicator(rdb *redis.Client, prefix string) *Deduplicator {
+	return &Deduplicator{
+		rdb:    rdb,
+		prefix: prefix,
+	}
+}
+
+// Reserve claims the recipient and content of msg for window, unless another message claimed them already.
+// A message holding the reservation itself, e.g. when retried after a crash, may reserve again.
+func (d *Deduplicator) Reserve(ctx context.Context, msg *message.Message, window time.Duration) (bool, error) {
+	reserved, err := reserveScript.Run(ctx, d.rdb, []string{d.key(ctx, msg)}, msg.ID, window.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrap(err, "reserving message content")
+	}
+	return reserved == 1, nil
+}
+
+// Release drops the reservation of the recipient and content of msg, if msg holds it.
+func (d *Deduplicator) Release(ctx context.Context, msg *message.Message) error {
+	if err := releaseScript.Run(ctx, d.rdb, []string{d.key(ctx, msg)}, msg.ID).Err(); err != nil {
+		return errors.Wrap(err, "releasing message content")
+	}
+	return nil
+}
+
+// key returns the Redis key reserving the recipient and content of msg within its tenant.
+func (d *Deduplicator) key(ctx context.Context, msg *message.Message) string {
+	sum := sha256.Sum256([]byte(msg.To + "\x00" + msg.Content))
+	return d.prefix + ":" + message.TenantOf(ctx, msg) + ":" + hex.EncodeToString(sum[:])
+}
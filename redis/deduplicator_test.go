@@ -0,0 +1,71 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduplicator(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	dedup := redis.NewDeduplicator(rdb, "dedup")
+	ctx := context.Background()
+	first := &message.Message{ID: "1", To: "+905551234567", Content: "hello", Tenant: "acme"}
+	second := &message.Message{ID: "2", To: "+905551234567", Content: "hello", Tenant: "acme"}
+	otherTenant := &message.Message{ID: "3", To: "+905551234567", Content: "hello", Tenant: "globex"}
+
+	reserved, err := dedup.Reserve(ctx, first, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+
+	reserved, err = dedup.Reserve(ctx, second, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, reserved, "identical messages are duplicates within the window")
+
+	reserved, err = dedup.Reserve(ctx, first, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, reserved, "the holder of a reservation may reserve again")
+
+	reserved, err = dedup.Reserve(ctx, otherTenant, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, reserved, "tenants are deduplicated separately")
+
+	mr.FastForward(2 * time.Minute)
+	reserved, err = dedup.Reserve(ctx, second, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, reserved, "reservations expire after the window")
+}
+
+func TestDeduplicator_Release(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	dedup := redis.NewDeduplicator(rdb, "dedup")
+	ctx := context.Background()
+	first := &message.Message{ID: "1", To: "+905551234567", Content: "hello"}
+	second := &message.Message{ID: "2", To: "+905551234567", Content: "hello"}
+
+	reserved, err := dedup.Reserve(ctx, first, time.Minute)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// only the holder releases the reservation
+	require.NoError(t, dedup.Release(ctx, second))
+	reserved, err = dedup.Reserve(ctx, second, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+
+	require.NoError(t, dedup.Release(ctx, first))
+	reserved, err = dedup.Reserve(ctx, second, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+}
@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/daemon"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireScript sets KEYS[1] to the holder in ARGV[1] for ARGV[2] milliseconds unless another holder has it,
+// returning 1 if the key now belongs to the holder.
+var acquireScript = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if holder == false or holder == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// holderBytes is the number of random bytes identifying the holder of a Lease.
+const holderBytes = 16
+
+// Lease is a daemon.Lease kept in a Redis key holding a random token of its holder, which Redis expires unless renewed.
+// Every replica of the service constructs its own Lease on the same key, and at most one of them holds it.
+type Lease struct {
+	rdb    *redis.Client // Redis client instance
+	key    string        // key holding the token of the current holder
+	holder string        // random token identifying this instance as holder
+}
+
+// Ensure Lease implements the daemon.Lease interface.
+var _ daemon.Lease = (*Lease)(nil)
+
+// NewLease constructs a Lease on key for this instance.
+// The key must not start with the key of a CacheRepository followed by a colon, whose flush would remove the lease otherwise.
+func NewLease(rdb *redis.Client, key string) (*Lease, error) {
+	token := make([]byte, holderBytes)
+	if _, err := rand.Read(token); err != nil {
+		return nil, errors.Wrap(err, "generating lease holder token")
+	}
+	return &Lease{
+		rdb:    rdb,
+		key:    key,
+		holder: hex.EncodeToString(token),
+	}, nil
+}
+
+// Acquire takes the lease for ttl unless another instance holds it, or extends it to ttl from now if this one does.
+func (l *Lease) Acquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	held, err := acquireScript.Run(ctx, l.rdb, []string{l.key}, l.holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrap(err, "acquiring lease")
+	}
+	return held == 1, nil
+}
+
+// Release deletes the lease if this instance holds it.
+func (l *Lease) Release(ctx context.Context) error {
+	if err := releaseScript.Run(ctx, l.rdb, []string{l.key}, l.holder).Err(); err != nil {
+		return errors.Wrap(err, "releasing lease")
+	}
+	return nil
+}
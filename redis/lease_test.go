@@ -0,0 +1,54 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/grustamli/insider-msg-sender/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLease(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	leader, err := redis.NewLease(rdb, "leader")
+	require.NoError(t, err)
+	follower, err := redis.NewLease(rdb, "leader")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	held, err := leader.Acquire(ctx, 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, held)
+	held, err = follower.Acquire(ctx, 10*time.Second)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	// the holder renews its lease
+	mr.FastForward(8 * time.Second)
+	held, err = leader.Acquire(ctx, 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, 10*time.Second, mr.TTL("leader"))
+
+	// a follower releasing does not free the lease of the leader
+	require.NoError(t, follower.Release(ctx))
+	assert.True(t, mr.Exists("leader"))
+
+	// once the leader stops renewing, the lease expires and a follower takes over
+	mr.FastForward(11 * time.Second)
+	held, err = follower.Acquire(ctx, 10*time.Second)
+	require.NoError(t, err)
+	assert.True(t, held)
+	held, err = leader.Acquire(ctx, 10*time.Second)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	require.NoError(t, follower.Release(ctx))
+	assert.False(t, mr.Exists("leader"))
+}
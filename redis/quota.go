@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// quotaReserveScript increments the count in KEYS[1] unless it reached the limit in ARGV[1], letting the key expire
+// after ARGV[2] milliseconds, and returns 1 if it did.
+var quotaReserveScript = redis.NewScript(`
+local used = tonumber(redis.call('GET', KEYS[1]) or '0')
+if used >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+// quotaReleaseScript decrements the count in KEYS[1] if it is positive.
+var quotaReleaseScript = redis.NewScript(`
+if tonumber(redis.call('GET', KEYS[1]) or '0') > 0 then
+	return redis.call('DECR', KEYS[1])
+end
+return 0
+`)
+
+// quotaKeyTTL is how long the count of a day is kept, long enough to outlast the day in any time zone.
+const quotaKeyTTL = 48 * time.Hour
+
+// quotaDayLayout is the layout of the day in the keys holding counts.
+const quotaDayLayout = "2006-01-02"
+
+// QuotaCounter counts messages sent per tenant and day in Redis under "<prefix>:<tenant>:<YYYY-MM-DD>",
+// letting Redis expire the counts of past days, so every replica of the service enforces the same quota.
+type QuotaCounter struct {
+	rdb    *redis.Client // Redis client instance
+	prefix string        // prefix of the keys holding counts
+}
+
+// Ensure QuotaCounter implements the message.QuotaCounter interface.
+var _ message.QuotaCounter = (*QuotaCounter)(nil)
+
+// NewQuotaCounter constructs a QuotaCounter keeping counts under keys starting with prefix.
+// The prefix must differ from the key of a CacheRepository, whose flush would reset the counts otherwise.
+func NewQuotaCounter(rdb *redis.Client, prefix string) *QuotaCounter {
+	return &QuotaCounter{
+		rdb:    rdb,
+		prefix: prefix,
+	}
+}
+
+// Reserve counts a message of tenant on day unless limit messages are counted already.
+func (q *QuotaCounter) Reserve(ctx context.Context, tenant string, day time.Time, limit int64) (bool, error) {
+	reserved, err := quotaReserveScript.Run(ctx, q.rdb, []string{q.key(tenant, day)}, limit, quotaKeyTTL.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrap(err, "reserving daily quota")
+	}
+	return reserved == 1, nil
+}
+
+// Release uncounts a message of tenant on day, never going below zero.
+func (q *QuotaCounter) Release(ctx context.Context, tenant string, day time.Time) error {
+	if err := quotaReleaseScript.Run(ctx, q.rdb, []string{q.key(tenant, day)}).Err(); err != nil {
+		return errors.Wrap(err, "releasing daily quota")
+	}
+	return nil
+}
+
+// Used returns the number of messages counted for tenant on day.
+func (q *QuotaCounter) Used(ctx context.Context, tenant string, day time.Time) (int64, error) {
+	used, err := q.rdb.Get(ctx, q.key(tenant, day)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "getting daily quota usage")
+	}
+	return used, nil
+}
+
+// key returns the Redis key counting the messages of tenant on day.
+func (q *QuotaCounter) key(tenant string, day time.Time) string {
+	return q.prefix + ":" + tenant + ":" + day.Format(quotaDayLayout)
+}
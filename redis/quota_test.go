@@ -0,0 +1,67 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/grustamli/insider-msg-sender/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaCounter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	quota := redis.NewQuotaCounter(rdb, "quota")
+	ctx := context.Background()
+	today := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	for range 2 {
+		reserved, err := quota.Reserve(ctx, "acme", today, 2)
+		require.NoError(t, err)
+		assert.True(t, reserved)
+	}
+	reserved, err := quota.Reserve(ctx, "acme", today, 2)
+	require.NoError(t, err)
+	assert.False(t, reserved, "the quota is used up")
+
+	reserved, err = quota.Reserve(ctx, "globex", today, 2)
+	require.NoError(t, err)
+	assert.True(t, reserved, "tenants are counted separately")
+
+	reserved, err = quota.Reserve(ctx, "acme", tomorrow, 2)
+	require.NoError(t, err)
+	assert.True(t, reserved, "the count starts over every day")
+
+	used, err := quota.Used(ctx, "acme", today)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), used)
+	assert.True(t, mr.TTL("quota:acme:2026-10-16") > 0, "counts expire")
+}
+
+func TestQuotaCounter_Release(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	quota := redis.NewQuotaCounter(rdb, "quota")
+	ctx := context.Background()
+	today := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	reserved, err := quota.Reserve(ctx, "acme", today, 1)
+	require.NoError(t, err)
+	require.True(t, reserved)
+	require.NoError(t, quota.Release(ctx, "acme", today))
+	require.NoError(t, quota.Release(ctx, "acme", today), "releasing below zero is ignored")
+
+	used, err := quota.Used(ctx, "acme", today)
+	require.NoError(t, err)
+	assert.Zero(t, used)
+	reserved, err = quota.Reserve(ctx, "acme", today, 1)
+	require.NoError(t, err)
+	assert.True(t, reserved, "released messages free their share of the quota")
+}
@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// ResponseStore keeps cached API responses in Redis under "<prefix>:<key>", letting Redis expire them,
+// so every replica of the service serves the same cached responses.
+type ResponseStore struct {
+	rdb    *redis.Client // Redis client instance
+	prefix string        // prefix of the keys holding responses
+}
+
+// NewResponseStore constructs a ResponseStore keeping responses under keys starting with prefix.
+// The prefix must differ from the key of a CacheRepository, whose flush would remove the responses otherwise.
+func NewResponseStore(rdb *redis.Client, prefix string) *ResponseStore {
+	return &ResponseStore{
+		rdb:    rdb,
+		prefix: prefix,
+	}
+}
+
+// Get returns the response stored under key, or nil if there is none or it expired.
+func (s *ResponseStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.rdb.Get(ctx, s.prefix+":"+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "getting cached response")
+	}
+	return data, nil
+}
+
+// Set stores value under key, expiring after ttl.
+func (s *ResponseStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.rdb.Set(ctx, s.prefix+":"+key, value, ttl).Err(); err != nil {
+		return errors.Wrap(err, "caching response")
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/grustamli/insider-msg-sender/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	store := redis.NewResponseStore(rdb, "responses")
+	ctx := context.Background()
+
+	data, err := store.Get(ctx, "acme:/messages")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	require.NoError(t, store.Set(ctx, "acme:/messages", []byte(`{"status":200}`), 5*time.Second))
+	assert.True(t, mr.Exists("responses:acme:/messages"))
+	data, err = store.Get(ctx, "acme:/messages")
+	require.NoError(t, err)
+	assert.Equal(t, `{"status":200}`, string(data))
+
+	mr.FastForward(6 * time.Second)
+	data, err = store.Get(ctx, "acme:/messages")
+	require.NoError(t, err)
+	assert.Nil(t, data, "responses expire after their TTL")
+}
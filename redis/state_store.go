@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/grustamli/insider-msg-sender/daemon"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// StateStore keeps the desired state of daemons in a Redis hash, one field per daemon,
+// so it survives restarts and is shared by every replica of the service.
+type StateStore struct {
+	rdb *redis.Client // Redis client instance
+	key string        // key of the hash holding the states
+}
+
+// Ensure StateStore implements the daemon.StateStore interface.
+var _ daemon.StateStore = (*StateStore)(nil)
+
+// NewStateStore constructs a StateStore keeping daemon states in the hash under key.
+// The key must not start with the key of a CacheRepository followed by a colon, whose flush would remove the states otherwise.
+func NewStateStore(rdb *redis.Client, key string) *StateStore {
+	return &StateStore{
+		rdb: rdb,
+		key: key,
+	}
+}
+
+// LoadState returns the state saved for the named daemon, or an empty state if none was saved.
+func (s *StateStore) LoadState(ctx context.Context, name string) (daemon.State, error) {
+	state, err := s.rdb.HGet(ctx, s.key, name).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "getting daemon state")
+	}
+	return daemon.State(state), nil
+}
+
+// SaveState saves the state of the named daemon.
+func (s *StateStore) SaveState(ctx context.Context, name string, state daemon.State) error {
+	if err := s.rdb.HSet(ctx, s.key, name, string(state)).Err(); err != nil {
+		return errors.Wrap(err, "saving daemon state")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/grustamli/insider-msg-sender/daemon"
+	"github.com/grustamli/insider-msg-sender/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	store := redis.NewStateStore(rdb, "scheduler")
+	ctx := context.Background()
+
+	state, err := store.LoadState(ctx, "MessageSender")
+	require.NoError(t, err)
+	assert.Empty(t, state)
+
+	require.NoError(t, store.SaveState(ctx, "MessageSender", daemon.StatePaused))
+	assert.Equal(t, "paused", mr.HGet("scheduler", "MessageSender"))
+	state, err = store.LoadState(ctx, "MessageSender")
+	require.NoError(t, err)
+	assert.Equal(t, daemon.StatePaused, state)
+
+	// a new store, as after a restart, sees the saved state
+	state, err = redis.NewStateStore(rdb, "scheduler").LoadState(ctx, "MessageSender")
+	require.NoError(t, err)
+	assert.Equal(t, daemon.StatePaused, state)
+}
+
+func TestStateStore_Unavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	mr.Close()
+
+	_, err := redis.NewStateStore(rdb, "scheduler").LoadState(context.Background(), "MessageSender")
+
+	assert.ErrorContains(t, err, "getting daemon state")
+}
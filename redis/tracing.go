@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records a span per Redis command. It is a no-op until a TracerProvider is installed, e.g. by tracing.Setup.
+var tracer = otel.Tracer("github.com/grustamli/insider-msg-sender/redis")
+
+// TracingHook is a redis.Hook recording a client span for every command and pipeline a client runs,
+// named after the command, e.g. "evalsha", so Redis calls show up in the trace of the send making them.
+type TracingHook struct{}
+
+var _ redis.Hook = TracingHook{}
+
+// Trace adds a TracingHook to rdb.
+func Trace(rdb *redis.Client) {
+	rdb.AddHook(TracingHook{})
+}
+
+// DialHook dials without recording a span.
+func (TracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook runs a command in a span.
+func (TracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := tracer.Start(ctx, cmd.Name(), trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation.name", cmd.Name()),
+		))
+		err := next(ctx, cmd)
+		endCommandSpan(span, err)
+		return err
+	}
+}
+
+// ProcessPipelineHook runs a pipeline, or transaction, in a single span.
+func (TracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := tracer.Start(ctx, "pipeline", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.Int("db.operation.batch.size", len(cmds)),
+		))
+		err := next(ctx, cmds)
+		endCommandSpan(span, err)
+		return err
+	}
+}
+
+// endCommandSpan records err, unless nil or redis.Nil, which only reports a missing key, as the error of span
+// and ends it.
+func endCommandSpan(span trace.Span, err error) {
+	if err != nil && err != redis.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,47 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/grustamli/insider-msg-sender/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx := context.Background()
+	// connect before tracing, so the handshake is not recorded
+	require.NoError(t, rdb.Ping(ctx).Err())
+	redis.Trace(rdb)
+
+	require.NoError(t, rdb.Set(ctx, "key", "value", 0).Err())
+	// a missing key is no error
+	require.ErrorIs(t, rdb.Get(ctx, "missing").Err(), goredis.Nil)
+	_, err := rdb.Pipelined(ctx, func(p goredis.Pipeliner) error {
+		p.Incr(ctx, "counter")
+		p.Incr(ctx, "counter")
+		return nil
+	})
+	require.NoError(t, err)
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+		assert.Contains(t, span.Attributes(), attribute.String("db.system", "redis"))
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	}
+	assert.Equal(t, []string{"set", "get", "pipeline"}, names)
+}
@@ -0,0 +1,56 @@
+// Package tracing configures OpenTelemetry tracing of the service: sends, repository queries, Redis commands and
+// webhook calls record spans that are exported over OTLP, so a single send can be followed end to end.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Config holds the settings of the exported traces.
+type Config struct {
+	ServiceName string  // name the service reports its spans under
+	SampleRatio float64 // fraction of traces recorded, from 0 to 1; sampling decisions of callers are respected
+}
+
+// Setup installs a global TracerProvider exporting spans over OTLP/HTTP to the collector configured by the standard
+// OTEL_EXPORTER_OTLP_* environment variables, http://localhost:4318 by default, and the W3C trace context
+// propagator, so outgoing requests carry a traceparent header. Without Setup, spans are not recorded.
+// The returned function flushes pending spans and stops exporting; it should be called before the process exits.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating trace exporter")
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, errors.Wrap(err, "describing traced service")
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Transport wraps base, http.DefaultTransport if nil, to record a client span for every request and to propagate
+// the trace of its context in the traceparent header, so the receiver, e.g. the webhook provider, can join the trace.
+// The request is cloned before the header is added, so headers shared between requests are left untouched.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
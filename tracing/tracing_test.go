@@ -0,0 +1,53 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/tracing"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTransport_PropagatesTraceToWebhook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	var traceparents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"provider-1"}`))
+	}))
+	t.Cleanup(srv.Close)
+	client := &http.Client{Transport: tracing.Transport(nil)}
+	sender, err := webhook.NewWebhookSender(client, srv.URL)
+	require.NoError(t, err)
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "send message")
+	for range 2 {
+		_, err = sender.Send(ctx, &message.Message{To: "+905551234567", Content: "Hello"})
+		require.NoError(t, err)
+	}
+	span.End()
+
+	// the webhook joins the trace of the send through a client span of each request
+	require.Len(t, traceparents, 2)
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	for i, header := range traceparents {
+		client := spans[i]
+		assert.Equal(t, trace.SpanKindClient, client.SpanKind())
+		assert.Equal(t, span.SpanContext().TraceID(), client.SpanContext().TraceID())
+		assert.Equal(t, "00-"+client.SpanContext().TraceID().String()+"-"+client.SpanContext().SpanID().String()+"-01", header)
+	}
+}
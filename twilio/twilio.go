@@ -0,0 +1,185 @@
+// Package twilio implements message.Sender against the Twilio Programmable Messaging API.
+package twilio
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// DefaultBaseURL is the base URL of the Twilio REST API.
+const DefaultBaseURL = "https://api.twilio.com"
+
+// OptFunc configures optional behavior on Options.
+type OptFunc func(options *Options)
+
+// Options holds sender customization settings.
+type Options struct {
+	baseURL        string // base URL of the Twilio REST API
+	characterLimit int    // max characters to include before truncation; zero disables truncation
+	segmentLimit   int    // max SMS segments to include before truncation; overrides characterLimit if set
+}
+
+// defaultOpts returns default Options sending to DefaultBaseURL without truncation.
+func defaultOpts() *Options {
+	return &Options{
+		baseURL: DefaultBaseURL,
+	}
+}
+
+// WithBaseURL sends requests to baseURL instead of DefaultBaseURL, e.g. to a regional edge or a test server.
+func WithBaseURL(baseURL string) OptFunc {
+	return func(options *Options) {
+		options.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithCharacterLimit sets a maximum character count for the message content. Zero disables truncation.
+func WithCharacterLimit(limit int) OptFunc {
+	return func(options *Options) {
+		options.characterLimit = limit
+	}
+}
+
+// WithSegmentLimit truncates the message content to fit in at most segments SMS segments, GSM-7 or UCS-2 encoded as
+// counted by message.SMSSegments, instead of a character count. Zero keeps the character limit.
+func WithSegmentLimit(segments int) OptFunc {
+	return func(options *Options) {
+		options.segmentLimit = segments
+	}
+}
+
+// Sender sends SMS through the Twilio Messages API, authenticating with an account SID and auth token.
+type Sender struct {
+	client     *http.Client // HTTP client for executing requests
+	accountSID string       // account the messages are sent from
+	authToken  string       // secret authenticating the account
+	from       string       // phone number messages are sent from, in E.164 format
+	opts       *Options     // sender configuration options
+}
+
+// Ensure Sender implements the message.Sender interface.
+var _ message.Sender = (*Sender)(nil)
+
+// NewSender constructs a Sender that sends messages from the phone number from with the Twilio account accountSID,
+// authenticated by authToken, using client and applying any provided functional options.
+// Returns an error if accountSID, authToken or from is blank.
+func NewSender(client *http.Client, accountSID, authToken, from string, optFuncs ...OptFunc) (*Sender, error) {
+	if accountSID == "" || authToken == "" || from == "" {
+		return nil, errors.New("creating twilio sender: account SID, auth token and from number are required")
+	}
+	opts := defaultOpts()
+	for _, f := range optFuncs {
+		f(opts)
+	}
+	return &Sender{
+		client:     client,
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		opts:       opts,
+	}, nil
+}
+
+// response is the part of a Twilio Message resource, or of a Twilio error, the Sender reads.
+type response struct {
+	SID     string `json:"sid"`     // identifier of the created message
+	Code    int    `json:"code"`    // Twilio error code, on failure
+	Message string `json:"message"` // error description, on failure
+}
+
+// providerError returns the error described by r, a Twilio error answered with status.
+func (r *response) providerError(status int) *message.ProviderError {
+	perr := &message.ProviderError{StatusCode: status, Message: r.Message}
+	if r.Code != 0 {
+		perr.Code = strconv.Itoa(r.Code)
+	}
+	return perr
+}
+
+// Send creates a Twilio message delivering msg and returns its SID as the provider message ID.
+// A 429 Too Many Requests response is returned as a message.RateLimitedError honoring its Retry-After header.
+func (s *Sender) Send(ctx context.Context, msg *message.Message) (*message.SendResult, error) {
+	content, err := s.content(msg)
+	if err != nil {
+		return nil, errors.Wrap(err, "truncating message")
+	}
+	form := url.Values{
+		"To":   {msg.To},
+		"From": {s.from},
+		"Body": {content},
+	}
+	endpoint := s.opts.baseURL + "/2010-04-01/Accounts/" + url.PathEscape(s.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	req.SetBasicAuth(s.accountSID, s.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	sentAt := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "sending request")
+	}
+	defer resp.Body.Close()
+	var res response
+	body, decodeErr := io.ReadAll(resp.Body)
+	if decodeErr == nil {
+		decodeErr = json.Unmarshal(body, &res)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &message.RateLimitedError{
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+			Err:        errors.Wrap(res.providerError(resp.StatusCode), "sending request"),
+		}
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return nil, errors.Wrap(res.providerError(resp.StatusCode), "sending request")
+	}
+	if decodeErr != nil {
+		return nil, errors.Wrap(decodeErr, "decoding response")
+	}
+	if res.SID == "" {
+		return nil, errors.New("blank message sid")
+	}
+	return &message.SendResult{
+		MessageID:  res.SID,
+		SentAt:     sentAt,
+		StatusCode: resp.StatusCode,
+		Response:   body,
+	}, nil
+}
+
+// SendBatch sends msgs one by one, as the Messages API creates a single message per request.
+func (s *Sender) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	return message.SendEach(ctx, s, msgs)
+}
+
+// content returns the content of msg truncated to the configured segment or character limit.
+func (s *Sender) content(msg *message.Message) (string, error) {
+	switch {
+	case s.opts.segmentLimit > 0:
+		return msg.TruncatedSegments(s.opts.segmentLimit)
+	case s.opts.characterLimit > 0:
+		return msg.TruncatedContent(s.opts.characterLimit)
+	}
+	return msg.Content, nil
+}
+
+// retryAfter returns the wait a Retry-After header value in seconds asks for, or zero if it is missing or malformed.
+func retryAfter(value string) time.Duration {
+	secs, err := strconv.Atoi(value)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
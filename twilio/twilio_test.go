@@ -0,0 +1,89 @@
+package twilio_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/twilio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSender_Send(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "token", pass)
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "+905551234567", r.PostForm.Get("To"))
+		assert.Equal(t, "+15005550006", r.PostForm.Get("From"))
+		assert.Equal(t, "hel", r.PostForm.Get("Body"))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid":"SM123","status":"queued"}`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := twilio.NewSender(srv.Client(), "AC123", "token", "+15005550006",
+		twilio.WithBaseURL(srv.URL+"/"), twilio.WithCharacterLimit(3))
+	require.NoError(t, err)
+
+	res, err := sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "SM123", res.MessageID)
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.JSONEq(t, `{"sid":"SM123","status":"queued"}`, string(res.Response))
+}
+
+func TestSender_Send_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		header  map[string]string
+		wantErr string
+		limited time.Duration
+	}{
+		{name: "twilio_error", status: http.StatusBadRequest, body: `{"code":21211,"message":"Invalid 'To' Phone Number","status":400}`, wantErr: "received status 400: error 21211: Invalid 'To' Phone Number"},
+		{name: "server_error", status: http.StatusBadGateway, body: `<html></html>`, wantErr: "received status 502"},
+		{name: "blank_sid", status: http.StatusCreated, body: `{"status":"queued"}`, wantErr: "blank message sid"},
+		{name: "rate_limited", status: http.StatusTooManyRequests, body: `{"code":20429,"message":"Too Many Requests"}`, header: map[string]string{"Retry-After": "30"}, wantErr: "received status 429", limited: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(srv.Close)
+			sender, err := twilio.NewSender(srv.Client(), "AC123", "token", "+15005550006", twilio.WithBaseURL(srv.URL))
+			require.NoError(t, err)
+
+			_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			var limited *message.RateLimitedError
+			if tt.limited > 0 {
+				require.ErrorAs(t, err, &limited)
+				assert.Equal(t, tt.limited, limited.RetryAfter)
+			} else {
+				assert.NotErrorAs(t, err, &limited)
+			}
+		})
+	}
+}
+
+func TestNewSender_MissingCredentials(t *testing.T) {
+	_, err := twilio.NewSender(http.DefaultClient, "AC123", "", "+15005550006")
+
+	require.Error(t, err)
+}
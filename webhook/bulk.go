@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// WithBatchSize makes SendBatch post up to n messages in a single request, for providers accepting bulk payloads.
+// The request body is a JSON array of the payloads of the messages, and the provider answers with a JSON array
+// holding the response of each message in the same order, each read with the configured ResponseFields.
+// Values below two send every message with its own request, the default.
+func WithBatchSize(n int) OptFunc {
+	return func(options *Options) {
+		options.batchSize = n
+	}
+}
+
+// SendBatch sends msgs in requests of up to the batch size configured with WithBatchSize, or one by one by default.
+// A request failing as a whole, e.g. with a rejected status, fails every message it carries; transient failures are
+// retried as configured with WithRetries. Every request has its own deadline set with WithRequestTimeout.
+func (s *MessageSender) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	if s.opts.batchSize < 2 {
+		return message.SendEach(ctx, s, msgs)
+	}
+	ret := make([]message.BatchResult, 0, len(msgs))
+	for start := 0; start < len(msgs); start += s.opts.batchSize {
+		batch := msgs[start:min(start+s.opts.batchSize, len(msgs))]
+		ret = append(ret, s.sendBulk(ctx, batch)...)
+	}
+	return ret
+}
+
+// sendBulk delivers msgs with a single request, returning their results in the order of msgs.
+// Messages whose payload cannot be built fail on their own and are left out of the request.
+func (s *MessageSender) sendBulk(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	ret := make([]message.BatchResult, len(msgs))
+	var (
+		body = []byte{'['}
+		sent []int // positions of the messages in the request
+	)
+	for i, msg := range msgs {
+		payload, err := s.body(msg)
+		if err != nil {
+			ret[i].Err = err
+			continue
+		}
+		if len(sent) > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, bytes.TrimSpace(payload)...)
+		sent = append(sent, i)
+	}
+	body = append(body, ']')
+	if len(sent) == 0 {
+		return ret
+	}
+	ctx, cancel := s.withRequestTimeout(ctx)
+	defer cancel()
+	ids := make([]string, len(sent))
+	for j, i := range sent {
+		ids[j] = msgs[i].ID
+	}
+	key := IdempotencyKey(ids...)
+	var (
+		responses []any
+		status    int
+		sentAt    time.Time
+	)
+	err := s.retry(ctx, func() error {
+		req, err := s.createRequest(ctx, body, key)
+		if err != nil {
+			return err
+		}
+		sentAt = time.Now()
+		doc, code, err := s.do(req)
+		if err != nil {
+			return err
+		}
+		status = code
+		var ok bool
+		if responses, ok = doc.([]any); !ok || len(responses) != len(sent) {
+			return errors.Errorf("parsing response: expected an array of %d responses", len(sent))
+		}
+		return nil
+	})
+	for j, i := range sent {
+		if err != nil {
+			ret[i].Err = err
+			continue
+		}
+		res := s.responseOf(responses[j])
+		if err := res.validate(s.opts.fields); err != nil {
+			ret[i].Err = err
+			continue
+		}
+		ret[i].Result = &message.SendResult{MessageID: res.MessageID, SentAt: sentAt, StatusCode: status, Response: encodeResponse(responses[j])}
+	}
+	return ret
+}
@@ -0,0 +1,145 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkProvider answers bulk requests with an accepted response per message, rejecting recipients in reject.
+func bulkProvider(t *testing.T, requests *[][]webhook.RequestPayload, reject string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payloads []webhook.RequestPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payloads))
+		*requests = append(*requests, payloads)
+		responses := make([]map[string]string, len(payloads))
+		for i, p := range payloads {
+			responses[i] = map[string]string{"message": "Accepted", "messageId": "ext-" + p.Content}
+			if p.To == reject {
+				responses[i] = map[string]string{"message": "Rejected"}
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(responses)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func messages(n int) []*message.Message {
+	msgs := make([]*message.Message, n)
+	for i := range msgs {
+		msgs[i] = &message.Message{ID: fmt.Sprint(i), To: "+90555000000" + fmt.Sprint(i), Content: fmt.Sprint(i)}
+	}
+	return msgs
+}
+
+func TestMessageSender_SendBatch_Bulk(t *testing.T) {
+	var requests [][]webhook.RequestPayload
+	srv := bulkProvider(t, &requests, "+905550000003")
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithBatchSize(2))
+	require.NoError(t, err)
+
+	results := sender.SendBatch(context.Background(), messages(5))
+
+	require.Len(t, results, 5)
+	assert.Equal(t, []int{2, 2, 1}, []int{len(requests[0]), len(requests[1]), len(requests[2])})
+	for i, r := range results {
+		if i == 3 {
+			assert.ErrorContains(t, r.Err, "invalid message: Rejected")
+			continue
+		}
+		require.NoError(t, r.Err, "message %d", i)
+		assert.Equal(t, fmt.Sprint("ext-", i), r.Result.MessageID)
+		assert.Contains(t, string(r.Result.Response), fmt.Sprintf(`"ext-%d"`, i), "each message keeps its own response")
+	}
+}
+
+func TestMessageSender_SendBatch_OneByOneByDefault(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL)
+	require.NoError(t, err)
+
+	results := sender.SendBatch(context.Background(), messages(3))
+
+	require.Len(t, results, 3)
+	assert.Equal(t, 3, calls)
+}
+
+func TestMessageSender_SendBatch_FailedRequestFailsEveryMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{
+			name:    "rejected status",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadRequest) },
+			want:    "received status 400",
+		},
+		{
+			name: "response count mismatch",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte(`[{"message":"Accepted","messageId":"ext-1"}]`))
+			},
+			want: "expected an array of 2 responses",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			t.Cleanup(srv.Close)
+			sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithBatchSize(10))
+			require.NoError(t, err)
+
+			results := sender.SendBatch(context.Background(), messages(2))
+
+			require.Len(t, results, 2)
+			for _, r := range results {
+				assert.ErrorContains(t, r.Err, tt.want)
+				assert.Nil(t, r.Result)
+			}
+		})
+	}
+}
+
+func TestMessageSender_SendBatch_RetriesTransientFailures(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`[{"message":"Accepted","messageId":"ext-0"},{"message":"Accepted","messageId":"ext-1"}]`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithBatchSize(2), webhook.WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+
+	results := sender.SendBatch(context.Background(), messages(2))
+
+	assert.Equal(t, 2, calls)
+	for i, r := range results {
+		require.NoError(t, r.Err)
+		assert.Equal(t, fmt.Sprint("ext-", i), r.Result.MessageID)
+	}
+}
@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+
+	"github.com/pkg/errors"
+)
+
+// WithGzip compresses request bodies of at least minBytes bytes with gzip, sending them with the Content-Encoding
+// header set, to cut the size of bulk payloads for providers accepting compressed requests. Smaller bodies are sent
+// as they are. Signatures set with WithHMACSignature are computed over the compressed body, as sent.
+// Values below 1 disable compression, the default.
+func WithGzip(minBytes int) OptFunc {
+	return func(options *Options) {
+		options.gzipMinBytes = minBytes
+	}
+}
+
+// compress returns body gzip compressed if it is large enough for the configured compression,
+// and whether it was compressed.
+func (s *MessageSender) compress(body []byte) ([]byte, bool, error) {
+	if s.opts.gzipMinBytes < 1 || len(body) < s.opts.gzipMinBytes {
+		return body, false, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, false, errors.Wrap(err, "compressing request body")
+	}
+	if err := zw.Close(); err != nil {
+		return nil, false, errors.Wrap(err, "compressing request body")
+	}
+	return buf.Bytes(), true, nil
+}
@@ -0,0 +1,63 @@
+package webhook_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageSender_Gzip(t *testing.T) {
+	var (
+		encodings []string
+		payloads  [][]webhook.RequestPayload
+		signed    bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		raw, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		signed = r.Header.Get(webhook.SignatureHeader) == webhook.Sign("s3cret", r.Header.Get(webhook.TimestampHeader), raw)
+		var body io.Reader = bytes.NewReader(raw)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			body, err = gzip.NewReader(body)
+			require.NoError(t, err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		var batch []webhook.RequestPayload
+		if err := json.NewDecoder(body).Decode(&batch); err != nil {
+			_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+			return
+		}
+		payloads = append(payloads, batch)
+		_, _ = w.Write([]byte(`[{"message":"Accepted","messageId":"ext-0"},{"message":"Accepted","messageId":"ext-1"}]`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL,
+		webhook.WithBatchSize(2),
+		webhook.WithGzip(150),
+		webhook.WithHMACSignature("s3cret", ""),
+	)
+	require.NoError(t, err)
+
+	results := sender.SendBatch(context.Background(), messages(2))
+	_, sendErr := sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hi"})
+
+	require.NoError(t, sendErr)
+	for _, r := range results {
+		require.NoError(t, r.Err)
+	}
+	assert.Equal(t, []string{"gzip", ""}, encodings, "only bodies of at least 150 bytes are compressed")
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "+905550000001", payloads[0][1].To)
+	assert.True(t, signed, "the signature covers the body as sent")
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ContentType selects how request bodies are encoded.
+type ContentType string
+
+const (
+	// ContentTypeJSON sends the payload as a JSON document, the default.
+	ContentTypeJSON ContentType = "json"
+	// ContentTypeForm sends the fields of the payload as an application/x-www-form-urlencoded form.
+	ContentTypeForm ContentType = "form"
+)
+
+// header returns the value of the Content-Type header of request bodies encoded as t.
+func (t ContentType) header() string {
+	if t == ContentTypeForm {
+		return "application/x-www-form-urlencoded"
+	}
+	return "application/json"
+}
+
+// WithMethod sends requests with the HTTP method instead of POST, e.g. PUT. An empty method keeps POST.
+func WithMethod(method string) OptFunc {
+	return func(options *Options) {
+		if method != "" {
+			options.method = strings.ToUpper(method)
+		}
+	}
+}
+
+// WithContentType encodes request bodies as t, JSON by default. With ContentTypeForm, every top-level field of the
+// JSON payload, the default one or the one rendered from the payload template, becomes a form field: strings as they
+// are, other values in their JSON encoding. An empty t keeps JSON.
+func WithContentType(t ContentType) OptFunc {
+	return func(options *Options) {
+		if t != "" {
+			options.contentType = t
+		}
+	}
+}
+
+// validateEncoding returns an error if opts select an unknown content type, or form bodies for bulk payloads.
+func validateEncoding(opts *Options) error {
+	switch opts.contentType {
+	case ContentTypeJSON:
+	case ContentTypeForm:
+		if opts.batchSize > 1 {
+			return errors.New("bulk payloads are sent as JSON only")
+		}
+	default:
+		return errors.Errorf("unknown content type %q", opts.contentType)
+	}
+	return nil
+}
+
+// encode returns the request body of the JSON payload in the configured content type.
+func (s *MessageSender) encode(payload []byte) ([]byte, error) {
+	if s.opts.contentType != ContentTypeForm {
+		return payload, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, errors.Wrap(err, "encoding form: payload is not a JSON object")
+	}
+	form := make(url.Values, len(fields))
+	for key, raw := range fields {
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			form.Set(key, str)
+			continue
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err != nil {
+			return nil, errors.Wrapf(err, "encoding form field %q", key)
+		}
+		form.Set(key, compact.String())
+	}
+	return []byte(form.Encode()), nil
+}
@@ -0,0 +1,105 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capture is a provider accepting every message, recording the method, content type and body of the last request.
+type capture struct {
+	method, contentType, body string
+}
+
+func (c *capture) server(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		c.method, c.contentType, c.body = r.Method, r.Header.Get("Content-Type"), string(body)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMessageSender_Send_DefaultsToJSONPost(t *testing.T) {
+	var c capture
+	srv := c.server(t)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL)
+	require.NoError(t, err)
+
+	_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, c.method)
+	assert.Equal(t, "application/json", c.contentType)
+	assert.JSONEq(t, `{"to":"+905551234567","content":"hello","idempotencyKey":"`+webhook.IdempotencyKey("1")+`"}`, c.body)
+}
+
+func TestMessageSender_Send_FormPut(t *testing.T) {
+	var c capture
+	srv := c.server(t)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL,
+		webhook.WithMethod("put"),
+		webhook.WithContentType(webhook.ContentTypeForm),
+		webhook.WithPayloadTemplate(`{"phone":{{json .Message.To}},"text":{{json .Content}},"priority":1,"tags":["a","b"]}`, nil),
+	)
+	require.NoError(t, err)
+
+	_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello & bye"})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPut, c.method)
+	assert.Equal(t, "application/x-www-form-urlencoded", c.contentType)
+	form, err := url.ParseQuery(c.body)
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{
+		"phone":    {"+905551234567"},
+		"text":     {"hello & bye"},
+		"priority": {"1"},
+		"tags":     {`["a","b"]`},
+	}, form)
+}
+
+func TestMessageSender_Send_ConfiguredContentTypeHeaderWins(t *testing.T) {
+	var c capture
+	srv := c.server(t)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithHeader("Content-Type", "application/vnd.provider+json"))
+	require.NoError(t, err)
+
+	_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "application/vnd.provider+json", c.contentType)
+}
+
+func TestNewWebhookSender_InvalidContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []webhook.OptFunc
+		want string
+	}{
+		{name: "unknown", opts: []webhook.OptFunc{webhook.WithContentType("xml")}, want: `unknown content type "xml"`},
+		{
+			name: "bulk_form",
+			opts: []webhook.OptFunc{webhook.WithContentType(webhook.ContentTypeForm), webhook.WithBatchSize(10)},
+			want: "bulk payloads are sent as JSON only",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := webhook.NewWebhookSender(http.DefaultClient, "http://localhost", tt.opts...)
+
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
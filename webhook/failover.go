@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// ErrNoHealthyEndpoint is returned when the circuits of all endpoints of a FailoverSender are open.
+var ErrNoHealthyEndpoint = errors.New("no healthy webhook endpoint")
+
+// FailoverOptFunc configures optional behavior on FailoverOptions.
+type FailoverOptFunc func(options *FailoverOptions)
+
+// FailoverOptions holds endpoint health tracking settings.
+type FailoverOptions struct {
+	threshold int                              // consecutive transient failures opening the circuit of an endpoint
+	cooldown  time.Duration                    // time an open circuit skips its endpoint before it is tried again
+	observe   func(endpoint string, err error) // called with the outcome of every send attempt at an endpoint
+}
+
+// defaultFailoverOpts returns default FailoverOptions opening a circuit after 3 consecutive failures for 30 seconds.
+func defaultFailoverOpts() *FailoverOptions {
+	return &FailoverOptions{
+		threshold: 3,
+		cooldown:  30 * time.Second,
+		observe:   func(string, error) {},
+	}
+}
+
+// WithCircuitBreaker sets the consecutive transient failures of an endpoint that open its circuit, and the time an
+// open circuit skips the endpoint before a single send tries it again. Non-positive values are ignored.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) FailoverOptFunc {
+	return func(options *FailoverOptions) {
+		if threshold > 0 {
+			options.threshold = threshold
+		}
+		if cooldown > 0 {
+			options.cooldown = cooldown
+		}
+	}
+}
+
+// WithEndpointObserver sets a function called with the endpoint and error of every send attempt, nil if the endpoint
+// served the send. Endpoints are identified by their URL without query and credentials.
+func WithEndpointObserver(observe func(endpoint string, err error)) FailoverOptFunc {
+	return func(options *FailoverOptions) {
+		options.observe = observe
+	}
+}
+
+// FailoverSender sends messages through the first healthy of several webhook endpoints, in order of preference.
+// A send failing at an endpoint with a connection error or a 5xx response, after the retries of its MessageSender,
+// fails over to the next endpoint. Other errors, such as rejected requests or 429 Too Many Requests, are returned
+// right away, as another endpoint is not expected to fare better.
+// Every endpoint has a circuit breaker: consecutive transient failures open its circuit, skipping the endpoint until
+// the cooldown has passed. Endpoints whose MessageSender has a health probe, see WithHealthProbe, are probed before
+// the first send after their cooldown, keeping them skipped without spending a message while the probe fails.
+// FailoverSender is safe for concurrent use.
+type FailoverSender struct {
+	endpoints []*endpoint      // endpoints in order of preference
+	opts      *FailoverOptions // health tracking configuration options
+}
+
+// Ensure FailoverSender implements the message.Sender interface.
+var _ message.Sender = (*FailoverSender)(nil)
+
+// endpoint is a webhook endpoint of a FailoverSender along with the state of its circuit.
+type endpoint struct {
+	name      string         // URL of the endpoint without query and credentials
+	sender    *MessageSender // sender posting to the endpoint
+	mu        sync.Mutex     // protects the fields below
+	failures  int            // consecutive transient failures
+	openUntil time.Time      // time the circuit closes again; zero if closed
+}
+
+// NewFailoverSender constructs a FailoverSender sending through senders, the first being the primary endpoint,
+// applying any provided functional options. Returns an error if senders is empty.
+func NewFailoverSender(senders []*MessageSender, optFuncs ...FailoverOptFunc) (*FailoverSender, error) {
+	if len(senders) == 0 {
+		return nil, errors.New("creating failover sender: no endpoints")
+	}
+	opts := defaultFailoverOpts()
+	for _, f := range optFuncs {
+		f(opts)
+	}
+	endpoints := make([]*endpoint, len(senders))
+	for i, s := range senders {
+		endpoints[i] = &endpoint{name: s.endpoint, sender: s}
+	}
+	return &FailoverSender{endpoints: endpoints, opts: opts}, nil
+}
+
+// Send sends msg through the first endpoint with a closed circuit, failing over to the next one on transient errors.
+// Returns the error of the last endpoint tried, or ErrNoHealthyEndpoint if all circuits are open.
+func (s *FailoverSender) Send(ctx context.Context, msg *message.Message) (*message.SendResult, error) {
+	var lastErr error
+	for _, e := range s.endpoints {
+		if !e.available(time.Now()) {
+			continue
+		}
+		if e.halfOpen() && e.sender.probes() {
+			// spare the message while the endpoint is still down
+			if err := e.sender.Ping(ctx); err != nil {
+				e.record(err, s.opts)
+				lastErr = errors.Wrapf(err, "sending through %s", e.name)
+				continue
+			}
+		}
+		res, err := e.sender.Send(ctx, msg)
+		s.opts.observe(e.name, err)
+		var transient *transientError
+		if err == nil || !errors.As(err, &transient) {
+			e.record(nil, s.opts)
+			return res, err
+		}
+		e.record(err, s.opts)
+		lastErr = errors.Wrapf(err, "sending through %s", e.name)
+		if ctx.Err() != nil {
+			return nil, lastErr
+		}
+	}
+	if lastErr == nil {
+		return nil, ErrNoHealthyEndpoint
+	}
+	return nil, lastErr
+}
+
+// SendBatch sends msgs one by one, each failing over independently.
+func (s *FailoverSender) SendBatch(ctx context.Context, msgs []*message.Message) []message.BatchResult {
+	return message.SendEach(ctx, s, msgs)
+}
+
+// available reports whether the circuit of e is closed at now, or its cooldown has passed.
+func (e *endpoint) available(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !now.Before(e.openUntil)
+}
+
+// halfOpen reports whether the circuit of e was opened and its cooldown has passed, so the next send tries whether
+// the endpoint recovered.
+func (e *endpoint) halfOpen() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.openUntil.IsZero()
+}
+
+// record updates the circuit of e with the outcome of a send: a transient failure err counts towards opening it,
+// anything else closes it. A failure while the circuit is half-open, after its cooldown, opens it again right away.
+func (e *endpoint) record(err error, opts *FailoverOptions) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err == nil {
+		e.failures = 0
+		e.openUntil = time.Time{}
+		return
+	}
+	e.failures++
+	if e.failures >= opts.threshold {
+		e.openUntil = time.Now().Add(opts.cooldown)
+	}
+}
+
+// endpointName returns rawURL without its query and user info, keeping credentials out of errors and metrics.
+func endpointName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "invalid-url"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
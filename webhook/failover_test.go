@@ -0,0 +1,126 @@
+package webhook_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// endpointServer starts a webhook endpoint responding with the current value of status, counting its requests.
+func endpointServer(t *testing.T, status *atomic.Int32, calls *atomic.Int32, id string) *webhook.MessageSender {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"` + id + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL+"?token=secret")
+	require.NoError(t, err)
+	return sender
+}
+
+func TestFailoverSender_Send(t *testing.T) {
+	tests := []struct {
+		name             string
+		primaryStatus    int
+		wantID           string
+		wantErr          string
+		wantBackupCalled bool
+	}{
+		{name: "primary_serves", primaryStatus: http.StatusAccepted, wantID: "primary"},
+		{name: "fails_over_on_5xx", primaryStatus: http.StatusServiceUnavailable, wantID: "backup", wantBackupCalled: true},
+		{name: "no_failover_on_4xx", primaryStatus: http.StatusBadRequest, wantErr: "received status 400"},
+		{name: "no_failover_on_429", primaryStatus: http.StatusTooManyRequests, wantErr: "received status 429"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var primaryStatus, backupStatus, primaryCalls, backupCalls atomic.Int32
+			primaryStatus.Store(int32(tt.primaryStatus))
+			backupStatus.Store(http.StatusAccepted)
+			var served []string
+			sender, err := webhook.NewFailoverSender([]*webhook.MessageSender{
+				endpointServer(t, &primaryStatus, &primaryCalls, "primary"),
+				endpointServer(t, &backupStatus, &backupCalls, "backup"),
+			}, webhook.WithEndpointObserver(func(endpoint string, err error) {
+				if err == nil {
+					served = append(served, endpoint)
+				}
+			}))
+			require.NoError(t, err)
+
+			res, err := sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+			assert.Equal(t, int32(1), primaryCalls.Load())
+			assert.Equal(t, tt.wantBackupCalled, backupCalls.Load() == 1)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantID, res.MessageID)
+			require.Len(t, served, 1)
+			assert.NotContains(t, served[0], "secret")
+		})
+	}
+}
+
+func TestFailoverSender_CircuitBreaker(t *testing.T) {
+	var primaryStatus, backupStatus, primaryCalls, backupCalls atomic.Int32
+	primaryStatus.Store(http.StatusInternalServerError)
+	backupStatus.Store(http.StatusAccepted)
+	sender, err := webhook.NewFailoverSender([]*webhook.MessageSender{
+		endpointServer(t, &primaryStatus, &primaryCalls, "primary"),
+		endpointServer(t, &backupStatus, &backupCalls, "backup"),
+	}, webhook.WithCircuitBreaker(2, 100*time.Millisecond))
+	require.NoError(t, err)
+	msg := &message.Message{ID: "1", To: "+905551234567", Content: "hello"}
+
+	// two failures open the circuit of the primary, so the third send skips it
+	for range 3 {
+		res, err := sender.Send(context.Background(), msg)
+		require.NoError(t, err)
+		assert.Equal(t, "backup", res.MessageID)
+	}
+	assert.Equal(t, int32(2), primaryCalls.Load())
+
+	// after the cooldown the recovered primary is tried again and serves
+	primaryStatus.Store(http.StatusAccepted)
+	time.Sleep(150 * time.Millisecond)
+	res, err := sender.Send(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", res.MessageID)
+	assert.Equal(t, int32(3), primaryCalls.Load())
+}
+
+func TestFailoverSender_NoHealthyEndpoint(t *testing.T) {
+	var status, calls atomic.Int32
+	status.Store(http.StatusBadGateway)
+	sender, err := webhook.NewFailoverSender([]*webhook.MessageSender{endpointServer(t, &status, &calls, "primary")},
+		webhook.WithCircuitBreaker(1, time.Hour))
+	require.NoError(t, err)
+	msg := &message.Message{ID: "1", To: "+905551234567", Content: "hello"}
+
+	_, err = sender.Send(context.Background(), msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "received status 502")
+
+	_, err = sender.Send(context.Background(), msg)
+	require.ErrorIs(t, err, webhook.ErrNoHealthyEndpoint)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestNewFailoverSender_NoEndpoints(t *testing.T) {
+	_, err := webhook.NewFailoverSender(nil)
+
+	require.Error(t, err)
+}
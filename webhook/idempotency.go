@@ -0,0 +1,18 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// IdempotencyKeyHeader is the HTTP header carrying the idempotency key of a request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyKey returns the idempotency key of a request delivering the messages with the given IDs: the same IDs
+// always give the same key, so a provider receiving a request again, e.g. when it is retried after a timeout that
+// left its outcome unknown, can tell it was already served. The key does not reveal the IDs.
+func IdempotencyKey(ids ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(ids, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of "<timestamp>.<body>", keyed with the subscription secret.
+	SignatureHeader = "X-Signature"
+	// TimestampHeader carries the Unix time the payload was signed at, letting receivers reject replays.
+	TimestampHeader = "X-Signature-Timestamp"
+	// EventHeader carries the event type of the payload.
+	EventHeader = "X-Event-Type"
+)
+
+// NotifierOptFunc configures optional behavior on NotifierOptions.
+type NotifierOptFunc func(options *NotifierOptions)
+
+// NotifierOptions holds event delivery settings.
+type NotifierOptions struct {
+	attempts int             // deliveries tried per subscription before giving up
+	backoff  time.Duration   // wait before the second attempt, doubled for every further one
+	onError  func(err error) // called with the error of every delivery that exhausted its attempts
+}
+
+// defaultNotifierOpts returns default NotifierOptions: three attempts, starting one second apart.
+func defaultNotifierOpts() *NotifierOptions {
+	return &NotifierOptions{
+		attempts: 3,
+		backoff:  time.Second,
+		onError:  func(error) {},
+	}
+}
+
+// WithAttempts sets how many times an event is delivered to a subscription before giving up. Values below 1 keep the default.
+func WithAttempts(n int) NotifierOptFunc {
+	return func(options *NotifierOptions) {
+		if n > 0 {
+			options.attempts = n
+		}
+	}
+}
+
+// WithBackoff sets the wait before retrying a failed delivery. It doubles after every further failure.
+func WithBackoff(d time.Duration) NotifierOptFunc {
+	return func(options *NotifierOptions) {
+		options.backoff = d
+	}
+}
+
+// WithErrorHandler sets a function receiving the error of every delivery that failed all attempts, e.g. to log it.
+func WithErrorHandler(fn func(err error)) NotifierOptFunc {
+	return func(options *NotifierOptions) {
+		options.onError = fn
+	}
+}
+
+// EventNotifier delivers message events to the callback URLs of subscriptions.
+// Each payload is signed with the subscription secret, see SignatureHeader.
+type EventNotifier struct {
+	client        *http.Client                   // HTTP client for executing requests
+	subscriptions message.SubscriptionRepository // source of the subscriptions to notify
+	opts          *NotifierOptions               // delivery configuration options
+}
+
+// Ensure EventNotifier implements the message.Notifier interface.
+var _ message.Notifier = (*EventNotifier)(nil)
+
+// NewEventNotifier constructs an EventNotifier that posts events with client to the subscriptions stored in subscriptions,
+// applying any provided functional options.
+func NewEventNotifier(client *http.Client, subscriptions message.SubscriptionRepository, optFuncs ...NotifierOptFunc) *EventNotifier {
+	opts := defaultNotifierOpts()
+	// apply each configuration option
+	for _, f := range optFuncs {
+		f(opts)
+	}
+	return &EventNotifier{
+		client:        client,
+		subscriptions: subscriptions,
+		opts:          opts,
+	}
+}
+
+// Notify delivers e in the background to every subscription of the message's tenant that asks for its type.
+// Deliveries outlive ctx's cancellation, so an event is not lost when the triggering request ends.
+func (n *EventNotifier) Notify(ctx context.Context, e *message.Event) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := n.notify(ctx, e); err != nil {
+			n.opts.onError(err)
+		}
+	}()
+}
+
+// notify delivers e to the interested subscriptions one after another.
+// Failed deliveries are reported to the error handler; only failing to look up subscriptions is returned.
+func (n *EventNotifier) notify(ctx context.Context, e *message.Event) error {
+	subs, err := n.subscriptions.ListSubscriptions(message.WithTenant(ctx, e.Message.Tenant))
+	if err != nil {
+		return errors.Wrap(err, "listing subscriptions")
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "marshaling event")
+	}
+	for _, sub := range subs {
+		if !sub.Wants(e.Type) {
+			continue
+		}
+		if err := n.deliver(ctx, sub, e.Type, body); err != nil {
+			n.opts.onError(errors.Wrapf(err, "notifying subscription %s", sub.ID))
+		}
+	}
+	return nil
+}
+
+// deliver posts body to the subscription, retrying with exponential backoff until it is accepted or attempts run out.
+func (n *EventNotifier) deliver(ctx context.Context, sub *message.Subscription, eventType string, body []byte) error {
+	backoff := n.opts.backoff
+	var err error
+	for attempt := 1; attempt <= n.opts.attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		if err = n.post(ctx, sub, eventType, body); err == nil {
+			return nil
+		}
+	}
+	return errors.Wrapf(err, "giving up after %d attempts", n.opts.attempts)
+}
+
+// post sends a single signed delivery. Any 2xx response counts as accepted.
+func (n *EventNotifier) post(ctx context.Context, sub *message.Subscription, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, timestamp, body))
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "sending request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("sending request: received status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the signature of body sent at timestamp, as carried in SignatureHeader.
+// Receivers recompute it with their copy of the secret and compare it in constant time.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,159 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSubscriptions serves a fixed list of subscriptions and records the tenant they were listed for.
+type stubSubscriptions struct {
+	message.SubscriptionRepository
+	subs   []*message.Subscription
+	tenant string
+}
+
+func (s *stubSubscriptions) ListSubscriptions(ctx context.Context) ([]*message.Subscription, error) {
+	s.tenant, _ = message.TenantFromContext(ctx)
+	return s.subs, nil
+}
+
+// delivery is a request received by a test callback server.
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+// callbackServer answers deliveries with the given statuses in turn, then with 200, and reports every delivery on the returned channel.
+func callbackServer(t *testing.T, statuses ...int) (*httptest.Server, <-chan delivery) {
+	t.Helper()
+	deliveries := make(chan delivery, 10)
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		mu.Unlock()
+		w.WriteHeader(status)
+		deliveries <- delivery{header: r.Header.Clone(), body: body}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, deliveries
+}
+
+func receive(t *testing.T, deliveries <-chan delivery) delivery {
+	t.Helper()
+	select {
+	case d := <-deliveries:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery received")
+		return delivery{}
+	}
+}
+
+func sentEvent() *message.Event {
+	msg := &message.Message{ID: "1", To: "+905551234567", Tenant: "acme"}
+	_ = msg.SetSent("ext-1", time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	return message.NewEvent(message.EventMessageSent, msg, nil)
+}
+
+func TestEventNotifier_DeliversSignedEvents(t *testing.T) {
+	srv, deliveries := callbackServer(t)
+	subs := &stubSubscriptions{subs: []*message.Subscription{
+		{ID: "1", URL: srv.URL, Secret: "s3cret", Events: []string{message.EventMessageSent}},
+	}}
+	notifier := webhook.NewEventNotifier(srv.Client(), subs)
+
+	notifier.Notify(context.Background(), sentEvent())
+
+	d := receive(t, deliveries)
+	assert.Equal(t, "acme", subs.tenant, "only subscriptions of the message's tenant are notified")
+	assert.Equal(t, message.EventMessageSent, d.header.Get(webhook.EventHeader))
+	timestamp := d.header.Get(webhook.TimestampHeader)
+	require.NotEmpty(t, timestamp)
+	assert.Equal(t, webhook.Sign("s3cret", timestamp, d.body), d.header.Get(webhook.SignatureHeader))
+	var e message.Event
+	require.NoError(t, json.Unmarshal(d.body, &e))
+	assert.Equal(t, message.EventMessageSent, e.Type)
+	assert.Equal(t, "ext-1", e.Message.MessageID)
+	assert.Equal(t, "acme", e.Message.Tenant)
+}
+
+func TestEventNotifier_SkipsUninterestedSubscriptions(t *testing.T) {
+	srv, deliveries := callbackServer(t)
+	subs := &stubSubscriptions{subs: []*message.Subscription{
+		{ID: "1", URL: srv.URL + "/failed", Events: []string{message.EventMessageFailed}},
+		{ID: "2", URL: srv.URL + "/sent", Events: []string{message.EventMessageSent}},
+	}}
+	notifier := webhook.NewEventNotifier(srv.Client(), subs)
+
+	notifier.Notify(context.Background(), sentEvent())
+
+	receive(t, deliveries)
+	select {
+	case <-deliveries:
+		t.Fatal("subscription not asking for the event was notified")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEventNotifier_RetriesFailedDeliveries(t *testing.T) {
+	srv, deliveries := callbackServer(t, http.StatusInternalServerError, http.StatusBadGateway)
+	subs := &stubSubscriptions{subs: []*message.Subscription{
+		{ID: "1", URL: srv.URL, Events: []string{message.EventMessageSent}},
+	}}
+	errs := make(chan error, 1)
+	notifier := webhook.NewEventNotifier(srv.Client(), subs,
+		webhook.WithBackoff(time.Millisecond),
+		webhook.WithErrorHandler(func(err error) { errs <- err }),
+	)
+
+	notifier.Notify(context.Background(), sentEvent())
+
+	for range 3 {
+		receive(t, deliveries)
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("delivery accepted on the third attempt reported an error: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEventNotifier_ReportsExhaustedDeliveries(t *testing.T) {
+	srv, deliveries := callbackServer(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	subs := &stubSubscriptions{subs: []*message.Subscription{
+		{ID: "7", URL: srv.URL, Events: []string{message.EventMessageSent}},
+	}}
+	errs := make(chan error, 1)
+	notifier := webhook.NewEventNotifier(srv.Client(), subs,
+		webhook.WithAttempts(2),
+		webhook.WithBackoff(time.Millisecond),
+		webhook.WithErrorHandler(func(err error) { errs <- err }),
+	)
+
+	notifier.Notify(context.Background(), sentEvent())
+
+	receive(t, deliveries)
+	receive(t, deliveries)
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "notifying subscription 7: giving up after 2 attempts: sending request: received status 500")
+	case <-time.After(5 * time.Second):
+		t.Fatal("exhausted delivery was not reported")
+	}
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"text/template"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/pkg/errors"
+)
+
+// PayloadData is what a payload template set with WithPayloadTemplate is executed with.
+type PayloadData struct {
+	Message *message.Message  // message being sent
+	Content string            // content of Message, truncated to the character limit
+	Fields  map[string]string // static fields configured along with the template
+
+	IdempotencyKey string // idempotency key of Message, see IdempotencyKey
+}
+
+// WithPayloadTemplate replaces the default request body, see RequestPayload, with the output of the Go template text
+// executed with the PayloadData of each message, so the sender can match the schema of any provider. The json
+// function renders a value as JSON and must be used to quote strings, e.g.
+//
+//	{"phone":{{json .Message.To}},"text":{{json .Content}},"from":{{json .Fields.sender}}}
+//
+// fields are static values available to the template as .Fields. NewWebhookSender fails if text does not parse,
+// sends fail if it does not render valid JSON. An empty text keeps the default body.
+func WithPayloadTemplate(text string, fields map[string]string) OptFunc {
+	return func(options *Options) {
+		options.payloadTemplate = text
+		options.payloadFields = fields
+	}
+}
+
+// payloadFuncs are the functions available to payload templates.
+var payloadFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// parsePayloadTemplate parses the payload template configured in opts, or returns nil if there is none.
+func parsePayloadTemplate(opts *Options) (*template.Template, error) {
+	if opts.payloadTemplate == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("payload").Funcs(payloadFuncs).Option("missingkey=zero").Parse(opts.payloadTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing payload template")
+	}
+	return tmpl, nil
+}
+
+// renderPayload returns the request body of msg rendered from the configured payload template.
+func (s *MessageSender) renderPayload(msg *message.Message) ([]byte, error) {
+	truncated, err := s.content(msg)
+	if err != nil {
+		return nil, errors.Wrap(err, "truncating message")
+	}
+	var buf bytes.Buffer
+	data := &PayloadData{Message: msg, Content: truncated, Fields: s.opts.payloadFields, IdempotencyKey: IdempotencyKey(msg.ID)}
+	if err := s.payload.Execute(&buf, data); err != nil {
+		return nil, errors.Wrap(err, "rendering payload")
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, errors.New("rendering payload: invalid JSON")
+	}
+	return buf.Bytes(), nil
+}
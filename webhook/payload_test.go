@@ -0,0 +1,61 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageSender_Send_PayloadTemplate(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL,
+		webhook.WithCharacterLimit(5),
+		webhook.WithPayloadTemplate(
+			`{"phone":{{json .Message.To}},"text":{{json .Content}},"from":{{json .Fields.sender}},"ref":{{json .Message.ID}}}`,
+			map[string]string{"sender": "Insider"},
+		),
+	)
+	require.NoError(t, err)
+
+	_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: `say "hello"`})
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"phone":"+905551234567","text":"say \"","from":"Insider","ref":"1"}`, string(body))
+}
+
+func TestNewWebhookSender_InvalidPayloadTemplate(t *testing.T) {
+	_, err := webhook.NewWebhookSender(http.DefaultClient, "http://localhost", webhook.WithPayloadTemplate(`{"to":{{json .Message.To}`, nil))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parsing payload template")
+}
+
+func TestMessageSender_Send_PayloadTemplateInvalidJSON(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithPayloadTemplate(`{"to":{{.Message.To}}}`, nil))
+	require.NoError(t, err)
+
+	_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid JSON")
+	assert.Zero(t, calls)
+}
@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithHealthProbe sets the request Ping probes the provider with: method, HEAD if empty, to path, resolved against
+// the webhook URL, e.g. "/health", or an absolute URL. Without a health probe, Ping sends a HEAD request to the webhook
+// URL, and FailoverSender tries endpoints again with a real send once their cooldown has passed.
+func WithHealthProbe(method, path string) OptFunc {
+	return func(options *Options) {
+		if method == "" {
+			method = http.MethodHead
+		}
+		options.probeMethod = strings.ToUpper(method)
+		options.probePath = path
+	}
+}
+
+// probeURL returns the URL the health probe of opts is sent to for the webhook URL webhookURL.
+func probeURL(webhookURL string, opts *Options) (string, error) {
+	if opts.probePath == "" {
+		return webhookURL, nil
+	}
+	base, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing webhook url")
+	}
+	ref, err := url.Parse(opts.probePath)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing health probe path")
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// Ping probes the availability of the provider without sending a message, with the request set with WithHealthProbe
+// carrying the configured headers. The provider is available if it answers with any status below 500, as even a
+// rejected probe proves it serves requests. Returns a *message.ProviderError for 5xx responses.
+func (s *MessageSender) Ping(ctx context.Context) error {
+	method := s.opts.probeMethod
+	if method == "" {
+		method = http.MethodHead
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.probeURL, nil)
+	if err != nil {
+		return errors.Wrap(err, "creating probe request")
+	}
+	req.Header = s.requestHeaders()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "probing provider")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return errors.Wrap(providerError(resp), "probing provider")
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+	return nil
+}
+
+// probes reports whether a health probe is configured with WithHealthProbe.
+func (s *MessageSender) probes() bool {
+	return s.opts.probeMethod != ""
+}
@@ -0,0 +1,138 @@
+package webhook_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageSender_Ping(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []webhook.OptFunc
+		status     int
+		wantMethod string
+		wantPath   string
+		wantErr    string
+	}{
+		{name: "default_head_to_webhook_url", status: http.StatusMethodNotAllowed, wantMethod: http.MethodHead, wantPath: "/send"},
+		{
+			name:       "health_path",
+			opts:       []webhook.OptFunc{webhook.WithHealthProbe("get", "/health")},
+			status:     http.StatusOK,
+			wantMethod: http.MethodGet,
+			wantPath:   "/health",
+		},
+		{
+			name:       "relative_path",
+			opts:       []webhook.OptFunc{webhook.WithHealthProbe("", "status")},
+			status:     http.StatusNoContent,
+			wantMethod: http.MethodHead,
+			wantPath:   "/status",
+		},
+		{
+			name:       "unavailable",
+			opts:       []webhook.OptFunc{webhook.WithHealthProbe("", "/health")},
+			status:     http.StatusServiceUnavailable,
+			wantMethod: http.MethodHead,
+			wantPath:   "/health",
+			wantErr:    "received status 503",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method, path, auth string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, path, auth = r.Method, r.URL.Path, r.Header.Get("X-Api-Key")
+				w.WriteHeader(tt.status)
+			}))
+			t.Cleanup(srv.Close)
+			opts := append([]webhook.OptFunc{webhook.WithHeader("X-Api-Key", "secret")}, tt.opts...)
+			sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL+"/send", opts...)
+			require.NoError(t, err)
+
+			err = sender.Ping(context.Background())
+
+			assert.Equal(t, tt.wantMethod, method)
+			assert.Equal(t, tt.wantPath, path)
+			assert.Equal(t, "secret", auth)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			var perr *message.ProviderError
+			require.ErrorAs(t, err, &perr)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestMessageSender_Ping_ConnectionError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL)
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, sender.Ping(context.Background()), "probing provider")
+}
+
+func TestFailoverSender_ProbesBeforeRetryingEndpoint(t *testing.T) {
+	var healthy atomic.Bool
+	var sends, probes atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			probes.Add(1)
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		}
+		sends.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"primary"}`))
+	}))
+	t.Cleanup(primary.Close)
+	primarySender, err := webhook.NewWebhookSender(primary.Client(), primary.URL+"/send", webhook.WithHealthProbe("GET", "/health"))
+	require.NoError(t, err)
+	var backupStatus, backupCalls atomic.Int32
+	backupStatus.Store(http.StatusAccepted)
+	sender, err := webhook.NewFailoverSender([]*webhook.MessageSender{
+		primarySender,
+		endpointServer(t, &backupStatus, &backupCalls, "backup"),
+	}, webhook.WithCircuitBreaker(1, 20*time.Millisecond))
+	require.NoError(t, err)
+	msg := &message.Message{ID: "1", To: "+905551234567", Content: "hello"}
+
+	// the failed send opens the circuit of the primary
+	res, err := sender.Send(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Equal(t, "backup", res.MessageID)
+
+	// after the cooldown the primary is probed, and skipped without a send while the probe fails
+	time.Sleep(30 * time.Millisecond)
+	res, err = sender.Send(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Equal(t, "backup", res.MessageID)
+	assert.Equal(t, int32(1), sends.Load())
+	assert.Equal(t, int32(1), probes.Load())
+
+	// once the probe passes, the primary serves again
+	healthy.Store(true)
+	time.Sleep(30 * time.Millisecond)
+	res, err = sender.Send(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", res.MessageID)
+	assert.Equal(t, int32(2), probes.Load())
+}
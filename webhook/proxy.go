@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyFunc returns the Proxy function of an http.Transport routing webhook requests through the HTTP(S) proxy at
+// proxyURL, e.g. for corporate egress, for use in the transport of the client given to NewWebhookSender.
+// Requests to the hosts of noProxy, a comma separated list in the format of the NO_PROXY environment variable, and to
+// localhost go direct. Unlike http.ProxyFromEnvironment this applies to the webhook senders only.
+// Returns an error if proxyURL is not an http, https or socks5 URL.
+func ProxyFunc(proxyURL, noProxy string) (func(*http.Request) (*url.URL, error), error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing proxy url")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, errors.Errorf("parsing proxy url: unsupported scheme %q", u.Scheme)
+	}
+	cfg := &httpproxy.Config{HTTPProxy: proxyURL, HTTPSProxy: proxyURL, NoProxy: noProxy}
+	proxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
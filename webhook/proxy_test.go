@@ -0,0 +1,47 @@
+package webhook_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyFunc(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+	}))
+	t.Cleanup(proxy.Close)
+	proxyFunc, err := webhook.ProxyFunc(proxy.URL, "internal.invalid")
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{Proxy: proxyFunc}}
+	msg := &message.Message{ID: "1", To: "+905551234567", Content: "hello"}
+
+	direct, err := webhook.NewWebhookSender(client, "http://internal.invalid/send")
+	require.NoError(t, err)
+	_, err = direct.Send(context.Background(), msg)
+	require.Error(t, err, "hosts of noProxy must not go through the proxy")
+
+	sender, err := webhook.NewWebhookSender(client, "http://provider.invalid/send")
+	require.NoError(t, err)
+	res, err := sender.Send(context.Background(), msg)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ext-1", res.MessageID)
+	assert.Equal(t, []string{"http://provider.invalid/send"}, proxied)
+}
+
+func TestProxyFunc_Invalid(t *testing.T) {
+	for _, proxyURL := range []string{"ftp://proxy.example.com", "proxy.example.com:3128", "http://[::1"} {
+		_, err := webhook.ProxyFunc(proxyURL, "")
+		assert.Error(t, err, proxyURL)
+	}
+}
@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ClientTLSConfig returns the TLS configuration of an HTTP client talking to providers that require mutual TLS,
+// for use as the TLSClientConfig of the http.Transport of the client given to NewWebhookSender.
+// The client authenticates with the certificate and private key of the PEM files certFile and keyFile, and trusts
+// only the CA certificates of the PEM bundle caFile. Without certFile and keyFile no client certificate is presented,
+// without caFile the system roots are trusted.
+// Returns an error if only one of certFile and keyFile is given, or a file cannot be loaded.
+func ClientTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("loading client certificate: certificate and key files must be given together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading client certificate")
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading CA bundle")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("loading CA bundle: no certificates in %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
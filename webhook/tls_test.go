@@ -0,0 +1,124 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issue returns a certificate for name signed by parent, self-signed if parent is nil, along with its key.
+func issue(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// writePEM writes the PEM block of type typ holding der to a file in dir and returns its path.
+func writePEM(t *testing.T, dir, file, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, file)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600))
+	return path
+}
+
+func TestClientTLSConfig_MutualTLS(t *testing.T) {
+	ca, caKey := issue(t, "test CA", nil, nil)
+	serverCert, serverKey := issue(t, "127.0.0.1", ca, caKey)
+	clientCert, clientKey := issue(t, "insider-msg-sender", ca, caKey)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"` + r.TLS.PeerCertificates[0].Subject.CommonName + `"}`))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	require.NoError(t, err)
+	certFile := writePEM(t, dir, "client.crt", "CERTIFICATE", clientCert.Raw)
+	keyFile := writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER)
+	caFile := writePEM(t, dir, "ca.crt", "CERTIFICATE", ca.Raw)
+	msg := &message.Message{ID: "1", To: "+905551234567", Content: "hello"}
+
+	t.Run("with_client_certificate", func(t *testing.T) {
+		cfg, err := webhook.ClientTLSConfig(certFile, keyFile, caFile)
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		sender, err := webhook.NewWebhookSender(client, srv.URL)
+		require.NoError(t, err)
+
+		res, err := sender.Send(context.Background(), msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, "insider-msg-sender", res.MessageID)
+	})
+	t.Run("without_client_certificate", func(t *testing.T) {
+		cfg, err := webhook.ClientTLSConfig("", "", caFile)
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		sender, err := webhook.NewWebhookSender(client, srv.URL)
+		require.NoError(t, err)
+
+		_, err = sender.Send(context.Background(), msg)
+
+		require.Error(t, err)
+	})
+}
+
+func TestClientTLSConfig_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	_, err := webhook.ClientTLSConfig("client.crt", "", "")
+	assert.Error(t, err)
+	_, err = webhook.ClientTLSConfig(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key"), "")
+	assert.Error(t, err)
+	_, err = webhook.ClientTLSConfig("", "", notPEM)
+	assert.ErrorContains(t, err, "no certificates")
+}
@@ -9,6 +9,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/grustamli/insider-msg-sender/message"
@@ -20,35 +24,86 @@ type OptFunc func(options *Options)
 
 // Options holds sender customization settings such as header overrides and character limits.
 type Options struct {
-	characterLimit int         // max characters to include before truncation
-	headers        http.Header // custom HTTP headers to include on each request
+	characterLimit  int               // max characters to include before truncation; zero disables truncation
+	segmentLimit    int               // max SMS segments to include before truncation; overrides characterLimit if set
+	headers         http.Header       // custom HTTP headers to include on each request; read-only once the sender is built
+	attempts        int               // requests tried per message before its send fails
+	backoff         time.Duration     // wait before the second request, doubled for every further one
+	signSecret      string            // secret request bodies are signed with; empty disables signing
+	signHeader      string            // HTTP header carrying the signature
+	accepted        []int             // response status codes a provider accepts a message with
+	fields          ResponseFields    // response fields a provider accepts a message with
+	payloadTemplate string            // Go template request bodies are rendered from; the default body if empty
+	payloadFields   map[string]string // static fields available to the payload template
+	observe         RequestObserver   // called with the outcome of every request
+	batchSize       int               // messages posted together in one request by SendBatch
+	requestTimeout  time.Duration     // deadline of each send, its retries included; none if zero
+	method          string            // HTTP method of requests
+	contentType     ContentType       // encoding of request bodies
+	probeMethod     string            // HTTP method of health probes; empty if no probe is configured
+	probePath       string            // path or URL health probes are sent to, resolved against the webhook URL
+	gzipMinBytes    int               // size from which request bodies are gzip compressed; zero disables compression
 }
 
-// defaultOpts returns default Options with an empty header map.
+// defaultOpts returns default Options with an empty header map, trying each message once.
 func defaultOpts() *Options {
 	return &Options{
-		headers: make(http.Header),
+		headers:     make(http.Header),
+		attempts:    1,
+		method:      http.MethodPost,
+		contentType: ContentTypeJSON,
+		accepted:    []int{http.StatusAccepted},
+		fields:      defaultResponseFields,
+		observe:     func(string, int, time.Duration, int64) {},
 	}
 }
 
+// RequestObserver is called after every request of a MessageSender, retries included, with the endpoint it was sent
+// to, identified by its URL without query and credentials, the response status code, zero if the request got no
+// response, the time the request took until the response headers arrived, and the size of the request body in bytes.
+type RequestObserver func(endpoint string, status int, duration time.Duration, payloadBytes int64)
+
+// ResponseFields locates the fields of the JSON response body a provider accepts a message with.
+// Fields are given as paths of dot separated object keys and array indexes, e.g. "messageId" for a top-level field or
+// "messages.0.id" for the id of the first element of the messages array.
+type ResponseFields struct {
+	MessageID   string // path of the field carrying the provider message ID, a string or a number
+	Status      string // path of the field carrying the acceptance status; not checked if empty
+	StatusValue string // value of Status meaning the message was accepted
+}
+
+// defaultResponseFields are the response fields of the default provider, e.g. {"message":"Accepted","messageId":"..."}.
+var defaultResponseFields = ResponseFields{MessageID: "messageId", Status: "message", StatusValue: "Accepted"}
+
 // MessageSender sends Message entities by POSTing a JSON payload to a webhook URL.
-// It supports per-request headers and content truncation via functional options.
+// It supports per-request headers, content truncation and other methods and content types via functional options.
 type MessageSender struct {
-	client *http.Client // HTTP client for executing requests
-	url    string       // target webhook URL
-	opts   *Options     // sender configuration options
+	client   *http.Client       // HTTP client for executing requests
+	url      string             // target webhook URL
+	endpoint string             // url without query and credentials, identifying the endpoint in observations
+	probeURL string             // URL health probes are sent to
+	opts     *Options           // sender configuration options
+	payload  *template.Template // template request bodies are rendered from; nil for the default body
 }
 
 // Ensure MessageSender implements the message.Sender interface.
 var _ message.Sender = (*MessageSender)(nil)
 
-// WithCharacterLimit sets a maximum character count for the message content.
+// WithCharacterLimit sets a maximum character count for the message content. Zero disables truncation.
 func WithCharacterLimit(limit int) OptFunc {
 	return func(options *Options) {
 		options.characterLimit = limit
 	}
 }
 
+// WithSegmentLimit truncates the message content to fit in at most segments SMS segments, GSM-7 or UCS-2 encoded as
+// counted by message.SMSSegments, instead of a character count. Zero keeps the character limit.
+func WithSegmentLimit(segments int) OptFunc {
+	return func(options *Options) {
+		options.segmentLimit = segments
+	}
+}
+
 // WithHeader adds a custom HTTP header for each webhook request.
 func WithHeader(key, val string) OptFunc {
 	return func(options *Options) {
@@ -56,22 +111,88 @@ func WithHeader(key, val string) OptFunc {
 	}
 }
 
+// WithRetries tries to send each message up to attempts times, waiting backoff before the second request and twice as
+// long before every further one, so transient provider errors do not fail the send. Only connection errors and 5xx
+// responses are retried; other responses, including 429 Too Many Requests, fail the send right away.
+// A provider that accepted a message but failed to answer may receive it twice. Values of attempts below 1 are ignored.
+func WithRetries(attempts int, backoff time.Duration) OptFunc {
+	return func(options *Options) {
+		if attempts >= 1 {
+			options.attempts = attempts
+			options.backoff = backoff
+		}
+	}
+}
+
+// WithRequestTimeout gives every send its own deadline of timeout, covering its retries and the backoff in between,
+// independent of the timeout of the http.Client, which applies to each request. A slow provider thus holds the
+// worker sending a message for at most timeout. Non-positive values set no deadline.
+func WithRequestTimeout(timeout time.Duration) OptFunc {
+	return func(options *Options) {
+		options.requestTimeout = timeout
+	}
+}
+
+// WithHMACSignature signs the body of each request with secret, so the provider can verify it originates from this
+// service. The signature is carried in headerName, SignatureHeader if empty, computed as by Sign over the body and the
+// Unix time it was sent at, which is carried in TimestampHeader. An empty secret disables signing.
+func WithHMACSignature(secret, headerName string) OptFunc {
+	return func(options *Options) {
+		if headerName == "" {
+			headerName = SignatureHeader
+		}
+		options.signSecret = secret
+		options.signHeader = headerName
+	}
+}
+
+// WithAcceptedStatus sets the response status codes a provider accepts a message with, 202 Accepted by default.
+// Responses with any other status fail the send. Without codes the default is kept.
+func WithAcceptedStatus(codes ...int) OptFunc {
+	return func(options *Options) {
+		if len(codes) > 0 {
+			options.accepted = codes
+		}
+	}
+}
+
+// WithResponseFields sets the fields of the JSON response body a provider accepts a message with,
+// {"message":"Accepted","messageId":"..."} by default. A blank fields.MessageID keeps the default fields.
+func WithResponseFields(fields ResponseFields) OptFunc {
+	return func(options *Options) {
+		if fields.MessageID != "" {
+			options.fields = fields
+		}
+	}
+}
+
+// WithRequestObserver sets a function called with the outcome of every request, e.g. to record provider latency and
+// status codes as metrics.
+func WithRequestObserver(observe RequestObserver) OptFunc {
+	return func(options *Options) {
+		if observe != nil {
+			options.observe = observe
+		}
+	}
+}
+
 // RequestPayload defines the JSON structure sent to the webhook endpoint.
 type RequestPayload struct {
-	To      string `json:"to"`      // recipient phone number
-	Content string `json:"content"` // message body (possibly truncated)
+	To             string `json:"to"`             // recipient phone number, email address or device token
+	Content        string `json:"content"`        // message body (possibly truncated)
+	IdempotencyKey string `json:"idempotencyKey"` // key the provider can deduplicate retried messages with
 }
 
-// Response represents the JSON response from the webhook provider.
+// Response represents the JSON response from the webhook provider, read from its configured ResponseFields.
 // Message indicates success status, MessageID is the provider-assigned ID.
 type Response struct {
-	Message   string `json:"message"`
-	MessageID string `json:"messageId"`
+	Message   string
+	MessageID string
 }
 
-// validate checks that the webhook response indicates acceptance and contains a non-blank ID.
-func (r *Response) validate() error {
-	if r.Message != "Accepted" {
+// validate checks that the webhook response indicates acceptance as expected by fields and contains a non-blank ID.
+func (r *Response) validate(fields ResponseFields) error {
+	if fields.Status != "" && r.Message != fields.StatusValue {
 		return fmt.Errorf("invalid message: %s", r.Message)
 	}
 	if r.MessageID == "" {
@@ -81,97 +202,338 @@ func (r *Response) validate() error {
 }
 
 // NewWebhookSender constructs a MessageSender that posts to webhookURL using client,
-// applying any provided functional options.
+// applying any provided functional options. Returns an error if the payload template does not parse, or if the
+// content type is unknown or cannot carry bulk payloads.
 func NewWebhookSender(client *http.Client, webhookURL string, optFuncs ...OptFunc) (*MessageSender, error) {
 	opts := defaultOpts()
 	// apply each configuration option
 	for _, f := range optFuncs {
 		f(opts)
 	}
+	if err := validateEncoding(opts); err != nil {
+		return nil, errors.Wrap(err, "creating webhook sender")
+	}
+	payload, err := parsePayloadTemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+	probe, err := probeURL(webhookURL, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating webhook sender")
+	}
 	return &MessageSender{
-		client: client,
-		url:    webhookURL,
-		opts:   opts,
+		client:   client,
+		url:      webhookURL,
+		endpoint: endpointName(webhookURL),
+		probeURL: probe,
+		opts:     opts,
+		payload:  payload,
 	}, nil
 }
 
 // Send constructs and executes an HTTP request for the given Message.
-// It enforces status code 202 Accepted, parses the JSON body, validates it, and
+// It enforces an accepted status code, 202 Accepted by default, parses the JSON body, validates it, and
 // returns a SendResult containing the external message ID and send timestamp.
+// A 429 Too Many Requests response is returned as a message.RateLimitedError honoring its Retry-After header.
+// Transient failures are retried as configured with WithRetries, within the deadline set with WithRequestTimeout.
 func (s *MessageSender) Send(ctx context.Context, msg *message.Message) (*message.SendResult, error) {
-	// build HTTP request
-	req, err := s.createRequest(ctx, msg)
+	ctx, cancel := s.withRequestTimeout(ctx)
+	defer cancel()
+	var res *message.SendResult
+	err := s.retry(ctx, func() (err error) {
+		res, err = s.send(ctx, msg)
+		return err
+	})
+	return res, err
+}
+
+// withRequestTimeout returns ctx with the deadline of a send configured with WithRequestTimeout, if any.
+func (s *MessageSender) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.opts.requestTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.opts.requestTimeout)
+}
+
+// retry calls attempt until it succeeds, fails with a non-transient error or the configured attempts are used up,
+// waiting the configured backoff in between.
+func (s *MessageSender) retry(ctx context.Context, attempt func() error) error {
+	backoff := s.opts.backoff
+	for n := 1; ; n++ {
+		err := attempt()
+		var transient *transientError
+		if err == nil || !errors.As(err, &transient) || n >= s.opts.attempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(err, "stopped retrying after %d attempts", n)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// transientError is a failure of a single request that may succeed when retried:
+// the connection failed or the provider answered with a 5xx status.
+type transientError struct {
+	err error // the failure
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+
+func (e *transientError) Unwrap() error { return e.err }
+
+// send makes a single request delivering msg.
+func (s *MessageSender) send(ctx context.Context, msg *message.Message) (*message.SendResult, error) {
+	payload, err := s.body(msg)
 	if err != nil {
 		return nil, err
 	}
-	// capture send timestamp before network call
-	sentTimestamp := time.Now()
-	// execute request
-	resp, err := s.client.Do(req)
+	body, err := s.encode(payload)
 	if err != nil {
-		return nil, errors.Wrap(err, "sending request")
+		return nil, err
 	}
-	defer resp.Body.Close()
-	// enforce expected status
-	if resp.StatusCode != http.StatusAccepted {
-		return nil, errors.Errorf("sending request: received status %d", resp.StatusCode)
+	// build HTTP request
+	req, err := s.createRequest(ctx, body, IdempotencyKey(msg.ID))
+	if err != nil {
+		return nil, err
 	}
-	// parse and validate response
-	res, err := s.parseResponse(resp.Body)
+	// capture send timestamp before network call
+	sentTimestamp := time.Now()
+	doc, status, err := s.do(req)
 	if err != nil {
-		return nil, errors.Wrap(err, "parsing response")
+		return nil, err
 	}
-	if err := res.validate(); err != nil {
+	// validate response
+	res := s.responseOf(doc)
+	if err := res.validate(s.opts.fields); err != nil {
 		return nil, err
 	}
 	// return send result
 	return &message.SendResult{
-		MessageID: res.MessageID,
-		SentAt:    sentTimestamp,
+		MessageID:  res.MessageID,
+		SentAt:     sentTimestamp,
+		StatusCode: status,
+		Response:   encodeResponse(doc),
 	}, nil
 }
 
-// createRequest marshals the message into JSON, constructs an HTTP POST, and sets headers.
-func (s *MessageSender) createRequest(ctx context.Context, msg *message.Message) (*http.Request, error) {
-	payload, err := s.payloadFromMessage(msg)
+// do executes req and returns its decoded JSON response body and status code once the provider accepted it.
+// Connection failures and 5xx responses are returned as a transientError, 429 Too Many Requests as a
+// message.RateLimitedError.
+func (s *MessageSender) do(req *http.Request) (any, int, error) {
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	s.opts.observe(s.endpoint, status, time.Since(start), req.ContentLength)
 	if err != nil {
-		return nil, err
+		if req.Context().Err() != nil {
+			return nil, 0, errors.Wrap(err, "sending request")
+		}
+		return nil, 0, &transientError{err: errors.Wrap(err, "sending request")}
 	}
-	body, err := json.Marshal(payload)
+	defer resp.Body.Close()
+	// enforce expected status
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, 0, &message.RateLimitedError{
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			Err:        errors.Wrap(providerError(resp), "sending request"),
+		}
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, 0, &transientError{err: errors.Wrap(providerError(resp), "sending request")}
+	}
+	if !slices.Contains(s.opts.accepted, resp.StatusCode) {
+		return nil, 0, errors.Wrap(providerError(resp), "sending request")
+	}
+	doc, err := decodeResponse(resp.Body)
 	if err != nil {
-		return nil, errors.Wrap(err, "marshaling payload")
+		return nil, 0, errors.Wrap(err, "parsing response")
+	}
+	return doc, resp.StatusCode, nil
+}
+
+// createRequest constructs an HTTP request of the encoded body with the configured method and sets headers,
+// including the idempotency key of the request, compressing and signing the body if configured.
+func (s *MessageSender) createRequest(ctx context.Context, body []byte, key string) (*http.Request, error) {
+	body, compressed, err := s.compress(body)
+	if err != nil {
+		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, s.opts.method, s.url, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, errors.Wrap(err, "creating request")
 	}
-	s.setRequestHeaders(req)
+	req.Header = s.requestHeaders()
+	req.Header.Set(IdempotencyKeyHeader, key)
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.opts.signSecret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(s.opts.signHeader, Sign(s.opts.signSecret, timestamp, body))
+	}
 	return req, nil
 }
 
-// setRequestHeaders applies both default and configured HTTP headers to the request.
-func (s *MessageSender) setRequestHeaders(req *http.Request) {
-	req.Header = s.opts.headers
-	req.Header.Set("Accept", "application/json")
+// requestHeaders returns a new header map holding both default and configured HTTP headers, owned by a single
+// request. The configured headers are shared by all concurrent sends and are never modified after construction.
+func (s *MessageSender) requestHeaders() http.Header {
+	header := s.opts.headers.Clone()
+	header.Set("Accept", "application/json")
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", s.opts.contentType.header())
+	}
+	return header
 }
 
-// parseResponse decodes JSON from the HTTP response body into a Response struct.
-func (s *MessageSender) parseResponse(body io.ReadCloser) (*Response, error) {
-	var res Response
-	if err := json.NewDecoder(body).Decode(&res); err != nil {
+// decodeResponse decodes the JSON of an HTTP response body, keeping numbers as json.Number.
+func decodeResponse(body io.Reader) (any, error) {
+	var doc any
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
 		return nil, errors.Wrap(err, "decoding response")
 	}
-	return &res, nil
+	return doc, nil
+}
+
+// encodeResponse encodes the decoded JSON response doc back to JSON, to be kept with the message it accepted.
+// Object keys come out sorted, but the values are those the provider answered.
+func encodeResponse(doc any) json.RawMessage {
+	// doc was decoded from JSON, so it always encodes
+	data, _ := json.Marshal(doc)
+	return data
+}
+
+// responseOf reads the configured fields of the decoded JSON response doc into a Response.
+func (s *MessageSender) responseOf(doc any) *Response {
+	return &Response{
+		Message:   responseString(responseField(doc, s.opts.fields.Status)),
+		MessageID: responseString(responseField(doc, s.opts.fields.MessageID)),
+	}
+}
+
+// responseField returns the value at path in the decoded JSON doc, or nil if there is none or path is empty.
+// See ResponseFields for the syntax of paths.
+func responseField(doc any, path string) any {
+	if path == "" {
+		return nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := doc.(type) {
+		case map[string]any:
+			doc = v[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			doc = v[i]
+		default:
+			return nil
+		}
+	}
+	return doc
+}
+
+// responseString returns a string or number response field as a string, or an empty string for any other value.
+func responseString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
+
+// body returns the request body delivering msg, rendered from the payload template if one is configured.
+func (s *MessageSender) body(msg *message.Message) ([]byte, error) {
+	if s.payload != nil {
+		return s.renderPayload(msg)
+	}
+	payload, err := s.payloadFromMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling payload")
+	}
+	return body, nil
 }
 
 // payloadFromMessage constructs a RequestPayload, truncating content if necessary.
 func (s *MessageSender) payloadFromMessage(msg *message.Message) (*RequestPayload, error) {
-	truncated, err := msg.TruncatedContent(s.opts.characterLimit)
+	truncated, err := s.content(msg)
 	if err != nil {
 		return nil, errors.Wrap(err, "truncating message")
 	}
 	return &RequestPayload{
-		To:      msg.To,
-		Content: truncated,
+		To:             msg.To,
+		Content:        truncated,
+		IdempotencyKey: IdempotencyKey(msg.ID),
 	}, nil
 }
+
+// content returns the content of msg truncated to the configured segment or character limit.
+func (s *MessageSender) content(msg *message.Message) (string, error) {
+	switch {
+	case s.opts.segmentLimit > 0:
+		return msg.TruncatedSegments(s.opts.segmentLimit)
+	case s.opts.characterLimit > 0:
+		return msg.TruncatedContent(s.opts.characterLimit)
+	}
+	return msg.Content, nil
+}
+
+// maxErrorBody is the number of bytes of an error response body read for the error it describes.
+const maxErrorBody = 4 << 10
+
+// providerError returns the error described by the error response resp. Codes and descriptions are read from the
+// fields of a JSON object body commonly carrying them, such as "code" and "message"; other bodies are taken as the
+// description as a whole.
+func providerError(resp *http.Response) *message.ProviderError {
+	perr := &message.ProviderError{StatusCode: resp.StatusCode}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		perr.Message = strings.Join(strings.Fields(string(body)), " ")
+		return perr
+	}
+	for _, key := range []string{"code", "errorCode", "error_code"} {
+		if perr.Code = responseString(doc[key]); perr.Code != "" {
+			break
+		}
+	}
+	for _, key := range []string{"message", "error", "error_description", "detail"} {
+		if perr.Message = responseString(doc[key]); perr.Message != "" {
+			break
+		}
+	}
+	return perr
+}
+
+// retryAfter returns the wait a Retry-After header value asks for at now, given either in seconds or as an HTTP date.
+// Returns zero if the value is missing, malformed or in the past.
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
@@ -0,0 +1,516 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grustamli/insider-msg-sender/message"
+	"github.com/grustamli/insider-msg-sender/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageSender_Send(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL)
+	require.NoError(t, err)
+
+	res, err := sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "ext-1", res.MessageID)
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.JSONEq(t, `{"message":"Accepted","messageId":"ext-1"}`, string(res.Response))
+}
+
+func TestMessageSender_Send_Truncation(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []webhook.OptFunc
+		content string
+		want    string
+	}{
+		{name: "no limit", content: "Çağrı merkezi", want: "Çağrı merkezi"},
+		{name: "character limit", opts: []webhook.OptFunc{webhook.WithCharacterLimit(5)}, content: "Çağrı merkezi", want: "Çağrı"},
+		{
+			name:    "segment limit overrides character limit",
+			opts:    []webhook.OptFunc{webhook.WithCharacterLimit(5), webhook.WithSegmentLimit(1)},
+			content: strings.Repeat("ş", 80),
+			want:    strings.Repeat("ş", 70),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload webhook.RequestPayload
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+			}))
+			t.Cleanup(srv.Close)
+			sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, tt.opts...)
+			require.NoError(t, err)
+
+			_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: tt.content})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, payload.Content)
+		})
+	}
+}
+
+func TestMessageSender_Send_ProviderError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   message.ProviderError
+	}{
+		{
+			name:   "json_error",
+			status: http.StatusBadRequest,
+			body:   `{"code":"INVALID_NUMBER","message":"Invalid recipient"}`,
+			want:   message.ProviderError{StatusCode: http.StatusBadRequest, Code: "INVALID_NUMBER", Message: "Invalid recipient"},
+		},
+		{
+			name:   "numeric_code",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"errorCode":1001,"error":"content too long"}`,
+			want:   message.ProviderError{StatusCode: http.StatusUnprocessableEntity, Code: "1001", Message: "content too long"},
+		},
+		{
+			name:   "text_body",
+			status: http.StatusForbidden,
+			body:   "account\nsuspended\n",
+			want:   message.ProviderError{StatusCode: http.StatusForbidden, Message: "account suspended"},
+		},
+		{
+			name:   "server_error",
+			status: http.StatusBadGateway,
+			want:   message.ProviderError{StatusCode: http.StatusBadGateway},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(srv.Close)
+			sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL)
+			require.NoError(t, err)
+
+			_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+			var perr *message.ProviderError
+			require.ErrorAs(t, err, &perr)
+			assert.Equal(t, tt.want, *perr)
+		})
+	}
+}
+
+func TestMessageSender_Send_RateLimited(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		expected   time.Duration
+	}{
+		{name: "seconds", retryAfter: "120", expected: 2 * time.Minute},
+		{name: "http_date", retryAfter: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), expected: time.Hour},
+		{name: "missing", retryAfter: "", expected: 0},
+		{name: "malformed", retryAfter: "soon", expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+			t.Cleanup(srv.Close)
+			sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL)
+			require.NoError(t, err)
+
+			_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+			var limited *message.RateLimitedError
+			require.ErrorAs(t, err, &limited)
+			assert.InDelta(t, tt.expected, limited.RetryAfter, float64(2*time.Second))
+			assert.Contains(t, err.Error(), "received status 429")
+		})
+	}
+}
+
+func TestMessageSender_Send_Retries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+		calls    int
+		wantErr  string
+	}{
+		{name: "succeeds_after_5xx", statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusAccepted}, attempts: 3, calls: 3},
+		{name: "gives_up_after_attempts", statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusAccepted}, attempts: 2, calls: 2, wantErr: "received status 500"},
+		{name: "no_retry_on_4xx", statuses: []int{http.StatusBadRequest, http.StatusAccepted}, attempts: 3, calls: 1, wantErr: "received status 400"},
+		{name: "no_retry_on_429", statuses: []int{http.StatusTooManyRequests, http.StatusAccepted}, attempts: 3, calls: 1, wantErr: "received status 429"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[calls]
+				calls++
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+			}))
+			t.Cleanup(srv.Close)
+			sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithRetries(tt.attempts, time.Millisecond))
+			require.NoError(t, err)
+
+			res, err := sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+			assert.Equal(t, tt.calls, calls)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "ext-1", res.MessageID)
+		})
+	}
+}
+
+func TestMessageSender_Send_RetriesConnectionErrors(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			// drop the connection without a response
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			_ = conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+
+	res, err := sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "ext-1", res.MessageID)
+}
+
+func TestMessageSender_Send_ObservesEveryRequest(t *testing.T) {
+	var (
+		calls int
+		size  int64
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		size = int64(len(body))
+		if calls == 1 {
+			// drop the connection without a response
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			_ = conn.Close()
+			return
+		}
+		if calls == 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+	}))
+	t.Cleanup(srv.Close)
+	type observation struct {
+		endpoint     string
+		status       int
+		payloadBytes int64
+	}
+	var observed []observation
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL+"?token=secret",
+		webhook.WithRetries(3, time.Millisecond),
+		webhook.WithRequestObserver(func(endpoint string, status int, duration time.Duration, payloadBytes int64) {
+			assert.Positive(t, duration)
+			observed = append(observed, observation{endpoint: endpoint, status: status, payloadBytes: payloadBytes})
+		}),
+	)
+	require.NoError(t, err)
+
+	_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []observation{
+		{endpoint: srv.URL, status: 0, payloadBytes: size},
+		{endpoint: srv.URL, status: http.StatusBadGateway, payloadBytes: size},
+		{endpoint: srv.URL, status: http.StatusAccepted, payloadBytes: size},
+	}, observed)
+}
+
+func TestMessageSender_Send_StopsRetryingOnCancel(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithRetries(5, time.Hour))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = sender.Send(ctx, &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stopped retrying after 1 attempts")
+	assert.Equal(t, 1, calls)
+}
+
+func TestMessageSender_Send_IdempotencyKey(t *testing.T) {
+	var (
+		headers  []string
+		payloads []webhook.RequestPayload
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhook.RequestPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		headers = append(headers, r.Header.Get(webhook.IdempotencyKeyHeader))
+		payloads = append(payloads, payload)
+		if len(payloads) == 1 {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = sender.Send(context.Background(), &message.Message{ID: "msg-1", To: "+905551234567", Content: "hello"})
+
+	require.NoError(t, err)
+	key := webhook.IdempotencyKey("msg-1")
+	assert.Equal(t, []string{key, key}, headers, "retries carry the same key")
+	for _, p := range payloads {
+		assert.Equal(t, key, p.IdempotencyKey)
+	}
+	assert.NotEqual(t, key, webhook.IdempotencyKey("msg-2"))
+	assert.NotContains(t, key, "msg-1")
+}
+
+func TestMessageSender_Send_Concurrent(t *testing.T) {
+	const sends = 50
+	var (
+		mu       sync.Mutex
+		mismatch []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload webhook.RequestPayload
+		_ = json.Unmarshal(body, &payload)
+		// every request carries the configured header once and the key and signature of its own body only
+		ok := len(r.Header.Values("X-Tenant")) == 1 &&
+			r.Header.Get(webhook.IdempotencyKeyHeader) == payload.IdempotencyKey &&
+			r.Header.Get(webhook.SignatureHeader) == webhook.Sign("s3cret", r.Header.Get(webhook.TimestampHeader), body)
+		if !ok {
+			mu.Lock()
+			mismatch = append(mismatch, fmt.Sprint(r.Header))
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL,
+		webhook.WithHeader("X-Tenant", "acme"),
+		webhook.WithHMACSignature("s3cret", ""),
+	)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range sends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := sender.Send(context.Background(), &message.Message{ID: fmt.Sprint(i), To: "+905551234567", Content: "hello"})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Empty(t, mismatch, "requests must not share headers")
+}
+
+func TestMessageSender_Send_RequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL,
+		webhook.WithRetries(5, 10*time.Millisecond),
+		webhook.WithRequestTimeout(50*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestMessageSender_Send_HMACSignature(t *testing.T) {
+	tests := []struct {
+		name       string
+		headerName string
+		expected   string
+	}{
+		{name: "default_header", headerName: "", expected: webhook.SignatureHeader},
+		{name: "custom_header", headerName: "X-Provider-Signature", expected: "X-Provider-Signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var signature, timestamp string
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				signature = r.Header.Get(tt.expected)
+				timestamp = r.Header.Get(webhook.TimestampHeader)
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+			}))
+			t.Cleanup(srv.Close)
+			sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithHMACSignature("s3cret", tt.headerName))
+			require.NoError(t, err)
+
+			_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+			require.NoError(t, err)
+			require.NotEmpty(t, timestamp)
+			assert.Equal(t, webhook.Sign("s3cret", timestamp, body), signature)
+		})
+	}
+}
+
+func TestMessageSender_Send_Unsigned(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"ext-1"}`))
+	}))
+	t.Cleanup(srv.Close)
+	sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, webhook.WithHMACSignature("", ""))
+	require.NoError(t, err)
+
+	_, err = sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+	require.NoError(t, err)
+	assert.Empty(t, header.Get(webhook.SignatureHeader))
+	assert.Empty(t, header.Get(webhook.TimestampHeader))
+}
+
+func TestMessageSender_Send_AcceptedResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		opts     []webhook.OptFunc
+		expected string
+		wantErr  string
+	}{
+		{name: "default", status: http.StatusAccepted, body: `{"message":"Accepted","messageId":"ext-1"}`, expected: "ext-1"},
+		{name: "default_rejects_200", status: http.StatusOK, body: `{"message":"Accepted","messageId":"ext-1"}`, wantErr: "received status 200"},
+		{name: "default_rejects_status_value", status: http.StatusAccepted, body: `{"message":"Queued","messageId":"ext-1"}`, wantErr: "invalid message: Queued"},
+		{
+			name:     "custom_status_codes",
+			status:   http.StatusCreated,
+			body:     `{"message":"Accepted","messageId":"ext-1"}`,
+			opts:     []webhook.OptFunc{webhook.WithAcceptedStatus(http.StatusOK, http.StatusCreated)},
+			expected: "ext-1",
+		},
+		{
+			name:     "custom_fields",
+			status:   http.StatusAccepted,
+			body:     `{"status":"queued","sid":"SM123"}`,
+			opts:     []webhook.OptFunc{webhook.WithResponseFields(webhook.ResponseFields{MessageID: "sid", Status: "status", StatusValue: "queued"})},
+			expected: "SM123",
+		},
+		{
+			name:     "numeric_id_without_status",
+			status:   http.StatusAccepted,
+			body:     `{"id":12345}`,
+			opts:     []webhook.OptFunc{webhook.WithResponseFields(webhook.ResponseFields{MessageID: "id"})},
+			expected: "12345",
+		},
+		{
+			name:     "nested_fields",
+			status:   http.StatusOK,
+			body:     `{"result":{"state":"sent"},"messages":[{"id":"wamid.1"}]}`,
+			opts:     []webhook.OptFunc{webhook.WithAcceptedStatus(http.StatusOK), webhook.WithResponseFields(webhook.ResponseFields{MessageID: "messages.0.id", Status: "result.state", StatusValue: "sent"})},
+			expected: "wamid.1",
+		},
+		{
+			name:    "nested_path_out_of_range",
+			status:  http.StatusAccepted,
+			body:    `{"messages":[]}`,
+			opts:    []webhook.OptFunc{webhook.WithResponseFields(webhook.ResponseFields{MessageID: "messages.0.id"})},
+			wantErr: "blank message id",
+		},
+		{
+			name:    "missing_id",
+			status:  http.StatusAccepted,
+			body:    `{"status":"queued"}`,
+			opts:    []webhook.OptFunc{webhook.WithResponseFields(webhook.ResponseFields{MessageID: "sid", Status: "status", StatusValue: "queued"})},
+			wantErr: "blank message id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(srv.Close)
+			sender, err := webhook.NewWebhookSender(srv.Client(), srv.URL, tt.opts...)
+			require.NoError(t, err)
+
+			res, err := sender.Send(context.Background(), &message.Message{ID: "1", To: "+905551234567", Content: "hello"})
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, res.MessageID)
+		})
+	}
+}